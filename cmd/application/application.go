@@ -53,6 +53,8 @@ func NewAppCommand() *cobra.Command {
 	envCmd.AddCommand(env.NewUpdateEnvCommand())
 	envCmd.AddCommand(env.NewDeleteEnvCommand())
 	envCmd.AddCommand(env.NewSyncEnvCommand())
+	envCmd.AddCommand(env.NewExportEnvCommand())
+	envCmd.AddCommand(env.NewPromoteEnvCommand())
 	cmd.AddCommand(envCmd)
 
 	// Add storage subcommand with its children
@@ -0,0 +1,17 @@
+package env
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+func NewExportEnvCommand() *cobra.Command {
+	return common.NewEnvExportCommand(common.EnvExportCommandOptions{
+		Use:       "export <app_uuid>",
+		Example:   "coolify app env",
+		NewTarget: service.NewApplicationEnvTarget,
+		Preview:   true,
+	})
+}
@@ -0,0 +1,16 @@
+package env
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+func NewPromoteEnvCommand() *cobra.Command {
+	return common.NewEnvPromoteCommand(common.EnvPromoteCommandOptions{
+		Use:       "promote <from_app_uuid> <to_app_uuid>",
+		Example:   "coolify app env",
+		NewTarget: service.NewApplicationEnvTarget,
+	})
+}
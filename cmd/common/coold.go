@@ -0,0 +1,206 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/config"
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	internalssh "github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// Values of --transport.
+const (
+	TransportAuto   = "auto"
+	TransportSSH    = "ssh"
+	TransportDirect = "direct"
+)
+
+// CooldFlags holds the flags shared by every command that talks to coold's
+// REST API on the mesh hosts (coolify firewall, coolify host).
+type CooldFlags struct {
+	// CooldToken is an optional bearer-token override for coold's REST API.
+	// When unset (and COOLIFY_COOLD_TOKEN env is unset), the CLI SSHes into
+	// each host and reads /etc/coolify/api-token instead — tokens are
+	// generated per-host at install time and are not centrally shared.
+	CooldToken string
+	// CooldPort is the TCP port coold listens on (bound to the WG mgmt IP).
+	// Must match COOLD_API_BIND emitted by internal/services/coold.go.
+	CooldPort int
+	// WGInterface is the WireGuard interface name used to discover coold's
+	// bind IP on each host. Must match --wg-interface used at `coolify init`.
+	WGInterface string
+	// Transport selects the route to coold: "ssh" (curl on the host over
+	// SSH), "direct" (HTTP through the operator peer's tunnel) or "auto"
+	// (direct when joined, SSH otherwise or when the tunnel is down).
+	Transport string
+	// OperatorFile is the operator peer state written by `firewall operator
+	// join`. Empty means firewall-operator.json next to the CLI config.
+	OperatorFile string
+}
+
+// BindCooldFlags registers the coold flags as PersistentFlags on cmd.
+func BindCooldFlags(cmd *cobra.Command, f *CooldFlags) {
+	pf := cmd.PersistentFlags()
+	pf.StringVar(&f.CooldToken, "coold-token", "",
+		"Bearer token override for coold REST API (also reads COOLIFY_COOLD_TOKEN env). "+
+			"When unset, CLI reads /etc/coolify/api-token over SSH per host.")
+	pf.IntVar(&f.CooldPort, "coold-port", coold.DefaultPort,
+		"TCP port coold's REST API listens on (bound to the WG mgmt IP)")
+	pf.StringVar(&f.WGInterface, "wg-interface", "wg0",
+		"WireGuard interface name on remote hosts (must match --wg-interface at init)")
+	pf.StringVar(&f.Transport, "transport", TransportAuto,
+		"Route to coold: ssh (curl on each host over SSH), direct (HTTP over the operator peer tunnel, "+
+			"see `firewall operator join`) or auto (direct when joined, falling back to ssh)")
+	pf.StringVar(&f.OperatorFile, "operator-file", "",
+		"Operator peer state (default: firewall-operator.json next to the CLI config)")
+}
+
+// ResolveCooldToken returns the bearer-token override supplied via flag or
+// env, or "" when neither is set. Callers treat an empty string as "no
+// override — SSH-fetch the per-host token instead".
+func (f *CooldFlags) ResolveCooldToken() (string, error) {
+	if f.CooldToken != "" {
+		return f.CooldToken, nil
+	}
+	if env := os.Getenv("COOLIFY_COOLD_TOKEN"); env != "" {
+		return env, nil
+	}
+	return "", nil
+}
+
+// OperatorFilePath returns the path of the operator peer state.
+func (f *CooldFlags) OperatorFilePath() string {
+	if f.OperatorFile != "" {
+		return f.OperatorFile
+	}
+	return DefaultOperatorFile()
+}
+
+// DefaultOperatorFile is where `firewall operator join` keeps the operator
+// peer state unless --operator-file says otherwise.
+func DefaultOperatorFile() string {
+	return filepath.Join(filepath.Dir(config.Path()), "firewall-operator.json")
+}
+
+// DefaultStateFile is the mesh state ledger `coolify init` keeps next to the
+// CLI config (see --state-file).
+func DefaultStateFile() string {
+	return filepath.Join(filepath.Dir(config.Path()), "mesh-state.json")
+}
+
+// TransportPeer validates --transport and returns the operator peer the
+// direct route goes through, or nil when coold is reached over SSH only:
+// --transport=ssh, or auto before `operator join`.
+func (f *CooldFlags) TransportPeer() (*coold.OperatorPeer, error) {
+	switch f.Transport {
+	case "", TransportSSH:
+		return nil, nil
+	case TransportAuto, TransportDirect:
+	default:
+		return nil, fmt.Errorf("--transport must be auto, ssh or direct (got %q)", f.Transport)
+	}
+	peer, err := coold.LoadOperatorPeer(f.OperatorFilePath())
+	if err != nil {
+		return nil, err
+	}
+	if peer == nil && f.Transport == TransportDirect {
+		return nil, fmt.Errorf("--transport=direct needs an operator peer; run `coolify firewall operator join` first")
+	}
+	return peer, nil
+}
+
+// CooldTokenResolver returns a closure that hands out coold bearer tokens
+// per-host. Precedence: explicit --coold-token (or COOLIFY_COOLD_TOKEN env)
+// wins for every host; then the token peer captured at join time; otherwise
+// SSH into the host once and cache the contents of /etc/coolify/api-token.
+// The cache is goroutine-safe so the closure can be used from a fanout.
+func CooldTokenResolver(
+	ctx context.Context,
+	runner internalssh.Runner,
+	mesh *SSHMeshFlags,
+	f *CooldFlags,
+	peer *coold.OperatorPeer,
+) func(host string) (string, error) {
+	if override, _ := f.ResolveCooldToken(); override != "" {
+		return func(string) (string, error) { return override, nil }
+	}
+	var (
+		mu    sync.Mutex
+		cache = map[string]string{}
+	)
+	return func(host string) (string, error) {
+		if peer != nil {
+			if h, ok := peer.Hosts[host]; ok && h.CooldToken != "" {
+				return h.CooldToken, nil
+			}
+		}
+		mu.Lock()
+		if tok, ok := cache[host]; ok {
+			mu.Unlock()
+			return tok, nil
+		}
+		mu.Unlock()
+		tok, err := coold.FetchToken(ctx, runner, host, mesh.SSHUser, mesh.SSHPort)
+		if err != nil {
+			return "", err
+		}
+		mu.Lock()
+		cache[host] = tok
+		mu.Unlock()
+		return tok, nil
+	}
+}
+
+// CooldClients returns a factory for per-host coold clients on the route
+// selected by --transport. runner is only used for the SSH route and for
+// fetching tokens.
+func CooldClients(
+	ctx context.Context,
+	runner internalssh.Runner,
+	mesh *SSHMeshFlags,
+	f *CooldFlags,
+) (func(host string) (*coold.Client, error), error) {
+	peer, err := f.TransportPeer()
+	if err != nil {
+		return nil, err
+	}
+	tokenFor := CooldTokenResolver(ctx, runner, mesh, f, peer)
+	return func(host string) (*coold.Client, error) {
+		token, err := tokenFor(host)
+		if err != nil {
+			return nil, err
+		}
+		bounce := &coold.SSHDoer{
+			Runner:    runner,
+			Host:      host,
+			User:      mesh.SSHUser,
+			SSHPort:   mesh.SSHPort,
+			CooldPort: f.CooldPort,
+			Iface:     f.WGInterface,
+			Token:     token,
+		}
+		// Streams (logs, exec) are tunnelled over the SSH connection.
+		if d, ok := runner.(internalssh.Dialer); ok {
+			bounce.Tunnel = d
+		}
+		if peer == nil {
+			return coold.New(bounce), nil
+		}
+		base, ok := peer.Endpoint(host, f.CooldPort)
+		switch {
+		case !ok && f.Transport == TransportDirect:
+			return nil, fmt.Errorf("%s is not a host of the operator peer; re-run `coolify firewall operator join`", host)
+		case !ok:
+			return coold.New(bounce), nil
+		case f.Transport == TransportDirect:
+			return coold.New(coold.NewHTTPDoer(base, token)), nil
+		}
+		return coold.New(&coold.FallbackDoer{Direct: coold.NewHTTPDoer(base, token), Fallback: bounce}), nil
+	}, nil
+}
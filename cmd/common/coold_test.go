@@ -0,0 +1,100 @@
+package common
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+)
+
+// cooldFakeRunner answers the token read and every curl with fixed output.
+type cooldFakeRunner struct {
+	calls []string
+}
+
+func (f *cooldFakeRunner) Run(_ context.Context, _, _ string, _ int, cmd string) (string, string, error) {
+	f.calls = append(f.calls, cmd)
+	if cmd == "cat "+coold.TokenPath {
+		return "host-token\n", "", nil
+	}
+	return "[]\n__COOLD_STATUS__=200", "", nil
+}
+
+func TestCooldFlags_TransportPeer(t *testing.T) {
+	f := &CooldFlags{Transport: TransportSSH, OperatorFile: filepath.Join(t.TempDir(), "op.json")}
+	peer, err := f.TransportPeer()
+	require.NoError(t, err)
+	assert.Nil(t, peer)
+
+	f.Transport = TransportAuto
+	peer, err = f.TransportPeer()
+	require.NoError(t, err)
+	assert.Nil(t, peer, "auto before join is SSH")
+
+	f.Transport = TransportDirect
+	_, err = f.TransportPeer()
+	assert.ErrorContains(t, err, "operator join")
+
+	f.Transport = "carrier-pigeon"
+	_, err = f.TransportPeer()
+	assert.Error(t, err)
+}
+
+func TestCooldClients_SSHFetchesTokenOnce(t *testing.T) {
+	runner := &cooldFakeRunner{}
+	mesh := &SSHMeshFlags{SSHUser: "root", SSHPort: 22}
+	f := &CooldFlags{CooldPort: 8443, WGInterface: "wg0", Transport: TransportSSH}
+
+	clients, err := CooldClients(context.Background(), runner, mesh, f)
+	require.NoError(t, err)
+	for range 2 {
+		c, err := clients("h1")
+		require.NoError(t, err)
+		_, err = c.ListImages(context.Background())
+		require.NoError(t, err)
+	}
+	require.Len(t, runner.calls, 3, "one token read, two requests")
+	assert.Contains(t, runner.calls[1], "Bearer host-token")
+}
+
+func TestCooldClients_DirectUsesCapturedToken(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = io.WriteString(w, "[]")
+	}))
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	cooldPort, _ := strconv.Atoi(port)
+
+	f := &CooldFlags{CooldPort: cooldPort, Transport: TransportDirect,
+		OperatorFile: filepath.Join(t.TempDir(), "op.json")}
+	require.NoError(t, (&coold.OperatorPeer{
+		MgmtIP: "100.64.255.254",
+		Hosts:  map[string]coold.OperatorHost{"h1": {MgmtIP: host, CooldToken: "captured"}},
+	}).Save(f.OperatorFile))
+	runner := &cooldFakeRunner{}
+
+	clients, err := CooldClients(context.Background(), runner, &SSHMeshFlags{}, f)
+	require.NoError(t, err)
+	c, err := clients("h1")
+	require.NoError(t, err)
+	_, err = c.ListImages(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer captured", gotAuth)
+	assert.Empty(t, runner.calls)
+
+	_, err = clients("h9")
+	assert.ErrorContains(t, err, "not a host of the operator peer")
+}
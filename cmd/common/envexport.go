@@ -0,0 +1,183 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/api"
+	"github.com/coollabsio/coolify-cli/internal/cli"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/parser"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+// Env export syntaxes
+const (
+	EnvSyntaxDotenv = "dotenv"
+	EnvSyntaxJSON   = "json"
+	EnvSyntaxShell  = "shell"
+)
+
+// EnvExportCommandOptions describes how `env export` differs between the
+// application, database and service command trees.
+type EnvExportCommandOptions struct {
+	// Use is the cobra Use line, e.g. "export <app_uuid>".
+	Use string
+	// Example is the resource prefix shown in help, e.g. "coolify app env".
+	Example string
+	// NewTarget builds the env endpoints for the resource.
+	NewTarget func(client *api.Client) service.EnvSyncTarget
+	// Preview enables the --preview flag.
+	Preview bool
+}
+
+// NewEnvExportCommand builds the shared `env export` command, the reverse of
+// `env sync`.
+func NewEnvExportCommand(opts EnvExportCommandOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   opts.Use,
+		Short: "Export environment variables to a .env, JSON or shell file",
+		Long: fmt.Sprintf(`Export environment variables so they can be reproduced locally. The output is
+written to stdout unless --output is given.
+
+Syntaxes:
+- dotenv: escaped KEY="value" lines readable by 'env sync --dotenv --file'
+- json:   a single JSON object of key/value pairs
+- shell:  export KEY='value' statements for 'source' or 'eval'
+
+Values are masked unless --show-sensitive is set.
+
+Example: %s export abc123 --show-sensitive --output .env.local`, opts.Example),
+		Args: cli.ExactArgs(1, "<uuid>"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			uuid := args[0]
+
+			syntax, _ := cmd.Flags().GetString("syntax")
+			if !cmd.Flags().Changed("syntax") {
+				if format, _ := cmd.Flags().GetString("format"); format == output.FormatJSON {
+					syntax = EnvSyntaxJSON
+				}
+			}
+			switch syntax {
+			case EnvSyntaxDotenv, EnvSyntaxJSON, EnvSyntaxShell:
+			default:
+				return fmt.Errorf("unsupported syntax %q (use dotenv, json or shell)", syntax)
+			}
+
+			client, err := cli.GetAPIClient(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to get API client: %w", err)
+			}
+
+			records, err := opts.NewTarget(client).ListEnvRecords(ctx, uuid)
+			if err != nil {
+				return fmt.Errorf("failed to list environment variables: %w", err)
+			}
+
+			filter := EnvExportFilter{}
+			filter.BuildTimeOnly, _ = cmd.Flags().GetBool("build-time")
+			filter.RuntimeOnly, _ = cmd.Flags().GetBool("runtime")
+			if opts.Preview {
+				filter.Preview, _ = cmd.Flags().GetBool("preview")
+			}
+			showSensitive, _ := cmd.Flags().GetBool("show-sensitive")
+			vars := FilterEnvExport(records, filter, showSensitive)
+
+			rendered, err := RenderEnvExport(vars, syntax)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = os.Stdout
+			outPath, _ := cmd.Flags().GetString("output")
+			if outPath != "" {
+				f, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+				if err != nil {
+					return fmt.Errorf("failed to open output file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+			if _, err := io.WriteString(w, rendered); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+
+			if !showSensitive && len(vars) > 0 {
+				fmt.Fprintln(os.Stderr, "Values are masked. Use --show-sensitive to export real values.")
+			}
+			if outPath != "" {
+				fmt.Fprintf(os.Stderr, "Exported %d environment variables to %s\n", len(vars), outPath)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("syntax", EnvSyntaxDotenv, "Output syntax (dotenv|json|shell)")
+	cmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout (created with 0600 permissions)")
+	cmd.Flags().Bool("build-time", false, "Only export variables available at build time")
+	cmd.Flags().Bool("runtime", false, "Only export variables available at runtime")
+	if opts.Preview {
+		cmd.Flags().Bool("preview", false, "Export preview environment variables instead of regular ones")
+	}
+
+	return cmd
+}
+
+// EnvExportFilter selects which variables are exported
+type EnvExportFilter struct {
+	BuildTimeOnly bool
+	RuntimeOnly   bool
+	Preview       bool
+}
+
+// FilterEnvExport applies filter to records, masks values unless
+// showSensitive is set and returns the variables sorted by key.
+func FilterEnvExport(records []service.EnvRecord, filter EnvExportFilter, showSensitive bool) []parser.EnvVar {
+	vars := make([]parser.EnvVar, 0, len(records))
+	for _, rec := range records {
+		if rec.IsPreview != filter.Preview {
+			continue
+		}
+		if filter.BuildTimeOnly && !rec.IsBuildTime {
+			continue
+		}
+		if filter.RuntimeOnly && !rec.IsRuntime {
+			continue
+		}
+		value := rec.Value
+		if !showSensitive {
+			value = cli.SensitiveInformationOverlay
+		}
+		vars = append(vars, parser.EnvVar{Key: rec.Key, Value: value})
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return vars[i].Key < vars[j].Key })
+	return vars
+}
+
+// RenderEnvExport renders vars in the requested syntax
+func RenderEnvExport(vars []parser.EnvVar, syntax string) (string, error) {
+	switch syntax {
+	case EnvSyntaxDotenv:
+		return parser.FormatEnvFile(vars), nil
+	case EnvSyntaxShell:
+		return parser.FormatShellExports(vars), nil
+	case EnvSyntaxJSON:
+		obj := make(map[string]string, len(vars))
+		for _, v := range vars {
+			obj[v.Key] = v.Value
+		}
+		data, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return string(data) + "\n", nil
+	default:
+		return "", fmt.Errorf("unsupported syntax %q", syntax)
+	}
+}
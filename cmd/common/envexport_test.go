@@ -0,0 +1,58 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/parser"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+func TestFilterEnvExport(t *testing.T) {
+	records := []service.EnvRecord{
+		{Key: "RUNTIME", Value: "r", IsRuntime: true},
+		{Key: "BUILD", Value: "b", IsBuildTime: true},
+		{Key: "BOTH", Value: "x", IsBuildTime: true, IsRuntime: true},
+		{Key: "PREVIEW", Value: "p", IsPreview: true, IsRuntime: true},
+	}
+
+	t.Run("masked by default", func(t *testing.T) {
+		got := FilterEnvExport(records, EnvExportFilter{}, false)
+		require.Len(t, got, 3)
+		for _, v := range got {
+			assert.Equal(t, "********", v.Value)
+		}
+		assert.Equal(t, "BOTH", got[0].Key)
+	})
+
+	t.Run("build-time only", func(t *testing.T) {
+		got := FilterEnvExport(records, EnvExportFilter{BuildTimeOnly: true}, true)
+		assert.Equal(t, []parser.EnvVar{{Key: "BOTH", Value: "x"}, {Key: "BUILD", Value: "b"}}, got)
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		got := FilterEnvExport(records, EnvExportFilter{Preview: true, RuntimeOnly: true}, true)
+		assert.Equal(t, []parser.EnvVar{{Key: "PREVIEW", Value: "p"}}, got)
+	})
+}
+
+func TestRenderEnvExport(t *testing.T) {
+	vars := []parser.EnvVar{{Key: "A", Value: "1"}, {Key: "B", Value: "two words"}}
+
+	got, err := RenderEnvExport(vars, EnvSyntaxDotenv)
+	require.NoError(t, err)
+	assert.Equal(t, "A=\"1\"\nB=\"two words\"\n", got)
+
+	got, err = RenderEnvExport(vars, EnvSyntaxJSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"A":"1","B":"two words"}`, got)
+
+	got, err = RenderEnvExport(vars, EnvSyntaxShell)
+	require.NoError(t, err)
+	assert.Equal(t, "export A='1'\nexport B='two words'\n", got)
+
+	_, err = RenderEnvExport(vars, "yaml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/envcrypt"
+	"github.com/coollabsio/coolify-cli/internal/parser"
+)
+
+// BindAgeKeyFlag registers --age-key-file, used to decrypt age-encrypted .env files.
+func BindAgeKeyFlag(cmd *cobra.Command) {
+	cmd.Flags().String("age-key-file", "",
+		fmt.Sprintf("age identity file for encrypted .env files (default: $%s, $%s or age.key in the config directory)",
+			envcrypt.KeyEnv, envcrypt.KeyFileEnv))
+}
+
+// ReadEnvVars reads a .env file ("-" for stdin), decrypting it in memory when
+// it is age-encrypted, and parses it.
+func ReadEnvVars(cmd *cobra.Command, path string, opts parser.ParseOptions) ([]parser.EnvVar, error) {
+	keyFile, _ := cmd.Flags().GetString("age-key-file")
+	data, err := envcrypt.ReadFile(path, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseEnv(bytes.NewReader(data), opts)
+}
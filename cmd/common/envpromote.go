@@ -0,0 +1,149 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/api"
+	"github.com/coollabsio/coolify-cli/internal/cli"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+// EnvPromoteCommandOptions describes how `env promote` differs between the
+// application, database and service command trees.
+type EnvPromoteCommandOptions struct {
+	// Use is the cobra Use line, e.g. "promote <from_app_uuid> <to_app_uuid>".
+	Use string
+	// Example is the resource prefix shown in help, e.g. "coolify app env".
+	Example string
+	// NewTarget builds the env endpoints for the resource.
+	NewTarget func(client *api.Client) service.EnvSyncTarget
+}
+
+// NewEnvPromoteCommand builds the shared `env promote` command that copies
+// variables from one resource to another.
+func NewEnvPromoteCommand(opts EnvPromoteCommandOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   opts.Use,
+		Short: "Copy environment variables from one resource to another",
+		Long: fmt.Sprintf(`Copy production environment variables from a source resource (e.g. the
+staging copy of an app) to a target resource. The diff is shown and confirmed
+before anything is changed; build-time, runtime and literal flags are copied
+along with the values.
+
+Use --deny to keep environment-specific keys such as DATABASE_URL out of the
+promotion, or --allow to promote only matching keys. Both accept globs
+(e.g. 'STRIPE_*') and can be repeated. Filtered keys are never pruned.
+
+Example: %s promote <staging_uuid> <production_uuid> --deny DATABASE_URL --deny 'REDIS_*'`, opts.Example),
+		Args: cli.ExactArgs(2, "<from_uuid> <to_uuid>"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := cli.GetAPIClient(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to get API client: %w", err)
+			}
+			return RunEnvPromote(cmd, EnvPromotion{
+				Source:     opts.NewTarget(client),
+				SourceUUID: args[0],
+				Target:     opts.NewTarget(client),
+				TargetUUID: args[1],
+				From:       args[0],
+				To:         args[1],
+			})
+		},
+	}
+	BindEnvPromoteFlags(cmd)
+	return cmd
+}
+
+// EnvPromotion names the two sides of a promotion. From and To are only used
+// in messages.
+type EnvPromotion struct {
+	Source     service.EnvSyncTarget
+	SourceUUID string
+	Target     service.EnvSyncTarget
+	TargetUUID string
+	From       string
+	To         string
+}
+
+// BindEnvPromoteFlags registers the flags read by RunEnvPromote.
+func BindEnvPromoteFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("allow", nil, "Only promote keys matching these glob patterns (repeatable)")
+	cmd.Flags().StringSlice("deny", nil, "Never promote keys matching these glob patterns, e.g. DATABASE_URL (repeatable)")
+	cmd.Flags().Bool("prune", false, "Delete target variables that do not exist in the source")
+	cmd.Flags().Bool("dry-run", false, "Show what would change without applying it")
+	cmd.Flags().Bool("force", false, "Skip confirmation prompt")
+}
+
+// RunEnvPromote plans a promotion, prints the masked diff, asks for
+// confirmation unless --force is set and applies it.
+func RunEnvPromote(cmd *cobra.Command, p EnvPromotion) error {
+	ctx := cmd.Context()
+
+	filter := service.EnvKeyFilter{}
+	filter.Allow, _ = cmd.Flags().GetStringSlice("allow")
+	filter.Deny, _ = cmd.Flags().GetStringSlice("deny")
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	source, err := p.Source.ListEnvRecords(ctx, p.SourceUUID)
+	if err != nil {
+		return fmt.Errorf("failed to list source environment variables: %w", err)
+	}
+	target, err := p.Target.ListEnvRecords(ctx, p.TargetUUID)
+	if err != nil {
+		return fmt.Errorf("failed to list target environment variables: %w", err)
+	}
+
+	prune, _ := cmd.Flags().GetBool("prune")
+	changes := service.PlanEnvPromotion(source, target, filter, prune)
+
+	pending := 0
+	for _, c := range changes {
+		if c.Kind != service.EnvUnchanged {
+			pending++
+		}
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if dryRun {
+		return PrintEnvSyncPlan(cmd, changes)
+	}
+	if pending == 0 {
+		fmt.Printf("Nothing to promote: %s already matches %s.\n", p.To, p.From)
+		return nil
+	}
+
+	if err := printEnvPlan(cmd, changes, "Plan"); err != nil {
+		return err
+	}
+
+	force, _ := cmd.Flags().GetBool("force")
+	if !force {
+		fmt.Printf("\nPromote %d change(s) from %s to %s? (y/N): ", pending, p.From, p.To)
+		reader := bufio.NewReader(os.Stdin)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("error reading input: %w", err)
+		}
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Promotion cancelled")
+			return nil
+		}
+	}
+
+	res := service.ApplyEnvSync(ctx, p.Target, p.TargetUUID, changes, EnvSyncProgressPrinter())
+	fmt.Printf("\nPromotion complete: %d updated, %d created, %d deleted, %d failed\n",
+		res.Updated, res.Created, res.Deleted, res.Failed)
+	if res.Failed > 0 {
+		return fmt.Errorf("some environment variables failed to promote")
+	}
+	return nil
+}
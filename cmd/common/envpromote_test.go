@@ -0,0 +1,87 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+type memEnvTarget struct {
+	records []service.EnvRecord
+	updated []service.EnvSyncVar
+	created []service.EnvSyncVar
+	deleted []string
+}
+
+func (m *memEnvTarget) ListEnvRecords(context.Context, string) ([]service.EnvRecord, error) {
+	return m.records, nil
+}
+
+func (m *memEnvTarget) BulkUpdateEnvVars(_ context.Context, _ string, vars []service.EnvSyncVar) error {
+	m.updated = append(m.updated, vars...)
+	return nil
+}
+
+func (m *memEnvTarget) CreateEnvVar(_ context.Context, _ string, v service.EnvSyncVar) error {
+	m.created = append(m.created, v)
+	return nil
+}
+
+func (m *memEnvTarget) DeleteEnv(_ context.Context, _, envUUID string) error {
+	m.deleted = append(m.deleted, envUUID)
+	return nil
+}
+
+func newPromoteTestCmd(args ...string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.Flags().String("format", "json", "")
+	cmd.Flags().Bool("show-sensitive", false, "")
+	BindEnvPromoteFlags(cmd)
+	_ = cmd.Flags().Parse(args)
+	return cmd
+}
+
+func TestRunEnvPromote(t *testing.T) {
+	newSides := func() (*memEnvTarget, *memEnvTarget) {
+		src := &memEnvTarget{records: []service.EnvRecord{
+			{Key: "APP_NAME", Value: "demo"},
+			{Key: "DATABASE_URL", Value: "postgres://staging"},
+		}}
+		dst := &memEnvTarget{records: []service.EnvRecord{
+			{UUID: "d1", Key: "DATABASE_URL", Value: "postgres://prod"},
+			{UUID: "d2", Key: "OLD", Value: "x"},
+		}}
+		return src, dst
+	}
+
+	t.Run("dry run changes nothing", func(t *testing.T) {
+		src, dst := newSides()
+		cmd := newPromoteTestCmd("--dry-run", "--prune")
+		require.NoError(t, RunEnvPromote(cmd, EnvPromotion{Source: src, Target: dst, From: "staging", To: "production"}))
+		assert.Empty(t, dst.created)
+		assert.Empty(t, dst.updated)
+		assert.Empty(t, dst.deleted)
+	})
+
+	t.Run("deny keeps environment-specific keys", func(t *testing.T) {
+		src, dst := newSides()
+		cmd := newPromoteTestCmd("--force", "--prune", "--deny", "DATABASE_*")
+		require.NoError(t, RunEnvPromote(cmd, EnvPromotion{Source: src, Target: dst, From: "staging", To: "production"}))
+		require.Len(t, dst.created, 1)
+		assert.Equal(t, "APP_NAME", dst.created[0].Key)
+		assert.Empty(t, dst.updated)
+		assert.Equal(t, []string{"d2"}, dst.deleted)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		src, dst := newSides()
+		cmd := newPromoteTestCmd("--force", "--allow", "[")
+		assert.Error(t, RunEnvPromote(cmd, EnvPromotion{Source: src, Target: dst}))
+	})
+}
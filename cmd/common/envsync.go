@@ -0,0 +1,285 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/api"
+	"github.com/coollabsio/coolify-cli/internal/cli"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/parser"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+// EnvSyncCommandOptions describes how `env sync` differs between the
+// application, database and service command trees.
+type EnvSyncCommandOptions struct {
+	// Use is the cobra Use line, e.g. "sync <app_uuid>".
+	Use string
+	// Example is the resource prefix shown in help, e.g. "coolify app env".
+	Example string
+	// NewTarget builds the env endpoints for the resource.
+	NewTarget func(client *api.Client) service.EnvSyncTarget
+	// BuildTimeRuntime enables the --build-time and --runtime flags.
+	BuildTimeRuntime bool
+	// Preview enables the --preview and --preview-file flags.
+	Preview bool
+}
+
+// NewEnvSyncCommand builds the shared `env sync` command.
+func NewEnvSyncCommand(opts EnvSyncCommandOptions) *cobra.Command {
+	long := `Sync environment variables from a .env file. This command intelligently:
+- Updates existing environment variables with new values
+- Creates new environment variables that don't exist yet
+- Deletes variables missing from the file when --prune is set
+- Uses efficient bulk operations where possible
+
+Values are read verbatim, with matching surrounding quotes stripped. With
+--dotenv the file is parsed as a full dotenv file instead: "export " prefixes,
+inline # comments and escapes such as \n, \" and \$ in double-quoted values.
+With --interpolate, ${VAR} references are expanded from earlier keys and the
+local environment; single-quoted values stay literal. Use --file - to read
+from stdin.
+
+Files encrypted with 'coolify env encrypt' (age format) are decrypted in
+memory using --age-key-file, $COOLIFY_AGE_KEY or $COOLIFY_AGE_KEY_FILE.
+
+Use --dry-run to print the diff (added/changed/removed/unchanged) without
+changing anything. Values are masked unless --show-sensitive is set.`
+	if opts.Preview {
+		long += `
+
+Preview and production variables are tracked separately: --file manages
+production variables (or preview variables with --preview) and
+--preview-file manages preview-only variables.`
+	}
+	long += fmt.Sprintf("\n\nExample: %s sync abc123 --file .env.production", opts.Example)
+
+	cmd := &cobra.Command{
+		Use:   opts.Use,
+		Short: "Sync environment variables from a .env file",
+		Long:  long,
+		Args:  cli.ExactArgs(1, "<uuid>"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			uuid := args[0]
+
+			filePath, _ := cmd.Flags().GetString("file")
+			previewPath := ""
+			if opts.Preview {
+				previewPath, _ = cmd.Flags().GetString("preview-file")
+			}
+			if filePath == "" && previewPath == "" {
+				return fmt.Errorf("--file is required")
+			}
+			if filePath == parser.StdinPath && previewPath == parser.StdinPath {
+				return fmt.Errorf("only one of --file and --preview-file can read from stdin")
+			}
+
+			fileIsPreview := false
+			if opts.Preview {
+				fileIsPreview, _ = cmd.Flags().GetBool("preview")
+				if fileIsPreview && previewPath != "" {
+					return fmt.Errorf("--preview and --preview-file cannot be used together")
+				}
+			}
+
+			var desired []service.EnvSyncVar
+			scope := service.EnvSyncScope{}
+			scope.Prune, _ = cmd.Flags().GetBool("prune")
+
+			if filePath != "" {
+				vars, err := ReadEnvSyncVars(cmd, filePath, fileIsPreview)
+				if err != nil {
+					return err
+				}
+				desired = append(desired, vars...)
+				if fileIsPreview {
+					scope.Preview = true
+				} else {
+					scope.Production = true
+				}
+			}
+			if previewPath != "" {
+				vars, err := ReadEnvSyncVars(cmd, previewPath, true)
+				if err != nil {
+					return err
+				}
+				desired = append(desired, vars...)
+				scope.Preview = true
+			}
+
+			if len(desired) == 0 && !scope.Prune {
+				fmt.Println("No environment variables found in file.")
+				return nil
+			}
+
+			client, err := cli.GetAPIClient(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to get API client: %w", err)
+			}
+			target := opts.NewTarget(client)
+
+			existing, err := target.ListEnvRecords(ctx, uuid)
+			if err != nil {
+				return fmt.Errorf("failed to list existing environment variables: %w", err)
+			}
+
+			changes := service.PlanEnvSync(existing, desired, scope)
+
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			if dryRun {
+				return PrintEnvSyncPlan(cmd, changes)
+			}
+
+			fmt.Printf("Found %d environment variables in file. Syncing...\n", len(desired))
+
+			res := service.ApplyEnvSync(ctx, target, uuid, changes, EnvSyncProgressPrinter())
+
+			fmt.Printf("\nSync complete: %d updated, %d created, %d deleted, %d failed\n",
+				res.Updated, res.Created, res.Deleted, res.Failed)
+
+			if res.Failed > 0 {
+				return fmt.Errorf("some environment variables failed to sync")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("file", "f", "", "Path to .env file, or - for stdin (required)")
+	if opts.BuildTimeRuntime {
+		cmd.Flags().Bool("build-time", true, "Make all variables available at build time (default: true)")
+	}
+	if opts.Preview {
+		cmd.Flags().Bool("preview", false, "Make all variables available in preview deployments")
+		cmd.Flags().String("preview-file", "", "Path to .env file with preview-only variables")
+	}
+	cmd.Flags().Bool("is-literal", false, "Treat all values as literal (don't interpolate variables)")
+	if opts.BuildTimeRuntime {
+		cmd.Flags().Bool("runtime", true, "Make all variables available at runtime (default: true)")
+	}
+	BindAgeKeyFlag(cmd)
+	cmd.Flags().Bool("interpolate", false, "Expand ${VAR} references from the file and the local environment before syncing")
+	cmd.Flags().Bool("dotenv", false, "Parse the file as a full dotenv file (export prefixes, inline comments, escapes)")
+	cmd.Flags().Bool("dry-run", false, "Show what would change without applying it")
+	cmd.Flags().Bool("prune", false, "Delete variables that are not present in the file")
+
+	return cmd
+}
+
+// EnvSyncProgressPrinter prints apply progress the way every sync command does.
+func EnvSyncProgressPrinter() service.EnvSyncProgress {
+	return service.EnvSyncProgress{
+		OnStep: func(msg string) { fmt.Println(msg) },
+		OnResult: func(key string, err error) {
+			switch {
+			case key == "" && err != nil:
+				fmt.Printf("  ✗ Bulk update failed: %v\n", err)
+			case key == "":
+				fmt.Println("  ✓ Bulk update succeeded")
+			case err != nil:
+				fmt.Printf("  ✗ Failed to sync '%s': %v\n", key, err)
+			default:
+				fmt.Printf("  ✓ Synced '%s'\n", key)
+			}
+		},
+	}
+}
+
+// ReadEnvSyncVars parses path (plain or age-encrypted) and applies the
+// attribute flags the user explicitly set to every variable.
+func ReadEnvSyncVars(cmd *cobra.Command, path string, preview bool) ([]service.EnvSyncVar, error) {
+	interpolate, _ := cmd.Flags().GetBool("interpolate")
+	dotenv, _ := cmd.Flags().GetBool("dotenv")
+	envVars, err := ReadEnvVars(cmd, path, parser.ParseOptions{
+		Dotenv:      dotenv,
+		Interpolate: interpolate,
+		Lookup:      os.LookupEnv,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .env file %s: %w", path, err)
+	}
+
+	vars := make([]service.EnvSyncVar, 0, len(envVars))
+	for _, envVar := range envVars {
+		v := service.EnvSyncVar{
+			Key:       envVar.Key,
+			Value:     envVar.Value,
+			IsPreview: preview,
+		}
+		if cmd.Flags().Changed("build-time") {
+			b, _ := cmd.Flags().GetBool("build-time")
+			v.IsBuildTime = &b
+		}
+		if cmd.Flags().Changed("is-literal") {
+			b, _ := cmd.Flags().GetBool("is-literal")
+			v.IsLiteral = &b
+		} else if interpolate && envVar.Literal {
+			// Single-quoted values were kept literal locally, so keep Coolify
+			// from interpolating them too.
+			literal := true
+			v.IsLiteral = &literal
+		}
+		if cmd.Flags().Changed("runtime") {
+			b, _ := cmd.Flags().GetBool("runtime")
+			v.IsRuntime = &b
+		}
+		// Auto-detect multiline values
+		if strings.Contains(envVar.Value, "\n") {
+			multiline := true
+			v.IsMultiline = &multiline
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+// PrintEnvSyncPlan prints a dry-run diff, masking values unless
+// --show-sensitive is set.
+func PrintEnvSyncPlan(cmd *cobra.Command, changes []service.EnvChange) error {
+	return printEnvPlan(cmd, changes, "Dry run")
+}
+
+// printEnvPlan prints changes followed by a table-mode summary prefixed with
+// label. The caller's slice is never modified, so it can still be applied.
+func printEnvPlan(cmd *cobra.Command, changes []service.EnvChange, label string) error {
+	format, _ := cmd.Flags().GetString("format")
+	showSensitive, _ := cmd.Flags().GetBool("show-sensitive")
+
+	rows := make([]service.EnvChange, len(changes))
+	copy(rows, changes)
+	if !showSensitive {
+		for i := range rows {
+			if rows[i].OldValue != "" {
+				rows[i].OldValue = cli.SensitiveInformationOverlay
+			}
+			if rows[i].NewValue != "" {
+				rows[i].NewValue = cli.SensitiveInformationOverlay
+			}
+		}
+	}
+
+	formatter, err := output.NewFormatter(format, output.Options{
+		ShowSensitive: showSensitive,
+	})
+	if err != nil {
+		return err
+	}
+	if err := formatter.Format(rows); err != nil {
+		return err
+	}
+
+	if format == output.FormatTable {
+		counts := map[service.EnvChangeKind]int{}
+		for _, c := range rows {
+			counts[c.Kind]++
+		}
+		fmt.Printf("\n%s: %d added, %d changed, %d removed, %d unchanged\n", label,
+			counts[service.EnvAdded], counts[service.EnvChanged], counts[service.EnvRemoved], counts[service.EnvUnchanged])
+	}
+	return nil
+}
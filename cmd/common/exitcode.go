@@ -0,0 +1,28 @@
+package common
+
+import "errors"
+
+// ExitError carries a specific process exit code for a failed command.
+// Execute exits with Code instead of the default 1, which lets monitoring
+// commands report warning and critical states differently.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// ExitCode returns the exit code for err: the code of the first ExitError in
+// its chain, 0 for nil and 1 otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
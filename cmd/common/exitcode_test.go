@@ -0,0 +1,18 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+	assert.Equal(t, 1, ExitCode(errors.New("boom")))
+	assert.Equal(t, 2, ExitCode(&ExitError{Code: 2, Err: errors.New("critical")}))
+	wrapped := fmt.Errorf("status: %w", &ExitError{Code: 1, Err: errors.New("warning")})
+	assert.Equal(t, 1, ExitCode(wrapped))
+	assert.Equal(t, "status: warning", wrapped.Error())
+}
@@ -7,10 +7,15 @@
 package common
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"regexp"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	internalssh "github.com/coollabsio/coolify-cli/internal/ssh"
 )
 
 // DefaultNamespace is the namespace used when the user does not pass
@@ -44,12 +49,18 @@ type MeshNetFlags struct {
 	ContainerPool string
 
 	// ContainerPrefix is the prefix length of each per-host, per-namespace
-	// container subnet (default 24 → 254 container IPs per host per ns).
+	// container subnet. 0 picks the family default: 24 for an IPv4 pool
+	// (254 container IPs per host per ns), 64 for an IPv6 pool.
 	ContainerPrefix int
+
+	// ContainerPool6 is an optional IPv6 pool next to an IPv4
+	// ContainerPool. When set every bridge is dual-stack and each
+	// (namespace, host) pair also gets a /64 from it.
+	ContainerPool6 string
 }
 
-// BindMeshNetMultiFlags registers --namespaces/--container-pool/--container-prefix
-// on cmd (init-style: many namespaces per invocation).
+// BindMeshNetMultiFlags registers --namespaces/--container-pool/--container-prefix/
+// --container-pool6 on cmd (init-style: many namespaces per invocation).
 func BindMeshNetMultiFlags(cmd *cobra.Command, f *MeshNetFlags) {
 	pf := cmd.PersistentFlags()
 	pf.StringSliceVar(&f.Namespaces, "namespaces", []string{DefaultNamespace},
@@ -57,10 +68,13 @@ func BindMeshNetMultiFlags(cmd *cobra.Command, f *MeshNetFlags) {
 			"namespace is a separate Podman bridge network (coolify-<ns>-mesh) "+
 			"with its own /<container-prefix> per host")
 	pf.StringVar(&f.ContainerPool, "container-pool", "10.210.0.0/16",
-		"Shared container address pool — each (namespace, host) pair gets a "+
+		"Shared IPv4 or IPv6 container address pool — each (namespace, host) pair gets a "+
 			"/<container-prefix> from here, owned by that namespace's Podman bridge")
-	pf.IntVar(&f.ContainerPrefix, "container-prefix", 24,
-		"Prefix length of each per-host, per-namespace container subnet")
+	pf.IntVar(&f.ContainerPrefix, "container-prefix", 0,
+		"Prefix length of each per-host, per-namespace container subnet (default 24 for an IPv4 pool, 64 for IPv6)")
+	pf.StringVar(&f.ContainerPool6, "container-pool6", "",
+		"IPv6 container pool for dual-stack bridges next to an IPv4 --container-pool — each "+
+			"(namespace, host) pair also gets a /64 from here")
 }
 
 // BindMeshNetSingleFlags registers --namespace on cmd (firewall-style: one
@@ -103,3 +117,33 @@ func ValidateNamespace(ns string) error {
 	}
 	return nil
 }
+
+// HostsByMgmtIP maps each server's WireGuard mgmt IP (the first global
+// address on iface) to its SSH host, so a host_mgmt_ip read from Corrosion
+// can be turned back into the server it belongs to. Servers that cannot be
+// reached are left out.
+func HostsByMgmtIP(
+	ctx context.Context,
+	runner internalssh.Runner,
+	mesh *SSHMeshFlags,
+	iface string,
+) func(mgmtIP string) (string, bool) {
+	script := fmt.Sprintf(
+		`ip -o addr show %s scope global 2>/dev/null | awk '{print $4}' | head -n 1 | cut -d/ -f1`,
+		iface)
+	results := internalssh.ForEachServer(ctx, mesh.Servers, mesh.Concurrency,
+		func(ctx context.Context, host string) (string, error) {
+			stdout, _, err := runner.Run(ctx, host, mesh.SSHUser, mesh.SSHPort, script)
+			return strings.TrimSpace(stdout), err
+		})
+	byIP := map[string]string{}
+	for _, r := range results {
+		if ip := net.ParseIP(r.Result); r.Err == nil && ip != nil {
+			byIP[ip.String()] = r.Host
+		}
+	}
+	return func(mgmtIP string) (string, bool) {
+		h, ok := byIP[mgmtIP]
+		return h, ok
+	}
+}
@@ -5,11 +5,15 @@ package common
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"golang.org/x/term"
 
+	"github.com/coollabsio/coolify-cli/internal/config"
 	internalssh "github.com/coollabsio/coolify-cli/internal/ssh"
 )
 
@@ -23,6 +27,22 @@ type SSHMeshFlags struct {
 	SSHPassphrasePrompt bool
 	Concurrency         int
 	SSHTimeout          string
+	// SSHStrictHostKeyChecking is yes, accept-new or no (see internalssh.HostKeyPolicy).
+	SSHStrictHostKeyChecking string
+	// SSHKnownHosts is the Coolify-managed known_hosts file; empty means
+	// DefaultManagedKnownHosts.
+	SSHKnownHosts string
+	// SSHAgent offers keys from the agent at $SSH_AUTH_SOCK.
+	SSHAgent bool
+	// SSHConfig is the OpenSSH client config to read; empty means
+	// ~/.ssh/config and "none" disables it.
+	SSHConfig string
+	// SSHJump is an explicit jump-host chain, overriding ProxyJump.
+	SSHJump []string
+
+	// flags records which flags were set explicitly so ~/.ssh/config can
+	// fill in the rest. Nil when the struct is built by hand (tests).
+	flags *pflag.FlagSet
 }
 
 // BindSSHMeshFlags registers the shared flags as PersistentFlags on cmd.
@@ -43,6 +63,90 @@ func BindSSHMeshFlags(cmd *cobra.Command, f *SSHMeshFlags) {
 		"Maximum number of parallel SSH connections")
 	pf.StringVar(&f.SSHTimeout, "ssh-timeout", "30s",
 		"SSH connection timeout (e.g. 30s, 1m)")
+	pf.StringVar(&f.SSHStrictHostKeyChecking, "ssh-strict-host-key-checking", string(internalssh.HostKeyStrict),
+		"Host key verification: yes (known_hosts only), accept-new (pin unknown hosts on first use) or no")
+	pf.StringVar(&f.SSHKnownHosts, "ssh-known-hosts", "",
+		"Coolify-managed known_hosts file, checked after ~/.ssh/known_hosts (default: known_hosts in the config directory)")
+	pf.BoolVar(&f.SSHAgent, "ssh-agent", true,
+		"Offer keys held by ssh-agent ($SSH_AUTH_SOCK)")
+	pf.StringVar(&f.SSHConfig, "ssh-config", "",
+		"OpenSSH client config for HostName/User/Port/IdentityFile/ProxyJump (default: ~/.ssh/config, \"none\" to disable)")
+	pf.StringSliceVar(&f.SSHJump, "ssh-jump", nil,
+		"Jump host chain as [user@]host[:port], comma-separated (overrides ProxyJump)")
+	f.flags = pf
+}
+
+// agentSocket returns $SSH_AUTH_SOCK when agent use is enabled.
+func (f *SSHMeshFlags) agentSocket() string {
+	if !f.SSHAgent {
+		return ""
+	}
+	return os.Getenv("SSH_AUTH_SOCK")
+}
+
+// changed reports whether the named flag was passed explicitly.
+func (f *SSHMeshFlags) changed(name string) bool {
+	return f.flags != nil && f.flags.Changed(name)
+}
+
+// loadSSHConfig reads the OpenSSH client config selected by --ssh-config.
+func (f *SSHMeshFlags) loadSSHConfig() (*internalssh.Config, error) {
+	switch f.SSHConfig {
+	case "none":
+		return nil, nil
+	case "":
+		return internalssh.LoadConfig(internalssh.DefaultConfigFile())
+	default:
+		if _, err := os.Stat(f.SSHConfig); err != nil {
+			return nil, fmt.Errorf("--ssh-config: %w", err)
+		}
+		return internalssh.LoadConfig(f.SSHConfig)
+	}
+}
+
+// serversWithoutIdentityFile lists the servers for which the SSH config
+// names no IdentityFile that exists on disk.
+func (f *SSHMeshFlags) serversWithoutIdentityFile() ([]string, error) {
+	cfg, err := f.loadSSHConfig()
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, server := range f.Servers {
+		found := false
+		for _, path := range cfg.Lookup(server).IdentityFiles {
+			if _, err := os.Stat(path); err == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, server)
+		}
+	}
+	return missing, nil
+}
+
+// DefaultManagedKnownHosts is the known_hosts file Coolify pins keys to.
+func DefaultManagedKnownHosts() string {
+	return filepath.Join(filepath.Dir(config.Path()), "known_hosts")
+}
+
+// HostKeyVerifier builds the host key verifier selected by the flags.
+func (f *SSHMeshFlags) HostKeyVerifier() (*internalssh.HostKeyVerifier, error) {
+	mode := f.SSHStrictHostKeyChecking
+	if mode == "" {
+		mode = string(internalssh.HostKeyStrict)
+	}
+	policy, err := internalssh.ParseHostKeyPolicy(mode)
+	if err != nil {
+		return nil, err
+	}
+	managed := f.SSHKnownHosts
+	if managed == "" {
+		managed = DefaultManagedKnownHosts()
+	}
+	return internalssh.NewHostKeyVerifier(policy, managed, internalssh.DefaultKnownHostsFiles()...), nil
 }
 
 // ParseSSHTimeout parses SSHTimeout, falling back to 30s on error/zero.
@@ -76,11 +180,26 @@ func (f *SSHMeshFlags) ResolvePassphrase() ([]byte, error) {
 
 // BuildSSHClient creates an SSH client, resolving any key passphrase first.
 func (f *SSHMeshFlags) BuildSSHClient() (*internalssh.Client, error) {
+	verifier, err := f.HostKeyVerifier()
+	if err != nil {
+		return nil, err
+	}
 	passphrase, err := f.ResolvePassphrase()
 	if err != nil {
 		return nil, err
 	}
-	return internalssh.NewClient(f.SSHKey, passphrase, f.ParseSSHTimeout())
+	sshConfig, err := f.loadSSHConfig()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := internalssh.ParseJumpHosts(strings.Join(f.SSHJump, ",")); err != nil {
+		return nil, fmt.Errorf("--ssh-jump: %w", err)
+	}
+	return internalssh.NewClient(f.SSHKey, passphrase, f.ParseSSHTimeout(),
+		internalssh.WithHostKeyVerifier(verifier),
+		internalssh.WithAgent(f.agentSocket()),
+		internalssh.WithSSHConfig(sshConfig, !f.changed("ssh-user"), !f.changed("ssh-port")),
+		internalssh.WithJumpHosts(f.SSHJump))
 }
 
 // Validate checks that the required flags are set.
@@ -88,8 +207,20 @@ func (f *SSHMeshFlags) Validate() error {
 	if len(f.Servers) == 0 {
 		return fmt.Errorf("--servers is required")
 	}
-	if f.SSHKey == "" {
-		return fmt.Errorf("--ssh-key is required")
+	if f.SSHKey == "" && f.agentSocket() == "" {
+		missing, err := f.serversWithoutIdentityFile()
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("--ssh-key is required when no ssh-agent is available and the SSH config has no IdentityFile for %s",
+				strings.Join(missing, ", "))
+		}
+	}
+	if f.SSHStrictHostKeyChecking != "" {
+		if _, err := internalssh.ParseHostKeyPolicy(f.SSHStrictHostKeyChecking); err != nil {
+			return fmt.Errorf("--ssh-strict-host-key-checking: %w", err)
+		}
 	}
 	return nil
 }
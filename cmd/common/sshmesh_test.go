@@ -1,11 +1,15 @@
 package common
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	internalssh "github.com/coollabsio/coolify-cli/internal/ssh"
 )
 
 func TestSSHMeshFlags_ParseSSHTimeout(t *testing.T) {
@@ -32,7 +36,7 @@ func TestSSHMeshFlags_Validate(t *testing.T) {
 		assert.Contains(t, err.Error(), "--servers")
 	})
 	t.Run("missing ssh key", func(t *testing.T) {
-		err := (&SSHMeshFlags{Servers: []string{"1.1.1.1"}}).Validate()
+		err := (&SSHMeshFlags{Servers: []string{"1.1.1.1"}, SSHConfig: "none"}).Validate()
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "--ssh-key")
 	})
@@ -40,6 +44,21 @@ func TestSSHMeshFlags_Validate(t *testing.T) {
 		err := (&SSHMeshFlags{Servers: []string{"1.1.1.1"}, SSHKey: "/k"}).Validate()
 		require.NoError(t, err)
 	})
+	t.Run("invalid host key checking", func(t *testing.T) {
+		err := (&SSHMeshFlags{Servers: []string{"1.1.1.1"}, SSHKey: "/k", SSHStrictHostKeyChecking: "ask"}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--ssh-strict-host-key-checking")
+	})
+}
+
+func TestSSHMeshFlags_HostKeyVerifier(t *testing.T) {
+	v, err := (&SSHMeshFlags{}).HostKeyVerifier()
+	require.NoError(t, err)
+	assert.Equal(t, internalssh.HostKeyStrict, v.Policy())
+
+	v, err = (&SSHMeshFlags{SSHStrictHostKeyChecking: "accept-new", SSHKnownHosts: "/tmp/kh"}).HostKeyVerifier()
+	require.NoError(t, err)
+	assert.Equal(t, internalssh.HostKeyAcceptNew, v.Policy())
 }
 
 func TestSSHMeshFlags_ResolvePassphrase_Env(t *testing.T) {
@@ -55,3 +74,29 @@ func TestSSHMeshFlags_ResolvePassphrase_NoPrompt(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, pass)
 }
+
+func TestSSHMeshFlags_Validate_Agent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/agent.sock")
+	err := (&SSHMeshFlags{Servers: []string{"1.1.1.1"}, SSHAgent: true}).Validate()
+	require.NoError(t, err, "ssh-agent should satisfy the key requirement")
+
+	err = (&SSHMeshFlags{Servers: []string{"1.1.1.1"}, SSHConfig: "none"}).Validate()
+	require.Error(t, err, "agent disabled and no --ssh-key")
+}
+
+func TestSSHMeshFlags_Validate_IdentityFile(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "id_web")
+	require.NoError(t, os.WriteFile(key, []byte("key"), 0o600))
+	cfg := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(cfg, []byte("Host web-*\n  IdentityFile "+key+"\nHost gone\n  IdentityFile "+filepath.Join(dir, "missing")+"\n"), 0o600))
+
+	err := (&SSHMeshFlags{Servers: []string{"web-1", "web-2"}, SSHConfig: cfg}).Validate()
+	require.NoError(t, err, "an IdentityFile from the SSH config should satisfy the key requirement")
+
+	err = (&SSHMeshFlags{Servers: []string{"web-1", "db-1", "gone"}, SSHConfig: cfg}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--ssh-key")
+	assert.Contains(t, err.Error(), "db-1, gone")
+	assert.NotContains(t, err.Error(), "web-1")
+}
@@ -44,6 +44,8 @@ func NewDatabaseCommand() *cobra.Command {
 	envCmd.AddCommand(env.NewUpdateCommand())
 	envCmd.AddCommand(env.NewDeleteCommand())
 	envCmd.AddCommand(env.NewSyncCommand())
+	envCmd.AddCommand(env.NewExportCommand())
+	envCmd.AddCommand(env.NewPromoteCommand())
 	cmd.AddCommand(envCmd)
 
 	// Add backup subcommand
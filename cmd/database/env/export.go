@@ -0,0 +1,16 @@
+package env
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+func NewExportCommand() *cobra.Command {
+	return common.NewEnvExportCommand(common.EnvExportCommandOptions{
+		Use:       "export <database_uuid>",
+		Example:   "coolify db env",
+		NewTarget: service.NewDatabaseEnvTarget,
+	})
+}
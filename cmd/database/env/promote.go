@@ -0,0 +1,16 @@
+package env
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+func NewPromoteCommand() *cobra.Command {
+	return common.NewEnvPromoteCommand(common.EnvPromoteCommandOptions{
+		Use:       "promote <from_database_uuid> <to_database_uuid>",
+		Example:   "coolify db env",
+		NewTarget: service.NewDatabaseEnvTarget,
+	})
+}
@@ -387,7 +387,7 @@ coolify team members list
 - All resource identifiers use UUIDs (not internal database IDs)
 - API base path: ` + "`/api/v1/`" + `
 - Authentication: Bearer token via ` + "`--token`" + ` flag or context configuration
-- ` + "`app env sync`" + ` behavior: updates existing variables, creates missing ones, does NOT delete variables not in the file
+- ` + "`app env sync`" + ` behavior: updates existing variables, creates missing ones, deletes variables not in the file only with ` + "`--prune`" + `; ` + "`--dry-run`" + ` prints a masked diff
 - ` + "`app start`" + ` aliases to ` + "`app deploy`" + ` and also accepts ` + "`--force`" + ` and ` + "`--instant-deploy`" + ` flags
 - Deployment logs support ` + "`--follow`" + ` for real-time streaming and ` + "`--debuglogs`" + ` for internal operations
 - ` + "`app logs`" + ` defaults to 100 lines; ` + "`app deployments logs`" + ` defaults to 0 (all lines)
@@ -0,0 +1,341 @@
+// Package envfile contains the `coolify env` helpers for age-encrypted .env files.
+package envfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/cli"
+	"github.com/coollabsio/coolify-cli/internal/envcrypt"
+	"github.com/coollabsio/coolify-cli/internal/parser"
+)
+
+func NewEnvCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Encrypt, decrypt and edit .env files",
+		Long: `Keep .env files encrypted in your repository with age X25519 keys.
+
+Encrypted files can be passed directly to 'env sync --file'; they are only
+decrypted in memory. The decryption key is read from --age-key-file,
+$COOLIFY_AGE_KEY, $COOLIFY_AGE_KEY_FILE or age.key next to the CLI config file
+(~/.config/coolify/age.key on Linux/macOS).`,
+	}
+	cmd.AddCommand(newKeygenCommand(), newEncryptCommand(), newDecryptCommand(), newEditCommand())
+	return cmd
+}
+
+func newKeygenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate an age X25519 key pair",
+		Long: `Generate an age X25519 identity. The secret key is written to --output
+(default: age.key next to the CLI config file) and the public recipient is
+printed so it can be shared with 'env encrypt --recipient'.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			path, _ := cmd.Flags().GetString("output")
+			if path == "" {
+				path = envcrypt.DefaultKeyFile()
+			}
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists, refusing to overwrite", path)
+			}
+
+			id, err := age.GenerateX25519Identity()
+			if err != nil {
+				return fmt.Errorf("failed to generate key: %w", err)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return fmt.Errorf("failed to create key directory: %w", err)
+			}
+			content := fmt.Sprintf("# public key: %s\n%s\n", id.Recipient(), id)
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				return fmt.Errorf("failed to write key file: %w", err)
+			}
+
+			fmt.Printf("Key written to %s\n", path)
+			fmt.Printf("Public key: %s\n", id.Recipient())
+			return nil
+		},
+	}
+	cmd.Flags().StringP("output", "o", "", "Path to write the secret key to")
+	return cmd
+}
+
+func bindRecipientFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceP("recipient", "r", nil, "age recipient public key (age1...), repeatable")
+	cmd.Flags().String("recipients-file", "", "File with one age recipient per line")
+	cmd.Flags().Bool("armor", false, "Write ASCII-armored output instead of binary")
+}
+
+// recipients returns the recipients from flags, falling back to the public
+// keys of the configured identity.
+func recipients(cmd *cobra.Command) ([]age.Recipient, error) {
+	inline, _ := cmd.Flags().GetStringSlice("recipient")
+	file, _ := cmd.Flags().GetString("recipients-file")
+	recs, err := envcrypt.ParseRecipients(inline, file)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) > 0 {
+		return recs, nil
+	}
+
+	keyFile, _ := cmd.Flags().GetString("age-key-file")
+	ids, err := envcrypt.LoadIdentities(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("no --recipient given and no age key found to derive one from")
+	}
+	recs = envcrypt.RecipientsFromIdentities(ids)
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("no --recipient given and the age key has no X25519 identity")
+	}
+	return recs, nil
+}
+
+// writeOutput atomically writes data to path with 0600 permissions, or to
+// stdout when path is empty
+func writeOutput(path string, data []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces path with data via a 0600 temp file in the same
+// directory, so an interrupted write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func newEncryptCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "encrypt <file>",
+		Short: "Encrypt a .env file for one or more age recipients",
+		Long: `Encrypt a .env file. The file is parsed first so syntax errors are caught
+before it is committed. Without --recipient the public key of the configured
+age key is used.
+
+Example: coolify env encrypt .env.production --recipient age1... -o .env.production.age`,
+		Args: cli.ExactArgs(1, "<file>"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plaintext, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open file: %w", err)
+			}
+			if envcrypt.IsEncrypted(plaintext) {
+				return fmt.Errorf("%s is already encrypted", args[0])
+			}
+			if _, err := parser.ParseEnv(bytes.NewReader(plaintext), parser.ParseOptions{}); err != nil {
+				return fmt.Errorf("refusing to encrypt invalid .env file: %w", err)
+			}
+
+			recs, err := recipients(cmd)
+			if err != nil {
+				return err
+			}
+			armored, _ := cmd.Flags().GetBool("armor")
+			ciphertext, err := envcrypt.Encrypt(plaintext, recs, armored)
+			if err != nil {
+				return err
+			}
+
+			out, _ := cmd.Flags().GetString("output")
+			inPlace, _ := cmd.Flags().GetBool("in-place")
+			if inPlace {
+				out = args[0]
+			}
+			return writeOutput(out, ciphertext)
+		},
+	}
+	bindRecipientFlags(cmd)
+	common.BindAgeKeyFlag(cmd)
+	cmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	cmd.Flags().Bool("in-place", false, "Replace the input file with its encrypted version")
+	return cmd
+}
+
+func newDecryptCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decrypt <file>",
+		Short: "Decrypt an age-encrypted .env file",
+		Long: `Decrypt an age-encrypted .env file to stdout or --output. Prefer passing the
+encrypted file straight to 'env sync', which never writes plaintext to disk.`,
+		Args: cli.ExactArgs(1, "<file>"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open file: %w", err)
+			}
+			if !envcrypt.IsEncrypted(data) {
+				return fmt.Errorf("%s is not encrypted", args[0])
+			}
+			keyFile, _ := cmd.Flags().GetString("age-key-file")
+			ids, err := envcrypt.LoadIdentities(keyFile)
+			if err != nil {
+				return err
+			}
+			plaintext, err := envcrypt.Decrypt(data, ids)
+			if err != nil {
+				return err
+			}
+			out, _ := cmd.Flags().GetString("output")
+			return writeOutput(out, plaintext)
+		},
+	}
+	common.BindAgeKeyFlag(cmd)
+	cmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout (created with 0600 permissions)")
+	return cmd
+}
+
+func newEditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit <file>",
+		Short: "Edit an age-encrypted .env file in $EDITOR",
+		Long: `Decrypt a file to a private temporary file, open it in $VISUAL or $EDITOR
+(default: vi), validate the result and re-encrypt it in place. A missing file
+is created for the public key of the configured age key unless --recipient
+is given.
+
+age files do not record their recipients, so editing an existing file needs
+every recipient it is shared with in --recipient or --recipients-file.
+When the edited file does not parse, nothing is saved and the plaintext is
+left in the temporary file so the edits can be recovered.`,
+		Args: cli.ExactArgs(1, "<file>"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			keyFile, _ := cmd.Flags().GetString("age-key-file")
+
+			var plaintext []byte
+			armored, _ := cmd.Flags().GetBool("armor")
+			data, err := os.ReadFile(path)
+			switch {
+			case os.IsNotExist(err):
+			case err != nil:
+				return fmt.Errorf("failed to open file: %w", err)
+			case !envcrypt.IsEncrypted(data):
+				return fmt.Errorf("%s is not encrypted; run 'coolify env encrypt --in-place' first", path)
+			default:
+				ids, err := envcrypt.LoadIdentities(keyFile)
+				if err != nil {
+					return err
+				}
+				if plaintext, err = envcrypt.Decrypt(data, ids); err != nil {
+					return err
+				}
+				if !cmd.Flags().Changed("armor") {
+					armored = envcrypt.IsArmored(data)
+				}
+			}
+
+			// age files do not name their recipients, so re-encrypting an
+			// existing file for the local key alone would lock out everyone
+			// else it was shared with.
+			if data != nil && !cmd.Flags().Changed("recipient") && !cmd.Flags().Changed("recipients-file") {
+				return fmt.Errorf("%s is already encrypted; pass every recipient it is shared with via --recipient or --recipients-file", path)
+			}
+			recs, err := recipients(cmd)
+			if err != nil {
+				return err
+			}
+
+			dir, err := os.MkdirTemp("", "coolify-env-edit-")
+			if err != nil {
+				return fmt.Errorf("failed to create temp dir: %w", err)
+			}
+			keep := false
+			defer func() {
+				if !keep {
+					os.RemoveAll(dir)
+				}
+			}()
+			tmp := filepath.Join(dir, strings.TrimSuffix(filepath.Base(path), ".age"))
+			if err := os.WriteFile(tmp, plaintext, 0600); err != nil {
+				return fmt.Errorf("failed to write temp file: %w", err)
+			}
+
+			if err := runEditor(tmp); err != nil {
+				return err
+			}
+
+			edited, err := os.ReadFile(tmp)
+			if err != nil {
+				return fmt.Errorf("failed to read edited file: %w", err)
+			}
+			if bytes.Equal(edited, plaintext) && data != nil {
+				fmt.Println("No changes.")
+				return nil
+			}
+			if _, err := parser.ParseEnv(bytes.NewReader(edited), parser.ParseOptions{}); err != nil {
+				keep = true
+				return fmt.Errorf("edited file is invalid, not saved (your edits are kept in %s): %w", tmp, err)
+			}
+
+			ciphertext, err := envcrypt.Encrypt(edited, recs, armored)
+			if err != nil {
+				return err
+			}
+			if err := writeFileAtomic(path, ciphertext); err != nil {
+				return err
+			}
+			fmt.Printf("Saved %s\n", path)
+			return nil
+		},
+	}
+	bindRecipientFlags(cmd)
+	common.BindAgeKeyFlag(cmd)
+	return cmd
+}
+
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], path)...) //nolint:gosec // editor is chosen by the user
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
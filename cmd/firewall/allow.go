@@ -23,6 +23,7 @@ type allowRevokeFlags struct {
 	Port          int
 	Proto         string
 	Bidirectional bool
+	ByName        bool
 }
 
 // newAllowCommand builds `coolify firewall allow`.
@@ -65,6 +66,9 @@ func bindAllowRevokeFlags(cmd *cobra.Command, f *allowRevokeFlags) {
 		"Protocol (tcp, udp, or empty for any)")
 	pf.BoolVar(&f.Bidirectional, "bidirectional", false,
 		"Also install the reverse rule on the source host (default: one-way; conntrack handles replies)")
+	pf.BoolVar(&f.ByName, "by-name", false,
+		"Treat --from/--to as container names resolved through Corrosion and keep the rule "+
+			"bound to the names, so `firewall reconcile` can follow IP changes")
 }
 
 func validateAllowRevokeFlags(f *allowRevokeFlags) error {
@@ -103,6 +107,7 @@ func runAllowRevoke(
 	if err != nil {
 		return fmt.Errorf("SSH client: %w", err)
 	}
+	defer runner.Close()
 	return emitAllowRevoke(ctx, cmd, parent, local, runner, revoke)
 }
 
@@ -116,6 +121,9 @@ func emitAllowRevoke(
 	runner ssh.Runner,
 	revoke bool,
 ) error {
+	if local.ByName {
+		return emitNamedAllowRevoke(ctx, cmd, parent, local, runner, revoke)
+	}
 	all, results := discoverAllViaPkg(ctx, runner, parent)
 	for _, r := range results {
 		if r.Err != nil {
@@ -181,28 +189,36 @@ func emitAllowRevoke(
 		}
 		rules = append(rules, reverse)
 	}
+	return sendAllowRevoke(ctx, cmd, parent, runner, rules, revoke)
+}
 
+// sendAllowRevoke POSTs (or DELETEs) rules against coold on each rule's host
+// and prints them.
+func sendAllowRevoke(
+	ctx context.Context,
+	cmd *cobra.Command,
+	parent *Flags,
+	runner ssh.Runner,
+	rules []ifw.AllowRule,
+	revoke bool,
+) error {
 	action := "allow"
 	past := "allowed"
 	if revoke {
 		action = "revoke"
 		past = "revoked"
 	}
-	tokenFor := tokenResolver(ctx, runner, parent)
+	transport, err := cooldTransport(ctx, runner, parent)
+	if err != nil {
+		return err
+	}
 	for _, r := range rules {
-		token, terr := tokenFor(r.Host)
-		if terr != nil {
-			return fmt.Errorf("%s on %s: %w", action, r.Host, terr)
-		}
 		var rerr error
 		if revoke {
 			// Revoke by id — coold is idempotent (204 even on unknown id).
-			id := strings.TrimPrefix(r.Comment, "cid:")
-			rerr = ifw.CooldRevoke(ctx, runner, r.Host, parent.SSHUser,
-				parent.SSHPort, parent.CooldPort, parent.WGInterface, token, id)
+			rerr = transport.Revoke(ctx, r.Host, strings.TrimPrefix(r.Comment, "cid:"))
 		} else {
-			rerr = ifw.CooldApply(ctx, runner, r.Host, parent.SSHUser,
-				parent.SSHPort, parent.CooldPort, parent.WGInterface, token, r)
+			rerr = transport.Apply(ctx, r.Host, r)
 		}
 		if rerr != nil {
 			return fmt.Errorf("%s on %s: %w", action, r.Host, rerr)
@@ -218,8 +234,8 @@ func emitAllowRevoke(
 			Host:      r.Host,
 			Namespace: r.Namespace,
 			ID:        r.Comment,
-			Src:       r.Src.String(),
-			Dst:       r.Dst.String(),
+			Src:       ipOrAny(r.Src),
+			Dst:       ipOrAny(r.Dst),
 			Proto:     r.Proto,
 			Port:      r.Port,
 			Comment:   r.Comment,
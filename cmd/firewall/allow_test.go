@@ -75,10 +75,10 @@ func parentWithToken() *Flags {
 		SSHMeshFlags: common.SSHMeshFlags{
 			Servers: []string{"h1"}, SSHUser: "root", SSHPort: 22, Concurrency: 1,
 		},
-		Namespace:   common.DefaultNamespace,
-		CooldToken:  "test-token",
-		CooldPort:   8443,
-		WGInterface: "wg0",
+		CooldFlags: common.CooldFlags{
+			CooldToken: "test-token", CooldPort: 8443, WGInterface: "wg0",
+		},
+		Namespace: common.DefaultNamespace,
 	}
 }
 
@@ -111,7 +111,7 @@ func TestEmitAllowRevoke_PostsOneAllowToCoold(t *testing.T) {
 	assert.Contains(t, posts[0], `"dst":"10.210.0.10"`)
 	assert.Contains(t, posts[0], `"port":80`)
 	// Discovers mgmt IP via wg0 before curl.
-	assert.Contains(t, posts[0], "ip -4 -o addr show wg0")
+	assert.Contains(t, posts[0], "ip -o addr show wg0 scope global")
 }
 
 // TestEmitAllowRevoke_CarriesNonDefaultNamespace verifies that the user's
@@ -0,0 +1,257 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// applyFlags are the per-subcommand flags for `apply`.
+type applyFlags struct {
+	File   string
+	Prune  bool
+	DryRun bool
+}
+
+// newApplyCommand builds `coolify firewall apply`.
+func newApplyCommand(parent *Flags) *cobra.Command {
+	local := &applyFlags{}
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Converge allow rules to a declarative policy file",
+		Long: `Converge the allow rules on every server to a policy file.
+
+Rules name containers by "host/name", name globs ("web-*", "db1/pg-*") or
+label selectors, and are expanded against the containers discovered on the
+mesh. The expanded rules are diffed by rule ID against what coold reports:
+missing rules are added, and with --prune, rules in the policy's namespaces
+that the file no longer implies are removed. --dry-run prints the plan only.
+
+  namespace: default
+  rules:
+    - from: web-*
+      to: db1/postgres
+      port: 5432
+    - from: {labels: {app: api}}
+      to: {host: db1, name: "cache-*"}
+      port: 6379
+      bidirectional: true
+
+proto defaults to tcp when a port is set; "proto: any" with no port allows
+every protocol. A rule may override the namespace; the file's namespace
+defaults to --namespace.`,
+		Example: `  coolify firewall apply -f policy.yaml --servers 10.0.0.1,10.0.0.2 --dry-run
+  coolify firewall apply -f policy.yaml --servers 10.0.0.1,10.0.0.2 --prune`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runApply(cmd.Context(), cmd, parent, local)
+		},
+	}
+	cmd.Flags().StringVarP(&local.File, "file", "f", "",
+		"Policy file (YAML or JSON) — required")
+	cmd.Flags().BoolVar(&local.Prune, "prune", false,
+		"Remove rules in the policy's namespaces that the file does not imply")
+	cmd.Flags().BoolVar(&local.DryRun, "dry-run", false,
+		"Print the plan without changing any rule")
+	return cmd
+}
+
+func runApply(ctx context.Context, cmd *cobra.Command, parent *Flags, local *applyFlags) error {
+	if err := parent.Validate(); err != nil {
+		return err
+	}
+	if local.File == "" {
+		return fmt.Errorf("--file is required")
+	}
+	policy, err := ifw.LoadPolicy(local.File)
+	if err != nil {
+		return err
+	}
+	runner, err := parent.BuildSSHClient()
+	if err != nil {
+		return fmt.Errorf("SSH client: %w", err)
+	}
+	defer runner.Close()
+	return emitApply(ctx, cmd, parent, local, policy, runner)
+}
+
+// emitApply is the core path: discover → expand → diff → apply. Split from
+// the cobra wrapper so tests inject a fake ssh.Runner and policy.
+func emitApply(
+	ctx context.Context,
+	cmd *cobra.Command,
+	parent *Flags,
+	local *applyFlags,
+	policy *ifw.Policy,
+	runner ssh.Runner,
+) error {
+	defaultNS := policy.Namespace
+	if defaultNS == "" {
+		defaultNS = parent.Namespace
+	}
+	ruleNS := make([]string, len(policy.Rules))
+	nsSet := map[string]bool{}
+	for i, r := range policy.Rules {
+		ruleNS[i] = r.Namespace
+		if ruleNS[i] == "" {
+			ruleNS[i] = defaultNS
+		}
+		if err := common.ValidateNamespace(ruleNS[i]); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		nsSet[ruleNS[i]] = true
+	}
+	if len(nsSet) == 0 {
+		// An empty policy still scopes --prune to the default namespace.
+		nsSet[defaultNS] = true
+	}
+	namespaces := make([]string, 0, len(nsSet))
+	for ns := range nsSet {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	all, discoverResults := discoverAcrossNamespaces(ctx, runner, parent, namespaces)
+	var errs []string
+	for _, r := range discoverResults {
+		if r.Err != nil {
+			errs = append(errs, fmt.Sprintf("discover %s: %v", r.Host, r.Err))
+		}
+	}
+	// Expanding against a partial view could prune rules for containers
+	// that are merely unreachable.
+	if len(errs) > 0 && local.Prune {
+		return fmt.Errorf("refusing to prune with unreachable hosts: %v", errs)
+	}
+
+	var desired []ifw.AllowRule
+	for i, r := range policy.Rules {
+		var inNS []ifw.Container
+		for _, c := range all {
+			if c.Namespace == ruleNS[i] {
+				inNS = append(inNS, c)
+			}
+		}
+		from, err := resolveSelector(r.From, inNS)
+		if err != nil {
+			return fmt.Errorf("rule %d from: %w", i+1, err)
+		}
+		to, err := resolveSelector(r.To, inNS)
+		if err != nil {
+			return fmt.Errorf("rule %d to: %w", i+1, err)
+		}
+		if len(from) == 0 || len(to) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: rule %d (%s → %s) matches no containers in namespace %s\n",
+				i+1, r.From, r.To, ruleNS[i])
+			continue
+		}
+		rules, err := ifw.ExpandRule(ruleNS[i], r, from, to)
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		desired = append(desired, rules...)
+	}
+
+	transport, err := cooldTransport(ctx, runner, parent)
+	if err != nil {
+		return err
+	}
+	listed, listResults := ifw.ListAllVia(ctx, transport, parent.Servers, parent.Concurrency, "")
+	for _, r := range listResults {
+		if r.Err != nil {
+			if local.Prune {
+				return fmt.Errorf("list rules on %s: %w", r.Host, r.Err)
+			}
+			errs = append(errs, fmt.Sprintf("list %s: %v", r.Host, r.Err))
+		}
+	}
+	var existing []ifw.AllowRule
+	for _, r := range listed {
+		if nsSet[r.Namespace] {
+			existing = append(existing, r)
+		}
+	}
+
+	changes, unchanged := ifw.DiffRules(desired, existing, local.Prune)
+	fmt.Fprintf(os.Stderr, "Plan: %d to add, %d to remove, %d unchanged.\n",
+		countOp(changes, "add"), countOp(changes, "remove"), unchanged)
+
+	rows := make([]models.FirewallPolicyChangeRow, 0, len(changes))
+	failed := 0
+	for _, ch := range changes {
+		r := ch.Rule
+		status := "planned"
+		if !local.DryRun {
+			status = "done"
+			if err := applyChange(ctx, transport, ch); err != nil {
+				status = "failed"
+				failed++
+				errs = append(errs, fmt.Sprintf("%s on %s: %v", ch.Op, r.Host, err))
+			}
+		}
+		rows = append(rows, models.FirewallPolicyChangeRow{
+			Op:        ch.Op,
+			Host:      r.Host,
+			Namespace: r.Namespace,
+			ID:        ifw.RuleID(r),
+			Src:       ipOrAny(r.Src),
+			Dst:       ipOrAny(r.Dst),
+			Proto:     r.Proto,
+			Port:      r.Port,
+			Status:    status,
+		})
+	}
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "Warning:", e)
+	}
+
+	format, _ := cmd.Root().PersistentFlags().GetString("format")
+	if format == "" {
+		format = output.FormatTable
+	}
+	formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+	if err != nil {
+		return err
+	}
+	if format == output.FormatJSON || format == output.FormatPretty {
+		err = formatter.Format(models.FirewallPolicyOutput{
+			DryRun: local.DryRun, Changes: rows, Unchanged: unchanged, Errors: errs,
+		})
+	} else if len(rows) > 0 {
+		err = formatter.Format(rows)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d changes failed", failed, len(changes))
+	}
+	return nil
+}
+
+// applyChange POSTs or DELETEs one planned change against coold on the
+// rule's host.
+func applyChange(ctx context.Context, transport ifw.Transport, ch ifw.RuleChange) error {
+	if ch.Op == "remove" {
+		return transport.Revoke(ctx, ch.Rule.Host, ifw.RuleID(ch.Rule))
+	}
+	return transport.Apply(ctx, ch.Rule.Host, ch.Rule)
+}
+
+func countOp(changes []ifw.RuleChange, op string) int {
+	n := 0
+	for _, ch := range changes {
+		if ch.Op == op {
+			n++
+		}
+	}
+	return n
+}
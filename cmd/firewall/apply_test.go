@@ -0,0 +1,89 @@
+package firewall
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
+)
+
+func TestEmitApply_AddsMissingAndPrunesStale(t *testing.T) {
+	apiToDB := ifw.ComputeID("default", net.ParseIP("10.210.0.11"), net.ParseIP("10.210.0.12"), "tcp", 5432)
+	fr := &cmdFakeRunner{responses: map[string]string{
+		"podman ps": "aaa111111111|web-1|10.210.0.10|\n" +
+			"bbb222222222|api|10.210.0.11|app=api,\n" +
+			"ccc333333333|db|10.210.0.12|\n",
+		`/allow"`: `[{"namespace":"default","src":"10.210.0.11","dst":"10.210.0.12","proto":"tcp","port":5432,"id":"` + apiToDB + `"},` +
+			`{"namespace":"default","src":"10.210.0.9","dst":"10.210.0.12","id":"stale0000000"},` +
+			`{"namespace":"alpha","src":"10.220.0.9","dst":"10.220.0.12","id":"other0000000"}]`,
+	}}
+	policy, err := ifw.ParsePolicy([]byte(`
+rules:
+  - from: {labels: {app: api}}
+    to: h1/db
+    port: 5432
+  - from: web-*
+    to: db
+    port: 5432
+`))
+	require.NoError(t, err)
+	inner := &cobra.Command{Use: "apply"}
+	rootCmdFor(inner)
+
+	err = emitApply(context.Background(), inner, parentWithToken(), &applyFlags{Prune: true}, policy, fr)
+	require.NoError(t, err)
+
+	var posts, deletes []string
+	for _, c := range fr.calls {
+		switch {
+		case strings.Contains(c, "-X POST"):
+			posts = append(posts, c)
+		case strings.Contains(c, "-X DELETE"):
+			deletes = append(deletes, c)
+		}
+	}
+	require.Len(t, posts, 1, "api → db already exists")
+	assert.Contains(t, posts[0], `"src":"10.210.0.10"`)
+	assert.Contains(t, posts[0], `"dst":"10.210.0.12"`)
+	require.Len(t, deletes, 1, "rules in other namespaces are left alone")
+	assert.Contains(t, deletes[0], "/allow/stale0000000")
+}
+
+func TestEmitApply_DryRunChangesNothing(t *testing.T) {
+	fr := &cmdFakeRunner{responses: map[string]string{
+		"podman ps": "aaa111111111|web|10.210.0.10|\nccc333333333|db|10.210.0.12|\n",
+	}}
+	policy, err := ifw.ParsePolicy([]byte("rules:\n  - from: web\n    to: db\n    port: 80\n"))
+	require.NoError(t, err)
+	inner := &cobra.Command{Use: "apply"}
+	rootCmdFor(inner)
+
+	err = emitApply(context.Background(), inner, parentWithToken(), &applyFlags{DryRun: true, Prune: true}, policy, fr)
+	require.NoError(t, err)
+	for _, c := range fr.calls {
+		assert.NotContains(t, c, "-X POST")
+		assert.NotContains(t, c, "-X DELETE")
+	}
+}
+
+func TestEmitApply_AmbiguousExactReferenceErrors(t *testing.T) {
+	fr := &cmdFakeRunner{responses: map[string]string{
+		"podman ps": "aaa111111111|web|10.210.0.10|\n",
+	}}
+	parent := parentWithToken()
+	parent.Servers = []string{"h1", "h2"}
+	policy, err := ifw.ParsePolicy([]byte("rules:\n  - from: web\n    to: web\n    port: 80\n"))
+	require.NoError(t, err)
+	inner := &cobra.Command{Use: "apply"}
+	rootCmdFor(inner)
+
+	err = emitApply(context.Background(), inner, parent, &applyFlags{}, policy, fr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ambiguous")
+}
@@ -0,0 +1,172 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// exitDenied is the exit code of `check` when the flow would be dropped,
+// so scripts can tell a denial from a failure to evaluate.
+const exitDenied = 2
+
+// checkFlags are the per-subcommand flags for `check`.
+type checkFlags struct {
+	Port  int
+	Proto string
+	Probe bool
+}
+
+// newCheckCommand builds `coolify firewall check`.
+func newCheckCommand(parent *Flags) *cobra.Command {
+	local := &checkFlags{}
+	cmd := &cobra.Command{
+		Use:   "check <src> <dst>",
+		Short: "Tell whether COOLIFY-INTRA would let src reach dst:port",
+		Long: `Resolve both endpoints (name, short-id, raw IP, or host:name), fetch the
+destination host's allow rules from coold and evaluate them the way
+COOLIFY-ALLOW does: the namespace must match, an empty rule protocol
+matches every protocol and a zero rule port every port. The first matching
+rule is reported.
+
+The verdict is worked out from the rule list alone and assumes the mesh
+runs with default-deny. --probe additionally opens a real TCP connection
+from the source container's network namespace.
+
+Exits 0 when the flow is allowed, 2 when it would be dropped and 1 when it
+cannot be evaluated.`,
+		Example: `  coolify firewall check web db --port 5432 --servers 10.0.0.1,10.0.0.2
+  coolify firewall check 10.0.0.1:web db --port 5432 --probe`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCheck(cmd.Context(), cmd, parent, local, args[0], args[1])
+		},
+	}
+	cmd.Flags().IntVar(&local.Port, "port", 0,
+		"Destination port (required unless --proto is empty)")
+	cmd.Flags().StringVar(&local.Proto, "proto", "tcp",
+		"Protocol (tcp, udp, or empty for any)")
+	cmd.Flags().BoolVar(&local.Probe, "probe", false,
+		"Also attempt a TCP connection from the source container")
+	return cmd
+}
+
+func runCheck(ctx context.Context, cmd *cobra.Command, parent *Flags, local *checkFlags, src, dst string) error {
+	if err := parent.Validate(); err != nil {
+		return err
+	}
+	if err := common.ValidateNamespace(parent.Namespace); err != nil {
+		return err
+	}
+	if err := validateAllowRevokeFlags(&allowRevokeFlags{From: src, To: dst, Port: local.Port, Proto: local.Proto}); err != nil {
+		return err
+	}
+	if local.Probe && local.Proto != "tcp" {
+		return fmt.Errorf("--probe only supports --proto tcp")
+	}
+	runner, err := parent.BuildSSHClient()
+	if err != nil {
+		return fmt.Errorf("SSH client: %w", err)
+	}
+	defer runner.Close()
+	return emitCheck(ctx, cmd, parent, local, runner, src, dst)
+}
+
+// emitCheck is the core path: discover → resolve → list dst rules →
+// evaluate (→ probe). Split from the cobra wrapper so tests inject a fake
+// ssh.Runner.
+func emitCheck(
+	ctx context.Context,
+	cmd *cobra.Command,
+	parent *Flags,
+	local *checkFlags,
+	runner ssh.Runner,
+	srcRef, dstRef string,
+) error {
+	all, results := discoverAllViaPkg(ctx, runner, parent)
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: discover %s: %v\n", r.Host, r.Err)
+		}
+	}
+	src, err := resolveEndpoint(srcRef, all)
+	if err != nil {
+		return fmt.Errorf("src: %w", err)
+	}
+	dst, err := resolveEndpoint(dstRef, all)
+	if err != nil {
+		return fmt.Errorf("dst: %w", err)
+	}
+	if src.Host == "" {
+		src.Host, _ = findHostForIP(src.IP, all)
+	}
+	if dst.Host == "" {
+		if h, ok := findHostForIP(dst.IP, all); ok {
+			dst.Host = h
+		} else {
+			return fmt.Errorf("cannot determine destination host for IP %s — no container on the mesh owns it", dst.IP)
+		}
+	}
+
+	transport, err := cooldTransport(ctx, runner, parent)
+	if err != nil {
+		return err
+	}
+	rules, err := transport.List(ctx, dst.Host, parent.Namespace)
+	if err != nil {
+		return err
+	}
+
+	out := models.FirewallCheckOutput{
+		Src:       src.IP.String(),
+		SrcHost:   src.Host,
+		Dst:       dst.IP.String(),
+		DstHost:   dst.Host,
+		Namespace: parent.Namespace,
+		Proto:     local.Proto,
+		Port:      local.Port,
+		Verdict:   "denied",
+	}
+	rule, allowed := ifw.FirstMatch(rules, parent.Namespace, src.IP, dst.IP, local.Proto, local.Port)
+	if allowed {
+		out.Verdict = "allowed"
+		out.Rule = ifw.RuleID(rule)
+	}
+
+	if local.Probe {
+		if src.ID == "" || src.Host == "" {
+			return fmt.Errorf("--probe needs the source to be a container on the mesh (got %s)", srcRef)
+		}
+		out.Probe = "connected"
+		if perr := ifw.ProbeTCP(ctx, runner, src.Host, parent.SSHUser, parent.SSHPort,
+			src.ID, dst.IP, local.Port); perr != nil {
+			out.Probe = "failed: " + perr.Error()
+		}
+	}
+
+	format, _ := cmd.Root().PersistentFlags().GetString("format")
+	if format == "" {
+		format = output.FormatTable
+	}
+	formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+	if err != nil {
+		return err
+	}
+	if err := formatter.Format(out); err != nil {
+		return err
+	}
+	if !allowed {
+		return &common.ExitError{Code: exitDenied, Err: fmt.Errorf(
+			"%s → %s %s/%d is not matched by any rule on %s and would be dropped",
+			out.Src, out.Dst, protoOrAny(local.Proto), local.Port, dst.Host)}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package firewall
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+)
+
+func checkRunner() *cmdFakeRunner {
+	return &cmdFakeRunner{responses: map[string]string{
+		"podman ps":  "aaa111111111|web|10.210.0.10|\nccc333333333|db|10.210.0.12|\n",
+		`/allow?nam`: `[{"namespace":"default","src":"10.210.0.10","dst":"10.210.0.12","proto":"tcp","id":"anytcp000000"}]`,
+	}}
+}
+
+func TestEmitCheck_ReportsMatchingRule(t *testing.T) {
+	fr := checkRunner()
+	inner := &cobra.Command{Use: "check"}
+	rootCmdFor(inner)
+
+	err := emitCheck(context.Background(), inner, parentWithToken(),
+		&checkFlags{Proto: "tcp", Port: 5432}, fr, "web", "db")
+	require.NoError(t, err, "a port-less tcp rule admits every tcp port")
+
+	var listed bool
+	for _, c := range fr.calls {
+		listed = listed || strings.Contains(c, "/allow?namespace=default")
+		assert.NotContains(t, c, "nsenter", "no probe without --probe")
+	}
+	assert.True(t, listed)
+}
+
+func TestEmitCheck_DeniedExitsTwo(t *testing.T) {
+	inner := &cobra.Command{Use: "check"}
+	rootCmdFor(inner)
+
+	err := emitCheck(context.Background(), inner, parentWithToken(),
+		&checkFlags{Proto: "tcp", Port: 80}, checkRunner(), "db", "web")
+	require.Error(t, err)
+	assert.Equal(t, exitDenied, common.ExitCode(err))
+	assert.Contains(t, err.Error(), "would be dropped")
+}
+
+func TestEmitCheck_ProbeRunsFromSourceContainer(t *testing.T) {
+	fr := checkRunner()
+	inner := &cobra.Command{Use: "check"}
+	rootCmdFor(inner)
+
+	err := emitCheck(context.Background(), inner, parentWithToken(),
+		&checkFlags{Proto: "tcp", Port: 5432, Probe: true}, fr, "web", "db")
+	require.NoError(t, err)
+	probe := fr.calls[len(fr.calls)-1]
+	assert.Contains(t, probe, "podman inspect --format '{{.State.Pid}}' 'aaa111111111'")
+	assert.Contains(t, probe, "nsenter -t \"$pid\" -n")
+	assert.Contains(t, probe, "nc -z -w 3 10.210.0.12 5432")
+}
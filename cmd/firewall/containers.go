@@ -32,6 +32,7 @@ func runContainers(ctx context.Context, cmd *cobra.Command, flags *Flags) error
 	if err != nil {
 		return fmt.Errorf("SSH client: %w", err)
 	}
+	defer runner.Close()
 	return emitContainers(ctx, cmd, flags, runner)
 }
 
@@ -24,7 +24,20 @@ Subcommands:
   containers  List discovered containers across the mesh.
   list        Show installed allow rules.
   allow       Add an allow rule (src container → dst container:port).
-  revoke      Remove an allow rule.`,
+  revoke      Remove an allow rule.
+  apply       Converge allow rules to a declarative policy file.
+  check       Tell whether a flow would be allowed, and by which rule.
+  reconcile   Re-resolve name-based rules (allow --by-name) after IP changes.
+  gc          Remove rules pointing at IPs no container owns.
+  operator    Join this machine to the mesh so coold is reached directly.
+
+Server host keys are verified against ~/.ssh/known_hosts and Coolify's own
+known_hosts file. Pass --ssh-strict-host-key-checking=accept-new to pin the
+keys of new servers on first use; a changed key is always rejected.
+
+Keys from ssh-agent and HostName/User/Port/IdentityFile/ProxyJump from
+~/.ssh/config are used automatically; --ssh-jump tunnels through bastion
+hosts. One connection per server is reused for the whole command.`,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			return cmd.Help()
 		},
@@ -36,6 +49,11 @@ Subcommands:
 	cmd.AddCommand(newListCommand(flags))
 	cmd.AddCommand(newAllowCommand(flags))
 	cmd.AddCommand(newRevokeCommand(flags))
+	cmd.AddCommand(newApplyCommand(flags))
+	cmd.AddCommand(newCheckCommand(flags))
+	cmd.AddCommand(newReconcileCommand(flags))
+	cmd.AddCommand(newGCCommand(flags))
+	cmd.AddCommand(newOperatorCommand(flags))
 
 	return cmd
 }
@@ -18,14 +18,20 @@ func TestNewFirewallCommand_Subcommands(t *testing.T) {
 	assert.Contains(t, subs, "list")
 	assert.Contains(t, subs, "allow")
 	assert.Contains(t, subs, "revoke")
+	assert.Contains(t, subs, "apply")
+	assert.Contains(t, subs, "check <src> <dst>")
+	assert.Contains(t, subs, "reconcile")
+	assert.Contains(t, subs, "gc")
+	assert.Contains(t, subs, "operator")
 }
 
 func TestNewFirewallCommand_PersistentFlags(t *testing.T) {
 	cmd := NewFirewallCommand()
 	pf := cmd.PersistentFlags()
 	for _, name := range []string{"servers", "ssh-key", "ssh-user", "ssh-port",
-		"concurrency", "ssh-timeout", "namespace", "all-namespaces",
-		"coold-token", "coold-port", "wg-interface"} {
+		"concurrency", "ssh-timeout", "ssh-strict-host-key-checking", "ssh-known-hosts", "ssh-agent", "ssh-config", "ssh-jump", "namespace", "all-namespaces",
+		"coold-token", "coold-port", "wg-interface", "corrosion-api-port", "names-file",
+		"transport", "operator-file"} {
 		assert.NotNil(t, pf.Lookup(name), "missing --%s", name)
 	}
 	// Replaced by --namespace; must be gone.
@@ -0,0 +1,146 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// newGCCommand builds `coolify firewall gc`.
+func newGCCommand(parent *Flags) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove allow rules pointing at container IPs no container owns",
+		Long: `Remove the allow rules in --namespace (every namespace with
+--all-namespaces) whose source or destination is a container IP held by no
+running container in Corrosion's service_endpoints. Container IPs are the
+container pool and subnets recorded in the mesh state (--state-file); rules
+from or to any other address, such as an external source, are kept.
+Preview with --dry-run first.`,
+		Example: `  coolify firewall gc --servers 10.0.0.1,10.0.0.2 --dry-run`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := parent.Validate(); err != nil {
+				return err
+			}
+			runner, err := parent.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer runner.Close()
+			return emitGC(cmd.Context(), cmd, parent, runner, dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"List the orphaned rules without removing them")
+	return cmd
+}
+
+func emitGC(ctx context.Context, cmd *cobra.Command, parent *Flags, runner ssh.Runner, dryRun bool) error {
+	// Without the container pool every IP outside the mesh would look
+	// orphaned.
+	ledger, err := wireguard.LoadLedger(parent.stateFile())
+	if err != nil {
+		return err
+	}
+	var containerNets []*net.IPNet
+	if ledger != nil {
+		containerNets = ledger.ContainerNets()
+	}
+	if len(containerNets) == 0 {
+		return fmt.Errorf("%s records no container pool; pass the --state-file coolify init wrote", parent.stateFile())
+	}
+
+	eps, err := loadEndpoints(ctx, runner, parent)
+	if err != nil {
+		return err
+	}
+	// An empty view (corrosion just reset, coold not yet synced) would make
+	// every rule look orphaned.
+	live := 0
+	for _, ep := range eps {
+		if ep.HoldsIP() {
+			live++
+		}
+	}
+	if live == 0 {
+		return fmt.Errorf("service_endpoints lists no running containers; refusing to gc")
+	}
+
+	ns := parent.Namespace
+	if parent.AllNamespaces {
+		ns = ""
+	}
+	transport, err := cooldTransport(ctx, runner, parent)
+	if err != nil {
+		return err
+	}
+	rules, results := ifw.ListAllVia(ctx, transport, parent.Servers, parent.Concurrency, ns)
+	var errs []string
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Sprintf("list %s: %v", r.Host, r.Err))
+		}
+	}
+
+	orphans := ifw.Orphans(rules, eps, containerNets)
+	rows := make([]models.FirewallPolicyChangeRow, 0, len(orphans))
+	failed := 0
+	for _, r := range orphans {
+		status := "planned"
+		if !dryRun {
+			status = "done"
+			if err := applyChange(ctx, transport, ifw.RuleChange{Op: "remove", Rule: r}); err != nil {
+				status = "failed"
+				failed++
+				errs = append(errs, fmt.Sprintf("remove on %s: %v", r.Host, err))
+			}
+		}
+		rows = append(rows, models.FirewallPolicyChangeRow{
+			Op:        "remove",
+			Host:      r.Host,
+			Namespace: r.Namespace,
+			ID:        ifw.RuleID(r),
+			Src:       ipOrAny(r.Src),
+			Dst:       ipOrAny(r.Dst),
+			Proto:     r.Proto,
+			Port:      r.Port,
+			Status:    status,
+		})
+	}
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "Warning:", e)
+	}
+
+	format, _ := cmd.Root().PersistentFlags().GetString("format")
+	if format == "" {
+		format = output.FormatTable
+	}
+	formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+	if err != nil {
+		return err
+	}
+	if format == output.FormatJSON || format == output.FormatPretty {
+		err = formatter.Format(models.FirewallGCOutput{DryRun: dryRun, Removed: rows, Errors: errs})
+	} else if len(rows) == 0 {
+		fmt.Fprintln(os.Stderr, "No orphaned rules.")
+	} else {
+		err = formatter.Format(rows)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d orphaned rules could not be removed", failed, len(orphans))
+	}
+	return nil
+}
@@ -2,9 +2,6 @@ package firewall
 
 import (
 	"context"
-	"sort"
-	"strings"
-	"sync"
 
 	"github.com/coollabsio/coolify-cli/cmd/common"
 	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
@@ -44,86 +41,47 @@ func discoverAcrossNamespaces(
 		common.PodmanNetworkFor, flags.Concurrency)
 }
 
-// discoverNamespacesOnHosts SSHes into every host and lists every podman
-// network carrying the io.coolify.managed=true label, collecting the unique
-// io.coolify.namespace label values. Used by `containers --all-namespaces`.
-// Returns the per-host results so host-level failures surface as warnings
-// instead of aborting the fanout.
+// discoverNamespacesOnHosts lists the namespaces present on the hosts (see
+// ifw.DiscoverNamespaces), always including the selected one — the caller
+// may have just created it and no host reports it yet. Used by
+// `containers --all-namespaces`.
 func discoverNamespacesOnHosts(
 	ctx context.Context,
 	runner ssh.Runner,
 	flags *Flags,
 ) ([]string, []ssh.ServerResult[[]string]) {
-	// `podman network ls`'s `{{.Labels}}` renders as a comma-separated `k=v`
-	// string (not a map, unlike `podman network inspect`), so `index` can't be
-	// used — pull `io.coolify.namespace=<val>` out with sed instead.
-	script := `podman network ls --filter label=io.coolify.managed=true ` +
-		`--format '{{.Labels}}' 2>/dev/null | ` +
-		`sed -n 's/.*io\.coolify\.namespace=\([^,]*\).*/\1/p' || true`
-	results := ssh.ForEachServer(ctx, flags.Servers, flags.Concurrency,
-		func(ctx context.Context, host string) ([]string, error) {
-			stdout, _, err := runner.Run(ctx, host, flags.SSHUser,
-				flags.SSHPort, script)
-			if err != nil {
-				return nil, err
-			}
-			var nss []string
-			for _, line := range strings.Split(stdout, "\n") {
-				ns := strings.TrimSpace(line)
-				if ns != "" {
-					nss = append(nss, ns)
-				}
-			}
-			return nss, nil
-		})
-	seen := map[string]struct{}{}
-	for _, r := range results {
-		for _, ns := range r.Result {
-			seen[ns] = struct{}{}
-		}
-	}
-	// Always probe the selected namespace too — caller may have just created
-	// it and we haven't seen it on any host yet.
-	seen[flags.Namespace] = struct{}{}
-	all := make([]string, 0, len(seen))
-	for ns := range seen {
-		all = append(all, ns)
-	}
-	sort.Strings(all)
-	return all, results
+	return ifw.DiscoverNamespaces(ctx, runner, flags.Servers, flags.SSHUser,
+		flags.SSHPort, flags.Concurrency, flags.Namespace)
 }
 
-// tokenResolver returns a closure that hands out coold bearer tokens
-// per-host. Precedence: explicit --coold-token (or COOLIFY_COOLD_TOKEN env)
-// wins for every host; otherwise SSH into the host once and cache the
-// contents of /etc/coolify/api-token. The cache is goroutine-safe so the
-// closure can be passed straight into CooldListAll's fanout.
-func tokenResolver(
-	ctx context.Context,
-	runner ssh.Runner,
-	flags *Flags,
-) func(host string) (string, error) {
-	if override, _ := flags.ResolveCooldToken(); override != "" {
-		return func(string) (string, error) { return override, nil }
+// cooldTransport builds the route to coold selected by --transport. Without
+// an operator peer, auto is plain SSH-bounce; with one, direct HTTP is tried
+// first and SSH is the fallback for hosts the tunnel can't reach.
+func cooldTransport(ctx context.Context, runner ssh.Runner, flags *Flags) (ifw.Transport, error) {
+	peer, err := flags.TransportPeer()
+	if err != nil {
+		return nil, err
+	}
+	tokenFor := common.CooldTokenResolver(ctx, runner, &flags.SSHMeshFlags, &flags.CooldFlags, peer)
+	bounce := &ifw.SSHTransport{
+		Runner:    runner,
+		User:      flags.SSHUser,
+		SSHPort:   flags.SSHPort,
+		CooldPort: flags.CooldPort,
+		Iface:     flags.WGInterface,
+		TokenFor:  tokenFor,
+	}
+	if peer == nil {
+		return bounce, nil
+	}
+	direct := &ifw.DirectTransport{
+		Endpoint: func(host string) (string, bool) {
+			return peer.Endpoint(host, flags.CooldPort)
+		},
+		TokenFor: tokenFor,
 	}
-	var (
-		mu    sync.Mutex
-		cache = map[string]string{}
-	)
-	return func(host string) (string, error) {
-		mu.Lock()
-		if tok, ok := cache[host]; ok {
-			mu.Unlock()
-			return tok, nil
-		}
-		mu.Unlock()
-		tok, err := ifw.FetchCooldToken(ctx, runner, host, flags.SSHUser, flags.SSHPort)
-		if err != nil {
-			return "", err
-		}
-		mu.Lock()
-		cache[host] = tok
-		mu.Unlock()
-		return tok, nil
+	if flags.Transport == common.TransportDirect {
+		return direct, nil
 	}
+	return &ifw.FallbackTransport{Direct: direct, Fallback: bounce}, nil
 }
@@ -32,6 +32,7 @@ func runList(ctx context.Context, cmd *cobra.Command, flags *Flags) error {
 	if err != nil {
 		return fmt.Errorf("SSH client: %w", err)
 	}
+	defer runner.Close()
 	return emitList(ctx, cmd, flags, runner)
 }
 
@@ -41,16 +42,17 @@ func emitList(
 	flags *Flags,
 	runner ssh.Runner,
 ) error {
-	tokenFor := tokenResolver(ctx, runner, flags)
+	transport, err := cooldTransport(ctx, runner, flags)
+	if err != nil {
+		return err
+	}
 
 	// --all-namespaces → omit the query param so coold returns the union.
 	ns := flags.Namespace
 	if flags.AllNamespaces {
 		ns = ""
 	}
-	all, results := ifw.CooldListAll(ctx, runner, flags.Servers, flags.SSHUser,
-		flags.SSHPort, flags.CooldPort, flags.WGInterface, tokenFor,
-		flags.Concurrency, ns)
+	all, results := ifw.ListAllVia(ctx, transport, flags.Servers, flags.Concurrency, ns)
 
 	rows := make([]models.AllowRuleRow, 0, len(all))
 	for _, r := range all {
@@ -0,0 +1,104 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// loadEndpoints reads Corrosion's service_endpoints from the first server
+// that answers. The table is replicated, so one host is enough.
+func loadEndpoints(ctx context.Context, runner ssh.Runner, flags *Flags) ([]ifw.ServiceEndpoint, error) {
+	var errs []string
+	for _, host := range flags.Servers {
+		eps, err := ifw.ListServiceEndpoints(ctx, runner, host, flags.SSHUser,
+			flags.SSHPort, flags.CorrosionAPIPort)
+		if err == nil {
+			return eps, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("read service_endpoints: %s", strings.Join(errs, "; "))
+}
+
+// hostsByMgmtIP maps each server's WireGuard mgmt IP to its SSH host, so
+// the host_mgmt_ip of a service endpoint can be turned into the host whose
+// coold owns the rule.
+func hostsByMgmtIP(ctx context.Context, runner ssh.Runner, flags *Flags) func(mgmtIP string) (string, bool) {
+	return common.HostsByMgmtIP(ctx, runner, &flags.SSHMeshFlags, flags.WGInterface)
+}
+
+// emitNamedAllowRevoke is the --by-name path of allow/revoke: resolve the
+// names through Corrosion, install (or remove) the IP rule and keep the
+// name binding in the names file.
+func emitNamedAllowRevoke(
+	ctx context.Context,
+	cmd *cobra.Command,
+	parent *Flags,
+	local *allowRevokeFlags,
+	runner ssh.Runner,
+	revoke bool,
+) error {
+	named, err := ifw.LoadNamedRules(parent.namesFile())
+	if err != nil {
+		return err
+	}
+	bindings := []ifw.NamedRule{{
+		Namespace: parent.Namespace,
+		SrcName:   local.From,
+		DstName:   local.To,
+		Proto:     local.Proto,
+		Port:      local.Port,
+	}}
+	if local.Bidirectional {
+		reverse := bindings[0]
+		reverse.SrcName, reverse.DstName = local.To, local.From
+		bindings = append(bindings, reverse)
+	}
+
+	eps, err := loadEndpoints(ctx, runner, parent)
+	if err != nil {
+		return err
+	}
+	hostFor := hostsByMgmtIP(ctx, runner, parent)
+
+	rules := make([]ifw.AllowRule, 0, len(bindings))
+	for i, b := range bindings {
+		if revoke {
+			// Remove the rule where it was installed, even if the names
+			// now resolve elsewhere or not at all.
+			if prev, ok := named.Get(b); ok && prev.RuleID != "" {
+				rules = append(rules, ifw.AllowRule{
+					Host: prev.Host, Namespace: b.Namespace, Proto: b.Proto, Port: b.Port,
+					Comment: "cid:" + prev.RuleID,
+				})
+				continue
+			}
+		}
+		r, err := b.Resolve(eps, hostFor)
+		if err != nil {
+			return fmt.Errorf("%s → %s: %w", b.SrcName, b.DstName, err)
+		}
+		rules = append(rules, r)
+		bindings[i].RuleID = ifw.RuleID(r)
+		bindings[i].Host = r.Host
+	}
+
+	if err := sendAllowRevoke(ctx, cmd, parent, runner, rules, revoke); err != nil {
+		return err
+	}
+	for _, b := range bindings {
+		if revoke {
+			named.Delete(b)
+		} else {
+			named.Put(b)
+		}
+	}
+	return named.Save(parent.namesFile())
+}
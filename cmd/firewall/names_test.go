@@ -0,0 +1,163 @@
+package firewall
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// meshRunner answers per host: the mgmt IP lookup, the corrosion query and
+// coold's rule list. Every command is recorded as "host: cmd".
+type meshRunner struct {
+	mu        sync.Mutex
+	mgmtIP    map[string]string
+	endpoints string
+	rules     map[string]string
+	calls     []string
+}
+
+func (m *meshRunner) Run(_ context.Context, host, _ string, _ int, cmd string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, host+": "+cmd)
+	switch {
+	case strings.Contains(cmd, "/v1/queries"):
+		return m.endpoints, "", nil
+	case strings.Contains(cmd, ifw.CooldAPIBasePath) && !strings.Contains(cmd, "-X "):
+		return m.rules[host], "", nil
+	case strings.HasPrefix(cmd, "ip -o addr show"):
+		return m.mgmtIP[host] + "\n", "", nil
+	}
+	return "", "", nil
+}
+
+func (m *meshRunner) sent(method string) []string {
+	var out []string
+	for _, c := range m.calls {
+		if strings.Contains(c, ifw.CooldAPIBasePath) && strings.Contains(c, "-X "+method) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func namedParent(t *testing.T) *Flags {
+	p := parentWithToken()
+	p.Servers = []string{"h1", "h2"}
+	p.CorrosionAPIPort = 8080
+	p.NamesFile = filepath.Join(t.TempDir(), "firewall-names.json")
+	return p
+}
+
+const endpointRows = `{"columns":["container_id","container_name","namespace","container_ip","host_mgmt_ip","state"]}
+{"row":[1,["a1","web","default","10.210.0.10","100.64.0.1","running"]]}
+{"row":[2,["b2","db","default","10.210.1.20","100.64.0.2","running"]]}
+{"eoq":{"time":0.001}}
+`
+
+func TestEmitAllowRevoke_ByNameRecordsBinding(t *testing.T) {
+	m := &meshRunner{
+		mgmtIP:    map[string]string{"h1": "100.64.0.1", "h2": "100.64.0.2"},
+		endpoints: endpointRows,
+	}
+	parent := namedParent(t)
+	inner := &cobra.Command{Use: "allow"}
+	rootCmdFor(inner)
+
+	local := &allowRevokeFlags{From: "web", To: "db", Proto: "tcp", Port: 5432, ByName: true}
+	require.NoError(t, emitAllowRevoke(context.Background(), inner, parent, local, m, false))
+	posts := m.sent("POST")
+	require.Len(t, posts, 1)
+	assert.True(t, strings.HasPrefix(posts[0], "h2: "), "rule lives on the db host")
+	assert.Contains(t, posts[0], `"dst":"10.210.1.20"`)
+
+	named, err := ifw.LoadNamedRules(parent.NamesFile)
+	require.NoError(t, err)
+	require.Len(t, named.Rules, 1)
+	assert.Equal(t, "h2", named.Rules[0].Host)
+	assert.Equal(t, ifw.ComputeID("default", net.ParseIP("10.210.0.10"), net.ParseIP("10.210.1.20"), "tcp", 5432), named.Rules[0].RuleID)
+
+	require.NoError(t, emitAllowRevoke(context.Background(), inner, parent, local, m, true))
+	deletes := m.sent("DELETE")
+	require.Len(t, deletes, 1)
+	assert.Contains(t, deletes[0], "/allow/"+named.Rules[0].RuleID)
+	named, err = ifw.LoadNamedRules(parent.NamesFile)
+	require.NoError(t, err)
+	assert.Empty(t, named.Rules)
+}
+
+func TestEmitReconcile_RewritesDriftedRule(t *testing.T) {
+	parent := namedParent(t)
+	oldID := ifw.ComputeID("default", net.ParseIP("10.210.0.10"), net.ParseIP("10.210.1.7"), "tcp", 5432)
+	named := &ifw.NamedRules{Rules: []ifw.NamedRule{{
+		Namespace: "default", SrcName: "web", DstName: "db", Proto: "tcp", Port: 5432,
+		RuleID: oldID, Host: "h2",
+	}}}
+	require.NoError(t, named.Save(parent.NamesFile))
+	m := &meshRunner{
+		mgmtIP:    map[string]string{"h1": "100.64.0.1", "h2": "100.64.0.2"},
+		endpoints: endpointRows,
+		rules: map[string]string{
+			"h2": `[{"namespace":"default","src":"10.210.0.10","dst":"10.210.1.7","proto":"tcp","port":5432,"id":"` + oldID + `"}]`,
+		},
+	}
+	inner := &cobra.Command{Use: "reconcile"}
+	rootCmdFor(inner)
+
+	require.NoError(t, emitReconcile(context.Background(), inner, parent, m, true))
+	assert.Empty(t, m.sent("POST"), "dry run")
+
+	require.NoError(t, emitReconcile(context.Background(), inner, parent, m, false))
+	posts, deletes := m.sent("POST"), m.sent("DELETE")
+	require.Len(t, posts, 1)
+	assert.Contains(t, posts[0], `"dst":"10.210.1.20"`)
+	require.Len(t, deletes, 1)
+	assert.Contains(t, deletes[0], "/allow/"+oldID)
+
+	got, err := ifw.LoadNamedRules(parent.NamesFile)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldID, got.Rules[0].RuleID)
+}
+
+func TestEmitGC_RemovesRulesForUnownedIPs(t *testing.T) {
+	parent := namedParent(t)
+	parent.StateFile = filepath.Join(t.TempDir(), "mesh-state.json")
+	m := &meshRunner{
+		endpoints: endpointRows,
+		rules: map[string]string{
+			"h2": `[{"namespace":"default","src":"10.210.0.10","dst":"10.210.1.20","id":"live00000000"},` +
+				`{"namespace":"default","src":"10.210.0.99","dst":"10.210.1.20","id":"stale0000000"},` +
+				`{"namespace":"default","src":"203.0.113.7","dst":"10.210.1.20","id":"external0000"}]`,
+		},
+	}
+	inner := &cobra.Command{Use: "gc"}
+	rootCmdFor(inner)
+
+	err := emitGC(context.Background(), inner, parent, m, false)
+	assert.ErrorContains(t, err, "records no container pool")
+	assert.Empty(t, m.sent("DELETE"))
+
+	ledger := wireguard.NewLedger()
+	ledger.ContainerPool = "10.210.0.0/16"
+	require.NoError(t, ledger.Save(parent.StateFile))
+
+	require.NoError(t, emitGC(context.Background(), inner, parent, m, false))
+	deletes := m.sent("DELETE")
+	require.Len(t, deletes, 1)
+	assert.True(t, strings.HasPrefix(deletes[0], "h2: "))
+	assert.Contains(t, deletes[0], "/allow/stale0000000")
+
+	m.endpoints = `{"eoq":{"time":0}}`
+	err = emitGC(context.Background(), inner, parent, m, false)
+	assert.ErrorContains(t, err, "refusing to gc")
+}
@@ -0,0 +1,392 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// operatorJoinFlags are the per-subcommand flags for `operator join`.
+type operatorJoinFlags struct {
+	MgmtPool string
+	IP       string
+}
+
+// newOperatorCommand builds `coolify firewall operator`.
+func newOperatorCommand(parent *Flags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "operator",
+		Short: "Join this machine to the mesh for direct coold access",
+		Long: `Make the CLI machine a lightweight WireGuard peer of the mesh (the
+"operator peer") so firewall commands reach coold's REST API on each host's
+wg0 mgmt IP directly instead of bouncing curl through SSH.
+
+The operator gets one address from the mgmt pool and is admitted on every
+host for that address only; container subnets are not routed to it. With
+--transport=auto (the default) commands use the tunnel when joined and fall
+back to SSH for hosts it can't reach.
+
+  join    Register the operator peer on every server.
+  config  Print the wg-quick config for this machine.
+  leave   Remove the operator peer from every server.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+	cmd.PersistentFlags().StringVar(&parent.StateFile, "state-file", "",
+		"Mesh state ledger the operator's mgmt IP is recorded in (must match --state-file at init; "+
+			"default: mesh-state.json next to the CLI config)")
+	cmd.AddCommand(newOperatorJoinCommand(parent))
+	cmd.AddCommand(newOperatorConfigCommand(parent))
+	cmd.AddCommand(newOperatorLeaveCommand(parent))
+	return cmd
+}
+
+func newOperatorJoinCommand(parent *Flags) *cobra.Command {
+	local := &operatorJoinFlags{}
+	cmd := &cobra.Command{
+		Use:   "join",
+		Short: "Register this machine as a WireGuard peer on every server",
+		Long: `Generate (or reuse) the operator peer's key, pick its mgmt IP and admit
+it on every server with "wg set" plus a marked [Peer] block in the host's
+WireGuard config. Each host's public key, mgmt IP and coold token are
+recorded in the operator file (mode 0600) so direct calls need no SSH.
+
+The operator's mgmt IP and public key are also recorded in the mesh state
+ledger, so "coolify init" never hands the IP to a new host and keeps the
+operator block when it re-renders the host configs. Joining is idempotent.`,
+		Example: `  coolify firewall operator join --servers 10.0.0.1,10.0.0.2
+  coolify firewall operator config | sudo tee /etc/wireguard/coolify.conf
+  sudo wg-quick up coolify`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := parent.Validate(); err != nil {
+				return err
+			}
+			runner, err := parent.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer runner.Close()
+			return emitOperatorJoin(cmd.Context(), cmd, parent, local, runner)
+		},
+	}
+	cmd.Flags().StringVar(&local.MgmtPool, "mgmt-pool", "100.64.0.0/16",
+		"Mesh mgmt pool the operator's address comes from (must match --wg-mgmt-pool at init)")
+	cmd.Flags().StringVar(&local.IP, "ip", "",
+		"Operator mgmt IP (default: keep the current one, else the highest free address of --mgmt-pool)")
+	return cmd
+}
+
+func newOperatorConfigCommand(parent *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "config",
+		Short: "Print the operator peer's wg-quick config",
+		Long: `Print the wg-quick config for this machine, private key included. Write it
+to /etc/wireguard/<name>.conf and bring it up with "wg-quick up <name>".`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			peer, err := coold.LoadOperatorPeer(parent.OperatorFilePath())
+			if err != nil {
+				return err
+			}
+			if peer == nil {
+				return fmt.Errorf("not joined; run `coolify firewall operator join` first")
+			}
+			conf, err := peer.Config()
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprint(os.Stdout, conf)
+			return err
+		},
+	}
+}
+
+func newOperatorLeaveCommand(parent *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "leave",
+		Short: "Remove the operator peer from every server",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := parent.Validate(); err != nil {
+				return err
+			}
+			runner, err := parent.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer runner.Close()
+			return emitOperatorLeave(cmd.Context(), cmd, parent, runner)
+		},
+	}
+}
+
+// operatorHostProbe is what join needs to know about one host.
+type operatorHostProbe struct {
+	PublicKey  string
+	ListenPort int
+	MgmtIP     net.IP
+	Token      string
+}
+
+// probeOperatorHost reads the host's WireGuard identity and coold token.
+// A missing token is not an error: the token resolver falls back to SSH.
+func probeOperatorHost(ctx context.Context, runner ssh.Runner, flags *Flags, host string) (operatorHostProbe, error) {
+	script := fmt.Sprintf(
+		`cat /etc/wireguard/publickey && wg show %[1]s listen-port && `+
+			`ip -o addr show %[1]s scope global | awk '{print $4}' | head -n 1 | cut -d/ -f1; `+
+			`cat %[2]s 2>/dev/null || true`,
+		flags.WGInterface, coold.TokenPath)
+	stdout, stderr, err := runner.Run(ctx, host, flags.SSHUser, flags.SSHPort, script)
+	if err != nil {
+		return operatorHostProbe{}, fmt.Errorf("%w (stderr: %s)", err, strings.TrimSpace(stderr))
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) < 3 {
+		return operatorHostProbe{}, fmt.Errorf("%s is not a mesh host (no %s identity)", host, flags.WGInterface)
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return operatorHostProbe{}, fmt.Errorf("parse %s listen port %q: %w", flags.WGInterface, lines[1], err)
+	}
+	ip := net.ParseIP(strings.TrimSpace(lines[2]))
+	if ip == nil {
+		return operatorHostProbe{}, fmt.Errorf("%s has no address on %s", host, flags.WGInterface)
+	}
+	p := operatorHostProbe{PublicKey: strings.TrimSpace(lines[0]), ListenPort: port, MgmtIP: ip}
+	if len(lines) > 3 {
+		p.Token = strings.TrimSpace(lines[3])
+	}
+	return p, nil
+}
+
+// emitOperatorJoin is the core of `operator join`. Split from the cobra
+// wrapper so tests inject a fake ssh.Runner.
+func emitOperatorJoin(
+	ctx context.Context,
+	cmd *cobra.Command,
+	parent *Flags,
+	local *operatorJoinFlags,
+	runner ssh.Runner,
+) error {
+	probes := ssh.ForEachServer(ctx, parent.Servers, parent.Concurrency,
+		func(ctx context.Context, host string) (operatorHostProbe, error) {
+			return probeOperatorHost(ctx, runner, parent, host)
+		})
+	used := make([]net.IP, 0, len(probes))
+	for _, r := range probes {
+		if r.Err != nil {
+			return fmt.Errorf("probe %s: %w", r.Host, r.Err)
+		}
+		used = append(used, r.Result.MgmtIP)
+	}
+	// Hosts outside --servers (or unreachable) keep their recorded IPs.
+	ledger, err := wireguard.LoadLedger(parent.stateFile())
+	if err != nil {
+		return err
+	}
+	if ledger != nil {
+		for _, h := range ledger.Hosts {
+			if ip := net.ParseIP(h.MgmtIP); ip != nil {
+				used = append(used, ip)
+			}
+		}
+	}
+
+	peer, err := coold.LoadOperatorPeer(parent.OperatorFilePath())
+	if err != nil {
+		return err
+	}
+	if peer == nil {
+		priv, pub, err := wireguard.GenerateKeyPair()
+		if err != nil {
+			return err
+		}
+		peer = &coold.OperatorPeer{PrivateKey: priv, PublicKey: pub}
+	}
+	if peer.Hosts == nil {
+		peer.Hosts = map[string]coold.OperatorHost{}
+	}
+	switch {
+	case local.IP != "":
+		peer.MgmtIP = local.IP
+	case peer.MgmtIP == "":
+		_, pool, err := net.ParseCIDR(local.MgmtPool)
+		if err != nil {
+			return fmt.Errorf("invalid --mgmt-pool %q: %w", local.MgmtPool, err)
+		}
+		ip, err := wireguard.OperatorIP(pool, used)
+		if err != nil {
+			return err
+		}
+		peer.MgmtIP = ip.String()
+	}
+	ip := net.ParseIP(peer.MgmtIP)
+	if ip == nil {
+		return fmt.Errorf("invalid operator IP %q", peer.MgmtIP)
+	}
+	for _, r := range probes {
+		if r.Result.MgmtIP.Equal(ip) {
+			return fmt.Errorf("operator IP %s is %s's mgmt IP; pick another with --ip", ip, r.Host)
+		}
+	}
+	if ledger != nil {
+		for host, h := range ledger.Hosts {
+			if ip.Equal(net.ParseIP(h.MgmtIP)) {
+				return fmt.Errorf("operator IP %s is recorded as %s's mgmt IP; pick another with --ip", ip, host)
+			}
+		}
+	}
+
+	addCmd := wireguard.AddOperatorPeerCommand(parent.WGInterface, peer.PublicKey, ip)
+	results := ssh.ForEachServer(ctx, parent.Servers, parent.Concurrency,
+		func(ctx context.Context, host string) (struct{}, error) {
+			_, stderr, err := runner.Run(ctx, host, parent.SSHUser, parent.SSHPort, addCmd)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("%w (stderr: %s)", err, strings.TrimSpace(stderr))
+			}
+			return struct{}{}, nil
+		})
+
+	rows := make([]models.FirewallOperatorHostRow, 0, len(results))
+	var errs []string
+	for i, r := range results {
+		probe := probes[i].Result
+		row := models.FirewallOperatorHostRow{
+			Host: r.Host, MgmtIP: probe.MgmtIP.String(), PublicKey: probe.PublicKey, Status: "joined",
+		}
+		if r.Err != nil {
+			row.Status = "failed"
+			errs = append(errs, fmt.Sprintf("join %s: %v", r.Host, r.Err))
+		} else {
+			peer.Hosts[r.Host] = coold.OperatorHost{
+				MgmtIP: row.MgmtIP, PublicKey: probe.PublicKey, CooldToken: probe.Token,
+			}
+			peer.ListenPort = probe.ListenPort
+		}
+		rows = append(rows, row)
+	}
+	if err := peer.Save(parent.OperatorFilePath()); err != nil {
+		return err
+	}
+	if ledger == nil {
+		ledger = wireguard.NewLedger()
+	}
+	ledger.RecordOperator(&wireguard.LedgerOperator{MgmtIP: ip.String(), PublicKey: peer.PublicKey})
+	if err := ledger.Save(parent.stateFile()); err != nil {
+		return err
+	}
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "Warning:", e)
+	}
+	fmt.Fprintf(os.Stderr, "Operator peer %s. Bring the tunnel up with:\n"+
+		"  coolify firewall operator config | sudo tee /etc/wireguard/coolify.conf && sudo wg-quick up coolify\n",
+		peer.MgmtIP)
+
+	if err := formatOperator(cmd, peer, rows, errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d servers failed to join", len(errs), len(results))
+	}
+	return nil
+}
+
+// emitOperatorLeave removes the operator peer from --servers and forgets
+// them; the operator file goes once no host is left.
+func emitOperatorLeave(ctx context.Context, cmd *cobra.Command, parent *Flags, runner ssh.Runner) error {
+	peer, err := coold.LoadOperatorPeer(parent.OperatorFilePath())
+	if err != nil {
+		return err
+	}
+	if peer == nil {
+		return fmt.Errorf("not joined; nothing to leave")
+	}
+	rmCmd := wireguard.RemoveOperatorPeerCommand(parent.WGInterface, peer.PublicKey)
+	results := ssh.ForEachServer(ctx, parent.Servers, parent.Concurrency,
+		func(ctx context.Context, host string) (struct{}, error) {
+			_, stderr, err := runner.Run(ctx, host, parent.SSHUser, parent.SSHPort, rmCmd)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("%w (stderr: %s)", err, strings.TrimSpace(stderr))
+			}
+			return struct{}{}, nil
+		})
+
+	rows := make([]models.FirewallOperatorHostRow, 0, len(results))
+	var errs []string
+	for _, r := range results {
+		h := peer.Hosts[r.Host]
+		row := models.FirewallOperatorHostRow{
+			Host: r.Host, MgmtIP: h.MgmtIP, PublicKey: h.PublicKey, Status: "left",
+		}
+		if r.Err != nil {
+			row.Status = "failed"
+			errs = append(errs, fmt.Sprintf("leave %s: %v", r.Host, r.Err))
+		} else {
+			delete(peer.Hosts, r.Host)
+		}
+		rows = append(rows, row)
+	}
+	if len(peer.Hosts) == 0 {
+		if err := os.Remove(parent.OperatorFilePath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove operator peer: %w", err)
+		}
+		if err := forgetOperator(parent.stateFile()); err != nil {
+			return err
+		}
+	} else if err := peer.Save(parent.OperatorFilePath()); err != nil {
+		return err
+	}
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "Warning:", e)
+	}
+
+	if err := formatOperator(cmd, peer, rows, errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d servers failed to drop the operator peer", len(errs), len(results))
+	}
+	return nil
+}
+
+// forgetOperator releases the operator's mgmt IP in the mesh state ledger.
+func forgetOperator(path string) error {
+	ledger, err := wireguard.LoadLedger(path)
+	if err != nil || ledger == nil || ledger.Operator == nil {
+		return err
+	}
+	ledger.RecordOperator(nil)
+	return ledger.Save(path)
+}
+
+func formatOperator(
+	cmd *cobra.Command,
+	peer *coold.OperatorPeer,
+	rows []models.FirewallOperatorHostRow,
+	errs []string,
+) error {
+	format, _ := cmd.Root().PersistentFlags().GetString("format")
+	if format == "" {
+		format = output.FormatTable
+	}
+	formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+	if err != nil {
+		return err
+	}
+	if format == output.FormatJSON || format == output.FormatPretty {
+		return formatter.Format(models.FirewallOperatorOutput{
+			MgmtIP: peer.MgmtIP, PublicKey: peer.PublicKey, Hosts: rows, Errors: errs,
+		})
+	}
+	return formatter.Format(rows)
+}
@@ -0,0 +1,166 @@
+package firewall
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+func TestEmitOperatorJoin_RegistersAndRecordsHosts(t *testing.T) {
+	fr := &cmdFakeRunner{responses: map[string]string{
+		"cat /etc/wireguard/publickey": "aG9zdDE=\n51820\n100.64.0.1\nhost-token\n",
+	}}
+	parent := parentWithToken()
+	parent.OperatorFile = filepath.Join(t.TempDir(), "operator.json")
+	parent.StateFile = filepath.Join(t.TempDir(), "mesh-state.json")
+	inner := &cobra.Command{Use: "join"}
+	rootCmdFor(inner)
+
+	err := emitOperatorJoin(context.Background(), inner, parent,
+		&operatorJoinFlags{MgmtPool: "100.64.0.0/16"}, fr)
+	require.NoError(t, err)
+
+	peer, err := coold.LoadOperatorPeer(parent.OperatorFile)
+	require.NoError(t, err)
+	require.NotNil(t, peer)
+	assert.Equal(t, "100.64.255.254", peer.MgmtIP)
+	assert.Equal(t, 51820, peer.ListenPort)
+	assert.Equal(t, coold.OperatorHost{MgmtIP: "100.64.0.1", PublicKey: "aG9zdDE=", CooldToken: "host-token"},
+		peer.Hosts["h1"])
+	ledger, err := wireguard.LoadLedger(parent.StateFile)
+	require.NoError(t, err)
+	assert.Equal(t, &wireguard.LedgerOperator{MgmtIP: "100.64.255.254", PublicKey: peer.PublicKey}, ledger.Operator,
+		"the operator IP is reserved in the mesh state")
+
+	var registered bool
+	for _, c := range fr.calls {
+		if strings.Contains(c, "wg set wg0 peer "+peer.PublicKey+" allowed-ips 100.64.255.254/32") {
+			registered = true
+		}
+	}
+	assert.True(t, registered, "the operator key should be admitted on the host")
+
+	// Joining again keeps the identity.
+	require.NoError(t, emitOperatorJoin(context.Background(), inner, parent,
+		&operatorJoinFlags{MgmtPool: "100.64.0.0/16"}, fr))
+	again, err := coold.LoadOperatorPeer(parent.OperatorFile)
+	require.NoError(t, err)
+	assert.Equal(t, peer.PrivateKey, again.PrivateKey)
+	assert.Equal(t, peer.MgmtIP, again.MgmtIP)
+}
+
+func TestEmitOperatorJoin_RejectsHostIP(t *testing.T) {
+	fr := &cmdFakeRunner{responses: map[string]string{
+		"cat /etc/wireguard/publickey": "aG9zdDE=\n51820\n100.64.0.1\n",
+	}}
+	parent := parentWithToken()
+	parent.OperatorFile = filepath.Join(t.TempDir(), "operator.json")
+	parent.StateFile = filepath.Join(t.TempDir(), "mesh-state.json")
+	inner := &cobra.Command{Use: "join"}
+	rootCmdFor(inner)
+
+	err := emitOperatorJoin(context.Background(), inner, parent,
+		&operatorJoinFlags{MgmtPool: "100.64.0.0/16", IP: "100.64.0.1"}, fr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "h1's mgmt IP")
+}
+
+func TestEmitOperatorLeave_RemovesPeerAndFile(t *testing.T) {
+	fr := &cmdFakeRunner{responses: map[string]string{}}
+	parent := parentWithToken()
+	parent.OperatorFile = filepath.Join(t.TempDir(), "operator.json")
+	parent.StateFile = filepath.Join(t.TempDir(), "mesh-state.json")
+	require.NoError(t, (&coold.OperatorPeer{
+		MgmtIP: "100.64.255.254", PublicKey: "b3BlcmF0b3I=",
+		Hosts: map[string]coold.OperatorHost{"h1": {MgmtIP: "100.64.0.1"}},
+	}).Save(parent.OperatorFile))
+	ledger := wireguard.NewLedger()
+	ledger.RecordOperator(&wireguard.LedgerOperator{MgmtIP: "100.64.255.254", PublicKey: "b3BlcmF0b3I="})
+	require.NoError(t, ledger.Save(parent.StateFile))
+	inner := &cobra.Command{Use: "leave"}
+	rootCmdFor(inner)
+
+	require.NoError(t, emitOperatorLeave(context.Background(), inner, parent, fr))
+	require.Len(t, fr.calls, 1)
+	assert.Contains(t, fr.calls[0], "wg set wg0 peer b3BlcmF0b3I= remove")
+	peer, err := coold.LoadOperatorPeer(parent.OperatorFile)
+	require.NoError(t, err)
+	assert.Nil(t, peer, "leaving the last host forgets the operator peer")
+	ledger, err = wireguard.LoadLedger(parent.StateFile)
+	require.NoError(t, err)
+	assert.Nil(t, ledger.Operator, "and releases its mgmt IP")
+}
+
+// joinedParent points an operator peer for h1 at srv, which stands in for
+// coold on h1's mgmt IP.
+func joinedParent(t *testing.T, srv *httptest.Server, transport string) *Flags {
+	t.Helper()
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	parent := parentWithToken()
+	parent.CooldPort, _ = strconv.Atoi(port)
+	parent.Transport = transport
+	parent.OperatorFile = filepath.Join(t.TempDir(), "operator.json")
+	require.NoError(t, (&coold.OperatorPeer{
+		MgmtIP: "100.64.255.254", PublicKey: "b3BlcmF0b3I=",
+		Hosts: map[string]coold.OperatorHost{"h1": {MgmtIP: host}},
+	}).Save(parent.OperatorFile))
+	return parent
+}
+
+func TestEmitList_DirectTransportSkipsSSH(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`[{"namespace":"default","src":"10.0.0.1","dst":"10.0.0.2","proto":"tcp","port":80,"id":"abc123def456"}]`))
+	}))
+	defer srv.Close()
+	fr := &cmdFakeRunner{responses: map[string]string{}}
+	parent := joinedParent(t, srv, common.TransportDirect)
+	inner := &cobra.Command{Use: "list"}
+	rootCmdFor(inner)
+
+	require.NoError(t, emitList(context.Background(), inner, parent, fr))
+	assert.Empty(t, fr.calls, "a joined operator must not SSH")
+	assert.Equal(t, "Bearer test-token", gotAuth)
+}
+
+func TestEmitList_AutoFallsBackToSSH(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	parent := joinedParent(t, srv, common.TransportAuto)
+	srv.Close()
+	fr := &cmdFakeRunner{responses: map[string]string{"/api/v1/firewall/allow": `[]`}}
+	inner := &cobra.Command{Use: "list"}
+	rootCmdFor(inner)
+
+	require.NoError(t, emitList(context.Background(), inner, parent, fr))
+	require.Len(t, fr.calls, 1)
+	assert.Contains(t, fr.calls[0], "curl")
+}
+
+func TestCooldTransport_DirectNeedsJoin(t *testing.T) {
+	parent := parentWithToken()
+	parent.Transport = common.TransportDirect
+	parent.OperatorFile = filepath.Join(t.TempDir(), "operator.json")
+
+	_, err := cooldTransport(context.Background(), &cmdFakeRunner{}, parent)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "operator join")
+
+	parent.Transport = "carrier-pigeon"
+	_, err = cooldTransport(context.Background(), &cmdFakeRunner{}, parent)
+	assert.Error(t, err)
+}
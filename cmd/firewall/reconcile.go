@@ -0,0 +1,157 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// newReconcileCommand builds `coolify firewall reconcile`.
+func newReconcileCommand(parent *Flags) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Re-resolve name-based rules and rewrite the ones whose IPs drifted",
+		Long: `Re-resolve every rule added with "allow --by-name" through Corrosion's
+service_endpoints. A rule whose container came back with a new IP (or moved
+to another host) gets the new IP rule installed and the stale one removed;
+a rule missing from coold is reinstalled. Names that no longer resolve are
+reported and left alone.`,
+		Example: `  coolify firewall reconcile --servers 10.0.0.1,10.0.0.2 --dry-run`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := parent.Validate(); err != nil {
+				return err
+			}
+			runner, err := parent.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer runner.Close()
+			return emitReconcile(cmd.Context(), cmd, parent, runner, dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"Report drift without changing any rule")
+	return cmd
+}
+
+func emitReconcile(ctx context.Context, cmd *cobra.Command, parent *Flags, runner ssh.Runner, dryRun bool) error {
+	named, err := ifw.LoadNamedRules(parent.namesFile())
+	if err != nil {
+		return err
+	}
+	if len(named.Rules) == 0 {
+		fmt.Fprintln(os.Stderr, "No name-based rules. Add one with `coolify firewall allow --by-name ...`.")
+		return nil
+	}
+	eps, err := loadEndpoints(ctx, runner, parent)
+	if err != nil {
+		return err
+	}
+	hostFor := hostsByMgmtIP(ctx, runner, parent)
+	transport, err := cooldTransport(ctx, runner, parent)
+	if err != nil {
+		return err
+	}
+	installed, results := ifw.ListAllVia(ctx, transport, parent.Servers, parent.Concurrency, "")
+	for _, r := range results {
+		if r.Err != nil {
+			return fmt.Errorf("list rules on %s: %w", r.Host, r.Err)
+		}
+	}
+	have := map[string]bool{}
+	for _, r := range installed {
+		have[r.Host+"|"+ifw.RuleID(r)] = true
+	}
+
+	rows := make([]models.FirewallNamedRuleRow, 0, len(named.Rules))
+	failed, changed := 0, false
+	for i, b := range named.Rules {
+		row := models.FirewallNamedRuleRow{
+			Namespace: b.Namespace, From: b.SrcName, To: b.DstName,
+			Proto: b.Proto, Port: b.Port, Host: b.Host, RuleID: b.RuleID,
+		}
+		want, err := b.Resolve(eps, hostFor)
+		if err != nil {
+			row.Status = "unresolved: " + err.Error()
+			rows = append(rows, row)
+			continue
+		}
+		wantID := ifw.RuleID(want)
+		drifted := wantID != b.RuleID || want.Host != b.Host
+		switch {
+		case !drifted && have[want.Host+"|"+wantID]:
+			row.Status = "in sync"
+		case dryRun && drifted:
+			row.Status = "would update"
+		case dryRun:
+			row.Status = "would reinstall"
+		default:
+			row.Status = "updated"
+			if !drifted {
+				row.Status = "reinstalled"
+			}
+			if err := replaceNamedRule(ctx, transport, b, want, drifted); err != nil {
+				row.Status = "failed: " + err.Error()
+				failed++
+				break
+			}
+			named.Rules[i].RuleID, named.Rules[i].Host = wantID, want.Host
+			row.Host, row.RuleID = want.Host, wantID
+			changed = true
+		}
+		rows = append(rows, row)
+	}
+	if changed {
+		if err := named.Save(parent.namesFile()); err != nil {
+			return err
+		}
+	}
+
+	format, _ := cmd.Root().PersistentFlags().GetString("format")
+	if format == "" {
+		format = output.FormatTable
+	}
+	formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+	if err != nil {
+		return err
+	}
+	if format == output.FormatJSON || format == output.FormatPretty {
+		err = formatter.Format(models.FirewallReconcileOutput{DryRun: dryRun, Rules: rows})
+	} else {
+		err = formatter.Format(rows)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d name-based rules failed to reconcile", failed, len(named.Rules))
+	}
+	return nil
+}
+
+// replaceNamedRule installs want and, when the binding drifted, removes the
+// rule it used to point at. Install comes first so the flow is never left
+// without a rule.
+func replaceNamedRule(
+	ctx context.Context,
+	transport ifw.Transport,
+	b ifw.NamedRule,
+	want ifw.AllowRule,
+	drifted bool,
+) error {
+	if err := transport.Apply(ctx, want.Host, want); err != nil {
+		return err
+	}
+	if !drifted || b.RuleID == "" || b.Host == "" {
+		return nil
+	}
+	return transport.Revoke(ctx, b.Host, b.RuleID)
+}
@@ -111,3 +111,33 @@ func findHostForIP(ip net.IP, all []ifw.Container) (string, bool) {
 	}
 	return "", false
 }
+
+// resolveSelector expands a policy selector into the containers it matches.
+// Exact references go through resolveEndpoint ("host/name" is accepted as
+// the "host:name" form), so ambiguity is an error just like for `allow`.
+// Globs and label selectors may match any number of containers.
+func resolveSelector(sel ifw.Selector, all []ifw.Container) ([]ifw.Container, error) {
+	if !sel.IsExact() {
+		var out []ifw.Container
+		for _, c := range all {
+			if sel.Matches(c) {
+				out = append(out, c)
+			}
+		}
+		return out, nil
+	}
+	ref := sel.Ref
+	if host, name, ok := strings.Cut(ref, "/"); ok {
+		ref = host + ":" + name
+	}
+	c, err := resolveEndpoint(ref, all)
+	if err != nil {
+		return nil, err
+	}
+	if c.Host == "" {
+		if h, ok := findHostForIP(c.IP, all); ok {
+			c.Host = h
+		}
+	}
+	return []ifw.Container{c}, nil
+}
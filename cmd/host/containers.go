@@ -0,0 +1,75 @@
+package host
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+// namespaceLabel is the label `coolify init` and coold put on mesh objects.
+const namespaceLabel = "io.coolify.namespace"
+
+// containersFlags are the per-subcommand flags for `host containers`.
+type containersFlags struct {
+	Namespace string
+	State     string
+}
+
+// newContainersCommand builds `coolify host containers`.
+func newContainersCommand(flags *Flags) *cobra.Command {
+	local := &containersFlags{}
+	cmd := &cobra.Command{
+		Use:   "containers",
+		Short: "List every container on every server (podman ps -a)",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitContainers(cmd.Context(), cmd, flags, local, clients)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&local.Namespace, "namespace", "",
+		"Only containers labelled with this mesh namespace")
+	cmd.Flags().StringVar(&local.State, "state", "",
+		"Only containers in this state (running, exited, created, ...)")
+	return cmd
+}
+
+func emitContainers(
+	ctx context.Context,
+	cmd *cobra.Command,
+	flags *Flags,
+	local *containersFlags,
+	clients clientFactory,
+) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) ([]coold.Container, error) {
+		return c.HostContainers(ctx)
+	})
+	rows := []models.HostContainerRow{}
+	for _, r := range results {
+		for _, c := range r.Result {
+			ns := c.Labels[namespaceLabel]
+			if local.Namespace != "" && ns != local.Namespace {
+				continue
+			}
+			if local.State != "" && !strings.EqualFold(c.State, local.State) {
+				continue
+			}
+			rows = append(rows, models.HostContainerRow{
+				Host:      r.Host,
+				Namespace: ns,
+				ID:        shortID(c.ID),
+				Name:      strings.Join(c.Names, ","),
+				Image:     c.Image,
+				State:     c.State,
+				Status:    c.Status,
+			})
+		}
+	}
+	errs := hostErrors(results)
+	return emit(cmd, models.HostContainersOutput{Containers: rows, Errors: errs}, rows, len(rows),
+		"No containers found.")
+}
@@ -0,0 +1,59 @@
+package host
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+// newDNSCommand builds `coolify host dns` and its subcommands.
+func newDNSCommand(flags *Flags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dns",
+		Short: "Inspect coold's embedded resolver",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+	cmd.AddCommand(newDNSLookupCommand(flags))
+	return cmd
+}
+
+func newDNSLookupCommand(flags *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lookup <name>",
+		Short: "Show what each server's resolver answers for a name",
+		Long: `Show what coold's resolver on each server answers for <name>, e.g.
+"db.myapp" or "db.myapp.coolify.internal". Answers that differ between
+servers point at Corrosion replicas that have not converged.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitDNSLookup(cmd.Context(), cmd, flags, args[0], clients)
+			})
+		},
+	}
+}
+
+func emitDNSLookup(ctx context.Context, cmd *cobra.Command, flags *Flags, name string, clients clientFactory) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) (coold.DNSAnswer, error) {
+		return c.DNSLookup(ctx, name)
+	})
+	rows := make([]models.HostDNSRow, 0, len(results))
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		a := r.Result
+		rows = append(rows, models.HostDNSRow{
+			Host: r.Host, Name: a.Name, Records: strings.Join(a.Records, ","), TTL: a.TTL, Source: a.Source,
+		})
+	}
+	errs := hostErrors(results)
+	return emit(cmd, models.HostDNSOutput{Answers: rows, Errors: errs}, rows, len(rows),
+		"No server answered.")
+}
@@ -0,0 +1,135 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/coold"
+)
+
+// execFlags are the per-subcommand flags for `host exec`.
+type execFlags struct {
+	targetFlags
+	Interactive bool
+	TTY         bool
+}
+
+// newExecCommand builds `coolify host exec`.
+func newExecCommand(flags *Flags) *cobra.Command {
+	local := &execFlags{}
+	cmd := &cobra.Command{
+		Use:   "exec <container> [-- command...]",
+		Short: "Run a command in a mesh container",
+		Long: `Run a command (default: sh) in a container found by name or ID prefix on
+the mesh networks of --servers, through coold's exec endpoint.
+
+A terminal is allocated when stdin and stdout are terminals; --tty=false
+turns that off, e.g. to pipe binary output. The command's exit code is
+the exit code of coolify.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("tty") {
+				local.TTY = term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+			}
+			command := args[1:]
+			if len(command) == 0 {
+				command = []string{"sh"}
+			}
+			return runExec(cmd.Context(), flags, local, args[0], command)
+		},
+	}
+	bindTargetFlags(cmd, &local.targetFlags)
+	cmd.Flags().BoolVarP(&local.Interactive, "interactive", "i", true,
+		"Forward stdin to the command")
+	cmd.Flags().BoolVarP(&local.TTY, "tty", "t", false,
+		"Allocate a terminal (default: when stdin and stdout are terminals)")
+	return cmd
+}
+
+func runExec(ctx context.Context, flags *Flags, local *execFlags, ref string, command []string) error {
+	if err := flags.Validate(); err != nil {
+		return err
+	}
+	runner, err := flags.BuildSSHClient()
+	if err != nil {
+		return fmt.Errorf("SSH client: %w", err)
+	}
+	defer runner.Close()
+
+	target, err := resolveContainer(ctx, runner, flags, &local.targetFlags, ref)
+	if err != nil {
+		return err
+	}
+	clients, err := common.CooldClients(ctx, runner, &flags.SSHMeshFlags, &flags.CooldFlags)
+	if err != nil {
+		return err
+	}
+	c, err := clients(target.Host)
+	if err != nil {
+		return err
+	}
+
+	spec := coold.ExecSpec{Cmd: command, TTY: local.TTY}
+	outFd := int(os.Stdout.Fd())
+	if local.TTY {
+		if cols, rows, err := term.GetSize(outFd); err == nil {
+			spec.Cols, spec.Rows = uint16(cols), uint16(rows)
+		}
+	}
+	sess, err := c.Exec(ctx, target.ID, spec)
+	if err != nil {
+		return fmt.Errorf("exec in %s on %s: %w", target.Name, target.Host, err)
+	}
+	defer sess.Close()
+
+	if local.TTY {
+		inFd := int(os.Stdin.Fd())
+		if term.IsTerminal(inFd) {
+			state, err := term.MakeRaw(inFd)
+			if err != nil {
+				return fmt.Errorf("raw terminal: %w", err)
+			}
+			defer func() { _ = term.Restore(inFd, state) }()
+		}
+		stop := watchResize(outFd, func(cols, rows int) {
+			_ = sess.Resize(uint16(cols), uint16(rows))
+		})
+		defer stop()
+	}
+
+	code, err := pipeExec(sess, local.Interactive, os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return &common.ExitError{Code: code, Err: fmt.Errorf("command exited with code %d", code)}
+	}
+	return nil
+}
+
+// pipeExec forwards stdin (when interactive) and relays the output until
+// the command exits. It returns the command's exit code.
+func pipeExec(sess *coold.ExecSession, interactive bool, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	if interactive {
+		go func() {
+			_, _ = io.Copy(sess, stdin)
+			_ = sess.CloseStdin()
+		}()
+	} else if err := sess.CloseStdin(); err != nil {
+		return -1, err
+	}
+	code, err := sess.Wait(stdout, stderr)
+	if err != nil {
+		return -1, err
+	}
+	if code < 0 {
+		return -1, fmt.Errorf("connection to coold closed before the command exited")
+	}
+	return code, nil
+}
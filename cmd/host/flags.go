@@ -0,0 +1,53 @@
+// Package host implements the `coolify host` command tree: a typed client of
+// the coold agent's REST API (CONTROL_PLANE.md §2) that fans each call out
+// across --servers. It reaches coold like `coolify firewall` does, over an
+// SSH bounce or the operator peer's tunnel.
+package host
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/coold"
+)
+
+// Flags is the shared flag set for every `coolify host` subcommand: SSH
+// plumbing and the coold endpoint/token/transport.
+type Flags struct {
+	common.SSHMeshFlags
+	common.CooldFlags
+}
+
+// bindFlags registers the persistent flags on the parent command.
+func bindFlags(cmd *cobra.Command, f *Flags) {
+	common.BindSSHMeshFlags(cmd, &f.SSHMeshFlags)
+	common.BindCooldFlags(cmd, &f.CooldFlags)
+}
+
+// clientFactory returns the coold client for one host.
+type clientFactory func(host string) (*coold.Client, error)
+
+// withClients validates the flags, opens the SSH client and hands emit a
+// per-host coold client factory on the route selected by --transport.
+func withClients(
+	ctx context.Context,
+	flags *Flags,
+	emit func(clients clientFactory) error,
+) error {
+	if err := flags.Validate(); err != nil {
+		return err
+	}
+	runner, err := flags.BuildSSHClient()
+	if err != nil {
+		return fmt.Errorf("SSH client: %w", err)
+	}
+	defer runner.Close()
+	clients, err := common.CooldClients(ctx, runner, &flags.SSHMeshFlags, &flags.CooldFlags)
+	if err != nil {
+		return err
+	}
+	return emit(clients)
+}
@@ -0,0 +1,89 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// fanOut calls fn with each host's coold client, in parallel.
+func fanOut[T any](
+	ctx context.Context,
+	flags *Flags,
+	clients clientFactory,
+	fn func(ctx context.Context, c *coold.Client) (T, error),
+) []ssh.ServerResult[T] {
+	return ssh.ForEachServer(ctx, flags.Servers, flags.Concurrency,
+		func(ctx context.Context, host string) (T, error) {
+			c, err := clients(host)
+			if err != nil {
+				var zero T
+				return zero, err
+			}
+			return fn(ctx, c)
+		})
+}
+
+// hostErrors prints a warning per failed host and returns the messages for
+// the JSON output.
+func hostErrors[T any](results []ssh.ServerResult[T]) []string {
+	var errs []string
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Host, r.Err))
+		}
+	}
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "Warning:", e)
+	}
+	return errs
+}
+
+// emit prints full for --format json/pretty and rows otherwise. empty is
+// printed to stderr instead of an empty table.
+func emit(cmd *cobra.Command, full, rows any, n int, empty string) error {
+	format, _ := cmd.Root().PersistentFlags().GetString("format")
+	if format == "" {
+		format = output.FormatTable
+	}
+	formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+	if err != nil {
+		return err
+	}
+	if format == output.FormatJSON || format == output.FormatPretty {
+		return formatter.Format(full)
+	}
+	if n == 0 {
+		fmt.Fprintln(os.Stderr, empty)
+		return nil
+	}
+	return formatter.Format(rows)
+}
+
+// humanBytes renders n in binary units, e.g. 1.5GiB.
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// shortID trims a container or image id to podman's 12-character form.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,56 @@
+package host
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewHostCommand creates the parent `coolify host` command.
+// The command tree is kept for tests and future v5 work but is not registered
+// on the public root CLI.
+// On bare invocation (no subcommand) it prints help.
+func NewHostCommand() *cobra.Command {
+	flags := &Flags{}
+
+	cmd := &cobra.Command{
+		Use:   "host",
+		Short: "[ALPHA] Inspect and drive the coold agent on mesh hosts (Coolify v5)",
+		Long: `[ALPHA] Call the coold agent's REST API on every server in --servers.
+coold is reached the same way as by "coolify firewall": SSH-bounced by
+default, or directly over the tunnel once "coolify firewall operator join"
+made this machine a mesh peer (see --transport).
+
+Subcommands:
+  info        Host facts: OS, kernel, podman and coold versions, WireGuard peers.
+  containers  Every container on each host (podman ps -a).
+  stats       CPU, memory and network snapshot of the running containers.
+  images      List, pull and remove images.
+  volumes     Show which hosts hold the named volumes.
+  networks    List podman networks.
+  dns         Ask coold's resolver what it would answer.
+  logs        Print or follow a container's logs, found by name on the mesh.
+  exec        Run a command in a container, with a terminal when interactive.
+
+logs and exec stream through coold too; over SSH the stream is tunnelled to
+coold's mgmt IP instead of bounced through curl.
+
+Hosts that fail are reported as warnings; the other hosts' results are still
+printed.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+
+	bindFlags(cmd, flags)
+
+	cmd.AddCommand(newInfoCommand(flags))
+	cmd.AddCommand(newContainersCommand(flags))
+	cmd.AddCommand(newStatsCommand(flags))
+	cmd.AddCommand(newImagesCommand(flags))
+	cmd.AddCommand(newVolumesCommand(flags))
+	cmd.AddCommand(newNetworksCommand(flags))
+	cmd.AddCommand(newDNSCommand(flags))
+	cmd.AddCommand(newLogsCommand(flags))
+	cmd.AddCommand(newExecCommand(flags))
+
+	return cmd
+}
@@ -0,0 +1,223 @@
+package host
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+func TestNewHostCommand_Subcommands(t *testing.T) {
+	cmd := NewHostCommand()
+	assert.Equal(t, "host", cmd.Use)
+	subs := map[string]*cobra.Command{}
+	for _, s := range cmd.Commands() {
+		subs[s.Name()] = s
+	}
+	for _, name := range []string{"info", "containers", "stats", "images", "volumes", "networks", "dns", "logs", "exec"} {
+		assert.Contains(t, subs, name)
+	}
+	var images []string
+	for _, s := range subs["images"].Commands() {
+		images = append(images, s.Name())
+	}
+	assert.ElementsMatch(t, []string{"list", "pull", "rm"}, images)
+	require.Len(t, subs["dns"].Commands(), 1)
+	assert.Equal(t, "lookup", subs["dns"].Commands()[0].Name())
+
+	pf := cmd.PersistentFlags()
+	for _, name := range []string{"servers", "ssh-key", "ssh-user", "ssh-port", "concurrency",
+		"coold-token", "coold-port", "wg-interface", "transport", "operator-file"} {
+		assert.NotNil(t, pf.Lookup(name), "missing --%s", name)
+	}
+}
+
+// fakeCoold serves canned bodies per host: each test server stands in for
+// one host's coold and answers "METHOD /path" from routes.
+func fakeCoold(t *testing.T, routes map[string]string) *coold.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.Method+" "+strings.TrimPrefix(r.URL.RequestURI(), coold.APIBasePath)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return coold.New(coold.NewHTTPDoer(srv.URL, "tok"))
+}
+
+// testFlags targets hosts h1 and h2.
+func testFlags() *Flags {
+	return &Flags{SSHMeshFlags: common.SSHMeshFlags{
+		Servers: []string{"h1", "h2"}, SSHUser: "root", SSHPort: 22, Concurrency: 2,
+	}}
+}
+
+// runJSON runs emit under a root command with --format json and decodes
+// what it printed into out.
+func runJSON(t *testing.T, use string, emit func(cmd *cobra.Command) error, out any) error {
+	t.Helper()
+	inner := &cobra.Command{Use: use}
+	root := &cobra.Command{Use: "coolify"}
+	root.PersistentFlags().String("format", "json", "")
+	root.AddCommand(inner)
+
+	read, write, err := os.Pipe()
+	require.NoError(t, err)
+	originalStdout := os.Stdout
+	os.Stdout = write
+	t.Cleanup(func() { os.Stdout = originalStdout })
+
+	runErr := emit(inner)
+	require.NoError(t, write.Close())
+	os.Stdout = originalStdout
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, read)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(buf.Bytes(), out), buf.String())
+	return runErr
+}
+
+func TestEmitInfo_ReportsFailedHosts(t *testing.T) {
+	h1 := fakeCoold(t, map[string]string{
+		"GET /host/info": `{"hostname":"node-1","cpus":4,"mem_total":8589934592,"load":[0.5,0.25,0.1],` +
+			`"podman_version":"5.2.0","wireguard":{"peers":3,"peers_up":2}}`,
+	})
+	h2 := fakeCoold(t, map[string]string{})
+	clients := func(host string) (*coold.Client, error) {
+		if host == "h1" {
+			return h1, nil
+		}
+		return h2, nil
+	}
+
+	var out models.HostInfoOutput
+	err := runJSON(t, "info", func(cmd *cobra.Command) error {
+		return emitInfo(context.Background(), cmd, testFlags(), clients)
+	}, &out)
+	require.NoError(t, err)
+	require.Len(t, out.Hosts, 1)
+	assert.Equal(t, models.HostInfoRow{
+		Host: "h1", Name: "node-1", CPUs: 4, Memory: "8.0GiB", Load: "0.50 0.25 0.10",
+		Podman: "5.2.0", WGPeers: "2/3",
+	}, out.Hosts[0])
+	require.Len(t, out.Errors, 1)
+	assert.Contains(t, out.Errors[0], "h2: coold GET /api/v1/host/info: HTTP 404")
+}
+
+func TestEmitContainers_FiltersByNamespaceAndState(t *testing.T) {
+	c := fakeCoold(t, map[string]string{
+		"GET /host/containers": `[
+			{"Id":"0123456789abcdef","Names":["web"],"Image":"nginx","State":"running","Labels":{"io.coolify.namespace":"alpha"}},
+			{"Id":"fedcba9876543210","Names":["job"],"Image":"busybox","State":"exited","Labels":{"io.coolify.namespace":"alpha"}},
+			{"Id":"aaaaaaaaaaaaaaaa","Names":["db"],"Image":"postgres","State":"running","Labels":{"io.coolify.namespace":"beta"}}]`,
+	})
+	clients := func(string) (*coold.Client, error) { return c, nil }
+	flags := testFlags()
+	flags.Servers = []string{"h1"}
+
+	var out models.HostContainersOutput
+	err := runJSON(t, "containers", func(cmd *cobra.Command) error {
+		return emitContainers(context.Background(), cmd, flags,
+			&containersFlags{Namespace: "alpha", State: "Running"}, clients)
+	}, &out)
+	require.NoError(t, err)
+	require.Len(t, out.Containers, 1)
+	assert.Equal(t, "0123456789ab", out.Containers[0].ID)
+	assert.Equal(t, "web", out.Containers[0].Name)
+}
+
+func TestEmitImagesRemove_MissingImageIsNotAnError(t *testing.T) {
+	h1 := fakeCoold(t, map[string]string{"DELETE /images/nginx:1": ``})
+	h2 := fakeCoold(t, map[string]string{})
+	clients := func(host string) (*coold.Client, error) {
+		if host == "h1" {
+			return h1, nil
+		}
+		return h2, nil
+	}
+
+	var out models.HostImagesOutput
+	err := runJSON(t, "rm", func(cmd *cobra.Command) error {
+		return emitImagesRemove(context.Background(), cmd, testFlags(), "nginx:1", clients)
+	}, &out)
+	require.NoError(t, err)
+	require.Len(t, out.Images, 2)
+	status := map[string]string{}
+	for _, r := range out.Images {
+		status[r.Host] = r.Status
+	}
+	assert.Equal(t, map[string]string{"h1": "removed", "h2": "not present"}, status)
+}
+
+func TestEmitImagesPull_FailsWhenAHostFails(t *testing.T) {
+	h1 := fakeCoold(t, map[string]string{"POST /images/pull": `{"digest":"sha256:aa"}`})
+	clients := func(host string) (*coold.Client, error) {
+		if host == "h1" {
+			return h1, nil
+		}
+		return nil, assert.AnError
+	}
+
+	var out models.HostImagesOutput
+	err := runJSON(t, "pull", func(cmd *cobra.Command) error {
+		return emitImagesPull(context.Background(), cmd, testFlags(), "nginx:1", nil, clients)
+	}, &out)
+	assert.ErrorContains(t, err, "pull failed on 1 of 2 servers")
+	require.Len(t, out.Images, 1)
+	assert.Equal(t, "sha256:aa", out.Images[0].Digest)
+}
+
+func TestEmitVolumes_SkipsHostsWithoutTheVolume(t *testing.T) {
+	h1 := fakeCoold(t, map[string]string{"GET /volumes/data": `{"name":"data","driver":"local","mountpoint":"/v/data"}`})
+	h2 := fakeCoold(t, map[string]string{})
+	clients := func(host string) (*coold.Client, error) {
+		if host == "h1" {
+			return h1, nil
+		}
+		return h2, nil
+	}
+
+	var out models.HostVolumesOutput
+	err := runJSON(t, "volumes", func(cmd *cobra.Command) error {
+		return emitVolumes(context.Background(), cmd, testFlags(), []string{"data"}, clients)
+	}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, []models.HostVolumeRow{{Host: "h1", Name: "data", Driver: "local", Mountpoint: "/v/data"}}, out.Volumes)
+	assert.Empty(t, out.Errors)
+}
+
+func TestRegistryAuth(t *testing.T) {
+	auth, err := registryAuth(&imagesPullFlags{})
+	require.NoError(t, err)
+	assert.Nil(t, auth)
+
+	_, err = registryAuth(&imagesPullFlags{RegistryPasswordStdin: true})
+	assert.Error(t, err)
+
+	t.Setenv("COOLIFY_REGISTRY_PASSWORD", "s3cret")
+	auth, err = registryAuth(&imagesPullFlags{RegistryUser: "bot"})
+	require.NoError(t, err)
+	assert.Equal(t, &coold.RegistryAuth{Username: "bot", Password: "s3cret"}, auth)
+}
+
+func TestHumanBytes(t *testing.T) {
+	assert.Equal(t, "512B", humanBytes(512))
+	assert.Equal(t, "1.5KiB", humanBytes(1536))
+	assert.Equal(t, "2.0GiB", humanBytes(2<<30))
+}
@@ -0,0 +1,182 @@
+package host
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+// newImagesCommand builds `coolify host images` and its subcommands.
+func newImagesCommand(flags *Flags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "List, pull and remove images on every server",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+	cmd.AddCommand(newImagesListCommand(flags))
+	cmd.AddCommand(newImagesPullCommand(flags))
+	cmd.AddCommand(newImagesRemoveCommand(flags))
+	return cmd
+}
+
+func newImagesListCommand(flags *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the images present on every server",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitImagesList(cmd.Context(), cmd, flags, clients)
+			})
+		},
+	}
+}
+
+func emitImagesList(ctx context.Context, cmd *cobra.Command, flags *Flags, clients clientFactory) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) ([]coold.Image, error) {
+		return c.ListImages(ctx)
+	})
+	rows := []models.HostImageRow{}
+	for _, r := range results {
+		for _, img := range r.Result {
+			rows = append(rows, models.HostImageRow{
+				Host:   r.Host,
+				Ref:    img.Ref,
+				Digest: img.Digest,
+				Size:   humanBytes(uint64(max(img.Size, 0))),
+			})
+		}
+	}
+	errs := hostErrors(results)
+	return emit(cmd, models.HostImagesOutput{Images: rows, Errors: errs}, rows, len(rows),
+		"No images found.")
+}
+
+// imagesPullFlags are the per-subcommand flags for `host images pull`.
+type imagesPullFlags struct {
+	RegistryUser          string
+	RegistryPasswordStdin bool
+}
+
+func newImagesPullCommand(flags *Flags) *cobra.Command {
+	local := &imagesPullFlags{}
+	cmd := &cobra.Command{
+		Use:   "pull <ref>",
+		Short: "Pull an image on every server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			auth, err := registryAuth(local)
+			if err != nil {
+				return err
+			}
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitImagesPull(cmd.Context(), cmd, flags, args[0], auth, clients)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&local.RegistryUser, "registry-user", "",
+		"Registry username for private images (password from COOLIFY_REGISTRY_PASSWORD or --registry-password-stdin)")
+	cmd.Flags().BoolVar(&local.RegistryPasswordStdin, "registry-password-stdin", false,
+		"Read the registry password from stdin")
+	return cmd
+}
+
+// registryAuth returns the credentials for `images pull`, or nil when no
+// --registry-user was given.
+func registryAuth(local *imagesPullFlags) (*coold.RegistryAuth, error) {
+	if local.RegistryUser == "" {
+		if local.RegistryPasswordStdin {
+			return nil, fmt.Errorf("--registry-password-stdin needs --registry-user")
+		}
+		return nil, nil
+	}
+	password := os.Getenv("COOLIFY_REGISTRY_PASSWORD")
+	if local.RegistryPasswordStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("read registry password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return nil, fmt.Errorf("--registry-user needs a password (COOLIFY_REGISTRY_PASSWORD or --registry-password-stdin)")
+	}
+	return &coold.RegistryAuth{Username: local.RegistryUser, Password: password}, nil
+}
+
+func emitImagesPull(
+	ctx context.Context,
+	cmd *cobra.Command,
+	flags *Flags,
+	ref string,
+	auth *coold.RegistryAuth,
+	clients clientFactory,
+) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) (string, error) {
+		return c.PullImage(ctx, ref, auth)
+	})
+	rows := make([]models.HostImageRow, 0, len(results))
+	for _, r := range results {
+		if r.Err == nil {
+			rows = append(rows, models.HostImageRow{Host: r.Host, Ref: ref, Digest: r.Result, Status: "pulled"})
+		}
+	}
+	errs := hostErrors(results)
+	if err := emit(cmd, models.HostImagesOutput{Images: rows, Errors: errs}, rows, len(rows),
+		"No server pulled the image."); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("pull failed on %d of %d servers", len(errs), len(results))
+	}
+	return nil
+}
+
+func newImagesRemoveCommand(flags *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm <ref>",
+		Short: "Remove an image from every server that has it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitImagesRemove(cmd.Context(), cmd, flags, args[0], clients)
+			})
+		},
+	}
+}
+
+func emitImagesRemove(ctx context.Context, cmd *cobra.Command, flags *Flags, ref string, clients clientFactory) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) (string, error) {
+		err := c.RemoveImage(ctx, ref)
+		if coold.IsNotFound(err) {
+			return "not present", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return "removed", nil
+	})
+	rows := make([]models.HostImageRow, 0, len(results))
+	for _, r := range results {
+		if r.Err == nil {
+			rows = append(rows, models.HostImageRow{Host: r.Host, Ref: ref, Status: r.Result})
+		}
+	}
+	errs := hostErrors(results)
+	if err := emit(cmd, models.HostImagesOutput{Images: rows, Errors: errs}, rows, len(rows),
+		"No server answered."); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("remove failed on %d of %d servers", len(errs), len(results))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package host
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+// newInfoCommand builds `coolify host info`.
+func newInfoCommand(flags *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "info",
+		Short: "Show host facts reported by coold on every server",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitInfo(cmd.Context(), cmd, flags, clients)
+			})
+		},
+	}
+}
+
+// emitInfo is factored out so tests can pass clients for a fake coold.
+func emitInfo(ctx context.Context, cmd *cobra.Command, flags *Flags, clients clientFactory) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) (coold.HostInfo, error) {
+		return c.HostInfo(ctx)
+	})
+	rows := make([]models.HostInfoRow, 0, len(results))
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		i := r.Result
+		rows = append(rows, models.HostInfoRow{
+			Host:    r.Host,
+			Name:    i.Hostname,
+			OS:      i.OS,
+			Kernel:  i.Kernel,
+			CPUs:    i.CPUs,
+			Memory:  humanBytes(i.MemTotal),
+			Load:    fmt.Sprintf("%.2f %.2f %.2f", i.Load[0], i.Load[1], i.Load[2]),
+			Podman:  i.PodmanVersion,
+			Coold:   i.CooldVersion,
+			WGPeers: fmt.Sprintf("%d/%d", i.WireGuard.PeersUp, i.WireGuard.Peers),
+		})
+	}
+	errs := hostErrors(results)
+	return emit(cmd, models.HostInfoOutput{Hosts: rows, Errors: errs}, rows, len(rows),
+		"No host answered.")
+}
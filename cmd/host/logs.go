@@ -0,0 +1,92 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/coold"
+)
+
+// logsFlags are the per-subcommand flags for `host logs`.
+type logsFlags struct {
+	targetFlags
+	Lines      int
+	Follow     bool
+	Since      string
+	Timestamps bool
+}
+
+// newLogsCommand builds `coolify host logs`.
+func newLogsCommand(flags *Flags) *cobra.Command {
+	local := &logsFlags{}
+	cmd := &cobra.Command{
+		Use:   "logs <container>",
+		Short: "Print or follow the logs of a mesh container",
+		Long: `Print the logs of a container found by name or ID prefix on the mesh
+networks of --servers, streamed from coold. With --follow the stream stays
+open until the container stops or Ctrl-C.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLogs(cmd.Context(), flags, local, args[0])
+		},
+	}
+	bindTargetFlags(cmd, &local.targetFlags)
+	cmd.Flags().IntVarP(&local.Lines, "lines", "n", 100, "Number of log lines to retrieve (0 for all)")
+	cmd.Flags().BoolVarP(&local.Follow, "follow", "f", false, "Follow log output (like tail -f)")
+	cmd.Flags().StringVar(&local.Since, "since", "", "Only lines since this time or duration (e.g. 10m, 2024-01-02T15:04:05Z)")
+	cmd.Flags().BoolVar(&local.Timestamps, "show-timestamps", false, "Show timestamps in log output")
+	return cmd
+}
+
+func runLogs(ctx context.Context, flags *Flags, local *logsFlags, ref string) error {
+	if err := flags.Validate(); err != nil {
+		return err
+	}
+	runner, err := flags.BuildSSHClient()
+	if err != nil {
+		return fmt.Errorf("SSH client: %w", err)
+	}
+	defer runner.Close()
+
+	target, err := resolveContainer(ctx, runner, flags, &local.targetFlags, ref)
+	if err != nil {
+		return err
+	}
+	clients, err := common.CooldClients(ctx, runner, &flags.SSHMeshFlags, &flags.CooldFlags)
+	if err != nil {
+		return err
+	}
+	c, err := clients(target.Host)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return streamLogs(ctx, c, target.ID, local, os.Stdout, os.Stderr)
+}
+
+// streamLogs copies container id's logs to stdout/stderr. Interrupting a
+// follow is not an error.
+func streamLogs(ctx context.Context, c *coold.Client, id string, local *logsFlags, stdout, stderr io.Writer) error {
+	rc, err := c.Logs(ctx, id, coold.LogsOptions{
+		Follow:     local.Follow,
+		Tail:       local.Lines,
+		Since:      local.Since,
+		Timestamps: local.Timestamps,
+	})
+	if err != nil {
+		return fmt.Errorf("logs of %s: %w", id, err)
+	}
+	defer rc.Close()
+	if _, err := coold.Demux(rc, stdout, stderr); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("logs of %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package host
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+)
+
+// frame renders one coold stream frame.
+func frame(typ byte, payload string) []byte {
+	hdr := make([]byte, 8)
+	hdr[0] = typ
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	return append(hdr, payload...)
+}
+
+func TestStreamLogs(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		_, _ = w.Write(frame(coold.FrameStdout, "hello\n"))
+		_, _ = w.Write(frame(coold.FrameStderr, "oops\n"))
+	}))
+	defer srv.Close()
+	c := coold.New(coold.NewHTTPDoer(srv.URL, "tok"))
+
+	var stdout, stderr bytes.Buffer
+	err := streamLogs(context.Background(), c, "abc", &logsFlags{Lines: 5, Since: "10m"}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", stdout.String())
+	assert.Equal(t, "oops\n", stderr.String())
+	assert.Contains(t, query, "tail=5")
+	assert.Contains(t, query, "since=10m")
+}
+
+func TestStreamLogs_InterruptedFollowIsNotAnError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(frame(coold.FrameStdout, "line\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c := coold.New(coold.NewHTTPDoer(srv.URL, "tok"))
+
+	// Ctrl-C after the first line.
+	var stdout bytes.Buffer
+	out := writerFunc(func(p []byte) (int, error) {
+		defer cancel()
+		return stdout.Write(p)
+	})
+	err := streamLogs(ctx, c, "abc", &logsFlags{Follow: true}, out, out)
+	require.NoError(t, err)
+	assert.Equal(t, "line\n", stdout.String())
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,41 @@
+package host
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+// newNetworksCommand builds `coolify host networks`.
+func newNetworksCommand(flags *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "networks",
+		Short: "List podman networks on every server",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitNetworks(cmd.Context(), cmd, flags, clients)
+			})
+		},
+	}
+}
+
+func emitNetworks(ctx context.Context, cmd *cobra.Command, flags *Flags, clients clientFactory) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) ([]coold.Network, error) {
+		return c.ListNetworks(ctx)
+	})
+	rows := []models.HostNetworkRow{}
+	for _, r := range results {
+		for _, n := range r.Result {
+			rows = append(rows, models.HostNetworkRow{
+				Host: r.Host, Name: n.Name, Driver: n.Driver, Subnets: strings.Join(n.Subnets, ","),
+			})
+		}
+	}
+	errs := hostErrors(results)
+	return emit(cmd, models.HostNetworksOutput{Networks: rows, Errors: errs}, rows, len(rows),
+		"No networks found.")
+}
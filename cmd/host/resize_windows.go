@@ -0,0 +1,33 @@
+//go:build windows
+
+package host
+
+import (
+	"time"
+
+	"golang.org/x/term"
+)
+
+// watchResize polls the size of console fd, as Windows has no SIGWINCH, and
+// calls fn when it changes until stop is called.
+func watchResize(fd int, fn func(cols, rows int)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		lastCols, lastRows, _ := term.GetSize(fd)
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cols, rows, err := term.GetSize(fd)
+				if err == nil && (cols != lastCols || rows != lastRows) {
+					lastCols, lastRows = cols, rows
+					fn(cols, rows)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,98 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	ifw "github.com/coollabsio/coolify-cli/internal/firewall"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// targetFlags narrow down the container `exec` and `logs` act on.
+type targetFlags struct {
+	Namespace string
+	Host      string
+}
+
+func bindTargetFlags(cmd *cobra.Command, t *targetFlags) {
+	cmd.Flags().StringVar(&t.Namespace, "namespace", "",
+		"Mesh namespace to look for the container in (default: every namespace on the servers)")
+	cmd.Flags().StringVar(&t.Host, "host", "",
+		"Server the container runs on, when the name exists on several (must be one of --servers)")
+}
+
+// resolveContainer finds ref — a container name or an ID prefix — on the
+// mesh networks of every server, as `firewall containers --all-namespaces`
+// sees them. Exactly one container must match.
+func resolveContainer(
+	ctx context.Context,
+	runner ssh.Runner,
+	flags *Flags,
+	t *targetFlags,
+	ref string,
+) (ifw.Container, error) {
+	hosts := flags.Servers
+	if t.Host != "" {
+		if !slices.Contains(flags.Servers, t.Host) {
+			return ifw.Container{}, fmt.Errorf("--host %s is not one of --servers", t.Host)
+		}
+		hosts = []string{t.Host}
+	}
+	namespaces := []string{t.Namespace}
+	var failed []string
+	if t.Namespace == "" {
+		var nsResults []ssh.ServerResult[[]string]
+		namespaces, nsResults = ifw.DiscoverNamespaces(ctx, runner, hosts, flags.SSHUser,
+			flags.SSHPort, flags.Concurrency, common.DefaultNamespace)
+		failed = failedHosts(nsResults)
+	}
+	all, results := ifw.DiscoverAllNamespaces(ctx, runner, hosts, flags.SSHUser,
+		flags.SSHPort, namespaces, common.PodmanNetworkFor, flags.Concurrency)
+	failed = append(failed, failedHosts(results)...)
+
+	var matches []ifw.Container
+	for _, c := range all {
+		if c.Name == ref {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 && len(ref) >= 3 {
+		for _, c := range all {
+			if strings.HasPrefix(c.ID, ref) {
+				matches = append(matches, c)
+			}
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		msg := fmt.Sprintf("no container %q on the mesh", ref)
+		if len(failed) > 0 {
+			slices.Sort(failed)
+			msg += fmt.Sprintf(" (unreachable: %s)", strings.Join(slices.Compact(failed), ", "))
+		}
+		return ifw.Container{}, fmt.Errorf("%s", msg)
+	}
+	where := make([]string, 0, len(matches))
+	for _, c := range matches {
+		where = append(where, c.Host+"/"+c.Namespace+"/"+c.Name)
+	}
+	return ifw.Container{}, fmt.Errorf("container %q is ambiguous (%s); pass --host or --namespace",
+		ref, strings.Join(where, ", "))
+}
+
+func failedHosts[T any](results []ssh.ServerResult[T]) []string {
+	var out []string
+	for _, r := range results {
+		if r.Err != nil {
+			out = append(out, r.Host)
+		}
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// meshRunner answers discovery scripts per host: the namespace listing
+// gets "default", the container listing gets containers[host]. Hosts in
+// down fail.
+type meshRunner struct {
+	containers map[string]string
+	down       map[string]bool
+}
+
+func (m *meshRunner) Run(_ context.Context, host, _ string, _ int, cmd string) (string, string, error) {
+	if m.down[host] {
+		return "", "", errors.New("connection refused")
+	}
+	if strings.Contains(cmd, "podman network ls") {
+		return "default\n", "", nil
+	}
+	return m.containers[host], "", nil
+}
+
+var _ ssh.Runner = (*meshRunner)(nil)
+
+func TestResolveContainer(t *testing.T) {
+	runner := &meshRunner{containers: map[string]string{
+		"h1": "aaaaaaaaaaaa|web|10.210.0.2|\nbbbbbbbbbbbb|db|10.210.0.3|\n",
+		"h2": "cccccccccccc|web|10.210.1.2|\n",
+	}}
+	flags := testFlags()
+	ctx := context.Background()
+
+	c, err := resolveContainer(ctx, runner, flags, &targetFlags{}, "db")
+	require.NoError(t, err)
+	assert.Equal(t, "h1", c.Host)
+	assert.Equal(t, "bbbbbbbbbbbb", c.ID)
+
+	c, err = resolveContainer(ctx, runner, flags, &targetFlags{}, "ccc")
+	require.NoError(t, err)
+	assert.Equal(t, "h2", c.Host, "ID prefix")
+
+	_, err = resolveContainer(ctx, runner, flags, &targetFlags{}, "web")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "h1/default/web, h2/default/web")
+
+	c, err = resolveContainer(ctx, runner, flags, &targetFlags{Host: "h2"}, "web")
+	require.NoError(t, err)
+	assert.Equal(t, "cccccccccccc", c.ID)
+
+	_, err = resolveContainer(ctx, runner, flags, &targetFlags{Host: "h9"}, "web")
+	assert.ErrorContains(t, err, "not one of --servers")
+}
+
+func TestResolveContainer_NotFoundNamesUnreachableHosts(t *testing.T) {
+	runner := &meshRunner{
+		containers: map[string]string{"h1": "aaaaaaaaaaaa|web|10.210.0.2|\n"},
+		down:       map[string]bool{"h2": true},
+	}
+	_, err := resolveContainer(context.Background(), runner, testFlags(), &targetFlags{}, "db")
+	require.Error(t, err)
+	assert.Equal(t, `no container "db" on the mesh (unreachable: h2)`, err.Error())
+}
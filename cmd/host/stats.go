@@ -0,0 +1,50 @@
+package host
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+// newStatsCommand builds `coolify host stats`.
+func newStatsCommand(flags *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show a resource snapshot of running containers on every server",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitStats(cmd.Context(), cmd, flags, clients)
+			})
+		},
+	}
+}
+
+func emitStats(ctx context.Context, cmd *cobra.Command, flags *Flags, clients clientFactory) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) ([]coold.ContainerStats, error) {
+		return c.HostStats(ctx)
+	})
+	rows := []models.HostStatsRow{}
+	for _, r := range results {
+		for _, s := range r.Result {
+			mem := humanBytes(s.MemUsage)
+			if s.MemLimit > 0 {
+				mem += " / " + humanBytes(s.MemLimit)
+			}
+			rows = append(rows, models.HostStatsRow{
+				Host:   r.Host,
+				Name:   s.Name,
+				CPU:    fmt.Sprintf("%.1f%%", s.CPUPercent),
+				Memory: mem,
+				NetIO:  humanBytes(s.NetInput) + " / " + humanBytes(s.NetOutput),
+				PIDs:   s.PIDs,
+			})
+		}
+	}
+	errs := hostErrors(results)
+	return emit(cmd, models.HostStatsOutput{Stats: rows, Errors: errs}, rows, len(rows),
+		"No running containers.")
+}
@@ -0,0 +1,54 @@
+package host
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+// newVolumesCommand builds `coolify host volumes`. coold has no volume
+// listing, only GET /volumes/{name}, so the command takes the names to look
+// for and reports the hosts that hold them.
+func newVolumesCommand(flags *Flags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "volumes <name>...",
+		Short: "Show which servers hold the named volumes",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClients(cmd.Context(), flags, func(clients clientFactory) error {
+				return emitVolumes(cmd.Context(), cmd, flags, args, clients)
+			})
+		},
+	}
+}
+
+func emitVolumes(ctx context.Context, cmd *cobra.Command, flags *Flags, names []string, clients clientFactory) error {
+	results := fanOut(ctx, flags, clients, func(ctx context.Context, c *coold.Client) ([]coold.Volume, error) {
+		var found []coold.Volume
+		for _, name := range names {
+			v, err := c.GetVolume(ctx, name)
+			if coold.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return found, err
+			}
+			found = append(found, v)
+		}
+		return found, nil
+	})
+	rows := []models.HostVolumeRow{}
+	for _, r := range results {
+		for _, v := range r.Result {
+			rows = append(rows, models.HostVolumeRow{
+				Host: r.Host, Name: v.Name, Driver: v.Driver, Mountpoint: v.Mountpoint,
+			})
+		}
+	}
+	errs := hostErrors(results)
+	return emit(cmd, models.HostVolumesOutput{Volumes: rows, Errors: errs}, rows, len(rows),
+		"No server holds these volumes.")
+}
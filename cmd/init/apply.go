@@ -5,10 +5,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/coold"
 	"github.com/coollabsio/coolify-cli/internal/models"
 	"github.com/coollabsio/coolify-cli/internal/output"
 	internalssh "github.com/coollabsio/coolify-cli/internal/ssh"
@@ -58,6 +62,7 @@ func runApply(ctx context.Context, cmd *cobra.Command, flags *InitFlags, opts ap
 	if err != nil {
 		return fmt.Errorf("SSH client: %w", err)
 	}
+	defer sshClient.Close()
 
 	if opts.Header != "" {
 		fmt.Fprintln(os.Stderr, opts.Header)
@@ -70,11 +75,20 @@ func runApply(ctx context.Context, cmd *cobra.Command, flags *InitFlags, opts ap
 	if probeErr != nil {
 		fmt.Fprintf(os.Stderr, "Warning: %v\n", probeErr)
 	}
+	ledger, ledgerWarns, err := reconcileLedger(ctx, sshClient, flags, &current)
+	if err != nil {
+		return err
+	}
+	if err := loadJWTRevocations(ctx, sshClient, flags, desired); err != nil {
+		return err
+	}
 
 	plan, err := wireguard.BuildPlan(desired, current)
 	if err != nil {
 		return fmt.Errorf("build plan: %w", err)
 	}
+	plan.Warnings = append(plan.Warnings, ledgerWarns...)
+	plan.Warnings = append(plan.Warnings, operatorPeerWarnings(plan, ledger, common.DefaultOperatorFile())...)
 
 	for _, w := range plan.Warnings {
 		fmt.Fprintf(os.Stderr, "Warning [%s]: %s\n", w.Host, w.Reason)
@@ -100,30 +114,27 @@ func runApply(ctx context.Context, cmd *cobra.Command, flags *InitFlags, opts ap
 	}
 
 	if plan.IsEmpty() {
+		if err := recordLedger(ctx, sshClient, flags, desired, ledger); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: mesh state not saved: %v\n", err)
+		}
 		return runVerify(ctx, sshClient, flags, desired, format)
 	}
 
 	fmt.Fprintln(os.Stderr, "Applying...")
+	journal := wireguard.NewJournal(desired.Interface)
 	actionResults, applyErr := wireguard.ApplyMesh(ctx, sshClient,
-		flags.SSHUser, flags.SSHPort, desired, current, flags.Concurrency)
-
-	rows := make([]models.ApplyResultRow, len(actionResults))
-	for i, r := range actionResults {
-		status := "ok"
-		detail := r.Action.Detail
-		if r.Err != nil {
-			status = "error"
-			if detail == "" {
-				detail = r.Err.Error()
-			}
-		}
-		rows[i] = models.ApplyResultRow{
-			Server: r.Action.Host,
-			Action: string(r.Action.Type),
-			Status: status,
-			Detail: detail,
-		}
+		flags.SSHUser, flags.SSHPort, desired, current, flags.Concurrency, journal)
+	if err := journal.Save(flags.journalFile()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: apply journal not saved: %v\n", err)
+	}
+	if applyErr != nil && !flags.NoRollback {
+		actionResults = append(actionResults, autoRollback(ctx, sshClient, flags, journal, actionResults)...)
 	}
+	if err := recordLedger(ctx, sshClient, flags, desired, ledger); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: mesh state not saved: %v\n", err)
+	}
+
+	rows := resultRows(actionResults)
 
 	if format == output.FormatJSON || format == output.FormatPretty {
 		verifyRows := collectVerifyRows(ctx, sshClient, flags, desired)
@@ -150,6 +161,28 @@ func runApply(ctx context.Context, cmd *cobra.Command, flags *InitFlags, opts ap
 	return applyErr
 }
 
+// resultRows converts executed actions into render rows.
+func resultRows(results []wireguard.ActionResult) []models.ApplyResultRow {
+	rows := make([]models.ApplyResultRow, len(results))
+	for i, r := range results {
+		status := "ok"
+		detail := r.Action.Detail
+		if r.Err != nil {
+			status = "error"
+			if detail == "" {
+				detail = r.Err.Error()
+			}
+		}
+		rows[i] = models.ApplyResultRow{
+			Server: r.Action.Host,
+			Action: string(r.Action.Type),
+			Status: status,
+			Detail: detail,
+		}
+	}
+	return rows
+}
+
 // shouldSkipGate returns true when the interactive alpha gate should be bypassed.
 func shouldSkipGate(flags *InitFlags) bool {
 	if flags.Yes {
@@ -177,7 +210,7 @@ func runVerify(ctx context.Context, sshClient *internalssh.Client, flags *InitFl
 
 func collectVerifyRows(ctx context.Context, sshClient *internalssh.Client, flags *InitFlags, desired *wireguard.DesiredMesh) []models.VerifyResultRow {
 	vresults := wireguard.Verify(ctx, sshClient,
-		flags.Servers, flags.SSHUser, flags.SSHPort, desired.Interface, flags.Concurrency)
+		desired.Hosts, flags.SSHUser, flags.SSHPort, desired.Interface, flags.Concurrency)
 
 	rows := make([]models.VerifyResultRow, len(vresults))
 	for i, v := range vresults {
@@ -198,3 +231,40 @@ func collectVerifyRows(ctx context.Context, sshClient *internalssh.Client, flags
 	}
 	return rows
 }
+
+// operatorPeerWarnings warns when plan rewrites a WireGuard config while an
+// operator peer is joined but cannot be rendered into it: the ledger keeps
+// the operator as a desired peer only with its public key, and an operator
+// that joined against a different --state-file is not in the ledger at all.
+// Either way the rewritten configs drop it until `coolify firewall operator
+// join` runs again.
+func operatorPeerWarnings(plan *wireguard.Plan, ledger *wireguard.Ledger, operatorFile string) []wireguard.Warning {
+	var ip, reason string
+	switch {
+	case ledger != nil && ledger.Operator != nil:
+		if ledger.Operator.PublicKey != "" {
+			return nil
+		}
+		ip, reason = ledger.Operator.MgmtIP, "is recorded without its public key"
+	default:
+		peer, err := coold.LoadOperatorPeer(operatorFile)
+		if err != nil || peer == nil {
+			return nil
+		}
+		ip, reason = peer.MgmtIP, "is not recorded in the mesh state (joined with another --state-file?)"
+	}
+	var hosts []string
+	for _, a := range plan.Actions {
+		if a.Type == wireguard.ActionWriteConfig && !slices.Contains(hosts, a.Host) {
+			hosts = append(hosts, a.Host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	return []wireguard.Warning{{
+		Host: "operator " + ip,
+		Reason: fmt.Sprintf("the operator peer %s, so the rewritten configs on %s drop it; run `coolify firewall operator join` again afterwards",
+			reason, strings.Join(hosts, ", ")),
+	}}
+}
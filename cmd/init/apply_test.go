@@ -1,11 +1,15 @@
 package initcmd
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
 )
 
 // TestNewInitCommand verifies the command tree structure.
@@ -23,6 +27,13 @@ func TestNewInitCommand(t *testing.T) {
 	assert.Contains(t, subCmds, "bootstrap")
 	assert.Contains(t, subCmds, "extend")
 	assert.Contains(t, subCmds, "upgrade")
+	assert.Contains(t, subCmds, "remove-host")
+	assert.Contains(t, subCmds, "rotate-keys")
+	assert.Contains(t, subCmds, "rollback")
+	assert.Contains(t, subCmds, "state")
+	assert.Contains(t, subCmds, "status")
+	assert.Contains(t, subCmds, "jwt")
+	assert.Contains(t, subCmds, "caps")
 	assert.NotContains(t, subCmds, "apply", "apply removed in favor of bootstrap/extend/upgrade")
 }
 
@@ -79,7 +90,7 @@ func TestNewInitCommand_FlagDefaults(t *testing.T) {
 
 	contPrefix, err := pf.GetInt("container-prefix")
 	require.NoError(t, err)
-	assert.Equal(t, 24, contPrefix)
+	assert.Equal(t, 0, contPrefix, "0 picks the pool family default")
 
 	iface, err := pf.GetString("wg-interface")
 	require.NoError(t, err)
@@ -123,3 +134,35 @@ func TestPlanCommand_FlagsInherited(t *testing.T) {
 	f := planCmd.InheritedFlags().Lookup("servers")
 	assert.NotNil(t, f, "plan should inherit --servers from parent")
 }
+
+func TestOperatorPeerWarnings(t *testing.T) {
+	plan := &wireguard.Plan{Actions: []wireguard.PlannedAction{
+		{Host: "h1", Type: wireguard.ActionWriteConfig},
+		{Host: "h1", Type: wireguard.ActionEnableService},
+		{Host: "h2", Type: wireguard.ActionWriteConfig},
+	}}
+	noFile := filepath.Join(t.TempDir(), "operator.json")
+	assert.Empty(t, operatorPeerWarnings(plan, nil, noFile), "not joined")
+
+	file := filepath.Join(t.TempDir(), "operator.json")
+	require.NoError(t, (&coold.OperatorPeer{MgmtIP: "100.64.255.253"}).Save(file))
+
+	ledger := wireguard.NewLedger()
+	ledger.RecordOperator(&wireguard.LedgerOperator{MgmtIP: "100.64.255.254", PublicKey: "op-key"})
+	assert.Empty(t, operatorPeerWarnings(plan, ledger, file), "rendered from the ledger")
+
+	ledger.RecordOperator(&wireguard.LedgerOperator{MgmtIP: "100.64.255.254"})
+	warns := operatorPeerWarnings(plan, ledger, noFile)
+	require.Len(t, warns, 1)
+	assert.Equal(t, "operator 100.64.255.254", warns[0].Host)
+	assert.Contains(t, warns[0].Reason, "without its public key")
+	assert.Contains(t, warns[0].Reason, "h1, h2")
+	assert.Contains(t, warns[0].Reason, "coolify firewall operator join")
+
+	assert.Empty(t, operatorPeerWarnings(&wireguard.Plan{}, ledger, noFile), "no config rewritten")
+
+	warns = operatorPeerWarnings(plan, nil, file)
+	require.Len(t, warns, 1)
+	assert.Equal(t, "operator 100.64.255.253", warns[0].Host)
+	assert.Contains(t, warns[0].Reason, "not recorded in the mesh state")
+}
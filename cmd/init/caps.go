@@ -0,0 +1,178 @@
+package initcmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/services"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// NewCapsCommand creates the `coolify init caps` parent command.
+func NewCapsCommand(flags *InitFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "caps",
+		Short: "Add or remove capability labels on hosts",
+		Long: `A host's capabilities are the caps claim of its JWT: always coold, builder
+on the hosts selected by --enable-builder / --builder-hosts, plus labels such
+as gpu, edge, storage or any custom name. coold gets the same list in
+COOLD_CAPS and advertises it to the scheduler, which rejects a host that
+claims more than its token.
+
+Changing labels re-issues the host JWT and rewrites the coold unit, then
+restarts coold. Labels are read back from each host's token, so later
+bootstrap / extend / upgrade runs keep them. ` + "`coolify init jwt list`" + ` shows
+every host's caps.`,
+	}
+	cmd.AddCommand(newCapsEditCommand(flags, "add"))
+	cmd.AddCommand(newCapsEditCommand(flags, "remove"))
+	return cmd
+}
+
+// newCapsEditCommand builds `caps add` (verb "add") or `caps remove`.
+func newCapsEditCommand(flags *InitFlags, verb string) *cobra.Command {
+	var (
+		hosts  []string
+		dryRun bool
+	)
+	short := "Grant capability labels to hosts"
+	if verb == "remove" {
+		short = "Take capability labels away from hosts"
+	}
+	cmd := &cobra.Command{
+		Use:   verb + " <capability>...",
+		Short: short,
+		Long: short + `. Each --host gets a fresh JWT with the new caps claim
+and a coold unit with the new COOLD_CAPS, and coold is restarted. Hosts that
+already have the requested labels are left alone.
+
+The steps are journaled like an apply: a failed host is rolled back unless
+--no-rollback is set, and ` + "`coolify init rollback`" + ` undoes the run.`,
+		Example: fmt.Sprintf(`  coolify init caps %[1]s gpu --host 10.0.0.2 --servers 10.0.0.1,10.0.0.2 --central 10.0.0.1
+  coolify init caps %[1]s edge storage --host 10.0.0.2 --host 10.0.0.3 --servers 10.0.0.1,10.0.0.2,10.0.0.3 --central 10.0.0.1 --dry-run`, verb),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			fmt.Fprint(os.Stderr, alphaBanner)
+			for _, label := range args {
+				if err := services.ValidateCapLabel(label); err != nil {
+					return err
+				}
+			}
+			if err := flags.Validate(); err != nil {
+				return err
+			}
+			if flags.CentralHost == "" {
+				return fmt.Errorf("--central is required to change host capabilities")
+			}
+			if len(hosts) == 0 {
+				return fmt.Errorf("--host is required")
+			}
+			desired, err := buildDesired(flags)
+			if err != nil {
+				return err
+			}
+			sshClient, err := flags.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer sshClient.Close()
+
+			current, probeErr := wireguard.Reconstruct(ctx, sshClient, flags.Servers,
+				flags.SSHUser, flags.SSHPort, flags.WGInterface,
+				flags.Namespaces, flags.Concurrency)
+			if probeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", probeErr)
+			}
+			if err := loadJWTRevocations(ctx, sshClient, flags, desired); err != nil {
+				return err
+			}
+			desired.HostLabels = map[string][]string{}
+			for _, h := range hosts {
+				var labels []string
+				if s := current.Servers[h]; s != nil {
+					labels = s.Labels()
+				}
+				if verb == "add" {
+					desired.HostLabels[h] = editLabels(labels, args, nil)
+				} else {
+					desired.HostLabels[h] = editLabels(labels, nil, args)
+				}
+			}
+			steps, err := wireguard.PlanHostCaps(desired, current, hosts)
+			if err != nil {
+				return err
+			}
+
+			format, _ := cmd.Root().PersistentFlags().GetString("format")
+			formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+			if err != nil {
+				return err
+			}
+			if len(steps) == 0 {
+				fmt.Fprintln(os.Stderr, "No changes needed. Every host already has the requested caps.")
+				return nil
+			}
+			if dryRun {
+				rows := make([]models.PlanActionRow, len(steps))
+				for i, a := range steps {
+					rows[i] = models.PlanActionRow{Server: a.Host, Action: string(a.Type), Detail: a.Detail}
+				}
+				return formatter.Format(rows)
+			}
+
+			if !shouldSkipGate(flags) {
+				var changed int
+				for _, a := range steps {
+					if a.Type == wireguard.ActionWriteHostJWT {
+						changed++
+					}
+				}
+				fmt.Fprintf(os.Stderr, "This will re-issue the JWT of %d host(s) and restart their coold.\n", changed)
+				fmt.Fprint(os.Stderr, "Press Enter to continue, or Ctrl+C to abort... ")
+				if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+					return fmt.Errorf("read confirmation: %w", err)
+				}
+			}
+
+			journal := wireguard.NewJournal(desired.Interface)
+			results, applyErr := wireguard.ApplyHostCaps(ctx, sshClient, flags.SSHUser, flags.SSHPort,
+				desired, current, hosts, flags.Concurrency, journal)
+			if err := journal.Save(flags.journalFile()); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: apply journal not saved: %v\n", err)
+			}
+			if applyErr != nil && !flags.NoRollback {
+				results = append(results, autoRollback(ctx, sshClient, flags, journal, results)...)
+			}
+			if err := formatter.Format(resultRows(results)); err != nil {
+				return err
+			}
+			return applyErr
+		},
+	}
+	cmd.Flags().StringSliceVar(&hosts, "host", nil,
+		"Host (from --servers) to change; repeat or comma-separate for several")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"Show the steps without changing anything")
+	return cmd
+}
+
+// editLabels returns labels plus add minus remove, sorted and without
+// duplicates. The result is never nil: an empty list clears the labels.
+func editLabels(labels, add, remove []string) []string {
+	out := []string{}
+	for _, l := range append(append([]string(nil), labels...), add...) {
+		if !slices.Contains(remove, l) && !slices.Contains(out, l) {
+			out = append(out, l)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,23 @@
+package initcmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditLabels(t *testing.T) {
+	assert.Equal(t, []string{"edge", "gpu"}, editLabels([]string{"gpu"}, []string{"edge", "gpu"}, nil))
+	assert.Equal(t, []string{"edge"}, editLabels([]string{"gpu", "edge"}, nil, []string{"gpu", "storage"}))
+	assert.Equal(t, []string{}, editLabels([]string{"gpu"}, nil, []string{"gpu"}),
+		"removing the last label must clear, not fall back to the token's labels")
+}
+
+func TestCapsCommand_RejectsBuiltinCaps(t *testing.T) {
+	cmd := NewInitCommand()
+	cmd.SetArgs([]string{"caps", "add", "builder", "--host", "h1", "--servers", "h1", "--central", "h1"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, "--builder-hosts")
+}
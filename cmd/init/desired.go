@@ -19,13 +19,39 @@ func buildDesired(flags *InitFlags) (*wireguard.DesiredMesh, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid --container-pool %q: %w", flags.ContainerPool, err)
 	}
+	contPrefix := flags.ContainerPrefix
+	if contPrefix == 0 {
+		contPrefix = wireguard.DefaultContainerPrefix(contPool)
+	}
+	if ones, bits := contPool.Mask.Size(); contPrefix < ones || contPrefix > bits-2 {
+		return nil, fmt.Errorf("--container-prefix /%d does not fit inside --container-pool %s", contPrefix, contPool)
+	}
+	var contPool6 *net.IPNet
+	var contPrefix6 int
+	if flags.ContainerPool6 != "" {
+		if _, contPool6, err = net.ParseCIDR(flags.ContainerPool6); err != nil {
+			return nil, fmt.Errorf("invalid --container-pool6 %q: %w", flags.ContainerPool6, err)
+		}
+		if !wireguard.IsIPv6(contPool6.IP) {
+			return nil, fmt.Errorf("--container-pool6 %s is not an IPv6 prefix", contPool6)
+		}
+		if wireguard.IsIPv6(contPool.IP) {
+			return nil, fmt.Errorf("--container-pool6 needs an IPv4 --container-pool, got %s", contPool)
+		}
+		contPrefix6 = wireguard.DefaultContainerPrefix(contPool6)
+		if ones, _ := contPool6.Mask.Size(); ones > contPrefix6 {
+			return nil, fmt.Errorf("--container-pool6 %s is smaller than the /%d each host gets", contPool6, contPrefix6)
+		}
+	}
 
 	return &wireguard.DesiredMesh{
-		Hosts:                 flags.Servers,
+		Hosts:                 meshHosts(flags),
 		Interface:             flags.WGInterface,
 		MgmtPool:              mgmtPool,
 		ContainerPool:         contPool,
-		ContainerPrefix:       flags.ContainerPrefix,
+		ContainerPrefix:       contPrefix,
+		ContainerPool6:        contPool6,
+		ContainerPrefix6:      contPrefix6,
 		ListenPort:            flags.WGListenPort,
 		InstallPodman:         true,
 		Namespaces:            flags.Namespaces,
@@ -45,7 +71,27 @@ func buildDesired(flags *InitFlags) (*wireguard.DesiredMesh, error) {
 		BuilderTimeoutSecs:    flags.BuilderTimeoutSecs,
 		Intent:                wireguard.Intent(flags.Intent),
 		NewHosts:              flags.NewHosts,
+		RemoveHosts:           flags.RemoveHosts,
 		AllowReplace:          flags.AllowReplace,
 		AllowNightly:          flags.AllowNightly,
 	}, nil
 }
+
+// meshHosts returns the hosts that make up the mesh after this run: --servers
+// minus any host being removed.
+func meshHosts(flags *InitFlags) []string {
+	if wireguard.Intent(flags.Intent) != wireguard.IntentRemoveHost {
+		return flags.Servers
+	}
+	removed := make(map[string]struct{}, len(flags.RemoveHosts))
+	for _, h := range flags.RemoveHosts {
+		removed[h] = struct{}{}
+	}
+	hosts := make([]string, 0, len(flags.Servers))
+	for _, h := range flags.Servers {
+		if _, ok := removed[h]; !ok {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
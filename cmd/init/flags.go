@@ -6,6 +6,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
 )
 
 // InitFlags holds all flags shared between `plan` and `apply`.
@@ -50,6 +51,11 @@ type InitFlags struct {
 	// actions; new hosts get the full first-time install.
 	NewHosts []string
 
+	// RemoveHosts is the remove-host-subcommand-only list of hosts leaving
+	// the mesh. Must be a subset of Servers; they are probed so they can be
+	// drained, then dropped from every other host's config.
+	RemoveHosts []string
+
 	// AllowReplace unlocks destructive-replace actions on existing hosts in
 	// extend mode (e.g. recreating a podman bridge whose dns_enabled=true
 	// pre-alpha drift would otherwise be blocked).
@@ -60,6 +66,18 @@ type InitFlags struct {
 	// every run instead of only when the pinned version changes.
 	AllowNightly bool
 
+	// StateFile is the local mesh state ledger; empty means
+	// common.DefaultStateFile().
+	StateFile string
+
+	// StateRemote mirrors the ledger to every host and, when reading,
+	// prefers the newest copy found locally or on any host.
+	StateRemote bool
+
+	// NoRollback keeps a failed apply's changes in place instead of rolling
+	// back the failed hosts from the apply journal.
+	NoRollback bool
+
 	// Intent selects the plan filter (bootstrap/extend/upgrade). Set by each
 	// subcommand before calling runPlan/runApply; not bound to a flag.
 	Intent string
@@ -73,7 +91,7 @@ func bindInitFlags(cmd *cobra.Command, f *InitFlags) {
 	pf := cmd.PersistentFlags()
 
 	pf.StringVar(&f.WGMgmtPool, "wg-mgmt-pool", "100.64.0.0/16",
-		"WireGuard management address pool — each host gets a /32 from here, assigned to wg0")
+		"WireGuard management address pool (IPv4 or IPv6) — each host gets a /32 or /128 from here, assigned to wg0")
 	pf.StringVar(&f.WGInterface, "wg-interface", "wg0",
 		"WireGuard interface name on the remote hosts")
 	pf.IntVar(&f.WGListenPort, "wg-listen-port", 51820,
@@ -88,6 +106,12 @@ func bindInitFlags(cmd *cobra.Command, f *InitFlags) {
 		"Corrosion SWIM gossip port (bound to the wg0 mgmt IP)")
 	pf.IntVar(&f.CorrosionAPIPort, "corrosion-api-port", 8080,
 		"Corrosion HTTP API port (bound to 127.0.0.1)")
+	pf.StringVar(&f.StateFile, "state-file", "",
+		"Mesh state ledger recording IP/subnet allocations, public keys and agent versions (default: mesh-state.json in the config directory)")
+	pf.BoolVar(&f.StateRemote, "state-remote", false,
+		"Also keep a copy of the mesh state on every server ("+wireguard.RemoteLedgerPath+") and read the newest copy")
+	pf.BoolVar(&f.NoRollback, "no-rollback", false,
+		"Leave a failed apply's changes in place instead of rolling back the failed hosts (see `init rollback`)")
 	pf.BoolVarP(&f.Yes, "yes", "y", false,
 		"Skip the interactive alpha confirmation prompt")
 	pf.StringVar(&f.CentralHost, "central", "",
@@ -35,7 +35,29 @@ Subcommands:
   extend     Add new hosts to an existing mesh; existing hosts get only
              peer-refresh actions.
   upgrade    Bump agent versions (coold / corrosion / scheduler / builder);
-             WG / podman / firewall untouched.`,
+             WG / podman / firewall untouched.
+  remove-host
+             Drain hosts and remove them; surviving hosts drop the peer.
+  rotate-keys
+             Rotate WireGuard keypairs host by host, optionally adding
+             per-pair preshared keys.
+  rollback   Undo the reversible steps of the last apply (failed hosts
+             are rolled back automatically unless --no-rollback).
+  state      Show, export or import the persisted mesh state (IPAM ledger).
+  status     Check handshakes, reachability and agent units; exits with
+             Nagios-style codes.
+  jwt        List, rotate and revoke the host JWTs coold presents to the
+             scheduler.
+  caps       Add or remove capability labels (gpu, edge, storage, ...)
+             carried in a host's JWT and coold env.
+
+Server host keys are verified against ~/.ssh/known_hosts and Coolify's own
+known_hosts file. Pass --ssh-strict-host-key-checking=accept-new to pin the
+keys of new servers on first use; a changed key is always rejected.
+
+Keys from ssh-agent and HostName/User/Port/IdentityFile/ProxyJump from
+~/.ssh/config are used automatically; --ssh-jump tunnels through bastion
+hosts. One connection per server is reused for the whole command.`,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			fmt.Fprint(os.Stderr, alphaBanner)
 			return cmd.Help()
@@ -48,6 +70,13 @@ Subcommands:
 	cmd.AddCommand(NewBootstrapCommand(flags))
 	cmd.AddCommand(NewExtendCommand(flags))
 	cmd.AddCommand(NewUpgradeCommand(flags))
+	cmd.AddCommand(NewRemoveHostCommand(flags))
+	cmd.AddCommand(NewRotateKeysCommand(flags))
+	cmd.AddCommand(NewRollbackCommand(flags))
+	cmd.AddCommand(NewStateCommand(flags))
+	cmd.AddCommand(NewStatusCommand(flags))
+	cmd.AddCommand(NewJWTCommand(flags))
+	cmd.AddCommand(NewCapsCommand(flags))
 
 	return cmd
 }
@@ -0,0 +1,348 @@
+package initcmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/services"
+	internalssh "github.com/coollabsio/coolify-cli/internal/ssh"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// NewJWTCommand creates the `coolify init jwt` parent command.
+func NewJWTCommand(flags *InitFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jwt",
+		Short: "Inspect, rotate and revoke the host JWTs coold presents to the scheduler",
+		Long: `Every host's coold authenticates to the scheduler on --central with an
+ES256 JWT in /etc/coolify/host-jwt, minted at bootstrap and valid for a year.
+Its sub claim is the host's mgmt IP and its caps claim the capabilities the
+host may advertise.
+
+The scheduler trusts the public keys in /etc/coolify/jwt.pubs on --central
+(current key first) and rejects tokens listed in /etc/coolify/jwt-revoked.json.
+Schedulers older than ` + services.MinSchedulerJWTKeySetVersion + ` read neither file, so revoke and
+rotate --new-key refuse to run against them.`,
+	}
+	cmd.AddCommand(newJWTListCommand(flags))
+	cmd.AddCommand(newJWTRotateCommand(flags))
+	cmd.AddCommand(newJWTRevokeCommand(flags))
+	return cmd
+}
+
+func newJWTListCommand(flags *InitFlags) *cobra.Command {
+	var warnDays int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show the subject, caps and expiry of every host's JWT",
+		Long: `Decode /etc/coolify/host-jwt on every host in --servers. With --central
+each token is also checked against the scheduler's trusted keys and
+revocations.
+
+The exit code follows the Nagios plugin convention, like init status:
+0 OK, 1 WARNING (a token expires within --warn-days, or a host has none),
+2 CRITICAL (expired, revoked, or signed by an untrusted key), 3 UNKNOWN (a
+token could not be read).`,
+		Example: `  coolify init jwt list --servers 10.0.0.1,10.0.0.2 --central 10.0.0.1
+  coolify init jwt list --servers 10.0.0.1,10.0.0.2 --warn-days 60 --format json`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			unknown := func(err error) error {
+				return &common.ExitError{Code: int(wireguard.SeverityUnknown), Err: err}
+			}
+			if err := flags.Validate(); err != nil {
+				return unknown(err)
+			}
+			if warnDays < 0 {
+				return unknown(fmt.Errorf("--warn-days must not be negative"))
+			}
+			sshClient, err := flags.BuildSSHClient()
+			if err != nil {
+				return unknown(fmt.Errorf("SSH client: %w", err))
+			}
+			defer sshClient.Close()
+
+			ctx := cmd.Context()
+			var trust *wireguard.JWTTrust
+			if flags.CentralHost != "" {
+				if trust, err = wireguard.ReadJWTTrust(ctx, sshClient, flags.CentralHost, flags.SSHUser, flags.SSHPort); err != nil {
+					return unknown(err)
+				}
+			}
+			tokens := wireguard.ReadHostJWTs(ctx, sshClient, flags.Servers,
+				flags.SSHUser, flags.SSHPort, flags.Concurrency, trust)
+
+			now, warnWithin := time.Now(), time.Duration(warnDays)*24*time.Hour
+			format, _ := cmd.Root().PersistentFlags().GetString("format")
+			out := jwtListOutput(trust, tokens, now, warnWithin)
+			if err := renderJWTList(os.Stdout, format, out); err != nil {
+				return unknown(err)
+			}
+			severity := jwtSeverity(trust, tokens, now, warnWithin)
+			if severity == wireguard.SeverityOK {
+				return nil
+			}
+			var problems int
+			for _, r := range out.Tokens {
+				if r.Status != wireguard.JWTStatusOK {
+					problems++
+				}
+			}
+			return &common.ExitError{
+				Code: int(severity),
+				Err:  fmt.Errorf("host JWTs %s: %d problem(s)", severity, problems),
+			}
+		},
+	}
+	cmd.Flags().IntVar(&warnDays, "warn-days", int(wireguard.DefaultJWTExpiryWarning/(24*time.Hour)),
+		"Warn when a token expires within this many days")
+	return cmd
+}
+
+// jwtSeverity is the worst verdict over tokens.
+func jwtSeverity(trust *wireguard.JWTTrust, tokens []wireguard.HostJWT, now time.Time, warnWithin time.Duration) wireguard.Severity {
+	worst := wireguard.SeverityOK
+	for _, t := range tokens {
+		if sev := t.Evaluate(trust, now, warnWithin).Severity; sev > worst {
+			worst = sev
+		}
+	}
+	return worst
+}
+
+func jwtListOutput(trust *wireguard.JWTTrust, tokens []wireguard.HostJWT, now time.Time, warnWithin time.Duration) models.HostJWTListOutput {
+	out := models.HostJWTListOutput{
+		Status: jwtSeverity(trust, tokens, now, warnWithin).String(),
+		Tokens: []models.HostJWTRow{},
+	}
+	if trust != nil {
+		for _, k := range trust.Keys {
+			out.Keys = append(out.Keys, k.ID)
+		}
+	}
+	for _, t := range tokens {
+		check := t.Evaluate(trust, now, warnWithin)
+		row := models.HostJWTRow{Server: t.Host, Status: check.Status, Detail: check.Message}
+		if c := t.Claims; c != nil {
+			row.Subject = c.Subject
+			row.Caps = strings.Join(c.Caps, ",")
+			row.Key = c.KeyID
+			if t.SignedBy != "" {
+				row.Key = t.SignedBy
+			}
+			if row.Key == "" {
+				row.Key = "-"
+			}
+			row.Issued = c.IssuedAt.UTC().Format(time.DateOnly)
+			row.Expires = c.ExpiresAt.UTC().Format(time.DateOnly)
+		}
+		out.Tokens = append(out.Tokens, row)
+	}
+	return out
+}
+
+// renderJWTList writes out as JSON, or as a summary line and the token
+// table.
+func renderJWTList(w io.Writer, format string, out models.HostJWTListOutput) error {
+	formatter, err := output.NewFormatter(format, output.Options{Writer: w})
+	if err != nil {
+		return err
+	}
+	if format == output.FormatJSON || format == output.FormatPretty {
+		return formatter.Format(out)
+	}
+	summary := fmt.Sprintf("HOST JWT %s - %d host(s)", out.Status, len(out.Tokens))
+	if len(out.Keys) > 0 {
+		summary += fmt.Sprintf(", trusted keys: %s", strings.Join(out.Keys, ", "))
+	}
+	fmt.Fprintf(w, "%s\n\n", summary)
+	return formatter.Format(out.Tokens)
+}
+
+func newJWTRotateCommand(flags *InitFlags) *cobra.Command {
+	var (
+		hosts  []string
+		newKey bool
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-issue host JWTs, optionally under a new central keypair",
+		Long: `Mint a fresh one-year token for --hosts (default: every host in --servers),
+keeping each host's subject and caps, push it and restart coold. Every host
+must already hold a token; hosts without one get it from bootstrap.
+
+--new-key first replaces the keypair on --central. The new public key is
+added to the scheduler's key set before anything is signed with it, and the
+previous key stays trusted for the hosts not re-issued yet. Once no
+unexpired token on --servers uses an old key any more, it is retired.
+--servers must therefore list the whole mesh. --new-key needs a scheduler
+that reads the key set (` + services.MinSchedulerJWTKeySetVersion + ` or later).
+
+Re-issuing a revoked host lifts its revocation.`,
+		Example: `  coolify init jwt rotate --servers 10.0.0.1,10.0.0.2 --central 10.0.0.1 --dry-run
+  coolify init jwt rotate --servers 10.0.0.1,10.0.0.2 --central 10.0.0.1 --new-key --yes`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			fmt.Fprint(os.Stderr, alphaBanner)
+			if err := flags.Validate(); err != nil {
+				return err
+			}
+			if flags.CentralHost == "" {
+				return fmt.Errorf("--central is required to rotate host JWTs")
+			}
+			if len(hosts) == 0 {
+				hosts = flags.Servers
+			}
+			sshClient, err := flags.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer sshClient.Close()
+
+			desired := &wireguard.DesiredMesh{Hosts: flags.Servers, CentralHost: flags.CentralHost}
+			trust, err := wireguard.ReadJWTTrust(ctx, sshClient, flags.CentralHost, flags.SSHUser, flags.SSHPort)
+			if err != nil {
+				return err
+			}
+			tokens := wireguard.ReadHostJWTs(ctx, sshClient, flags.Servers,
+				flags.SSHUser, flags.SSHPort, flags.Concurrency, trust)
+			opts := wireguard.JWTRotateOptions{Hosts: hosts, NewKey: newKey}
+			steps, err := wireguard.PlanJWTRotation(desired, trust, tokens, opts)
+			if err != nil {
+				return err
+			}
+
+			format, _ := cmd.Root().PersistentFlags().GetString("format")
+			formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				rows := make([]models.PlanActionRow, len(steps))
+				for i, a := range steps {
+					rows[i] = models.PlanActionRow{Server: a.Host, Action: string(a.Type), Detail: a.Detail}
+				}
+				return formatter.Format(rows)
+			}
+
+			if !shouldSkipGate(flags) {
+				fmt.Fprintf(os.Stderr, "This will re-issue the JWT of %d host(s) and restart their coold.\n", len(hosts))
+				fmt.Fprint(os.Stderr, "Press Enter to continue, or Ctrl+C to abort... ")
+				if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+					return fmt.Errorf("read confirmation: %w", err)
+				}
+			}
+
+			results, rotateErr := wireguard.RotateHostJWTs(ctx, sshClient, flags.SSHUser, flags.SSHPort,
+				desired, tokens, opts, flags.Concurrency)
+			if err := formatter.Format(resultRows(results)); err != nil {
+				return err
+			}
+			return rotateErr
+		},
+	}
+	cmd.Flags().StringSliceVar(&hosts, "hosts", nil,
+		"Comma-separated subset of --servers to re-issue (default: all of --servers)")
+	cmd.Flags().BoolVar(&newKey, "new-key", false,
+		"Replace the central keypair first; old keys stay trusted until no host uses them")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"Show the rotation steps without changing anything")
+	return cmd
+}
+
+func newJWTRevokeCommand(flags *InitFlags) *cobra.Command {
+	var subject string
+	cmd := &cobra.Command{
+		Use:   "revoke <host>",
+		Short: "Make the scheduler reject the JWTs issued to a host so far",
+		Long: `Record a revocation on --central for every token issued to <host> until
+now and restart the scheduler, which drops the host's stream. <host> itself
+is not contacted when its subject (mgmt IP) is in the mesh state; pass
+--subject for a host that is in neither the mesh state nor reachable.
+
+The revocation stays until init jwt rotate --hosts <host> re-issues the
+host's token: bootstrap, extend, upgrade and caps issue no token to a revoked
+host and warn about it instead.`,
+		Example: `  coolify init jwt revoke 10.0.0.3 --servers 10.0.0.1,10.0.0.2 --central 10.0.0.1`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if err := flags.Validate(); err != nil {
+				return err
+			}
+			if flags.CentralHost == "" {
+				return fmt.Errorf("--central is required to revoke a host JWT")
+			}
+			sshClient, err := flags.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer sshClient.Close()
+
+			host := args[0]
+			if subject == "" {
+				if subject, err = jwtSubject(ctx, sshClient, flags, host); err != nil {
+					return err
+				}
+			}
+			results, revokeErr := wireguard.RevokeHostJWT(ctx, sshClient, flags.CentralHost,
+				flags.SSHUser, flags.SSHPort, host, subject, time.Now())
+
+			format, _ := cmd.Root().PersistentFlags().GetString("format")
+			formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+			if err != nil {
+				return err
+			}
+			if err := formatter.Format(resultRows(results)); err != nil {
+				return err
+			}
+			return revokeErr
+		},
+	}
+	cmd.Flags().StringVar(&subject, "subject", "",
+		"JWT subject (the host's mgmt IP) to revoke, when it cannot be looked up")
+	return cmd
+}
+
+// jwtSubject returns the subject of host's tokens: its mgmt IP from the
+// mesh state, or else the sub claim of the token on the host.
+func jwtSubject(ctx context.Context, runner internalssh.Runner, flags *InitFlags, host string) (string, error) {
+	ledger, err := loadLedger(ctx, runner, flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: mesh state: %v\n", err)
+	}
+	if ledger != nil {
+		if h := ledger.Hosts[host]; h != nil && h.MgmtIP != "" {
+			return h.MgmtIP, nil
+		}
+	}
+	tokens := wireguard.ReadHostJWTs(ctx, runner, []string{host}, flags.SSHUser, flags.SSHPort, 1, nil)
+	if t := tokens[0]; t.Err == nil && t.Claims != nil && t.Claims.Subject != "" {
+		return t.Claims.Subject, nil
+	}
+	return "", fmt.Errorf("cannot tell the JWT subject of %s: it is not in the mesh state and has no readable host JWT; pass --subject", host)
+}
+
+// loadJWTRevocations reads the revocations on --central into desired, so
+// that no new token is issued to a revoked host.
+func loadJWTRevocations(ctx context.Context, runner internalssh.Runner, flags *InitFlags, desired *wireguard.DesiredMesh) error {
+	if flags.CentralHost == "" {
+		return nil
+	}
+	revs, err := wireguard.ReadJWTRevocations(ctx, runner, flags.CentralHost, flags.SSHUser, flags.SSHPort)
+	if err != nil {
+		return err
+	}
+	desired.JWTRevocations = revs
+	return nil
+}
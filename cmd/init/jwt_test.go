@@ -0,0 +1,70 @@
+package initcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/services"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+func sampleHostJWTs(now time.Time) (*wireguard.JWTTrust, []wireguard.HostJWT) {
+	trust := &wireguard.JWTTrust{Keys: []services.JWTPublicKey{{ID: "k2"}, {ID: "k1"}}}
+	claims := func(sub string, exp time.Duration) *services.HostJWTClaims {
+		return &services.HostJWTClaims{
+			Subject: sub, Caps: []string{"coold", "builder"}, KeyID: "k1",
+			IssuedAt: now.Add(-300 * 24 * time.Hour), ExpiresAt: now.Add(exp),
+		}
+	}
+	return trust, []wireguard.HostJWT{
+		{Host: "h1", Claims: claims("100.64.0.1", 200*24*time.Hour), SignedBy: "k2"},
+		{Host: "h2", Claims: claims("100.64.0.2", 10*24*time.Hour), SignedBy: "k1"},
+		{Host: "h3", Err: errors.New("dial tcp: timeout")},
+	}
+}
+
+func TestJWTListOutput(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	trust, tokens := sampleHostJWTs(now)
+
+	out := jwtListOutput(trust, tokens, now, wireguard.DefaultJWTExpiryWarning)
+	assert.Equal(t, "UNKNOWN", out.Status)
+	assert.Equal(t, []string{"k2", "k1"}, out.Keys)
+	require.Len(t, out.Tokens, 3)
+	assert.Equal(t, models.HostJWTRow{
+		Server: "h1", Subject: "100.64.0.1", Caps: "coold,builder", Key: "k2",
+		Issued: "2023-01-18", Expires: "2024-06-01", Status: wireguard.JWTStatusOK,
+	}, out.Tokens[0])
+	assert.Equal(t, wireguard.JWTStatusExpiring, out.Tokens[1].Status)
+	assert.Equal(t, "expires in 10d", out.Tokens[1].Detail)
+	assert.Equal(t, wireguard.JWTStatusUnreadable, out.Tokens[2].Status)
+
+	assert.Equal(t, wireguard.SeverityWarning, jwtSeverity(trust, tokens[:2], now, wireguard.DefaultJWTExpiryWarning))
+	assert.Equal(t, wireguard.SeverityOK, jwtSeverity(trust, tokens[:2], now, 24*time.Hour))
+}
+
+func TestRenderJWTList(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	trust, tokens := sampleHostJWTs(now)
+	out := jwtListOutput(trust, tokens[:2], now, wireguard.DefaultJWTExpiryWarning)
+
+	var buf bytes.Buffer
+	require.NoError(t, renderJWTList(&buf, output.FormatTable, out))
+	assert.Contains(t, buf.String(), "HOST JWT WARNING - 2 host(s), trusted keys: k2, k1\n")
+	assert.Contains(t, buf.String(), "expires in 10d")
+
+	buf.Reset()
+	require.NoError(t, renderJWTList(&buf, output.FormatJSON, out))
+	var got models.HostJWTListOutput
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "WARNING", got.Status)
+	assert.Len(t, got.Tokens, 2)
+}
@@ -51,6 +51,7 @@ func runPlan(ctx context.Context, cmd *cobra.Command, flags *InitFlags) error {
 	if err != nil {
 		return fmt.Errorf("SSH client: %w", err)
 	}
+	defer sshClient.Close()
 
 	fmt.Fprintf(os.Stderr, "Probing %d server(s)...\n", len(flags.Servers))
 
@@ -60,11 +61,19 @@ func runPlan(ctx context.Context, cmd *cobra.Command, flags *InitFlags) error {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
+	_, ledgerWarns, err := reconcileLedger(ctx, sshClient, flags, &current)
+	if err != nil {
+		return err
+	}
+	if err := loadJWTRevocations(ctx, sshClient, flags, desired); err != nil {
+		return err
+	}
 
 	plan, err := wireguard.BuildPlan(desired, current)
 	if err != nil {
 		return fmt.Errorf("build plan: %w", err)
 	}
+	plan.Warnings = append(plan.Warnings, ledgerWarns...)
 
 	for _, w := range plan.Warnings {
 		fmt.Fprintf(os.Stderr, "Warning [%s]: %s\n", w.Host, w.Reason)
@@ -66,3 +66,59 @@ func TestShouldSkipGate(t *testing.T) {
 	t.Setenv("COOLIFY_NON_INTERACTIVE", "1")
 	assert.True(t, shouldSkipGate(&InitFlags{}))
 }
+
+// TestBuildDesired_ContainerPrefixDefaultsPerFamily checks the auto prefix
+// and the pool bounds check.
+func TestBuildDesired_ContainerPrefixDefaultsPerFamily(t *testing.T) {
+	base := func(pool string, prefix int) *InitFlags {
+		return &InitFlags{
+			WGMgmtPool: "fd00:64::/64",
+			MeshNetFlags: common.MeshNetFlags{
+				Namespaces:      []string{common.DefaultNamespace},
+				ContainerPool:   pool,
+				ContainerPrefix: prefix,
+			},
+		}
+	}
+
+	d, err := buildDesired(base("10.210.0.0/16", 0))
+	require.NoError(t, err)
+	assert.Equal(t, 24, d.ContainerPrefix)
+
+	d, err = buildDesired(base("fd00:210::/48", 0))
+	require.NoError(t, err)
+	assert.Equal(t, 64, d.ContainerPrefix)
+
+	_, err = buildDesired(base("10.210.0.0/16", 8))
+	assert.ErrorContains(t, err, "does not fit inside --container-pool")
+}
+
+func TestBuildDesired_DualStackContainerPool(t *testing.T) {
+	flags := func(pool, pool6 string) *InitFlags {
+		return &InitFlags{
+			WGMgmtPool: "100.64.0.0/16",
+			MeshNetFlags: common.MeshNetFlags{
+				Namespaces:     []string{common.DefaultNamespace},
+				ContainerPool:  pool,
+				ContainerPool6: pool6,
+			},
+		}
+	}
+
+	d, err := buildDesired(flags("10.210.0.0/16", ""))
+	require.NoError(t, err)
+	assert.Nil(t, d.ContainerPool6)
+
+	d, err = buildDesired(flags("10.210.0.0/16", "fd00:210::/48"))
+	require.NoError(t, err)
+	assert.Equal(t, "fd00:210::/48", d.ContainerPool6.String())
+	assert.Equal(t, 64, d.ContainerPrefix6)
+	assert.True(t, d.ContainerIPv6())
+
+	_, err = buildDesired(flags("10.210.0.0/16", "10.220.0.0/16"))
+	assert.ErrorContains(t, err, "not an IPv6 prefix")
+	_, err = buildDesired(flags("fd00:210::/48", "fd00:220::/48"))
+	assert.ErrorContains(t, err, "needs an IPv4 --container-pool")
+	_, err = buildDesired(flags("10.210.0.0/16", "fd00:210::/96"))
+	assert.ErrorContains(t, err, "smaller than the /64")
+}
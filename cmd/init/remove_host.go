@@ -0,0 +1,70 @@
+package initcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// NewRemoveHostCommand creates the `coolify init remove-host` subcommand. It
+// shrinks the mesh: the hosts in --remove-hosts are drained and torn down,
+// and every surviving host drops them from its WireGuard peers and corrosion
+// bootstrap list. Their mgmt IPs and container subnets return to the pools.
+func NewRemoveHostCommand(flags *InitFlags) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "remove-host",
+		Short: "Drain hosts and remove them from an existing mesh",
+		Long: `Remove hosts from an existing mesh. --servers lists the current mesh
+including the hosts to remove; --remove-hosts is the subset leaving it.
+
+Removal runs in three steps:
+
+  1. Drain: stop the containers on every coolify bridge, then stop and
+     disable coold and corrosion (unit files, host JWT and corrosion DB are
+     deleted).
+  2. Surviving hosts: drop the removed peer block and its AllowedIPs from
+     wg0, refresh the corrosion bootstrap list and restart corrosion.
+  3. Teardown: delete the removed host's rows from corrosion, remove its
+     podman bridges and take wg0 down. WireGuard keys are kept.
+
+A host that does not answer the probe is dropped from every peer without
+being drained. The central host running the scheduler cannot be removed.
+Pass --dry-run to preview the plan without changing anything.`,
+		Example: `  coolify init remove-host --servers 10.0.0.1,10.0.0.2,10.0.0.3 --remove-hosts 10.0.0.3 --dry-run
+  coolify init remove-host --servers 10.0.0.1,10.0.0.2,10.0.0.3 --remove-hosts 10.0.0.3 --yes`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if len(flags.RemoveHosts) == 0 {
+				return fmt.Errorf("--remove-hosts is required: list the subset of --servers that leaves the mesh")
+			}
+			servers := make(map[string]struct{}, len(flags.Servers))
+			for _, s := range flags.Servers {
+				servers[s] = struct{}{}
+			}
+			for _, rh := range flags.RemoveHosts {
+				if _, ok := servers[rh]; !ok {
+					return fmt.Errorf("--remove-hosts: %q is not in --servers", rh)
+				}
+			}
+
+			flags.Intent = string(wireguard.IntentRemoveHost)
+
+			if dryRun {
+				fmt.Fprint(os.Stderr, alphaBanner)
+				return runPlan(cmd.Context(), cmd, flags)
+			}
+			header := fmt.Sprintf("Removing %d host(s) from the mesh: %v", len(flags.RemoveHosts), flags.RemoveHosts)
+			return runApply(cmd.Context(), cmd, flags, applyOptions{Header: header})
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&flags.RemoveHosts, "remove-hosts", nil,
+		"Comma-separated subset of --servers to drain and remove from the mesh (required).")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"Show the removal plan without applying it")
+
+	return cmd
+}
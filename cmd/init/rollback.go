@@ -0,0 +1,157 @@
+package initcmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	internalssh "github.com/coollabsio/coolify-cli/internal/ssh"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// journalFile is the journal of the last apply, next to the state ledger.
+func (f *InitFlags) journalFile() string {
+	return filepath.Join(filepath.Dir(f.stateFile()), "mesh-journal.json")
+}
+
+// autoRollback undoes the journaled steps on the hosts where apply failed.
+// Hosts that converged keep their changes.
+func autoRollback(ctx context.Context, runner internalssh.Runner, flags *InitFlags, journal *wireguard.Journal, results []wireguard.ActionResult) []wireguard.ActionResult {
+	var hosts []string
+	for _, h := range wireguard.FailedHosts(results) {
+		for _, jh := range journal.Hosts() {
+			if h == jh {
+				hosts = append(hosts, h)
+			}
+		}
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Rolling back %v (pass --no-rollback to keep the partial changes)...\n", hosts)
+	rolled, err := journal.Rollback(ctx, runner, flags.SSHUser, flags.SSHPort, hosts, flags.Concurrency)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; retry with `coolify init rollback`\n", err)
+	}
+	return rolled
+}
+
+// NewRollbackCommand creates the `coolify init rollback` subcommand. It
+// restores the files and units the last apply journaled.
+func NewRollbackCommand(flags *InitFlags) *cobra.Command {
+	var (
+		hosts  []string
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Undo the reversible steps of the last apply",
+		Long: `Undo the reversible steps of the last bootstrap, extend, upgrade or
+remove-host run on --hosts (default: every host the run touched).
+
+Before each reversible step apply backs up the files it is about to
+rewrite, and records the state of the units it restarts:
+
+  write-config                 /etc/wireguard/<iface>.conf, wg-quick@<iface>
+  install-firewall             firewall unit and bridge scaffold
+  write-corrosion-config       /etc/corrosion/config.toml, corrosion
+  install-corrosion-service    corrosion and coold unit files
+  write-host-jwt               the coold host JWT
+  update-coold-scheduler-env   coold unit file, coold
+
+The backups stay on the hosts under ` + wireguard.RemoteJournalDir + `; the
+list of steps is kept in mesh-journal.json next to the mesh state. Rollback
+restores each file (or deletes it if the run created it), then re-enables,
+restarts or stops the units to match their state before the run. Package
+installs, key generation and bridge creation are not undone.
+
+A failed apply rolls back the hosts that failed on its own unless
+--no-rollback is passed.`,
+		Example: `  coolify init rollback --dry-run
+  coolify init rollback --hosts 10.0.0.3 --yes`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			fmt.Fprint(os.Stderr, alphaBanner)
+			journal, err := wireguard.LoadJournal(flags.journalFile())
+			if err != nil {
+				return err
+			}
+			if journal == nil || len(journal.Entries) == 0 {
+				return fmt.Errorf("no apply journal in %s: nothing to roll back", flags.journalFile())
+			}
+			if len(hosts) == 0 {
+				hosts = journal.Hosts()
+			}
+
+			format, _ := cmd.Root().PersistentFlags().GetString("format")
+			formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				return formatter.Format(journalRows(journal, hosts))
+			}
+
+			if !shouldSkipGate(flags) {
+				fmt.Fprintf(os.Stderr, "This will restore %d host(s) to their state before run %s.\n", len(hosts), journal.RunID)
+				fmt.Fprint(os.Stderr, "Press Enter to continue, or Ctrl+C to abort... ")
+				if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+					return fmt.Errorf("read confirmation: %w", err)
+				}
+			}
+
+			sshClient, err := flags.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer sshClient.Close()
+
+			results, rollbackErr := journal.Rollback(ctx, sshClient, flags.SSHUser, flags.SSHPort, hosts, flags.Concurrency)
+			if err := formatter.Format(resultRows(results)); err != nil {
+				return err
+			}
+			return rollbackErr
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&hosts, "hosts", nil,
+		"Comma-separated hosts to roll back (default: every host in the journal)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"List the journaled steps without restoring anything")
+
+	return cmd
+}
+
+// journalRows lists the journaled steps on hosts, in the order they ran.
+func journalRows(journal *wireguard.Journal, hosts []string) []models.PlanActionRow {
+	want := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		want[h] = true
+	}
+	rows := []models.PlanActionRow{}
+	for _, e := range journal.Entries {
+		if !want[e.Host] {
+			continue
+		}
+		paths := make([]string, len(e.Files))
+		for i, f := range e.Files {
+			paths[i] = f.Path
+			if !f.Existed {
+				paths[i] += " (new)"
+			}
+		}
+		rows = append(rows, models.PlanActionRow{
+			Server: e.Host,
+			Action: string(e.Action),
+			Detail: strings.Join(paths, ", "),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,181 @@
+package initcmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// NewRotateKeysCommand creates the `coolify init rotate-keys` subcommand. It
+// replaces WireGuard keypairs host by host and can introduce per-pair
+// preshared keys.
+func NewRotateKeysCommand(flags *InitFlags) *cobra.Command {
+	var (
+		hosts            []string
+		presharedKeys    bool
+		handshakeTimeout time.Duration
+		dryRun           bool
+	)
+	cmd := &cobra.Command{
+		Use:   "rotate-keys",
+		Short: "Rotate WireGuard keypairs (and optionally preshared keys) host by host",
+		Long: `Rotate the WireGuard keypair of --hosts (default: every host in --servers).
+--servers must list the whole mesh and every host must be up.
+
+Hosts are rotated one at a time so only the tunnels of the host being rotated
+drop, for about a second:
+
+  1. The host generates its next keypair next to the current one.
+  2. Every other host swaps in the new public key and applies it with
+     wg syncconf, without restarting wg0.
+  3. The host switches to the new keypair the same way; the previous one is
+     kept as /etc/wireguard/privatekey.old.
+  4. The host must complete a fresh handshake with every peer within
+     --handshake-timeout, or rotation stops before the next host.
+
+--preshared-keys installs a new preshared key for every pair that includes a
+rotated host (rotating all hosts gives every pair one). Preshared keys live
+in /etc/wireguard/psk/ and are kept by later applies; without the flag the
+existing ones are left as they are.
+
+A joined operator peer keeps its block in every rewritten config, and the
+new host keys are written to its operator file; reload its tunnel
+afterwards as printed.`,
+		Example: `  coolify init rotate-keys --servers 10.0.0.1,10.0.0.2,10.0.0.3 --dry-run
+  coolify init rotate-keys --servers 10.0.0.1,10.0.0.2,10.0.0.3 --hosts 10.0.0.2 --yes
+  coolify init rotate-keys --servers 10.0.0.1,10.0.0.2,10.0.0.3 --preshared-keys --yes`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			fmt.Fprint(os.Stderr, alphaBanner)
+			if err := validatePlanFlags(flags); err != nil {
+				return err
+			}
+			if len(hosts) == 0 {
+				hosts = flags.Servers
+			}
+			desired, err := buildDesired(flags)
+			if err != nil {
+				return err
+			}
+
+			sshClient, err := flags.BuildSSHClient()
+			if err != nil {
+				return fmt.Errorf("SSH client: %w", err)
+			}
+			defer sshClient.Close()
+
+			fmt.Fprintf(os.Stderr, "Probing %d server(s)...\n", len(flags.Servers))
+			current, probeErr := wireguard.Reconstruct(ctx, sshClient, flags.Servers,
+				flags.SSHUser, flags.SSHPort, flags.WGInterface,
+				flags.Namespaces, flags.Concurrency)
+			if probeErr != nil {
+				return probeErr
+			}
+			ledger, _, err := reconcileLedger(ctx, sshClient, flags, &current)
+			if err != nil {
+				return err
+			}
+
+			opts := wireguard.RotateOptions{
+				Hosts:            hosts,
+				PresharedKeys:    presharedKeys,
+				HandshakeTimeout: handshakeTimeout,
+			}
+			steps, err := wireguard.PlanRotation(desired, current, opts)
+			if err != nil {
+				return err
+			}
+
+			format, _ := cmd.Root().PersistentFlags().GetString("format")
+			if dryRun {
+				rows := make([]models.PlanActionRow, len(steps))
+				for i, a := range steps {
+					rows[i] = models.PlanActionRow{Server: a.Host, Action: string(a.Type), Detail: a.Detail}
+				}
+				formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+				if err != nil {
+					return err
+				}
+				return formatter.Format(rows)
+			}
+
+			if !shouldSkipGate(flags) {
+				fmt.Fprintf(os.Stderr, "This will rotate the WireGuard keys of %d host(s); each host's tunnels drop briefly.\n", len(hosts))
+				fmt.Fprint(os.Stderr, "Press Enter to continue, or Ctrl+C to abort... ")
+				if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+					return fmt.Errorf("read confirmation: %w", err)
+				}
+			}
+
+			fmt.Fprintf(os.Stderr, "Rotating keys on %v...\n", hosts)
+			results, rotateErr := wireguard.RotateKeys(ctx, sshClient, flags.SSHUser, flags.SSHPort,
+				desired, current, opts, flags.Concurrency)
+			if len(results) > 0 {
+				if err := recordLedger(ctx, sshClient, flags, desired, ledger); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: mesh state not saved: %v\n", err)
+				}
+			}
+
+			if updated, err := refreshOperatorPeer(common.DefaultOperatorFile(), current, hosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: operator peer not updated: %v\n", err)
+			} else if updated {
+				fmt.Fprint(os.Stderr, "The operator peer's host keys changed. Reload its tunnel with:\n"+
+					"  coolify firewall operator config | sudo tee /etc/wireguard/coolify.conf && sudo wg-quick down coolify && sudo wg-quick up coolify\n")
+			}
+
+			formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+			if err != nil {
+				return err
+			}
+			if err := formatter.Format(resultRows(results)); err != nil {
+				return err
+			}
+			return rotateErr
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&hosts, "hosts", nil,
+		"Comma-separated subset of --servers to rotate, in order (default: all of --servers)")
+	cmd.Flags().BoolVar(&presharedKeys, "preshared-keys", false,
+		"Install a new preshared key for every pair that includes a rotated host")
+	cmd.Flags().DurationVar(&handshakeTimeout, "handshake-timeout", wireguard.DefaultHandshakeTimeout,
+		"How long a rotated host may take to handshake with every peer before rotation stops")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"Show the rotation steps without changing anything")
+
+	return cmd
+}
+
+// refreshOperatorPeer copies the new public keys of the rotated hosts into
+// the operator file, so `coolify firewall operator config` renders them. It
+// reports whether anything changed; a missing file means no operator peer.
+func refreshOperatorPeer(path string, current wireguard.MeshState, hosts []string) (bool, error) {
+	peer, err := coold.LoadOperatorPeer(path)
+	if err != nil || peer == nil {
+		return false, err
+	}
+	changed := false
+	for _, h := range hosts {
+		oh, ok := peer.Hosts[h]
+		s := current.Servers[h]
+		if !ok || s == nil || s.PublicKey == "" || oh.PublicKey == s.PublicKey {
+			continue
+		}
+		oh.PublicKey = s.PublicKey
+		peer.Hosts[h] = oh
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, peer.Save(path)
+}
@@ -0,0 +1,41 @@
+package initcmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+func TestRefreshOperatorPeer(t *testing.T) {
+	current := wireguard.MeshState{Servers: map[string]*wireguard.ServerState{
+		"h1": {Host: "h1", PublicKey: "new-h1"},
+		"h2": {Host: "h2", PublicKey: "old-h2"},
+	}}
+	file := filepath.Join(t.TempDir(), "operator.json")
+
+	updated, err := refreshOperatorPeer(file, current, []string{"h1"})
+	require.NoError(t, err)
+	assert.False(t, updated, "not joined")
+
+	require.NoError(t, (&coold.OperatorPeer{MgmtIP: "100.64.255.254", Hosts: map[string]coold.OperatorHost{
+		"h1": {MgmtIP: "100.64.0.1", PublicKey: "old-h1", CooldToken: "tok"},
+		"h2": {MgmtIP: "100.64.0.2", PublicKey: "old-h2"},
+	}}).Save(file))
+	updated, err = refreshOperatorPeer(file, current, []string{"h1", "h2"})
+	require.NoError(t, err)
+	assert.True(t, updated)
+
+	peer, err := coold.LoadOperatorPeer(file)
+	require.NoError(t, err)
+	assert.Equal(t, coold.OperatorHost{MgmtIP: "100.64.0.1", PublicKey: "new-h1", CooldToken: "tok"}, peer.Hosts["h1"])
+	assert.Equal(t, "old-h2", peer.Hosts["h2"].PublicKey)
+
+	updated, err = refreshOperatorPeer(file, current, []string{"h1", "h2"})
+	require.NoError(t, err)
+	assert.False(t, updated, "already current")
+}
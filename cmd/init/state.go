@@ -0,0 +1,349 @@
+package initcmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	internalssh "github.com/coollabsio/coolify-cli/internal/ssh"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+func (f *InitFlags) stateFile() string {
+	if f.StateFile != "" {
+		return f.StateFile
+	}
+	return common.DefaultStateFile()
+}
+
+// loadLedger reads the local ledger and, with --state-remote, the copies on
+// every server; the highest serial wins. Returns nil when none exists.
+func loadLedger(ctx context.Context, runner internalssh.Runner, flags *InitFlags) (*wireguard.Ledger, error) {
+	local, err := wireguard.LoadLedger(flags.stateFile())
+	if err != nil {
+		return nil, err
+	}
+	if !flags.StateRemote || runner == nil {
+		return local, nil
+	}
+	remote, err := wireguard.FetchRemoteLedger(ctx, runner, flags.Servers,
+		flags.SSHUser, flags.SSHPort, flags.Concurrency)
+	if err != nil {
+		if local == nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return wireguard.NewestLedger(local, remote), nil
+}
+
+// saveLedger writes the ledger locally and, with --state-remote, to hosts.
+func saveLedger(ctx context.Context, runner internalssh.Runner, flags *InitFlags, hosts []string, l *wireguard.Ledger) error {
+	if err := l.Save(flags.stateFile()); err != nil {
+		return err
+	}
+	if flags.StateRemote && runner != nil {
+		return wireguard.PushLedger(ctx, runner, hosts, flags.SSHUser, flags.SSHPort, flags.Concurrency, l)
+	}
+	return nil
+}
+
+// reconcileLedger loads the ledger and attaches it to current so hosts that
+// did not answer the probe keep their recorded allocations. Conflicts are
+// returned as plan warnings.
+func reconcileLedger(ctx context.Context, runner internalssh.Runner, flags *InitFlags, current *wireguard.MeshState) (*wireguard.Ledger, []wireguard.Warning, error) {
+	ledger, err := loadLedger(ctx, runner, flags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mesh state: %w", err)
+	}
+	conflicts := wireguard.ReconcileLedger(current, ledger)
+	warns := make([]wireguard.Warning, len(conflicts))
+	for i, c := range conflicts {
+		warns[i] = wireguard.Warning{
+			Host:   c.Host,
+			Reason: fmt.Sprintf("mesh state conflict: %s recorded as %s but probed as %s; the probed value wins", c.Field, c.Recorded, c.Probed),
+		}
+	}
+	return ledger, warns, nil
+}
+
+// recordLedger re-probes the mesh after apply and persists what is now on
+// the hosts.
+func recordLedger(ctx context.Context, runner internalssh.Runner, flags *InitFlags, desired *wireguard.DesiredMesh, ledger *wireguard.Ledger) error {
+	fresh, err := wireguard.Reconstruct(ctx, runner, desired.Hosts,
+		flags.SSHUser, flags.SSHPort, desired.Interface, desired.Namespaces, flags.Concurrency)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if ledger == nil {
+		ledger = wireguard.NewLedger()
+	}
+	ledger.Interface = desired.Interface
+	ledger.MgmtPool = desired.MgmtPool.String()
+	ledger.ContainerPool = desired.ContainerPool.String()
+	ledger.ContainerPool6 = ""
+	if desired.ContainerPool6 != nil {
+		ledger.ContainerPool6 = desired.ContainerPool6.String()
+	}
+	ledger.Record(fresh, desired.Hosts, desired.RemoveHosts)
+	return saveLedger(ctx, runner, flags, desired.Hosts, ledger)
+}
+
+// NewStateCommand creates the `coolify init state` parent command.
+func NewStateCommand(flags *InitFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and move the persisted mesh state",
+		Long: `The mesh state ledger records each host's mgmt IP, container subnets,
+WireGuard public key and agent versions. It is written after every
+bootstrap/extend/upgrade/remove-host and read before planning, so a host that
+is unreachable keeps its allocations instead of having them handed out again.
+When a probe disagrees with the ledger the probed value wins and the conflict
+is reported as a warning.
+
+The ledger lives in --state-file (default: mesh-state.json in the config
+directory). With --state-remote a copy is also kept on every server and the
+copy with the highest serial is used.`,
+	}
+	cmd.AddCommand(newStateShowCommand(flags))
+	cmd.AddCommand(newStateExportCommand(flags))
+	cmd.AddCommand(newStateImportCommand(flags))
+	return cmd
+}
+
+// stateRunner builds an SSH client only when the command needs one.
+func stateRunner(flags *InitFlags, needed bool) (*internalssh.Client, error) {
+	if !needed {
+		return nil, nil
+	}
+	if err := flags.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := flags.BuildSSHClient()
+	if err != nil {
+		return nil, fmt.Errorf("SSH client: %w", err)
+	}
+	return client, nil
+}
+
+func newStateShowCommand(flags *InitFlags) *cobra.Command {
+	var probe bool
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the recorded mesh state",
+		Long: `Show the recorded mesh state. With --probe every host in --servers is
+probed and any disagreement with the ledger is listed; the command then exits
+non-zero when conflicts were found.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			client, err := stateRunner(flags, probe || flags.StateRemote)
+			if err != nil {
+				return err
+			}
+			var runner internalssh.Runner
+			if client != nil {
+				defer client.Close()
+				runner = client
+			}
+
+			ledger, err := loadLedger(ctx, runner, flags)
+			if err != nil {
+				return err
+			}
+			if ledger == nil {
+				fmt.Fprintf(os.Stderr, "No mesh state recorded in %s.\n", flags.stateFile())
+				return nil
+			}
+
+			format, _ := cmd.Root().PersistentFlags().GetString("format")
+			formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+			if err != nil {
+				return err
+			}
+			if format == output.FormatJSON || format == output.FormatPretty {
+				err = formatter.Format(ledger)
+			} else {
+				fmt.Fprintf(os.Stderr, "Mesh state serial %d, updated %s\n", ledger.Serial, ledger.UpdatedAt.Format(time.RFC3339))
+				err = formatter.Format(ledgerRows(ledger))
+			}
+			if err != nil || !probe {
+				return err
+			}
+
+			current, probeErr := wireguard.Reconstruct(ctx, client, flags.Servers,
+				flags.SSHUser, flags.SSHPort, flags.WGInterface, flags.Namespaces, flags.Concurrency)
+			if probeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", probeErr)
+			}
+			conflicts := wireguard.ReconcileLedger(&current, ledger)
+			if len(conflicts) == 0 {
+				fmt.Fprintln(os.Stderr, "No conflicts between the mesh state and the probed hosts.")
+				return nil
+			}
+			fmt.Fprintln(os.Stderr, "Conflicts:")
+			for _, c := range conflicts {
+				fmt.Fprintf(os.Stderr, "  %s\n", c)
+			}
+			return fmt.Errorf("%d mesh state conflict(s)", len(conflicts))
+		},
+	}
+	cmd.Flags().BoolVar(&probe, "probe", false, "Probe --servers and report conflicts with the recorded state")
+	return cmd
+}
+
+// ledgerRows flattens the ledger into one table row per host.
+func ledgerRows(l *wireguard.Ledger) []models.MeshStateRow {
+	hosts := map[string]struct{}{}
+	for h := range l.Hosts {
+		hosts[h] = struct{}{}
+	}
+	for _, byHost := range l.Subnets {
+		for h := range byHost {
+			hosts[h] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(hosts))
+	for h := range hosts {
+		names = append(names, h)
+	}
+	sort.Strings(names)
+
+	namespaces := make([]string, 0, len(l.Subnets))
+	for ns := range l.Subnets {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	rows := make([]models.MeshStateRow, 0, len(names))
+	for _, h := range names {
+		row := models.MeshStateRow{Server: h}
+		if rec := l.Hosts[h]; rec != nil {
+			row.MgmtIP = rec.MgmtIP
+			row.PublicKey = rec.PublicKey
+			row.CooldVersion = rec.CooldVersion
+			row.CorrosionVersion = rec.CorrosionVersion
+		}
+		var subnets []string
+		for _, ns := range namespaces {
+			if sn := l.Subnets[ns][h]; sn != "" {
+				subnets = append(subnets, ns+"="+sn)
+			}
+		}
+		row.Subnets = strings.Join(subnets, ",")
+		rows = append(rows, row)
+	}
+	if op := l.Operator; op != nil {
+		rows = append(rows, models.MeshStateRow{Server: wireguard.OperatorLedgerKey, MgmtIP: op.MgmtIP, PublicKey: op.PublicKey})
+	}
+	return rows
+}
+
+func newStateExportCommand(flags *InitFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "export [file]",
+		Short: "Write the mesh state as JSON to a file or stdout",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := stateRunner(flags, flags.StateRemote)
+			if err != nil {
+				return err
+			}
+			var runner internalssh.Runner
+			if client != nil {
+				defer client.Close()
+				runner = client
+			}
+			ledger, err := loadLedger(cmd.Context(), runner, flags)
+			if err != nil {
+				return err
+			}
+			if ledger == nil {
+				return fmt.Errorf("no mesh state recorded in %s", flags.stateFile())
+			}
+			data, err := ledger.Marshal()
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 || args[0] == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := os.WriteFile(args[0], data, 0o600); err != nil {
+				return fmt.Errorf("write %s: %w", args[0], err)
+			}
+			fmt.Fprintf(os.Stderr, "Exported mesh state (serial %d) to %s\n", ledger.Serial, args[0])
+			return nil
+		},
+	}
+}
+
+func newStateImportCommand(flags *InitFlags) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Replace the mesh state with a previously exported file",
+		Long: `Replace the recorded mesh state with an exported file (use - for stdin).
+The file is validated first: duplicate mgmt IPs and overlapping subnets are
+rejected. Importing a file older than the current state requires --force.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				data []byte
+				err  error
+			)
+			if args[0] == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return fmt.Errorf("read %s: %w", args[0], err)
+			}
+			imported, err := wireguard.ParseLedger(data)
+			if err != nil {
+				return err
+			}
+
+			client, err := stateRunner(flags, flags.StateRemote)
+			if err != nil {
+				return err
+			}
+			var runner internalssh.Runner
+			if client != nil {
+				defer client.Close()
+				runner = client
+			}
+			existing, err := loadLedger(cmd.Context(), runner, flags)
+			if err != nil && !force {
+				return fmt.Errorf("%w (pass --force to overwrite it)", err)
+			}
+			if existing != nil {
+				if existing.Serial > imported.Serial && !force {
+					return fmt.Errorf("the current mesh state (serial %d) is newer than %s (serial %d); pass --force to overwrite it",
+						existing.Serial, args[0], imported.Serial)
+				}
+				imported.Serial = max(imported.Serial, existing.Serial)
+			}
+			imported.Serial++
+			imported.UpdatedAt = time.Now().UTC()
+
+			if err := saveLedger(cmd.Context(), runner, flags, flags.Servers, imported); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Imported mesh state (%d host(s), serial %d) into %s\n",
+				len(imported.Hosts), imported.Serial, flags.stateFile())
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite a newer or unreadable mesh state")
+	return cmd
+}
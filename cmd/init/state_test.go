@@ -0,0 +1,64 @@
+package initcmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+func writeLedger(t *testing.T, path string, serial int64) {
+	t.Helper()
+	l := wireguard.NewLedger()
+	l.Serial = serial
+	l.Hosts["10.0.0.1"] = &wireguard.LedgerHost{MgmtIP: "100.64.0.1", PublicKey: "AAAAAAAA="}
+	l.Subnets["default"] = map[string]string{"10.0.0.1": "10.210.0.0/24"}
+	require.NoError(t, l.Save(path))
+}
+
+func TestStateImportExport(t *testing.T) {
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "mesh-state.json")
+	older := filepath.Join(dir, "older.json")
+	writeLedger(t, stateFile, 5)
+	writeLedger(t, older, 2)
+
+	run := func(args ...string) error {
+		cmd := NewInitCommand()
+		cmd.SetArgs(append(args, "--state-file", stateFile))
+		return cmd.Execute()
+	}
+
+	err := run("state", "import", older)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "newer")
+
+	require.NoError(t, run("state", "import", older, "--force"))
+	l, err := wireguard.LoadLedger(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), l.Serial, "an import always moves the serial forward")
+
+	exported := filepath.Join(dir, "exported.json")
+	require.NoError(t, run("state", "export", exported))
+	data, err := os.ReadFile(exported)
+	require.NoError(t, err)
+	back, err := wireguard.ParseLedger(data)
+	require.NoError(t, err)
+	assert.Equal(t, l.Hosts, back.Hosts)
+}
+
+func TestStateImport_RejectsInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	require.NoError(t, os.WriteFile(bad, []byte(`{"version":1,"hosts":{"a":{"mgmt_ip":"100.64.0.1"},"b":{"mgmt_ip":"100.64.0.1"}}}`), 0o600))
+
+	cmd := NewInitCommand()
+	cmd.SetArgs([]string{"state", "import", bad, "--state-file", filepath.Join(dir, "s.json")})
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "recorded for both")
+}
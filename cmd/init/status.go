@@ -0,0 +1,217 @@
+package initcmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// NewStatusCommand creates the `coolify init status` subcommand: a read-only
+// health check of a running mesh.
+func NewStatusCommand(flags *InitFlags) *cobra.Command {
+	var staleAfter time.Duration
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Check mesh health: handshakes, reachability and agent units",
+		Long: `Check the health of a running mesh without changing anything.
+
+Every host in --servers reports its WireGuard peers (with the latest
+handshake from wg show dump) and the state of the coold, corrosion and
+scheduler units, then pings the mgmt IP of every other host over wg0. The
+result is an N×N matrix: each row is one host's view of the others.
+
+  ok 42s     peered, handshake 42s ago, ping answered
+  stale 9m   handshake older than --stale-after
+  no-hs      peered but no handshake has ever completed
+  no-peer    the other host's key is not a peer
+  +ping!     the mgmt IP did not answer a ping
+
+The first line of the table output is a one-line summary, and the exit code
+follows the Nagios plugin convention so the command can run from cron or as
+a check: 0 OK, 1 WARNING (stale handshakes), 2 CRITICAL (a host unreachable,
+a missing peer, a failed ping or a failed unit), 3 UNKNOWN (the check could
+not run). A unit without a unit file is ignored, except the scheduler on
+--central.`,
+		Example: `  coolify init status --servers 10.0.0.1,10.0.0.2,10.0.0.3 --central 10.0.0.1
+  coolify init status --servers 10.0.0.1,10.0.0.2 --stale-after 10m --format json`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := validatePlanFlags(flags); err != nil {
+				return &common.ExitError{Code: int(wireguard.SeverityUnknown), Err: err}
+			}
+			sshClient, err := flags.BuildSSHClient()
+			if err != nil {
+				return &common.ExitError{Code: int(wireguard.SeverityUnknown), Err: fmt.Errorf("SSH client: %w", err)}
+			}
+			defer sshClient.Close()
+
+			status := wireguard.CheckStatus(cmd.Context(), sshClient, flags.Servers,
+				flags.SSHUser, flags.SSHPort, flags.WGInterface, flags.Concurrency)
+			problems := status.Evaluate(flags.CentralHost, staleAfter)
+			severity := wireguard.WorstSeverity(problems)
+
+			format, _ := cmd.Root().PersistentFlags().GetString("format")
+			if err := renderStatus(os.Stdout, format, &status, problems, staleAfter); err != nil {
+				return &common.ExitError{Code: int(wireguard.SeverityUnknown), Err: err}
+			}
+			if severity == wireguard.SeverityOK {
+				return nil
+			}
+			return &common.ExitError{
+				Code: int(severity),
+				Err:  fmt.Errorf("mesh %s: %d problem(s)", severity, len(problems)),
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&staleAfter, "stale-after", wireguard.DefaultStaleHandshake,
+		"Warn when a peer's latest handshake is older than this")
+	return cmd
+}
+
+// renderStatus writes the status as JSON, or as a summary line, the host
+// table, the reachability matrix and the problem list.
+func renderStatus(w io.Writer, format string, status *wireguard.MeshStatus, problems []wireguard.StatusProblem, staleAfter time.Duration) error {
+	formatter, err := output.NewFormatter(format, output.Options{Writer: w})
+	if err != nil {
+		return err
+	}
+	out := statusOutput(status, problems, staleAfter)
+	if format == output.FormatJSON || format == output.FormatPretty {
+		return formatter.Format(out)
+	}
+
+	fmt.Fprintf(w, "MESH %s - %d host(s), %d problem(s)\n\n", out.Status, len(out.Hosts), len(out.Problems))
+	if err := formatter.Format(out.Hosts); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	writeMatrix(w, status, staleAfter)
+	if len(out.Problems) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	return formatter.Format(out.Problems)
+}
+
+func statusOutput(status *wireguard.MeshStatus, problems []wireguard.StatusProblem, staleAfter time.Duration) models.MeshStatusOutput {
+	out := models.MeshStatusOutput{
+		Status:   wireguard.WorstSeverity(problems).String(),
+		Hosts:    []models.MeshHostStatusRow{},
+		Links:    []models.MeshLinkRow{},
+		Problems: []models.MeshProblemRow{},
+	}
+	for _, h := range status.Hosts {
+		row := models.MeshHostStatusRow{Server: h.Host, WireGuard: "down", Peers: len(h.Peers)}
+		switch {
+		case h.Err != nil:
+			row.WireGuard = "unreachable"
+		case h.Active:
+			row.WireGuard = "up"
+		}
+		if h.MgmtIP != nil {
+			row.MgmtIP = h.MgmtIP.String()
+		}
+		row.Coold = h.Units["coold"]
+		row.Corrosion = h.Units["corrosion"]
+		row.Scheduler = h.Units["scheduler"]
+		out.Hosts = append(out.Hosts, row)
+
+		for _, o := range status.Hosts {
+			if o.Host == h.Host {
+				continue
+			}
+			link := status.Link(h.Host, o.Host)
+			lr := models.MeshLinkRow{
+				From:                h.Host,
+				To:                  o.Host,
+				Peered:              link.Peered,
+				HandshakeAgeSeconds: -1,
+				Status:              linkCell(status, link, staleAfter),
+			}
+			if link.HandshakeAge > 0 {
+				lr.HandshakeAgeSeconds = int64(link.HandshakeAge / time.Second)
+			}
+			if link.Pinged {
+				reachable := link.Reachable
+				lr.Reachable = &reachable
+			}
+			out.Links = append(out.Links, lr)
+		}
+	}
+	for _, p := range problems {
+		out.Problems = append(out.Problems, models.MeshProblemRow{
+			Server:   p.Host,
+			Severity: p.Severity.String(),
+			Message:  p.Message,
+		})
+	}
+	return out
+}
+
+// linkCell renders one matrix cell; the legend is in the status help text.
+func linkCell(status *wireguard.MeshStatus, link wireguard.LinkStatus, staleAfter time.Duration) string {
+	from, to := status.Host(link.From), status.Host(link.To)
+	if from == nil || to == nil || from.Err != nil || !from.Active || to.PublicKey == "" {
+		return "?"
+	}
+	var cell string
+	switch {
+	case !link.Peered:
+		return "no-peer"
+	case link.HandshakeAge == 0:
+		cell = "no-hs"
+	case link.HandshakeAge > staleAfter:
+		cell = "stale " + shortDuration(link.HandshakeAge)
+	default:
+		cell = "ok " + shortDuration(link.HandshakeAge)
+	}
+	if link.Pinged && !link.Reachable {
+		cell += " +ping!"
+	}
+	return cell
+}
+
+// shortDuration renders d with a single unit: 42s, 9m, 3h, 2d.
+func shortDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+}
+
+// writeMatrix prints the N×N reachability matrix; rows are the viewing host.
+func writeMatrix(w io.Writer, status *wireguard.MeshStatus, staleAfter time.Duration) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	hosts := make([]string, len(status.Hosts))
+	for i, h := range status.Hosts {
+		hosts[i] = h.Host
+	}
+	fmt.Fprintf(tw, "from \\ to\t%s\n", strings.Join(hosts, "\t"))
+	for _, from := range hosts {
+		cells := make([]string, len(hosts))
+		for i, to := range hosts {
+			if from == to {
+				cells[i] = "-"
+				continue
+			}
+			cells[i] = linkCell(status, status.Link(from, to), staleAfter)
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", from, strings.Join(cells, "\t"))
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,65 @@
+package initcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+func sampleMeshStatus() *wireguard.MeshStatus {
+	up := map[string]string{"coold": "active", "corrosion": "active", "scheduler": wireguard.UnitNotInstalled}
+	return &wireguard.MeshStatus{Hosts: []wireguard.HostStatus{
+		{
+			Host: "h1", PublicKey: "PUB1=", MgmtIP: net.ParseIP("100.64.0.1").To4(), Active: true, Now: 1000,
+			Peers: []wireguard.Peer{{PublicKey: "PUB2=", LatestHandshake: 400}},
+			Units: up, Reach: map[string]bool{"h2": false},
+		},
+		{
+			Host: "h2", PublicKey: "PUB2=", MgmtIP: net.ParseIP("100.64.0.2").To4(), Active: true, Now: 1000,
+			Peers: []wireguard.Peer{{PublicKey: "PUB1=", LatestHandshake: 990}},
+			Units: up, Reach: map[string]bool{"h1": true},
+		},
+		{Host: "h3", Err: errors.New("dial tcp: timeout")},
+	}}
+}
+
+func TestRenderStatus_Table(t *testing.T) {
+	status := sampleMeshStatus()
+	problems := status.Evaluate("", wireguard.DefaultStaleHandshake)
+
+	var buf bytes.Buffer
+	require.NoError(t, renderStatus(&buf, output.FormatTable, status, problems, wireguard.DefaultStaleHandshake))
+	out := buf.String()
+	assert.Contains(t, out, "MESH CRITICAL - 3 host(s), 3 problem(s)\n")
+	assert.Contains(t, out, "stale 10m +ping!")
+	assert.Contains(t, out, "ok 10s")
+	assert.Contains(t, out, "unreachable")
+}
+
+func TestRenderStatus_JSON(t *testing.T) {
+	status := sampleMeshStatus()
+	problems := status.Evaluate("", wireguard.DefaultStaleHandshake)
+
+	var buf bytes.Buffer
+	require.NoError(t, renderStatus(&buf, output.FormatJSON, status, problems, wireguard.DefaultStaleHandshake))
+	var got models.MeshStatusOutput
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+
+	assert.Equal(t, "CRITICAL", got.Status)
+	assert.Len(t, got.Links, 6)
+	h1h2 := got.Links[0]
+	assert.Equal(t, "h2", h1h2.To)
+	assert.Equal(t, int64(600), h1h2.HandshakeAgeSeconds)
+	require.NotNil(t, h1h2.Reachable)
+	assert.False(t, *h1h2.Reachable)
+	assert.Equal(t, "?", got.Links[1].Status, "h3 was not probed")
+}
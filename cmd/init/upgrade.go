@@ -7,17 +7,22 @@ import (
 )
 
 // NewUpgradeCommand creates the `coolify init upgrade` subcommand: bumps
-// coold/corrosion/scheduler/builder binaries across every host. Does not touch
-// WG config, podman networks, firewall rules, or the corrosion schema. Rejects
-// "nightly" version tags unless --allow-nightly is set.
+// coold/corrosion/scheduler/builder binaries across every host and applies
+// pending corrosion schema migrations. Does not touch WG config, podman
+// networks, or firewall rules. Rejects "nightly" version tags unless
+// --allow-nightly is set.
 func NewUpgradeCommand(flags *InitFlags) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "upgrade",
 		Short: "Bump agent binary versions (coold / corrosion / scheduler / builder) on every host",
 		Long: `Upgrade the agent binaries managed by coolify init across every host in
---servers. Only binary-fetch actions and their follow-up service restarts
-run; WG config, podman networks, firewall rules, and the corrosion schema
-are left untouched.
+--servers. Only binary-fetch actions, their follow-up service restarts and
+pending corrosion schema migrations run; WG config, podman networks and
+firewall rules are left untouched.
+
+Schema migrations are additive (new tables, new columns with defaults) and
+are applied to the running corrosion DB, so replicated rows are kept. A host
+whose schema is not a revision this CLI knows is reported and not reset.
 
 Pin each binary with --coold-version / --corrosion-version /
 --scheduler-version. "nightly" is rejected by default because it forces a
@@ -0,0 +1,486 @@
+package mesh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/corrosion"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// healthValues are the values coold writes to service_endpoints.health.
+var healthValues = []string{"healthy", "unhealthy", "starting", "unknown"}
+
+// newEndpointsCommand builds `coolify mesh endpoints` and its subcommands.
+func newEndpointsCommand(flags *Flags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "endpoints",
+		Short: "Inspect the service endpoints replicated through Corrosion",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+	cmd.AddCommand(newEndpointsListCommand(flags))
+	cmd.AddCommand(newEndpointsWatchCommand(flags))
+	cmd.AddCommand(newEndpointsCheckCommand(flags))
+	return cmd
+}
+
+// endpointFilter selects the rows `list` and `watch` show.
+type endpointFilter struct {
+	Namespace string
+	States    []string
+	Health    []string
+	// From is the server whose replica is read.
+	From string
+}
+
+func bindEndpointFilter(cmd *cobra.Command, f *endpointFilter) {
+	cmd.Flags().StringVar(&f.Namespace, "namespace", "", "Only endpoints in this namespace")
+	cmd.Flags().StringSliceVar(&f.States, "state", nil, "Only endpoints in these container states (e.g. running,exited)")
+	cmd.Flags().StringSliceVar(&f.Health, "health", nil,
+		"Only endpoints with these health values ("+strings.Join(healthValues, ", ")+")")
+	cmd.Flags().StringVar(&f.From, "from", "",
+		"Server whose replica is read (default: the first of --servers that answers)")
+}
+
+func (f *endpointFilter) validate(flags *Flags) error {
+	for _, h := range f.Health {
+		if !slices.Contains(healthValues, h) {
+			return fmt.Errorf("--health %q must be one of %s", h, strings.Join(healthValues, ", "))
+		}
+	}
+	if f.From != "" && !slices.Contains(flags.Servers, f.From) {
+		return fmt.Errorf("--from %s is not one of --servers", f.From)
+	}
+	return nil
+}
+
+func (f *endpointFilter) apply(eps []corrosion.Endpoint) []corrosion.Endpoint {
+	out := make([]corrosion.Endpoint, 0, len(eps))
+	for _, ep := range eps {
+		if f.Namespace != "" && namespaceOf(ep) != f.Namespace {
+			continue
+		}
+		if len(f.States) > 0 && !slices.Contains(f.States, ep.State) {
+			continue
+		}
+		if len(f.Health) > 0 && !slices.Contains(f.Health, ep.Health) {
+			continue
+		}
+		out = append(out, ep)
+	}
+	return out
+}
+
+// namespaceOf returns ep's namespace; coold writes "" in single-tenant
+// deployments, which is the default namespace.
+func namespaceOf(ep corrosion.Endpoint) string {
+	if ep.Namespace == "" {
+		return common.DefaultNamespace
+	}
+	return ep.Namespace
+}
+
+// serverNamer maps a host_mgmt_ip to the server in --servers that holds it,
+// falling back to the IP itself.
+func serverNamer(ctx context.Context, runner ssh.Runner, flags *Flags) func(mgmtIP string) string {
+	hostFor := common.HostsByMgmtIP(ctx, runner, &flags.SSHMeshFlags, flags.WGInterface)
+	return func(mgmtIP string) string {
+		if h, ok := hostFor(mgmtIP); ok {
+			return h
+		}
+		return mgmtIP
+	}
+}
+
+func newEndpointsListCommand(flags *Flags) *cobra.Command {
+	local := &endpointFilter{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the service endpoints in Corrosion",
+		Long: `List the rows of Corrosion's service_endpoints table: every container coold
+published, with the server it runs on, its IP on the namespace bridge, its
+podman state and its healthcheck result. The table is replicated, so it is
+read from a single server.`,
+		Example: `  coolify mesh endpoints list --servers 10.0.0.1,10.0.0.2
+  coolify mesh endpoints list --servers 10.0.0.1 --namespace alpha --health unhealthy,starting`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := local.validate(flags); err != nil {
+				return err
+			}
+			return withRunner(flags, func(runner ssh.Runner) error {
+				return emitEndpointsList(cmd.Context(), cmd, flags, local, runner, time.Now())
+			})
+		},
+	}
+	bindEndpointFilter(cmd, local)
+	return cmd
+}
+
+// emitEndpointsList is factored out so tests can pass a fake runner.
+func emitEndpointsList(
+	ctx context.Context,
+	cmd *cobra.Command,
+	flags *Flags,
+	local *endpointFilter,
+	runner ssh.Runner,
+	now time.Time,
+) error {
+	source, eps, err := readEndpoints(ctx, runner, flags, local.From)
+	if err != nil {
+		return err
+	}
+	serverFor := serverNamer(ctx, runner, flags)
+	rows := []models.MeshEndpointRow{}
+	for _, ep := range local.apply(eps) {
+		rows = append(rows, models.MeshEndpointRow{
+			Server:    serverFor(ep.HostMgmtIP),
+			Namespace: namespaceOf(ep),
+			Name:      ep.Name,
+			ID:        shortID(ep.ContainerID),
+			IP:        ep.ContainerIP,
+			State:     ep.State,
+			Health:    ep.Health,
+			Updated:   age(now, ep.UpdatedAt),
+		})
+	}
+	return emit(cmd, models.MeshEndpointsOutput{Source: source, Endpoints: rows}, rows, len(rows),
+		"No service endpoints found.")
+}
+
+// watchFlags are the per-subcommand flags for `mesh endpoints watch`.
+type watchFlags struct {
+	endpointFilter
+	Interval time.Duration
+}
+
+func newEndpointsWatchCommand(flags *Flags) *cobra.Command {
+	local := &watchFlags{}
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Print service endpoint changes as they replicate",
+		Long: `Poll one server's replica of service_endpoints every --interval and print
+each row that was added, removed or changed. The first poll reports every
+current row as added. coold's periodic resync only bumps updated_at and is
+not reported. A row that stops matching the filters is reported as removed.
+
+With --format json every change is printed as one JSON object per line.
+Stop with Ctrl-C.`,
+		Example: `  coolify mesh endpoints watch --servers 10.0.0.1 --namespace alpha
+  coolify mesh endpoints watch --servers 10.0.0.1 --format json | jq .`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := local.validate(flags); err != nil {
+				return err
+			}
+			if local.Interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return withRunner(flags, func(runner ssh.Runner) error {
+				return watchEndpoints(ctx, cmd, flags, local, runner, os.Stdout)
+			})
+		},
+	}
+	bindEndpointFilter(cmd, &local.endpointFilter)
+	cmd.Flags().DurationVar(&local.Interval, "interval", 2*time.Second, "How often the replica is read")
+	return cmd
+}
+
+// watchEndpoints polls until ctx is done. A failed read is reported and
+// the next poll compares against the last good snapshot.
+func watchEndpoints(
+	ctx context.Context,
+	cmd *cobra.Command,
+	flags *Flags,
+	local *watchFlags,
+	runner ssh.Runner,
+	w io.Writer,
+) error {
+	var enc *json.Encoder
+	if format := formatOf(cmd); format == output.FormatJSON || format == output.FormatPretty {
+		enc = json.NewEncoder(w)
+	}
+	serverFor := serverNamer(ctx, runner, flags)
+	ticker := time.NewTicker(local.Interval)
+	defer ticker.Stop()
+
+	var prev []corrosion.Endpoint
+	for {
+		_, eps, err := readEndpoints(ctx, runner, flags, local.From)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			fmt.Fprintln(os.Stderr, "Warning:", err)
+		default:
+			eps = local.apply(eps)
+			now := time.Now().Format(time.TimeOnly)
+			for _, ch := range corrosion.Diff(prev, eps) {
+				row := changeRow(now, ch, serverFor)
+				if enc != nil {
+					if err := enc.Encode(row); err != nil {
+						return err
+					}
+					continue
+				}
+				fmt.Fprintf(w, "%s  %-7s  %s/%s  %s  %s  %s\n",
+					row.Time, row.Change, row.Namespace, row.Name, row.ID, row.Server, row.Detail)
+			}
+			prev = eps
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// changeRow renders one change for `watch`.
+func changeRow(now string, ch corrosion.Change, serverFor func(string) string) models.MeshEndpointChangeRow {
+	ep := ch.New
+	if ch.Kind == corrosion.ChangeRemoved {
+		ep = ch.Old
+	}
+	row := models.MeshEndpointChangeRow{
+		Time:      now,
+		Change:    ch.Kind,
+		Namespace: namespaceOf(ep),
+		Name:      ep.Name,
+		ID:        shortID(ep.ContainerID),
+		Server:    serverFor(ep.HostMgmtIP),
+	}
+	if ch.Kind != corrosion.ChangeUpdated {
+		row.Detail = fmt.Sprintf("%s %s/%s", ep.ContainerIP, ep.State, ep.Health)
+		return row
+	}
+	details := make([]string, 0, len(ch.Fields))
+	for _, f := range ch.Fields {
+		details = append(details, fmt.Sprintf("%s %s→%s", f, fieldValue(ch.Old, f), fieldValue(ch.New, f)))
+	}
+	row.Detail = strings.Join(details, ", ")
+	return row
+}
+
+// fieldValue returns column f of ep, for the fields Diff reports.
+func fieldValue(ep corrosion.Endpoint, f string) string {
+	switch f {
+	case "container_name":
+		return ep.Name
+	case "namespace":
+		return namespaceOf(ep)
+	case "host_mgmt_ip":
+		return ep.HostMgmtIP
+	case "container_ip":
+		return ep.ContainerIP
+	case "state":
+		return ep.State
+	case "health":
+		return ep.Health
+	}
+	return ""
+}
+
+// checkFlags are the per-subcommand flags for `mesh endpoints check`.
+type checkFlags struct {
+	Namespace string
+	Settle    time.Duration
+}
+
+func newEndpointsCheckCommand(flags *Flags) *cobra.Command {
+	local := &checkFlags{}
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Compare the service endpoints replicas of every server",
+		Long: `Read service_endpoints from the Corrosion replica of every server in
+--servers and report the rows they disagree on: a row missing from some
+replicas, or a replica holding an older version of it than the newest one
+(by updated_at, which Corrosion's last-write-wins merge converges on).
+
+Replication takes a moment, so rows found inconsistent are read again after
+--settle and only the ones that still differ are reported.
+
+The exit code follows the Nagios plugin convention, like "coolify init
+status": 0 OK, 1 WARNING (a server could not be read), 2 CRITICAL (the
+replicas disagree), 3 UNKNOWN (fewer than two replicas could be read).`,
+		Example: `  coolify mesh endpoints check --servers 10.0.0.1,10.0.0.2,10.0.0.3
+  coolify mesh endpoints check --servers 10.0.0.1,10.0.0.2 --settle 0 --format json`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := flags.Validate(); err != nil {
+				return &common.ExitError{Code: int(wireguard.SeverityUnknown), Err: err}
+			}
+			runner, err := flags.BuildSSHClient()
+			if err != nil {
+				return &common.ExitError{Code: int(wireguard.SeverityUnknown), Err: fmt.Errorf("SSH client: %w", err)}
+			}
+			defer runner.Close()
+			return emitEndpointsCheck(cmd.Context(), cmd, flags, local, runner)
+		},
+	}
+	cmd.Flags().StringVar(&local.Namespace, "namespace", "", "Only compare endpoints in this namespace")
+	cmd.Flags().DurationVar(&local.Settle, "settle", 5*time.Second,
+		"Wait this long and read again before reporting an inconsistency (0 reports the first read)")
+	return cmd
+}
+
+// readReplicas reads service_endpoints from every server.
+func readReplicas(
+	ctx context.Context,
+	runner ssh.Runner,
+	flags *Flags,
+	namespace string,
+) (map[string][]corrosion.Endpoint, []string) {
+	filter := &endpointFilter{Namespace: namespace}
+	results := ssh.ForEachServer(ctx, flags.Servers, flags.Concurrency,
+		func(ctx context.Context, host string) ([]corrosion.Endpoint, error) {
+			return corrosion.ListEndpoints(ctx, runner, host, flags.SSHUser,
+				flags.SSHPort, flags.CorrosionAPIPort)
+		})
+	replicas := map[string][]corrosion.Endpoint{}
+	var errs []string
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Host, r.Err))
+			continue
+		}
+		replicas[r.Host] = filter.apply(r.Result)
+	}
+	return replicas, errs
+}
+
+// emitEndpointsCheck is factored out so tests can pass a fake runner.
+func emitEndpointsCheck(
+	ctx context.Context,
+	cmd *cobra.Command,
+	flags *Flags,
+	local *checkFlags,
+	runner ssh.Runner,
+) error {
+	replicas, errs := readReplicas(ctx, runner, flags, local.Namespace)
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "Warning:", e)
+	}
+	if len(replicas) < 2 {
+		return &common.ExitError{
+			Code: int(wireguard.SeverityUnknown),
+			Err:  fmt.Errorf("need at least two replicas to compare, read %d", len(replicas)),
+		}
+	}
+	incs := corrosion.Compare(replicas)
+	if len(incs) > 0 && local.Settle > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(local.Settle):
+		}
+		again, _ := readReplicas(ctx, runner, flags, local.Namespace)
+		incs = persisting(incs, corrosion.Compare(again))
+	}
+
+	severity := wireguard.SeverityOK
+	switch {
+	case len(incs) > 0:
+		severity = wireguard.SeverityCritical
+	case len(errs) > 0:
+		severity = wireguard.SeverityWarning
+	}
+	out := models.MeshEndpointsCheckOutput{
+		Status:          severity.String(),
+		Replicas:        map[string]int{},
+		Inconsistencies: []models.MeshEndpointInconsistencyRow{},
+		Errors:          errs,
+	}
+	for host, eps := range replicas {
+		out.Replicas[host] = len(eps)
+	}
+	for _, inc := range incs {
+		out.Inconsistencies = append(out.Inconsistencies, models.MeshEndpointInconsistencyRow{
+			Namespace: namespaceOf(corrosion.Endpoint{Namespace: inc.Namespace}),
+			Name:      inc.Name,
+			ID:        shortID(inc.ContainerID),
+			Problem:   inc.Describe(),
+		})
+	}
+	if err := renderCheck(cmd, out); err != nil {
+		return &common.ExitError{Code: int(wireguard.SeverityUnknown), Err: err}
+	}
+	if severity == wireguard.SeverityOK {
+		return nil
+	}
+	return &common.ExitError{
+		Code: int(severity),
+		Err:  fmt.Errorf("service endpoints %s: %d inconsistent row(s), %d unreadable replica(s)", severity, len(incs), len(errs)),
+	}
+}
+
+// persisting keeps the inconsistencies of the second read whose rows were
+// already inconsistent in the first.
+func persisting(first, second []corrosion.Inconsistency) []corrosion.Inconsistency {
+	seen := make(map[string]bool, len(first))
+	for _, inc := range first {
+		seen[inc.ContainerID] = true
+	}
+	var out []corrosion.Inconsistency
+	for _, inc := range second {
+		if seen[inc.ContainerID] {
+			out = append(out, inc)
+		}
+	}
+	return out
+}
+
+// renderCheck prints the check as JSON, or as a summary line followed by
+// the inconsistent rows.
+func renderCheck(cmd *cobra.Command, out models.MeshEndpointsCheckOutput) error {
+	format := formatOf(cmd)
+	formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+	if err != nil {
+		return err
+	}
+	if format == output.FormatJSON || format == output.FormatPretty {
+		return formatter.Format(out)
+	}
+	fmt.Fprintf(os.Stdout, "ENDPOINTS %s - %d replica(s), %d inconsistent row(s)\n",
+		out.Status, len(out.Replicas), len(out.Inconsistencies))
+	if len(out.Inconsistencies) == 0 {
+		return nil
+	}
+	fmt.Fprintln(os.Stdout)
+	return formatter.Format(out.Inconsistencies)
+}
+
+// shortID truncates a container ID to the 12 characters podman shows.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// age renders how long ago unix time updated was, "-" when never.
+func age(now time.Time, updated int64) string {
+	if updated <= 0 {
+		return "-"
+	}
+	d := now.Sub(time.Unix(updated, 0)).Truncate(time.Second)
+	if d < 0 {
+		d = 0
+	}
+	return d.String() + " ago"
+}
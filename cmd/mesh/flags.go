@@ -0,0 +1,104 @@
+// Package mesh implements the `coolify mesh` command tree: read-only
+// inspection of the state the mesh hosts replicate through Corrosion.
+// Corrosion's API listens on each host's loopback, so it is queried over
+// SSH like `coolify firewall` does for names.
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/corrosion"
+	"github.com/coollabsio/coolify-cli/internal/output"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// Flags is the shared flag set for every `coolify mesh` subcommand.
+type Flags struct {
+	common.SSHMeshFlags
+	// CorrosionAPIPort is corrosion's loopback API port. Must match
+	// --corrosion-api-port used at `coolify init`.
+	CorrosionAPIPort int
+	// WGInterface is used to map host_mgmt_ip back to a server.
+	WGInterface string
+}
+
+// bindFlags registers the persistent flags on the parent command.
+func bindFlags(cmd *cobra.Command, f *Flags) {
+	common.BindSSHMeshFlags(cmd, &f.SSHMeshFlags)
+	pf := cmd.PersistentFlags()
+	pf.IntVar(&f.CorrosionAPIPort, "corrosion-api-port", corrosion.DefaultAPIPort,
+		"Corrosion's loopback API port on each host (must match --corrosion-api-port at init)")
+	pf.StringVar(&f.WGInterface, "wg-interface", "wg0",
+		"WireGuard interface name on remote hosts (must match --wg-interface at init)")
+}
+
+// withRunner validates the flags and opens the SSH client for emit.
+func withRunner(flags *Flags, emit func(runner ssh.Runner) error) error {
+	if err := flags.Validate(); err != nil {
+		return err
+	}
+	runner, err := flags.BuildSSHClient()
+	if err != nil {
+		return fmt.Errorf("SSH client: %w", err)
+	}
+	defer runner.Close()
+	return emit(runner)
+}
+
+// formatOf returns the --format of cmd's root, table by default.
+func formatOf(cmd *cobra.Command) string {
+	format, _ := cmd.Root().PersistentFlags().GetString("format")
+	if format == "" {
+		return output.FormatTable
+	}
+	return format
+}
+
+// emit prints full for --format json/pretty and rows otherwise. empty is
+// printed to stderr instead of an empty table.
+func emit(cmd *cobra.Command, full, rows any, n int, empty string) error {
+	format := formatOf(cmd)
+	formatter, err := output.NewFormatter(format, output.Options{Writer: os.Stdout})
+	if err != nil {
+		return err
+	}
+	if format == output.FormatJSON || format == output.FormatPretty {
+		return formatter.Format(full)
+	}
+	if n == 0 {
+		fmt.Fprintln(os.Stderr, empty)
+		return nil
+	}
+	return formatter.Format(rows)
+}
+
+// readEndpoints reads service_endpoints from from, or from the first server
+// that answers. Every replica converges on the same rows, so one is enough
+// outside of `check`.
+func readEndpoints(
+	ctx context.Context,
+	runner ssh.Runner,
+	flags *Flags,
+	from string,
+) (string, []corrosion.Endpoint, error) {
+	hosts := flags.Servers
+	if from != "" {
+		hosts = []string{from}
+	}
+	var errs []string
+	for _, host := range hosts {
+		eps, err := corrosion.ListEndpoints(ctx, runner, host, flags.SSHUser,
+			flags.SSHPort, flags.CorrosionAPIPort)
+		if err == nil {
+			return host, eps, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return "", nil, fmt.Errorf("read service_endpoints: %s", strings.Join(errs, "; "))
+}
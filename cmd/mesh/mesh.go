@@ -0,0 +1,37 @@
+package mesh
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewMeshCommand creates the parent `coolify mesh` command.
+// The command tree is kept for tests and future v5 work but is not registered
+// on the public root CLI.
+// On bare invocation (no subcommand) it prints help.
+func NewMeshCommand() *cobra.Command {
+	flags := &Flags{}
+
+	cmd := &cobra.Command{
+		Use:   "mesh",
+		Short: "[ALPHA] Inspect the state mesh hosts replicate through Corrosion (Coolify v5)",
+		Long: `[ALPHA] Read the Corrosion replica on the servers in --servers.
+Corrosion's API listens on each host's loopback, so every query is made
+over SSH with curl on the host.
+
+Subcommands:
+  endpoints list   Service endpoints coold published, filtered by namespace,
+                   state or health.
+  endpoints watch  Print endpoint changes as they replicate.
+  endpoints check  Compare the replicas of every server and report the rows
+                   they disagree on.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+
+	bindFlags(cmd, flags)
+
+	cmd.AddCommand(newEndpointsCommand(flags))
+
+	return cmd
+}
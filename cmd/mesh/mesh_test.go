@@ -0,0 +1,252 @@
+package mesh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/models"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+func TestNewMeshCommand_Subcommands(t *testing.T) {
+	cmd := NewMeshCommand()
+	assert.Equal(t, "mesh", cmd.Use)
+	require.Len(t, cmd.Commands(), 1)
+	endpoints := cmd.Commands()[0]
+	assert.Equal(t, "endpoints", endpoints.Name())
+	var subs []string
+	for _, s := range endpoints.Commands() {
+		subs = append(subs, s.Name())
+	}
+	assert.ElementsMatch(t, []string{"list", "watch", "check"}, subs)
+	for _, name := range []string{"servers", "ssh-key", "corrosion-api-port", "wg-interface"} {
+		assert.NotNil(t, cmd.PersistentFlags().Lookup(name), "missing --%s", name)
+	}
+}
+
+// replicaRunner stands in for the servers: each has a mgmt IP and a queue
+// of service_endpoints replicas, one per read; the last one repeats.
+// Servers without replicas fail.
+type replicaRunner struct {
+	mu       sync.Mutex
+	mgmtIPs  map[string]string
+	replicas map[string][][]row
+	reads    int
+	onRead   func(reads int)
+}
+
+// row is id, name, namespace, mgmt IP, container IP, state, health, updated_at.
+type row [8]any
+
+func (r *replicaRunner) Run(_ context.Context, host, _ string, _ int, cmd string) (string, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if strings.Contains(cmd, "ip -o addr show") {
+		return r.mgmtIPs[host] + "\n", "", nil
+	}
+	queue, ok := r.replicas[host]
+	if !ok {
+		return "", "", errors.New("connection refused")
+	}
+	rows := queue[0]
+	if len(queue) > 1 {
+		r.replicas[host] = queue[1:]
+	}
+	r.reads++
+	if r.onRead != nil {
+		r.onRead(r.reads)
+	}
+	var b strings.Builder
+	b.WriteString(`{"columns":["container_id"]}` + "\n")
+	for i, values := range rows {
+		data, _ := json.Marshal(values)
+		fmt.Fprintf(&b, `{"row":[%d,%s]}`+"\n", i+1, data)
+	}
+	b.WriteString(`{"eoq":{"time":0.001}}` + "\n")
+	return b.String(), "", nil
+}
+
+var _ ssh.Runner = (*replicaRunner)(nil)
+
+var (
+	web      = row{"aaaaaaaaaaaaaaaa", "web", "", "100.64.0.1", "10.210.0.2", "running", "healthy", 1000}
+	webSick  = row{"aaaaaaaaaaaaaaaa", "web", "", "100.64.0.1", "10.210.0.2", "running", "unhealthy", 1010}
+	db       = row{"bbbbbbbbbbbbbbbb", "db", "alpha", "100.64.0.2", "10.220.1.2", "running", "unknown", 1000}
+	dbExited = row{"bbbbbbbbbbbbbbbb", "db", "alpha", "100.64.0.2", "10.220.1.2", "exited", "unknown", 1020}
+)
+
+func testFlags(servers ...string) *Flags {
+	return &Flags{
+		SSHMeshFlags:     common.SSHMeshFlags{Servers: servers, SSHUser: "root", SSHPort: 22, Concurrency: 2},
+		CorrosionAPIPort: 8080, WGInterface: "wg0",
+	}
+}
+
+func mgmtIPs() map[string]string {
+	return map[string]string{"h1": "100.64.0.1", "h2": "100.64.0.2", "h3": "100.64.0.3"}
+}
+
+// capture runs fn under a root command with the given --format and returns
+// what it printed to stdout.
+func capture(t *testing.T, format string, fn func(cmd *cobra.Command) error) (string, error) {
+	t.Helper()
+	inner := &cobra.Command{Use: "inner"}
+	root := &cobra.Command{Use: "coolify"}
+	root.PersistentFlags().String("format", format, "")
+	root.AddCommand(inner)
+
+	read, write, err := os.Pipe()
+	require.NoError(t, err)
+	originalStdout := os.Stdout
+	os.Stdout = write
+	t.Cleanup(func() { os.Stdout = originalStdout })
+
+	runErr := fn(inner)
+	require.NoError(t, write.Close())
+	os.Stdout = originalStdout
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, read)
+	require.NoError(t, err)
+	return buf.String(), runErr
+}
+
+func TestEmitEndpointsList_FiltersAndNamesServers(t *testing.T) {
+	runner := &replicaRunner{mgmtIPs: mgmtIPs(), replicas: map[string][][]row{"h2": {{web, db}}}}
+	flags := testFlags("h1", "h2")
+	now := time.Unix(1030, 0)
+
+	out, err := capture(t, "json", func(cmd *cobra.Command) error {
+		return emitEndpointsList(context.Background(), cmd, flags, &endpointFilter{}, runner, now)
+	})
+	require.NoError(t, err)
+	var got models.MeshEndpointsOutput
+	require.NoError(t, json.Unmarshal([]byte(out), &got), out)
+	assert.Equal(t, "h2", got.Source, "h1 has no replica, so h2 is read")
+	require.Len(t, got.Endpoints, 2)
+	assert.Equal(t, models.MeshEndpointRow{
+		Server: "h1", Namespace: "default", Name: "web", ID: "aaaaaaaaaaaa",
+		IP: "10.210.0.2", State: "running", Health: "healthy", Updated: "30s ago",
+	}, got.Endpoints[0])
+
+	out, err = capture(t, "json", func(cmd *cobra.Command) error {
+		return emitEndpointsList(context.Background(), cmd, flags,
+			&endpointFilter{Namespace: "default", Health: []string{"unhealthy"}}, runner, now)
+	})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(out), &got), out)
+	assert.Empty(t, got.Endpoints)
+}
+
+func TestEndpointFilter_Validate(t *testing.T) {
+	flags := testFlags("h1")
+	assert.NoError(t, (&endpointFilter{Health: []string{"healthy", "starting"}}).validate(flags))
+	assert.ErrorContains(t, (&endpointFilter{Health: []string{"sick"}}).validate(flags), "--health")
+	assert.ErrorContains(t, (&endpointFilter{From: "h9"}).validate(flags), "not one of --servers")
+}
+
+func TestWatchEndpoints_PrintsChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runner := &replicaRunner{
+		mgmtIPs:  mgmtIPs(),
+		replicas: map[string][][]row{"h1": {{web}, {web}, {webSick, db}, {webSick}}},
+		onRead: func(reads int) {
+			if reads == 4 {
+				cancel()
+			}
+		},
+	}
+	flags := testFlags("h1", "h2")
+	local := &watchFlags{Interval: time.Millisecond}
+
+	inner := &cobra.Command{Use: "watch"}
+	root := &cobra.Command{Use: "coolify"}
+	root.PersistentFlags().String("format", "json", "")
+	root.AddCommand(inner)
+	var buf bytes.Buffer
+	require.NoError(t, watchEndpoints(ctx, inner, flags, local, runner, &buf))
+
+	var changes []models.MeshEndpointChangeRow
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var ch models.MeshEndpointChangeRow
+		require.NoError(t, dec.Decode(&ch))
+		changes = append(changes, ch)
+	}
+	require.Len(t, changes, 3, "the fourth read was interrupted")
+	assert.Equal(t, "added", changes[0].Change)
+	assert.Equal(t, "web", changes[0].Name)
+	assert.Equal(t, "h1", changes[0].Server)
+	assert.Equal(t, "updated", changes[1].Change)
+	assert.Equal(t, "health healthy→unhealthy", changes[1].Detail)
+	assert.Equal(t, "added", changes[2].Change)
+	assert.Equal(t, "db", changes[2].Name)
+	assert.Equal(t, "h2", changes[2].Server)
+}
+
+func TestEmitEndpointsCheck(t *testing.T) {
+	check := func(runner *replicaRunner, servers ...string) (models.MeshEndpointsCheckOutput, error) {
+		var out models.MeshEndpointsCheckOutput
+		printed, err := capture(t, "json", func(cmd *cobra.Command) error {
+			return emitEndpointsCheck(context.Background(), cmd, testFlags(servers...), &checkFlags{}, runner)
+		})
+		if printed != "" {
+			require.NoError(t, json.Unmarshal([]byte(printed), &out), printed)
+		}
+		return out, err
+	}
+
+	out, err := check(&replicaRunner{replicas: map[string][][]row{
+		"h1": {{web, db}}, "h2": {{web, db}},
+	}}, "h1", "h2")
+	require.NoError(t, err)
+	assert.Equal(t, "OK", out.Status)
+	assert.Equal(t, map[string]int{"h1": 2, "h2": 2}, out.Replicas)
+
+	out, err = check(&replicaRunner{replicas: map[string][][]row{
+		"h1": {{webSick, dbExited}}, "h2": {{web}}, "h3": {{webSick, dbExited}},
+	}}, "h1", "h2", "h3")
+	assert.Equal(t, 2, common.ExitCode(err))
+	assert.Equal(t, "CRITICAL", out.Status)
+	assert.Equal(t, []models.MeshEndpointInconsistencyRow{
+		{Namespace: "default", Name: "web", ID: "aaaaaaaaaaaa", Problem: "h2 has an older health"},
+		{Namespace: "alpha", Name: "db", ID: "bbbbbbbbbbbb", Problem: "missing on h2"},
+	}, out.Inconsistencies)
+
+	out, err = check(&replicaRunner{replicas: map[string][][]row{
+		"h1": {{web}}, "h2": {{web}},
+	}}, "h1", "h2", "h3")
+	assert.Equal(t, 1, common.ExitCode(err))
+	assert.Equal(t, "WARNING", out.Status)
+	require.Len(t, out.Errors, 1)
+	assert.Contains(t, out.Errors[0], "h3")
+
+	_, err = check(&replicaRunner{replicas: map[string][][]row{"h1": {{web}}}}, "h1", "h2")
+	assert.Equal(t, 3, common.ExitCode(err))
+}
+
+func TestEmitEndpointsCheck_SettleDropsReplicationLag(t *testing.T) {
+	runner := &replicaRunner{replicas: map[string][][]row{
+		"h1": {{webSick}},
+		"h2": {{web}, {webSick}},
+	}}
+	_, err := capture(t, "json", func(cmd *cobra.Command) error {
+		return emitEndpointsCheck(context.Background(), cmd, testFlags("h1", "h2"),
+			&checkFlags{Settle: time.Millisecond}, runner)
+	})
+	assert.NoError(t, err, "h2 caught up before the second read")
+}
@@ -12,12 +12,14 @@ import (
 	"github.com/coollabsio/coolify-cli/cmd/application"
 	"github.com/coollabsio/coolify-cli/cmd/cloudinit"
 	"github.com/coollabsio/coolify-cli/cmd/cloudtoken"
+	"github.com/coollabsio/coolify-cli/cmd/common"
 	"github.com/coollabsio/coolify-cli/cmd/completion"
 	configcmd "github.com/coollabsio/coolify-cli/cmd/config"
 	"github.com/coollabsio/coolify-cli/cmd/context"
 	"github.com/coollabsio/coolify-cli/cmd/database"
 	"github.com/coollabsio/coolify-cli/cmd/deployment"
 	"github.com/coollabsio/coolify-cli/cmd/destination"
+	"github.com/coollabsio/coolify-cli/cmd/envfile"
 	"github.com/coollabsio/coolify-cli/cmd/github"
 	"github.com/coollabsio/coolify-cli/cmd/gitlab"
 	"github.com/coollabsio/coolify-cli/cmd/mcp"
@@ -67,9 +69,8 @@ var rootCmd = &cobra.Command{
 
 // Execute runs the root command
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(common.ExitCode(err))
 	}
 }
 
@@ -95,7 +96,7 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&Debug, "debug", "", false, "Debug mode")
 
 	// Register all subcommands.
-	// v5 mesh trees (cmd/init, cmd/firewall + internal/wireguard) stay in the
+	// v5 mesh trees (cmd/init, cmd/firewall, cmd/host, cmd/mesh + internal/wireguard) stay in the
 	// repo for development but are deliberately not added here, so they cannot
 	// be invoked from the public CLI.
 	rootCmd.AddCommand(application.NewAppCommand())
@@ -107,6 +108,7 @@ func init() {
 	rootCmd.AddCommand(database.NewDatabaseCommand())
 	rootCmd.AddCommand(deployment.NewDeploymentCommand())
 	rootCmd.AddCommand(destination.NewDestinationCommand())
+	rootCmd.AddCommand(envfile.NewEnvCommand())
 	rootCmd.AddCommand(github.NewGitHubCommand())
 	rootCmd.AddCommand(gitlab.NewGitLabCommand())
 	rootCmd.AddCommand(mcp.NewMCPCommand())
@@ -0,0 +1,16 @@
+package env
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/coollabsio/coolify-cli/cmd/common"
+	"github.com/coollabsio/coolify-cli/internal/service"
+)
+
+func NewPromoteCommand() *cobra.Command {
+	return common.NewEnvPromoteCommand(common.EnvPromoteCommandOptions{
+		Use:       "promote <from_service_uuid> <to_service_uuid>",
+		Example:   "coolify service env",
+		NewTarget: service.NewServiceEnvTarget,
+	})
+}
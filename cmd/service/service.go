@@ -49,6 +49,8 @@ func NewServiceCommand() *cobra.Command {
 	envCmd.AddCommand(env.NewUpdateCommand())
 	envCmd.AddCommand(env.NewDeleteCommand())
 	envCmd.AddCommand(env.NewSyncCommand())
+	envCmd.AddCommand(env.NewExportCommand())
+	envCmd.AddCommand(env.NewPromoteCommand())
 	cmd.AddCommand(envCmd)
 
 	// Add storage subcommand
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/coollabsio/coolify-cli/cmd/common"
 	"github.com/coollabsio/coolify-cli/internal/cli"
 	"github.com/coollabsio/coolify-cli/internal/models"
 	"github.com/coollabsio/coolify-cli/internal/output"
@@ -19,7 +20,7 @@ func NewSharedEnvCommand() *cobra.Command {
 		Short:   "Manage hierarchical shared environment variables",
 		Long:    "Team, project, environment, and server shared env vars (inherited by resources).",
 	}
-	cmd.AddCommand(newTeamCmd(), newProjectCmd(), newEnvironmentCmd(), newServerCmd())
+	cmd.AddCommand(newTeamCmd(), newProjectCmd(), newEnvironmentCmd(), newServerCmd(), newPromoteCmd())
 	return cmd
 }
 
@@ -184,6 +185,9 @@ func newTeamCmd() *cobra.Command {
 		fmt.Println("Shared env deleted.")
 		return nil
 	}})
+	cmd.AddCommand(newSyncCmd("sync", cobra.NoArgs, func(_ []string) service.SharedEnvScope {
+		return service.SharedEnvScope{Kind: service.SharedEnvTeam}
+	}))
 	return cmd
 }
 
@@ -256,6 +260,9 @@ func newProjectCmd() *cobra.Command {
 		fmt.Println("Shared env deleted.")
 		return nil
 	}})
+	cmd.AddCommand(newSyncCmd("sync <project_uuid>", cli.ExactArgs(1, "<project_uuid>"), func(args []string) service.SharedEnvScope {
+		return service.SharedEnvScope{Kind: service.SharedEnvProject, ProjectUUID: args[0]}
+	}))
 	return cmd
 }
 
@@ -328,6 +335,9 @@ func newEnvironmentCmd() *cobra.Command {
 		fmt.Println("Shared env deleted.")
 		return nil
 	}})
+	cmd.AddCommand(newSyncCmd("sync <project_uuid> <environment>", cli.ExactArgs(2, "<project_uuid> <environment>"), func(args []string) service.SharedEnvScope {
+		return service.SharedEnvScope{Kind: service.SharedEnvEnvironment, ProjectUUID: args[0], Environment: args[1]}
+	}))
 	return cmd
 }
 
@@ -400,5 +410,113 @@ func newServerCmd() *cobra.Command {
 		fmt.Println("Shared env deleted.")
 		return nil
 	}})
+	cmd.AddCommand(newSyncCmd("sync <server_uuid>", cli.ExactArgs(1, "<server_uuid>"), func(args []string) service.SharedEnvScope {
+		return service.SharedEnvScope{Kind: service.SharedEnvServer, ServerUUID: args[0]}
+	}))
+	return cmd
+}
+
+// newSyncCmd builds `sync` for one shared env level. scopeFor maps the
+// positional arguments to the scope being synced.
+func newSyncCmd(use string, args cobra.PositionalArgs, scopeFor func(args []string) service.SharedEnvScope) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Args:  args,
+		Short: "Sync shared envs from a .env file",
+		Long: `Create and update shared env vars from a .env file. The file may be
+age-encrypted (see 'coolify env encrypt'); it is only decrypted in memory.
+Use --dry-run to print a masked diff and --prune to delete keys missing from
+the file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filePath, _ := cmd.Flags().GetString("file")
+			if filePath == "" {
+				return fmt.Errorf("--file is required")
+			}
+			desired, err := common.ReadEnvSyncVars(cmd, filePath, false)
+			if err != nil {
+				return err
+			}
+
+			client, err := cli.GetAPIClient(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to get API client: %w", err)
+			}
+			target := service.NewSharedEnvTarget(client, scopeFor(args))
+			existing, err := target.ListEnvRecords(cmd.Context(), "")
+			if err != nil {
+				return err
+			}
+
+			prune, _ := cmd.Flags().GetBool("prune")
+			changes := service.PlanEnvSync(existing, desired, service.EnvSyncScope{Production: true, Prune: prune})
+
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				return common.PrintEnvSyncPlan(cmd, changes)
+			}
+
+			res := service.ApplyEnvSync(cmd.Context(), target, "", changes, common.EnvSyncProgressPrinter())
+			fmt.Printf("\nSync complete: %d updated, %d created, %d deleted, %d failed\n",
+				res.Updated, res.Created, res.Deleted, res.Failed)
+			if res.Failed > 0 {
+				return fmt.Errorf("some shared env vars failed to sync")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringP("file", "f", "", "Path to .env file, or - for stdin (required)")
+	cmd.Flags().Bool("is-literal", false, "Treat all values as literal (don't interpolate variables)")
+	cmd.Flags().Bool("interpolate", false, "Expand ${VAR} references from the file and the local environment before syncing")
+	cmd.Flags().Bool("dotenv", false, "Parse the file as a full dotenv file (export prefixes, inline comments, escapes)")
+	cmd.Flags().Bool("dry-run", false, "Show what would change without applying it")
+	cmd.Flags().Bool("prune", false, "Delete shared envs that are not present in the file")
+	common.BindAgeKeyFlag(cmd)
+	return cmd
+}
+
+func newPromoteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Promote shared environment variables from one environment to another",
+		Long: `Copy the shared env vars of one project environment to another, e.g. from
+staging to production. The masked diff is shown and confirmed before anything
+is changed.
+
+Use --deny to keep environment-specific keys such as DATABASE_URL out of the
+promotion, or --allow to promote only matching keys. Both accept globs
+(e.g. 'STRIPE_*') and can be repeated. Filtered keys are never pruned.
+
+Example: coolify shared-env promote --project <uuid> --from-env staging --to-env production --deny DATABASE_URL`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			project, _ := cmd.Flags().GetString("project")
+			from, _ := cmd.Flags().GetString("from-env")
+			to, _ := cmd.Flags().GetString("to-env")
+			if project == "" || from == "" || to == "" {
+				return fmt.Errorf("--project, --from-env and --to-env are required")
+			}
+			if from == to {
+				return fmt.Errorf("--from-env and --to-env must differ")
+			}
+
+			client, err := cli.GetAPIClient(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to get API client: %w", err)
+			}
+			return common.RunEnvPromote(cmd, common.EnvPromotion{
+				Source: service.NewSharedEnvTarget(client, service.SharedEnvScope{
+					Kind: service.SharedEnvEnvironment, ProjectUUID: project, Environment: from,
+				}),
+				Target: service.NewSharedEnvTarget(client, service.SharedEnvScope{
+					Kind: service.SharedEnvEnvironment, ProjectUUID: project, Environment: to,
+				}),
+				From: from,
+				To:   to,
+			})
+		},
+	}
+	cmd.Flags().String("project", "", "Project UUID (required)")
+	cmd.Flags().String("from-env", "", "Source environment name (required)")
+	cmd.Flags().String("to-env", "", "Target environment name (required)")
+	common.BindEnvPromoteFlags(cmd)
 	return cmd
 }
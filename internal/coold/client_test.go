@@ -0,0 +1,272 @@
+package coold
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// request is one call seen by the fake coold.
+type request struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// fakeCoold answers "METHOD /path" (path without APIBasePath, query
+// included) from routes and records every request.
+type fakeCoold struct {
+	routes   map[string]string
+	requests []request
+}
+
+func newFakeCoold(t *testing.T, routes map[string]string) (*fakeCoold, *Client) {
+	t.Helper()
+	f := &fakeCoold{routes: routes}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, New(NewHTTPDoer(srv.URL, "tok"))
+}
+
+func (f *fakeCoold) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer tok" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	path := strings.TrimPrefix(r.URL.RequestURI(), APIBasePath)
+	f.requests = append(f.requests, request{r.Method, path, string(body)})
+	resp, ok := f.routes[r.Method+" "+path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if resp == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	_, _ = io.WriteString(w, resp)
+}
+
+func TestClient_Firewall(t *testing.T) {
+	f, c := newFakeCoold(t, map[string]string{
+		"POST /firewall/allow":                `{"id":"abc123def456"}`,
+		"GET /firewall/allow?namespace=alpha": `[{"namespace":"alpha","src":"10.0.0.1","dst":"10.0.0.2","proto":"tcp","port":80,"id":"abc123def456"}]`,
+		"GET /firewall/allow/abc123def456":    `{"namespace":"alpha","src":"10.0.0.1","dst":"10.0.0.2","id":"abc123def456","packets":7,"bytes":420}`,
+		"DELETE /firewall/allow/abc123def456": ``,
+		"POST /firewall/allow/bulk":           ``,
+		"POST /firewall/reconcile":            ``,
+	})
+	ctx := context.Background()
+
+	id, err := c.Allow(ctx, FirewallRule{Namespace: "alpha", Src: "10.0.0.1", Dst: "10.0.0.2", Proto: "tcp", Port: 80})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123def456", id)
+	assert.JSONEq(t, `{"namespace":"alpha","src":"10.0.0.1","dst":"10.0.0.2","proto":"tcp","port":80}`, f.requests[0].Body)
+
+	rules, err := c.ListRules(ctx, "alpha")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, uint16(80), rules[0].Port)
+
+	detail, err := c.ShowRule(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), detail.Packets)
+	assert.Equal(t, "10.0.0.2", detail.Dst)
+
+	require.NoError(t, c.Revoke(ctx, id))
+	assert.Error(t, c.Revoke(ctx, ""))
+	require.NoError(t, c.BulkRules(ctx, nil, nil))
+	assert.JSONEq(t, `{"add":[],"remove":[]}`, f.requests[len(f.requests)-1].Body)
+	require.NoError(t, c.ReconcileFirewall(ctx))
+}
+
+func TestClient_ImagesAndContainers(t *testing.T) {
+	f, c := newFakeCoold(t, map[string]string{
+		"POST /images/pull":                   `{"digest":"sha256:aa"}`,
+		"GET /images":                         `[{"ref":"nginx:1","digest":"sha256:aa","size":1024}]`,
+		"DELETE /images/ghcr.io%2Fa%2Fb:1":    ``,
+		"POST /containers":                    `{"id":"c1"}`,
+		"POST /containers/c1/stop":            ``,
+		"DELETE /containers/c1":               ``,
+		"GET /containers/c1":                  `{"Id":"c1","State":{"Running":true}}`,
+		"POST /containers/c1/healthcheck/run": `{"healthy":false,"output":"timeout"}`,
+	})
+	ctx := context.Background()
+
+	digest, err := c.PullImage(ctx, "nginx:1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:aa", digest)
+	assert.JSONEq(t, `{"ref":"nginx:1"}`, f.requests[0].Body, "no auth means no auth key")
+
+	images, err := c.ListImages(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Image{{Ref: "nginx:1", Digest: "sha256:aa", Size: 1024}}, images)
+	require.NoError(t, c.RemoveImage(ctx, "ghcr.io/a/b:1"), "refs are path-escaped")
+
+	id, err := c.CreateContainer(ctx, ContainerSpec{Name: "web", Image: "nginx:1"})
+	require.NoError(t, err)
+	assert.Equal(t, "c1", id)
+	require.NoError(t, c.StopContainer(ctx, id, 5))
+	assert.JSONEq(t, `{"timeout":5}`, f.requests[len(f.requests)-1].Body)
+	require.NoError(t, c.RemoveContainer(ctx, id, true))
+	assert.JSONEq(t, `{"force":true}`, f.requests[len(f.requests)-1].Body)
+
+	raw, err := c.InspectContainer(ctx, id)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"Running":true`)
+
+	hc, err := c.RunHealthcheck(ctx, id)
+	require.NoError(t, err)
+	assert.False(t, hc.Healthy)
+
+	err = c.StartContainer(ctx, "missing")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestClient_ServicesDNSAndHost(t *testing.T) {
+	f, c := newFakeCoold(t, map[string]string{
+		"POST /services/register":          ``,
+		"GET /services?namespace=myapp":    `[{"service_id":"s1","name":"db","namespace":"myapp","port":5432}]`,
+		"GET /services/s1/endpoints":       `[{"service_id":"s1","container_id":"c1","container_ip":"10.210.0.3"}]`,
+		"DELETE /services/s1/endpoints/c1": ``,
+		"GET /dns/lookup/db.myapp":         `{"name":"db.myapp","records":["10.210.0.3"],"ttl":5}`,
+		"GET /dns/stats":                   `{"qps":1.5,"hits":10,"misses":2,"forwards":3}`,
+		"GET /host/info":                   `{"hostname":"h1","cpus":4,"wireguard":{"interface":"wg0","peers":2,"peers_up":1}}`,
+		"GET /host/containers":             `[{"Id":"c1","Names":["web"],"State":"running"}]`,
+		"GET /host/stats":                  `[{"id":"c1","name":"web","cpu_percent":2.5}]`,
+		"GET /networks":                    `[{"name":"coolify-default-mesh","driver":"bridge"}]`,
+		"GET /volumes/data":                `{"name":"data","driver":"local"}`,
+	})
+	ctx := context.Background()
+
+	require.NoError(t, c.RegisterEndpoint(ctx, ServiceEndpoint{ServiceID: "s1", ContainerID: "c1", Port: 5432}))
+	assert.Contains(t, f.requests[0].Body, `"service_id":"s1"`)
+	svcs, err := c.ListServices(ctx, "myapp")
+	require.NoError(t, err)
+	assert.Equal(t, "db", svcs[0].Name)
+	eps, err := c.ServiceEndpoints(ctx, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, "10.210.0.3", eps[0].ContainerIP)
+	require.NoError(t, c.DeregisterEndpoint(ctx, "s1", "c1"))
+
+	ans, err := c.DNSLookup(ctx, "db.myapp")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.210.0.3"}, ans.Records)
+	stats, err := c.DNSStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), stats.Forwards)
+
+	info, err := c.HostInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.WireGuard.PeersUp)
+	ctrs, err := c.HostContainers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, ctrs[0].Names)
+	cs, err := c.HostStats(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, cs[0].CPUPercent, 0.001)
+
+	nets, err := c.ListNetworks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "bridge", nets[0].Driver)
+	vol, err := c.GetVolume(ctx, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "local", vol.Driver)
+}
+
+func TestClient_HTTPErrorIsTyped(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := New(NewHTTPDoer(srv.URL, "wrong")).ListImages(context.Background())
+	var httpErr *HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusUnauthorized, httpErr.Status)
+	assert.Equal(t, "/api/v1/images", httpErr.Path)
+	assert.Contains(t, err.Error(), "unauthorized")
+	assert.False(t, IsNotFound(err))
+}
+
+func TestBaseURL_BracketsIPv6(t *testing.T) {
+	assert.Equal(t, "http://100.64.0.1:8443", BaseURL(net.ParseIP("100.64.0.1"), 8443))
+	assert.Equal(t, "http://[fd64::1]:8443", BaseURL(net.ParseIP("fd64::1"), 8443))
+}
+
+// fakeRunner returns stdout for every command and records the commands.
+type fakeRunner struct {
+	stdout string
+	err    error
+	calls  []string
+}
+
+func (f *fakeRunner) Run(_ context.Context, _, _ string, _ int, cmd string) (string, string, error) {
+	f.calls = append(f.calls, cmd)
+	return f.stdout, "", f.err
+}
+
+func TestSSHDoer_ParsesStatusAfterBody(t *testing.T) {
+	r := &fakeRunner{stdout: `{"error":"no such image"}` + statusMarker + "404"}
+	c := New(&SSHDoer{Runner: r, Host: "h1", User: "root", SSHPort: 22, CooldPort: 8443, Iface: "wg0", Token: "it's"})
+
+	err := c.RemoveImage(context.Background(), "nginx:1")
+	assert.True(t, IsNotFound(err))
+	assert.Contains(t, err.Error(), "no such image")
+
+	require.Len(t, r.calls, 1)
+	cmd := r.calls[0]
+	assert.Contains(t, cmd, "ip -o addr show wg0")
+	assert.Contains(t, cmd, "-X DELETE")
+	assert.Contains(t, cmd, `'Authorization: Bearer it'\''s'`)
+	assert.Contains(t, cmd, `"http://$MGMT:8443"'/api/v1/images/nginx:1'`)
+	assert.NotContains(t, cmd, " -d ", "DELETE without a body sends none")
+
+	r.stdout = "garbage"
+	_, err = c.ListImages(context.Background())
+	assert.ErrorContains(t, err, "no status")
+}
+
+func TestFallbackDoer_RetriesOnlyDialErrors(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	bounce := &fakeRunner{stdout: `[]` + statusMarker + "200"}
+	ssh := &SSHDoer{Runner: bounce, Host: "h1", Iface: "wg0", CooldPort: 8443}
+	c := New(&FallbackDoer{Direct: NewHTTPDoer(deadURL, "tok"), Fallback: ssh})
+	_, err := c.ListImages(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, bounce.calls, 1, "a refused dial is retried over SSH")
+
+	_, live := newFakeCoold(t, map[string]string{})
+	bounce.calls = nil
+	c = New(&FallbackDoer{Direct: live.d, Fallback: ssh})
+	_, err = c.ListImages(context.Background())
+	assert.True(t, IsNotFound(err))
+	assert.Empty(t, bounce.calls, "an answer from coold is final")
+}
+
+func TestIsDialError(t *testing.T) {
+	assert.True(t, IsDialError(fmt.Errorf("wrap: %w", &net.OpError{Op: "dial", Err: io.EOF})))
+	assert.False(t, IsDialError(&net.OpError{Op: "read", Err: io.EOF}))
+	assert.False(t, IsDialError(nil))
+}
+
+func TestFetchToken(t *testing.T) {
+	tok, err := FetchToken(context.Background(), &fakeRunner{stdout: "abc\n"}, "h1", "root", 22)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", tok)
+
+	_, err = FetchToken(context.Background(), &fakeRunner{stdout: "\n"}, "h1", "root", 22)
+	assert.ErrorContains(t, err, "empty")
+}
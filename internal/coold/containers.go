@@ -0,0 +1,104 @@
+package coold
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// ContainerSpec is the filtered podman create surface coold accepts: no
+// privileged mode, host mounts only under coold-managed volumes.
+type ContainerSpec struct {
+	Name      string            `json:"name"`
+	Image     string            `json:"image"`
+	Namespace string            `json:"namespace,omitempty"`
+	Cmd       []string          `json:"cmd,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Volumes   []VolumeMount     `json:"volumes,omitempty"`
+	Restart   string            `json:"restart,omitempty"`
+}
+
+// VolumeMount mounts a named volume into a container.
+type VolumeMount struct {
+	Volume   string `json:"volume"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+}
+
+// Container is one row of `podman ps -a`, as coold relays it.
+type Container struct {
+	ID       string            `json:"Id"`
+	Names    []string          `json:"Names"`
+	Image    string            `json:"Image"`
+	State    string            `json:"State"`
+	Status   string            `json:"Status"`
+	Created  int64             `json:"Created"`
+	Labels   map[string]string `json:"Labels"`
+	Networks []string          `json:"Networks"`
+}
+
+// HealthcheckResult is the outcome of running a container's healthcheck.
+type HealthcheckResult struct {
+	Healthy bool   `json:"healthy"`
+	Output  string `json:"output,omitempty"`
+}
+
+// CreateContainer creates (but does not start) a container and returns its
+// id.
+func (c *Client) CreateContainer(ctx context.Context, spec ContainerSpec) (string, error) {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/containers", spec, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// StartContainer starts container id.
+func (c *Client) StartContainer(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodPost, containerPath(id, "/start"), nil, nil)
+}
+
+// StopContainer stops container id. timeout is the grace period in seconds
+// before SIGKILL; 0 leaves podman's default.
+func (c *Client) StopContainer(ctx context.Context, id string, timeout int) error {
+	body := struct {
+		Timeout int `json:"timeout,omitempty"`
+	}{timeout}
+	return c.call(ctx, http.MethodPost, containerPath(id, "/stop"), body, nil)
+}
+
+// RestartContainer restarts container id.
+func (c *Client) RestartContainer(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodPost, containerPath(id, "/restart"), nil, nil)
+}
+
+// RemoveContainer deletes container id; force removes a running one.
+func (c *Client) RemoveContainer(ctx context.Context, id string, force bool) error {
+	body := struct {
+		Force bool `json:"force,omitempty"`
+	}{force}
+	return c.call(ctx, http.MethodDelete, containerPath(id, ""), body, nil)
+}
+
+// InspectContainer returns podman's inspect document for container id,
+// unparsed.
+func (c *Client) InspectContainer(ctx context.Context, id string) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.call(ctx, http.MethodGet, containerPath(id, ""), nil, &out)
+	return out, err
+}
+
+// RunHealthcheck runs container id's healthcheck now.
+func (c *Client) RunHealthcheck(ctx context.Context, id string) (HealthcheckResult, error) {
+	var out HealthcheckResult
+	err := c.call(ctx, http.MethodPost, containerPath(id, "/healthcheck/run"), nil, &out)
+	return out, err
+}
+
+func containerPath(id, suffix string) string {
+	return "/containers/" + url.PathEscape(id) + suffix
+}
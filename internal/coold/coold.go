@@ -0,0 +1,97 @@
+// Package coold is a typed client for the coold agent's REST API, the wire
+// surface enumerated in CONTROL_PLANE.md §2 (images, containers, volumes,
+// networks, firewall, services, dns, host facts). The client is transport
+// agnostic: a Doer carries each request either directly over HTTP, when the
+// caller is a mesh peer, or bounced through SSH onto the host.
+package coold
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIBasePath is the prefix every coold route is served under.
+const APIBasePath = "/api/v1"
+
+// DefaultPort is the TCP port coold's REST API binds on the wg0 mgmt IP.
+// Must match services.CooldAPIPort.
+const DefaultPort = 8443
+
+// Doer sends one request to coold on a host and returns the status code
+// and response body. body is nil for requests without one.
+type Doer interface {
+	Do(ctx context.Context, method, path string, body []byte) (status int, resp []byte, err error)
+}
+
+// HTTPError is a non-2xx answer from coold. It means coold was reached and
+// refused the request, so callers must not retry it over another transport.
+type HTTPError struct {
+	Method string
+	Path   string
+	Status int
+	Body   string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("coold %s %s: HTTP %d", e.Method, e.Path, e.Status)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// newHTTPError builds the error for a non-2xx answer, keeping at most 4KiB
+// of the body.
+func newHTTPError(method, path string, status int, body []byte) *HTTPError {
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 4096 {
+		msg = msg[:4096]
+	}
+	return &HTTPError{Method: method, Path: path, Status: status, Body: msg}
+}
+
+// IsNotFound reports whether err is coold answering 404.
+func IsNotFound(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound
+}
+
+// Client calls coold's API on one host.
+type Client struct {
+	d Doer
+}
+
+// New returns a client that sends its requests through d.
+func New(d Doer) *Client {
+	return &Client{d: d}
+}
+
+// call sends method path. in, when non-nil, is marshalled as the JSON body;
+// out, when non-nil, receives the decoded JSON response.
+func (c *Client) call(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("marshal %s %s: %w", method, path, err)
+		}
+	}
+	status, resp, err := c.d.Do(ctx, method, APIBasePath+path, body)
+	if err != nil {
+		return err
+	}
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return newHTTPError(method, APIBasePath+path, status, resp)
+	}
+	if out == nil || status == http.StatusNoContent || len(strings.TrimSpace(string(resp))) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp, out); err != nil {
+		return fmt.Errorf("parse coold %s %s: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package coold
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// FirewallRule is an allow rule on the wire (coold/src/firewall/rule.rs):
+// src/dst are IP strings, proto/port/id are omitted when absent.
+type FirewallRule struct {
+	Namespace string `json:"namespace"`
+	Src       string `json:"src"`
+	Dst       string `json:"dst"`
+	Proto     string `json:"proto,omitempty"`
+	Port      uint16 `json:"port,omitempty"`
+	ID        string `json:"id,omitempty"`
+}
+
+// FirewallRuleDetail is a rule plus the match counters of its iptables
+// entry.
+type FirewallRuleDetail struct {
+	FirewallRule
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// Allow installs r and returns the id coold assigned.
+func (c *Client) Allow(ctx context.Context, r FirewallRule) (string, error) {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/firewall/allow", r, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// Revoke removes rule id. Unknown ids are a no-op on coold's side.
+func (c *Client) Revoke(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("coold revoke: empty id")
+	}
+	return c.call(ctx, http.MethodDelete, "/firewall/allow/"+url.PathEscape(id), nil, nil)
+}
+
+// ListRules returns the installed rules, restricted to namespace when set.
+func (c *Client) ListRules(ctx context.Context, namespace string) ([]FirewallRule, error) {
+	path := "/firewall/allow"
+	if namespace != "" {
+		path += "?namespace=" + url.QueryEscape(namespace)
+	}
+	var out []FirewallRule
+	err := c.call(ctx, http.MethodGet, path, nil, &out)
+	return out, err
+}
+
+// ShowRule returns rule id with its match counters.
+func (c *Client) ShowRule(ctx context.Context, id string) (FirewallRuleDetail, error) {
+	var out FirewallRuleDetail
+	err := c.call(ctx, http.MethodGet, "/firewall/allow/"+url.PathEscape(id), nil, &out)
+	return out, err
+}
+
+// BulkRules adds and removes rules as one atomic batch.
+func (c *Client) BulkRules(ctx context.Context, add []FirewallRule, remove []string) error {
+	if add == nil {
+		add = []FirewallRule{}
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+	body := struct {
+		Add    []FirewallRule `json:"add"`
+		Remove []string       `json:"remove"`
+	}{add, remove}
+	return c.call(ctx, http.MethodPost, "/firewall/allow/bulk", body, nil)
+}
+
+// ReconcileFirewall makes coold reload its full rule set into the kernel.
+func (c *Client) ReconcileFirewall(ctx context.Context) error {
+	return c.call(ctx, http.MethodPost, "/firewall/reconcile", nil, nil)
+}
@@ -0,0 +1,63 @@
+package coold
+
+import (
+	"context"
+	"net/http"
+)
+
+// HostInfo is coold's summary of the host: podman, kernel, WireGuard and
+// load.
+type HostInfo struct {
+	Hostname      string     `json:"hostname"`
+	OS            string     `json:"os"`
+	Kernel        string     `json:"kernel"`
+	Arch          string     `json:"arch"`
+	CPUs          int        `json:"cpus"`
+	MemTotal      uint64     `json:"mem_total"`
+	Load          [3]float64 `json:"load"`
+	PodmanVersion string     `json:"podman_version"`
+	CooldVersion  string     `json:"coold_version"`
+	WireGuard     WGInfo     `json:"wireguard"`
+}
+
+// WGInfo is the host's WireGuard state.
+type WGInfo struct {
+	Interface string `json:"interface"`
+	MgmtIP    string `json:"mgmt_ip"`
+	Peers     int    `json:"peers"`
+	// PeersUp counts peers with a handshake in the last three minutes.
+	PeersUp int `json:"peers_up"`
+}
+
+// ContainerStats is one row of a `podman stats` snapshot.
+type ContainerStats struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemUsage   uint64  `json:"mem_usage"`
+	MemLimit   uint64  `json:"mem_limit"`
+	NetInput   uint64  `json:"net_input"`
+	NetOutput  uint64  `json:"net_output"`
+	PIDs       int     `json:"pids"`
+}
+
+// HostInfo returns the host summary.
+func (c *Client) HostInfo(ctx context.Context) (HostInfo, error) {
+	var out HostInfo
+	err := c.call(ctx, http.MethodGet, "/host/info", nil, &out)
+	return out, err
+}
+
+// HostContainers returns every container on the host (`podman ps -a`).
+func (c *Client) HostContainers(ctx context.Context) ([]Container, error) {
+	var out []Container
+	err := c.call(ctx, http.MethodGet, "/host/containers", nil, &out)
+	return out, err
+}
+
+// HostStats returns a resource snapshot of the running containers.
+func (c *Client) HostStats(ctx context.Context) ([]ContainerStats, error) {
+	var out []ContainerStats
+	err := c.call(ctx, http.MethodGet, "/host/stats", nil, &out)
+	return out, err
+}
@@ -0,0 +1,47 @@
+package coold
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// RegistryAuth is the credential for pulling from a private registry.
+type RegistryAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Image is one entry of coold's image list.
+type Image struct {
+	Ref    string `json:"ref"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// PullImage pulls ref on the host and returns its digest. auth may be nil.
+func (c *Client) PullImage(ctx context.Context, ref string, auth *RegistryAuth) (string, error) {
+	body := struct {
+		Ref  string        `json:"ref"`
+		Auth *RegistryAuth `json:"auth,omitempty"`
+	}{ref, auth}
+	var resp struct {
+		Digest string `json:"digest"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/images/pull", body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Digest, nil
+}
+
+// ListImages returns the images present on the host.
+func (c *Client) ListImages(ctx context.Context) ([]Image, error) {
+	var out []Image
+	err := c.call(ctx, http.MethodGet, "/images", nil, &out)
+	return out, err
+}
+
+// RemoveImage deletes ref from the host.
+func (c *Client) RemoveImage(ctx context.Context, ref string) error {
+	return c.call(ctx, http.MethodDelete, "/images/"+url.PathEscape(ref), nil, nil)
+}
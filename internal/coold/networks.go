@@ -0,0 +1,41 @@
+package coold
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// NetworkSpec creates a podman network. Bootstrap owns the per-namespace
+// coolify-<ns>-mesh bridges; this is for extra per-app networks.
+type NetworkSpec struct {
+	Name    string            `json:"name"`
+	Driver  string            `json:"driver,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// Network is a podman network on the host.
+type Network struct {
+	Name    string            `json:"name"`
+	Driver  string            `json:"driver"`
+	Subnets []string          `json:"subnets,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// CreateNetwork creates a network.
+func (c *Client) CreateNetwork(ctx context.Context, spec NetworkSpec) error {
+	return c.call(ctx, http.MethodPost, "/networks", spec, nil)
+}
+
+// RemoveNetwork deletes network name.
+func (c *Client) RemoveNetwork(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, "/networks/"+url.PathEscape(name), nil, nil)
+}
+
+// ListNetworks returns the host's networks.
+func (c *Client) ListNetworks(ctx context.Context) ([]Network, error) {
+	var out []Network
+	err := c.call(ctx, http.MethodGet, "/networks", nil, &out)
+	return out, err
+}
@@ -0,0 +1,117 @@
+package coold
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/coollabsio/coolify-cli/internal/wireguard"
+)
+
+// OperatorPeer is the CLI machine's membership in the mesh: a WireGuard
+// identity admitted on every host with a /32 from the mgmt pool, so coold's
+// REST API on each host's wg0 IP is reachable without an SSH bounce. It is
+// kept next to the CLI config and holds a private key, so it is written
+// with mode 0600.
+type OperatorPeer struct {
+	MgmtIP     string `json:"mgmt_ip"`
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+	// ListenPort is the mesh's WireGuard port, used for the host endpoints
+	// and as the operator's own listen port.
+	ListenPort int `json:"listen_port"`
+	// Hosts maps SSH host → the host's peer identity.
+	Hosts map[string]OperatorHost `json:"hosts"`
+}
+
+// OperatorHost is one mesh host as seen by the operator peer.
+type OperatorHost struct {
+	MgmtIP    string `json:"mgmt_ip"`
+	PublicKey string `json:"public_key"`
+	// CooldToken is the host's coold bearer token, captured at join time so
+	// direct calls need no SSH at all.
+	CooldToken string `json:"coold_token,omitempty"`
+}
+
+// LoadOperatorPeer reads path. A missing file means the CLI has not joined
+// the mesh and returns nil, nil.
+func LoadOperatorPeer(path string) (*OperatorPeer, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read operator peer: %w", err)
+	}
+	var p OperatorPeer
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Save atomically writes the peer to path with mode 0600.
+func (p *OperatorPeer) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create operator peer dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write operator peer: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write operator peer: %w", err)
+	}
+	return nil
+}
+
+// Endpoint returns coold's base URL on host, or false when host is not one
+// of the peer's hosts.
+func (p *OperatorPeer) Endpoint(host string, cooldPort int) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	h, ok := p.Hosts[host]
+	if !ok {
+		return "", false
+	}
+	ip := net.ParseIP(h.MgmtIP)
+	if ip == nil {
+		return "", false
+	}
+	return BaseURL(ip, cooldPort), true
+}
+
+// Config renders the wg-quick config for the operator's own machine. Each
+// host is routed by its mgmt IP only; container subnets stay unreachable.
+func (p *OperatorPeer) Config() (string, error) {
+	ip := net.ParseIP(p.MgmtIP)
+	if ip == nil {
+		return "", fmt.Errorf("operator peer has invalid mgmt IP %q", p.MgmtIP)
+	}
+	hosts := make([]string, 0, len(p.Hosts))
+	for h := range p.Hosts {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	peers := make([]wireguard.PeerConfig, 0, len(hosts))
+	for _, h := range hosts {
+		mgmt := net.ParseIP(p.Hosts[h].MgmtIP)
+		if mgmt == nil {
+			return "", fmt.Errorf("host %s has invalid mgmt IP %q", h, p.Hosts[h].MgmtIP)
+		}
+		peers = append(peers, wireguard.PeerConfig{
+			Endpoint:  h,
+			PublicKey: p.Hosts[h].PublicKey,
+			MgmtIP:    mgmt,
+		})
+	}
+	return wireguard.RenderOperatorConfig(p.PrivateKey, ip, p.ListenPort, peers), nil
+}
@@ -0,0 +1,54 @@
+package coold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOperatorPeer_MissingIsNil(t *testing.T) {
+	p, err := LoadOperatorPeer(filepath.Join(t.TempDir(), "none.json"))
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestOperatorPeer_SaveLoadEndpointConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "operator.json")
+	p := &OperatorPeer{
+		MgmtIP:     "100.64.255.254",
+		PrivateKey: "cHJpdmF0ZQ==",
+		PublicKey:  "b3BlcmF0b3I=",
+		ListenPort: 51820,
+		Hosts: map[string]OperatorHost{
+			"10.0.0.2": {MgmtIP: "100.64.0.2", PublicKey: "aG9zdDI=", CooldToken: "t2"},
+			"10.0.0.1": {MgmtIP: "100.64.0.1", PublicKey: "aG9zdDE="},
+		},
+	}
+	require.NoError(t, p.Save(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	got, err := LoadOperatorPeer(path)
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	url, ok := got.Endpoint("10.0.0.1", 8443)
+	require.True(t, ok)
+	assert.Equal(t, "http://100.64.0.1:8443", url)
+	_, ok = got.Endpoint("10.0.0.3", 8443)
+	assert.False(t, ok)
+
+	conf, err := got.Config()
+	require.NoError(t, err)
+	assert.Contains(t, conf, "Address = 100.64.255.254/32")
+	assert.Contains(t, conf, "PrivateKey = cHJpdmF0ZQ==")
+	assert.NotContains(t, conf, "__PRIVKEY__")
+	assert.Contains(t, conf, "AllowedIPs = 100.64.0.1/32\n")
+	assert.Contains(t, conf, "Endpoint = 10.0.0.2:51820")
+	assert.Less(t, strings.Index(conf, "10.0.0.1"), strings.Index(conf, "10.0.0.2"), "peers sorted by host")
+}
@@ -0,0 +1,89 @@
+package coold
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ServiceEndpoint registers one container as a backend of a service.
+// coold writes it to Corrosion's service_endpoints table.
+type ServiceEndpoint struct {
+	ServiceID   string `json:"service_id"`
+	AppID       string `json:"app_id"`
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	Port        int    `json:"port"`
+	ContainerID string `json:"container_id"`
+	ContainerIP string `json:"container_ip"`
+	HostMgmtIP  string `json:"host_mgmt_ip"`
+}
+
+// Service is one registered service.
+type Service struct {
+	ServiceID string `json:"service_id"`
+	AppID     string `json:"app_id"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Port      int    `json:"port"`
+}
+
+// DNSAnswer is what coold's resolver would answer for a name.
+type DNSAnswer struct {
+	Name    string   `json:"name"`
+	Records []string `json:"records"`
+	TTL     int      `json:"ttl"`
+	Source  string   `json:"source,omitempty"` // "corrosion", "forward", ...
+}
+
+// DNSStats are the resolver's counters.
+type DNSStats struct {
+	QPS      float64 `json:"qps"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Forwards uint64  `json:"forwards"`
+}
+
+// RegisterEndpoint publishes ep.
+func (c *Client) RegisterEndpoint(ctx context.Context, ep ServiceEndpoint) error {
+	return c.call(ctx, http.MethodPost, "/services/register", ep, nil)
+}
+
+// DeregisterEndpoint removes containerID from service serviceID.
+func (c *Client) DeregisterEndpoint(ctx context.Context, serviceID, containerID string) error {
+	return c.call(ctx, http.MethodDelete,
+		"/services/"+url.PathEscape(serviceID)+"/endpoints/"+url.PathEscape(containerID), nil, nil)
+}
+
+// ServiceEndpoints lists the backends of service serviceID.
+func (c *Client) ServiceEndpoints(ctx context.Context, serviceID string) ([]ServiceEndpoint, error) {
+	var out []ServiceEndpoint
+	err := c.call(ctx, http.MethodGet, "/services/"+url.PathEscape(serviceID)+"/endpoints", nil, &out)
+	return out, err
+}
+
+// ListServices lists the services in namespace; empty means every
+// namespace.
+func (c *Client) ListServices(ctx context.Context, namespace string) ([]Service, error) {
+	path := "/services"
+	if namespace != "" {
+		path += "?namespace=" + url.QueryEscape(namespace)
+	}
+	var out []Service
+	err := c.call(ctx, http.MethodGet, path, nil, &out)
+	return out, err
+}
+
+// DNSLookup returns what the host's resolver would answer for name.
+func (c *Client) DNSLookup(ctx context.Context, name string) (DNSAnswer, error) {
+	var out DNSAnswer
+	err := c.call(ctx, http.MethodGet, "/dns/lookup/"+url.PathEscape(name), nil, &out)
+	return out, err
+}
+
+// DNSStats returns the resolver's counters.
+func (c *Client) DNSStats(ctx context.Context) (DNSStats, error) {
+	var out DNSStats
+	err := c.call(ctx, http.MethodGet, "/dns/stats", nil, &out)
+	return out, err
+}
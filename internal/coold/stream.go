@@ -0,0 +1,222 @@
+package coold
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// Streamed endpoints (logs, exec) carry frames: an 8-byte header — frame
+// type, three zero bytes, big-endian uint32 payload length — followed by
+// the payload. It is the multiplexed format of podman's own API, extended
+// with the frame types exec needs in the client → coold direction.
+const (
+	FrameStdin      byte = 0 // client → coold: bytes for the process's stdin
+	FrameStdout     byte = 1
+	FrameStderr     byte = 2
+	FrameExit       byte = 3 // coold → client: big-endian int32 exit code, last frame
+	FrameResize     byte = 4 // client → coold: big-endian uint16 cols, rows
+	FrameCloseStdin byte = 5 // client → coold: stdin reached EOF
+)
+
+// UpgradeProtocol is the Upgrade header value that asks coold to switch an
+// exec request to the bidirectional frame protocol.
+const UpgradeProtocol = "coold-stream"
+
+// maxFrame bounds one frame's payload so a corrupt header cannot make the
+// reader allocate gigabytes.
+const maxFrame = 16 << 20
+
+// Streamer is a Doer that can also hold a connection open for the streamed
+// endpoints. With upgrade, coold switches the connection to the frame
+// protocol in both directions and the result is an io.ReadWriteCloser;
+// otherwise the response body is the stream.
+type Streamer interface {
+	Stream(ctx context.Context, method, path string, body []byte, upgrade bool) (io.ReadCloser, error)
+}
+
+func writeFrame(w io.Writer, typ byte, payload []byte) error {
+	var hdr [8]byte
+	hdr[0] = typ
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[4:])
+	if n > maxFrame {
+		return 0, nil, fmt.Errorf("coold stream: frame of %d bytes exceeds %d", n, maxFrame)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, fmt.Errorf("coold stream: short frame: %w", err)
+	}
+	return hdr[0], payload, nil
+}
+
+// Demux copies stdout and stderr frames from r until an exit frame or EOF.
+// It returns the exit code, or -1 when the stream ended without one (logs
+// always do).
+func Demux(r io.Reader, stdout, stderr io.Writer) (int, error) {
+	for {
+		typ, payload, err := readFrame(r)
+		if errors.Is(err, io.EOF) {
+			return -1, nil
+		}
+		if err != nil {
+			return -1, err
+		}
+		switch typ {
+		case FrameStdout:
+			_, err = stdout.Write(payload)
+		case FrameStderr:
+			_, err = stderr.Write(payload)
+		case FrameExit:
+			if len(payload) != 4 {
+				return -1, fmt.Errorf("coold stream: exit frame of %d bytes", len(payload))
+			}
+			return int(int32(binary.BigEndian.Uint32(payload))), nil
+		}
+		if err != nil {
+			return -1, err
+		}
+	}
+}
+
+// stream opens a streamed endpoint through c's Doer.
+func (c *Client) stream(ctx context.Context, method, path string, in any, upgrade bool) (io.ReadCloser, error) {
+	s, ok := c.d.(Streamer)
+	if !ok {
+		return nil, fmt.Errorf("coold %s %s: transport %T cannot stream", method, path, c.d)
+	}
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("marshal %s %s: %w", method, path, err)
+		}
+	}
+	return s.Stream(ctx, method, APIBasePath+path, body, upgrade)
+}
+
+// LogsOptions selects the log lines Logs streams.
+type LogsOptions struct {
+	Follow     bool
+	Tail       int // last N lines; 0 means all
+	Since      string
+	Timestamps bool
+}
+
+// Logs streams container id's logs as stdout/stderr frames; read them with
+// Demux. With Follow the stream stays open until ctx is done or the
+// container exits.
+func (c *Client) Logs(ctx context.Context, id string, opts LogsOptions) (io.ReadCloser, error) {
+	q := url.Values{}
+	if opts.Follow {
+		q.Set("follow", "true")
+	}
+	if opts.Tail > 0 {
+		q.Set("tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Since != "" {
+		q.Set("since", opts.Since)
+	}
+	if opts.Timestamps {
+		q.Set("timestamps", "true")
+	}
+	path := containerPath(id, "/logs")
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	return c.stream(ctx, http.MethodGet, path, nil, false)
+}
+
+// ExecSpec is the process to start in a container. With TTY, coold
+// allocates a pseudo-terminal of Cols×Rows and sends all output as stdout
+// frames.
+type ExecSpec struct {
+	Cmd  []string `json:"cmd"`
+	TTY  bool     `json:"tty,omitempty"`
+	Cols uint16   `json:"cols,omitempty"`
+	Rows uint16   `json:"rows,omitempty"`
+}
+
+// ExecSession is a running exec. Writes go to the process's stdin; Wait
+// relays its output and returns its exit code.
+type ExecSession struct {
+	rw  io.ReadWriteCloser
+	wmu sync.Mutex
+}
+
+// Exec starts spec in container id.
+func (c *Client) Exec(ctx context.Context, id string, spec ExecSpec) (*ExecSession, error) {
+	if len(spec.Cmd) == 0 {
+		return nil, fmt.Errorf("coold exec: empty command")
+	}
+	rc, err := c.stream(ctx, http.MethodPost, containerPath(id, "/exec"), spec, true)
+	if err != nil {
+		return nil, err
+	}
+	rw, ok := rc.(io.ReadWriteCloser)
+	if !ok {
+		rc.Close()
+		return nil, fmt.Errorf("coold exec: connection was not upgraded")
+	}
+	return &ExecSession{rw: rw}, nil
+}
+
+func (s *ExecSession) send(typ byte, payload []byte) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	return writeFrame(s.rw, typ, payload)
+}
+
+// Write sends p to the process's stdin.
+func (s *ExecSession) Write(p []byte) (int, error) {
+	if err := s.send(FrameStdin, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// CloseStdin signals EOF on the process's stdin.
+func (s *ExecSession) CloseStdin() error {
+	return s.send(FrameCloseStdin, nil)
+}
+
+// Resize changes the pseudo-terminal size of a TTY exec.
+func (s *ExecSession) Resize(cols, rows uint16) error {
+	var p [4]byte
+	binary.BigEndian.PutUint16(p[:2], cols)
+	binary.BigEndian.PutUint16(p[2:], rows)
+	return s.send(FrameResize, p[:])
+}
+
+// Wait copies the process's output until it exits and returns its exit
+// code; -1 means the connection ended first.
+func (s *ExecSession) Wait(stdout, stderr io.Writer) (int, error) {
+	return Demux(s.rw, stdout, stderr)
+}
+
+// Close ends the session, killing the process if it is still running.
+func (s *ExecSession) Close() error {
+	return s.rw.Close()
+}
@@ -0,0 +1,157 @@
+package coold
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func frames(t *testing.T, fs ...any) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	for i := 0; i < len(fs); i += 2 {
+		require.NoError(t, writeFrame(&b, fs[i].(byte), []byte(fs[i+1].(string))))
+	}
+	return b.Bytes()
+}
+
+func exitPayload(code int32) string {
+	var p [4]byte
+	binary.BigEndian.PutUint32(p[:], uint32(code))
+	return string(p[:])
+}
+
+func TestDemux(t *testing.T) {
+	in := frames(t, FrameStdout, "out ", FrameStderr, "err", FrameStdout, "more", FrameExit, exitPayload(3), FrameStdout, "after exit")
+	var stdout, stderr bytes.Buffer
+	code, err := Demux(bytes.NewReader(in), &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "out more", stdout.String())
+	assert.Equal(t, "err", stderr.String())
+
+	code, err = Demux(bytes.NewReader(frames(t, FrameStdout, "x")), io.Discard, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, -1, code, "a stream without exit frame")
+
+	_, err = Demux(bytes.NewReader(in[:10]), io.Discard, io.Discard)
+	assert.ErrorContains(t, err, "short frame")
+}
+
+// execServer emulates coold's exec upgrade: it echoes stdin back upper-cased
+// as stdout, reports resizes on stderr and exits 7 once stdin is closed.
+func execServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != APIBasePath+"/containers/web/exec" || r.Header.Get("Upgrade") != UpgradeProtocol {
+			http.Error(w, "no such container", http.StatusNotFound)
+			return
+		}
+		// The exec spec precedes the frames on the connection.
+		if _, err := io.ReadAll(r.Body); err != nil {
+			return
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + UpgradeProtocol + "\r\n\r\n")
+		_ = rw.Flush()
+		for {
+			typ, payload, err := readFrame(rw)
+			if err != nil {
+				return
+			}
+			switch typ {
+			case FrameStdin:
+				_ = writeFrame(rw, FrameStdout, bytes.ToUpper(payload))
+			case FrameResize:
+				cols, rows := binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:])
+				_ = writeFrame(rw, FrameStderr, []byte(strings.Repeat("r", int(cols/rows))))
+			case FrameCloseStdin:
+				_ = writeFrame(rw, FrameExit, []byte(exitPayload(7)))
+				_ = rw.Flush()
+				return
+			}
+			_ = rw.Flush()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestExec_OverHTTP(t *testing.T) {
+	srv := execServer(t)
+	c := New(NewHTTPDoer(srv.URL, "tok"))
+
+	sess, err := c.Exec(context.Background(), "web", ExecSpec{Cmd: []string{"sh"}, TTY: true})
+	require.NoError(t, err)
+	defer sess.Close()
+	_, err = sess.Write([]byte("hi"))
+	require.NoError(t, err)
+	require.NoError(t, sess.Resize(80, 40))
+	require.NoError(t, sess.CloseStdin())
+
+	var stdout, stderr bytes.Buffer
+	code, err := sess.Wait(&stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 7, code)
+	assert.Equal(t, "HI", stdout.String())
+	assert.Equal(t, "rr", stderr.String())
+
+	_, err = c.Exec(context.Background(), "db", ExecSpec{Cmd: []string{"sh"}})
+	assert.True(t, IsNotFound(err))
+	_, err = c.Exec(context.Background(), "web", ExecSpec{})
+	assert.ErrorContains(t, err, "empty command")
+}
+
+// tunnel dials target whatever address it is asked for, standing in for a
+// direct-tcpip channel from the host.
+type tunnel struct {
+	target string
+	addrs  []string
+}
+
+func (f *tunnel) Dial(ctx context.Context, _, _ string, _ int, network, addr string) (net.Conn, error) {
+	f.addrs = append(f.addrs, addr)
+	var d net.Dialer
+	return d.DialContext(ctx, network, f.target)
+}
+
+func TestLogs_OverSSHTunnel(t *testing.T) {
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		_, _ = w.Write(frames(t, FrameStdout, "line 1\n", FrameStderr, "oops\n"))
+	}))
+	defer srv.Close()
+	tun := &tunnel{target: strings.TrimPrefix(srv.URL, "http://")}
+	runner := &fakeRunner{stdout: "100.64.0.1\n"}
+	c := New(&SSHDoer{Runner: runner, Tunnel: tun, Host: "h1", CooldPort: 8443, Iface: "wg0", Token: "tok"})
+
+	rc, err := c.Logs(context.Background(), "web", LogsOptions{Follow: true, Tail: 10})
+	require.NoError(t, err)
+	defer rc.Close()
+	var stdout, stderr bytes.Buffer
+	_, err = Demux(bufio.NewReader(rc), &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\n", stdout.String())
+	assert.Equal(t, "oops\n", stderr.String())
+	assert.Equal(t, "follow=true&tail=10", gotQuery)
+	assert.Equal(t, []string{"100.64.0.1:8443"}, tun.addrs)
+	assert.Contains(t, runner.calls[0], `echo "$MGMT"`)
+
+	_, err = New(&SSHDoer{Runner: runner, Host: "h1"}).Logs(context.Background(), "web", LogsOptions{})
+	assert.ErrorContains(t, err, "needs a tunnel")
+}
@@ -0,0 +1,30 @@
+package coold
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// TokenPath is the remote file coold reads its bearer token from. Kept in
+// sync with internal/services/coold.go.
+const TokenPath = "/etc/coolify/api-token" //nolint:gosec // filesystem path, not a credential
+
+// FetchToken SSHes into host and reads the coold bearer token at
+// TokenPath. Each host generates its own random token at install time, so
+// per-host fetch is the default when no global override is supplied.
+func FetchToken(ctx context.Context, runner ssh.Runner, host, user string, sshPort int) (string, error) {
+	stdout, stderr, err := runner.Run(ctx, host, user, sshPort, "cat "+TokenPath)
+	if err != nil {
+		return "", fmt.Errorf("fetch coold token from %s: %w (stderr: %s)",
+			host, err, strings.TrimSpace(stderr))
+	}
+	tok := strings.TrimSpace(stdout)
+	if tok == "" {
+		return "", fmt.Errorf("coold token on %s is empty — is coold installed? (expected at %s)",
+			host, TokenPath)
+	}
+	return tok, nil
+}
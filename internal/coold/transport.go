@@ -0,0 +1,236 @@
+package coold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// RequestTimeout bounds one non-streamed request on either transport.
+const RequestTimeout = 10 * time.Second
+
+// BaseURL returns coold's REST root on a host's wg0 mgmt IP, bracketing
+// IPv6 addresses.
+func BaseURL(mgmtIP net.IP, port int) string {
+	return "http://" + net.JoinHostPort(mgmtIP.String(), strconv.Itoa(port))
+}
+
+// HTTPDoer reaches coold directly. Only mesh peers (a host, or the operator
+// peer) can route to the mgmt IP in BaseURL.
+type HTTPDoer struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewHTTPDoer returns an HTTPDoer with the default request timeout.
+func NewHTTPDoer(baseURL, token string) *HTTPDoer {
+	return &HTTPDoer{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		HTTP:    &http.Client{Timeout: RequestTimeout},
+	}
+}
+
+func (d *HTTPDoer) Do(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	req, err := d.newRequest(ctx, method, path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := d.HTTP.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("read coold %s %s: %w", method, path, err)
+	}
+	return resp.StatusCode, data, nil
+}
+
+func (d *HTTPDoer) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.BaseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// Stream opens a streamed endpoint. The request timeout does not apply:
+// the stream lasts until ctx is done or the body is closed.
+func (d *HTTPDoer) Stream(ctx context.Context, method, path string, body []byte, upgrade bool) (io.ReadCloser, error) {
+	req, err := d.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	want := http.StatusOK
+	if upgrade {
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", UpgradeProtocol)
+		want = http.StatusSwitchingProtocols
+	}
+	hc := &http.Client{Transport: d.HTTP.Transport}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != want {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+			return nil, fmt.Errorf("coold %s %s: expected HTTP %d, got %d", method, path, want, resp.StatusCode)
+		}
+		return nil, newHTTPError(method, path, resp.StatusCode, msg)
+	}
+	return resp.Body, nil
+}
+
+// SSHDoer reaches coold by SSHing into Host and curling its wg0 mgmt IP
+// there, for CLI machines that are not mesh peers. Streams cannot go
+// through curl; they are tunnelled over the SSH connection instead, which
+// needs Tunnel.
+type SSHDoer struct {
+	Runner    ssh.Runner
+	Tunnel    ssh.Dialer
+	Host      string
+	User      string
+	SSHPort   int
+	CooldPort int
+	Iface     string
+	Token     string
+}
+
+// statusMarker separates the response body from curl's status line.
+const statusMarker = "\n__COOLD_STATUS__="
+
+func (d *SSHDoer) Do(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	stdout, stderr, err := d.Runner.Run(ctx, d.Host, d.User, d.SSHPort, d.command(method, path, body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("coold %s %s on %s: %w (stderr: %s)",
+			method, path, d.Host, err, strings.TrimSpace(stderr))
+	}
+	i := strings.LastIndex(stdout, statusMarker)
+	if i < 0 {
+		return 0, nil, fmt.Errorf("coold %s %s on %s: no status in curl output", method, path, d.Host)
+	}
+	status, err := strconv.Atoi(strings.TrimSpace(stdout[i+len(statusMarker):]))
+	if err != nil {
+		return 0, nil, fmt.Errorf("coold %s %s on %s: parse status: %w", method, path, d.Host, err)
+	}
+	return status, []byte(stdout[:i]), nil
+}
+
+// command renders the curl one-liner. Unlike the firewall helpers it does
+// not pass -f: the status is printed after the body so the caller sees
+// coold's error message too.
+func (d *SSHDoer) command(method, path string, body []byte) string {
+	var b strings.Builder
+	b.WriteString(MgmtIPScript(d.Iface))
+	fmt.Fprintf(&b, `curl -sS --max-time %d -X %s `, int(RequestTimeout.Seconds()), method)
+	b.WriteString(`-H ` + ShellQuote("Authorization: Bearer "+d.Token) + ` `)
+	if body != nil {
+		b.WriteString(`-H 'Content-Type: application/json' -d ` + ShellQuote(string(body)) + ` `)
+	}
+	b.WriteString(`-w ` + ShellQuote(statusMarker+"%{http_code}") + ` `)
+	fmt.Fprintf(&b, `"http://$MGMT:%d"%s`, d.CooldPort, ShellQuote(path))
+	return b.String()
+}
+
+// Stream opens a streamed endpoint through a direct-tcpip channel from Host
+// to coold's mgmt IP, which is read from the interface first.
+func (d *SSHDoer) Stream(ctx context.Context, method, path string, body []byte, upgrade bool) (io.ReadCloser, error) {
+	if d.Tunnel == nil {
+		return nil, fmt.Errorf("coold %s %s on %s: streaming over SSH needs a tunnel", method, path, d.Host)
+	}
+	stdout, stderr, err := d.Runner.Run(ctx, d.Host, d.User, d.SSHPort, MgmtIPScript(d.Iface)+`echo "$MGMT"`)
+	if err != nil {
+		return nil, fmt.Errorf("coold mgmt IP on %s: %w (stderr: %s)", d.Host, err, strings.TrimSpace(stderr))
+	}
+	mgmt := strings.TrimSpace(stdout)
+	tunnel := &HTTPDoer{
+		BaseURL: fmt.Sprintf("http://%s:%d", mgmt, d.CooldPort),
+		Token:   d.Token,
+		HTTP: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return d.Tunnel.Dial(ctx, d.Host, d.User, d.SSHPort, network, addr)
+			},
+		}},
+	}
+	return tunnel.Stream(ctx, method, path, body, upgrade)
+}
+
+// MgmtIPScript sets $MGMT to coold's bind address on the remote host: the
+// first global address on the WireGuard interface, bracketed when IPv6. It
+// fails when the interface has none.
+func MgmtIPScript(iface string) string {
+	return fmt.Sprintf(
+		`MGMT=$(ip -o addr show %[1]s scope global 2>/dev/null | awk '{print $4}' | head -n 1 | cut -d/ -f1); `+
+			`test -n "$MGMT" || { echo "coold mgmt IP (%[1]s) not found on $(hostname) — is coold installed?" >&2; exit 1; }; `+
+			`case "$MGMT" in *:*) MGMT="[$MGMT]" ;; esac; `,
+		iface)
+}
+
+// ShellQuote wraps s in POSIX-shell single quotes.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// FallbackDoer tries Direct first and retries over Fallback when the direct
+// connection could not be made (tunnel down, no route). Only dial failures
+// are retried: once the request may have reached coold, repeating a create
+// or an exec elsewhere could run it twice.
+type FallbackDoer struct {
+	Direct   Doer
+	Fallback Doer
+}
+
+func (d *FallbackDoer) Do(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	status, resp, err := d.Direct.Do(ctx, method, path, body)
+	if IsDialError(err) {
+		return d.Fallback.Do(ctx, method, path, body)
+	}
+	return status, resp, err
+}
+
+// IsDialError reports whether err is a failure to connect, i.e. nothing was
+// sent.
+func IsDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// Stream opens a streamed endpoint on Direct, retrying on Fallback when the
+// direct connection could not be made. Both must be Streamers.
+func (d *FallbackDoer) Stream(ctx context.Context, method, path string, body []byte, upgrade bool) (io.ReadCloser, error) {
+	direct, ok := d.Direct.(Streamer)
+	if !ok {
+		return nil, fmt.Errorf("coold %s %s: transport %T cannot stream", method, path, d.Direct)
+	}
+	rc, err := direct.Stream(ctx, method, path, body, upgrade)
+	if !IsDialError(err) {
+		return rc, err
+	}
+	fallback, ok := d.Fallback.(Streamer)
+	if !ok {
+		return nil, err
+	}
+	return fallback.Stream(ctx, method, path, body, upgrade)
+}
@@ -0,0 +1,40 @@
+package coold
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// VolumeSpec creates a named volume.
+type VolumeSpec struct {
+	Name   string            `json:"name"`
+	Driver string            `json:"driver,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Volume is a named volume on the host.
+type Volume struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	Mountpoint string            `json:"mountpoint"`
+	CreatedAt  string            `json:"created_at,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// CreateVolume creates a named volume.
+func (c *Client) CreateVolume(ctx context.Context, spec VolumeSpec) error {
+	return c.call(ctx, http.MethodPost, "/volumes", spec, nil)
+}
+
+// RemoveVolume deletes volume name.
+func (c *Client) RemoveVolume(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name), nil, nil)
+}
+
+// GetVolume returns volume name.
+func (c *Client) GetVolume(ctx context.Context, name string) (Volume, error) {
+	var out Volume
+	err := c.call(ctx, http.MethodGet, "/volumes/"+url.PathEscape(name), nil, &out)
+	return out, err
+}
@@ -0,0 +1,94 @@
+// Package corrosion reads the Corrosion replica on mesh hosts. Corrosion's
+// API listens on the host's loopback only, so every query is SSH-bounced:
+// curl on the host against 127.0.0.1:<api-port>.
+package corrosion
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// DefaultAPIPort is corrosion's loopback API port. Must match
+// --corrosion-api-port used at `coolify init`.
+const DefaultAPIPort = 8080
+
+// BuildQuery returns the shell one-liner that runs stmt against the host's
+// local corrosion API. Corrosion answers with one JSON object per line:
+// columns, then rows, then eoq (or error).
+func BuildQuery(apiPort int, stmt string, params []string) (string, error) {
+	if params == nil {
+		params = []string{}
+	}
+	body, err := json.Marshal([]any{stmt, params})
+	if err != nil {
+		return "", err
+	}
+	return `curl -fsS --max-time 10 -X POST -H 'Content-Type: application/json' ` +
+		`-d ` + shellQuote(string(body)) + ` ` +
+		fmt.Sprintf(`http://127.0.0.1:%d/v1/queries`, apiPort), nil
+}
+
+// ParseRows decodes the row values of a /v1/queries response.
+func ParseRows(stdout string) ([][]any, error) {
+	var rows [][]any
+	sc := bufio.NewScanner(strings.NewReader(stdout))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var ev struct {
+			Row   []json.RawMessage `json:"row"`
+			Error json.RawMessage   `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			return nil, fmt.Errorf("parse corrosion response: %w (line: %s)", err, line)
+		}
+		if len(ev.Error) > 0 {
+			return nil, fmt.Errorf("corrosion query: %s", ev.Error)
+		}
+		// A row is [rowid, [values...]].
+		if len(ev.Row) != 2 {
+			continue
+		}
+		var values []any
+		if err := json.Unmarshal(ev.Row[1], &values); err != nil {
+			return nil, fmt.Errorf("parse corrosion row: %w", err)
+		}
+		rows = append(rows, values)
+	}
+	return rows, sc.Err()
+}
+
+// Query runs a read-only statement through corrosion's API on host and
+// returns the row values.
+func Query(
+	ctx context.Context,
+	runner ssh.Runner,
+	host, user string,
+	sshPort, apiPort int,
+	stmt string,
+	params ...string,
+) ([][]any, error) {
+	cmd, err := BuildQuery(apiPort, stmt, params)
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr, err := runner.Run(ctx, host, user, sshPort, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("corrosion query on %s: %w (stderr: %s)",
+			host, err, strings.TrimSpace(stderr))
+	}
+	return ParseRows(stdout)
+}
+
+// shellQuote wraps s in POSIX-shell single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,85 @@
+package corrosion
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRows(t *testing.T) {
+	rows, err := ParseRows(`{"columns":["container_name","container_ip"]}
+{"row":[1,["web","10.210.0.10"]]}
+{"row":[2,["db","10.210.1.10"]]}
+{"eoq":{"time":0.001}}
+`)
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"web", "10.210.0.10"}, {"db", "10.210.1.10"}}, rows)
+
+	_, err = ParseRows(`{"error":"no such table: service_endpoints"}`)
+	assert.ErrorContains(t, err, "no such table")
+}
+
+type fakeRunner struct {
+	stdout string
+	cmd    string
+}
+
+func (f *fakeRunner) Run(_ context.Context, _, _ string, _ int, cmd string) (string, string, error) {
+	f.cmd = cmd
+	return f.stdout, "", nil
+}
+
+func TestListEndpoints(t *testing.T) {
+	fr := &fakeRunner{stdout: `{"columns":["container_id"]}
+{"row":[1,["abc","web","","100.64.0.1","10.210.0.2","running","healthy",1700000000]]}
+{"eoq":{"time":0.001}}
+`}
+	eps, err := ListEndpoints(context.Background(), fr, "h1", "root", 22, 8080)
+	require.NoError(t, err)
+	assert.Equal(t, []Endpoint{{
+		ContainerID: "abc", Name: "web", HostMgmtIP: "100.64.0.1", ContainerIP: "10.210.0.2",
+		State: "running", Health: "healthy", UpdatedAt: 1700000000,
+	}}, eps)
+	assert.True(t, strings.HasSuffix(fr.cmd, "http://127.0.0.1:8080/v1/queries"))
+}
+
+func TestDiff(t *testing.T) {
+	web := Endpoint{ContainerID: "a", Name: "web", State: "running", Health: "starting", UpdatedAt: 1}
+	db := Endpoint{ContainerID: "b", Name: "db", State: "running", UpdatedAt: 1}
+	webHealthy := web
+	webHealthy.Health, webHealthy.UpdatedAt = "healthy", 2
+	dbResynced := db
+	dbResynced.UpdatedAt = 5
+	cache := Endpoint{ContainerID: "c", Name: "cache", State: "running"}
+
+	changes := Diff([]Endpoint{web, db}, []Endpoint{webHealthy, dbResynced, cache})
+	require.Len(t, changes, 2, "an updated_at bump alone is not a change")
+	assert.Equal(t, Change{Kind: ChangeUpdated, Old: web, New: webHealthy, Fields: []string{"health"}}, changes[0])
+	assert.Equal(t, Change{Kind: ChangeAdded, New: cache}, changes[1])
+
+	changes = Diff([]Endpoint{web, db}, []Endpoint{db})
+	assert.Equal(t, []Change{{Kind: ChangeRemoved, Old: web}}, changes)
+}
+
+func TestCompare(t *testing.T) {
+	web := Endpoint{ContainerID: "a", Name: "web", State: "running", Health: "healthy", UpdatedAt: 10}
+	webOld := web
+	webOld.Health, webOld.UpdatedAt = "starting", 5
+	db := Endpoint{ContainerID: "b", Name: "db", State: "running", UpdatedAt: 3}
+
+	assert.Empty(t, Compare(map[string][]Endpoint{"h1": {web, db}, "h2": {web, db}}))
+
+	incs := Compare(map[string][]Endpoint{
+		"h1": {web, db},
+		"h2": {webOld},
+		"h3": {web, db},
+	})
+	require.Len(t, incs, 2)
+	assert.Equal(t, Inconsistency{ContainerID: "b", Name: "db", Missing: []string{"h2"}}, incs[0])
+	assert.Equal(t, Inconsistency{ContainerID: "a", Name: "web", Stale: map[string][]string{"h2": {"health"}}}, incs[1])
+	assert.Equal(t, "h2 has an older health", incs[1].Describe())
+	assert.Equal(t, "missing on h2", incs[0].Describe())
+}
@@ -0,0 +1,216 @@
+package corrosion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// Endpoint is one row of the service_endpoints table as defined by
+// services.CoolifySchemaSQL.
+type Endpoint struct {
+	ContainerID string `json:"container_id"`
+	Name        string `json:"container_name"`
+	Namespace   string `json:"namespace"`
+	HostMgmtIP  string `json:"host_mgmt_ip"`
+	ContainerIP string `json:"container_ip"`
+	State       string `json:"state"`
+	Health      string `json:"health"`
+	// UpdatedAt is the unix time of coold's last upsert of the row.
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+const endpointsQuery = `SELECT container_id, container_name, namespace, host_mgmt_ip, ` +
+	`container_ip, state, health, updated_at FROM service_endpoints ` +
+	`ORDER BY namespace, container_name, container_id`
+
+// ListEndpoints reads every service_endpoints row from host's replica.
+func ListEndpoints(
+	ctx context.Context,
+	runner ssh.Runner,
+	host, user string,
+	sshPort, apiPort int,
+) ([]Endpoint, error) {
+	rows, err := Query(ctx, runner, host, user, sshPort, apiPort, endpointsQuery)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Endpoint, 0, len(rows))
+	for _, r := range rows {
+		if len(r) != 8 {
+			return nil, fmt.Errorf("service_endpoints on %s: expected 8 columns, got %d", host, len(r))
+		}
+		col := func(i int) string {
+			s, _ := r[i].(string)
+			return s
+		}
+		// JSON numbers decode as float64.
+		updated, _ := r[7].(float64)
+		out = append(out, Endpoint{
+			ContainerID: col(0),
+			Name:        col(1),
+			Namespace:   col(2),
+			HostMgmtIP:  col(3),
+			ContainerIP: col(4),
+			State:       col(5),
+			Health:      col(6),
+			UpdatedAt:   int64(updated),
+		})
+	}
+	return out, nil
+}
+
+// Change kinds reported by Diff.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeUpdated = "updated"
+)
+
+// Change is one difference between two snapshots of the table.
+type Change struct {
+	Kind string   `json:"kind"`
+	Old  Endpoint `json:"old,omitzero"`
+	New  Endpoint `json:"new,omitzero"`
+	// Fields names the columns that changed, for ChangeUpdated. A bare
+	// updated_at bump (coold's periodic resync) is not a change.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Diff returns the changes from before to after, keyed by container_id and
+// ordered like the table.
+func Diff(before, after []Endpoint) []Change {
+	old := make(map[string]Endpoint, len(before))
+	for _, ep := range before {
+		old[ep.ContainerID] = ep
+	}
+	var changes []Change
+	seen := make(map[string]bool, len(after))
+	for _, ep := range after {
+		seen[ep.ContainerID] = true
+		prev, ok := old[ep.ContainerID]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeAdded, New: ep})
+			continue
+		}
+		if fields := changedFields(prev, ep); len(fields) > 0 {
+			changes = append(changes, Change{Kind: ChangeUpdated, Old: prev, New: ep, Fields: fields})
+		}
+	}
+	for _, ep := range before {
+		if !seen[ep.ContainerID] {
+			changes = append(changes, Change{Kind: ChangeRemoved, Old: ep})
+		}
+	}
+	return changes
+}
+
+// changedFields names the columns other than updated_at that differ.
+func changedFields(a, b Endpoint) []string {
+	var fields []string
+	for _, f := range []struct {
+		name string
+		a, b string
+	}{
+		{"container_name", a.Name, b.Name},
+		{"namespace", a.Namespace, b.Namespace},
+		{"host_mgmt_ip", a.HostMgmtIP, b.HostMgmtIP},
+		{"container_ip", a.ContainerIP, b.ContainerIP},
+		{"state", a.State, b.State},
+		{"health", a.Health, b.Health},
+	} {
+		if f.a != f.b {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// Inconsistency is a row the replicas disagree on.
+type Inconsistency struct {
+	ContainerID string `json:"container_id"`
+	Name        string `json:"container_name"`
+	Namespace   string `json:"namespace"`
+	// Missing lists the hosts whose replica lacks the row.
+	Missing []string `json:"missing,omitempty"`
+	// Stale maps each host holding an older version of the row to the
+	// columns that differ from the newest version.
+	Stale map[string][]string `json:"stale,omitempty"`
+}
+
+// Compare reports the rows that differ between replicas, keyed by host.
+// The version with the newest updated_at is taken as the reference, as
+// Corrosion's last-write-wins merge will converge on it.
+func Compare(replicas map[string][]Endpoint) []Inconsistency {
+	hosts := make([]string, 0, len(replicas))
+	for h := range replicas {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+
+	byHost := make(map[string]map[string]Endpoint, len(hosts))
+	newest := map[string]Endpoint{}
+	for _, h := range hosts {
+		rows := make(map[string]Endpoint, len(replicas[h]))
+		for _, ep := range replicas[h] {
+			rows[ep.ContainerID] = ep
+			if cur, ok := newest[ep.ContainerID]; !ok || ep.UpdatedAt > cur.UpdatedAt {
+				newest[ep.ContainerID] = ep
+			}
+		}
+		byHost[h] = rows
+	}
+
+	var out []Inconsistency
+	for id, ref := range newest {
+		inc := Inconsistency{ContainerID: id, Name: ref.Name, Namespace: ref.Namespace}
+		for _, h := range hosts {
+			ep, ok := byHost[h][id]
+			if !ok {
+				inc.Missing = append(inc.Missing, h)
+				continue
+			}
+			if fields := changedFields(ep, ref); len(fields) > 0 {
+				if inc.Stale == nil {
+					inc.Stale = map[string][]string{}
+				}
+				inc.Stale[h] = fields
+			}
+		}
+		if len(inc.Missing) > 0 || len(inc.Stale) > 0 {
+			out = append(out, inc)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ContainerID < b.ContainerID
+	})
+	return out
+}
+
+// Describe renders what is inconsistent about the row, e.g.
+// "missing on h2; h3 has an older state, health".
+func (inc Inconsistency) Describe() string {
+	var parts []string
+	if len(inc.Missing) > 0 {
+		parts = append(parts, "missing on "+strings.Join(inc.Missing, ", "))
+	}
+	hosts := make([]string, 0, len(inc.Stale))
+	for h := range inc.Stale {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		parts = append(parts, h+" has an older "+strings.Join(inc.Stale[h], ", "))
+	}
+	return strings.Join(parts, "; ")
+}
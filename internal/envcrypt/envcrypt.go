@@ -0,0 +1,200 @@
+// Package envcrypt encrypts and decrypts .env files with age X25519 keys so
+// secrets can be committed to a repository and only decrypted in memory.
+//
+// Files use the standard age format (binary or ASCII-armored), so they can
+// also be handled with the upstream `age` tool.
+package envcrypt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+
+	"github.com/coollabsio/coolify-cli/internal/config"
+)
+
+// Environment variables consulted when no key file flag is given
+const (
+	// KeyEnv holds an AGE-SECRET-KEY-1... identity directly
+	KeyEnv = "COOLIFY_AGE_KEY"
+	// KeyFileEnv holds the path to an identity file
+	KeyFileEnv = "COOLIFY_AGE_KEY_FILE"
+)
+
+const binaryHeader = "age-encryption.org/v1"
+
+// ErrNoIdentity is returned when an encrypted file is read but no key is configured
+var ErrNoIdentity = errors.New("file is encrypted but no age key was found: pass --age-key-file or set " + KeyEnv + " or " + KeyFileEnv)
+
+// DefaultKeyFile is the identity file used when nothing else is configured
+func DefaultKeyFile() string {
+	return filepath.Join(filepath.Dir(config.Path()), "age.key")
+}
+
+// IsEncrypted reports whether data is an age file (binary or armored)
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(binaryHeader)) || IsArmored(data)
+}
+
+// IsArmored reports whether data is an ASCII-armored age file
+func IsArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(armor.Header))
+}
+
+// LoadIdentities resolves the identities used for decryption. keyFile wins,
+// then KeyEnv, then KeyFileEnv, then DefaultKeyFile if it exists. It returns
+// ErrNoIdentity when none is configured.
+func LoadIdentities(keyFile string) ([]age.Identity, error) {
+	if keyFile != "" {
+		return parseIdentityFile(keyFile)
+	}
+	if key := os.Getenv(KeyEnv); key != "" {
+		ids, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", KeyEnv, err)
+		}
+		return ids, nil
+	}
+	if path := os.Getenv(KeyFileEnv); path != "" {
+		return parseIdentityFile(path)
+	}
+	if _, err := os.Stat(DefaultKeyFile()); err == nil {
+		return parseIdentityFile(DefaultKeyFile())
+	}
+	return nil, ErrNoIdentity
+}
+
+func parseIdentityFile(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open age key file: %w", err)
+	}
+	defer f.Close()
+
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age key file %s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// ParseRecipients parses age1... recipients given inline and/or in a
+// recipients file (one per line, # comments allowed).
+func ParseRecipients(inline []string, recipientsFile string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, r := range inline {
+		rec, err := age.ParseX25519Recipient(strings.TrimSpace(r))
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", r, err)
+		}
+		recipients = append(recipients, rec)
+	}
+	if recipientsFile != "" {
+		f, err := os.Open(recipientsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open recipients file: %w", err)
+		}
+		defer f.Close()
+		recs, err := age.ParseRecipients(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse recipients file %s: %w", recipientsFile, err)
+		}
+		recipients = append(recipients, recs...)
+	}
+	return recipients, nil
+}
+
+// RecipientsFromIdentities returns the public keys of X25519 identities, used
+// to re-encrypt a file for the key that decrypted it.
+func RecipientsFromIdentities(ids []age.Identity) []age.Recipient {
+	var recipients []age.Recipient
+	for _, id := range ids {
+		if x, ok := id.(*age.X25519Identity); ok {
+			recipients = append(recipients, x.Recipient())
+		}
+	}
+	return recipients
+}
+
+// Encrypt encrypts plaintext to recipients, optionally ASCII-armored
+func Encrypt(plaintext []byte, recipients []age.Recipient, armored bool) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+
+	var buf bytes.Buffer
+	var dst io.Writer = &buf
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(&buf)
+		dst = armorWriter
+	}
+
+	w, err := age.Encrypt(dst, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if armorWriter != nil {
+		if err := armorWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to armor: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt decrypts an age file (binary or armored) with identities
+func Decrypt(data []byte, identities []age.Identity) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(data)
+	if IsArmored(data) {
+		src = armor.NewReader(bufio.NewReader(bytes.NewReader(bytes.TrimLeft(data, " \t\r\n"))))
+	}
+
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// ReadFile reads path ("-" for stdin) and transparently decrypts it when it
+// is an age file. Identities are only loaded when decryption is needed.
+func ReadFile(path, keyFile string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	ids, err := LoadIdentities(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data, ids)
+}
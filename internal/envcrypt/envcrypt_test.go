@@ -0,0 +1,85 @@
+package envcrypt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt_RoundTrip(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	plaintext := []byte("DB_PASSWORD=\"s3cret\"\n")
+
+	for _, armored := range []bool{false, true} {
+		ciphertext, err := Encrypt(plaintext, []age.Recipient{id.Recipient()}, armored)
+		require.NoError(t, err)
+		assert.True(t, IsEncrypted(ciphertext))
+		assert.Equal(t, armored, IsArmored(ciphertext))
+		assert.NotContains(t, string(ciphertext), "s3cret")
+
+		got, err := Decrypt(ciphertext, []age.Identity{id})
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+	}
+
+	assert.False(t, IsEncrypted(plaintext))
+}
+
+func TestDecrypt_WrongKey(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	other, _ := age.GenerateX25519Identity()
+	ciphertext, err := Encrypt([]byte("A=1\n"), []age.Recipient{id.Recipient()}, false)
+	require.NoError(t, err)
+
+	_, err = Decrypt(ciphertext, []age.Identity{other})
+	assert.Error(t, err)
+}
+
+func TestReadFile(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	dir := t.TempDir()
+
+	keyFile := filepath.Join(dir, "age.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(id.String()+"\n"), 0600))
+
+	plainPath := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(plainPath, []byte("A=1\n"), 0600))
+	got, err := ReadFile(plainPath, "")
+	require.NoError(t, err)
+	assert.Equal(t, "A=1\n", string(got))
+
+	ciphertext, err := Encrypt([]byte("A=2\n"), []age.Recipient{id.Recipient()}, true)
+	require.NoError(t, err)
+	encPath := filepath.Join(dir, ".env.age")
+	require.NoError(t, os.WriteFile(encPath, ciphertext, 0600))
+
+	got, err = ReadFile(encPath, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "A=2\n", string(got))
+
+	t.Setenv(KeyEnv, id.String())
+	got, err = ReadFile(encPath, "")
+	require.NoError(t, err)
+	assert.Equal(t, "A=2\n", string(got))
+}
+
+func TestParseRecipients(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "recipients.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# team\n"+id.Recipient().String()+"\n"), 0600))
+
+	recs, err := ParseRecipients([]string{id.Recipient().String()}, path)
+	require.NoError(t, err)
+	assert.Len(t, recs, 2)
+
+	_, err = ParseRecipients([]string{"not-a-key"}, "")
+	assert.Error(t, err)
+
+	assert.Len(t, RecipientsFromIdentities([]age.Identity{id}), 1)
+}
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/coollabsio/coolify-cli/internal/coold"
 	"github.com/coollabsio/coolify-cli/internal/ssh"
 )
 
@@ -16,51 +17,25 @@ import (
 const CooldAPIBasePath = "/api/v1/firewall"
 
 // CooldAPITokenPath is the remote file coold reads its bearer token from.
-// Kept in sync with internal/services/coold.go — the CLI falls back to
-// reading this file over SSH when the user hasn't supplied --coold-token.
-const CooldAPITokenPath = "/etc/coolify/api-token" //nolint:gosec // filesystem path, not a credential
+// The CLI falls back to reading this file over SSH when the user hasn't
+// supplied --coold-token.
+const CooldAPITokenPath = coold.TokenPath
 
 // FetchCooldToken SSHes into host and reads the coold bearer token at
-// CooldAPITokenPath. Each host generates its own random token at install
-// time (see EnsureCooldAPITokenCommand), so per-host fetch is the default
-// path when the user hasn't provided a global --coold-token override.
+// CooldAPITokenPath (see coold.FetchToken).
 func FetchCooldToken(
 	ctx context.Context,
 	runner ssh.Runner,
 	host, user string,
 	sshPort int,
 ) (string, error) {
-	cmd := "cat " + CooldAPITokenPath
-	stdout, stderr, err := runner.Run(ctx, host, user, sshPort, cmd)
-	if err != nil {
-		return "", fmt.Errorf("fetch coold token from %s: %w (stderr: %s)",
-			host, err, strings.TrimSpace(stderr))
-	}
-	tok := strings.TrimSpace(stdout)
-	if tok == "" {
-		return "", fmt.Errorf("coold token on %s is empty — is coold installed? (expected at %s)",
-			host, CooldAPITokenPath)
-	}
-	return tok, nil
-}
-
-// cooldRulePayload mirrors the JSON shape coold's REST API expects on POST
-// and returns on GET /allow. Kept aligned with coold/src/firewall/rule.rs:
-// namespace is required (defaults to "default" on the wire), src/dst are
-// string IPs, proto/port/id are omitted when absent.
-type cooldRulePayload struct {
-	Namespace string `json:"namespace"`
-	Src       string `json:"src"`
-	Dst       string `json:"dst"`
-	Proto     string `json:"proto,omitempty"`
-	Port      uint16 `json:"port,omitempty"`
-	ID        string `json:"id,omitempty"`
+	return coold.FetchToken(ctx, runner, host, user, sshPort)
 }
 
-// toAllowRule converts a payload coming back from coold into the CLI's
+// ruleFromWire converts a rule coming back from coold into the CLI's
 // AllowRule. The host field is filled in by the caller (it is the mesh host
 // the list came from, not part of the payload).
-func (p cooldRulePayload) toAllowRule() (AllowRule, bool) {
+func ruleFromWire(p coold.FirewallRule) (AllowRule, bool) {
 	src := net.ParseIP(p.Src)
 	dst := net.ParseIP(p.Dst)
 	if src == nil || dst == nil {
@@ -83,16 +58,16 @@ func (p cooldRulePayload) toAllowRule() (AllowRule, bool) {
 	return r, true
 }
 
-// allowRulePayload converts an AllowRule into the wire shape coold accepts.
+// wireRule converts an AllowRule into the wire shape coold accepts.
 // coold normalizes and computes the id itself, so we send only the tuple.
 // Empty namespace is materialized as "default" on the wire so older coold
 // builds with a default-only schema keep working.
-func allowRulePayload(r AllowRule) cooldRulePayload {
+func wireRule(r AllowRule) coold.FirewallRule {
 	ns := r.Namespace
 	if ns == "" {
 		ns = "default"
 	}
-	p := cooldRulePayload{
+	p := coold.FirewallRule{
 		Namespace: ns,
 		Src:       r.Src.String(),
 		Dst:       r.Dst.String(),
@@ -106,8 +81,8 @@ func allowRulePayload(r AllowRule) cooldRulePayload {
 
 // CooldApply POSTs r to coold's /allow endpoint on host. coold is reached
 // via SSH-bounce: SSH into host, curl localhost wg0 mgmt IP. This is the
-// transport of choice for the alpha because the CLI runs on a laptop that
-// isn't a mesh peer — only hosts inside the wg0 network can reach coold.
+// transport for a CLI machine that isn't a mesh peer — only hosts inside
+// the wg0 network can reach coold. Operator peers use DirectTransport instead.
 func CooldApply(
 	ctx context.Context,
 	runner ssh.Runner,
@@ -116,7 +91,7 @@ func CooldApply(
 	iface, token string,
 	r AllowRule,
 ) error {
-	body, err := json.Marshal(allowRulePayload(r))
+	body, err := json.Marshal(wireRule(r))
 	if err != nil {
 		return fmt.Errorf("marshal allow rule: %w", err)
 	}
@@ -170,14 +145,14 @@ func CooldList(
 	if stdout == "" {
 		return nil, nil
 	}
-	var payloads []cooldRulePayload
+	var payloads []coold.FirewallRule
 	if err := json.Unmarshal([]byte(stdout), &payloads); err != nil {
 		return nil, fmt.Errorf("parse coold list on %s: %w (body: %s)",
 			host, err, stdout)
 	}
 	out := make([]AllowRule, 0, len(payloads))
 	for _, p := range payloads {
-		r, ok := p.toAllowRule()
+		r, ok := ruleFromWire(p)
 		if !ok {
 			continue
 		}
@@ -203,18 +178,14 @@ func CooldListAll(
 	concurrency int,
 	namespace string,
 ) ([]AllowRule, []ssh.ServerResult[[]AllowRule]) {
-	results := ssh.ForEachServer(ctx, hosts, concurrency,
-		func(ctx context.Context, host string) ([]AllowRule, error) {
-			token, err := tokenFor(host)
-			if err != nil {
-				return nil, err
-			}
-			return CooldList(ctx, runner, host, user, sshPort, cooldPort, iface, token, namespace)
-		})
-	var all []AllowRule
-	for _, r := range results {
-		all = append(all, r.Result...)
-	}
+	return ListAllVia(ctx, &SSHTransport{
+		Runner: runner, User: user, SSHPort: sshPort, CooldPort: cooldPort,
+		Iface: iface, TokenFor: tokenFor,
+	}, hosts, concurrency, namespace)
+}
+
+// sortRules orders rules by host, namespace, src, dst and port.
+func sortRules(all []AllowRule) {
 	sort.Slice(all, func(i, j int) bool {
 		if all[i].Host != all[j].Host {
 			return all[i].Host < all[j].Host
@@ -232,7 +203,6 @@ func CooldListAll(
 		}
 		return all[i].Port < all[j].Port
 	})
-	return all, results
 }
 
 // shellSingleQuote wraps s in POSIX-shell single quotes, escaping any
@@ -247,25 +217,18 @@ func shellSingleQuote(s string) string {
 // `coolify init --wg-interface`.
 const DefaultWGInterface = "wg0"
 
-// mgmtIPScript discovers coold's bind IP on the remote host by reading the
-// first IPv4 address on the host's WireGuard interface. Emitted as part of
-// every curl command so the CLI doesn't need to track per-host mgmt IPs
-// (they are already encoded in the host's own WG interface).
-func mgmtIPScript(iface string) string {
-	return fmt.Sprintf(
-		`MGMT=$(ip -4 -o addr show %[1]s 2>/dev/null | awk '{print $4}' | cut -d/ -f1); `+
-			`test -n "$MGMT" || { echo "coold mgmt IP (%[1]s) not found on $(hostname) — is coold installed?" >&2; exit 1; }; `,
-		iface)
-}
+// bracketMgmtIP wraps an IPv6 $MGMT in brackets so it can sit in a URL.
+const bracketMgmtIP = `case "$MGMT" in *:*) MGMT="[$MGMT]" ;; esac; `
 
-// mgmtIPScriptSoft is the same as mgmtIPScript but treats a missing WG
+// mgmtIPScriptSoft is the same as coold.MgmtIPScript but treats a missing WG
 // interface as "no rules" rather than a failure. Used by list so a host
 // without coold is simply absent from the output instead of aborting the
 // whole fanout.
 func mgmtIPScriptSoft(iface string) string {
 	return fmt.Sprintf(
-		`MGMT=$(ip -4 -o addr show %s 2>/dev/null | awk '{print $4}' | cut -d/ -f1); `+
-			`if [ -z "$MGMT" ]; then echo '[]'; exit 0; fi; `,
+		`MGMT=$(ip -o addr show %s scope global 2>/dev/null | awk '{print $4}' | head -n 1 | cut -d/ -f1); `+
+			`if [ -z "$MGMT" ]; then echo '[]'; exit 0; fi; `+
+			bracketMgmtIP,
 		iface)
 }
 
@@ -275,7 +238,7 @@ func mgmtIPScriptSoft(iface string) string {
 // the curl invocation. Acceptable for alpha; TLS + stdin-fed tokens are a
 // follow-up.
 func buildCurlAllow(iface, token string, port int, body string) string {
-	return mgmtIPScript(iface) +
+	return coold.MgmtIPScript(iface) +
 		`curl -fsS --max-time 10 ` +
 		`-H ` + shellSingleQuote("Authorization: Bearer "+token) + ` ` +
 		`-H 'Content-Type: application/json' ` +
@@ -285,7 +248,7 @@ func buildCurlAllow(iface, token string, port int, body string) string {
 
 // buildCurlRevoke returns the shell one-liner that DELETEs rule id.
 func buildCurlRevoke(iface, token string, port int, id string) string {
-	return mgmtIPScript(iface) +
+	return coold.MgmtIPScript(iface) +
 		`curl -fsS --max-time 10 -o /dev/null ` +
 		`-H ` + shellSingleQuote("Authorization: Bearer "+token) + ` ` +
 		`-X DELETE ` +
@@ -44,7 +44,7 @@ func TestShellSingleQuote_Escapes(t *testing.T) {
 
 func TestBuildCurlAllow_Shape(t *testing.T) {
 	cmd := buildCurlAllow("wg0", "tok-xyz", 8443, `{"src":"10.0.0.1","dst":"10.0.0.2"}`)
-	assert.Contains(t, cmd, "ip -4 -o addr show wg0")
+	assert.Contains(t, cmd, "ip -o addr show wg0 scope global")
 	assert.Contains(t, cmd, "curl -fsS")
 	assert.Contains(t, cmd, "Authorization: Bearer tok-xyz")
 	assert.Contains(t, cmd, "Content-Type: application/json")
@@ -0,0 +1,114 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/coollabsio/coolify-cli/internal/corrosion"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// DefaultCorrosionAPIPort is corrosion's loopback API port. Must match
+// --corrosion-api-port used at `coolify init`.
+const DefaultCorrosionAPIPort = corrosion.DefaultAPIPort
+
+// ServiceEndpoint is one row of Corrosion's service_endpoints table: a
+// container coold has published, with the IP it holds on its bridge.
+type ServiceEndpoint struct {
+	ContainerID string
+	Name        string
+	Namespace   string // "" is the single-tenant default namespace
+	IP          net.IP
+	HostMgmtIP  net.IP
+	State       string
+}
+
+// CorrosionQuery runs a read-only statement through corrosion's API on
+// host (SSH-bounced, like the coold client) and returns the row values.
+func CorrosionQuery(
+	ctx context.Context,
+	runner ssh.Runner,
+	host, user string,
+	sshPort, apiPort int,
+	stmt string,
+	params ...string,
+) ([][]any, error) {
+	return corrosion.Query(ctx, runner, host, user, sshPort, apiPort, stmt, params...)
+}
+
+// ListServiceEndpoints reads every service_endpoints row from corrosion on
+// host. The table is replicated, so any mesh host gives the full view.
+func ListServiceEndpoints(
+	ctx context.Context,
+	runner ssh.Runner,
+	host, user string,
+	sshPort, apiPort int,
+) ([]ServiceEndpoint, error) {
+	rows, err := CorrosionQuery(ctx, runner, host, user, sshPort, apiPort,
+		`SELECT container_id, container_name, namespace, container_ip, host_mgmt_ip, state FROM service_endpoints`)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ServiceEndpoint, 0, len(rows))
+	for _, r := range rows {
+		if len(r) != 6 {
+			continue
+		}
+		col := func(i int) string {
+			s, _ := r[i].(string)
+			return s
+		}
+		ip := net.ParseIP(col(3))
+		if ip == nil {
+			continue
+		}
+		out = append(out, ServiceEndpoint{
+			ContainerID: col(0),
+			Name:        col(1),
+			Namespace:   col(2),
+			IP:          ip,
+			HostMgmtIP:  net.ParseIP(col(4)),
+			State:       col(5),
+		})
+	}
+	return out, nil
+}
+
+// HoldsIP reports whether the container still holds its bridge IP. Only
+// running, paused or restarting containers do; the published IP of a
+// stopped container may already belong to another.
+func (ep ServiceEndpoint) HoldsIP() bool {
+	switch ep.State {
+	case "running", "paused", "restarting":
+		return true
+	}
+	return false
+}
+
+// FindEndpoint returns the endpoint named name in namespace. coold leaves
+// rows of stopped containers in place, so a running row wins; more than one
+// running row is ambiguous.
+func FindEndpoint(eps []ServiceEndpoint, namespace, name string) (ServiceEndpoint, error) {
+	var matches, running []ServiceEndpoint
+	for _, ep := range eps {
+		if ep.Name != name || normalizeNamespace(ep.Namespace) != normalizeNamespace(namespace) {
+			continue
+		}
+		matches = append(matches, ep)
+		if ep.State == "running" {
+			running = append(running, ep)
+		}
+	}
+	switch {
+	case len(running) == 1:
+		return running[0], nil
+	case len(running) > 1:
+		return ServiceEndpoint{}, fmt.Errorf("container name %q is published by %d running containers", name, len(running))
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > 1:
+		return ServiceEndpoint{}, fmt.Errorf("container name %q has %d endpoints and none is running", name, len(matches))
+	}
+	return ServiceEndpoint{}, fmt.Errorf("no container named %q in namespace %s", name, normalizeNamespace(namespace))
+}
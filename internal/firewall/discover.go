@@ -18,28 +18,31 @@ type Container struct {
 	ID        string // short (12-char) podman ID
 	Name      string // podman container name
 	IP        net.IP // IP on the coolify-<ns>-mesh bridge network
+	// Labels are the container's podman labels, matched by policy selectors.
+	Labels map[string]string
 }
 
-// discoverScript prints one `id|name|ip` line per running container on the
-// target network. Piped through `podman inspect` to resolve the per-network
-// IP because `podman ps` doesn't surface that directly. `|| true` keeps the
-// script from erroring when podman is absent or the network has no members.
+// discoverScript prints one `id|name|ip|labels` line per running container
+// on the target network, labels rendered as `k=v,k=v,`. Piped through
+// `podman inspect` to resolve the per-network IP because `podman ps` doesn't
+// surface that directly. `|| true` keeps the script from erroring when
+// podman is absent or the network has no members.
 func discoverScript(networkName string) string {
 	return fmt.Sprintf(
 		`podman ps --filter network=%[1]s --format '{{.ID}}|{{.Names}}' 2>/dev/null | `+
 			`while IFS='|' read id name; do `+
 			`  [ -z "$id" ] && continue; `+
-			`  ip=$(podman inspect --format '{{(index .NetworkSettings.Networks %[2]q).IPAddress}}' "$id" 2>/dev/null); `+
-			`  printf '%%s|%%s|%%s\n' "$id" "$name" "$ip"; `+
+			`  info=$(podman inspect --format '{{(index .NetworkSettings.Networks %[2]q).IPAddress}}|{{range $k, $v := .Config.Labels}}{{$k}}={{$v}},{{end}}' "$id" 2>/dev/null); `+
+			`  printf '%%s|%%s|%%s\n' "$id" "$name" "$info"; `+
 			`done || true`,
 		networkName, networkName)
 }
 
-// ParseDiscoverLine parses one `id|name|ip` line from discoverScript.
-// Returns (_, false) when the line is blank or malformed.
+// ParseDiscoverLine parses one `id|name|ip[|labels]` line from
+// discoverScript. Returns (_, false) when the line is blank or malformed.
 func ParseDiscoverLine(line string) (id, name string, ip net.IP, ok bool) {
-	parts := strings.SplitN(strings.TrimSpace(line), "|", 3)
-	if len(parts) != 3 {
+	parts := strings.SplitN(strings.TrimSpace(line), "|", 4)
+	if len(parts) < 3 {
 		return "", "", nil, false
 	}
 	if parts[0] == "" || parts[1] == "" || parts[2] == "" {
@@ -56,6 +59,27 @@ func ParseDiscoverLine(line string) (id, name string, ip net.IP, ok bool) {
 	return id, parts[1], ip, true
 }
 
+// parseDiscoverLabels returns the labels field of a discoverScript line, or
+// nil when the line carries none.
+func parseDiscoverLabels(line string) map[string]string {
+	parts := strings.SplitN(strings.TrimSpace(line), "|", 4)
+	if len(parts) < 4 {
+		return nil
+	}
+	var labels map[string]string
+	for _, kv := range strings.Split(parts[3], ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			continue
+		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[k] = v
+	}
+	return labels
+}
+
 // DiscoverContainers SSHes into host and returns every container on
 // networkName (the podman bridge backing namespace) with its bridge IP.
 func DiscoverContainers(
@@ -78,6 +102,7 @@ func DiscoverContainers(
 		out = append(out, Container{
 			Host: host, Namespace: namespace,
 			ID: id, Name: name, IP: ip,
+			Labels: parseDiscoverLabels(line),
 		})
 	}
 	sort.Slice(out, func(i, j int) bool {
@@ -171,3 +196,55 @@ func DiscoverAllNamespaces(
 	})
 	return all, allResults
 }
+
+// DiscoverNamespaces SSHes into every host and lists every podman network
+// carrying the io.coolify.managed=true label, collecting the unique
+// io.coolify.namespace label values plus extra. Returns the per-host
+// results so host-level failures surface as warnings instead of aborting
+// the fanout.
+func DiscoverNamespaces(
+	ctx context.Context,
+	runner ssh.Runner,
+	hosts []string,
+	user string,
+	port int,
+	concurrency int,
+	extra ...string,
+) ([]string, []ssh.ServerResult[[]string]) {
+	// `podman network ls`'s `{{.Labels}}` renders as a comma-separated `k=v`
+	// string (not a map, unlike `podman network inspect`), so `index` can't be
+	// used — pull `io.coolify.namespace=<val>` out with sed instead.
+	script := `podman network ls --filter label=io.coolify.managed=true ` +
+		`--format '{{.Labels}}' 2>/dev/null | ` +
+		`sed -n 's/.*io\.coolify\.namespace=\([^,]*\).*/\1/p' || true`
+	results := ssh.ForEachServer(ctx, hosts, concurrency,
+		func(ctx context.Context, host string) ([]string, error) {
+			stdout, _, err := runner.Run(ctx, host, user, port, script)
+			if err != nil {
+				return nil, err
+			}
+			var nss []string
+			for _, line := range strings.Split(stdout, "\n") {
+				ns := strings.TrimSpace(line)
+				if ns != "" {
+					nss = append(nss, ns)
+				}
+			}
+			return nss, nil
+		})
+	seen := map[string]struct{}{}
+	for _, ns := range extra {
+		seen[ns] = struct{}{}
+	}
+	for _, r := range results {
+		for _, ns := range r.Result {
+			seen[ns] = struct{}{}
+		}
+	}
+	all := make([]string, 0, len(seen))
+	for ns := range seen {
+		all = append(all, ns)
+	}
+	sort.Strings(all)
+	return all, results
+}
@@ -20,6 +20,7 @@ func TestParseDiscoverLine(t *testing.T) {
 	}{
 		{"abcdef123456|web|10.210.0.10", true, "abcdef123456", "web", "10.210.0.10"},
 		{"abcdef1234567890|web|10.210.0.10", true, "abcdef123456", "web", "10.210.0.10"},
+		{"abcdef123456|web|10.210.0.10|app=web,tier=front,", true, "abcdef123456", "web", "10.210.0.10"},
 		{"|name|10.0.0.1", false, "", "", ""},
 		{"id|name|", false, "", "", ""},
 		{"id|name|not-an-ip", false, "", "", ""},
@@ -76,6 +77,18 @@ func TestDiscoverContainers(t *testing.T) {
 	assert.Equal(t, "10.210.0.11", got[0].IP.String())
 }
 
+func TestDiscoverContainers_ParsesLabels(t *testing.T) {
+	r := &fakeRunner{responses: map[string]string{
+		"podman ps": "abc111111111|web|10.210.0.10|app=web,tier=front,\ndef222222222|api|10.210.0.11|\n",
+	}}
+	got, err := DiscoverContainers(context.Background(), r, "h1", "root", 22,
+		"default", "coolify-default-mesh")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Nil(t, got[0].Labels)
+	assert.Equal(t, map[string]string{"app": "web", "tier": "front"}, got[1].Labels)
+}
+
 func TestDiscoverContainers_EmptyOutput(t *testing.T) {
 	r := &fakeRunner{responses: map[string]string{}}
 	got, err := DiscoverContainers(context.Background(), r, "h1", "root", 22,
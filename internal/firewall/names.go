@@ -0,0 +1,158 @@
+package firewall
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// NamedRule is an allow rule expressed by container name. The IP rule it
+// implies is installed in coold like any other; RuleID and Host record which
+// one, so `reconcile` can replace it when a container comes back with a new
+// IP and `revoke --by-name` can remove it wherever it currently lives.
+type NamedRule struct {
+	Namespace string `json:"namespace"`
+	SrcName   string `json:"src_name"`
+	DstName   string `json:"dst_name"`
+	Proto     string `json:"proto,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	RuleID    string `json:"rule_id,omitempty"` // cid of the installed IP rule
+	Host      string `json:"host,omitempty"`    // host the IP rule is installed on
+}
+
+// ID is the stable identity of the name tuple, independent of IPs.
+func (n NamedRule) ID() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "name|%s|%s|%s|%s|%d",
+		normalizeNamespace(n.Namespace), n.SrcName, n.DstName, strings.ToLower(n.Proto), n.Port)
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Resolve maps the rule's names to the IP rule that should be installed
+// now. hostFor turns the owning host's mgmt IP into its SSH host.
+func (n NamedRule) Resolve(eps []ServiceEndpoint, hostFor func(mgmtIP string) (string, bool)) (AllowRule, error) {
+	src, err := FindEndpoint(eps, n.Namespace, n.SrcName)
+	if err != nil {
+		return AllowRule{}, err
+	}
+	dst, err := FindEndpoint(eps, n.Namespace, n.DstName)
+	if err != nil {
+		return AllowRule{}, err
+	}
+	host, ok := hostFor(dst.HostMgmtIP.String())
+	if !ok {
+		return AllowRule{}, fmt.Errorf("%s runs on mgmt IP %s, which is not one of --servers", n.DstName, dst.HostMgmtIP)
+	}
+	return newAllowRule(host, normalizeNamespace(n.Namespace), src.IP, dst.IP, n.Proto, n.Port), nil
+}
+
+// NamedRules is the local record of name-based rules, kept next to the CLI
+// config like the mesh state ledger.
+type NamedRules struct {
+	Rules []NamedRule `json:"rules"`
+}
+
+// LoadNamedRules reads path. A missing file is an empty set.
+func LoadNamedRules(path string) (*NamedRules, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &NamedRules{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read named rules: %w", err)
+	}
+	var n NamedRules
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &n, nil
+}
+
+// Save atomically writes the set to path with mode 0600.
+func (n *NamedRules) Save(path string) error {
+	sort.Slice(n.Rules, func(i, j int) bool { return n.Rules[i].ID() < n.Rules[j].ID() })
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create named rules dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write named rules: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write named rules: %w", err)
+	}
+	return nil
+}
+
+// Put adds r, replacing an entry with the same ID.
+func (n *NamedRules) Put(r NamedRule) {
+	for i := range n.Rules {
+		if n.Rules[i].ID() == r.ID() {
+			n.Rules[i] = r
+			return
+		}
+	}
+	n.Rules = append(n.Rules, r)
+}
+
+// Get returns the entry with r's ID.
+func (n *NamedRules) Get(r NamedRule) (NamedRule, bool) {
+	for _, e := range n.Rules {
+		if e.ID() == r.ID() {
+			return e, true
+		}
+	}
+	return NamedRule{}, false
+}
+
+// Delete removes the entry with r's ID.
+func (n *NamedRules) Delete(r NamedRule) {
+	kept := n.Rules[:0]
+	for _, e := range n.Rules {
+		if e.ID() != r.ID() {
+			kept = append(kept, e)
+		}
+	}
+	n.Rules = kept
+}
+
+// Orphans returns the rules whose Src or Dst is a container address (inside
+// one of containerNets) held by no container in eps. Any-address ends and
+// IPs outside containerNets, such as an external source, never make a rule
+// an orphan.
+func Orphans(rules []AllowRule, eps []ServiceEndpoint, containerNets []*net.IPNet) []AllowRule {
+	owned := make(map[string]bool, len(eps))
+	for _, ep := range eps {
+		if ep.HoldsIP() {
+			owned[ep.IP.String()] = true
+		}
+	}
+	unowned := func(ip net.IP) bool {
+		if ip == nil || owned[ip.String()] {
+			return false
+		}
+		for _, n := range containerNets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	var out []AllowRule
+	for _, r := range rules {
+		if unowned(r.Src) || unowned(r.Dst) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
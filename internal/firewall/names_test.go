@@ -0,0 +1,87 @@
+package firewall
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEndpoints() []ServiceEndpoint {
+	return []ServiceEndpoint{
+		{Name: "web", IP: net.ParseIP("10.210.0.10"), HostMgmtIP: net.ParseIP("100.64.0.1"), State: "running"},
+		{Name: "db", IP: net.ParseIP("10.210.1.7"), HostMgmtIP: net.ParseIP("100.64.0.2"), State: "exited"},
+		{Name: "db", IP: net.ParseIP("10.210.1.10"), HostMgmtIP: net.ParseIP("100.64.0.2"), State: "running"},
+		{Name: "db", Namespace: "alpha", IP: net.ParseIP("10.220.1.10"), HostMgmtIP: net.ParseIP("100.64.0.2"), State: "running"},
+	}
+}
+
+func TestFindEndpoint(t *testing.T) {
+	ep, err := FindEndpoint(testEndpoints(), "default", "db")
+	require.NoError(t, err)
+	assert.Equal(t, "10.210.1.10", ep.IP.String(), "the running row wins")
+
+	ep, err = FindEndpoint(testEndpoints(), "alpha", "db")
+	require.NoError(t, err)
+	assert.Equal(t, "10.220.1.10", ep.IP.String())
+
+	_, err = FindEndpoint(testEndpoints(), "alpha", "web")
+	assert.ErrorContains(t, err, `no container named "web" in namespace alpha`)
+}
+
+func TestNamedRuleResolve(t *testing.T) {
+	hosts := map[string]string{"100.64.0.1": "h1", "100.64.0.2": "h2"}
+	hostFor := func(ip string) (string, bool) { h, ok := hosts[ip]; return h, ok }
+	n := NamedRule{Namespace: "default", SrcName: "web", DstName: "db", Proto: "tcp", Port: 5432}
+
+	r, err := n.Resolve(testEndpoints(), hostFor)
+	require.NoError(t, err)
+	assert.Equal(t, "h2", r.Host)
+	assert.Equal(t, "10.210.1.10", r.Dst.String())
+	assert.Equal(t, ComputeID("default", net.ParseIP("10.210.0.10"), net.ParseIP("10.210.1.10"), "tcp", 5432), RuleID(r))
+
+	withIP := n
+	withIP.RuleID, withIP.Host = "abc", "h9"
+	assert.Equal(t, n.ID(), withIP.ID(), "identity ignores the installed rule")
+
+	delete(hosts, "100.64.0.2")
+	_, err = n.Resolve(testEndpoints(), hostFor)
+	assert.ErrorContains(t, err, "not one of --servers")
+}
+
+func TestNamedRulesSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "firewall-names.json")
+	n, err := LoadNamedRules(path)
+	require.NoError(t, err)
+	assert.Empty(t, n.Rules)
+
+	r := NamedRule{Namespace: "default", SrcName: "web", DstName: "db", Port: 80, Proto: "tcp"}
+	n.Put(r)
+	r.RuleID = "abc"
+	n.Put(r)
+	require.Len(t, n.Rules, 1, "Put replaces by identity")
+	require.NoError(t, n.Save(path))
+
+	got, err := LoadNamedRules(path)
+	require.NoError(t, err)
+	prev, ok := got.Get(NamedRule{Namespace: "default", SrcName: "web", DstName: "db", Port: 80, Proto: "tcp"})
+	require.True(t, ok)
+	assert.Equal(t, "abc", prev.RuleID)
+	got.Delete(prev)
+	assert.Empty(t, got.Rules)
+}
+
+func TestOrphans(t *testing.T) {
+	live := AllowRule{Src: net.ParseIP("10.210.0.10"), Dst: net.ParseIP("10.210.1.10")}
+	stale := AllowRule{Src: net.ParseIP("10.210.0.10"), Dst: net.ParseIP("10.210.1.7")}
+	external := AllowRule{Src: net.ParseIP("192.0.2.1"), Dst: net.ParseIP("10.210.1.10")}
+	externalStale := AllowRule{Src: net.ParseIP("192.0.2.1"), Dst: net.ParseIP("10.210.1.7")}
+	anySrc := AllowRule{Dst: net.ParseIP("10.210.1.10")}
+	otherNet := AllowRule{Src: net.ParseIP("10.220.1.99"), Dst: net.ParseIP("10.210.1.10")}
+	_, pool, _ := net.ParseCIDR("10.210.0.0/16")
+	assert.Equal(t, []AllowRule{stale, externalStale},
+		Orphans([]AllowRule{live, stale, external, externalStale, anySrc, otherNet}, testEndpoints(), []*net.IPNet{pool}),
+		"only container addresses are checked")
+}
@@ -0,0 +1,288 @@
+package firewall
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Policy is a declarative allow-list for `coolify firewall apply -f`. Each
+// rule names its endpoints with selectors; the CLI expands them against the
+// discovered containers and converges coold to the resulting rule set.
+//
+//	namespace: default
+//	rules:
+//	  - from: web-*              # name glob
+//	    to: db1/postgres         # host/name
+//	    port: 5432
+//	  - from: {labels: {app: api}}
+//	    to: {host: db1, name: "cache-*"}
+//	    proto: udp
+//	    port: 6379
+type Policy struct {
+	// Namespace is the default namespace for rules that don't set one.
+	// Empty falls back to the --namespace flag.
+	Namespace string       `yaml:"namespace"`
+	Rules     []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule allows From → To:Port. Proto defaults to tcp when Port is
+// set; "any" (or no port and no proto) matches every protocol.
+type PolicyRule struct {
+	Namespace     string   `yaml:"namespace"`
+	From          Selector `yaml:"from"`
+	To            Selector `yaml:"to"`
+	Proto         string   `yaml:"proto"`
+	Port          int      `yaml:"port"`
+	Bidirectional bool     `yaml:"bidirectional"`
+}
+
+// Selector picks containers. The string form is either an exact reference
+// (name, short-id, raw IP, "host/name") or a glob over "name" or
+// "host/name". The mapping form matches host and name globs plus labels;
+// every set field must match.
+type Selector struct {
+	Ref    string            `yaml:"-"`
+	Host   string            `yaml:"host"`
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// UnmarshalYAML accepts both the string and the mapping form.
+func (s *Selector) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		s.Ref = strings.TrimSpace(n.Value)
+		return nil
+	}
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: selector must be a string or a mapping", n.Line)
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		switch k := n.Content[i].Value; k {
+		case "host", "name", "labels":
+		default:
+			return fmt.Errorf("line %d: unknown selector field %q (want host, name or labels)", n.Content[i].Line, k)
+		}
+	}
+	type plain Selector
+	return n.Decode((*plain)(s))
+}
+
+// String renders s for error messages and plan output.
+func (s Selector) String() string {
+	if s.Ref != "" {
+		return s.Ref
+	}
+	var parts []string
+	if s.Host != "" {
+		parts = append(parts, "host="+s.Host)
+	}
+	if s.Name != "" {
+		parts = append(parts, "name="+s.Name)
+	}
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, "label:"+k+"="+s.Labels[k])
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// IsExact reports whether s names one container and must resolve like an
+// `allow --from/--to` reference (ambiguity is an error). Globs and
+// mappings may match any number of containers.
+func (s Selector) IsExact() bool {
+	return s.Ref != "" && !strings.ContainsAny(s.Ref, "*?[")
+}
+
+// Matches reports whether c satisfies a glob or mapping selector.
+func (s Selector) Matches(c Container) bool {
+	host, name := s.Host, s.Name
+	if s.Ref != "" {
+		host, name = "", s.Ref
+		if h, n, ok := strings.Cut(s.Ref, "/"); ok {
+			host, name = h, n
+		}
+	}
+	if !globMatch(host, c.Host) || !globMatch(name, c.Name) {
+		return false
+	}
+	for k, v := range s.Labels {
+		if got, ok := c.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// globMatch treats an empty pattern as "match anything".
+func globMatch(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, s)
+	return err == nil && ok
+}
+
+// LoadPolicy reads and validates a policy file. JSON is accepted too, as a
+// YAML subset.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read policy: %w", err)
+	}
+	p, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return p, nil
+}
+
+// ParsePolicy decodes a policy document, rejecting unknown fields so a
+// typo doesn't silently widen or drop a rule.
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	for i := range p.Rules {
+		if err := p.Rules[i].normalize(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return &p, nil
+}
+
+// normalize validates r and fills in the protocol default.
+func (r *PolicyRule) normalize() error {
+	if r.From.isEmpty() {
+		return fmt.Errorf("from is required")
+	}
+	if r.To.isEmpty() {
+		return fmt.Errorf("to is required")
+	}
+	r.Proto = strings.ToLower(r.Proto)
+	switch r.Proto {
+	case "any":
+		r.Proto = ""
+	case "":
+		if r.Port > 0 {
+			r.Proto = "tcp"
+		}
+	case "tcp", "udp":
+	default:
+		return fmt.Errorf("proto must be tcp, udp or any (got %q)", r.Proto)
+	}
+	if r.Port < 0 || r.Port > 65535 {
+		return fmt.Errorf("port %d out of range", r.Port)
+	}
+	if r.Proto != "" && r.Port == 0 {
+		return fmt.Errorf("port is required when proto is %s", r.Proto)
+	}
+	return nil
+}
+
+func (s Selector) isEmpty() bool {
+	return s.Ref == "" && s.Host == "" && s.Name == "" && len(s.Labels) == 0
+}
+
+// ExpandRule turns a policy rule with resolved endpoints into the allow
+// rules it implies: one per (src, dst) pair, owned by dst's host, plus the
+// reverse rule on src's host when bidirectional. A container is never
+// allowed to itself.
+func ExpandRule(namespace string, r PolicyRule, from, to []Container) ([]AllowRule, error) {
+	var out []AllowRule
+	for _, src := range from {
+		for _, dst := range to {
+			if src.IP.Equal(dst.IP) {
+				continue
+			}
+			if dst.Host == "" {
+				return nil, fmt.Errorf("no mesh host owns destination %s", dst.IP)
+			}
+			out = append(out, newAllowRule(dst.Host, namespace, src.IP, dst.IP, r.Proto, r.Port))
+			if r.Bidirectional {
+				if src.Host == "" {
+					return nil, fmt.Errorf("bidirectional rule needs source %s to belong to a mesh host", src.IP)
+				}
+				out = append(out, newAllowRule(src.Host, namespace, dst.IP, src.IP, r.Proto, r.Port))
+			}
+		}
+	}
+	return out, nil
+}
+
+func newAllowRule(host, namespace string, src, dst net.IP, proto string, port int) AllowRule {
+	return AllowRule{
+		Host:      host,
+		Namespace: namespace,
+		Src:       src,
+		Dst:       dst,
+		Proto:     proto,
+		Port:      port,
+		Comment:   "cid:" + ComputeID(namespace, src, dst, proto, port),
+	}
+}
+
+// RuleChange is one step of a policy plan.
+type RuleChange struct {
+	Op   string // "add" | "remove"
+	Rule AllowRule
+}
+
+// RuleID returns r's ComputeID identity. Rules listed by an older coold
+// without an id fall back to hashing the tuple.
+func RuleID(r AllowRule) string {
+	if id := strings.TrimPrefix(r.Comment, "cid:"); id != "" {
+		return id
+	}
+	return ComputeID(r.Namespace, r.Src, r.Dst, r.Proto, r.Port)
+}
+
+// ruleKey identifies r on its host.
+func ruleKey(r AllowRule) string {
+	return r.Host + "|" + RuleID(r)
+}
+
+// DiffRules plans the changes that take existing to desired: every desired
+// rule missing on its host is added and, with prune, every existing rule
+// not in desired is removed. unchanged counts the desired rules already
+// present. Duplicate desired rules collapse into one.
+func DiffRules(desired, existing []AllowRule, prune bool) (changes []RuleChange, unchanged int) {
+	have := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		have[ruleKey(r)] = true
+	}
+	want := make(map[string]bool, len(desired))
+	for _, r := range desired {
+		k := ruleKey(r)
+		if want[k] {
+			continue
+		}
+		want[k] = true
+		if have[k] {
+			unchanged++
+			continue
+		}
+		changes = append(changes, RuleChange{Op: "add", Rule: r})
+	}
+	if prune {
+		for _, r := range existing {
+			if !want[ruleKey(r)] {
+				changes = append(changes, RuleChange{Op: "remove", Rule: r})
+			}
+		}
+	}
+	return changes, unchanged
+}
@@ -0,0 +1,98 @@
+package firewall
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(`
+namespace: alpha
+rules:
+  - from: web-*
+    to: db1/postgres
+    port: 5432
+  - from: {labels: {app: api}}
+    to: {host: db1, name: "cache-*"}
+    proto: udp
+    port: 6379
+    bidirectional: true
+  - from: 10.210.0.5
+    to: web
+    proto: any
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "alpha", p.Namespace)
+	require.Len(t, p.Rules, 3)
+
+	assert.Equal(t, "web-*", p.Rules[0].From.Ref)
+	assert.Equal(t, "tcp", p.Rules[0].Proto, "proto defaults to tcp with a port")
+	assert.Equal(t, map[string]string{"app": "api"}, p.Rules[1].From.Labels)
+	assert.Equal(t, "db1", p.Rules[1].To.Host)
+	assert.Equal(t, "cache-*", p.Rules[1].To.Name)
+	assert.True(t, p.Rules[1].Bidirectional)
+	assert.Empty(t, p.Rules[2].Proto)
+}
+
+func TestParsePolicy_Rejects(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":          "rules:\n  - from: a\n    to: b\n    prot: tcp\n",
+		"unknown selector field": "rules:\n  - from: {label: {a: b}}\n    to: b\n",
+		"missing to":             "rules:\n  - from: a\n",
+		"bad proto":              "rules:\n  - from: a\n    to: b\n    proto: icmp\n    port: 1\n",
+		"proto without port":     "rules:\n  - from: a\n    to: b\n    proto: udp\n",
+	}
+	for name, doc := range tests {
+		_, err := ParsePolicy([]byte(doc))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	c := Container{Host: "db1", Name: "cache-1", Labels: map[string]string{"app": "cache"}}
+	assert.True(t, Selector{Ref: "cache-*"}.Matches(c))
+	assert.True(t, Selector{Ref: "db*/cache-?"}.Matches(c))
+	assert.False(t, Selector{Ref: "db2/cache-*"}.Matches(c))
+	assert.True(t, Selector{Host: "db1", Labels: map[string]string{"app": "cache"}}.Matches(c))
+	assert.False(t, Selector{Labels: map[string]string{"app": "web"}}.Matches(c))
+	assert.True(t, Selector{Ref: "db1/cache-1"}.IsExact())
+	assert.False(t, Selector{Ref: "cache-*"}.IsExact())
+	assert.False(t, Selector{Name: "cache-1"}.IsExact())
+}
+
+func TestExpandRule(t *testing.T) {
+	web := Container{Host: "h1", Name: "web", IP: net.ParseIP("10.210.0.10")}
+	db := Container{Host: "h2", Name: "db", IP: net.ParseIP("10.210.1.10")}
+	r := PolicyRule{Proto: "tcp", Port: 5432, Bidirectional: true}
+
+	got, err := ExpandRule("default", r, []Container{web, db}, []Container{db})
+	require.NoError(t, err)
+	require.Len(t, got, 2, "db → db is skipped")
+	assert.Equal(t, "h2", got[0].Host)
+	assert.Equal(t, "cid:"+ComputeID("default", web.IP, db.IP, "tcp", 5432), got[0].Comment)
+	assert.Equal(t, "h1", got[1].Host, "reverse rule lives on the source host")
+	assert.True(t, got[1].Dst.Equal(web.IP))
+
+	_, err = ExpandRule("default", r, []Container{{IP: net.ParseIP("10.9.9.9")}}, []Container{db})
+	assert.ErrorContains(t, err, "bidirectional")
+}
+
+func TestDiffRules(t *testing.T) {
+	a := newAllowRule("h1", "default", net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), "tcp", 80)
+	b := newAllowRule("h1", "default", net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.3"), "tcp", 80)
+	stale := newAllowRule("h1", "default", net.ParseIP("10.0.0.9"), net.ParseIP("10.0.0.2"), "", 0)
+	legacy := stale
+	legacy.Comment = "" // listed without an id: matched by tuple hash
+
+	changes, unchanged := DiffRules([]AllowRule{a, b, b}, []AllowRule{a, legacy}, false)
+	assert.Equal(t, 1, unchanged)
+	assert.Equal(t, []RuleChange{{Op: "add", Rule: b}}, changes)
+
+	changes, _ = DiffRules([]AllowRule{a, b}, []AllowRule{a, legacy}, true)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "remove", changes[1].Op)
+	assert.Equal(t, RuleID(stale), RuleID(changes[1].Rule))
+}
@@ -0,0 +1,50 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// ProbeTimeoutSecs bounds a single probe connection attempt.
+const ProbeTimeoutSecs = 3
+
+// probeScript opens a TCP connection to dst:port from inside containerID's
+// network namespace. It runs the host's nc (or bash's /dev/tcp) under
+// nsenter so the container image needs no tooling of its own.
+func probeScript(containerID string, dst net.IP, port int) string {
+	inner := fmt.Sprintf(
+		`if command -v nc >/dev/null 2>&1; then nc -z -w %[1]d %[2]s %[3]d; `+
+			`else timeout %[1]d bash -c "exec 3<>/dev/tcp/%[2]s/%[3]d"; fi`,
+		ProbeTimeoutSecs, dst, port)
+	return fmt.Sprintf(
+		`pid=$(podman inspect --format '{{.State.Pid}}' %s 2>/dev/null) && [ "$pid" -gt 0 ] || `+
+			`{ echo "container not running" >&2; exit 2; }; `+
+			`nsenter -t "$pid" -n sh -c %s`,
+		shellSingleQuote(containerID), shellSingleQuote(inner))
+}
+
+// ProbeTCP attempts a real connection to dst:port from the source container
+// on host. A nil error means the connection was accepted; a refused or
+// dropped connection is returned as an error.
+func ProbeTCP(
+	ctx context.Context,
+	runner ssh.Runner,
+	host, user string,
+	sshPort int,
+	containerID string,
+	dst net.IP,
+	port int,
+) error {
+	_, stderr, err := runner.Run(ctx, host, user, sshPort, probeScript(containerID, dst, port))
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return fmt.Errorf("%w (%s)", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -46,11 +46,43 @@ type AllowRule struct {
 // rendered as 0 when unset. Mixed writers (CLI + coold) produce identical IDs
 // for identical tuples.
 func ComputeID(namespace string, src, dst net.IP, proto string, port int) string {
-	if namespace == "" {
-		namespace = "default"
-	}
 	h := sha256.New()
 	fmt.Fprintf(h, "%s|%s|%s|%s|%d",
-		namespace, src.String(), dst.String(), strings.ToLower(proto), port)
+		normalizeNamespace(namespace), src.String(), dst.String(), strings.ToLower(proto), port)
 	return hex.EncodeToString(h.Sum(nil))[:12]
 }
+
+// Admits reports whether r lets a new flow src → dst:port through
+// COOLIFY-ALLOW. An empty rule proto matches every protocol and a zero
+// port every port, as in the iptables rule coold renders; the namespace
+// must match exactly (empty meaning "default").
+func (r AllowRule) Admits(namespace string, src, dst net.IP, proto string, port int) bool {
+	if normalizeNamespace(r.Namespace) != normalizeNamespace(namespace) {
+		return false
+	}
+	if !r.Src.Equal(src) || !r.Dst.Equal(dst) {
+		return false
+	}
+	if r.Proto != "" && !strings.EqualFold(r.Proto, proto) {
+		return false
+	}
+	return r.Port == 0 || r.Port == port
+}
+
+// FirstMatch returns the first rule in rules that admits the flow, in chain
+// order. ok is false when COOLIFY-INTRA would drop it.
+func FirstMatch(rules []AllowRule, namespace string, src, dst net.IP, proto string, port int) (rule AllowRule, ok bool) {
+	for _, r := range rules {
+		if r.Admits(namespace, src, dst, proto, port) {
+			return r, true
+		}
+	}
+	return AllowRule{}, false
+}
+
+func normalizeNamespace(ns string) string {
+	if ns == "" {
+		return "default"
+	}
+	return ns
+}
@@ -43,3 +43,29 @@ func TestComputeID_EmptyNamespaceMatchesDefault(t *testing.T) {
 	def := ComputeID("default", net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), "tcp", 80)
 	assert.Equal(t, empty, def)
 }
+
+func TestFirstMatch(t *testing.T) {
+	src, dst := net.ParseIP("10.210.0.10"), net.ParseIP("10.210.1.10")
+	rules := []AllowRule{
+		{Namespace: "alpha", Src: src, Dst: dst},
+		{Namespace: "default", Src: src, Dst: dst, Proto: "tcp", Port: 443, Comment: "cid:https"},
+		{Src: src, Dst: dst, Proto: "udp", Comment: "cid:anyudp"},
+	}
+
+	r, ok := FirstMatch(rules, "default", src, dst, "tcp", 443)
+	assert.True(t, ok)
+	assert.Equal(t, "cid:https", r.Comment)
+
+	_, ok = FirstMatch(rules, "default", src, dst, "tcp", 80)
+	assert.False(t, ok, "port mismatch")
+
+	r, ok = FirstMatch(rules, "", src, dst, "UDP", 53)
+	assert.True(t, ok, "zero port matches any port, empty namespace is default")
+	assert.Equal(t, "cid:anyudp", r.Comment)
+
+	_, ok = FirstMatch(rules, "default", dst, src, "udp", 53)
+	assert.False(t, ok, "rules are directional")
+
+	_, ok = FirstMatch(rules, "alpha", src, dst, "udp", 9)
+	assert.True(t, ok, "empty proto matches any protocol")
+}
@@ -0,0 +1,183 @@
+package firewall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// Transport carries allow-rule calls to coold on a mesh host. host is
+// always the SSH address from --servers, whatever the route underneath.
+type Transport interface {
+	Apply(ctx context.Context, host string, r AllowRule) error
+	Revoke(ctx context.Context, host, id string) error
+	List(ctx context.Context, host, namespace string) ([]AllowRule, error)
+}
+
+// ErrNoDirectRoute is returned by DirectTransport for a host the operator
+// peer has no route to.
+var ErrNoDirectRoute = errors.New("no direct route to coold")
+
+// SSHTransport reaches coold by SSHing into the host and curling its wg0
+// mgmt IP there (CooldApply, CooldRevoke, CooldList).
+type SSHTransport struct {
+	Runner    ssh.Runner
+	User      string
+	SSHPort   int
+	CooldPort int
+	Iface     string
+	TokenFor  func(host string) (string, error)
+}
+
+func (t *SSHTransport) Apply(ctx context.Context, host string, r AllowRule) error {
+	token, err := t.TokenFor(host)
+	if err != nil {
+		return err
+	}
+	return CooldApply(ctx, t.Runner, host, t.User, t.SSHPort, t.CooldPort, t.Iface, token, r)
+}
+
+func (t *SSHTransport) Revoke(ctx context.Context, host, id string) error {
+	token, err := t.TokenFor(host)
+	if err != nil {
+		return err
+	}
+	return CooldRevoke(ctx, t.Runner, host, t.User, t.SSHPort, t.CooldPort, t.Iface, token, id)
+}
+
+func (t *SSHTransport) List(ctx context.Context, host, namespace string) ([]AllowRule, error) {
+	token, err := t.TokenFor(host)
+	if err != nil {
+		return nil, err
+	}
+	return CooldList(ctx, t.Runner, host, t.User, t.SSHPort, t.CooldPort, t.Iface, token, namespace)
+}
+
+// DirectTransport calls coold over HTTP from a machine that is itself a
+// mesh peer. Endpoint maps an SSH host to coold's base URL.
+type DirectTransport struct {
+	Endpoint func(host string) (string, bool)
+	TokenFor func(host string) (string, error)
+}
+
+func (t *DirectTransport) client(host string) (*coold.Client, error) {
+	base, ok := t.Endpoint(host)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", host, ErrNoDirectRoute)
+	}
+	token, err := t.TokenFor(host)
+	if err != nil {
+		return nil, err
+	}
+	return coold.New(coold.NewHTTPDoer(base, token)), nil
+}
+
+func (t *DirectTransport) Apply(ctx context.Context, host string, r AllowRule) error {
+	c, err := t.client(host)
+	if err != nil {
+		return err
+	}
+	if _, err := c.Allow(ctx, wireRule(r)); err != nil {
+		return fmt.Errorf("coold apply on %s: %w", host, err)
+	}
+	return nil
+}
+
+func (t *DirectTransport) Revoke(ctx context.Context, host, id string) error {
+	c, err := t.client(host)
+	if err != nil {
+		return err
+	}
+	if err := c.Revoke(ctx, id); err != nil {
+		return fmt.Errorf("coold revoke on %s: %w", host, err)
+	}
+	return nil
+}
+
+func (t *DirectTransport) List(ctx context.Context, host, namespace string) ([]AllowRule, error) {
+	c, err := t.client(host)
+	if err != nil {
+		return nil, err
+	}
+	wire, err := c.ListRules(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("coold list on %s: %w", host, err)
+	}
+	rules := make([]AllowRule, 0, len(wire))
+	for _, p := range wire {
+		if r, ok := ruleFromWire(p); ok {
+			r.Host = host
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// FallbackTransport tries Direct first and retries over Fallback when coold
+// could not be reached that way (no route, tunnel down, dial timeout). An
+// HTTP error from coold is final: coold saw the request, so repeating it
+// elsewhere would only mask the answer. Rule writes are idempotent by id,
+// so a retried apply whose first attempt did land is harmless.
+type FallbackTransport struct {
+	Direct   Transport
+	Fallback Transport
+}
+
+func (t *FallbackTransport) Apply(ctx context.Context, host string, r AllowRule) error {
+	err := t.Direct.Apply(ctx, host, r)
+	if shouldFallBack(err) {
+		return t.Fallback.Apply(ctx, host, r)
+	}
+	return err
+}
+
+func (t *FallbackTransport) Revoke(ctx context.Context, host, id string) error {
+	err := t.Direct.Revoke(ctx, host, id)
+	if shouldFallBack(err) {
+		return t.Fallback.Revoke(ctx, host, id)
+	}
+	return err
+}
+
+func (t *FallbackTransport) List(ctx context.Context, host, namespace string) ([]AllowRule, error) {
+	rules, err := t.Direct.List(ctx, host, namespace)
+	if shouldFallBack(err) {
+		return t.Fallback.List(ctx, host, namespace)
+	}
+	return rules, err
+}
+
+// shouldFallBack reports whether err means the direct route failed before
+// coold answered.
+func shouldFallBack(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var httpErr *coold.HTTPError
+	return !errors.As(err, &httpErr)
+}
+
+// ListAllVia fans t.List across every host in parallel and returns a
+// stably-sorted flattened slice plus the per-host results, like
+// CooldListAll.
+func ListAllVia(
+	ctx context.Context,
+	t Transport,
+	hosts []string,
+	concurrency int,
+	namespace string,
+) ([]AllowRule, []ssh.ServerResult[[]AllowRule]) {
+	results := ssh.ForEachServer(ctx, hosts, concurrency,
+		func(ctx context.Context, host string) ([]AllowRule, error) {
+			return t.List(ctx, host, namespace)
+		})
+	var all []AllowRule
+	for _, r := range results {
+		all = append(all, r.Result...)
+	}
+	sortRules(all)
+	return all, results
+}
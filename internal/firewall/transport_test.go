@@ -0,0 +1,150 @@
+package firewall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/coold"
+)
+
+// fakeCoold is an in-memory stand-in for coold's allow-rule routes.
+type fakeCoold struct {
+	mu    sync.Mutex
+	token string
+	rules map[string]coold.FirewallRule
+}
+
+func newFakeCoold(t *testing.T, token string) (*fakeCoold, *httptest.Server) {
+	t.Helper()
+	f := &fakeCoold{token: token, rules: map[string]coold.FirewallRule{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeCoold) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, CooldAPIBasePath)
+	switch {
+	case r.Method == http.MethodPost && path == "/allow":
+		var p coold.FirewallRule
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.ID = ComputeID(p.Namespace, net.ParseIP(p.Src), net.ParseIP(p.Dst), p.Proto, int(p.Port))
+		f.rules[p.ID] = p
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": p.ID})
+	case r.Method == http.MethodGet && path == "/allow":
+		ns := r.URL.Query().Get("namespace")
+		out := []coold.FirewallRule{}
+		for _, p := range f.rules {
+			if ns == "" || p.Namespace == ns {
+				out = append(out, p)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/allow/"):
+		delete(f.rules, strings.TrimPrefix(path, "/allow/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func testRule(src, dst string, port int) AllowRule {
+	return newAllowRule("h1", "default", net.ParseIP(src), net.ParseIP(dst), "tcp", port)
+}
+
+func staticToken(string) (string, error) { return "tok", nil }
+
+func TestDirectTransport_SetsHostAndRejectsUnknownHost(t *testing.T) {
+	_, srv := newFakeCoold(t, "tok")
+	tr := &DirectTransport{
+		Endpoint: func(host string) (string, bool) { return srv.URL, host == "h1" },
+		TokenFor: staticToken,
+	}
+	ctx := context.Background()
+
+	require.NoError(t, tr.Apply(ctx, "h1", testRule("10.210.0.2", "10.210.1.2", 80)))
+	rules, err := tr.List(ctx, "h1", "")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "h1", rules[0].Host)
+
+	_, err = tr.List(ctx, "h2", "")
+	assert.ErrorIs(t, err, ErrNoDirectRoute)
+}
+
+func TestFallbackTransport_FallsBackWhenCooldUnreachable(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	runner := &fakeCooldRunner{responses: map[string]string{
+		"/allow": `[{"namespace":"default","src":"10.0.0.1","dst":"10.0.0.2","id":"abc123def456"}]`,
+	}}
+	tr := &FallbackTransport{
+		Direct: &DirectTransport{
+			Endpoint: func(string) (string, bool) { return deadURL, true },
+			TokenFor: staticToken,
+		},
+		Fallback: &SSHTransport{Runner: runner, User: "root", SSHPort: 22,
+			CooldPort: 8443, Iface: "wg0", TokenFor: staticToken},
+	}
+
+	rules, err := tr.List(context.Background(), "h1", "")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "h1", rules[0].Host)
+	assert.Len(t, runner.calls, 1, "the list should have been retried over SSH")
+}
+
+func TestFallbackTransport_KeepsCooldRefusal(t *testing.T) {
+	_, srv := newFakeCoold(t, "other")
+	runner := &fakeCooldRunner{}
+	tr := &FallbackTransport{
+		Direct: &DirectTransport{
+			Endpoint: func(string) (string, bool) { return srv.URL, true },
+			TokenFor: staticToken,
+		},
+		Fallback: &SSHTransport{Runner: runner, TokenFor: staticToken},
+	}
+
+	err := tr.Apply(context.Background(), "h1", testRule("10.210.0.2", "10.210.1.2", 80))
+	var httpErr *coold.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Empty(t, runner.calls, "a coold refusal must not be retried over SSH")
+}
+
+func TestListAllVia_SortsAcrossHosts(t *testing.T) {
+	_, srv := newFakeCoold(t, "tok")
+	tr := &DirectTransport{
+		Endpoint: func(string) (string, bool) { return srv.URL, true },
+		TokenFor: staticToken,
+	}
+	require.NoError(t, tr.Apply(context.Background(), "h1", testRule("10.210.0.9", "10.210.1.2", 80)))
+	require.NoError(t, tr.Apply(context.Background(), "h1", testRule("10.210.0.2", "10.210.1.2", 80)))
+
+	all, results := ListAllVia(context.Background(), tr, []string{"h2", "h1"}, 2, "")
+	require.Len(t, results, 2)
+	require.Len(t, all, 4)
+	assert.Equal(t, "h1", all[0].Host)
+	assert.Equal(t, "10.210.0.2", all[0].Src.String())
+	assert.Equal(t, "h2", all[3].Host)
+}
@@ -37,3 +37,81 @@ type FirewallListOutput struct {
 type FirewallAllowOutput struct {
 	Rules []AllowRuleRow `json:"rules"`
 }
+
+// FirewallPolicyChangeRow is one planned or applied change of
+// `firewall apply -f`.
+type FirewallPolicyChangeRow struct {
+	Op        string `json:"op"`
+	Host      string `json:"host"`
+	Namespace string `json:"namespace"`
+	ID        string `json:"id"`
+	Src       string `json:"src"`
+	Dst       string `json:"dst"`
+	Proto     string `json:"proto,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	Status    string `json:"status"`
+}
+
+// FirewallPolicyOutput is the JSON output for `firewall apply -f`.
+type FirewallPolicyOutput struct {
+	DryRun    bool                      `json:"dry_run"`
+	Changes   []FirewallPolicyChangeRow `json:"changes"`
+	Unchanged int                       `json:"unchanged"`
+	Errors    []string                  `json:"errors,omitempty"`
+}
+
+// FirewallCheckOutput is the verdict of `firewall check`.
+type FirewallCheckOutput struct {
+	Src       string `json:"src"`
+	SrcHost   string `json:"src_host,omitempty"`
+	Dst       string `json:"dst"`
+	DstHost   string `json:"dst_host"`
+	Namespace string `json:"namespace"`
+	Proto     string `json:"proto,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	Verdict   string `json:"verdict"`
+	Rule      string `json:"rule,omitempty"`
+	Probe     string `json:"probe,omitempty"`
+}
+
+// FirewallNamedRuleRow is one name-based rule in `firewall reconcile`.
+type FirewallNamedRuleRow struct {
+	Namespace string `json:"namespace"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Proto     string `json:"proto,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	Host      string `json:"host"`
+	RuleID    string `json:"rule_id"`
+	Status    string `json:"status"`
+}
+
+// FirewallReconcileOutput is the JSON output for `firewall reconcile`.
+type FirewallReconcileOutput struct {
+	DryRun bool                   `json:"dry_run"`
+	Rules  []FirewallNamedRuleRow `json:"rules"`
+}
+
+// FirewallGCOutput is the JSON output for `firewall gc`.
+type FirewallGCOutput struct {
+	DryRun  bool                      `json:"dry_run"`
+	Removed []FirewallPolicyChangeRow `json:"removed"`
+	Errors  []string                  `json:"errors,omitempty"`
+}
+
+// FirewallOperatorHostRow is one host of `firewall operator join/leave`.
+type FirewallOperatorHostRow struct {
+	Host      string `json:"host"`
+	MgmtIP    string `json:"mgmt_ip"`
+	PublicKey string `json:"public_key"`
+	Status    string `json:"status"`
+}
+
+// FirewallOperatorOutput is the JSON output for `firewall operator join`
+// and `leave`.
+type FirewallOperatorOutput struct {
+	MgmtIP    string                    `json:"mgmt_ip"`
+	PublicKey string                    `json:"public_key"`
+	Hosts     []FirewallOperatorHostRow `json:"hosts"`
+	Errors    []string                  `json:"errors,omitempty"`
+}
@@ -0,0 +1,113 @@
+package models
+
+// HostInfoRow is a table-friendly row for `coolify host info`.
+type HostInfoRow struct {
+	Host   string `json:"host"`
+	Name   string `json:"hostname"`
+	OS     string `json:"os"`
+	Kernel string `json:"kernel"`
+	CPUs   int    `json:"cpus"`
+	Memory string `json:"memory"`
+	Load   string `json:"load"`
+	Podman string `json:"podman"`
+	Coold  string `json:"coold"`
+	// WGPeers is "<up>/<total>" peers with a recent handshake.
+	WGPeers string `json:"wg_peers"`
+}
+
+// HostContainerRow is a table-friendly row for `coolify host containers`.
+type HostContainerRow struct {
+	Host      string `json:"host"`
+	Namespace string `json:"namespace,omitempty"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Image     string `json:"image"`
+	State     string `json:"state"`
+	Status    string `json:"status"`
+}
+
+// HostStatsRow is a table-friendly row for `coolify host stats`.
+type HostStatsRow struct {
+	Host   string `json:"host"`
+	Name   string `json:"name"`
+	CPU    string `json:"cpu"`
+	Memory string `json:"memory"`
+	NetIO  string `json:"net_io"`
+	PIDs   int    `json:"pids"`
+}
+
+// HostImageRow is a table-friendly row for `coolify host images list/pull/rm`.
+type HostImageRow struct {
+	Host   string `json:"host"`
+	Ref    string `json:"ref"`
+	Digest string `json:"digest,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// HostVolumeRow is a table-friendly row for `coolify host volumes`.
+type HostVolumeRow struct {
+	Host       string `json:"host"`
+	Name       string `json:"name"`
+	Driver     string `json:"driver"`
+	Mountpoint string `json:"mountpoint"`
+}
+
+// HostNetworkRow is a table-friendly row for `coolify host networks`.
+type HostNetworkRow struct {
+	Host    string `json:"host"`
+	Name    string `json:"name"`
+	Driver  string `json:"driver"`
+	Subnets string `json:"subnets"`
+}
+
+// HostDNSRow is a table-friendly row for `coolify host dns lookup`.
+type HostDNSRow struct {
+	Host    string `json:"host"`
+	Name    string `json:"name"`
+	Records string `json:"records"`
+	TTL     int    `json:"ttl"`
+	Source  string `json:"source,omitempty"`
+}
+
+// HostInfoOutput is the JSON output for `host info`.
+type HostInfoOutput struct {
+	Hosts  []HostInfoRow `json:"hosts"`
+	Errors []string      `json:"errors,omitempty"`
+}
+
+// HostContainersOutput is the JSON output for `host containers`.
+type HostContainersOutput struct {
+	Containers []HostContainerRow `json:"containers"`
+	Errors     []string           `json:"errors,omitempty"`
+}
+
+// HostStatsOutput is the JSON output for `host stats`.
+type HostStatsOutput struct {
+	Stats  []HostStatsRow `json:"stats"`
+	Errors []string       `json:"errors,omitempty"`
+}
+
+// HostImagesOutput is the JSON output for `host images`.
+type HostImagesOutput struct {
+	Images []HostImageRow `json:"images"`
+	Errors []string       `json:"errors,omitempty"`
+}
+
+// HostVolumesOutput is the JSON output for `host volumes`.
+type HostVolumesOutput struct {
+	Volumes []HostVolumeRow `json:"volumes"`
+	Errors  []string        `json:"errors,omitempty"`
+}
+
+// HostNetworksOutput is the JSON output for `host networks`.
+type HostNetworksOutput struct {
+	Networks []HostNetworkRow `json:"networks"`
+	Errors   []string         `json:"errors,omitempty"`
+}
+
+// HostDNSOutput is the JSON output for `host dns lookup`.
+type HostDNSOutput struct {
+	Answers []HostDNSRow `json:"answers"`
+	Errors  []string     `json:"errors,omitempty"`
+}
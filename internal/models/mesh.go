@@ -46,3 +46,122 @@ type ApplyOutput struct {
 	Results  []ApplyResultRow  `json:"results"`
 	Verified []VerifyResultRow `json:"verified"`
 }
+
+// MeshStateRow is a table-friendly row for one host in the mesh state ledger.
+type MeshStateRow struct {
+	Server           string `json:"server"`
+	MgmtIP           string `json:"mgmt_ip"`
+	Subnets          string `json:"subnets"`
+	PublicKey        string `json:"public_key"`
+	CooldVersion     string `json:"coold_version"`
+	CorrosionVersion string `json:"corrosion_version"`
+}
+
+// MeshHostStatusRow is a table-friendly row for one host in the mesh status.
+type MeshHostStatusRow struct {
+	Server    string `json:"server"`
+	MgmtIP    string `json:"mgmt_ip"`
+	WireGuard string `json:"wireguard"`
+	Peers     int    `json:"peers"`
+	Coold     string `json:"coold"`
+	Corrosion string `json:"corrosion"`
+	Scheduler string `json:"scheduler"`
+}
+
+// MeshLinkRow is one directed link of the mesh status matrix.
+type MeshLinkRow struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Peered bool   `json:"peered"`
+	// HandshakeAgeSeconds is -1 when no handshake has completed.
+	HandshakeAgeSeconds int64 `json:"handshake_age_seconds"`
+	// Reachable is nil when the link was not pinged.
+	Reachable *bool  `json:"reachable,omitempty"`
+	Status    string `json:"status"`
+}
+
+// MeshProblemRow is a table-friendly row for one mesh status finding.
+type MeshProblemRow struct {
+	Server   string `json:"server"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// MeshStatusOutput is the structured JSON output for the status command.
+type MeshStatusOutput struct {
+	Status   string              `json:"status"`
+	Hosts    []MeshHostStatusRow `json:"hosts"`
+	Links    []MeshLinkRow       `json:"links"`
+	Problems []MeshProblemRow    `json:"problems"`
+}
+
+// HostJWTRow is a table-friendly row for `coolify init jwt list`: the
+// decoded host JWT of one server.
+type HostJWTRow struct {
+	Server  string `json:"server"`
+	Subject string `json:"subject"`
+	Caps    string `json:"caps"`
+	Key     string `json:"key"`
+	Issued  string `json:"issued"`
+	Expires string `json:"expires"`
+	Status  string `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// HostJWTListOutput is the structured JSON output for `coolify init jwt list`.
+type HostJWTListOutput struct {
+	Status string `json:"status"`
+	// Keys are the IDs of the keys the scheduler trusts, current first.
+	// Empty without --central.
+	Keys   []string     `json:"keys,omitempty"`
+	Tokens []HostJWTRow `json:"tokens"`
+}
+
+// MeshEndpointRow is a table-friendly row for `coolify mesh endpoints list`:
+// one service_endpoints row, with the server its host_mgmt_ip belongs to.
+type MeshEndpointRow struct {
+	Server    string `json:"server"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	IP        string `json:"ip"`
+	State     string `json:"state"`
+	Health    string `json:"health"`
+	Updated   string `json:"updated"`
+}
+
+// MeshEndpointsOutput is the JSON output for `mesh endpoints list`.
+type MeshEndpointsOutput struct {
+	// Source is the server whose replica was read.
+	Source    string            `json:"source"`
+	Endpoints []MeshEndpointRow `json:"endpoints"`
+}
+
+// MeshEndpointChangeRow is one line of `mesh endpoints watch`.
+type MeshEndpointChangeRow struct {
+	Time      string `json:"time"`
+	Change    string `json:"change"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	Server    string `json:"server"`
+	Detail    string `json:"detail"`
+}
+
+// MeshEndpointInconsistencyRow is a row the replicas disagree on, for
+// `mesh endpoints check`.
+type MeshEndpointInconsistencyRow struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	Problem   string `json:"problem"`
+}
+
+// MeshEndpointsCheckOutput is the JSON output for `mesh endpoints check`.
+type MeshEndpointsCheckOutput struct {
+	Status string `json:"status"`
+	// Replicas maps each server read to its row count.
+	Replicas        map[string]int                 `json:"replicas"`
+	Inconsistencies []MeshEndpointInconsistencyRow `json:"inconsistencies"`
+	Errors          []string                       `json:"errors,omitempty"`
+}
@@ -1,8 +1,38 @@
+// Package parser reads and writes .env files.
+//
+// By default the legacy line-based format is parsed:
+//
+//	# full-line comment
+//	KEY=value                  the value is taken verbatim, including any
+//	                           whitespace or '#' after the '='
+//	KEY="value" / KEY='value'  a value that starts and ends with the same
+//	                           quote has it stripped; nothing is unescaped
+//	KEY="line1
+//	line2"                     quoted values may span lines and end on the
+//	                           first line that ends with the opening quote
+//
+// ParseOptions.Dotenv opts into the full dotenv dialect instead:
+//
+//	KEY=value                  unquoted, surrounding whitespace is trimmed
+//	KEY=value # comment        '#' starts a comment when preceded by whitespace
+//	export KEY=value           an optional "export " prefix is ignored
+//	KEY='literal $VALUE'       single quotes: no escapes, never interpolated
+//	KEY="a\nb ${OTHER}"        double quotes: escapes \n \r \t \" \\ \$
+//	KEY="line1
+//	line2" # comment           quoted values may span lines and be followed
+//	                           by a comment
+//
+// When ParseOptions.Interpolate is set, ${NAME}, ${NAME:-default} and $NAME
+// in unquoted and double-quoted values are replaced with earlier keys of the
+// same file or ParseOptions.Lookup. Unresolvable references are kept verbatim
+// so Coolify can still resolve them server side. Single-quoted values are
+// literal in both formats, matching Coolify's is_literal semantics, and are
+// reported with EnvVar.Literal set.
 package parser
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -11,98 +41,413 @@ import (
 type EnvVar struct {
 	Key   string
 	Value string
+	// Literal is true for single-quoted values, which are never interpolated.
+	Literal bool
+	// Line is the 1-based line the definition starts on.
+	Line int
 }
 
-// ParseEnvFile parses a .env file and returns a slice of environment variables
-// Supports:
-// - KEY=value
-// - KEY="value"
-// - KEY='value'
-// - Multiline values with quotes
-// - Comments (lines starting with #)
-// - Empty lines
+// ParseOptions controls optional parser features
+type ParseOptions struct {
+	// Dotenv parses the full dotenv dialect instead of the legacy format.
+	Dotenv bool
+	// Interpolate expands ${NAME} references in unquoted and double-quoted values.
+	Interpolate bool
+	// Lookup resolves names not defined earlier in the file, e.g. os.LookupEnv.
+	Lookup func(name string) (string, bool)
+}
+
+// ParseError is returned for malformed input and carries the offending line
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid format at line %d: %s", e.Line, e.Msg)
+}
+
+// StdinPath is the file path that makes ParseEnvFile read from stdin
+const StdinPath = "-"
+
+// ParseEnvFile parses a .env file and returns a slice of environment variables.
+// A path of "-" reads from stdin. See the package documentation for the dialect.
 func ParseEnvFile(filepath string) ([]EnvVar, error) {
+	return ParseEnvFileWithOptions(filepath, ParseOptions{})
+}
+
+// ParseEnvFileWithOptions is ParseEnvFile with optional features enabled
+func ParseEnvFileWithOptions(filepath string, opts ParseOptions) ([]EnvVar, error) {
+	if filepath == StdinPath {
+		return ParseEnv(os.Stdin, opts)
+	}
+
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
+	return ParseEnv(file, opts)
+}
+
+// ParseEnv parses .env content from r
+func ParseEnv(r io.Reader, opts ParseOptions) ([]EnvVar, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	p := &envParser{
+		src:      strings.ReplaceAll(string(data), "\r\n", "\n"),
+		line:     1,
+		opts:     opts,
+		resolved: make(map[string]string),
+	}
+	return p.parse()
+}
+
+type envParser struct {
+	src      string
+	pos      int
+	line     int
+	opts     ParseOptions
+	resolved map[string]string
+}
+
+func (p *envParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *envParser) peek() byte {
+	return p.src[p.pos]
+}
+
+// advance consumes one byte, keeping the line counter in sync
+func (p *envParser) advance() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *envParser) skipBlanks() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// restOfLine consumes up to (not including) the next newline
+func (p *envParser) restOfLine() string {
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *envParser) errorf(line int, format string, args ...any) error {
+	return &ParseError{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *envParser) parse() ([]EnvVar, error) {
+	if !p.opts.Dotenv {
+		return p.parseLegacy()
+	}
+
 	var envVars []EnvVar
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	var currentVar *EnvVar
-	var inMultiline bool
-	var quoteChar rune
+	for {
+		p.skipBlanks()
+		if p.eof() {
+			return envVars, nil
+		}
+		switch p.peek() {
+		case '\n':
+			p.advance()
+			continue
+		case '#':
+			p.restOfLine()
+			continue
+		}
 
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
+		v, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		p.resolved[v.Key] = v.Value
+		envVars = append(envVars, v)
+	}
+}
+
+// parseLegacy reads the line-based format ParseEnvFile has always accepted
+func (p *envParser) parseLegacy() ([]EnvVar, error) {
+	var envVars []EnvVar
+	var current *EnvVar
+	var quote string
+
+	for i, line := range strings.Split(p.src, "\n") {
+		lineNum := i + 1
 
 		// Handle multiline continuation
-		if inMultiline {
-			currentVar.Value += "\n" + line
-			// Check if this line closes the multiline value
-			if strings.HasSuffix(line, string(quoteChar)) {
-				// Remove the closing quote
-				currentVar.Value = strings.TrimSuffix(currentVar.Value, string(quoteChar))
-				envVars = append(envVars, *currentVar)
-				currentVar = nil
-				inMultiline = false
-				quoteChar = 0
+		if current != nil {
+			current.Value += "\n" + line
+			if strings.HasSuffix(line, quote) {
+				current.Value = strings.TrimSuffix(current.Value, quote)
+				envVars = append(envVars, p.legacyVar(*current))
+				current = nil
 			}
 			continue
 		}
 
-		// Skip empty lines and comments
 		trimmed := strings.TrimSpace(line)
 		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
 			continue
 		}
 
-		// Find the first = sign
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid format at line %d: missing '='", lineNum)
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, p.errorf(lineNum, "missing '='")
 		}
-
-		key := strings.TrimSpace(parts[0])
-		value := parts[1]
-
+		key = strings.TrimSpace(key)
 		if key == "" {
-			return nil, fmt.Errorf("invalid format at line %d: empty key", lineNum)
-		}
-
-		// Handle quoted values
-		if len(value) >= 2 {
-			firstChar := rune(value[0])
-			if firstChar == '"' || firstChar == '\'' {
-				// Check if the closing quote is on the same line
-				if strings.HasSuffix(value, string(firstChar)) && len(value) > 1 {
-					// Single-line quoted value
-					value = value[1 : len(value)-1]
-					envVars = append(envVars, EnvVar{Key: key, Value: value})
-				} else {
-					// Start of multiline quoted value
-					currentVar = &EnvVar{Key: key, Value: value[1:]} // Remove opening quote
-					inMultiline = true
-					quoteChar = firstChar
-				}
+			return nil, p.errorf(lineNum, "empty key")
+		}
+
+		v := EnvVar{Key: key, Value: value, Line: lineNum}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+			quote = value[:1]
+			v.Literal = quote == "'"
+			if strings.HasSuffix(value, quote) {
+				v.Value = value[1 : len(value)-1]
+			} else {
+				// Start of multiline quoted value
+				v.Value = value[1:]
+				current = &v
 				continue
 			}
 		}
+		envVars = append(envVars, p.legacyVar(v))
+	}
 
-		// Unquoted value
-		envVars = append(envVars, EnvVar{Key: key, Value: value})
+	if current != nil {
+		return nil, p.errorf(current.Line, "unclosed quoted value for key '%s'", current.Key)
 	}
+	return envVars, nil
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+func (p *envParser) legacyVar(v EnvVar) EnvVar {
+	if p.opts.Interpolate && !v.Literal {
+		v.Value = p.expand(v.Value)
+	}
+	p.resolved[v.Key] = v.Value
+	return v
+}
+
+func (p *envParser) parseAssignment() (EnvVar, error) {
+	line := p.line
+	start := p.pos
+	for !p.eof() && p.peek() != '=' && p.peek() != '\n' {
+		p.pos++
+	}
+	if p.eof() || p.peek() != '=' {
+		return EnvVar{}, p.errorf(line, "missing '='")
+	}
+	key := strings.TrimSpace(p.src[start:p.pos])
+	p.pos++ // '='
+
+	if rest, ok := strings.CutPrefix(key, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+		key = strings.TrimSpace(rest)
+	}
+	if key == "" {
+		return EnvVar{}, p.errorf(line, "empty key")
+	}
+	if strings.ContainsAny(key, " \t\"'") {
+		return EnvVar{}, p.errorf(line, "invalid key %q", key)
 	}
 
-	if inMultiline {
-		return nil, fmt.Errorf("unclosed quoted value for key '%s'", currentVar.Key)
+	v := EnvVar{Key: key, Line: line}
+	valueStart := p.pos
+	p.skipBlanks()
+	spaced := p.pos > valueStart
+	if p.eof() {
+		return v, nil
 	}
 
-	return envVars, nil
+	var err error
+	switch p.peek() {
+	case '\'':
+		v.Literal = true
+		v.Value, err = p.singleQuoted(key, line)
+	case '"':
+		v.Value, err = p.doubleQuoted(key, line)
+	default:
+		v.Value = p.unquoted(spaced)
+		return v, nil
+	}
+	if err != nil {
+		return EnvVar{}, err
+	}
+
+	// Only whitespace or a comment may follow a closing quote
+	closeLine := p.line
+	rest := strings.TrimSpace(p.restOfLine())
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return EnvVar{}, p.errorf(closeLine, "unexpected %q after closing quote of '%s'", rest, key)
+	}
+	return v, nil
+}
+
+// unquoted reads an unquoted value; spaced reports whether blanks were
+// skipped before it, which makes a leading '#' a comment.
+func (p *envParser) unquoted(spaced bool) string {
+	raw := p.restOfLine()
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '#' {
+			continue
+		}
+		if (i == 0 && spaced) || (i > 0 && (raw[i-1] == ' ' || raw[i-1] == '\t')) {
+			raw = raw[:i]
+			break
+		}
+	}
+	value := strings.TrimSpace(raw)
+	if p.opts.Interpolate {
+		value = p.expand(value)
+	}
+	return value
+}
+
+func (p *envParser) singleQuoted(key string, line int) (string, error) {
+	p.advance() // opening quote
+	start := p.pos
+	for !p.eof() {
+		if p.peek() == '\'' {
+			value := p.src[start:p.pos]
+			p.advance()
+			return value, nil
+		}
+		p.advance()
+	}
+	return "", p.errorf(line, "unclosed quoted value for key '%s'", key)
+}
+
+func (p *envParser) doubleQuoted(key string, line int) (string, error) {
+	p.advance() // opening quote
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.advance()
+		switch {
+		case c == '"':
+			return sb.String(), nil
+		case c == '\\' && !p.eof():
+			next := p.advance()
+			switch next {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\', '$':
+				sb.WriteByte(next)
+			default:
+				// Unknown escapes are kept verbatim, e.g. Windows paths
+				sb.WriteByte('\\')
+				sb.WriteByte(next)
+			}
+		case c == '$' && p.opts.Interpolate:
+			value, n := p.reference(p.src[p.pos-1:])
+			sb.WriteString(value)
+			for i := 1; i < n; i++ {
+				p.advance()
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf(line, "unclosed quoted value for key '%s'", key)
+}
+
+// expand interpolates every reference in s
+func (p *envParser) expand(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '$' {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+		value, n := p.reference(s[i:])
+		sb.WriteString(value)
+		i += n
+	}
+	return sb.String()
+}
+
+// reference resolves the reference at the start of s (which begins with '$')
+// and returns its replacement and the number of bytes it spans.
+func (p *envParser) reference(s string) (string, int) {
+	if len(s) > 1 && s[1] == '{' {
+		end := strings.IndexByte(s, '}')
+		if end < 0 || strings.ContainsAny(s[:end], "\"\n") {
+			return "$", 1
+		}
+		name, def, hasDef := strings.Cut(s[2:end], ":-")
+		if !isEnvName(name) {
+			return s[:end+1], end + 1
+		}
+		if value, ok := p.lookup(name); ok {
+			return value, end + 1
+		}
+		if hasDef {
+			return def, end + 1
+		}
+		return s[:end+1], end + 1
+	}
+
+	n := 1
+	for n < len(s) && isNameByte(s[n], n == 1) {
+		n++
+	}
+	if n == 1 {
+		return "$", 1
+	}
+	if value, ok := p.lookup(s[1:n]); ok {
+		return value, n
+	}
+	return s[:n], n
+}
+
+func (p *envParser) lookup(name string) (string, bool) {
+	if value, ok := p.resolved[name]; ok {
+		return value, true
+	}
+	if p.opts.Lookup != nil {
+		return p.opts.Lookup(name)
+	}
+	return "", false
+}
+
+func isEnvName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameByte(s[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
 }
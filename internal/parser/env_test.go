@@ -3,6 +3,7 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -227,3 +228,152 @@ func createTempEnvFile(t *testing.T, content string) string {
 	require.NoError(t, err)
 	return tmpFile
 }
+
+func TestParseEnv_Dialect(t *testing.T) {
+	content := "export EXPORTED=yes\n" +
+		"INLINE=value # trailing comment\n" +
+		"HASH=a#b\n" +
+		"COMMENT_ONLY= # comment\n" +
+		"LEADING_HASH=#not-a-comment\n" +
+		"SPACED =   padded   \n" +
+		"ESCAPES=\"tab\\there\\nnew \\\"q\\\" \\\\ \\$HOME \\p\"\n" +
+		"DQ_COMMENT=\"quoted\" # comment\n" +
+		"SQ='raw \\n $HOME' # comment\n" +
+		"MULTI=\"a\nb\" # done\n" +
+		"CRLF=value\r\n"
+
+	envVars, err := ParseEnv(strings.NewReader(content), ParseOptions{Dotenv: true})
+	require.NoError(t, err)
+
+	got := map[string]string{}
+	for _, v := range envVars {
+		got[v.Key] = v.Value
+	}
+	assert.Equal(t, map[string]string{
+		"EXPORTED":     "yes",
+		"INLINE":       "value",
+		"HASH":         "a#b",
+		"COMMENT_ONLY": "",
+		"LEADING_HASH": "#not-a-comment",
+		"SPACED":       "padded",
+		"ESCAPES":      "tab\there\nnew \"q\" \\ $HOME \\p",
+		"DQ_COMMENT":   "quoted",
+		"SQ":           `raw \n $HOME`,
+		"MULTI":        "a\nb",
+		"CRLF":         "value",
+	}, got)
+
+	assert.True(t, envVars[8].Literal)
+	assert.Equal(t, 10, envVars[9].Line)
+}
+
+func TestParseEnv_Interpolation(t *testing.T) {
+	content := `HOST=db.internal
+URL=postgres://${HOST}:5432
+BARE="$HOST/x"
+LITERAL='${HOST}'
+ESCAPED="\${HOST}"
+FROM_ENV=${OUTER}
+DEFAULTED=${MISSING:-fallback}
+UNRESOLVED=${SERVICE_FQDN_APP}
+`
+	lookup := func(name string) (string, bool) {
+		if name == "OUTER" {
+			return "outer", true
+		}
+		return "", false
+	}
+
+	envVars, err := ParseEnv(strings.NewReader(content), ParseOptions{Dotenv: true, Interpolate: true, Lookup: lookup})
+	require.NoError(t, err)
+	values := make([]string, len(envVars))
+	for i, v := range envVars {
+		values[i] = v.Value
+	}
+	assert.Equal(t, []string{
+		"db.internal",
+		"postgres://db.internal:5432",
+		"db.internal/x",
+		"${HOST}",
+		"${HOST}",
+		"outer",
+		"fallback",
+		"${SERVICE_FQDN_APP}",
+	}, values)
+
+	// Without interpolation references are kept verbatim
+	envVars, err = ParseEnv(strings.NewReader(content), ParseOptions{Dotenv: true})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://${HOST}:5432", envVars[1].Value)
+
+	// The legacy format interpolates too, but has no escapes
+	envVars, err = ParseEnv(strings.NewReader(content), ParseOptions{Interpolate: true, Lookup: lookup})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://db.internal:5432", envVars[1].Value)
+	assert.Equal(t, "${HOST}", envVars[3].Value)
+	assert.Equal(t, `\db.internal`, envVars[4].Value)
+}
+
+func TestParseEnv_LineAccurateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		msg     string
+	}{
+		{"missing equals", "A=1\n\n# c\nBROKEN\n", 4, "missing '='"},
+		{"garbage after quote", "A=\"x\nyz\" tail\n", 2, "after closing quote"},
+		{"unclosed", "A=1\nB='open\nmore\n", 2, "unclosed quoted value"},
+		{"invalid key", "MY KEY=1\n", 1, "invalid key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseEnv(strings.NewReader(tt.content), ParseOptions{Dotenv: true})
+			var perr *ParseError
+			require.ErrorAs(t, err, &perr)
+			assert.Equal(t, tt.line, perr.Line)
+			assert.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}
+
+func TestParseEnvFile_LegacyFormat(t *testing.T) {
+	content := "SQ='it's'\n" +
+		"DQ=\"say \"hi\"\"\n" +
+		"BACKSLASH=C:\\new\\path\n" +
+		"DQ_BACKSLASH=\"a\\nb \\$HOME\"\n" +
+		"INLINE=value # not a comment\n" +
+		"PADDED= spaced \n" +
+		"export EXPORTED=kept\n" +
+		"MY KEY=spaces allowed\n" +
+		"MULTI='a\nb'\n"
+
+	envVars, err := ParseEnvFile(createTempEnvFile(t, content))
+	require.NoError(t, err)
+
+	got := map[string]string{}
+	for _, v := range envVars {
+		got[v.Key] = v.Value
+	}
+	assert.Equal(t, map[string]string{
+		"SQ":              "it's",
+		"DQ":              `say "hi"`,
+		"BACKSLASH":       `C:\new\path`,
+		"DQ_BACKSLASH":    `a\nb \$HOME`,
+		"INLINE":          "value # not a comment",
+		"PADDED":          " spaced ",
+		"export EXPORTED": "kept",
+		"MY KEY":          "spaces allowed",
+		"MULTI":           "a\nb",
+	}, got)
+	assert.True(t, envVars[0].Literal)
+	assert.Equal(t, 9, envVars[8].Line)
+}
+
+func TestParseEnv_LegacyUnclosedQuoteLine(t *testing.T) {
+	_, err := ParseEnv(strings.NewReader("A=1\nB='open\nmore\n"), ParseOptions{})
+	var perr *ParseError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, 2, perr.Line)
+	assert.Contains(t, err.Error(), "unclosed quoted value for key 'B'")
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatEnvFile renders variables as a .env file that ParseEnvFile reads back
+// unchanged with ParseOptions.Dotenv, with or without interpolation. Every
+// value is double-quoted with backslashes, quotes, dollar signs and line
+// breaks escaped, so any value can be written.
+func FormatEnvFile(vars []EnvVar) string {
+	var sb strings.Builder
+	for _, v := range vars {
+		fmt.Fprintf(&sb, "%s=%s\n", v.Key, quoteEnvValue(v.Value))
+	}
+	return sb.String()
+}
+
+var envValueEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	`$`, `\$`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+func quoteEnvValue(s string) string {
+	return `"` + envValueEscaper.Replace(s) + `"`
+}
+
+// FormatShellExports renders variables as POSIX shell `export KEY='value'`
+// statements suitable for `eval` or `source`.
+func FormatShellExports(vars []EnvVar) string {
+	var sb strings.Builder
+	for _, v := range vars {
+		fmt.Fprintf(&sb, "export %s=%s\n", v.Key, shellQuote(v.Value))
+	}
+	return sb.String()
+}
+
+// shellQuote wraps s in single quotes, escaping embedded single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
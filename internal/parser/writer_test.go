@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatEnvFile_RoundTrip(t *testing.T) {
+	vars := []EnvVar{
+		{Key: "SIMPLE", Value: "value"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "SPACES", Value: "  padded value  "},
+		{Key: "HASH", Value: "a # not a comment"},
+		{Key: "QUOTED", Value: `"already quoted"`},
+		{Key: "SINGLE", Value: `it's`},
+		{Key: "MULTILINE", Value: "line1\nline2\r\nline3"},
+		{Key: "MULTILINE_DQ", Value: "say \"hi\"\nbye"},
+		{Key: "MIXED_QUOTES", Value: "a\"\nb'\nc"},
+		{Key: "LEADING_NEWLINE", Value: "\nafter a blank line"},
+		{Key: "BACKSLASH", Value: `C:\new\path\`},
+		{Key: "ESCAPE_LIKE", Value: `literal \n \" \$`},
+		{Key: "DOLLAR", Value: "${SIMPLE} and $SIMPLE"},
+	}
+
+	content := FormatEnvFile(vars)
+
+	tests := []struct {
+		name string
+		opts ParseOptions
+		// plainOnly limits the check to values without characters the
+		// writer escapes; the legacy format has no escapes.
+		plainOnly bool
+	}{
+		{"dotenv", ParseOptions{Dotenv: true}, false},
+		{"dotenv interpolated", ParseOptions{Dotenv: true, Interpolate: true}, false},
+		{"legacy", ParseOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEnv(strings.NewReader(content), tt.opts)
+			require.NoError(t, err)
+			require.Len(t, got, len(vars))
+			for i := range vars {
+				assert.Equal(t, vars[i].Key, got[i].Key)
+				if tt.plainOnly && strings.ContainsAny(vars[i].Value, "\\\"$\n\r") {
+					continue
+				}
+				assert.Equal(t, vars[i].Value, got[i].Value, "key %s", vars[i].Key)
+			}
+		})
+	}
+}
+
+func TestFormatShellExports(t *testing.T) {
+	got := FormatShellExports([]EnvVar{
+		{Key: "A", Value: "plain"},
+		{Key: "B", Value: "it's"},
+	})
+	assert.Equal(t, "export A='plain'\nexport B='it'\\''s'\n", got)
+}
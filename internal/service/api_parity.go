@@ -192,6 +192,35 @@ func (s *SharedEnvService) DeleteServer(ctx context.Context, serverUUID string,
 	return s.client.Delete(ctx, "servers/"+url.PathEscape(serverUUID)+"/envs/"+strconv.Itoa(id))
 }
 
+// Shared env scope kinds
+const (
+	SharedEnvTeam        = "team"
+	SharedEnvProject     = "project"
+	SharedEnvEnvironment = "environment"
+	SharedEnvServer      = "server"
+)
+
+// SharedEnvScope identifies one level of the shared env hierarchy.
+type SharedEnvScope struct {
+	Kind        string
+	ProjectUUID string
+	Environment string
+	ServerUUID  string
+}
+
+func (sc SharedEnvScope) String() string {
+	switch sc.Kind {
+	case SharedEnvProject:
+		return "project " + sc.ProjectUUID
+	case SharedEnvEnvironment:
+		return fmt.Sprintf("environment %s/%s", sc.ProjectUUID, sc.Environment)
+	case SharedEnvServer:
+		return "server " + sc.ServerUUID
+	default:
+		return sc.Kind
+	}
+}
+
 // --- Cloud-init ---
 
 type CloudInitService struct{ client *api.Client }
@@ -114,6 +114,91 @@ func TestSharedEnvService_TeamPaths(t *testing.T) {
 	}
 }
 
+func TestSharedEnvTarget_EnvironmentScope(t *testing.T) {
+	var updated, created, deleted bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/projects/p1/environments/staging/envs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode([]models.SharedEnvironmentVariable{
+				{ID: 1, Key: "A", Value: strPtr("old")},
+				{ID: 2, Key: "GONE", Value: strPtr("x")},
+			})
+		case http.MethodPost:
+			created = true
+			_ = json.NewEncoder(w).Encode(models.SharedEnvCreateResponse{ID: 3})
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	})
+	mux.HandleFunc("/api/v1/projects/p1/environments/staging/envs/1", func(w http.ResponseWriter, r *http.Request) {
+		updated = r.Method == http.MethodPatch
+		_ = json.NewEncoder(w).Encode(models.SharedEnvironmentVariable{ID: 1, Key: "A"})
+	})
+	mux.HandleFunc("/api/v1/projects/p1/environments/staging/envs/2", func(w http.ResponseWriter, r *http.Request) {
+		deleted = r.Method == http.MethodDelete
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	scope := SharedEnvScope{Kind: SharedEnvEnvironment, ProjectUUID: "p1", Environment: "staging"}
+	target := NewSharedEnvTarget(api.NewClient(server.URL, "token", api.WithRetries(0)), scope)
+	ctx := context.Background()
+
+	existing, err := target.ListEnvRecords(ctx, "")
+	if err != nil || len(existing) != 2 {
+		t.Fatalf("list: %v %#v", err, existing)
+	}
+	changes := PlanEnvSync(existing, []EnvSyncVar{{Key: "A", Value: "new"}, {Key: "B", Value: "b"}},
+		EnvSyncScope{Production: true, Prune: true})
+	res := ApplyEnvSync(ctx, target, "", changes, EnvSyncProgress{})
+	if res.Failed != 0 || !updated || !created || !deleted {
+		t.Fatalf("apply: %#v updated=%v created=%v deleted=%v", res, updated, created, deleted)
+	}
+	if got := scope.String(); got != "environment p1/staging" {
+		t.Fatalf("scope string: %q", got)
+	}
+}
+
+func TestSharedEnvTarget_PartialUpdateFailure(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/team/envs", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode([]models.SharedEnvironmentVariable{
+			{ID: 1, Key: "OK", Value: strPtr("old")},
+			{ID: 2, Key: "BAD", Value: strPtr("old")},
+		})
+	})
+	mux.HandleFunc("/api/v1/team/envs/1", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(models.SharedEnvironmentVariable{ID: 1, Key: "OK"})
+	})
+	mux.HandleFunc("/api/v1/team/envs/2", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	target := NewSharedEnvTarget(api.NewClient(server.URL, "token", api.WithRetries(0)),
+		SharedEnvScope{Kind: SharedEnvTeam})
+	ctx := context.Background()
+	existing, err := target.ListEnvRecords(ctx, "")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	changes := PlanEnvSync(existing, []EnvSyncVar{{Key: "OK", Value: "new"}, {Key: "BAD", Value: "new"}},
+		EnvSyncScope{Production: true})
+	results := map[string]bool{}
+	res := ApplyEnvSync(ctx, target, "", changes, EnvSyncProgress{
+		OnResult: func(key string, err error) { results[key] = err == nil },
+	})
+	if res.Updated != 1 || res.Failed != 1 || len(res.Errors) != 1 {
+		t.Fatalf("apply: %#v", res)
+	}
+	if !results["OK"] || results["BAD"] {
+		t.Fatalf("per-key results: %v", results)
+	}
+}
+
 func TestNotificationService_Paths(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/v1/notifications/webhook", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,709 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/api"
+	"github.com/coollabsio/coolify-cli/internal/models"
+)
+
+// EnvRecord is the resource-agnostic view of an existing environment variable
+// used by env sync and export. Databases and services never set IsPreview.
+type EnvRecord struct {
+	UUID        string
+	Key         string
+	Value       string
+	IsPreview   bool
+	IsBuildTime bool
+	IsRuntime   bool
+	IsLiteral   bool
+}
+
+// EnvSyncVar is a desired environment variable read from a local file.
+// Attribute pointers are only set when the user passed the matching flag, so
+// the API keeps its own defaults otherwise.
+type EnvSyncVar struct {
+	Key         string
+	Value       string
+	IsPreview   bool
+	IsBuildTime *bool
+	IsLiteral   *bool
+	IsRuntime   *bool
+	IsMultiline *bool
+}
+
+// changedAttributes lists the explicit attributes of v that differ from rec,
+// by their API names.
+func (v EnvSyncVar) changedAttributes(rec EnvRecord) []string {
+	var attrs []string
+	if v.IsBuildTime != nil && *v.IsBuildTime != rec.IsBuildTime {
+		attrs = append(attrs, "is_buildtime")
+	}
+	if v.IsRuntime != nil && *v.IsRuntime != rec.IsRuntime {
+		attrs = append(attrs, "is_runtime")
+	}
+	if v.IsLiteral != nil && *v.IsLiteral != rec.IsLiteral {
+		attrs = append(attrs, "is_literal")
+	}
+	return attrs
+}
+
+// EnvSyncTarget abstracts the environment variable endpoints of a resource
+// (application, database or service) so sync and export logic can be shared.
+type EnvSyncTarget interface {
+	ListEnvRecords(ctx context.Context, uuid string) ([]EnvRecord, error)
+	BulkUpdateEnvVars(ctx context.Context, uuid string, vars []EnvSyncVar) error
+	CreateEnvVar(ctx context.Context, uuid string, v EnvSyncVar) error
+	DeleteEnv(ctx context.Context, uuid, envUUID string) error
+}
+
+// EnvUpdateErrors is returned by a BulkUpdateEnvVars that sends updates one
+// by one and only some of them failed. It maps each failed key to its error;
+// every other key was updated.
+type EnvUpdateErrors map[string]error
+
+func (e EnvUpdateErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	msgs := make([]string, len(keys))
+	for i, k := range keys {
+		msgs[i] = e[k].Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// EnvChangeKind classifies a single entry of an env sync plan
+type EnvChangeKind string
+
+// Env sync change kinds
+const (
+	EnvAdded     EnvChangeKind = "added"
+	EnvChanged   EnvChangeKind = "changed"
+	EnvRemoved   EnvChangeKind = "removed"
+	EnvUnchanged EnvChangeKind = "unchanged"
+)
+
+// EnvChange is one row of an env sync plan. Attributes names the flags that
+// change on an existing variable, whether or not its value changes too.
+type EnvChange struct {
+	Kind       EnvChangeKind `json:"change"`
+	Key        string        `json:"key"`
+	Preview    bool          `json:"is_preview"`
+	OldValue   string        `json:"old_value" sensitive:"true"`
+	NewValue   string        `json:"new_value" sensitive:"true"`
+	Attributes string        `json:"attributes,omitempty"`
+	UUID       string        `json:"-" table:"-"`
+	Var        *EnvSyncVar   `json:"-" table:"-"`
+}
+
+// EnvSyncScope selects which variables a sync manages. Variables outside the
+// managed scopes are never reported or touched, so syncing a production file
+// leaves preview variables alone and vice versa.
+type EnvSyncScope struct {
+	Production bool
+	Preview    bool
+	Prune      bool
+}
+
+func (s EnvSyncScope) manages(preview bool) bool {
+	if preview {
+		return s.Preview
+	}
+	return s.Production
+}
+
+type envSyncKey struct {
+	key     string
+	preview bool
+}
+
+// PlanEnvSync diffs the desired variables against the existing ones. Existing
+// variables missing from the desired set are reported as removed only when
+// scope.Prune is set. A variable whose value matches but whose explicit
+// attributes differ is reported as changed. Changes are sorted production
+// first, then by key.
+func PlanEnvSync(existing []EnvRecord, desired []EnvSyncVar, scope EnvSyncScope) []EnvChange {
+	existingMap := make(map[envSyncKey]EnvRecord, len(existing))
+	for _, rec := range existing {
+		if !scope.manages(rec.IsPreview) {
+			continue
+		}
+		existingMap[envSyncKey{rec.Key, rec.IsPreview}] = rec
+	}
+
+	// Later definitions of the same key win, matching how shells source .env files.
+	latest := make(map[envSyncKey]EnvSyncVar, len(desired))
+	var order []envSyncKey
+	for _, v := range desired {
+		k := envSyncKey{v.Key, v.IsPreview}
+		if _, ok := latest[k]; !ok {
+			order = append(order, k)
+		}
+		latest[k] = v
+	}
+
+	changes := make([]EnvChange, 0, len(order))
+	for _, k := range order {
+		v := latest[k]
+		change := EnvChange{Key: v.Key, Preview: v.IsPreview, NewValue: v.Value, Var: &v}
+		if rec, ok := existingMap[k]; ok {
+			change.UUID = rec.UUID
+			change.OldValue = rec.Value
+			change.Attributes = strings.Join(v.changedAttributes(rec), ",")
+			if rec.Value == v.Value && change.Attributes == "" {
+				change.Kind = EnvUnchanged
+			} else {
+				change.Kind = EnvChanged
+			}
+		} else {
+			change.Kind = EnvAdded
+		}
+		changes = append(changes, change)
+	}
+
+	if scope.Prune {
+		for k, rec := range existingMap {
+			if _, ok := latest[k]; ok {
+				continue
+			}
+			changes = append(changes, EnvChange{
+				Kind:     EnvRemoved,
+				Key:      rec.Key,
+				Preview:  rec.IsPreview,
+				OldValue: rec.Value,
+				UUID:     rec.UUID,
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Preview != changes[j].Preview {
+			return !changes[i].Preview
+		}
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// EnvSyncResult summarises the outcome of applying an env sync plan
+type EnvSyncResult struct {
+	Updated int
+	Created int
+	Deleted int
+	Failed  int
+	Errors  []error
+}
+
+// EnvSyncProgress receives per-step notifications while a plan is applied.
+// Either callback may be nil.
+type EnvSyncProgress struct {
+	OnStep   func(msg string)
+	OnResult func(key string, err error)
+}
+
+func (p EnvSyncProgress) step(msg string) {
+	if p.OnStep != nil {
+		p.OnStep(msg)
+	}
+}
+
+func (p EnvSyncProgress) result(key string, err error) {
+	if p.OnResult != nil {
+		p.OnResult(key, err)
+	}
+}
+
+// ApplyEnvSync executes a plan: changed variables are sent in a single bulk
+// update, added variables are created one by one and removed variables are
+// deleted. Unchanged variables are left alone.
+func ApplyEnvSync(ctx context.Context, target EnvSyncTarget, uuid string, changes []EnvChange, progress EnvSyncProgress) EnvSyncResult {
+	var toUpdate, toCreate []EnvSyncVar
+	var toDelete []EnvChange
+	for _, c := range changes {
+		switch c.Kind {
+		case EnvChanged:
+			toUpdate = append(toUpdate, *c.Var)
+		case EnvAdded:
+			toCreate = append(toCreate, *c.Var)
+		case EnvRemoved:
+			toDelete = append(toDelete, c)
+		}
+	}
+
+	var res EnvSyncResult
+	if len(toUpdate) > 0 {
+		progress.step(pluralVars("Updating %d existing variable%s...", len(toUpdate)))
+		err := target.BulkUpdateEnvVars(ctx, uuid, toUpdate)
+		var perKey EnvUpdateErrors
+		switch {
+		case err == nil:
+			res.Updated = len(toUpdate)
+			progress.result("", nil)
+		case errors.As(err, &perKey):
+			for _, v := range toUpdate {
+				keyErr := perKey[v.Key]
+				progress.result(v.Key, keyErr)
+				if keyErr != nil {
+					res.Failed++
+					res.Errors = append(res.Errors, keyErr)
+					continue
+				}
+				res.Updated++
+			}
+		default:
+			progress.result("", err)
+			res.Failed += len(toUpdate)
+			res.Errors = append(res.Errors, err)
+		}
+	}
+
+	if len(toCreate) > 0 {
+		progress.step(pluralVars("Creating %d new variable%s...", len(toCreate)))
+		for _, v := range toCreate {
+			err := target.CreateEnvVar(ctx, uuid, v)
+			progress.result(v.Key, err)
+			if err != nil {
+				res.Failed++
+				res.Errors = append(res.Errors, err)
+				continue
+			}
+			res.Created++
+		}
+	}
+
+	if len(toDelete) > 0 {
+		progress.step(pluralVars("Deleting %d variable%s not present in the source...", len(toDelete)))
+		for _, c := range toDelete {
+			err := target.DeleteEnv(ctx, uuid, c.UUID)
+			progress.result(c.Key, err)
+			if err != nil {
+				res.Failed++
+				res.Errors = append(res.Errors, err)
+				continue
+			}
+			res.Deleted++
+		}
+	}
+
+	return res
+}
+
+func pluralVars(format string, n int) string {
+	suffix := "s"
+	if n == 1 {
+		suffix = ""
+	}
+	return fmt.Sprintf(format, n, suffix)
+}
+
+// NewApplicationEnvTarget returns an EnvSyncTarget for application env vars
+func NewApplicationEnvTarget(client *api.Client) EnvSyncTarget {
+	return applicationEnvTarget{svc: NewApplicationService(client)}
+}
+
+type applicationEnvTarget struct {
+	svc *ApplicationService
+}
+
+func (t applicationEnvTarget) ListEnvRecords(ctx context.Context, uuid string) ([]EnvRecord, error) {
+	envs, err := t.svc.ListEnvs(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]EnvRecord, 0, len(envs))
+	for _, e := range envs {
+		records = append(records, EnvRecord{
+			UUID:        e.UUID,
+			Key:         e.Key,
+			Value:       e.Value,
+			IsPreview:   e.IsPreview,
+			IsBuildTime: e.IsBuildTime,
+			IsRuntime:   e.IsRuntime,
+			IsLiteral:   e.IsLiteralValue,
+		})
+	}
+	return records, nil
+}
+
+func (t applicationEnvTarget) request(v EnvSyncVar) models.EnvironmentVariableCreateRequest {
+	req := models.EnvironmentVariableCreateRequest{
+		Key:         v.Key,
+		Value:       v.Value,
+		IsBuildTime: v.IsBuildTime,
+		IsLiteral:   v.IsLiteral,
+		IsMultiline: v.IsMultiline,
+		IsRuntime:   v.IsRuntime,
+	}
+	if v.IsPreview {
+		preview := true
+		req.IsPreview = &preview
+	}
+	return req
+}
+
+func (t applicationEnvTarget) BulkUpdateEnvVars(ctx context.Context, uuid string, vars []EnvSyncVar) error {
+	req := &BulkUpdateEnvsRequest{}
+	for _, v := range vars {
+		req.Data = append(req.Data, t.request(v))
+	}
+	_, err := t.svc.BulkUpdateEnvs(ctx, uuid, req)
+	return err
+}
+
+func (t applicationEnvTarget) CreateEnvVar(ctx context.Context, uuid string, v EnvSyncVar) error {
+	req := t.request(v)
+	_, err := t.svc.CreateEnv(ctx, uuid, &req)
+	return err
+}
+
+func (t applicationEnvTarget) DeleteEnv(ctx context.Context, uuid, envUUID string) error {
+	return t.svc.DeleteEnv(ctx, uuid, envUUID)
+}
+
+// NewDatabaseEnvTarget returns an EnvSyncTarget for database env vars
+func NewDatabaseEnvTarget(client *api.Client) EnvSyncTarget {
+	return databaseEnvTarget{svc: NewDatabaseService(client)}
+}
+
+type databaseEnvTarget struct {
+	svc *DatabaseService
+}
+
+func (t databaseEnvTarget) ListEnvRecords(ctx context.Context, uuid string) ([]EnvRecord, error) {
+	envs, err := t.svc.ListEnvs(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]EnvRecord, 0, len(envs))
+	for _, e := range envs {
+		records = append(records, EnvRecord{
+			UUID:        e.UUID,
+			Key:         e.Key,
+			Value:       e.Value,
+			IsBuildTime: e.IsBuildTime,
+			IsRuntime:   e.IsRuntime,
+			IsLiteral:   e.IsLiteralValue,
+		})
+	}
+	return records, nil
+}
+
+func (t databaseEnvTarget) request(v EnvSyncVar) models.DatabaseEnvironmentVariableCreateRequest {
+	return models.DatabaseEnvironmentVariableCreateRequest{
+		Key:         v.Key,
+		Value:       v.Value,
+		IsLiteral:   v.IsLiteral,
+		IsMultiline: v.IsMultiline,
+	}
+}
+
+func (t databaseEnvTarget) BulkUpdateEnvVars(ctx context.Context, uuid string, vars []EnvSyncVar) error {
+	req := &models.DatabaseEnvBulkUpdateRequest{}
+	for _, v := range vars {
+		req.Data = append(req.Data, t.request(v))
+	}
+	_, err := t.svc.BulkUpdateEnvs(ctx, uuid, req)
+	return err
+}
+
+func (t databaseEnvTarget) CreateEnvVar(ctx context.Context, uuid string, v EnvSyncVar) error {
+	req := t.request(v)
+	_, err := t.svc.CreateEnv(ctx, uuid, &req)
+	return err
+}
+
+func (t databaseEnvTarget) DeleteEnv(ctx context.Context, uuid, envUUID string) error {
+	return t.svc.DeleteEnv(ctx, uuid, envUUID)
+}
+
+// NewServiceEnvTarget returns an EnvSyncTarget for service env vars
+func NewServiceEnvTarget(client *api.Client) EnvSyncTarget {
+	return serviceEnvTarget{svc: NewService(client)}
+}
+
+type serviceEnvTarget struct {
+	svc *Service
+}
+
+func (t serviceEnvTarget) ListEnvRecords(ctx context.Context, uuid string) ([]EnvRecord, error) {
+	envs, err := t.svc.ListEnvs(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]EnvRecord, 0, len(envs))
+	for _, e := range envs {
+		records = append(records, EnvRecord{
+			UUID:        e.UUID,
+			Key:         e.Key,
+			Value:       e.Value,
+			IsBuildTime: e.IsBuildTime,
+			IsRuntime:   e.IsRuntime,
+			IsLiteral:   e.IsLiteralValue,
+		})
+	}
+	return records, nil
+}
+
+func (t serviceEnvTarget) request(v EnvSyncVar) models.ServiceEnvironmentVariableCreateRequest {
+	return models.ServiceEnvironmentVariableCreateRequest{
+		Key:         v.Key,
+		Value:       v.Value,
+		IsBuildTime: v.IsBuildTime,
+		IsLiteral:   v.IsLiteral,
+		IsMultiline: v.IsMultiline,
+		IsRuntime:   v.IsRuntime,
+	}
+}
+
+func (t serviceEnvTarget) BulkUpdateEnvVars(ctx context.Context, uuid string, vars []EnvSyncVar) error {
+	req := &models.ServiceEnvBulkUpdateRequest{}
+	for _, v := range vars {
+		req.Data = append(req.Data, t.request(v))
+	}
+	_, err := t.svc.BulkUpdateEnvs(ctx, uuid, req)
+	return err
+}
+
+func (t serviceEnvTarget) CreateEnvVar(ctx context.Context, uuid string, v EnvSyncVar) error {
+	req := t.request(v)
+	_, err := t.svc.CreateEnv(ctx, uuid, &req)
+	return err
+}
+
+func (t serviceEnvTarget) DeleteEnv(ctx context.Context, uuid, envUUID string) error {
+	return t.svc.DeleteEnv(ctx, uuid, envUUID)
+}
+
+// NewSharedEnvTarget returns an EnvSyncTarget for shared env vars at scope.
+// The uuid argument of its methods is ignored; the scope identifies the
+// variables. Shared envs have no bulk endpoint, so updates are sent one by one.
+func NewSharedEnvTarget(client *api.Client, scope SharedEnvScope) EnvSyncTarget {
+	return &sharedEnvTarget{svc: NewSharedEnvService(client), scope: scope, ids: map[string]int{}}
+}
+
+type sharedEnvTarget struct {
+	svc   *SharedEnvService
+	scope SharedEnvScope
+	// ids maps keys to the numeric IDs seen by the last ListEnvRecords call
+	ids map[string]int
+}
+
+func (t *sharedEnvTarget) list(ctx context.Context) ([]models.SharedEnvironmentVariable, error) {
+	sc := t.scope
+	switch sc.Kind {
+	case SharedEnvTeam:
+		return t.svc.ListTeam(ctx)
+	case SharedEnvProject:
+		return t.svc.ListProject(ctx, sc.ProjectUUID)
+	case SharedEnvEnvironment:
+		return t.svc.ListEnvironment(ctx, sc.ProjectUUID, sc.Environment)
+	case SharedEnvServer:
+		return t.svc.ListServer(ctx, sc.ServerUUID)
+	}
+	return nil, fmt.Errorf("unknown shared env scope %q", sc.Kind)
+}
+
+func (t *sharedEnvTarget) create(ctx context.Context, req models.SharedEnvCreateRequest) (*models.SharedEnvCreateResponse, error) {
+	sc := t.scope
+	switch sc.Kind {
+	case SharedEnvTeam:
+		return t.svc.CreateTeam(ctx, req)
+	case SharedEnvProject:
+		return t.svc.CreateProject(ctx, sc.ProjectUUID, req)
+	case SharedEnvEnvironment:
+		return t.svc.CreateEnvironment(ctx, sc.ProjectUUID, sc.Environment, req)
+	case SharedEnvServer:
+		return t.svc.CreateServer(ctx, sc.ServerUUID, req)
+	}
+	return nil, fmt.Errorf("unknown shared env scope %q", sc.Kind)
+}
+
+func (t *sharedEnvTarget) update(ctx context.Context, id int, req models.SharedEnvUpdateRequest) error {
+	sc := t.scope
+	var err error
+	switch sc.Kind {
+	case SharedEnvTeam:
+		_, err = t.svc.UpdateTeam(ctx, id, req)
+	case SharedEnvProject:
+		_, err = t.svc.UpdateProject(ctx, sc.ProjectUUID, id, req)
+	case SharedEnvEnvironment:
+		_, err = t.svc.UpdateEnvironment(ctx, sc.ProjectUUID, sc.Environment, id, req)
+	case SharedEnvServer:
+		_, err = t.svc.UpdateServer(ctx, sc.ServerUUID, id, req)
+	default:
+		err = fmt.Errorf("unknown shared env scope %q", sc.Kind)
+	}
+	return err
+}
+
+func (t *sharedEnvTarget) delete(ctx context.Context, id int) error {
+	sc := t.scope
+	switch sc.Kind {
+	case SharedEnvTeam:
+		return t.svc.DeleteTeam(ctx, id)
+	case SharedEnvProject:
+		return t.svc.DeleteProject(ctx, sc.ProjectUUID, id)
+	case SharedEnvEnvironment:
+		return t.svc.DeleteEnvironment(ctx, sc.ProjectUUID, sc.Environment, id)
+	case SharedEnvServer:
+		return t.svc.DeleteServer(ctx, sc.ServerUUID, id)
+	}
+	return fmt.Errorf("unknown shared env scope %q", sc.Kind)
+}
+
+func (t *sharedEnvTarget) ListEnvRecords(ctx context.Context, _ string) ([]EnvRecord, error) {
+	envs, err := t.list(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared env vars for %s: %w", t.scope, err)
+	}
+	records := make([]EnvRecord, 0, len(envs))
+	for _, e := range envs {
+		t.ids[e.Key] = e.ID
+		value := ""
+		if e.Value != nil {
+			value = *e.Value
+		}
+		records = append(records, EnvRecord{
+			UUID:      strconv.Itoa(e.ID),
+			Key:       e.Key,
+			Value:     value,
+			IsLiteral: e.IsLiteral,
+		})
+	}
+	return records, nil
+}
+
+// BulkUpdateEnvVars updates vars one by one. When some fail it returns
+// EnvUpdateErrors, so the variables that were updated are reported as such.
+func (t *sharedEnvTarget) BulkUpdateEnvVars(ctx context.Context, _ string, vars []EnvSyncVar) error {
+	errs := EnvUpdateErrors{}
+	for _, v := range vars {
+		id, ok := t.ids[v.Key]
+		if !ok {
+			errs[v.Key] = fmt.Errorf("shared env var '%s' not found in %s", v.Key, t.scope)
+			continue
+		}
+		value := v.Value
+		req := models.SharedEnvUpdateRequest{Value: &value, IsLiteral: v.IsLiteral, IsMultiline: v.IsMultiline}
+		if err := t.update(ctx, id, req); err != nil {
+			errs[v.Key] = fmt.Errorf("failed to update shared env var '%s': %w", v.Key, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (t *sharedEnvTarget) CreateEnvVar(ctx context.Context, _ string, v EnvSyncVar) error {
+	req := models.SharedEnvCreateRequest{Key: v.Key, Value: v.Value, IsLiteral: v.IsLiteral, IsMultiline: v.IsMultiline}
+	resp, err := t.create(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to create shared env var '%s': %w", v.Key, err)
+	}
+	t.ids[v.Key] = resp.ID
+	return nil
+}
+
+func (t *sharedEnvTarget) DeleteEnv(ctx context.Context, _, envID string) error {
+	id, err := strconv.Atoi(envID)
+	if err != nil {
+		return fmt.Errorf("invalid shared env id %q", envID)
+	}
+	if err := t.delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete shared env var %d: %w", id, err)
+	}
+	return nil
+}
+
+// EnvKeyFilter selects which keys an env promotion copies. Patterns use
+// path.Match glob syntax (e.g. "STRIPE_*"). An empty Allow list allows every
+// key; Deny always wins over Allow.
+type EnvKeyFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+// Validate reports malformed glob patterns
+func (f EnvKeyFilter) Validate() error {
+	for _, p := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid key pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether key passes the filter
+func (f EnvKeyFilter) Match(key string) bool {
+	for _, p := range f.Deny {
+		if ok, _ := path.Match(p, key); ok {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, p := range f.Allow {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanEnvPromotion diffs the production variables of a source against a
+// target, restricted to keys passing filter. Filtered-out keys are ignored on
+// both sides, so prune never deletes a denied key from the target. Attributes
+// are copied for added and changed variables only, so unchanged variables are
+// not rewritten.
+func PlanEnvPromotion(source, target []EnvRecord, filter EnvKeyFilter, prune bool) []EnvChange {
+	desired := make([]EnvSyncVar, 0, len(source))
+	for _, rec := range source {
+		if rec.IsPreview || !filter.Match(rec.Key) {
+			continue
+		}
+		v := EnvSyncVar{
+			Key:         rec.Key,
+			Value:       rec.Value,
+			IsBuildTime: &rec.IsBuildTime,
+			IsRuntime:   &rec.IsRuntime,
+			IsLiteral:   &rec.IsLiteral,
+		}
+		if strings.Contains(rec.Value, "\n") {
+			multiline := true
+			v.IsMultiline = &multiline
+		}
+		desired = append(desired, v)
+	}
+
+	existing := make([]EnvRecord, 0, len(target))
+	for _, rec := range target {
+		if filter.Match(rec.Key) {
+			existing = append(existing, rec)
+		}
+	}
+
+	changes := PlanEnvSync(existing, desired, EnvSyncScope{Production: true, Prune: prune})
+	for i, c := range changes {
+		// Promotion moves values; differing attributes alone are not promoted.
+		if c.Kind == EnvChanged && c.OldValue == c.NewValue {
+			changes[i].Kind = EnvUnchanged
+			changes[i].Attributes = ""
+			c.Kind = EnvUnchanged
+		}
+		if c.Kind == EnvUnchanged {
+			changes[i].Var = &EnvSyncVar{Key: c.Var.Key, Value: c.Var.Value}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/api"
+)
+
+func TestPlanEnvSync(t *testing.T) {
+	existing := []EnvRecord{
+		{UUID: "u1", Key: "SAME", Value: "a"},
+		{UUID: "u2", Key: "CHANGED", Value: "old"},
+		{UUID: "u3", Key: "STALE", Value: "x"},
+		{UUID: "u4", Key: "SAME", Value: "preview", IsPreview: true},
+	}
+	desired := []EnvSyncVar{
+		{Key: "SAME", Value: "a"},
+		{Key: "CHANGED", Value: "first"},
+		{Key: "NEW", Value: "n"},
+		{Key: "CHANGED", Value: "new"},
+	}
+
+	t.Run("without prune", func(t *testing.T) {
+		changes := PlanEnvSync(existing, desired, EnvSyncScope{Production: true})
+		require.Len(t, changes, 3)
+		assert.Equal(t, EnvChange{Kind: EnvChanged, Key: "CHANGED", OldValue: "old", NewValue: "new", UUID: "u2"}, withoutVar(changes[0]))
+		assert.Equal(t, EnvAdded, changes[1].Kind)
+		assert.Equal(t, "NEW", changes[1].Key)
+		assert.Equal(t, EnvUnchanged, changes[2].Kind)
+	})
+
+	t.Run("prune only touches managed scopes", func(t *testing.T) {
+		changes := PlanEnvSync(existing, desired, EnvSyncScope{Production: true, Prune: true})
+		require.Len(t, changes, 4)
+		assert.Equal(t, EnvRemoved, changes[3].Kind)
+		assert.Equal(t, "STALE", changes[3].Key)
+		assert.Equal(t, "u3", changes[3].UUID)
+	})
+
+	t.Run("attribute-only change", func(t *testing.T) {
+		yes, no := true, false
+		existing := []EnvRecord{
+			{UUID: "u1", Key: "FLAGS", Value: "a", IsBuildTime: true, IsRuntime: true},
+			{UUID: "u2", Key: "KEPT", Value: "b", IsBuildTime: true},
+		}
+		desired := []EnvSyncVar{
+			{Key: "FLAGS", Value: "a", IsBuildTime: &no, IsRuntime: &yes, IsLiteral: &yes},
+			{Key: "KEPT", Value: "b", IsBuildTime: &yes},
+		}
+		changes := PlanEnvSync(existing, desired, EnvSyncScope{Production: true})
+		require.Len(t, changes, 2)
+		assert.Equal(t, EnvChanged, changes[0].Kind)
+		assert.Equal(t, "is_buildtime,is_literal", changes[0].Attributes)
+		assert.Equal(t, EnvUnchanged, changes[1].Kind)
+		assert.Empty(t, changes[1].Attributes)
+	})
+
+	t.Run("preview scope", func(t *testing.T) {
+		changes := PlanEnvSync(existing, []EnvSyncVar{{Key: "OTHER", Value: "p", IsPreview: true}},
+			EnvSyncScope{Preview: true, Prune: true})
+		require.Len(t, changes, 2)
+		assert.Equal(t, EnvAdded, changes[0].Kind)
+		assert.True(t, changes[0].Preview)
+		assert.Equal(t, EnvRemoved, changes[1].Kind)
+		assert.Equal(t, "u4", changes[1].UUID)
+	})
+}
+
+func withoutVar(c EnvChange) EnvChange {
+	c.Var = nil
+	return c
+}
+
+type fakeEnvTarget struct {
+	bulk    []EnvSyncVar
+	created []string
+	deleted []string
+	failKey string
+}
+
+func (f *fakeEnvTarget) ListEnvRecords(context.Context, string) ([]EnvRecord, error) {
+	return nil, nil
+}
+
+func (f *fakeEnvTarget) BulkUpdateEnvVars(_ context.Context, _ string, vars []EnvSyncVar) error {
+	f.bulk = append(f.bulk, vars...)
+	return nil
+}
+
+func (f *fakeEnvTarget) CreateEnvVar(_ context.Context, _ string, v EnvSyncVar) error {
+	if v.Key == f.failKey {
+		return fmt.Errorf("boom")
+	}
+	f.created = append(f.created, v.Key)
+	return nil
+}
+
+func (f *fakeEnvTarget) DeleteEnv(_ context.Context, _, envUUID string) error {
+	f.deleted = append(f.deleted, envUUID)
+	return nil
+}
+
+func TestApplyEnvSync(t *testing.T) {
+	literal := true
+	existing := []EnvRecord{
+		{UUID: "u1", Key: "SAME", Value: "a"},
+		{UUID: "u2", Key: "CHANGED", Value: "old"},
+		{UUID: "u3", Key: "STALE", Value: "x"},
+	}
+	desired := []EnvSyncVar{
+		{Key: "SAME", Value: "a"},
+		{Key: "CHANGED", Value: "new"},
+		{Key: "NEW", Value: "n"},
+		{Key: "BROKEN", Value: "b"},
+	}
+
+	target := &fakeEnvTarget{failKey: "BROKEN"}
+	changes := PlanEnvSync(existing, desired, EnvSyncScope{Production: true, Prune: true})
+	res := ApplyEnvSync(context.Background(), target, "app", changes, EnvSyncProgress{})
+
+	assert.Equal(t, 1, res.Updated)
+	assert.Equal(t, 1, res.Created)
+	assert.Equal(t, 1, res.Deleted)
+	assert.Equal(t, 1, res.Failed)
+	require.Len(t, target.bulk, 1)
+	assert.Equal(t, "CHANGED", target.bulk[0].Key)
+	assert.Equal(t, []string{"NEW"}, target.created)
+	assert.Equal(t, []string{"u3"}, target.deleted)
+
+	// Unchanged values are resent when an explicit attribute flag differs.
+	target = &fakeEnvTarget{}
+	desired = []EnvSyncVar{{Key: "SAME", Value: "a", IsLiteral: &literal}}
+	changes = PlanEnvSync(existing, desired, EnvSyncScope{Production: true})
+	require.Len(t, changes, 1)
+	assert.Equal(t, EnvChanged, changes[0].Kind)
+	res = ApplyEnvSync(context.Background(), target, "app", changes, EnvSyncProgress{})
+	assert.Equal(t, 1, res.Updated)
+	require.Len(t, target.bulk, 1)
+
+	// ...and left alone when the flag already matches.
+	target = &fakeEnvTarget{}
+	existing[0].IsLiteral = true
+	changes = PlanEnvSync(existing, desired, EnvSyncScope{Production: true})
+	require.Len(t, changes, 1)
+	assert.Equal(t, EnvUnchanged, changes[0].Kind)
+	res = ApplyEnvSync(context.Background(), target, "app", changes, EnvSyncProgress{})
+	assert.Equal(t, 0, res.Updated)
+	assert.Empty(t, target.bulk)
+}
+
+func TestApplicationEnvTarget_PreviewFlag(t *testing.T) {
+	var body map[string][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/applications/app-uuid/envs/bulk", r.URL.Path)
+		raw, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(raw, &body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL, "test-token")
+	target := NewApplicationEnvTarget(client)
+	err := target.BulkUpdateEnvVars(context.Background(), "app-uuid", []EnvSyncVar{
+		{Key: "A", Value: "1"},
+		{Key: "A", Value: "2", IsPreview: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, body["data"], 2)
+	assert.NotContains(t, body["data"][0], "is_preview")
+	assert.Equal(t, true, body["data"][1]["is_preview"])
+}
+
+func TestEnvKeyFilter(t *testing.T) {
+	f := EnvKeyFilter{Allow: []string{"APP_*", "DATABASE_URL"}, Deny: []string{"DATABASE_*"}}
+	assert.True(t, f.Match("APP_NAME"))
+	assert.False(t, f.Match("DATABASE_URL"))
+	assert.False(t, f.Match("OTHER"))
+	assert.True(t, EnvKeyFilter{}.Match("ANY"))
+
+	assert.NoError(t, f.Validate())
+	assert.Error(t, EnvKeyFilter{Deny: []string{"["}}.Validate())
+}
+
+func TestPlanEnvPromotion(t *testing.T) {
+	source := []EnvRecord{
+		{Key: "APP_NAME", Value: "demo", IsBuildTime: true, IsRuntime: true},
+		{Key: "FEATURE", Value: "on", IsRuntime: true},
+		{Key: "DATABASE_URL", Value: "postgres://staging"},
+		{Key: "PREVIEW_ONLY", Value: "p", IsPreview: true},
+	}
+	target := []EnvRecord{
+		{UUID: "t1", Key: "APP_NAME", Value: "demo"},
+		{UUID: "t2", Key: "FEATURE", Value: "off"},
+		{UUID: "t3", Key: "DATABASE_URL", Value: "postgres://prod"},
+		{UUID: "t4", Key: "LEGACY", Value: "x"},
+	}
+
+	changes := PlanEnvPromotion(source, target, EnvKeyFilter{Deny: []string{"DATABASE_URL"}}, true)
+	require.Len(t, changes, 3)
+
+	assert.Equal(t, "APP_NAME", changes[0].Key)
+	assert.Equal(t, EnvUnchanged, changes[0].Kind)
+	assert.Empty(t, changes[0].Attributes, "promotion compares values only")
+
+	assert.Equal(t, "FEATURE", changes[1].Key)
+	assert.Equal(t, EnvChanged, changes[1].Kind)
+	require.NotNil(t, changes[1].Var.IsRuntime)
+	assert.True(t, *changes[1].Var.IsRuntime)
+	assert.False(t, *changes[1].Var.IsBuildTime)
+
+	assert.Equal(t, "LEGACY", changes[2].Key)
+	assert.Equal(t, EnvRemoved, changes[2].Kind)
+}
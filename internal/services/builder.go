@@ -12,16 +12,13 @@ const BuilderWorkDir = "/var/lib/coolify-builder/work"
 const BuilderBinaryPath = "/usr/local/bin/builder"
 
 // BuilderInstallCommand returns a shell snippet that installs buildah + git
-// (required by the builder pipeline), ensures the work directory exists,
-// and downloads the builder binary from the GitHub release for the given
-// version tag. The version tag should track the coold release — builder
-// and coold ship from the same workspace.
-func BuilderInstallCommand(version string) string {
+// (required by the builder pipeline) with pm, ensures the work directory
+// exists, and downloads the builder binary from the GitHub release for the
+// given version tag. The version tag should track the coold release —
+// builder and coold ship from the same workspace.
+func BuilderInstallCommand(pm PackageManager, version string) string {
 	return fmt.Sprintf(`set -e
-DEBIAN_FRONTEND=noninteractive apt-get update -qq 2>/dev/null
-DEBIAN_FRONTEND=noninteractive apt-get install -y \
-  -o Dpkg::Options::="--force-confold" \
-  buildah git ca-certificates 2>&1 >/dev/null
+%[4]s
 mkdir -p %[1]s
 ARCH_RAW=$(uname -m)
 case "$ARCH_RAW" in
@@ -38,5 +35,5 @@ test -f "$DLDIR/builder" || { echo "builder binary not found in tarball" >&2; ex
 install -m 0755 "$DLDIR/builder" %[3]s.tmp
 mv %[3]s.tmp %[3]s
 echo '%[2]s' > %[3]s.version`,
-		BuilderWorkDir, version, BuilderBinaryPath)
+		BuilderWorkDir, version, BuilderBinaryPath, pm.InstallCommand(PackageBuilderTools))
 }
@@ -3,6 +3,7 @@ package services
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 )
 
@@ -45,8 +46,9 @@ func CooldNamespacesEnvValue(ns []CooldNamespace) string {
 // SchedulerConfig carries optional scheduler connectivity injected into the coold unit
 // for non-central hosts. nil means no scheduler env vars are emitted.
 type SchedulerConfig struct {
-	URL     string // e.g. "http://100.64.0.1:6443"
-	JWTPath string // e.g. "/etc/coolify/host-jwt"
+	URL     string   // e.g. "http://100.64.0.1:6443"
+	JWTPath string   // e.g. "/etc/coolify/host-jwt"
+	Caps    []string // caps claim of the host JWT; coold advertises exactly these
 }
 
 // BuilderConfig carries the builder-capability env vars coold needs when it
@@ -88,15 +90,18 @@ Environment=COOLD_DNS_ZONE=%s
 	// Firewall REST API binds wg0-only (never a public interface) and requires
 	// a bearer token. Plain HTTP for alpha — TLS material is managed by the
 	// central Coolify control plane and will be wired in a follow-up.
-	apiEnv := fmt.Sprintf(`Environment=COOLD_API_BIND=%s:%d
+	apiEnv := fmt.Sprintf(`Environment=COOLD_API_BIND=%s
 Environment=COOLD_API_TOKEN_FILE=%s
-`, mgmtIP, CooldAPIPort, CooldAPITokenPath)
+`, net.JoinHostPort(mgmtIP.String(), strconv.Itoa(CooldAPIPort)), CooldAPITokenPath)
 
 	schedulerEnv := ""
 	if scheduler != nil {
 		schedulerEnv = fmt.Sprintf(`Environment=COOLD_SCHEDULER_URL=%s
 Environment=COOLD_HOST_JWT_PATH=%s
 `, scheduler.URL, scheduler.JWTPath)
+		if len(scheduler.Caps) > 0 {
+			schedulerEnv += fmt.Sprintf("Environment=COOLD_CAPS=%s\n", strings.Join(scheduler.Caps, ","))
+		}
 	}
 
 	builderEnv := ""
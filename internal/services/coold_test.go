@@ -135,6 +135,24 @@ func TestCooldServiceUnit_OmitsBuilderEnvWhenNil(t *testing.T) {
 	}
 }
 
+func TestCooldServiceUnit_CapsEnv(t *testing.T) {
+	got := CooldServiceUnitWithScheduler(
+		net.ParseIP("100.64.0.5"),
+		nil,
+		&SchedulerConfig{URL: "http://100.64.0.1:6443", JWTPath: "/etc/coolify/host-jwt", Caps: []string{"coold", "builder", "gpu"}},
+		nil,
+	)
+	if !strings.Contains(got, "Environment=COOLD_CAPS=coold,builder,gpu\n") {
+		t.Errorf("unit missing COOLD_CAPS:\n%s", got)
+	}
+
+	got = CooldServiceUnitWithScheduler(net.ParseIP("100.64.0.5"), nil,
+		&SchedulerConfig{URL: "http://100.64.0.1:6443", JWTPath: "/etc/coolify/host-jwt"}, nil)
+	if strings.Contains(got, "COOLD_CAPS") {
+		t.Errorf("expected no COOLD_CAPS without caps, got:\n%s", got)
+	}
+}
+
 func TestCooldNamespacesEnvValue_Triples(t *testing.T) {
 	ns := []CooldNamespace{
 		{Name: "default", Network: "coolify-default-mesh", BridgeGateway: net.ParseIP("10.210.0.1")},
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"net"
 	"sort"
+	"strconv"
 	"strings"
 )
 
-// CoolifySchemaSQL is the Corrosion schema that coold's sync loop writes to.
+// CoolifySchemaSQL is the Corrosion schema that coold's sync loop writes to,
+// rendered from CorrosionMigrations at the latest version.
 //
 // Every NOT NULL column MUST have a DEFAULT — corrosion's CR-SQLite backend
 // rejects schemas missing defaults with "needs a default value for forward
@@ -29,17 +31,7 @@ import (
 //   - health: podman HEALTHCHECK result. One of:
 //     "healthy", "unhealthy", "starting", "unknown". "unknown" when the
 //     container has no HEALTHCHECK declared. Readiness.
-const CoolifySchemaSQL = `CREATE TABLE service_endpoints (
-    container_id    TEXT NOT NULL DEFAULT '' PRIMARY KEY,
-    container_name  TEXT NOT NULL DEFAULT '',
-    namespace       TEXT NOT NULL DEFAULT '',
-    host_mgmt_ip    TEXT NOT NULL DEFAULT '',
-    container_ip    TEXT NOT NULL DEFAULT '',
-    state           TEXT NOT NULL DEFAULT '',
-    health          TEXT NOT NULL DEFAULT 'unknown',
-    updated_at      INTEGER NOT NULL DEFAULT 0
-);
-`
+var CoolifySchemaSQL = CorrosionSchemaAt(CorrosionSchemaVersion)
 
 // CorrosionConfigBytes renders /etc/corrosion/config.toml for a single host.
 //
@@ -63,13 +55,13 @@ func CorrosionConfigBytes(bindAddr net.IP, gossipPort, apiPort int, peers []net.
 	b.WriteString(`path = "/var/lib/corrosion/corrosion.db"` + "\n")
 	b.WriteString(`schema_paths = ["/etc/corrosion/schemas"]` + "\n")
 	b.WriteString("\n[gossip]\n")
-	fmt.Fprintf(&b, "addr = \"%s:%d\"\n", bindAddr, gossipPort)
+	fmt.Fprintf(&b, "addr = \"%s\"\n", net.JoinHostPort(bindAddr.String(), strconv.Itoa(gossipPort)))
 	b.WriteString("bootstrap = [")
 	for i, p := range sorted {
 		if i > 0 {
 			b.WriteString(", ")
 		}
-		fmt.Fprintf(&b, "\"%s:%d\"", p, gossipPort)
+		fmt.Fprintf(&b, "\"%s\"", net.JoinHostPort(p, strconv.Itoa(gossipPort)))
 	}
 	b.WriteString("]\n")
 	b.WriteString("plaintext = true\n")
@@ -80,6 +72,16 @@ func CorrosionConfigBytes(bindAddr net.IP, gossipPort, apiPort int, peers []net.
 	return []byte(b.String())
 }
 
+// CorrosionPurgeHostCommand returns a shell snippet that deletes every
+// service_endpoints row published by the host with the given mgmt IP,
+// through the local corrosion API. Run it on a surviving host after the
+// removed host's coold has stopped so its containers drop out of DNS.
+func CorrosionPurgeHostCommand(apiPort int, mgmtIP net.IP) string {
+	body := fmt.Sprintf(`[["DELETE FROM service_endpoints WHERE host_mgmt_ip = ?", ["%s"]]]`, mgmtIP)
+	return fmt.Sprintf(`curl -fsS --max-time 30 -X POST -H 'Content-Type: application/json' `+
+		`--data '%s' http://127.0.0.1:%d/v1/transactions 2>&1`, body, apiPort)
+}
+
 // CorrosionInstallCommand returns a shell snippet that downloads and installs
 // corrosion from the GitHub release for the given version tag.
 // Architecture is auto-detected on the remote host via uname -m.
@@ -37,6 +37,19 @@ func TestCorrosionInstallCommand_ArchDetection(t *testing.T) {
 	}
 }
 
+func TestCorrosionPurgeHostCommand(t *testing.T) {
+	cmd := CorrosionPurgeHostCommand(8080, net.ParseIP("100.64.0.3"))
+	for _, want := range []string{
+		"http://127.0.0.1:8080/v1/transactions",
+		`DELETE FROM service_endpoints WHERE host_mgmt_ip = ?`,
+		`["100.64.0.3"]`,
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in purge command:\n%s", want, cmd)
+		}
+	}
+}
+
 func TestCorrosionConfigBytes_GoldenThreeHost(t *testing.T) {
 	self := net.ParseIP("100.64.0.1")
 	peers := []net.IP{
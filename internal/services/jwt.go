@@ -1,15 +1,50 @@
 package services
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// HostJWTLifetime is how long a host JWT minted by MintHostJWT is valid.
+const HostJWTLifetime = 365 * 24 * time.Hour
+
+// Built-in capabilities of a host JWT. CapCoold is always present;
+// CapBuilder follows --enable-builder / --builder-hosts. Everything else in
+// the caps claim is an operator label (gpu, edge, storage, ...).
+const (
+	CapCoold   = "coold"
+	CapBuilder = "builder"
+)
+
+// capLabelPattern is the shape of an operator capability label. Labels are
+// joined with commas into COOLD_CAPS, so they must not contain one.
+var capLabelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
+// ValidateCapLabel rejects a malformed label or one naming a built-in
+// capability.
+func ValidateCapLabel(label string) error {
+	switch {
+	case label == CapCoold:
+		return fmt.Errorf("%q is always granted and cannot be changed", label)
+	case label == CapBuilder:
+		return fmt.Errorf("%q is managed by --enable-builder / --builder-hosts", label)
+	case !capLabelPattern.MatchString(label):
+		return fmt.Errorf("invalid capability %q: use lowercase letters, digits, '.', '_' or '-' (at most 63)", label)
+	}
+	return nil
+}
+
 // MintHostJWT creates a 1-year ES256 JWT signed with the EC P-256 private key.
 //
 // privKeyPEM must be PKCS8 EC PEM (produced by `openssl genpkey -algorithm EC
@@ -18,8 +53,11 @@ import (
 //
 // caps lists the capabilities this host is authorized to advertise in the
 // coold Hello frame. Always includes "coold"; hosts that accept builds also
-// carry "builder". The scheduler cross-checks the advertised Hello capability
+// carry "builder", followed by any operator labels. The scheduler cross-checks the advertised Hello capability
 // set against this claim and rejects streams that try to elevate.
+//
+// The `kid` header is the JWTKeyID of the signing key, so a scheduler
+// trusting several keys during a rotation can pick the right one.
 func MintHostJWT(privKeyPEM []byte, hostID string, caps []string) (string, error) {
 	block, _ := pem.Decode(privKeyPEM)
 	if block == nil {
@@ -33,17 +71,185 @@ func MintHostJWT(privKeyPEM []byte, hostID string, caps []string) (string, error
 	if !ok {
 		return "", fmt.Errorf("expected EC private key, got %T", raw)
 	}
+	kid, err := JWTKeyID(&ecKey.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("generate jti: %w", err)
+	}
 	if len(caps) == 0 {
-		caps = []string{"coold"}
+		caps = []string{CapCoold}
 	}
 	now := time.Now()
 	claims := jwt.MapClaims{
 		"sub":  hostID,
 		"aud":  "coold",
 		"caps": caps,
+		"jti":  hex.EncodeToString(jti),
 		"iat":  now.Unix(),
-		"exp":  now.Add(365 * 24 * time.Hour).Unix(),
+		"exp":  now.Add(HostJWTLifetime).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
 	return token.SignedString(ecKey)
 }
+
+// JWTKeyID identifies a scheduler public key: the first 16 hex digits of
+// the sha256 of its DER SubjectPublicKeyInfo.
+func JWTKeyID(pub *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("marshal public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// HostJWTClaims are the claims of a host JWT, decoded without verifying
+// its signature.
+type HostJWTClaims struct {
+	Subject  string
+	Audience []string
+	Caps     []string
+	ID       string
+	// KeyID is the `kid` header; empty for tokens minted before key IDs.
+	KeyID     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+type hostClaims struct {
+	jwt.RegisteredClaims
+	Caps []string `json:"caps"`
+}
+
+// ParseHostJWT decodes a host JWT. It does not verify the signature; see
+// VerifyHostJWT.
+func ParseHostJWT(token string) (*HostJWTClaims, error) {
+	var claims hostClaims
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("decode host JWT: %w", err)
+	}
+	out := &HostJWTClaims{
+		Subject:  claims.Subject,
+		Audience: claims.Audience,
+		Caps:     claims.Caps,
+		ID:       claims.ID,
+	}
+	if kid, ok := parsed.Header["kid"].(string); ok {
+		out.KeyID = kid
+	}
+	if claims.IssuedAt != nil {
+		out.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		out.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return out, nil
+}
+
+// JWTPublicKey is one public key the scheduler trusts for host JWTs.
+type JWTPublicKey struct {
+	ID  string
+	Key *ecdsa.PublicKey
+}
+
+// ParseJWTKeySet decodes the scheduler's key set: concatenated PEM public
+// keys, current key first.
+func ParseJWTKeySet(data []byte) ([]JWTPublicKey, error) {
+	var keys []JWTPublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		raw, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key %d: %w", len(keys)+1, err)
+		}
+		pub, ok := raw.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key %d: expected EC key, got %T", len(keys)+1, raw)
+		}
+		id, err := JWTKeyID(pub)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, JWTPublicKey{ID: id, Key: pub})
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		return nil, fmt.Errorf("trailing data after %d public key(s)", len(keys))
+	}
+	return keys, nil
+}
+
+// EncodeJWTKeySet renders keys in the format ParseJWTKeySet reads.
+func EncodeJWTKeySet(keys []JWTPublicKey) ([]byte, error) {
+	var b bytes.Buffer
+	for _, k := range keys {
+		der, err := x509.MarshalPKIXPublicKey(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("marshal public key %s: %w", k.ID, err)
+		}
+		if err := pem.Encode(&b, &pem.Block{Type: "PUBLIC KEY", Bytes: der}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// VerifyHostJWT returns the ID of the key in keys whose signature token
+// carries, or "" when none of them signed it. Expiry is not checked.
+func VerifyHostJWT(token string, keys []JWTPublicKey) string {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}), jwt.WithoutClaimsValidation())
+	for _, k := range keys {
+		_, err := parser.Parse(token, func(*jwt.Token) (any, error) { return k.Key, nil })
+		if err == nil {
+			return k.ID
+		}
+	}
+	return ""
+}
+
+// JWTRevocation makes the scheduler reject every host JWT for Subject
+// issued at or before Before (unix seconds). Host is informational.
+type JWTRevocation struct {
+	Subject string `json:"sub"`
+	Before  int64  `json:"before"`
+	Host    string `json:"host,omitempty"`
+}
+
+// Revokes reports whether r rejects a token with claims c.
+func (r JWTRevocation) Revokes(c *HostJWTClaims) bool {
+	return c.Subject == r.Subject && c.IssuedAt.Unix() <= r.Before
+}
+
+// ParseJWTRevocations decodes SchedulerJWTRevokedPath. An empty file is an
+// empty list.
+func ParseJWTRevocations(data []byte) ([]JWTRevocation, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var revs []JWTRevocation
+	if err := json.Unmarshal(data, &revs); err != nil {
+		return nil, fmt.Errorf("decode JWT revocations: %w", err)
+	}
+	return revs, nil
+}
+
+// EncodeJWTRevocations renders revs in the format ParseJWTRevocations
+// reads.
+func EncodeJWTRevocations(revs []JWTRevocation) ([]byte, error) {
+	if revs == nil {
+		revs = []JWTRevocation{}
+	}
+	data, err := json.MarshalIndent(revs, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testJWTKey(t *testing.T) ([]byte, JWTPublicKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := JWTKeyID(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), JWTPublicKey{ID: id, Key: &key.PublicKey}
+}
+
+func TestMintHostJWT_ParseRoundTrip(t *testing.T) {
+	priv, pub := testJWTKey(t)
+	token, err := MintHostJWT(priv, "100.64.0.2", []string{"coold", "builder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseHostJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Subject != "100.64.0.2" || strings.Join(c.Caps, ",") != "coold,builder" {
+		t.Errorf("claims = %+v", c)
+	}
+	if c.KeyID != pub.ID {
+		t.Errorf("kid = %q, want %q", c.KeyID, pub.ID)
+	}
+	if c.ID == "" {
+		t.Error("missing jti")
+	}
+	if got := c.ExpiresAt.Sub(c.IssuedAt); got != HostJWTLifetime {
+		t.Errorf("lifetime = %s, want %s", got, HostJWTLifetime)
+	}
+}
+
+func TestVerifyHostJWT_FindsSigningKey(t *testing.T) {
+	oldPriv, oldPub := testJWTKey(t)
+	_, newPub := testJWTKey(t)
+	token, err := MintHostJWT(oldPriv, "100.64.0.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := VerifyHostJWT(token, []JWTPublicKey{newPub, oldPub}); got != oldPub.ID {
+		t.Errorf("signed by %q, want %q", got, oldPub.ID)
+	}
+	if got := VerifyHostJWT(token, []JWTPublicKey{newPub}); got != "" {
+		t.Errorf("untrusted token verified by %q", got)
+	}
+}
+
+func TestJWTKeySet_RoundTrip(t *testing.T) {
+	_, a := testJWTKey(t)
+	_, b := testJWTKey(t)
+	data, err := EncodeJWTKeySet([]JWTPublicKey{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseJWTKeySet(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].ID != a.ID || keys[1].ID != b.ID {
+		t.Fatalf("keys = %+v", keys)
+	}
+	if _, err := ParseJWTKeySet(append(data, "garbage"...)); err == nil {
+		t.Error("expected an error for trailing data")
+	}
+}
+
+func TestJWTRevocation_Revokes(t *testing.T) {
+	issued := time.Unix(1_700_000_000, 0)
+	c := &HostJWTClaims{Subject: "100.64.0.2", IssuedAt: issued}
+	if !(JWTRevocation{Subject: "100.64.0.2", Before: issued.Unix()}).Revokes(c) {
+		t.Error("a token issued at the cutoff must be revoked")
+	}
+	if (JWTRevocation{Subject: "100.64.0.2", Before: issued.Unix() - 1}).Revokes(c) {
+		t.Error("a token issued after the cutoff must stay valid")
+	}
+	if (JWTRevocation{Subject: "100.64.0.3", Before: issued.Unix()}).Revokes(c) {
+		t.Error("another subject must stay valid")
+	}
+
+	revs, err := ParseJWTRevocations(nil)
+	if err != nil || revs != nil {
+		t.Fatalf("empty file: %v, %v", revs, err)
+	}
+	data, err := EncodeJWTRevocations([]JWTRevocation{{Subject: "100.64.0.2", Before: 5, Host: "h2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revs, err = ParseJWTRevocations(data)
+	if err != nil || len(revs) != 1 || revs[0].Host != "h2" {
+		t.Fatalf("round trip: %v, %v", revs, err)
+	}
+}
+
+func TestValidateCapLabel(t *testing.T) {
+	for _, ok := range []string{"gpu", "edge", "storage", "region-eu", "zone.a_1"} {
+		if err := ValidateCapLabel(ok); err != nil {
+			t.Errorf("%q: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "coold", "builder", "GPU", "a,b", "-edge", "has space"} {
+		if err := ValidateCapLabel(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestRotateJWTKeypairCommand_TrustsNewKeyBeforeSwitching(t *testing.T) {
+	cmd := RotateJWTKeypairCommand()
+	trust := strings.Index(cmd, "mv "+SchedulerJWTPubSetPath+".tmp")
+	restart := strings.Index(cmd, "systemctl try-restart scheduler")
+	switchKey := strings.Index(cmd, "mv "+SchedulerJWTPrivPath+".next")
+	if trust < 0 || restart < trust || switchKey < restart {
+		t.Errorf("key set must be updated and the scheduler restarted before the private key switches:\n%s", cmd)
+	}
+}
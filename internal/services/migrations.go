@@ -0,0 +1,247 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CorrosionSchemaPath is the schema file corrosion loads from schema_paths
+// at start.
+const CorrosionSchemaPath = "/etc/corrosion/schemas/coolify.sql"
+
+// CorrosionSchemaVersionPath records the version of the last schema written
+// or migrated on the host. It lives outside schema_paths, which corrosion
+// would try to parse.
+const CorrosionSchemaVersionPath = "/var/lib/corrosion/coolify-schema.version"
+
+// SchemaColumn is one column of a Corrosion table: its name and the rest of
+// its definition. A NOT NULL column needs a DEFAULT (see CoolifySchemaSQL).
+type SchemaColumn struct {
+	Name string
+	Def  string
+}
+
+// SchemaStep is one additive change: a new table, or columns appended to a
+// table an earlier migration created. Nothing is ever renamed, retyped or
+// dropped — CR-SQLite cannot apply that to a replicated table in place.
+type SchemaStep struct {
+	Table   string
+	Create  bool
+	Columns []SchemaColumn
+}
+
+// CorrosionMigration moves the Corrosion schema from Version-1 to Version.
+type CorrosionMigration struct {
+	Version     int
+	Description string
+	Steps       []SchemaStep
+}
+
+// CorrosionMigrations is the ordered history of the Corrosion schema. The
+// file a host loads is always the schema at one of these versions, so its
+// sha256 tells which migrations it still needs. Append new migrations;
+// never edit a released one, or hosts on it will no longer be recognized.
+var CorrosionMigrations = []CorrosionMigration{
+	{
+		Version:     1,
+		Description: "service_endpoints",
+		Steps: []SchemaStep{{
+			Table:  "service_endpoints",
+			Create: true,
+			Columns: []SchemaColumn{
+				{"container_id", "TEXT NOT NULL DEFAULT '' PRIMARY KEY"},
+				{"container_name", "TEXT NOT NULL DEFAULT ''"},
+				{"namespace", "TEXT NOT NULL DEFAULT ''"},
+				{"host_mgmt_ip", "TEXT NOT NULL DEFAULT ''"},
+				{"container_ip", "TEXT NOT NULL DEFAULT ''"},
+				{"state", "TEXT NOT NULL DEFAULT ''"},
+				{"health", "TEXT NOT NULL DEFAULT 'unknown'"},
+				{"updated_at", "INTEGER NOT NULL DEFAULT 0"},
+			},
+		}},
+	},
+}
+
+// CorrosionSchemaVersion is the version of CoolifySchemaSQL.
+var CorrosionSchemaVersion = CorrosionMigrations[len(CorrosionMigrations)-1].Version
+
+// CorrosionSchemaAt renders the schema file at version.
+func CorrosionSchemaAt(version int) string {
+	return renderSchema(schemaTables(CorrosionMigrations, version))
+}
+
+// CorrosionSchemaVersionOf returns the version whose schema file has the
+// given sha256 (hex), or 0 when it is none of them.
+func CorrosionSchemaVersionOf(sum string) int {
+	return versionOf(CorrosionMigrations, sum)
+}
+
+// CorrosionMigrationsSince returns the migrations a host at version from
+// still needs to reach CorrosionSchemaVersion.
+func CorrosionMigrationsSince(from int) []CorrosionMigration {
+	var out []CorrosionMigration
+	for _, m := range CorrosionMigrations {
+		if m.Version > from {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// CorrosionMigrateCommand returns a shell snippet that migrates a live
+// corrosion from version from to CorrosionSchemaVersion: the CREATE TABLE
+// statement of every table the pending migrations touch, at the new
+// version, is posted to /v1/migrations. Corrosion diffs it against the
+// tables it has and adds the new tables and columns in place, keeping every
+// row. When corrosion is not running the snippet does nothing; it loads the
+// schema file at start instead.
+func CorrosionMigrateCommand(apiPort, from int) (string, error) {
+	stmts := migrationStatements(CorrosionMigrations, from, CorrosionSchemaVersion)
+	if len(stmts) == 0 {
+		return "", fmt.Errorf("corrosion schema v%d needs no migration", from)
+	}
+	body, err := json.Marshal(stmts)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`if systemctl is-active --quiet corrosion; then `+
+		`curl -fsS --max-time 30 -X POST -H 'Content-Type: application/json' `+
+		`--data '%s' http://127.0.0.1:%d/v1/migrations; fi`,
+		strings.ReplaceAll(string(body), `'`, `'\''`), apiPort), nil
+}
+
+// RecordCorrosionSchemaVersionCommand returns a shell snippet that records
+// CorrosionSchemaVersion on the host.
+func RecordCorrosionSchemaVersionCommand() string {
+	return fmt.Sprintf(`mkdir -p /var/lib/corrosion && echo %d > %s`,
+		CorrosionSchemaVersion, CorrosionSchemaVersionPath)
+}
+
+// schemaTable is a table as of some version, in creation order.
+type schemaTable struct {
+	name    string
+	columns []SchemaColumn
+}
+
+// schemaTables folds the steps of migrations up to version.
+func schemaTables(migrations []CorrosionMigration, version int) []schemaTable {
+	var tables []schemaTable
+	for _, m := range migrations {
+		if m.Version > version {
+			break
+		}
+		for _, s := range m.Steps {
+			if s.Create {
+				tables = append(tables, schemaTable{name: s.Table, columns: append([]SchemaColumn(nil), s.Columns...)})
+				continue
+			}
+			for i := range tables {
+				if tables[i].name == s.Table {
+					tables[i].columns = append(tables[i].columns, s.Columns...)
+				}
+			}
+		}
+	}
+	return tables
+}
+
+// createTable renders the CREATE TABLE statement of t, without the
+// trailing newline.
+func createTable(t schemaTable) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", t.name)
+	for i, c := range t.columns {
+		fmt.Fprintf(&b, "    %-15s %s", c.Name, c.Def)
+		if i < len(t.columns)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(");")
+	return b.String()
+}
+
+func renderSchema(tables []schemaTable) string {
+	parts := make([]string, 0, len(tables))
+	for _, t := range tables {
+		parts = append(parts, createTable(t)+"\n")
+	}
+	return strings.Join(parts, "\n")
+}
+
+func versionOf(migrations []CorrosionMigration, sum string) int {
+	for _, m := range migrations {
+		h := sha256.Sum256([]byte(renderSchema(schemaTables(migrations, m.Version))))
+		if hex.EncodeToString(h[:]) == sum {
+			return m.Version
+		}
+	}
+	return 0
+}
+
+// migrationStatements returns the CREATE TABLE statements, at version to,
+// of the tables the migrations after from touch.
+func migrationStatements(migrations []CorrosionMigration, from, to int) []string {
+	touched := map[string]bool{}
+	for _, m := range migrations {
+		if m.Version <= from || m.Version > to {
+			continue
+		}
+		for _, s := range m.Steps {
+			touched[s.Table] = true
+		}
+	}
+	var stmts []string
+	for _, t := range schemaTables(migrations, to) {
+		if touched[t.name] {
+			stmts = append(stmts, createTable(t))
+		}
+	}
+	return stmts
+}
+
+// validateMigrations checks that migrations are numbered 1..n and only
+// make changes CR-SQLite can apply to a live table.
+func validateMigrations(migrations []CorrosionMigration) error {
+	columns := map[string]map[string]bool{}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return fmt.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if len(m.Steps) == 0 {
+			return fmt.Errorf("migration v%d has no steps", m.Version)
+		}
+		for _, s := range m.Steps {
+			cols, exists := columns[s.Table]
+			switch {
+			case s.Create && exists:
+				return fmt.Errorf("migration v%d creates table %s again", m.Version, s.Table)
+			case !s.Create && !exists:
+				return fmt.Errorf("migration v%d adds columns to unknown table %s", m.Version, s.Table)
+			case s.Create:
+				cols = map[string]bool{}
+				columns[s.Table] = cols
+			}
+			if len(s.Columns) == 0 {
+				return fmt.Errorf("migration v%d: %s has no columns", m.Version, s.Table)
+			}
+			for _, c := range s.Columns {
+				def := strings.ToUpper(c.Def)
+				if cols[c.Name] {
+					return fmt.Errorf("migration v%d: column %s.%s already exists", m.Version, s.Table, c.Name)
+				}
+				cols[c.Name] = true
+				if strings.Contains(def, "NOT NULL") && !strings.Contains(def, "DEFAULT") {
+					return fmt.Errorf("migration v%d: %s.%s is NOT NULL without a DEFAULT", m.Version, s.Table, c.Name)
+				}
+				if !s.Create && (strings.Contains(def, "PRIMARY KEY") || strings.Contains(def, "UNIQUE")) {
+					return fmt.Errorf("migration v%d: added column %s.%s cannot be a key", m.Version, s.Table, c.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
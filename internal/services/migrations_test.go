@@ -0,0 +1,141 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// schemaV1 is the schema file every host installed before migrations
+// existed. Its bytes must never change or those hosts stop being
+// recognized as v1.
+const schemaV1 = `CREATE TABLE service_endpoints (
+    container_id    TEXT NOT NULL DEFAULT '' PRIMARY KEY,
+    container_name  TEXT NOT NULL DEFAULT '',
+    namespace       TEXT NOT NULL DEFAULT '',
+    host_mgmt_ip    TEXT NOT NULL DEFAULT '',
+    container_ip    TEXT NOT NULL DEFAULT '',
+    state           TEXT NOT NULL DEFAULT '',
+    health          TEXT NOT NULL DEFAULT 'unknown',
+    updated_at      INTEGER NOT NULL DEFAULT 0
+);
+`
+
+func shaOf(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// withV2 appends a synthetic migration adding a column and a table.
+func withV2() []CorrosionMigration {
+	return append(append([]CorrosionMigration(nil), CorrosionMigrations[0]), CorrosionMigration{
+		Version:     2,
+		Description: "service_endpoints.image, service_ports",
+		Steps: []SchemaStep{
+			{Table: "service_endpoints", Columns: []SchemaColumn{{"image", "TEXT NOT NULL DEFAULT ''"}}},
+			{Table: "service_ports", Create: true, Columns: []SchemaColumn{
+				{"container_id", "TEXT NOT NULL DEFAULT ''"},
+				{"port", "INTEGER NOT NULL DEFAULT 0"},
+			}},
+		},
+	})
+}
+
+func TestCorrosionMigrations_Valid(t *testing.T) {
+	if err := validateMigrations(CorrosionMigrations); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateMigrations(withV2()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCorrosionSchemaAt_V1IsByteStable(t *testing.T) {
+	if got := CorrosionSchemaAt(1); got != schemaV1 {
+		t.Fatalf("v1 schema changed:\n%s", got)
+	}
+	if v := CorrosionSchemaVersionOf(shaOf(schemaV1)); v != 1 {
+		t.Fatalf("version of v1 sha = %d, want 1", v)
+	}
+	if v := CorrosionSchemaVersionOf(shaOf("CREATE TABLE edited (x TEXT);\n")); v != 0 {
+		t.Fatalf("version of unknown sha = %d, want 0", v)
+	}
+}
+
+func TestSchemaTables_AppendsColumnsAndTables(t *testing.T) {
+	got := renderSchema(schemaTables(withV2(), 2))
+	want := strings.Replace(schemaV1, "    updated_at      INTEGER NOT NULL DEFAULT 0\n",
+		"    updated_at      INTEGER NOT NULL DEFAULT 0,\n    image           TEXT NOT NULL DEFAULT ''\n", 1) +
+		"\nCREATE TABLE service_ports (\n" +
+		"    container_id    TEXT NOT NULL DEFAULT '',\n" +
+		"    port            INTEGER NOT NULL DEFAULT 0\n);\n"
+	if got != want {
+		t.Fatalf("v2 schema:\n%s\nwant:\n%s", got, want)
+	}
+	if v := versionOf(withV2(), shaOf(schemaV1)); v != 1 {
+		t.Fatalf("v1 sha under a v2 history = %d, want 1", v)
+	}
+}
+
+func TestMigrationStatements_OnlyTouchedTables(t *testing.T) {
+	stmts := migrationStatements(withV2(), 1, 2)
+	if len(stmts) != 2 {
+		t.Fatalf("got %d statements, want 2: %q", len(stmts), stmts)
+	}
+	if !strings.Contains(stmts[0], "image           TEXT NOT NULL DEFAULT ''") {
+		t.Errorf("service_endpoints not rendered at v2: %s", stmts[0])
+	}
+	if !strings.HasPrefix(stmts[1], "CREATE TABLE service_ports (") {
+		t.Errorf("second statement = %s", stmts[1])
+	}
+	if got := migrationStatements(withV2(), 2, 2); len(got) != 0 {
+		t.Errorf("no migration pending, got %q", got)
+	}
+}
+
+func TestValidateMigrations_RejectsUnsafeSteps(t *testing.T) {
+	v1 := CorrosionMigrations[0]
+	for name, m := range map[string]CorrosionMigration{
+		"gap": {Version: 3, Description: "x", Steps: v1.Steps},
+		"recreate": {Version: 2, Description: "x", Steps: []SchemaStep{
+			{Table: "service_endpoints", Create: true, Columns: []SchemaColumn{{"a", "TEXT"}}},
+		}},
+		"unknown table": {Version: 2, Description: "x", Steps: []SchemaStep{
+			{Table: "nope", Columns: []SchemaColumn{{"a", "TEXT"}}},
+		}},
+		"duplicate column": {Version: 2, Description: "x", Steps: []SchemaStep{
+			{Table: "service_endpoints", Columns: []SchemaColumn{{"state", "TEXT"}}},
+		}},
+		"no default": {Version: 2, Description: "x", Steps: []SchemaStep{
+			{Table: "service_endpoints", Columns: []SchemaColumn{{"a", "TEXT NOT NULL"}}},
+		}},
+		"added key": {Version: 2, Description: "x", Steps: []SchemaStep{
+			{Table: "service_endpoints", Columns: []SchemaColumn{{"a", "TEXT NOT NULL DEFAULT '' UNIQUE"}}},
+		}},
+	} {
+		if err := validateMigrations([]CorrosionMigration{v1, m}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCorrosionMigrateCommand(t *testing.T) {
+	if _, err := CorrosionMigrateCommand(8080, CorrosionSchemaVersion); err == nil {
+		t.Fatal("expected an error when no migration is pending")
+	}
+	cmd, err := CorrosionMigrateCommand(8080, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"systemctl is-active --quiet corrosion",
+		"http://127.0.0.1:8080/v1/migrations",
+		`["CREATE TABLE service_endpoints (\n`,
+		`DEFAULT '\''unknown'\''`,
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command missing %q:\n%s", want, cmd)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PackageManager is the distro package manager used to install host
+// packages. The empty value means the host's distro was not detected; its
+// commands then pick the manager on the host at run time, from os-release
+// like DetectPackageManager and by binary for unknown distros.
+type PackageManager string
+
+const (
+	PackageManagerApt    PackageManager = "apt"
+	PackageManagerDnf    PackageManager = "dnf"
+	PackageManagerZypper PackageManager = "zypper"
+	// PackageManagerApk only helps Alpine hosts booted with systemd; the
+	// mesh probe rejects OpenRC hosts before anything is installed.
+	PackageManagerApk PackageManager = "apk"
+)
+
+// PackageManagers lists the supported managers in detection order.
+var PackageManagers = []PackageManager{
+	PackageManagerApt, PackageManagerDnf, PackageManagerZypper, PackageManagerApk,
+}
+
+// Package is a distro-independent package set that resolves to per-distro
+// package names.
+type Package string
+
+const (
+	PackageWireGuard    Package = "wireguard"
+	PackagePodman       Package = "podman"
+	PackageBuilderTools Package = "builder-tools"
+)
+
+// OSReleaseCommand prints /etc/os-release for DetectPackageManager.
+const OSReleaseCommand = `cat /etc/os-release 2>/dev/null || cat /usr/lib/os-release 2>/dev/null || true`
+
+// osFamilies maps /etc/os-release ID and ID_LIKE values to managers.
+var osFamilies = map[string]PackageManager{
+	"debian":    PackageManagerApt,
+	"ubuntu":    PackageManagerApt,
+	"fedora":    PackageManagerDnf,
+	"rhel":      PackageManagerDnf,
+	"centos":    PackageManagerDnf,
+	"rocky":     PackageManagerDnf,
+	"almalinux": PackageManagerDnf,
+	"suse":      PackageManagerZypper,
+	"opensuse":  PackageManagerZypper,
+	"sles":      PackageManagerZypper,
+	"alpine":    PackageManagerApk,
+}
+
+// ParseOSRelease returns the ID and ID_LIKE values of an os-release file.
+func ParseOSRelease(osRelease string) (id string, idLike []string) {
+	sc := bufio.NewScanner(strings.NewReader(osRelease))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+		if !ok {
+			continue
+		}
+		value = strings.ToLower(strings.Trim(value, `"'`))
+		switch key {
+		case "ID":
+			id = value
+		case "ID_LIKE":
+			idLike = strings.Fields(value)
+		}
+	}
+	return id, idLike
+}
+
+// DetectPackageManager picks the manager for an os-release file: ID first,
+// then each ID_LIKE entry (Rocky and Alma report ID_LIKE="rhel centos
+// fedora"). Returns "" for an unknown distro.
+func DetectPackageManager(osRelease string) PackageManager {
+	id, idLike := ParseOSRelease(osRelease)
+	for _, candidate := range append([]string{id}, idLike...) {
+		if pm, ok := osFamilies[candidate]; ok {
+			return pm
+		}
+		// openSUSE reports ID="opensuse-leap" / "opensuse-tumbleweed".
+		if strings.HasPrefix(candidate, "opensuse") {
+			return PackageManagerZypper
+		}
+	}
+	return ""
+}
+
+// PackageNames returns the distro package names for p. Debian keeps the
+// "wireguard" meta-package so existing hosts probe as installed; everywhere
+// else the kernel ships the module and only the tools are packaged.
+func (pm PackageManager) PackageNames(p Package) []string {
+	switch p {
+	case PackageWireGuard:
+		if pm == PackageManagerApt {
+			return []string{"wireguard", "wireguard-tools"}
+		}
+		return []string{"wireguard-tools"}
+	case PackageBuilderTools:
+		return []string{"buildah", "git", "ca-certificates"}
+	}
+	return []string{string(p)}
+}
+
+// binary is the command whose presence identifies pm on a host.
+func (pm PackageManager) binary() string {
+	if pm == PackageManagerApt {
+		return "apt-get"
+	}
+	return string(pm)
+}
+
+// InstallCommand returns a non-interactive install of p. Debian keeps
+// modified config files (--force-confold) so a re-install never prompts.
+func (pm PackageManager) InstallCommand(p Package) string {
+	pkgs := strings.Join(pm.PackageNames(p), " ")
+	switch pm {
+	case PackageManagerApt:
+		return `DEBIAN_FRONTEND=noninteractive apt-get update -qq 2>/dev/null && ` +
+			`DEBIAN_FRONTEND=noninteractive apt-get install -y ` +
+			`-o Dpkg::Options::="--force-confold" ` + pkgs + ` 2>&1`
+	case PackageManagerDnf:
+		return `dnf install -y -q ` + pkgs + ` 2>&1`
+	case PackageManagerZypper:
+		return `zypper --non-interactive --quiet install --no-recommends ` + pkgs + ` 2>&1`
+	case PackageManagerApk:
+		return `apk add --no-cache --quiet ` + pkgs + ` 2>&1`
+	}
+	return detectOnHost(func(m PackageManager) string { return m.InstallCommand(p) },
+		`echo "no supported package manager (apt-get, dnf, zypper, apk) found" >&2; exit 1`)
+}
+
+// InstalledCommand prints 1 when the first package of p is installed and 0
+// otherwise. It never exits non-zero, so it is safe inside a probe.
+func (pm PackageManager) InstalledCommand(p Package) string {
+	pkg := pm.PackageNames(p)[0]
+	switch pm {
+	case PackageManagerApt:
+		return fmt.Sprintf(`dpkg-query -W -f='${Status}' %s 2>/dev/null | grep -c 'install ok installed' || echo 0`, pkg)
+	case PackageManagerDnf, PackageManagerZypper:
+		return fmt.Sprintf(`rpm -q %s >/dev/null 2>&1 && echo 1 || echo 0`, pkg)
+	case PackageManagerApk:
+		return fmt.Sprintf(`apk info -e %s >/dev/null 2>&1 && echo 1 || echo 0`, pkg)
+	}
+	return detectOnHost(func(m PackageManager) string { return m.InstalledCommand(p) }, `echo 0`)
+}
+
+// osReleaseFiles are read by OSReleaseCommand, in order.
+var osReleaseFiles = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// detectOnHost renders render(pm) for the manager DetectPackageManager would
+// pick from the host's os-release, so probes and installs agree on hosts
+// carrying several managers. Unknown distros fall back to the first manager
+// whose binary is present, then to otherwise.
+func detectOnHost(render func(PackageManager) string, otherwise string) string {
+	return detectFromOSRelease(osReleaseFiles, render, detectByBinary(render, otherwise))
+}
+
+// detectFromOSRelease renders a subshell that sources the first readable
+// file and dispatches on ID, then each ID_LIKE entry, using osFamilies.
+func detectFromOSRelease(files []string, render func(PackageManager) string, otherwise string) string {
+	var sb strings.Builder
+	// A failing "." exits a non-interactive shell, so test before sourcing.
+	sb.WriteString("( ID= ID_LIKE=; ")
+	for i, f := range files {
+		if i > 0 {
+			sb.WriteString("el")
+		}
+		fmt.Fprintf(&sb, "if [ -r %s ]; then . %s; ", f, f)
+	}
+	sb.WriteString(`fi; for id in $(echo "$ID $ID_LIKE" | tr 'A-Z' 'a-z'); do case "$id" in `)
+	for _, pm := range PackageManagers {
+		var ids []string
+		for id, family := range osFamilies {
+			if family == pm {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		if pm == PackageManagerZypper {
+			// openSUSE reports ID="opensuse-leap" / "opensuse-tumbleweed".
+			ids = append(ids, "opensuse*")
+		}
+		fmt.Fprintf(&sb, "%s) %s; exit;; ", strings.Join(ids, "|"), render(pm))
+	}
+	fmt.Fprintf(&sb, "esac; done; %s )", otherwise)
+	return sb.String()
+}
+
+// detectByBinary renders render(pm) for each manager behind a check for its
+// binary, falling through to otherwise.
+func detectByBinary(render func(PackageManager) string, otherwise string) string {
+	var sb strings.Builder
+	for i, pm := range PackageManagers {
+		if i > 0 {
+			sb.WriteString("el")
+		}
+		fmt.Fprintf(&sb, "if command -v %s >/dev/null 2>&1; then %s; ", pm.binary(), render(pm))
+	}
+	fmt.Fprintf(&sb, "else %s; fi", otherwise)
+	return sb.String()
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		name      string
+		osRelease string
+		want      PackageManager
+	}{
+		{"debian", "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\n", PackageManagerApt},
+		{"ubuntu", "NAME=\"Ubuntu\"\nID=ubuntu\nID_LIKE=debian\n", PackageManagerApt},
+		{"rocky", "NAME=\"Rocky Linux\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\n", PackageManagerDnf},
+		{"alma", "ID=\"almalinux\"\nID_LIKE=\"rhel centos fedora\"\n", PackageManagerDnf},
+		{"fedora", "ID=fedora\n", PackageManagerDnf},
+		{"opensuse leap", "ID=\"opensuse-leap\"\nID_LIKE=\"suse opensuse\"\n", PackageManagerZypper},
+		{"opensuse tumbleweed", "ID=\"opensuse-tumbleweed\"\n", PackageManagerZypper},
+		{"alpine", "ID=alpine\n", PackageManagerApk},
+		{"derivative via ID_LIKE", "ID=pop\nID_LIKE=\"ubuntu debian\"\n", PackageManagerApt},
+		{"unknown", "ID=arch\n", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectPackageManager(tt.osRelease); got != tt.want {
+			t.Errorf("%s: DetectPackageManager = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPackageManagerInstallCommand_PerDistro(t *testing.T) {
+	tests := []struct {
+		pm   PackageManager
+		pkg  Package
+		want []string
+	}{
+		{PackageManagerApt, PackageWireGuard, []string{
+			"DEBIAN_FRONTEND=noninteractive apt-get update -qq",
+			`apt-get install -y -o Dpkg::Options::="--force-confold" wireguard wireguard-tools`,
+		}},
+		{PackageManagerDnf, PackageWireGuard, []string{"dnf install -y -q wireguard-tools"}},
+		{PackageManagerZypper, PackagePodman, []string{"zypper --non-interactive --quiet install --no-recommends podman"}},
+		{PackageManagerApk, PackageBuilderTools, []string{"apk add --no-cache --quiet buildah git ca-certificates"}},
+	}
+	for _, tt := range tests {
+		got := tt.pm.InstallCommand(tt.pkg)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s install %s: missing %q in:\n%s", tt.pm, tt.pkg, want, got)
+			}
+		}
+		for _, other := range PackageManagers {
+			if other != tt.pm && strings.Contains(got, "command -v "+other.binary()) {
+				t.Errorf("%s install %s should not detect on the host:\n%s", tt.pm, tt.pkg, got)
+			}
+		}
+	}
+}
+
+func TestPackageManagerInstalledCommand_PerDistro(t *testing.T) {
+	tests := []struct {
+		pm   PackageManager
+		want string
+	}{
+		{PackageManagerApt, `dpkg-query -W -f='${Status}' wireguard 2>/dev/null | grep -c 'install ok installed' || echo 0`},
+		{PackageManagerDnf, `rpm -q wireguard-tools >/dev/null 2>&1 && echo 1 || echo 0`},
+		{PackageManagerZypper, `rpm -q wireguard-tools >/dev/null 2>&1 && echo 1 || echo 0`},
+		{PackageManagerApk, `apk info -e wireguard-tools >/dev/null 2>&1 && echo 1 || echo 0`},
+	}
+	for _, tt := range tests {
+		if got := tt.pm.InstalledCommand(PackageWireGuard); got != tt.want {
+			t.Errorf("%s: InstalledCommand =\n%s\nwant\n%s", tt.pm, got, tt.want)
+		}
+	}
+}
+
+func TestPackageManager_UndetectedPicksManagerOnHost(t *testing.T) {
+	var pm PackageManager
+	installed := pm.InstalledCommand(PackagePodman)
+	for _, want := range []string{
+		"if command -v apt-get >/dev/null 2>&1; then dpkg-query",
+		"elif command -v dnf >/dev/null 2>&1; then rpm -q podman",
+		"elif command -v zypper >/dev/null 2>&1; then rpm -q podman",
+		"elif command -v apk >/dev/null 2>&1; then apk info -e podman",
+		"else echo 0; fi",
+	} {
+		if !strings.Contains(installed, want) {
+			t.Errorf("missing %q in:\n%s", want, installed)
+		}
+	}
+
+	install := pm.InstallCommand(PackageWireGuard)
+	if !strings.Contains(install, "dnf install -y -q wireguard-tools") ||
+		!strings.Contains(install, "no supported package manager") {
+		t.Errorf("undetected install must try every manager and fail otherwise:\n%s", install)
+	}
+}
+
+func TestBuilderInstallCommand_UsesPackageManager(t *testing.T) {
+	cmd := BuilderInstallCommand(PackageManagerDnf, "v1.2.3")
+	if !strings.Contains(cmd, "dnf install -y -q buildah git ca-certificates") {
+		t.Errorf("builder tools not installed with dnf:\n%s", cmd)
+	}
+	if strings.Contains(cmd, "apt-get") {
+		t.Errorf("dnf host must not run apt-get:\n%s", cmd)
+	}
+	if !strings.Contains(cmd, "coold/releases/download/v1.2.3/builder-linux-${ARCH}.tar.gz") {
+		t.Errorf("release URL missing version:\n%s", cmd)
+	}
+}
+
+func TestDetectFromOSRelease_MatchesDetectPackageManager(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	render := func(pm PackageManager) string { return "echo " + string(pm) }
+	for _, osRelease := range []string{
+		"ID=ubuntu\nID_LIKE=debian\n",
+		`ID="rocky"` + "\n" + `ID_LIKE="rhel centos fedora"` + "\n",
+		"ID=opensuse-tumbleweed\n",
+		"ID=Alpine\n",
+		"ID=mystery\nID_LIKE=\"suse\"\n",
+		"ID=gentoo\n",
+	} {
+		path := filepath.Join(t.TempDir(), "os-release")
+		if err := os.WriteFile(path, []byte(osRelease), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		script := detectFromOSRelease([]string{"/nonexistent/os-release", path}, render, "echo none")
+		out, err := exec.Command("sh", "-c", script).Output()
+		if err != nil {
+			t.Fatalf("%q: %v\n%s", osRelease, err, script)
+		}
+		want := string(DetectPackageManager(osRelease))
+		if want == "" {
+			want = "none"
+		}
+		if got := strings.TrimSpace(string(out)); got != want {
+			t.Errorf("%q: picked %q, DetectPackageManager picks %q", osRelease, got, want)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package services
 
-import "fmt"
+import (
+	"fmt"
+
+	compareVersion "github.com/hashicorp/go-version"
+)
 
 // SchedulerGRPCPort is the TCP port scheduler listens on. coold dials this stream
 // and carries both coold and builder traffic on the same connection — there
@@ -13,6 +17,40 @@ const SchedulerJWTPubPath = "/etc/coolify/jwt.pub"
 // SchedulerJWTPrivPath is the on-central path for the EC private key (chmod 0600).
 const SchedulerJWTPrivPath = "/etc/coolify/jwt.priv"
 
+// SchedulerJWTPubSetPath is the on-central set of public keys the scheduler
+// accepts host JWTs from: concatenated PEM, current key first. During a key
+// rotation it also holds the previous key until no host uses it.
+const SchedulerJWTPubSetPath = "/etc/coolify/jwt.pubs"
+
+// SchedulerJWTRevokedPath is the on-central JSON list of JWTRevocation the
+// scheduler rejects host JWTs by.
+const SchedulerJWTRevokedPath = "/etc/coolify/jwt-revoked.json"
+
+// SchedulerVersionPath is where SchedulerInstallCommand records the release
+// tag it installed.
+const SchedulerVersionPath = "/usr/local/bin/scheduler.version"
+
+// MinSchedulerJWTKeySetVersion is the first scheduler release that reads
+// SCHEDULER_JWT_PUBLIC_KEYS_PATH and SCHEDULER_JWT_REVOKED_PATH. Older
+// releases only trust SCHEDULER_JWT_PUBLIC_KEY_PATH, so revocations and
+// retired keys have no effect on them.
+const MinSchedulerJWTKeySetVersion = "v0.3.0"
+
+// SchedulerSupportsJWTKeySet reports whether the scheduler release tag
+// version reads the key set and revocations. Tags that are not versions,
+// such as "nightly", follow the main branch and do; an empty tag (nothing
+// recorded) does not.
+func SchedulerSupportsJWTKeySet(version string) bool {
+	if version == "" {
+		return false
+	}
+	v, err := compareVersion.NewVersion(version)
+	if err != nil {
+		return true
+	}
+	return !v.LessThan(compareVersion.Must(compareVersion.NewVersion(MinSchedulerJWTKeySetVersion)))
+}
+
 // HostJWTPath is the on-host path where coold reads its bearer JWT.
 const HostJWTPath = "/etc/coolify/host-jwt"
 
@@ -42,13 +80,15 @@ RuntimeDirectoryMode=0750
 Environment=SCHEDULER_GRPC_BIND=%s
 Environment=SCHEDULER_UNIX_SOCKET_PATH=%s
 Environment=SCHEDULER_JWT_PUBLIC_KEY_PATH=%s
+Environment=SCHEDULER_JWT_PUBLIC_KEYS_PATH=%s
+Environment=SCHEDULER_JWT_REVOKED_PATH=%s
 ExecStart=/usr/local/bin/scheduler
 Restart=on-failure
 RestartSec=2s
 
 [Install]
 WantedBy=multi-user.target
-`, grpcBind, SchedulerUnixSocketPath, jwtPubPath)
+`, grpcBind, SchedulerUnixSocketPath, jwtPubPath, SchedulerJWTPubSetPath, SchedulerJWTRevokedPath)
 }
 
 // SchedulerInstallCommand returns a shell snippet that downloads and installs
@@ -69,11 +109,12 @@ tar -xzf "$DLDIR/scheduler.tar.gz" -C "$DLDIR"
 test -f "$DLDIR/scheduler" || { echo "scheduler binary not found in tarball" >&2; exit 1; }
 install -m 0755 "$DLDIR/scheduler" /usr/local/bin/scheduler.tmp
 mv /usr/local/bin/scheduler.tmp /usr/local/bin/scheduler
-echo '%s' > /usr/local/bin/scheduler.version`, version, version)
+echo '%s' > %s`, version, version, SchedulerVersionPath)
 }
 
 // EnsureJWTKeypairCommand returns a shell snippet that generates an EC P-256
-// keypair in PKCS8 format on the central host (idempotent).
+// keypair in PKCS8 format on the central host (idempotent), and seeds the
+// key set with its public key.
 func EnsureJWTKeypairCommand() string {
 	return `mkdir -p /etc/coolify && ` +
 		`if [ ! -f ` + SchedulerJWTPrivPath + ` ]; then ` +
@@ -82,5 +123,26 @@ func EnsureJWTKeypairCommand() string {
 		`chmod 0600 ` + SchedulerJWTPrivPath + `.tmp && ` +
 		`mv ` + SchedulerJWTPrivPath + `.tmp ` + SchedulerJWTPrivPath + ` && ` +
 		`openssl pkey -in ` + SchedulerJWTPrivPath + ` -pubout -out ` + SchedulerJWTPubPath + ` 2>&1 && ` +
-		`chmod 0644 ` + SchedulerJWTPubPath + `; fi`
+		`chmod 0644 ` + SchedulerJWTPubPath + `; fi && ` +
+		`if [ ! -f ` + SchedulerJWTPubSetPath + ` ]; then ` +
+		`cp ` + SchedulerJWTPubPath + ` ` + SchedulerJWTPubSetPath + ` && ` +
+		`chmod 0644 ` + SchedulerJWTPubSetPath + `; fi`
+}
+
+// RotateJWTKeypairCommand returns a shell snippet that replaces the central
+// keypair. The new public key is added to the key set and the scheduler
+// restarted before the private key is switched, so the scheduler accepts
+// tokens signed by either key until the old one is retired.
+func RotateJWTKeypairCommand() string {
+	return fmt.Sprintf(`set -e
+test -f %[2]s || cp %[3]s %[2]s
+openssl genpkey -algorithm EC -pkeyopt ec_paramgen_curve:P-256 -out %[1]s.next 2>&1
+chmod 0600 %[1]s.next
+openssl pkey -in %[1]s.next -pubout -out %[3]s.next 2>&1
+cat %[3]s.next %[2]s > %[2]s.tmp
+chmod 0644 %[3]s.next %[2]s.tmp
+mv %[2]s.tmp %[2]s
+systemctl try-restart scheduler
+mv %[3]s.next %[3]s
+mv %[1]s.next %[1]s`, SchedulerJWTPrivPath, SchedulerJWTPubSetPath, SchedulerJWTPubPath)
 }
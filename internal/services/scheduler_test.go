@@ -49,9 +49,25 @@ func TestSchedulerServiceUnit_ExecStartPath(t *testing.T) {
 		"SCHEDULER_UNIX_SOCKET_PATH=" + SchedulerUnixSocketPath,
 		"RuntimeDirectory=coolify",
 		SchedulerJWTPubPath,
+		"SCHEDULER_JWT_PUBLIC_KEYS_PATH=" + SchedulerJWTPubSetPath,
+		"SCHEDULER_JWT_REVOKED_PATH=" + SchedulerJWTRevokedPath,
 	} {
 		if !strings.Contains(unit, want) {
 			t.Errorf("SchedulerServiceUnit missing %q", want)
 		}
 	}
 }
+
+func TestSchedulerSupportsJWTKeySet(t *testing.T) {
+	for version, want := range map[string]bool{
+		MinSchedulerJWTKeySetVersion: true,
+		"v1.0.0":                     true,
+		"nightly":                    true,
+		"v0.2.9":                     false,
+		"":                           false,
+	} {
+		if got := SchedulerSupportsJWTKeySet(version); got != want {
+			t.Errorf("SchedulerSupportsJWTKeySet(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
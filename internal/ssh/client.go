@@ -5,13 +5,15 @@ package ssh
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
-	"strconv"
+	"sync"
 	"time"
 
 	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 // Runner executes a shell command on a remote host and returns its
@@ -27,30 +29,100 @@ type FileUploader interface {
 	UploadFile(ctx context.Context, host, user string, port int, localPath, remotePath string, mode os.FileMode) error
 }
 
+// Dialer opens a connection to addr from host's side of the SSH link, like
+// `ssh -L`. Used to reach services that only listen on the host's private
+// interfaces. Kept separate from Runner so existing Runner mocks stay
+// valid.
+type Dialer interface {
+	Dial(ctx context.Context, host, user string, port int, network, addr string) (net.Conn, error)
+}
+
 // Client implements Runner using the golang.org/x/crypto/ssh library.
-// Keys must be unencrypted PEM files.
-// NOTE: host-key verification is intentionally disabled in v1 (alpha).
-// This is acceptable for a bootstrap tool in controlled environments
-// and should be improved in a future release.
+// Connections are pooled per user@host:port and reused across Run and
+// UploadFile calls until Close. Host keys are verified against
+// ~/.ssh/known_hosts unless a different HostKeyVerifier is supplied.
 type Client struct {
-	signer  gossh.Signer
-	timeout time.Duration
+	signer     gossh.Signer
+	passphrase []byte
+	timeout    time.Duration
+	hostKeys   *HostKeyVerifier
+
+	agentSock  string
+	sshConfig  *Config
+	configUser bool
+	configPort bool
+	jumps      []string
+
+	mu         sync.Mutex
+	conns      map[string]*pooledConn
+	identities map[string]gossh.Signer
+	agentConn  net.Conn
+	agent      agent.ExtendedAgent
+}
+
+// ClientOption customises a Client.
+type ClientOption func(*Client)
+
+// WithHostKeyVerifier sets how server host keys are verified.
+func WithHostKeyVerifier(v *HostKeyVerifier) ClientOption {
+	return func(c *Client) {
+		c.hostKeys = v
+	}
+}
+
+// WithAgent offers the keys held by the ssh-agent listening on sock
+// (usually $SSH_AUTH_SOCK). An empty sock disables the agent.
+func WithAgent(sock string) ClientOption {
+	return func(c *Client) {
+		c.agentSock = sock
+	}
+}
+
+// WithSSHConfig applies HostName, IdentityFile and ProxyJump from cfg.
+// User and Port from cfg only replace the values passed to Run when
+// useUser / usePort are set, i.e. when the user did not pass them explicitly.
+func WithSSHConfig(cfg *Config, useUser, usePort bool) ClientOption {
+	return func(c *Client) {
+		c.sshConfig = cfg
+		c.configUser = useUser
+		c.configPort = usePort
+	}
+}
+
+// WithJumpHosts tunnels every connection through the given
+// "[user@]host[:port]" hops, overriding ProxyJump from the SSH config.
+func WithJumpHosts(hops []string) ClientOption {
+	return func(c *Client) {
+		c.jumps = hops
+	}
 }
 
 // NewClient loads the private key at keyPath and returns a Client ready to
 // SSH into hosts.  If passphrase is non-nil it is used to decrypt the key;
-// pass nil for unencrypted keys.
-func NewClient(keyPath string, passphrase []byte, timeout time.Duration) (*Client, error) {
+// pass nil for unencrypted keys.  keyPath may be empty when keys come from
+// ssh-agent or IdentityFile entries instead.
+func NewClient(keyPath string, passphrase []byte, timeout time.Duration, opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		passphrase: passphrase,
+		timeout:    timeout,
+		hostKeys:   NewHostKeyVerifier(HostKeyStrict, "", DefaultKnownHostsFiles()...),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if keyPath == "" {
+		return c, nil
+	}
+
 	raw, err := os.ReadFile(keyPath)
 	if err != nil {
 		return nil, fmt.Errorf("read SSH key %q: %w", keyPath, err)
 	}
 
-	var signer gossh.Signer
 	if len(passphrase) > 0 {
-		signer, err = gossh.ParsePrivateKeyWithPassphrase(raw, passphrase)
+		c.signer, err = gossh.ParsePrivateKeyWithPassphrase(raw, passphrase)
 	} else {
-		signer, err = gossh.ParsePrivateKey(raw)
+		c.signer, err = gossh.ParsePrivateKey(raw)
 	}
 	if err != nil {
 		// Give the user an actionable hint when the key is passphrase-protected.
@@ -59,11 +131,7 @@ func NewClient(keyPath string, passphrase []byte, timeout time.Duration) (*Clien
 		}
 		return nil, fmt.Errorf("parse SSH key %q: %w", keyPath, err)
 	}
-
-	return &Client{
-		signer:  signer,
-		timeout: timeout,
-	}, nil
+	return c, nil
 }
 
 // isPassphraseError returns true when err is the "passphrase protected" error
@@ -88,49 +156,15 @@ func contains(s, sub string) bool {
 		}()
 }
 
-// dial opens an SSH connection to host:port as user and returns it.  Caller
-// owns Close().  Shared by Run and UploadFile so host-key/timeout behaviour
-// stays identical across commands and file transfers.
-func (c *Client) dial(ctx context.Context, host, user string, port int) (*gossh.Client, error) {
-	cfg := &gossh.ClientConfig{
-		User:            user,
-		Auth:            []gossh.AuthMethod{gossh.PublicKeys(c.signer)},
-		HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec // alpha v1, documented limitation
-		Timeout:         c.timeout,
-	}
-
-	addr := net.JoinHostPort(host, strconv.Itoa(port))
-
-	dialer := &net.Dialer{Timeout: c.timeout}
-	netConn, err := dialer.DialContext(ctx, "tcp", addr)
-	if err != nil {
-		return nil, fmt.Errorf("dial %s: %w", addr, err)
-	}
-
-	sshConn, chans, reqs, err := gossh.NewClientConn(netConn, addr, cfg)
-	if err != nil {
-		_ = netConn.Close()
-		return nil, fmt.Errorf("SSH handshake %s: %w", addr, err)
-	}
-	return gossh.NewClient(sshConn, chans, reqs), nil
-}
-
-// Run connects to host:port over SSH as user, executes cmd, and returns
-// the combined stdout, stderr, and any error.  The connection is
-// closed when the command finishes or ctx is cancelled.
+// Run executes cmd on host:port over SSH as user and returns the
+// combined stdout, stderr, and any error.  The session is closed when the
+// command finishes or ctx is cancelled; the connection stays pooled for
+// other sessions either way.
 func (c *Client) Run(ctx context.Context, host, user string, port int, cmd string) (string, string, error) {
-	conn, err := c.dial(ctx, host, user, port)
+	sess, addr, evict, err := c.session(ctx, host, user, port)
 	if err != nil {
 		return "", "", err
 	}
-	defer conn.Close()
-
-	addr := net.JoinHostPort(host, strconv.Itoa(port))
-
-	sess, err := conn.NewSession()
-	if err != nil {
-		return "", "", fmt.Errorf("SSH new session on %s: %w", addr, err)
-	}
 	defer sess.Close()
 
 	var stdout, stderr bytes.Buffer
@@ -146,14 +180,38 @@ func (c *Client) Run(ctx context.Context, host, user string, port int, cmd strin
 
 	select {
 	case <-ctx.Done():
-		// Best-effort signal; ignore error since we're already cancelled.
-		_ = sess.Signal(gossh.SIGTERM)
+		// Once Wait has returned the output buffers are no longer written to.
+		c.cancelSession(sess, waitDone, evict)
 		return stdout.String(), stderr.String(), ctx.Err()
 	case runErr := <-waitDone:
 		return stdout.String(), stderr.String(), runErr
 	}
 }
 
+// Dial opens a direct-tcpip channel to addr over the pooled connection to
+// host. sshd must allow TCP forwarding (the OpenSSH default). ctx bounds
+// the dial only; close the returned conn to end the channel.
+func (c *Client) Dial(ctx context.Context, host, user string, port int, network, addr string) (net.Conn, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := c.conn(ctx, host, user, port)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := client.DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		// A rejected channel (forwarding disabled, nothing listening) means
+		// the connection itself is fine; anything else may be a stale pooled
+		// connection, so redial once.
+		var openErr *gossh.OpenChannelError
+		if errors.As(err, &openErr) || ctx.Err() != nil || attempt > 0 {
+			return nil, fmt.Errorf("SSH dial %s via %s: %w", addr, host, err)
+		}
+		c.drop(host, user, port, client)
+	}
+}
+
 // uploadShellCmd returns the remote command that atomically writes stdin
 // to remotePath with the given mode.  Exposed as a function so it can be
 // unit-tested without opening an SSH connection.
@@ -174,18 +232,10 @@ func (c *Client) UploadFile(ctx context.Context, host, user string, port int, lo
 	}
 	defer f.Close()
 
-	conn, err := c.dial(ctx, host, user, port)
+	sess, addr, evict, err := c.session(ctx, host, user, port)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-
-	addr := net.JoinHostPort(host, strconv.Itoa(port))
-
-	sess, err := conn.NewSession()
-	if err != nil {
-		return fmt.Errorf("SSH new session on %s: %w", addr, err)
-	}
 	defer sess.Close()
 
 	var stderr bytes.Buffer
@@ -201,7 +251,7 @@ func (c *Client) UploadFile(ctx context.Context, host, user string, port int, lo
 
 	select {
 	case <-ctx.Done():
-		_ = sess.Signal(gossh.SIGTERM)
+		c.cancelSession(sess, waitDone, evict)
 		return ctx.Err()
 	case runErr := <-waitDone:
 		if runErr != nil {
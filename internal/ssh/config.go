@@ -0,0 +1,277 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HostConfig is the subset of OpenSSH client settings the mesh commands
+// honour for a single host.
+type HostConfig struct {
+	HostName      string
+	User          string
+	Port          int
+	IdentityFiles []string
+	// ProxyJump is the raw comma-separated jump chain, or "none".
+	ProxyJump string
+}
+
+// Config is a parsed ~/.ssh/config. Only Host blocks and the HostName,
+// User, Port, IdentityFile and ProxyJump keywords are interpreted; Match
+// blocks and Include directives are skipped.
+type Config struct {
+	blocks []configBlock
+}
+
+type configBlock struct {
+	patterns []string
+	params   [][2]string
+}
+
+// DefaultConfigFile returns ~/.ssh/config.
+func DefaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "config")
+}
+
+// LoadConfig parses the OpenSSH client config at path. A missing file
+// yields an empty Config.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open SSH config: %w", err)
+	}
+	defer f.Close()
+
+	cfg, err := ParseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse SSH config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses OpenSSH client config syntax from r.
+func ParseConfig(r io.Reader) (*Config, error) {
+	cfg := &Config{}
+	// Settings before the first Host line apply to every host.
+	current := &configBlock{patterns: []string{"*"}}
+	skipping := false
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := splitConfigLine(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing value", lineNum)
+		}
+
+		switch strings.ToLower(key) {
+		case "host":
+			cfg.blocks = append(cfg.blocks, *current)
+			current = &configBlock{patterns: strings.Fields(value)}
+			skipping = false
+		case "match":
+			cfg.blocks = append(cfg.blocks, *current)
+			current = &configBlock{}
+			skipping = true
+		default:
+			if !skipping {
+				current.params = append(current.params, [2]string{strings.ToLower(key), value})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	cfg.blocks = append(cfg.blocks, *current)
+	return cfg, nil
+}
+
+// splitConfigLine splits "Key value" or "Key=value" and strips quotes.
+func splitConfigLine(line string) (key, value string, ok bool) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return "", "", false
+	}
+	key = line[:i]
+	value = strings.TrimLeft(line[i:], " \t")
+	value = strings.TrimPrefix(value, "=")
+	value = strings.TrimSpace(value)
+	value = strings.Trim(value, `"`)
+	return key, value, value != ""
+}
+
+// Lookup returns the settings for host. As in OpenSSH the first value
+// obtained for each keyword wins, except IdentityFile which accumulates.
+func (c *Config) Lookup(host string) HostConfig {
+	var hc HostConfig
+	if c == nil {
+		return hc
+	}
+	for _, b := range c.blocks {
+		if !matchHostPatterns(b.patterns, host) {
+			continue
+		}
+		for _, p := range b.params {
+			switch p[0] {
+			case "hostname":
+				if hc.HostName == "" {
+					hc.HostName = strings.ReplaceAll(p[1], "%h", host)
+				}
+			case "user":
+				if hc.User == "" {
+					hc.User = p[1]
+				}
+			case "port":
+				if hc.Port == 0 {
+					if n, err := strconv.Atoi(p[1]); err == nil {
+						hc.Port = n
+					}
+				}
+			case "identityfile":
+				hc.IdentityFiles = append(hc.IdentityFiles, expandHome(strings.ReplaceAll(p[1], "%h", host)))
+			case "proxyjump":
+				if hc.ProxyJump == "" {
+					hc.ProxyJump = p[1]
+				}
+			}
+		}
+	}
+	return hc
+}
+
+// matchHostPatterns applies OpenSSH Host semantics: any positive match and
+// no negated (!pattern) match.
+func matchHostPatterns(patterns []string, host string) bool {
+	matched := false
+	for _, p := range patterns {
+		if neg, ok := strings.CutPrefix(p, "!"); ok {
+			if wildcardMatch(neg, host) {
+				return false
+			}
+			continue
+		}
+		if wildcardMatch(p, host) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// wildcardMatch matches s against a pattern with * and ? wildcards.
+func wildcardMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = pattern[1:]
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if wildcardMatch(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return s == ""
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// JumpHost is one hop of a ProxyJump / --ssh-jump chain.
+type JumpHost struct {
+	User string
+	Host string
+	Port int
+}
+
+// ParseJumpHosts parses a comma-separated "[user@]host[:port]" chain.
+// "none" and "" yield no hops.
+func ParseJumpHosts(spec string) ([]JumpHost, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "none") {
+		return nil, nil
+	}
+	var hops []JumpHost
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "ssh://")
+		if part == "" {
+			continue
+		}
+		var hop JumpHost
+		if at := strings.LastIndex(part, "@"); at >= 0 {
+			hop.User, part = part[:at], part[at+1:]
+		}
+		hop.Host = part
+		if h, p, ok := splitHostPort(part); ok {
+			hop.Host = h
+			if p != "" {
+				port, err := strconv.Atoi(p)
+				if err != nil || port <= 0 || port > 65535 {
+					return nil, fmt.Errorf("invalid jump host port in %q", part)
+				}
+				hop.Port = port
+			}
+		}
+		if hop.Host == "" {
+			return nil, fmt.Errorf("invalid jump host %q", part)
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// splitHostPort accepts host:port and [v6]:port; bare IPv6 literals have no
+// port.
+func splitHostPort(s string) (host, port string, ok bool) {
+	if strings.HasPrefix(s, "[") {
+		end := strings.Index(s, "]")
+		if end < 0 {
+			return "", "", false
+		}
+		if rest := s[end+1:]; strings.HasPrefix(rest, ":") {
+			return s[1:end], rest[1:], true
+		}
+		return s[1:end], "", true
+	}
+	if strings.Count(s, ":") != 1 {
+		return "", "", false
+	}
+	i := strings.Index(s, ":")
+	return s[:i], s[i+1:], true
+}
@@ -0,0 +1,100 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `
+# global defaults
+IdentityFile ~/.ssh/id_global
+
+Host bastion
+  HostName 203.0.113.10
+  User jump
+  Port 2222
+
+Host web-* !web-legacy
+  HostName %h.internal
+  User deploy
+  ProxyJump bastion
+  IdentityFile=/keys/web
+
+Host *
+  User fallback
+  Port 22
+
+Match host foo
+  User ignored
+`
+
+func TestConfigLookup(t *testing.T) {
+	cfg, err := ParseConfig(strings.NewReader(sampleConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, _ := os.UserHomeDir()
+
+	web := cfg.Lookup("web-1")
+	want := HostConfig{
+		HostName:      "web-1.internal",
+		User:          "deploy",
+		Port:          22,
+		IdentityFiles: []string{filepath.Join(home, ".ssh/id_global"), "/keys/web"},
+		ProxyJump:     "bastion",
+	}
+	if !reflect.DeepEqual(web, want) {
+		t.Errorf("Lookup(web-1) = %+v, want %+v", web, want)
+	}
+
+	legacy := cfg.Lookup("web-legacy")
+	if legacy.User != "fallback" || legacy.ProxyJump != "" || legacy.HostName != "" {
+		t.Errorf("negated pattern should not match: %+v", legacy)
+	}
+
+	bastion := cfg.Lookup("bastion")
+	if bastion.HostName != "203.0.113.10" || bastion.User != "jump" || bastion.Port != 2222 {
+		t.Errorf("Lookup(bastion) = %+v", bastion)
+	}
+
+	var nilCfg *Config
+	if got := nilCfg.Lookup("x"); !reflect.DeepEqual(got, HostConfig{}) {
+		t.Errorf("nil config lookup = %+v", got)
+	}
+}
+
+func TestLoadConfig_Missing(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "nope"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Lookup("h"); got.HostName != "" {
+		t.Errorf("expected empty config, got %+v", got)
+	}
+}
+
+func TestParseJumpHosts(t *testing.T) {
+	got, err := ParseJumpHosts("ops@bastion:2222, 10.0.0.5,[2001:db8::1]:22,[2001:db8::2]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []JumpHost{
+		{User: "ops", Host: "bastion", Port: 2222},
+		{Host: "10.0.0.5"},
+		{Host: "2001:db8::1", Port: 22},
+		{Host: "2001:db8::2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseJumpHosts = %+v, want %+v", got, want)
+	}
+
+	if hops, _ := ParseJumpHosts("none"); hops != nil {
+		t.Errorf("none should disable jumps, got %+v", hops)
+	}
+	if _, err := ParseJumpHosts("bastion:notaport"); err == nil {
+		t.Error("expected error for invalid port")
+	}
+}
@@ -0,0 +1,317 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// endpoint is one resolved SSH hop.
+type endpoint struct {
+	host          string
+	user          string
+	port          int
+	identityFiles []string
+}
+
+func (e endpoint) addr() string {
+	return net.JoinHostPort(e.host, strconv.Itoa(e.port))
+}
+
+// pooledConn is a cached connection plus the jump-host connections it is
+// tunnelled through, outermost first.
+type pooledConn struct {
+	client *gossh.Client
+	jumps  []*gossh.Client
+}
+
+func (p *pooledConn) close() {
+	_ = p.client.Close()
+	p.closeJumps()
+}
+
+// resolve applies ~/.ssh/config to host and computes the jump chain.
+// --ssh-jump wins over ProxyJump from the config file.
+func (c *Client) resolve(host, user string, port int) (endpoint, []endpoint, error) {
+	target := c.applyConfig(host, user, port, c.configUser, c.configPort)
+
+	spec := strings.Join(c.jumps, ",")
+	if spec == "" {
+		spec = c.sshConfig.Lookup(host).ProxyJump
+	}
+	hops, err := ParseJumpHosts(spec)
+	if err != nil {
+		return endpoint{}, nil, err
+	}
+
+	jumps := make([]endpoint, 0, len(hops))
+	for _, hop := range hops {
+		hopUser, hopPort := hop.User, hop.Port
+		if hopPort == 0 {
+			hopPort = 22
+		}
+		// Settings spelled out in the jump spec win; otherwise the config
+		// entry for the jump host applies, falling back to the mesh user.
+		j := c.applyConfig(hop.Host, hopUser, hopPort, hop.User == "", hop.Port == 0)
+		if j.user == "" {
+			j.user = user
+		}
+		jumps = append(jumps, j)
+	}
+	return target, jumps, nil
+}
+
+func (c *Client) applyConfig(host, user string, port int, useUser, usePort bool) endpoint {
+	hc := c.sshConfig.Lookup(host)
+	e := endpoint{host: host, user: user, port: port, identityFiles: hc.IdentityFiles}
+	if hc.HostName != "" {
+		e.host = hc.HostName
+	}
+	if useUser && hc.User != "" {
+		e.user = hc.User
+	}
+	if usePort && hc.Port != 0 {
+		e.port = hc.Port
+	}
+	return e
+}
+
+// conn returns a pooled connection for user@host:port, dialling (through
+// any jump hosts) on first use.
+func (c *Client) conn(ctx context.Context, host, user string, port int) (*gossh.Client, error) {
+	key := fmt.Sprintf("%s@%s", user, net.JoinHostPort(host, strconv.Itoa(port)))
+
+	c.mu.Lock()
+	if p, ok := c.conns[key]; ok {
+		c.mu.Unlock()
+		return p.client, nil
+	}
+	c.mu.Unlock()
+
+	p, err := c.dialChain(ctx, host, user, port)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.conns[key]; ok {
+		// Another goroutine won the race; keep its connection.
+		p.close()
+		return existing.client, nil
+	}
+	if c.conns == nil {
+		c.conns = map[string]*pooledConn{}
+	}
+	c.conns[key] = p
+	return p.client, nil
+}
+
+// drop closes and forgets a pooled connection that turned out to be dead.
+func (c *Client) drop(host, user string, port int, client *gossh.Client) {
+	key := fmt.Sprintf("%s@%s", user, net.JoinHostPort(host, strconv.Itoa(port)))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.conns[key]; ok && p.client == client {
+		p.close()
+		delete(c.conns, key)
+	}
+}
+
+// session opens a session on the pooled connection, redialling once when
+// the cached connection has gone away. Other sessions may share the
+// connection, so a refused channel (e.g. sshd's MaxSessions) leaves it
+// pooled; only a transport failure drops it. The returned evict func closes
+// the connection for a session whose server stopped answering.
+func (c *Client) session(ctx context.Context, host, user string, port int) (*gossh.Session, string, func(), error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	for attempt := 0; ; attempt++ {
+		client, err := c.conn(ctx, host, user, port)
+		if err != nil {
+			return nil, addr, nil, err
+		}
+		sess, err := client.NewSession()
+		if err == nil {
+			return sess, addr, func() { c.drop(host, user, port, client) }, nil
+		}
+		var openErr *gossh.OpenChannelError
+		if errors.As(err, &openErr) {
+			return nil, addr, nil, fmt.Errorf("SSH new session on %s: %w", addr, err)
+		}
+		c.drop(host, user, port, client)
+		if attempt > 0 {
+			return nil, addr, nil, fmt.Errorf("SSH new session on %s: %w", addr, err)
+		}
+	}
+}
+
+// cancelSession stops a session whose ctx was cancelled. Closing just the
+// session unblocks Wait and leaves concurrent sessions on the pooled
+// connection running; the connection is evicted only when the server does
+// not acknowledge the close within the client timeout.
+func (c *Client) cancelSession(sess *gossh.Session, waitDone <-chan error, evict func()) {
+	// Best-effort signal; ignore error since we're already cancelled.
+	_ = sess.Signal(gossh.SIGTERM)
+	_ = sess.Close()
+	select {
+	case <-waitDone:
+	case <-time.After(c.timeout):
+		evict()
+		<-waitDone
+	}
+}
+
+// dialChain connects to the target, hopping through the resolved jump hosts.
+func (c *Client) dialChain(ctx context.Context, host, user string, port int) (*pooledConn, error) {
+	target, jumps, err := c.resolve(host, user, port)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pooledConn{}
+	var prev *gossh.Client
+	hops := append(append([]endpoint{}, jumps...), target)
+	for i, hop := range hops {
+		addr := hop.addr()
+
+		var netConn net.Conn
+		if prev == nil {
+			dialer := &net.Dialer{Timeout: c.timeout}
+			netConn, err = dialer.DialContext(ctx, "tcp", addr)
+		} else {
+			netConn, err = prev.DialContext(ctx, "tcp", addr)
+		}
+		if err != nil {
+			p.closeJumps()
+			if i > 0 {
+				return nil, fmt.Errorf("dial %s via jump host %s: %w", addr, jumps[i-1].addr(), err)
+			}
+			return nil, fmt.Errorf("dial %s: %w", addr, err)
+		}
+
+		cfg := &gossh.ClientConfig{
+			User:              hop.user,
+			Auth:              []gossh.AuthMethod{c.authMethod(hop.identityFiles)},
+			HostKeyCallback:   c.hostKeys.Callback(),
+			HostKeyAlgorithms: c.hostKeys.HostKeyAlgorithms(addr),
+			Timeout:           c.timeout,
+		}
+		sshConn, chans, reqs, err := gossh.NewClientConn(netConn, addr, cfg)
+		if err != nil {
+			_ = netConn.Close()
+			p.closeJumps()
+			if i < len(jumps) {
+				return nil, fmt.Errorf("SSH handshake with jump host %s: %w", addr, err)
+			}
+			return nil, fmt.Errorf("SSH handshake %s: %w", addr, err)
+		}
+		prev = gossh.NewClient(sshConn, chans, reqs)
+		if i < len(jumps) {
+			p.jumps = append(p.jumps, prev)
+		}
+	}
+	p.client = prev
+	return p, nil
+}
+
+func (p *pooledConn) closeJumps() {
+	for i := len(p.jumps) - 1; i >= 0; i-- {
+		_ = p.jumps[i].Close()
+	}
+}
+
+// authMethod offers, in order, the --ssh-key signer, the IdentityFile keys
+// from ~/.ssh/config and every key held by ssh-agent. They are combined in
+// one method because the SSH client only tries each method type once.
+func (c *Client) authMethod(identityFiles []string) gossh.AuthMethod {
+	return gossh.PublicKeysCallback(func() ([]gossh.Signer, error) {
+		var signers []gossh.Signer
+		if c.signer != nil {
+			signers = append(signers, c.signer)
+		}
+		for _, path := range identityFiles {
+			if s := c.identitySigner(path); s != nil {
+				signers = append(signers, s)
+			}
+		}
+		if a := c.agentClient(); a != nil {
+			if agentSigners, err := a.Signers(); err == nil {
+				signers = append(signers, agentSigners...)
+			}
+		}
+		if len(signers) == 0 {
+			return nil, fmt.Errorf("no SSH keys available: pass --ssh-key or load a key into ssh-agent")
+		}
+		return signers, nil
+	})
+}
+
+// identitySigner loads an IdentityFile once. Keys that cannot be used
+// (missing, passphrase-protected without a passphrase) are skipped so
+// ssh-agent can still provide them.
+func (c *Client) identitySigner(path string) gossh.Signer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.identities[path]; ok {
+		return s
+	}
+	var signer gossh.Signer
+	if raw, err := os.ReadFile(path); err == nil {
+		if len(c.passphrase) > 0 {
+			signer, err = gossh.ParsePrivateKeyWithPassphrase(raw, c.passphrase)
+		} else {
+			signer, err = gossh.ParsePrivateKey(raw)
+		}
+		if err != nil {
+			signer = nil
+		}
+	}
+	if c.identities == nil {
+		c.identities = map[string]gossh.Signer{}
+	}
+	c.identities[path] = signer
+	return signer
+}
+
+// agentClient lazily connects to ssh-agent and keeps the connection open
+// for the lifetime of the Client.
+func (c *Client) agentClient() agent.ExtendedAgent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.agentSock == "" {
+		return nil
+	}
+	if c.agent == nil {
+		conn, err := net.Dial("unix", c.agentSock)
+		if err != nil {
+			c.agentSock = ""
+			return nil
+		}
+		c.agentConn = conn
+		c.agent = agent.NewClient(conn)
+	}
+	return c.agent
+}
+
+// Close closes every pooled connection and the ssh-agent connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, p := range c.conns {
+		p.close()
+		delete(c.conns, key)
+	}
+	if c.agentConn != nil {
+		_ = c.agentConn.Close()
+		c.agentConn, c.agent = nil, nil
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// testServer is a minimal SSH server that answers exec requests with
+// "<name>:<command>" (except "hang", which never returns, and "slow", which
+// answers after 300ms) and forwards direct-tcpip channels, so it can act as
+// both a target and a jump host.
+type testServer struct {
+	name  string
+	port  int
+	conns atomic.Int32
+}
+
+func startTestServer(t *testing.T, name string, authorized gossh.PublicKey) *testServer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := gossh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &gossh.ServerConfig{
+		PublicKeyCallback: func(_ gossh.ConnMetadata, key gossh.PublicKey) (*gossh.Permissions, error) {
+			if string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unauthorized")
+		},
+	}
+	cfg.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &testServer{name: name, port: ln.Addr().(*net.TCPAddr).Port}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.conns.Add(1)
+			go s.serve(nc, cfg)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) serve(nc net.Conn, cfg *gossh.ServerConfig) {
+	_, chans, reqs, err := gossh.NewServerConn(nc, cfg)
+	if err != nil {
+		return
+	}
+	go gossh.DiscardRequests(reqs)
+	for nch := range chans {
+		switch nch.ChannelType() {
+		case "session":
+			ch, chReqs, err := nch.Accept()
+			if err != nil {
+				continue
+			}
+			go func() {
+				for req := range chReqs {
+					if req.Type != "exec" {
+						_ = req.Reply(false, nil)
+						continue
+					}
+					var payload struct{ Command string }
+					_ = gossh.Unmarshal(req.Payload, &payload)
+					_ = req.Reply(true, nil)
+					if payload.Command == "hang" {
+						continue
+					}
+					if payload.Command == "slow" {
+						time.Sleep(300 * time.Millisecond)
+					}
+					_, _ = fmt.Fprintf(ch, "%s:%s", s.name, payload.Command)
+					_, _ = ch.SendRequest("exit-status", false, gossh.Marshal(struct{ Status uint32 }{0}))
+					_ = ch.Close()
+				}
+			}()
+		case "direct-tcpip":
+			var payload struct {
+				Host     string
+				Port     uint32
+				OrigHost string
+				OrigPort uint32
+			}
+			_ = gossh.Unmarshal(nch.ExtraData(), &payload)
+			upstream, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+			if err != nil {
+				_ = nch.Reject(gossh.ConnectionFailed, err.Error())
+				continue
+			}
+			ch, chReqs, err := nch.Accept()
+			if err != nil {
+				_ = upstream.Close()
+				continue
+			}
+			go gossh.DiscardRequests(chReqs)
+			go func() {
+				_, _ = io.Copy(ch, upstream)
+				_ = ch.Close()
+			}()
+			go func() {
+				_, _ = io.Copy(upstream, ch)
+				_ = upstream.Close()
+			}()
+		default:
+			_ = nch.Reject(gossh.UnknownChannelType, "unsupported")
+		}
+	}
+}
+
+// startTestAgent serves an in-memory ssh-agent holding one key and returns
+// its socket path and public key.
+func startTestAgent(t *testing.T) (string, gossh.PublicKey) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	signer, _ := gossh.NewSignerFromKey(priv)
+
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _ = agent.ServeAgent(keyring, c) }()
+		}
+	}()
+	return sock, signer.PublicKey()
+}
+
+func TestClient_AgentJumpAndReuse(t *testing.T) {
+	sock, pub := startTestAgent(t)
+	bastion := startTestServer(t, "bastion", pub)
+	target := startTestServer(t, "target", pub)
+
+	verifier := NewHostKeyVerifier(HostKeyAcceptNew, filepath.Join(t.TempDir(), "known_hosts"))
+	c, err := NewClient("", nil, 5*time.Second,
+		WithHostKeyVerifier(verifier),
+		WithAgent(sock),
+		WithJumpHosts([]string{"ops@127.0.0.1:" + strconv.Itoa(bastion.port)}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		out, _, err := c.Run(ctx, "127.0.0.1", "root", target.port, "wg show")
+		if err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		if out != "target:wg show" {
+			t.Fatalf("run %d: stdout = %q", i, out)
+		}
+	}
+
+	if n := bastion.conns.Load(); n != 1 {
+		t.Errorf("bastion connections = %d, want 1 (connection reuse)", n)
+	}
+	if n := target.conns.Load(); n != 1 {
+		t.Errorf("target connections = %d, want 1 (connection reuse)", n)
+	}
+
+	// A closed pool redials transparently.
+	_ = c.Close()
+	if _, _, err := c.Run(ctx, "127.0.0.1", "root", target.port, "true"); err != nil {
+		t.Fatalf("run after Close: %v", err)
+	}
+	if n := target.conns.Load(); n != 2 {
+		t.Errorf("target connections after Close = %d, want 2", n)
+	}
+}
+
+func TestClient_SSHConfigProxyJump(t *testing.T) {
+	sock, pub := startTestAgent(t)
+	bastion := startTestServer(t, "bastion", pub)
+	target := startTestServer(t, "target", pub)
+
+	cfg, err := ParseConfig(strings.NewReader(fmt.Sprintf(`
+Host gw
+  HostName 127.0.0.1
+  Port %d
+Host app
+  HostName 127.0.0.1
+  Port %d
+  ProxyJump gw
+`, bastion.port, target.port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient("", nil, 5*time.Second,
+		WithHostKeyVerifier(NewHostKeyVerifier(HostKeyAcceptNew, filepath.Join(t.TempDir(), "known_hosts"))),
+		WithAgent(sock),
+		WithSSHConfig(cfg, true, true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	out, _, err := c.Run(context.Background(), "app", "root", 22, "hostname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "target:hostname" {
+		t.Errorf("stdout = %q", out)
+	}
+	if bastion.conns.Load() != 1 {
+		t.Errorf("expected the connection to go through the ProxyJump host")
+	}
+}
+
+func TestClient_NoKeys(t *testing.T) {
+	_, pub := startTestAgent(t)
+	target := startTestServer(t, "target", pub)
+
+	c, err := NewClient("", nil, 5*time.Second,
+		WithHostKeyVerifier(NewHostKeyVerifier(HostKeyOff, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, _, err := c.Run(context.Background(), "127.0.0.1", "root", target.port, "true"); err == nil {
+		t.Fatal("expected authentication to fail without keys")
+	}
+}
+
+func TestClient_CancelKeepsSharedConnection(t *testing.T) {
+	sock, pub := startTestAgent(t)
+	target := startTestServer(t, "target", pub)
+
+	c, err := NewClient("", nil, 5*time.Second,
+		WithHostKeyVerifier(NewHostKeyVerifier(HostKeyOff, "")),
+		WithAgent(sock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Dial first so both sessions share one pooled connection.
+	if _, _, err := c.Run(context.Background(), "127.0.0.1", "root", target.port, "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		out string
+		err error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		out, _, err := c.Run(context.Background(), "127.0.0.1", "root", target.port, "slow")
+		slow <- result{out, err}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.Run(ctx, "127.0.0.1", "root", target.port, "hang"); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if r := <-slow; r.err != nil || r.out != "target:slow" {
+		t.Fatalf("concurrent session on the shared connection: %q %v", r.out, r.err)
+	}
+
+	out, _, err := c.Run(context.Background(), "127.0.0.1", "root", target.port, "uptime")
+	if err != nil || out != "target:uptime" {
+		t.Fatalf("run after cancel: %q %v", out, err)
+	}
+	if n := target.conns.Load(); n != 1 {
+		t.Errorf("connections = %d, want 1 (a cancelled session keeps the connection)", n)
+	}
+}
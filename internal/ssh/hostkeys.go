@@ -0,0 +1,220 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyPolicy mirrors OpenSSH's StrictHostKeyChecking option.
+type HostKeyPolicy string
+
+const (
+	// HostKeyStrict only accepts hosts whose key is already in a known_hosts
+	// file.
+	HostKeyStrict HostKeyPolicy = "yes"
+	// HostKeyAcceptNew pins the key of previously unknown hosts in the
+	// managed known_hosts file (trust on first use) and rejects changed keys.
+	HostKeyAcceptNew HostKeyPolicy = "accept-new"
+	// HostKeyOff disables verification entirely.
+	HostKeyOff HostKeyPolicy = "no"
+)
+
+// ParseHostKeyPolicy validates a --ssh-strict-host-key-checking value.
+func ParseHostKeyPolicy(s string) (HostKeyPolicy, error) {
+	switch p := HostKeyPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case HostKeyStrict, HostKeyAcceptNew, HostKeyOff:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid host key checking mode %q (use yes, accept-new or no)", s)
+	}
+}
+
+// UnknownHostKeyError is returned in strict mode when a host has no entry in
+// any known_hosts file.
+type UnknownHostKeyError struct {
+	Host        string
+	Fingerprint string
+	KeyType     string
+}
+
+func (e *UnknownHostKeyError) Error() string {
+	return fmt.Sprintf("host key for %s is not known (%s %s); verify the fingerprint and add it to known_hosts "+
+		"(e.g. ssh-keyscan), or pass --ssh-strict-host-key-checking=accept-new to pin it on first use",
+		e.Host, e.KeyType, e.Fingerprint)
+}
+
+// HostKeyMismatchError is returned when a host presents a key that differs
+// from every key recorded for it.
+type HostKeyMismatchError struct {
+	Host        string
+	Fingerprint string
+	KeyType     string
+	// Known lists the recorded keys as "file:line fingerprint".
+	Known []string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("HOST KEY MISMATCH for %s: server presented %s %s but known_hosts has %s. "+
+		"This could mean the server was reinstalled or that someone is intercepting the connection; "+
+		"verify the new key out of band, then remove the stale entry (ssh-keygen -R %s -f <file>)",
+		e.Host, e.KeyType, e.Fingerprint, strings.Join(e.Known, ", "), e.Host)
+}
+
+// HostKeyVerifier checks server host keys against OpenSSH known_hosts files.
+// Files are re-read on every check so keys pinned by concurrent connections
+// are seen immediately.
+type HostKeyVerifier struct {
+	policy HostKeyPolicy
+	files  []string
+	// managed is the file new keys are pinned to in accept-new mode. It is
+	// always consulted when it exists.
+	managed string
+
+	mu sync.Mutex
+}
+
+// NewHostKeyVerifier returns a verifier reading files plus managed, which
+// is also where accept-new pins keys.
+func NewHostKeyVerifier(policy HostKeyPolicy, managed string, files ...string) *HostKeyVerifier {
+	return &HostKeyVerifier{policy: policy, files: files, managed: managed}
+}
+
+// DefaultKnownHostsFiles returns the user's OpenSSH known_hosts files.
+func DefaultKnownHostsFiles() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".ssh", "known_hosts")}
+}
+
+// Policy returns the configured policy.
+func (v *HostKeyVerifier) Policy() HostKeyPolicy {
+	return v.policy
+}
+
+// load builds a knownhosts callback over the files that currently exist.
+func (v *HostKeyVerifier) load() (gossh.HostKeyCallback, error) {
+	var existing []string
+	for _, f := range append(append([]string{}, v.files...), v.managed) {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err == nil {
+			existing = append(existing, f)
+		}
+	}
+	return knownhosts.New(existing...)
+}
+
+// Callback returns the gossh.HostKeyCallback enforcing the policy.
+func (v *HostKeyVerifier) Callback() gossh.HostKeyCallback {
+	if v.policy == HostKeyOff {
+		return gossh.InsecureIgnoreHostKey() //nolint:gosec // explicitly requested with --ssh-strict-host-key-checking=no
+	}
+	return func(hostname string, remote net.Addr, key gossh.PublicKey) error {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+
+		cb, err := v.load()
+		if err != nil {
+			return fmt.Errorf("read known_hosts: %w", err)
+		}
+		err = cb(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			known := make([]string, 0, len(keyErr.Want))
+			for _, k := range keyErr.Want {
+				known = append(known, fmt.Sprintf("%s:%d %s", k.Filename, k.Line, gossh.FingerprintSHA256(k.Key)))
+			}
+			return &HostKeyMismatchError{
+				Host:        hostname,
+				Fingerprint: gossh.FingerprintSHA256(key),
+				KeyType:     key.Type(),
+				Known:       known,
+			}
+		}
+
+		if v.policy != HostKeyAcceptNew || v.managed == "" {
+			return &UnknownHostKeyError{Host: hostname, Fingerprint: gossh.FingerprintSHA256(key), KeyType: key.Type()}
+		}
+		return v.pin(hostname, key)
+	}
+}
+
+// pin appends key for hostname to the managed known_hosts file.
+func (v *HostKeyVerifier) pin(hostname string, key gossh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(v.managed), 0o700); err != nil {
+		return fmt.Errorf("create known_hosts dir: %w", err)
+	}
+	f, err := os.OpenFile(v.managed, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", v.managed, err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return fmt.Errorf("pin host key in %s: %w", v.managed, err)
+	}
+	fmt.Fprintf(os.Stderr, "Pinned host key for %s (%s %s) in %s\n",
+		hostname, key.Type(), gossh.FingerprintSHA256(key), v.managed)
+	return nil
+}
+
+// probeKey never matches a real host key; checking it returns every
+// recorded key for a host in KeyError.Want.
+var probeKey, _ = gossh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+
+// HostKeyAlgorithms returns the key algorithms recorded for addr so the
+// handshake negotiates a key type we can actually verify. It returns nil
+// (library defaults) when nothing is recorded.
+func (v *HostKeyVerifier) HostKeyAlgorithms(addr string) []string {
+	if v.policy == HostKeyOff {
+		return nil
+	}
+	v.mu.Lock()
+	cb, err := v.load()
+	v.mu.Unlock()
+	if err != nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if !errors.As(cb(addr, &net.TCPAddr{IP: net.IPv4zero}, probeKey), &keyErr) {
+		return nil
+	}
+
+	var algos []string
+	seen := map[string]bool{}
+	add := func(a string) {
+		if !seen[a] {
+			seen[a] = true
+			algos = append(algos, a)
+		}
+	}
+	for _, k := range keyErr.Want {
+		if k.Key.Type() == gossh.KeyAlgoRSA {
+			add(gossh.KeyAlgoRSASHA512)
+			add(gossh.KeyAlgoRSASHA256)
+		}
+		add(k.Key.Type())
+	}
+	return algos
+}
@@ -0,0 +1,118 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newHostKey(t *testing.T) gossh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+var remoteAddr = &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+
+func TestHostKeyVerifier_Strict(t *testing.T) {
+	dir := t.TempDir()
+	known := filepath.Join(dir, "known_hosts")
+	key := newHostKey(t)
+	line := knownhosts.Line([]string{knownhosts.Normalize("10.0.0.1:22")}, key)
+	if err := os.WriteFile(known, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cb := NewHostKeyVerifier(HostKeyStrict, filepath.Join(dir, "managed"), known).Callback()
+
+	if err := cb("10.0.0.1:22", remoteAddr, key); err != nil {
+		t.Fatalf("known key rejected: %v", err)
+	}
+
+	var unknown *UnknownHostKeyError
+	if err := cb("10.0.0.2:22", remoteAddr, key); !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownHostKeyError, got %v", err)
+	}
+
+	var mismatch *HostKeyMismatchError
+	err := cb("10.0.0.1:22", remoteAddr, newHostKey(t))
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected HostKeyMismatchError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), known+":1") {
+		t.Errorf("mismatch error should point at the known_hosts line: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "managed")); !os.IsNotExist(err) {
+		t.Errorf("strict mode must not write the managed file")
+	}
+}
+
+func TestHostKeyVerifier_AcceptNewPins(t *testing.T) {
+	dir := t.TempDir()
+	managed := filepath.Join(dir, "coolify", "known_hosts")
+	v := NewHostKeyVerifier(HostKeyAcceptNew, managed)
+	cb := v.Callback()
+	key := newHostKey(t)
+
+	if err := cb("10.0.0.1:2222", remoteAddr, key); err != nil {
+		t.Fatalf("first use should pin: %v", err)
+	}
+	data, err := os.ReadFile(managed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "[10.0.0.1]:2222 ssh-ed25519 ") {
+		t.Errorf("unexpected pinned line: %q", data)
+	}
+
+	if err := cb("10.0.0.1:2222", remoteAddr, key); err != nil {
+		t.Fatalf("pinned key rejected: %v", err)
+	}
+
+	var mismatch *HostKeyMismatchError
+	if err := cb("10.0.0.1:2222", remoteAddr, newHostKey(t)); !errors.As(err, &mismatch) {
+		t.Fatalf("changed key must be rejected even in accept-new mode, got %v", err)
+	}
+
+	algos := v.HostKeyAlgorithms("10.0.0.1:2222")
+	if len(algos) != 1 || algos[0] != gossh.KeyAlgoED25519 {
+		t.Errorf("HostKeyAlgorithms = %v, want [%s]", algos, gossh.KeyAlgoED25519)
+	}
+	if algos := v.HostKeyAlgorithms("10.9.9.9:22"); algos != nil {
+		t.Errorf("unknown host should use library defaults, got %v", algos)
+	}
+}
+
+func TestHostKeyVerifier_Off(t *testing.T) {
+	cb := NewHostKeyVerifier(HostKeyOff, "").Callback()
+	if err := cb("10.0.0.1:22", remoteAddr, newHostKey(t)); err != nil {
+		t.Fatalf("off mode should accept any key: %v", err)
+	}
+}
+
+func TestParseHostKeyPolicy(t *testing.T) {
+	for _, in := range []string{"yes", "accept-new", "no", " YES "} {
+		if _, err := ParseHostKeyPolicy(in); err != nil {
+			t.Errorf("ParseHostKeyPolicy(%q): %v", in, err)
+		}
+	}
+	if _, err := ParseHostKeyPolicy("ask"); err == nil {
+		t.Error("expected error for unsupported mode")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/coollabsio/coolify-cli/internal/services"
@@ -26,25 +28,28 @@ type VerifyResult struct {
 	Err         error
 }
 
-const aptInstallCmd = `DEBIAN_FRONTEND=noninteractive apt-get update -qq 2>/dev/null && ` +
-	`DEBIAN_FRONTEND=noninteractive apt-get install -y ` +
-	`-o Dpkg::Options::="--force-confold" ` +
-	`wireguard wireguard-tools 2>&1`
-
-const podmanInstallCmd = `DEBIAN_FRONTEND=noninteractive apt-get update -qq 2>/dev/null && ` +
-	`DEBIAN_FRONTEND=noninteractive apt-get install -y ` +
-	`-o Dpkg::Options::="--force-confold" ` +
-	`podman 2>&1`
-
 // enablePodmanSocketCmd ensures /run/podman/podman.sock exists via systemd
 // socket activation. The socket is NEVER exposed on TCP — it stays a Unix
 // socket on the host so the per-host coold agent can bind-mount it and
 // proxy a curated REST API over wg0. See CONTROL_PLANE.md §2 + §12.
 const enablePodmanSocketCmd = `systemctl enable --now podman.socket 2>&1`
 
-const enableIPForwardCmd = `sysctl -w net.ipv4.ip_forward=1 && ` +
-	`mkdir -p /etc/sysctl.d && ` +
-	`echo 'net.ipv4.ip_forward=1' > /etc/sysctl.d/99-coolify-mesh.conf`
+// enableIPForwardCmd turns on forwarding now and across reboots. With ipv6
+// it also sets accept_ra=2 on every interface: enabling IPv6 forwarding
+// otherwise stops the kernel from accepting router advertisements, and hosts
+// that get their IPv6 default route via SLAAC would lose it.
+func enableIPForwardCmd(ipv6 bool) string {
+	if !ipv6 {
+		return `sysctl -w net.ipv4.ip_forward=1 && ` +
+			`mkdir -p /etc/sysctl.d && ` +
+			`echo 'net.ipv4.ip_forward=1' > /etc/sysctl.d/99-coolify-mesh.conf`
+	}
+	return `for f in /proc/sys/net/ipv6/conf/*/accept_ra; do echo 2 > "$f"; done && ` +
+		`sysctl -w net.ipv4.ip_forward=1 net.ipv6.conf.all.forwarding=1 && ` +
+		`mkdir -p /etc/sysctl.d && ` +
+		`printf '%s\n' 'net.ipv4.ip_forward=1' 'net.ipv6.conf.all.forwarding=1' ` +
+		`'net.ipv6.conf.all.accept_ra=2' 'net.ipv6.conf.default.accept_ra=2' > /etc/sysctl.d/99-coolify-mesh.conf`
+}
 
 // podmanNetCreateCmd creates a per-namespace Podman bridge network. Idempotent:
 // skips if the network already exists. The bridge gateway is MachineIP(subnet)
@@ -54,25 +59,43 @@ const enableIPForwardCmd = `sysctl -w net.ipv4.ip_forward=1 && ` +
 // gateway IP:53 — coold owns that socket for cluster-wide service discovery
 // (see CONTROL_PLANE.md §5). Labels mark the network as ours + carry its
 // namespace so `podman network inspect` drift checks can assert it.
-func podmanNetCreateCmd(name, namespace string, subnet *net.IPNet, gateway net.IP) string {
+func podmanNetCreateCmd(name, namespace string, subnet *net.IPNet, gateway net.IP, subnet6 *net.IPNet) string {
 	return fmt.Sprintf(
 		`podman network exists %s 2>/dev/null && echo "network exists, skipping" || `+
-			`podman network create --driver bridge --disable-dns `+
+			`podman network create --driver bridge --disable-dns %s`+
 			`--label io.coolify.managed=true --label io.coolify.namespace=%s `+
-			`--subnet=%s --gateway=%s %s`,
-		name, namespace, subnet, gateway, name)
+			`%s %s`,
+		name, ipv6Flag(subnet, subnet6), namespace, subnetFlags(subnet, gateway, subnet6), name)
+}
+
+// ipv6Flag returns "--ipv6 " for an IPv6 or dual-stack bridge.
+func ipv6Flag(subnet, subnet6 *net.IPNet) string {
+	if IsIPv6(subnet.IP) || subnet6 != nil {
+		return "--ipv6 "
+	}
+	return ""
+}
+
+// subnetFlags returns the --subnet/--gateway pair of a bridge, repeated for
+// the IPv6 subnet of a dual-stack one.
+func subnetFlags(subnet *net.IPNet, gateway net.IP, subnet6 *net.IPNet) string {
+	flags := fmt.Sprintf("--subnet=%s --gateway=%s", subnet, gateway)
+	if subnet6 != nil {
+		flags += fmt.Sprintf(" --subnet=%s --gateway=%s", subnet6, MachineIP(subnet6))
+	}
+	return flags
 }
 
 // podmanNetRecreateCmd drops and recreates a per-namespace Podman bridge
 // network to clear drift (dns_enabled=true, subnet mismatch, missing label).
 // Uses `rm -f` to detach any attached containers first.
-func podmanNetRecreateCmd(name, namespace string, subnet *net.IPNet, gateway net.IP) string {
+func podmanNetRecreateCmd(name, namespace string, subnet *net.IPNet, gateway net.IP, subnet6 *net.IPNet) string {
 	return fmt.Sprintf(
 		`podman network rm -f %s 2>&1 && `+
-			`podman network create --driver bridge --disable-dns `+
+			`podman network create --driver bridge --disable-dns %s`+
 			`--label io.coolify.managed=true --label io.coolify.namespace=%s `+
-			`--subnet=%s --gateway=%s %s`,
-		name, namespace, subnet, gateway, name)
+			`%s %s`,
+		name, ipv6Flag(subnet, subnet6), namespace, subnetFlags(subnet, gateway, subnet6), name)
 }
 
 // runStep executes a single shell command on a remote host, appends an
@@ -86,6 +109,15 @@ func runStep(
 	atype ActionType,
 	namespace, cmd, errFmt string,
 ) error {
+	if jr, ok := runner.(journaledRunner); ok {
+		if err := jr.journal.snapshot(ctx, jr.Runner, host, user, port, atype); err != nil {
+			*out = append(*out, ActionResult{
+				Action: PlannedAction{Host: host, Namespace: namespace, Type: atype, Detail: err.Error()},
+				Err:    err,
+			})
+			return fmt.Errorf(errFmt+": %w", err)
+		}
+	}
 	stdout, stderr, err := runner.Run(ctx, host, user, port, cmd)
 	detail := ""
 	if err != nil {
@@ -115,6 +147,12 @@ func runStep(
 //   - Phase 2 (per-server, parallel): write WG config, enable/reload service,
 //     create per-namespace Podman networks, install firewall service.
 //   - Phase 3 (per-server, parallel, optional): download + enable corrosion/coold.
+//
+// With IntentRemoveHost the hosts in RemoveHosts are drained before phase 1
+// and torn down after the surviving hosts have been reconfigured.
+//
+// A non-nil journal records the files and units each reversible step is
+// about to change, so the run can be undone with Journal.Rollback.
 func ApplyMesh(
 	ctx context.Context,
 	runner ssh.Runner,
@@ -123,8 +161,21 @@ func ApplyMesh(
 	desired *DesiredMesh,
 	current MeshState,
 	concurrency int,
+	journal *Journal,
 ) ([]ActionResult, error) {
 	var results []ActionResult
+	if journal != nil {
+		runner = journaledRunner{Runner: runner, journal: journal}
+	}
+
+	drain, teardown, _ := planRemoval(desired, current)
+	if len(drain) > 0 {
+		drained, err := runRemovalSteps(ctx, runner, user, port, drain, concurrency)
+		results = append(results, drained...)
+		if err != nil {
+			return results, fmt.Errorf("drain removed host(s): %w; surviving hosts left untouched", err)
+		}
+	}
 
 	p1 := ssh.ForEachServer(ctx, desired.Hosts, concurrency,
 		func(ctx context.Context, host string) ([]ActionResult, error) {
@@ -148,6 +199,7 @@ func ApplyMesh(
 	if err != nil {
 		return results, fmt.Errorf("re-probe after phase 1: %w", err)
 	}
+	fresh.Ledger = current.Ledger
 
 	mgmtAssignments, _, err := AllocateMgmtIPs(desired.MgmtPool, fresh.AssignedMgmtIPs(), desired.Hosts)
 	if err != nil {
@@ -158,10 +210,19 @@ func ApplyMesh(
 	if err != nil {
 		return results, fmt.Errorf("container subnet allocation: %w", err)
 	}
+	var containerAssignments6 map[string]map[string]*net.IPNet
+	if desired.ContainerPool6 != nil {
+		containerAssignments6, _, err = AllocateNamespaced(desired.ContainerPool6, desired.ContainerPrefix6,
+			fresh.AssignedContainerSubnets6(), desired.Namespaces, desired.Hosts)
+		if err != nil {
+			return results, fmt.Errorf("IPv6 container subnet allocation: %w", err)
+		}
+	}
 
 	p2 := ssh.ForEachServer(ctx, desired.Hosts, concurrency,
 		func(ctx context.Context, host string) ([]ActionResult, error) {
-			return phase2Server(ctx, runner, host, user, port, desired, fresh, mgmtAssignments, containerAssignments)
+			return phase2Server(ctx, runner, host, user, port, desired, fresh,
+				mgmtAssignments, containerAssignments, containerAssignments6)
 		})
 
 	for _, r := range p2 {
@@ -209,7 +270,7 @@ func ApplyMesh(
 			err = fmt.Errorf("read jwt.priv from central %s: %w", desired.CentralHost, keyErr)
 		} else {
 			centralMgmtIP := mgmtAssignments[desired.CentralHost]
-			schedulerURL := fmt.Sprintf("http://%s:%d", centralMgmtIP, services.SchedulerGRPCPort)
+			schedulerURL := "http://" + net.JoinHostPort(centralMgmtIP.String(), strconv.Itoa(services.SchedulerGRPCPort))
 			// Include central itself: in single-server topology central *is* the coold
 			// target, and in fleet mode central's own coold still benefits from scheduler
 			// wiring (uniform dispatch path, no standalone-API exception).
@@ -228,6 +289,14 @@ func ApplyMesh(
 		}
 	}
 
+	if len(teardown) > 0 && err == nil {
+		tornDown, tdErr := runRemovalSteps(ctx, runner, user, port, teardown, concurrency)
+		results = append(results, tornDown...)
+		if tdErr != nil {
+			err = fmt.Errorf("tear down removed host(s): %w", tdErr)
+		}
+	}
+
 	return results, err
 }
 
@@ -250,7 +319,7 @@ func phase1Server(
 
 	if !state.Installed {
 		if err := runStep(ctx, runner, host, user, port, &out,
-			ActionInstallWG, "", aptInstallCmd,
+			ActionInstallWG, "", state.PackageManager.InstallCommand(services.PackageWireGuard),
 			fmt.Sprintf("install WireGuard on %s", host)); err != nil {
 			return out, err
 		}
@@ -270,7 +339,7 @@ func phase1Server(
 	if desired.InstallPodman {
 		if !state.PodmanInstalled {
 			if err := runStep(ctx, runner, host, user, port, &out,
-				ActionInstallPodman, "", podmanInstallCmd,
+				ActionInstallPodman, "", state.PackageManager.InstallCommand(services.PackagePodman),
 				fmt.Sprintf("install Podman on %s", host)); err != nil {
 				return out, err
 			}
@@ -282,9 +351,9 @@ func phase1Server(
 				return out, err
 			}
 		}
-		if !state.IPForwardEnabled {
+		if desired.needsIPForward(state) {
 			if err := runStep(ctx, runner, host, user, port, &out,
-				ActionEnableIPForward, "", enableIPForwardCmd,
+				ActionEnableIPForward, "", enableIPForwardCmd(desired.ContainerIPv6()),
 				fmt.Sprintf("enable IP forwarding on %s", host)); err != nil {
 				return out, err
 			}
@@ -294,6 +363,37 @@ func phase1Server(
 	return out, nil
 }
 
+// peerConfigs builds host's peer list: every other desired host with a known
+// public key, then the operator peer when one is recorded. Each host peer's
+// AllowedIPs covers every namespace subnet it owns.
+func peerConfigs(
+	desired *DesiredMesh,
+	host string,
+	pubkeys map[string]string,
+	mgmtAssignments map[string]net.IP,
+	containerAssignments map[string]map[string]*net.IPNet,
+	containerAssignments6 map[string]map[string]*net.IPNet,
+	operator *PeerConfig,
+) []PeerConfig {
+	nsSorted := desired.SortedNamespaces()
+	var peers []PeerConfig
+	for _, peer := range desired.Hosts {
+		if peer == host || pubkeys[peer] == "" {
+			continue
+		}
+		peers = append(peers, PeerConfig{
+			Endpoint:         peer,
+			PublicKey:        pubkeys[peer],
+			MgmtIP:           mgmtAssignments[peer],
+			ContainerSubnets: hostContainerSubnets(peer, nsSorted, containerAssignments, containerAssignments6),
+		})
+	}
+	if operator != nil {
+		peers = append(peers, *operator)
+	}
+	return peers
+}
+
 // phase2Server writes the WireGuard config, enables/reloads the service,
 // creates per-namespace Podman bridges, and installs the firewall service.
 func phase2Server(
@@ -305,36 +405,18 @@ func phase2Server(
 	fresh MeshState,
 	mgmtAssignments map[string]net.IP,
 	containerAssignments map[string]map[string]*net.IPNet,
+	containerAssignments6 map[string]map[string]*net.IPNet,
 ) ([]ActionResult, error) {
 	var out []ActionResult
 
 	mgmtIP := mgmtAssignments[host]
 	nsSorted := desired.SortedNamespaces()
 
-	// Build peer list (everyone except self, skip hosts with no pubkey).
-	// Each peer's AllowedIPs covers every namespace subnet that peer owns.
-	var peers []PeerConfig
-	for _, peer := range desired.Hosts {
-		if peer == host {
-			continue
-		}
-		ps, ok := fresh.Servers[peer]
-		if !ok || ps.PublicKey == "" {
-			continue
-		}
-		var subnets []*net.IPNet
-		for _, ns := range nsSorted {
-			if sn := containerAssignments[ns][peer]; sn != nil {
-				subnets = append(subnets, sn)
-			}
-		}
-		peers = append(peers, PeerConfig{
-			Endpoint:         peer,
-			PublicKey:        ps.PublicKey,
-			MgmtIP:           mgmtAssignments[peer],
-			ContainerSubnets: subnets,
-		})
+	pubkeys := make(map[string]string, len(fresh.Servers))
+	for h, s := range fresh.Servers {
+		pubkeys[h] = s.PublicKey
 	}
+	peers := peerConfigs(desired, host, pubkeys, mgmtAssignments, containerAssignments, containerAssignments6, fresh.operatorPeer())
 
 	// Write WG config.
 	configCmd := WriteConfigCommand(desired.Interface, mgmtIP, desired.ListenPort, peers)
@@ -370,6 +452,7 @@ func phase2Server(
 			if contSubnet == nil {
 				continue
 			}
+			contSubnet6 := containerAssignments6[ns][host]
 			netName := PodmanNetworkFor(ns)
 			gw := MachineIP(contSubnet)
 
@@ -379,7 +462,7 @@ func phase2Server(
 			}
 
 			if nss == nil || !nss.NetworkExists {
-				netCmd := podmanNetCreateCmd(netName, ns, contSubnet, gw)
+				netCmd := podmanNetCreateCmd(netName, ns, contSubnet, gw, contSubnet6)
 				if err := runStep(ctx, runner, host, user, port, &out,
 					ActionCreatePodmanNet, ns, netCmd,
 					fmt.Sprintf("create Podman network %s on %s", netName, host)); err != nil {
@@ -388,9 +471,10 @@ func phase2Server(
 				continue
 			}
 
-			subnetDrift := nss.ContainerSubnet != nil && nss.ContainerSubnet.String() != contSubnet.String()
+			subnetDrift := nss.ContainerSubnet != nil && nss.ContainerSubnet.String() != contSubnet.String() ||
+				!sameSubnet(nss.ContainerSubnet6, contSubnet6)
 			if nss.DNSEnabled || subnetDrift || nss.Label != ns {
-				recreateCmd := podmanNetRecreateCmd(netName, ns, contSubnet, gw)
+				recreateCmd := podmanNetRecreateCmd(netName, ns, contSubnet, gw, contSubnet6)
 				if err := runStep(ctx, runner, host, user, port, &out,
 					ActionRecreatePodmanNet, ns, recreateCmd,
 					fmt.Sprintf("recreate Podman network %s on %s", netName, host)); err != nil {
@@ -401,12 +485,7 @@ func phase2Server(
 
 		// Firewall service: union of namespace subnets; reinstall when missing,
 		// default-deny flipped, or unit text drifted (e.g. namespace added).
-		var subnets []*net.IPNet
-		for _, ns := range nsSorted {
-			if sn := containerAssignments[ns][host]; sn != nil {
-				subnets = append(subnets, sn)
-			}
-		}
+		subnets := hostContainerSubnets(host, nsSorted, containerAssignments, containerAssignments6)
 		expectedUnit := FirewallServiceUnit(desired.Interface, desired.SortedNamespaces(), subnets, desired.DefaultDenyContainers)
 		expectedUnitHash := sha256Hex([]byte(expectedUnit))
 		unitDrift := freshState != nil && freshState.FirewallUnitSha256 != expectedUnitHash
@@ -548,7 +627,7 @@ func phase4Central(
 
 	// 3. Write scheduler unit + enable service.
 	mgmtIP := mgmtAssignments[host]
-	grpcBind := fmt.Sprintf("%s:%d", mgmtIP, services.SchedulerGRPCPort)
+	grpcBind := net.JoinHostPort(mgmtIP.String(), strconv.Itoa(services.SchedulerGRPCPort))
 	schedulerUnit := services.SchedulerServiceUnit(grpcBind, services.SchedulerJWTPubPath)
 	serviceCmd := heredocWrite("/etc/systemd/system/scheduler.service",
 		schedulerUnit, "COOLIFY_SCHEDULER_UNIT_EOF", 0o644) +
@@ -572,7 +651,7 @@ func phase5PerHost(
 	host, user string,
 	port int,
 	desired *DesiredMesh,
-	_ MeshState,
+	current MeshState,
 	mgmtAssignments map[string]net.IP,
 	containerAssignments map[string]map[string]*net.IPNet,
 	privKeyPEM []byte,
@@ -588,33 +667,23 @@ func phase5PerHost(
 	// Mint JWT with sub = wg0 mgmt IP (stable, scheduler-addressable identifier).
 	// caps claim must match what coold will advertise in its Hello frame —
 	// the scheduler cross-checks and rejects a stream whose Hello elevates over
-	// its JWT. Per-host toggle via desired.HasBuilderCap(host).
-	hostID := mgmtIP.String()
-	hasBuilder := desired.HasBuilderCap(host)
-	caps := []string{"coold"}
-	if hasBuilder {
-		caps = append(caps, "builder")
-	}
-	jwtToken, err := services.MintHostJWT(privKeyPEM, hostID, caps)
-	if err != nil {
-		return out, fmt.Errorf("mint JWT for %s: %w", host, err)
-	}
-
-	// 1. Write JWT to /etc/coolify/host-jwt (mode 0600, idempotent).
-	writeJWTCmd := fmt.Sprintf(
-		`mkdir -p /etc/coolify && printf '%%s' '%s' > %s.tmp && chmod 0600 %s.tmp && mv %s.tmp %s`,
-		jwtToken, services.HostJWTPath, services.HostJWTPath, services.HostJWTPath, services.HostJWTPath)
-	if err := runStep(ctx, runner, host, user, port, &out,
-		ActionWriteHostJWT, "", writeJWTCmd,
-		fmt.Sprintf("write host JWT on %s", host)); err != nil {
-		return out, err
+	// its JWT. Per-host toggle via desired.HasBuilderCap(host), plus the
+	// host's labels.
+	caps := desired.HostCaps(host, current.Servers[host])
+
+	// 1. Write JWT to /etc/coolify/host-jwt (mode 0600, idempotent). A
+	// revoked host is skipped; BuildPlan warns about it.
+	if !desired.JWTRevoked(mgmtIP.String()) {
+		if err := writeHostJWTStep(ctx, runner, host, user, port, &out, privKeyPEM, mgmtIP, caps); err != nil {
+			return out, err
+		}
 	}
 
 	// 2. Install builder binary + buildah/git (only on builder-capable hosts).
-	if hasBuilder {
+	if desired.HasBuilderCap(host) {
 		if err := runStep(ctx, runner, host, user, port, &out,
 			ActionInstallBuilder, "",
-			services.BuilderInstallCommand(desired.CooldVersion),
+			services.BuilderInstallCommand(current.packageManager(host), desired.CooldVersion),
 			fmt.Sprintf("install builder on %s", host)); err != nil {
 			return out, err
 		}
@@ -622,14 +691,55 @@ func phase5PerHost(
 
 	// 3. Rewrite coold unit with scheduler env vars (and builder env when
 	// enabled) + restart.
-	nsSorted := desired.SortedNamespaces()
-	nsConfigs := buildNamespaceConfigs(host, nsSorted, containerAssignments)
+	err := updateCooldSchedulerEnvStep(ctx, runner, host, user, port, &out,
+		desired, mgmtIP, buildNamespaceConfigs(host, desired.SortedNamespaces(), containerAssignments),
+		schedulerURL, caps)
+	return out, err
+}
+
+// writeHostJWTStep mints host's JWT (sub = its mgmt IP) with caps and
+// writes it to services.HostJWTPath.
+func writeHostJWTStep(
+	ctx context.Context,
+	runner ssh.Runner,
+	host, user string,
+	port int,
+	out *[]ActionResult,
+	privKeyPEM []byte,
+	mgmtIP net.IP,
+	caps []string,
+) error {
+	jwtToken, err := services.MintHostJWT(privKeyPEM, mgmtIP.String(), caps)
+	if err != nil {
+		return fmt.Errorf("mint JWT for %s: %w", host, err)
+	}
+	return runStep(ctx, runner, host, user, port, out,
+		ActionWriteHostJWT, "", writeHostJWTCommand(jwtToken),
+		fmt.Sprintf("write host JWT on %s", host))
+}
+
+// updateCooldSchedulerEnvStep rewrites the coold unit with the scheduler
+// env and caps, plus the builder env when caps include builder, and
+// restarts coold.
+func updateCooldSchedulerEnvStep(
+	ctx context.Context,
+	runner ssh.Runner,
+	host, user string,
+	port int,
+	out *[]ActionResult,
+	desired *DesiredMesh,
+	mgmtIP net.IP,
+	nsConfigs []services.CooldNamespace,
+	schedulerURL string,
+	caps []string,
+) error {
 	scheduler := &services.SchedulerConfig{
 		URL:     schedulerURL,
 		JWTPath: services.HostJWTPath,
+		Caps:    caps,
 	}
 	var builderCfg *services.BuilderConfig
-	if hasBuilder {
+	if slices.Contains(caps, services.CapBuilder) {
 		denyNets := []string{}
 		if desired.MgmtPool != nil {
 			denyNets = append(denyNets, desired.MgmtPool.String())
@@ -650,13 +760,9 @@ func phase5PerHost(
 		cooldUnit, "COOLIFY_COOLD_SCHEDULER_UNIT_EOF", 0o644) +
 		` && systemctl daemon-reload` +
 		` && systemctl restart coold`
-	if err := runStep(ctx, runner, host, user, port, &out,
+	return runStep(ctx, runner, host, user, port, out,
 		ActionUpdateCooldSchedulerEnv, "", updateCmd,
-		fmt.Sprintf("update coold scheduler env on %s", host)); err != nil {
-		return out, err
-	}
-
-	return out, nil
+		fmt.Sprintf("update coold scheduler env on %s", host))
 }
 
 // phase3Server downloads corrosion + coold from GitHub releases, writes their
@@ -739,27 +845,23 @@ func phase3Server(
 		return out, err
 	}
 
-	// 5. Write schema. When schema content drifts (not first install) the
-	// CR-SQLite on-disk DB is incompatible — stop corrosion and wipe the DB
-	// so it re-bootstraps from the new schema. Coold repopulates within ~2s.
-	expectedSchemaSha := sha256Hex([]byte(services.CoolifySchemaSQL))
-	schemaDrift := freshState != nil &&
-		freshState.CorrosionSchemaSha256 != "" &&
-		freshState.CorrosionSchemaSha256 != expectedSchemaSha
-	schemaCmd := heredocWrite("/etc/corrosion/schemas/coolify.sql",
-		services.CoolifySchemaSQL, "COOLIFY_SCHEMA_EOF", 0o600)
-	if schemaDrift {
-		schemaCmd = `systemctl stop corrosion 2>/dev/null || true; ` +
-			`rm -f /var/lib/corrosion/corrosion.db ` +
-			`/var/lib/corrosion/corrosion.db-shm ` +
-			`/var/lib/corrosion/corrosion.db-wal && ` +
-			schemaCmd
-	}
-	if err := runStep(ctx, runner, host, user, port, &out,
-		ActionWriteCorrosionSchema, "", schemaCmd,
-		fmt.Sprintf("write corrosion schema on %s", host)); err != nil {
+	// 5. Write the schema, or migrate the live DB to it (see
+	// corrosionSchemaCommand).
+	schemaAction, schemaCmd, err := corrosionSchemaCommand(desired, host, freshState)
+	if err != nil {
+		out = append(out, ActionResult{
+			Action: PlannedAction{Host: host, Type: schemaAction, Detail: err.Error()},
+			Err:    err,
+		})
 		return out, err
 	}
+	if schemaCmd != "" {
+		if err := runStep(ctx, runner, host, user, port, &out,
+			schemaAction, "", schemaCmd,
+			fmt.Sprintf("write corrosion schema on %s", host)); err != nil {
+			return out, err
+		}
+	}
 
 	// 6. Write corrosion unit + 7. Write coold unit + 8. daemon-reload + enable.
 	// Use enable + restart (not enable --now) so an already-active service still
@@ -1,11 +1,15 @@
 package wireguard
 
 import (
+	"context"
+	"errors"
 	"net"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFirstLine(t *testing.T) {
@@ -27,7 +31,7 @@ func TestFirstLine(t *testing.T) {
 func TestPodmanNetCreateCmd_DisablesDNSAndLabels(t *testing.T) {
 	_, subnet, _ := net.ParseCIDR("10.210.0.0/24")
 	gw := net.ParseIP("10.210.0.1")
-	got := podmanNetCreateCmd("coolify-default-mesh", "default", subnet, gw)
+	got := podmanNetCreateCmd("coolify-default-mesh", "default", subnet, gw, nil)
 
 	// Must pass --disable-dns so aardvark-dns never binds bridge gateway :53
 	// (coold owns that socket).
@@ -44,7 +48,7 @@ func TestPodmanNetCreateCmd_DisablesDNSAndLabels(t *testing.T) {
 func TestPodmanNetRecreateCmd_DropsAndCreatesWithDisableDNS(t *testing.T) {
 	_, subnet, _ := net.ParseCIDR("10.220.0.0/24")
 	gw := net.ParseIP("10.220.0.1")
-	got := podmanNetRecreateCmd("coolify-alpha-mesh", "alpha", subnet, gw)
+	got := podmanNetRecreateCmd("coolify-alpha-mesh", "alpha", subnet, gw, nil)
 
 	assert.Contains(t, got, "podman network rm -f coolify-alpha-mesh")
 	assert.Contains(t, got, "--disable-dns")
@@ -56,6 +60,41 @@ func TestPodmanNetRecreateCmd_DropsAndCreatesWithDisableDNS(t *testing.T) {
 	assert.True(t, rmIdx >= 0 && createIdx > rmIdx, "rm must precede create")
 }
 
+func TestPodmanNetCreateCmd_IPv6Subnet(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("fd00:210:0:1::/64")
+	got := podmanNetCreateCmd("coolify-default-mesh", "default", subnet, MachineIP(subnet), nil)
+
+	assert.Contains(t, got, "--disable-dns --ipv6 --label")
+	assert.Contains(t, got, "--subnet=fd00:210:0:1::/64 --gateway=fd00:210:0:1::1")
+
+	_, v4, _ := net.ParseCIDR("10.210.0.0/24")
+	assert.NotContains(t, podmanNetCreateCmd("n", "default", v4, MachineIP(v4), nil), "--ipv6")
+}
+
+func TestPodmanNetCreateCmd_DualStack(t *testing.T) {
+	_, v4, _ := net.ParseCIDR("10.210.0.0/24")
+	_, v6, _ := net.ParseCIDR("fd00:210::/64")
+	got := podmanNetCreateCmd("coolify-default-mesh", "default", v4, MachineIP(v4), v6)
+
+	assert.Contains(t, got, "--disable-dns --ipv6 --label")
+	assert.Contains(t, got,
+		"--subnet=10.210.0.0/24 --gateway=10.210.0.1 --subnet=fd00:210::/64 --gateway=fd00:210::1 coolify-default-mesh")
+
+	got = podmanNetRecreateCmd("coolify-default-mesh", "default", v4, MachineIP(v4), v6)
+	assert.Contains(t, got, "--ipv6")
+	assert.Equal(t, 2, strings.Count(got, "--subnet="))
+}
+
+func TestEnableIPForwardCmd_IPv6KeepsRouterAdvertisements(t *testing.T) {
+	assert.NotContains(t, enableIPForwardCmd(false), "ipv6")
+
+	got := enableIPForwardCmd(true)
+	assert.Contains(t, got, "net.ipv6.conf.all.forwarding=1")
+	assert.Contains(t, got, "accept_ra")
+	assert.Less(t, strings.Index(got, "accept_ra"), strings.Index(got, "forwarding=1"),
+		"accept_ra=2 must be set before forwarding turns RA processing off")
+}
+
 func TestHeredocWrite_EmitsChmodBeforeMv(t *testing.T) {
 	got := heredocWrite("/etc/corrosion/config.toml", "body", "TAG", 0o600)
 
@@ -94,3 +133,41 @@ func TestNonEmptyLines(t *testing.T) {
 		assert.Equal(t, tt.want, got, "input: %q", tt.input)
 	}
 }
+
+// recordingRunner records every command per host and fails those matching
+// failOn.
+type recordingRunner struct {
+	mu     sync.Mutex
+	calls  map[string][]string
+	failOn string
+}
+
+func (r *recordingRunner) Run(_ context.Context, host, _ string, _ int, cmd string) (string, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls == nil {
+		r.calls = map[string][]string{}
+	}
+	r.calls[host] = append(r.calls[host], cmd)
+	if r.failOn != "" && strings.Contains(cmd, r.failOn) {
+		return "", "boom", errors.New("exit status 1")
+	}
+	return "", "", nil
+}
+
+func TestApplyMesh_RemoveHostDrainFailureLeavesSurvivorsUntouched(t *testing.T) {
+	removed := convergedServer("2.2.2.2", "BBBBBBBB=", "AAAAAAAA=", "100.64.0.2", "10.210.1.0/24")
+	removed.CooldActive = true
+	current := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": convergedServer("1.1.1.1", "AAAAAAAA=", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24"),
+		"2.2.2.2": removed,
+	}}
+	runner := &recordingRunner{failOn: "podman stop"}
+
+	results, err := ApplyMesh(context.Background(), runner, "root", 22, desiredRemoveSecondHost(), current, 2, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "surviving hosts left untouched")
+	assert.Empty(t, runner.calls["1.1.1.1"])
+	require.Len(t, results, 1)
+	assert.Equal(t, ActionDrainHost, results[0].Action.Type)
+}
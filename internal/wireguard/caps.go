@@ -0,0 +1,144 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// keepBuilder returns a copy of d whose builder set is read from each
+// host's current token. Changing labels never installs or drops the
+// builder; that stays with --enable-builder / --builder-hosts.
+func keepBuilder(d *DesiredMesh, current MeshState) *DesiredMesh {
+	kept := *d
+	kept.EnableBuilder = false
+	kept.BuilderHosts = nil
+	for _, h := range d.Hosts {
+		if s := current.Servers[h]; s != nil && slices.Contains(s.HostCaps, services.CapBuilder) {
+			kept.BuilderHosts = append(kept.BuilderHosts, h)
+		}
+	}
+	return &kept
+}
+
+// PlanHostCaps returns the steps that move hosts to the caps d asks for
+// (see DesiredMesh.HostCaps): a re-issued host JWT and a rewritten coold
+// unit. Hosts whose token already carries those caps get no steps. Every
+// host must be in the mesh and hold a host JWT.
+func PlanHostCaps(d *DesiredMesh, current MeshState, hosts []string) ([]PlannedAction, error) {
+	if d.CentralHost == "" {
+		return nil, fmt.Errorf("changing host capabilities needs the --central host")
+	}
+	d = keepBuilder(d, current)
+	var actions []PlannedAction
+	for _, h := range hosts {
+		s := current.Servers[h]
+		switch {
+		case !slices.Contains(d.Hosts, h):
+			return nil, fmt.Errorf("%s is not in --servers", h)
+		case s == nil || s.WireGuardMgmtIP == nil:
+			return nil, fmt.Errorf("%s is not in the mesh", h)
+		case d.JWTRevoked(s.WireGuardMgmtIP.String()):
+			return nil, fmt.Errorf("%s's host JWT is revoked; lift it with `coolify init jwt rotate --hosts %s` first", h, h)
+		case s.HostCaps == nil:
+			return nil, fmt.Errorf("%s has no host JWT; run `coolify init bootstrap --central %s` first", h, d.CentralHost)
+		}
+		caps := d.HostCaps(h, s)
+		if slices.Equal(caps, s.HostCaps) {
+			continue
+		}
+		actions = append(actions,
+			PlannedAction{
+				Host: h, Type: ActionWriteHostJWT,
+				Detail: fmt.Sprintf("re-issue with caps %s (was %s)", strings.Join(caps, ","), strings.Join(s.HostCaps, ",")),
+			},
+			PlannedAction{
+				Host: h, Type: ActionUpdateCooldSchedulerEnv,
+				Detail: fmt.Sprintf("COOLD_CAPS=%s, restart coold", strings.Join(caps, ",")),
+			},
+		)
+	}
+	return actions, nil
+}
+
+// ApplyHostCaps runs the steps PlanHostCaps plans: phase 5 of ApplyMesh
+// (write host JWT, rewrite the coold unit and restart coold) for the hosts
+// whose caps change, and nothing else. A non-nil journal records the files
+// each step rewrites, like ApplyMesh.
+func ApplyHostCaps(
+	ctx context.Context,
+	runner ssh.Runner,
+	user string,
+	port int,
+	d *DesiredMesh,
+	current MeshState,
+	hosts []string,
+	concurrency int,
+	journal *Journal,
+) ([]ActionResult, error) {
+	steps, err := PlanHostCaps(d, current, hosts)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for _, a := range steps {
+		if a.Type == ActionWriteHostJWT {
+			changed = append(changed, a.Host)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	if journal != nil {
+		runner = journaledRunner{Runner: runner, journal: journal}
+	}
+
+	central := current.Servers[d.CentralHost]
+	if central == nil || central.WireGuardMgmtIP == nil {
+		return nil, fmt.Errorf("central %s has no mgmt IP", d.CentralHost)
+	}
+	schedulerURL := "http://" + net.JoinHostPort(central.WireGuardMgmtIP.String(), strconv.Itoa(services.SchedulerGRPCPort))
+	privKeyPEM, stderr, err := runner.Run(ctx, d.CentralHost, user, port, "cat "+services.SchedulerJWTPrivPath)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt.priv from central %s: %w (stderr: %s)", d.CentralHost, err, strings.TrimSpace(stderr))
+	}
+
+	d = keepBuilder(d, current)
+	nsSorted := d.SortedNamespaces()
+	containerAssignments := current.AssignedContainerSubnets()
+	results := ssh.ForEachServer(ctx, changed, concurrency,
+		func(ctx context.Context, host string) ([]ActionResult, error) {
+			var out []ActionResult
+			s := current.Servers[host]
+			caps := d.HostCaps(host, s)
+			if err := writeHostJWTStep(ctx, runner, host, user, port, &out,
+				[]byte(privKeyPEM), s.WireGuardMgmtIP, caps); err != nil {
+				return out, err
+			}
+			err := updateCooldSchedulerEnvStep(ctx, runner, host, user, port, &out,
+				d, s.WireGuardMgmtIP, buildNamespaceConfigs(host, nsSorted, containerAssignments),
+				schedulerURL, caps)
+			return out, err
+		})
+
+	var out []ActionResult
+	for _, r := range results {
+		out = append(out, r.Result...)
+		if r.Err != nil {
+			err = fmt.Errorf("changing capabilities failed on one or more hosts")
+			if len(r.Result) == 0 {
+				out = append(out, ActionResult{
+					Action: PlannedAction{Host: r.Host, Type: ActionWriteHostJWT, Detail: r.Err.Error()},
+					Err:    r.Err,
+				})
+			}
+		}
+	}
+	return out, err
+}
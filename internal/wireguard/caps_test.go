@@ -0,0 +1,92 @@
+package wireguard
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
+)
+
+func TestDesiredMesh_HostCaps(t *testing.T) {
+	d := &DesiredMesh{Hosts: []string{"h1", "h2"}, BuilderHosts: []string{"h1"}}
+	s := &ServerState{HostCaps: []string{"coold", "storage", "builder", "edge"}}
+
+	assert.Equal(t, []string{"coold", "builder", "edge", "storage"}, d.HostCaps("h1", s),
+		"without an entry the labels of the current token are kept")
+	assert.Equal(t, []string{"coold"}, d.HostCaps("h2", nil))
+
+	d.HostLabels = map[string][]string{"h1": {"gpu", "edge", "gpu"}, "h2": {}}
+	assert.Equal(t, []string{"coold", "builder", "edge", "gpu"}, d.HostCaps("h1", s))
+	assert.Equal(t, []string{"coold"}, d.HostCaps("h2", s), "an empty entry clears the labels")
+}
+
+// capsMesh is jwtMesh with the probed state of c, h1 and h2; h1 is a
+// builder.
+func capsMesh(t *testing.T) (*jwtRunner, *DesiredMesh, MeshState) {
+	r, d := jwtMesh(t)
+	d.Hosts = []string{"c", "h1", "h2"}
+	d.EnableBuilder = true
+	current := MeshState{Servers: map[string]*ServerState{
+		"c":  {Host: "c", WireGuardMgmtIP: net.ParseIP("100.64.0.9"), HostCaps: []string{"coold"}},
+		"h1": {Host: "h1", WireGuardMgmtIP: net.ParseIP("100.64.0.1"), HostCaps: []string{"coold", "builder"}},
+		"h2": {Host: "h2", WireGuardMgmtIP: net.ParseIP("100.64.0.2"), HostCaps: []string{"coold", "edge"}},
+	}}
+	return r, d, current
+}
+
+func TestPlanHostCaps(t *testing.T) {
+	_, d, current := capsMesh(t)
+	d.HostLabels = map[string][]string{"h1": {"gpu"}, "h2": {"edge"}}
+
+	steps, err := PlanHostCaps(d, current, []string{"h1", "h2"})
+	require.NoError(t, err)
+	require.Len(t, steps, 2, "h2 already carries its caps")
+	assert.Equal(t, ActionWriteHostJWT, steps[0].Type)
+	assert.Equal(t, "re-issue with caps coold,builder,gpu (was coold,builder)", steps[0].Detail)
+	assert.Equal(t, ActionUpdateCooldSchedulerEnv, steps[1].Type)
+
+	// --enable-builder does not turn h2 into a builder.
+	d.HostLabels = map[string][]string{"h2": {}}
+	steps, err = PlanHostCaps(d, current, []string{"h2"})
+	require.NoError(t, err)
+	require.NotEmpty(t, steps)
+	assert.Contains(t, steps[0].Detail, "re-issue with caps coold (was")
+
+	d.JWTRevocations = []services.JWTRevocation{{Subject: "100.64.0.2", Before: 1}}
+	_, err = PlanHostCaps(d, current, []string{"h2"})
+	assert.ErrorContains(t, err, "jwt rotate --hosts h2")
+	d.JWTRevocations = nil
+
+	current.Servers["h2"].HostCaps = nil
+	_, err = PlanHostCaps(d, current, []string{"h2"})
+	assert.ErrorContains(t, err, "no host JWT")
+	_, err = PlanHostCaps(d, current, []string{"h3"})
+	assert.ErrorContains(t, err, "not in --servers")
+}
+
+func TestApplyHostCaps_ReissuesAndRewritesCoold(t *testing.T) {
+	r, d, current := capsMesh(t)
+	h2Token := r.files["h2"][services.HostJWTPath]
+	d.HostLabels = map[string][]string{"h1": {"gpu", "storage"}}
+
+	results, err := ApplyHostCaps(context.Background(), r, "root", 22, d, current,
+		[]string{"h1", "h2"}, 2, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	c, err := services.ParseHostJWT(r.files["h1"][services.HostJWTPath])
+	require.NoError(t, err)
+	assert.Equal(t, "100.64.0.1", c.Subject)
+	assert.Equal(t, []string{"coold", "builder", "gpu", "storage"}, c.Caps)
+
+	unit := r.files["h1"]["/etc/systemd/system/coold.service"]
+	assert.Contains(t, unit, "Environment=COOLD_CAPS=coold,builder,gpu,storage\n")
+	assert.Contains(t, unit, "Environment=COOLD_SCHEDULER_URL=http://100.64.0.9:")
+	assert.Contains(t, unit, "COOLD_BUILDER_ENABLED=true", "h1 stays a builder")
+
+	assert.Equal(t, h2Token, r.files["h2"][services.HostJWTPath], "h2 keeps its labels and token")
+}
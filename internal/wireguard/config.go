@@ -3,6 +3,7 @@ package wireguard
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 )
 
@@ -12,20 +13,36 @@ type PeerConfig struct {
 	Endpoint string
 	// PublicKey is the peer's WireGuard public key.
 	PublicKey string
-	// MgmtIP is the peer's /32 wg0 management IP.
+	// MgmtIP is the peer's wg0 management IP (/32, or /128 for IPv6).
 	MgmtIP net.IP
 	// ContainerSubnets is the peer's per-namespace container bridge subnets,
 	// sorted by namespace name for stable output. All of them — along with
-	// MgmtIP/32 — are listed in AllowedIPs so every namespace's cross-host
+	// the MgmtIP host prefix — are listed in AllowedIPs so every namespace's cross-host
 	// traffic can route via the tunnel.
 	ContainerSubnets []*net.IPNet
+	// Operator marks the operator peer (`coolify firewall operator join`).
+	// It dials in from the CLI machine, so its block has no Endpoint or
+	// keepalive and is bracketed by the markers RemoveOperatorPeerCommand
+	// deletes.
+	Operator bool
 }
 
-// allowedIPsLine joins the mgmt /32 and every container subnet into a single
-// comma-separated AllowedIPs value.
+// PSKDir holds the per-pair preshared keys on every host, one file per peer
+// named after the peer's mgmt IP. Both ends of a pair hold the same key.
+const PSKDir = "/etc/wireguard/psk"
+
+// PSKPath returns the preshared key file for the peer with mgmtIP.
+func PSKPath(mgmtIP net.IP) string {
+	return PSKDir + "/" + mgmtIP.String()
+}
+
+// allowedIPsLine joins the mgmt host prefix and every container subnet into
+// a single comma-separated AllowedIPs value. IPv4 and IPv6 entries mix
+// freely, so a mesh can carry a mgmt pool and container pool of different
+// families.
 func allowedIPsLine(p PeerConfig) string {
 	parts := make([]string, 0, 1+len(p.ContainerSubnets))
-	parts = append(parts, fmt.Sprintf("%s/32", p.MgmtIP))
+	parts = append(parts, HostCIDR(p.MgmtIP))
 	for _, sn := range p.ContainerSubnets {
 		if sn == nil {
 			continue
@@ -35,27 +52,37 @@ func allowedIPsLine(p PeerConfig) string {
 	return strings.Join(parts, ", ")
 }
 
+// endpoint renders host:port, bracketing IPv6 literals.
+func endpoint(host string, port int) string {
+	return net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+}
+
 // RenderConfig returns the content of wg0.conf for one host.
 //
-// The host's own Address is the management IP /32 (e.g. 100.64.0.0/32). It
-// lives in a separate pool from the container subnets, so the Podman bridges
-// can own their per-host /24s without conflict.
+// The host's own Address is the management IP as a host prefix (e.g.
+// 100.64.0.1/32 or fd64::1/128). It lives in a separate pool from the
+// container subnets, so the Podman bridges can own their per-host /24s
+// without conflict. IPv6 endpoints are bracketed ([2001:db8::1]:51820).
 //
 // The literal string __PRIVKEY__ is used as a placeholder; callers must
 // substitute the actual key before (or during) writing to disk.
 func RenderConfig(mgmtIP net.IP, listenPort int, peers []PeerConfig) string {
 	var b strings.Builder
 	fmt.Fprintf(&b, "[Interface]\n")
-	fmt.Fprintf(&b, "Address = %s/32\n", mgmtIP)
+	fmt.Fprintf(&b, "Address = %s\n", HostCIDR(mgmtIP))
 	fmt.Fprintf(&b, "ListenPort = %d\n", listenPort)
 	fmt.Fprintf(&b, "PrivateKey = __PRIVKEY__\n")
 
 	for _, p := range peers {
+		if p.Operator {
+			fmt.Fprintf(&b, "\n%s\n", strings.Join(operatorPeerBlock(p.PublicKey, p.MgmtIP), "\n"))
+			continue
+		}
 		fmt.Fprintf(&b, "\n[Peer]\n")
 		fmt.Fprintf(&b, "# %s\n", p.Endpoint)
 		fmt.Fprintf(&b, "PublicKey = %s\n", p.PublicKey)
 		fmt.Fprintf(&b, "AllowedIPs = %s\n", allowedIPsLine(p))
-		fmt.Fprintf(&b, "Endpoint = %s:%d\n", p.Endpoint, listenPort)
+		fmt.Fprintf(&b, "Endpoint = %s\n", endpoint(p.Endpoint, listenPort))
 		fmt.Fprintf(&b, "PersistentKeepalive = 25\n")
 	}
 	return b.String()
@@ -65,25 +92,36 @@ func RenderConfig(mgmtIP net.IP, listenPort int, peers []PeerConfig) string {
 // /etc/wireguard/<iface>.conf on the remote host.
 //
 // The private key is read from /etc/wireguard/privatekey on the remote so it
-// never traverses SSH.  The config is written to a .tmp file first and then
-// moved into place so a killed session cannot leave a torn config.
+// never traverses SSH.  A peer gets a PresharedKey line when its PSKPath file
+// exists, so re-rendering keeps keys installed by rotate-keys.  The config is
+// written to a .tmp file first and then moved into place so a killed session
+// cannot leave a torn config.
 func WriteConfigCommand(iface string, mgmtIP net.IP, listenPort int, peers []PeerConfig) string {
 	var b strings.Builder
 
 	b.WriteString(`PRIVKEY=$(cat /etc/wireguard/privatekey) && `)
 	b.WriteString(`mkdir -p /etc/wireguard && `)
 	b.WriteString(`{ echo "[Interface]"; `)
-	fmt.Fprintf(&b, `echo "Address = %s/32"; `, mgmtIP)
+	fmt.Fprintf(&b, `echo "Address = %s"; `, HostCIDR(mgmtIP))
 	fmt.Fprintf(&b, `echo "ListenPort = %d"; `, listenPort)
 	b.WriteString(`echo "PrivateKey = $PRIVKEY"; `)
 
 	for _, p := range peers {
 		b.WriteString(`echo ""; `)
+		if p.Operator {
+			for _, line := range operatorPeerBlock(p.PublicKey, p.MgmtIP) {
+				fmt.Fprintf(&b, `echo "%s"; `, line)
+			}
+			continue
+		}
 		b.WriteString(`echo "[Peer]"; `)
 		fmt.Fprintf(&b, `echo "# %s"; `, p.Endpoint)
 		fmt.Fprintf(&b, `echo "PublicKey = %s"; `, p.PublicKey)
+		if p.MgmtIP != nil {
+			fmt.Fprintf(&b, `if [ -s %[1]s ]; then echo "PresharedKey = $(cat %[1]s)"; fi; `, PSKPath(p.MgmtIP))
+		}
 		fmt.Fprintf(&b, `echo "AllowedIPs = %s"; `, allowedIPsLine(p))
-		fmt.Fprintf(&b, `echo "Endpoint = %s:%d"; `, p.Endpoint, listenPort)
+		fmt.Fprintf(&b, `echo "Endpoint = %s"; `, endpoint(p.Endpoint, listenPort))
 		b.WriteString(`echo "PersistentKeepalive = 25"; `)
 	}
 
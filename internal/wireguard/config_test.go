@@ -83,3 +83,29 @@ func TestWriteConfigCommand_NoPeers(t *testing.T) {
 	assert.Contains(t, cmd, "51820")
 	assert.NotContains(t, cmd, "[Peer]")
 }
+
+func TestWriteConfigCommand_KeepsPresharedKeys(t *testing.T) {
+	peers := []PeerConfig{{
+		Endpoint:  "203.0.113.11",
+		PublicKey: "BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB=",
+		MgmtIP:    net.ParseIP("100.64.0.2").To4(),
+	}}
+
+	cmd := WriteConfigCommand("wg0", net.ParseIP("100.64.0.1").To4(), 51820, peers)
+
+	assert.Contains(t, cmd, `if [ -s /etc/wireguard/psk/100.64.0.2 ]; then echo "PresharedKey = $(cat /etc/wireguard/psk/100.64.0.2)"; fi`)
+}
+
+func TestRenderConfig_IPv6(t *testing.T) {
+	peers := []PeerConfig{{
+		Endpoint:         "2001:db8::12",
+		PublicKey:        "CCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCC=",
+		MgmtIP:           net.ParseIP("fd00:64::2"),
+		ContainerSubnets: []*net.IPNet{mustParseCIDR("10.210.2.0/24")},
+	}}
+	got := RenderConfig(net.ParseIP("fd00:64::1"), 51820, peers)
+
+	assert.Contains(t, got, "Address = fd00:64::1/128")
+	assert.Contains(t, got, "Endpoint = [2001:db8::12]:51820")
+	assert.Contains(t, got, "AllowedIPs = fd00:64::2/128, 10.210.2.0/24")
+}
@@ -16,6 +16,10 @@ const firewallServiceName = "coolify-mesh-fw.service"
 // after the kernel tables are cleared.
 const AllowRulesPath = "/etc/coolify/allow.rules"
 
+// AllowRules6Path is the ip6tables-restore counterpart of AllowRulesPath,
+// replayed when the host has IPv6 container subnets.
+const AllowRules6Path = "/etc/coolify/allow6.rules"
+
 // BridgeTableName is the nftables table name owned by the CLI scaffold.
 const BridgeTableName = "coolify_bridge"
 
@@ -54,8 +58,27 @@ const BridgeScaffoldPath = "/etc/coolify/bridge-fw.nft"
 //
 // Both modes preserve the POSTROUTING RETURN rule that prevents podman's
 // MASQUERADE from rewriting container egress to wg0's IP.
+//
+// Rules for IPv6 subnets go through ip6tables; the chain setup is repeated
+// for ip6tables when any subnet is IPv6.
 func FirewallServiceUnit(iface string, namespaces []string, containerSubnets []*net.IPNet, defaultDeny bool) string {
 	var b strings.Builder
+	hasV6 := false
+	for _, sn := range containerSubnets {
+		hasV6 = hasV6 || IsIPv6(sn.IP)
+	}
+	// perSubnet writes an IPv4 rule block for sn in sn's family.
+	perSubnet := func(sn *net.IPNet, format string, args ...any) {
+		b.WriteString(forFamily(fmt.Sprintf(format, args...), IsIPv6(sn.IP)))
+	}
+	// global writes a chain-level block for IPv4 and, with IPv6 subnets,
+	// again for IPv6.
+	global := func(block string) {
+		b.WriteString(block)
+		if hasV6 {
+			b.WriteString(forFamily(block, true))
+		}
+	}
 
 	fmt.Fprintf(&b, `[Unit]
 Description=Coolify mesh firewall rules
@@ -70,7 +93,7 @@ RemainAfterExit=yes
 
 	// POSTROUTING RETURN — needed in both modes, once per subnet.
 	for _, sn := range containerSubnets {
-		fmt.Fprintf(&b,
+		perSubnet(sn,
 			`ExecStart=/bin/sh -c "/usr/sbin/iptables -t nat -C POSTROUTING -s %[2]s -o %[1]s -j RETURN 2>/dev/null || /usr/sbin/iptables -t nat -I POSTROUTING -s %[2]s -o %[1]s -j RETURN"
 `, iface, sn.String())
 	}
@@ -79,21 +102,22 @@ RemainAfterExit=yes
 		fmt.Fprint(&b, `# Tear down default-deny scaffold from prior --default-deny run.
 `)
 		for _, sn := range containerSubnets {
-			fmt.Fprintf(&b,
+			perSubnet(sn,
 				`ExecStart=/bin/sh -c "/usr/sbin/iptables -D FORWARD -d %[1]s -j COOLIFY-INTRA 2>/dev/null || true"
 ExecStart=/bin/sh -c "/usr/sbin/iptables -D FORWARD -s %[1]s -j COOLIFY-INTRA 2>/dev/null || true"
 `, sn.String())
 		}
-		fmt.Fprintf(&b, `ExecStart=/bin/sh -c "/usr/sbin/iptables -F COOLIFY-INTRA 2>/dev/null || true"
+		global(`ExecStart=/bin/sh -c "/usr/sbin/iptables -F COOLIFY-INTRA 2>/dev/null || true"
 ExecStart=/bin/sh -c "/usr/sbin/iptables -X COOLIFY-INTRA 2>/dev/null || true"
-# COOLIFY-ALLOW intentionally NOT removed — preserves runtime allows for re-enable.
+`)
+		fmt.Fprintf(&b, `# COOLIFY-ALLOW intentionally NOT removed — preserves runtime allows for re-enable.
 # Remove bridge-family scaffold (permissive mode) before installing blanket ACCEPT.
 ExecStart=/bin/sh -c "nft delete table bridge %[1]s 2>/dev/null || true"
 
 # Blanket ACCEPT — allow all traffic to/from every namespace's container subnet.
 `, BridgeTableName)
 		for _, sn := range containerSubnets {
-			fmt.Fprintf(&b,
+			perSubnet(sn,
 				`ExecStart=/bin/sh -c "/usr/sbin/iptables -C FORWARD -s %[1]s -j ACCEPT 2>/dev/null || /usr/sbin/iptables -I FORWARD -s %[1]s -j ACCEPT"
 ExecStart=/bin/sh -c "/usr/sbin/iptables -C FORWARD -d %[1]s -j ACCEPT 2>/dev/null || /usr/sbin/iptables -I FORWARD -d %[1]s -j ACCEPT"
 `, sn.String())
@@ -102,12 +126,12 @@ ExecStart=/bin/sh -c "/usr/sbin/iptables -C FORWARD -d %[1]s -j ACCEPT 2>/dev/nu
 		fmt.Fprint(&b, `# Remove blanket ACCEPT from prior mode-A run.
 `)
 		for _, sn := range containerSubnets {
-			fmt.Fprintf(&b,
+			perSubnet(sn,
 				`ExecStart=/bin/sh -c "/usr/sbin/iptables -D FORWARD -s %[1]s -j ACCEPT 2>/dev/null || true"
 ExecStart=/bin/sh -c "/usr/sbin/iptables -D FORWARD -d %[1]s -j ACCEPT 2>/dev/null || true"
 `, sn.String())
 		}
-		fmt.Fprintf(&b, `
+		global(fmt.Sprintf(`
 # Create chains (idempotent).
 ExecStart=/bin/sh -c "/usr/sbin/iptables -N COOLIFY-ALLOW 2>/dev/null || true"
 ExecStart=/bin/sh -c "/usr/sbin/iptables -N COOLIFY-INTRA 2>/dev/null || true"
@@ -127,10 +151,11 @@ ExecStart=/bin/sh -c "[ -s %[1]s ] && /usr/sbin/iptables -F COOLIFY-ALLOW && /us
 # Conntrack early-accept at top of FORWARD (idempotent).
 ExecStart=/bin/sh -c "/usr/sbin/iptables -C FORWARD -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT 2>/dev/null || /usr/sbin/iptables -I FORWARD 1 -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT"
 
-# Top-level FORWARD jumps for every namespace's subnet (both directions).
-`, AllowRulesPath)
+`, AllowRulesPath))
+		b.WriteString(`# Top-level FORWARD jumps for every namespace's subnet (both directions).
+`)
 		for _, sn := range containerSubnets {
-			fmt.Fprintf(&b,
+			perSubnet(sn,
 				`ExecStart=/bin/sh -c "/usr/sbin/iptables -C FORWARD -d %[1]s -j COOLIFY-INTRA 2>/dev/null || /usr/sbin/iptables -A FORWARD -d %[1]s -j COOLIFY-INTRA"
 ExecStart=/bin/sh -c "/usr/sbin/iptables -C FORWARD -s %[1]s -j COOLIFY-INTRA 2>/dev/null || /usr/sbin/iptables -A FORWARD -s %[1]s -j COOLIFY-INTRA"
 `, sn.String())
@@ -154,6 +179,17 @@ WantedBy=multi-user.target
 	return b.String()
 }
 
+// forFamily rewrites an iptables rule block for IPv6 when v6 is set.
+func forFamily(block string, v6 bool) string {
+	if !v6 {
+		return block
+	}
+	return strings.NewReplacer(
+		"/usr/sbin/iptables", "/usr/sbin/ip6tables", // also -restore
+		AllowRulesPath, AllowRules6Path,
+	).Replace(block)
+}
+
 // InstallFirewallCommand returns a shell command that atomically writes the
 // service unit, reloads systemd, and enables/starts (or restarts) it.
 func InstallFirewallCommand(iface string, namespaces []string, containerSubnets []*net.IPNet, defaultDeny bool) string {
@@ -187,20 +223,32 @@ mv %s.tmp %s && `, BridgeScaffoldPath, scaffold, BridgeScaffoldPath, BridgeScaff
 // `add rule` so forward and coolify_intra are atomically replaced on every
 // apply without touching coolify_allow (owned by coold).
 //
-// Dispatch to coolify_intra is keyed on container subnet (ip saddr / ip daddr)
+// Dispatch to coolify_intra is keyed on container subnet (ip/ip6 saddr, daddr)
 // rather than bridge interface name — podman auto-names bridges (e.g. podman2)
 // and the CLI-level "coolify-<ns>-mesh" network name exceeds Linux IFNAMSIZ=16
 // when the kernel sees it anyway. Subnets are disjoint per namespace so this
 // still confines deny to coolify-managed traffic and leaves foreign bridges
 // untouched.
 func renderBridgeScaffold(subnets []*net.IPNet) string {
-	sortedSubnets := make([]string, 0, len(subnets))
+	var v4, v6 []string
 	for _, sn := range subnets {
-		sortedSubnets = append(sortedSubnets, sn.String())
+		if IsIPv6(sn.IP) {
+			v6 = append(v6, sn.String())
+		} else {
+			v4 = append(v4, sn.String())
+		}
 	}
-	sort.Strings(sortedSubnets)
+	sort.Strings(v4)
+	sort.Strings(v6)
 
-	subnetSet := "{ " + strings.Join(sortedSubnets, ", ") + " }"
+	// Non-IP frames (ARP, and IPv6 NDP when no subnet is IPv6) pass.
+	protocols := "ip"
+	switch {
+	case len(v4) > 0 && len(v6) > 0:
+		protocols = "{ ip, ip6 }"
+	case len(v6) > 0:
+		protocols = "ip6"
+	}
 
 	var b strings.Builder
 	b.WriteString("# Managed by coolify init — do not edit manually.\n")
@@ -216,9 +264,23 @@ func renderBridgeScaffold(subnets []*net.IPNet) string {
 	fmt.Fprintf(&b, "add rule bridge %s coolify_intra drop\n", BridgeTableName)
 	fmt.Fprintf(&b, "add chain bridge %s forward { type filter hook forward priority -200; policy accept; }\n", BridgeTableName)
 	fmt.Fprintf(&b, "flush chain bridge %s forward\n", BridgeTableName)
-	fmt.Fprintf(&b, "add rule bridge %s forward meta protocol != ip accept\n", BridgeTableName)
+	fmt.Fprintf(&b, "add rule bridge %s forward meta protocol != %s accept\n", BridgeTableName, protocols)
 	fmt.Fprintf(&b, "add rule bridge %s forward ct state established,related accept\n", BridgeTableName)
-	fmt.Fprintf(&b, "add rule bridge %s forward ip saddr %s jump coolify_intra\n", BridgeTableName, subnetSet)
-	fmt.Fprintf(&b, "add rule bridge %s forward ip daddr %s jump coolify_intra\n", BridgeTableName, subnetSet)
+	// nft rejects an empty set, so each family only gets rules when it has
+	// subnets. IPv6 NDP must reach the bridge peers, so it bypasses the deny.
+	for _, fam := range []struct {
+		name    string
+		subnets []string
+	}{{"ip", v4}, {"ip6", v6}} {
+		if len(fam.subnets) == 0 {
+			continue
+		}
+		if fam.name == "ip6" {
+			fmt.Fprintf(&b, "add rule bridge %s forward icmpv6 type { nd-neighbor-solicit, nd-neighbor-advert, nd-router-solicit, nd-router-advert } accept\n", BridgeTableName)
+		}
+		subnetSet := "{ " + strings.Join(fam.subnets, ", ") + " }"
+		fmt.Fprintf(&b, "add rule bridge %s forward %s saddr %s jump coolify_intra\n", BridgeTableName, fam.name, subnetSet)
+		fmt.Fprintf(&b, "add rule bridge %s forward %s daddr %s jump coolify_intra\n", BridgeTableName, fam.name, subnetSet)
+	}
 	return b.String()
 }
@@ -226,3 +226,23 @@ func TestFirewallServiceUnit_MultipleNamespacesEmitPerSubnetRules(t *testing.T)
 		assert.Contains(t, got, "/usr/sbin/iptables -A FORWARD -s "+sub+" -j COOLIFY-INTRA")
 	}
 }
+
+func TestFirewallServiceUnit_IPv6SubnetsUseIP6tables(t *testing.T) {
+	subnets := []*net.IPNet{
+		mustParseCIDR("10.210.1.0/24"),
+		mustParseCIDR("fd00:210:0:1::/64"),
+	}
+	got := FirewallServiceUnit("wg0", []string{"default"}, subnets, true)
+
+	assert.Contains(t, got, "/usr/sbin/iptables -t nat -I POSTROUTING -s 10.210.1.0/24 -o wg0 -j RETURN")
+	assert.Contains(t, got, "/usr/sbin/ip6tables -t nat -I POSTROUTING -s fd00:210:0:1::/64 -o wg0 -j RETURN")
+	assert.Contains(t, got, "/usr/sbin/ip6tables -A FORWARD -d fd00:210:0:1::/64 -j COOLIFY-INTRA")
+	assert.NotContains(t, got, "/usr/sbin/iptables -A FORWARD -d fd00:210:0:1::/64")
+	assert.Contains(t, got, AllowRules6Path)
+
+	scaffold := renderBridgeScaffold(subnets)
+	assert.Contains(t, scaffold, "meta protocol != { ip, ip6 } accept")
+	assert.Contains(t, scaffold, "forward ip saddr { 10.210.1.0/24 } jump coolify_intra")
+	assert.Contains(t, scaffold, "forward ip6 saddr { fd00:210:0:1::/64 } jump coolify_intra")
+	assert.Contains(t, scaffold, "nd-neighbor-solicit")
+}
@@ -3,6 +3,8 @@ package wireguard
 import (
 	"fmt"
 	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
 )
 
 // actionCategory classifies every ActionType so the intent filter can decide
@@ -42,6 +44,17 @@ const (
 	// prior schema is present (CorrosionSchemaSha256 is empty). Safe
 	// everywhere because nothing gets wiped.
 	catCorrosionSchemaFirstWrite
+
+	// catSchemaMigration: ActionMigrateCorrosionSchema, additive changes
+	// applied to the live DB. Nothing is wiped, but it is part of moving the
+	// mesh to a new CLI release, so existing hosts only get it in upgrade
+	// mode.
+	catSchemaMigration
+
+	// catTeardown: drains or dismantles a host leaving the mesh. Only
+	// planned by the remove-host intent, and only for hosts in RemoveHosts
+	// (plus the corrosion purge, which runs on a survivor).
+	catTeardown
 )
 
 // categorize returns the category for a planned action. The schema action is
@@ -79,17 +92,32 @@ func categorize(a PlannedAction) actionCategory {
 		ActionInstallScheduler,
 		ActionInstallBuilder:
 		return catVersionBump
+	case ActionDrainHost,
+		ActionStopAgents,
+		ActionPurgeCorrosionHost,
+		ActionRemovePodmanNet,
+		ActionTeardownWG:
+		return catTeardown
 	case ActionWriteCorrosionSchema:
 		if strings.Contains(a.Detail, "DB will be reset") {
 			return catWipeDB
 		}
 		return catCorrosionSchemaFirstWrite
+	case ActionMigrateCorrosionSchema:
+		return catSchemaMigration
 	}
 	return catSafeAlways
 }
 
 // ValidateIntent enforces pre-plan invariants the filter itself can't express.
 func ValidateIntent(d *DesiredMesh) error {
+	for _, host := range sortedKeys(d.HostLabels) {
+		for _, l := range d.HostLabels[host] {
+			if err := services.ValidateCapLabel(l); err != nil {
+				return fmt.Errorf("%s: %w", host, err)
+			}
+		}
+	}
 	switch d.Intent {
 	case IntentBootstrap:
 		return nil
@@ -123,6 +151,26 @@ func ValidateIntent(d *DesiredMesh) error {
 			}
 		}
 		return nil
+	case IntentRemoveHost:
+		if len(d.RemoveHosts) == 0 {
+			return fmt.Errorf("remove-host mode requires at least one host in RemoveHosts")
+		}
+		hostSet := make(map[string]struct{}, len(d.Hosts))
+		for _, h := range d.Hosts {
+			hostSet[h] = struct{}{}
+		}
+		for _, rh := range d.RemoveHosts {
+			if _, ok := hostSet[rh]; ok {
+				return fmt.Errorf("remove-host mode: host %q is both removed and kept", rh)
+			}
+			if rh == d.CentralHost {
+				return fmt.Errorf("remove-host mode: %q is the central host running the scheduler and cannot be removed", rh)
+			}
+		}
+		if len(d.Hosts) == 0 {
+			return fmt.Errorf("remove-host mode: at least one host must remain in the mesh")
+		}
+		return nil
 	default:
 		return fmt.Errorf("unknown intent %q", d.Intent)
 	}
@@ -177,20 +225,37 @@ func decide(a PlannedAction, d *DesiredMesh, newHostSet map[string]struct{}) str
 		case catVersionBump:
 			return "extend: version-bump on existing host skipped; use `coolify init upgrade` to bump versions"
 		case catWipeDB:
-			return "extend: corrosion DB wipe on existing host is never allowed; resolve schema drift with `coolify init upgrade` on a fresh schema"
-		case catCorrosionSchemaFirstWrite:
+			return "extend: corrosion DB wipe on existing host is never allowed; the schema is not a revision this CLI can migrate"
+		case catSchemaMigration:
+			return "extend: corrosion schema migration on existing host skipped; use `coolify init upgrade` to migrate"
+		case catCorrosionSchemaFirstWrite, catTeardown:
 			return ""
 		}
-	case IntentUpgrade:
+	case IntentRemoveHost:
+		// Surviving hosts only refresh their peers; the removed hosts only
+		// get teardown actions, which BuildPlan emits for them alone.
 		switch cat {
+		case catSafeAlways, catPeerRefresh, catCorrosionSchemaFirstWrite, catTeardown:
+			return ""
+		case catDestructiveReplace:
+			return "remove-host: destructive-replace on surviving host skipped; use `coolify init extend --allow-replace`"
 		case catVersionBump:
+			return "remove-host: version-bump skipped; use `coolify init upgrade` to bump versions"
+		case catWipeDB:
+			return "remove-host: corrosion DB wipe on surviving host is never allowed"
+		case catSchemaMigration:
+			return "remove-host: corrosion schema migration skipped; use `coolify init upgrade` to migrate"
+		}
+	case IntentUpgrade:
+		switch cat {
+		case catVersionBump, catSchemaMigration:
 			return ""
 		case catPeerRefresh:
 			if isUpgradeServiceRestart(a.Type) {
 				return ""
 			}
 			return "upgrade: peer-refresh skipped; use `coolify init extend` for mesh topology changes"
-		case catSafeAlways, catDestructiveReplace, catWipeDB, catCorrosionSchemaFirstWrite:
+		case catSafeAlways, catDestructiveReplace, catWipeDB, catCorrosionSchemaFirstWrite, catTeardown:
 			return "upgrade: non-version-bump action skipped"
 		}
 	default:
@@ -237,6 +237,21 @@ func TestFilterByIntent_ExtendAllowReplaceUnlocksDestructive(t *testing.T) {
 	assert.Empty(t, plan.Skipped)
 }
 
+func TestFilterByIntent_SchemaMigrationOnlyInUpgrade(t *testing.T) {
+	migrate := PlannedAction{Host: "A-old", Type: ActionMigrateCorrosionSchema, Detail: "coolify.sql v1 → v2"}
+	assert.Equal(t, catSchemaMigration, categorize(migrate))
+
+	plan := &Plan{Actions: []PlannedAction{migrate}}
+	filterByIntent(plan, &DesiredMesh{Intent: IntentUpgrade})
+	assert.Len(t, plan.Actions, 1)
+
+	plan = &Plan{Actions: []PlannedAction{migrate}}
+	filterByIntent(plan, &DesiredMesh{Intent: IntentExtend, NewHosts: []string{"A-new"}})
+	assert.Empty(t, plan.Actions)
+	require.Len(t, plan.Skipped, 1)
+	assert.Contains(t, plan.Skipped[0].Reason, "coolify init upgrade")
+}
+
 func TestFilterByIntent_ExtendAllowReplaceDoesNotUnlockWipeDB(t *testing.T) {
 	plan := &Plan{Actions: []PlannedAction{
 		{Host: "A-old", Type: ActionWriteCorrosionSchema, Detail: "schema drift — DB will be reset"},
@@ -289,3 +304,41 @@ func TestFilterByIntent_UpgradeOnlyKeepsVersionBumpsAndServiceRestarts(t *testin
 		assert.True(t, skippedTypes[want], "expected %s skipped in upgrade", want)
 	}
 }
+
+func TestValidateIntent_RemoveHost(t *testing.T) {
+	require.Error(t, ValidateIntent(&DesiredMesh{Intent: IntentRemoveHost, Hosts: []string{"A"}}))
+
+	err := ValidateIntent(&DesiredMesh{Intent: IntentRemoveHost, Hosts: []string{"A", "B"}, RemoveHosts: []string{"B"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "both removed and kept")
+
+	err = ValidateIntent(&DesiredMesh{Intent: IntentRemoveHost, Hosts: []string{"A"}, RemoveHosts: []string{"B"}, CentralHost: "B"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "central")
+
+	err = ValidateIntent(&DesiredMesh{Intent: IntentRemoveHost, RemoveHosts: []string{"B"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "at least one host must remain")
+
+	require.NoError(t, ValidateIntent(&DesiredMesh{Intent: IntentRemoveHost, Hosts: []string{"A"}, RemoveHosts: []string{"B"}}))
+}
+
+func TestFilterByIntent_RemoveHostSurvivorsPeerRefreshOnly(t *testing.T) {
+	plan := &Plan{Actions: []PlannedAction{
+		{Host: "B", Type: ActionDrainHost},
+		{Host: "B", Type: ActionStopAgents},
+		{Host: "A", Type: ActionRemovePeer},
+		{Host: "A", Type: ActionWriteConfig},
+		{Host: "A", Type: ActionWriteCorrosionConfig},
+		{Host: "A", Type: ActionInstallCoold},      // version bump: skipped
+		{Host: "A", Type: ActionRecreatePodmanNet}, // destructive: skipped
+		{Host: "A", Type: ActionPurgeCorrosionHost},
+		{Host: "B", Type: ActionTeardownWG},
+	}}
+	filterByIntent(plan, &DesiredMesh{Intent: IntentRemoveHost, Hosts: []string{"A"}, RemoveHosts: []string{"B"}})
+
+	assert.Len(t, plan.Actions, 7)
+	require.Len(t, plan.Skipped, 2)
+	assert.Equal(t, ActionInstallCoold, plan.Skipped[0].Action.Type)
+	assert.Equal(t, ActionRecreatePodmanNet, plan.Skipped[1].Action.Type)
+}
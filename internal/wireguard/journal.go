@@ -0,0 +1,337 @@
+package wireguard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// RemoteJournalDir holds the file backups of the last journaled apply on
+// every host, one directory per run. Backups stay on the host so secrets
+// such as the WireGuard private key in wg0.conf never cross SSH.
+const RemoteJournalDir = "/var/lib/coolify/journal"
+
+// ActionRollback is the result type of a rolled back host.
+const ActionRollback ActionType = "rollback"
+
+// Journal records, per host, the files and unit states that apply's
+// reversible actions are about to change, so a failed run can be undone.
+// Attach it to ApplyMesh; each reversible step is preceded by a snapshot.
+type Journal struct {
+	RunID     string         `json:"run_id"`
+	Interface string         `json:"interface"`
+	StartedAt time.Time      `json:"started_at"`
+	Entries   []JournalEntry `json:"entries"`
+
+	mu       sync.Mutex
+	seq      int
+	prepared map[string]bool
+}
+
+// JournalEntry is the snapshot taken on Host before Action ran.
+type JournalEntry struct {
+	Seq    int           `json:"seq"`
+	Host   string        `json:"host"`
+	Action ActionType    `json:"action"`
+	Files  []JournalFile `json:"files,omitempty"`
+	Units  []JournalUnit `json:"units,omitempty"`
+}
+
+// JournalFile is one file backed up to Backup on the host. Existed is false
+// when the action created it; rollback then deletes it.
+type JournalFile struct {
+	Path    string `json:"path"`
+	Backup  string `json:"backup,omitempty"`
+	Existed bool   `json:"existed"`
+}
+
+// JournalUnit is a systemd unit's state before the action.
+type JournalUnit struct {
+	Name    string `json:"name"`
+	Active  string `json:"active"`  // systemctl is-active
+	Enabled string `json:"enabled"` // systemctl is-enabled
+}
+
+// NewJournal starts a journal for one apply run.
+func NewJournal(iface string) *Journal {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	now := time.Now().UTC()
+	return &Journal{
+		RunID:     now.Format("20060102T150405Z") + "-" + hex.EncodeToString(b),
+		Interface: iface,
+		StartedAt: now,
+	}
+}
+
+// journaledRunner is the runner ApplyMesh hands to its phases when a
+// journal is attached; runStep snapshots through it before every action.
+type journaledRunner struct {
+	ssh.Runner
+	journal *Journal
+}
+
+// reversibleTargets returns the files and units action a rewrites. Actions
+// not listed (package installs, key generation, bridge creation, DB wipes,
+// schema writes and migrations, which are forward-only) are not rolled back.
+func reversibleTargets(a ActionType, iface string) (files, units []string) {
+	switch a {
+	case ActionWriteConfig:
+		return []string{fmt.Sprintf("/etc/wireguard/%s.conf", iface)}, []string{"wg-quick@" + iface}
+	case ActionInstallFirewall:
+		return []string{firewallUnitPath, BridgeScaffoldPath}, []string{firewallServiceName}
+	case ActionWriteCorrosionConfig:
+		return []string{"/etc/corrosion/config.toml"}, []string{"corrosion"}
+	case ActionInstallCorrosionService:
+		return []string{"/etc/systemd/system/corrosion.service", "/etc/systemd/system/coold.service"},
+			[]string{"corrosion", "coold"}
+	case ActionWriteHostJWT:
+		return []string{services.HostJWTPath}, nil
+	case ActionUpdateCooldSchedulerEnv:
+		return []string{"/etc/systemd/system/coold.service"}, []string{"coold"}
+	}
+	return nil, nil
+}
+
+// snapshot backs up what action a is about to change on host. It is a no-op
+// for actions rollback cannot undo. The first snapshot on a host clears the
+// backups of earlier runs.
+func (j *Journal) snapshot(ctx context.Context, runner ssh.Runner, host, user string, port int, a ActionType) error {
+	files, units := reversibleTargets(a, j.Interface)
+	if len(files) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	j.seq++
+	seq := j.seq
+	fresh := !j.prepared[host]
+	if j.prepared == nil {
+		j.prepared = map[string]bool{}
+	}
+	j.prepared[host] = true
+	j.mu.Unlock()
+
+	dir := fmt.Sprintf("%s/%s", RemoteJournalDir, j.RunID)
+	var sb strings.Builder
+	if fresh {
+		fmt.Fprintf(&sb, "rm -rf %s && ", RemoteJournalDir)
+	}
+	fmt.Fprintf(&sb, "mkdir -p -m 700 %s && ", dir)
+	entry := JournalEntry{Seq: seq, Host: host, Action: a}
+	for i, f := range files {
+		backup := fmt.Sprintf("%s/%d-%d", dir, seq, i)
+		entry.Files = append(entry.Files, JournalFile{Path: f, Backup: backup})
+		fmt.Fprintf(&sb, "if [ -e %[1]s ]; then cp -p %[1]s %[2]s && echo 'file %[3]d 1'; else echo 'file %[3]d 0'; fi && ", f, backup, i)
+	}
+	for i, u := range units {
+		entry.Units = append(entry.Units, JournalUnit{Name: u})
+		fmt.Fprintf(&sb, "echo \"unit %[1]d $(systemctl is-active %[2]s 2>/dev/null || true) $(systemctl is-enabled %[2]s 2>/dev/null || true)\" && ", i, u)
+	}
+	sb.WriteString("true")
+
+	stdout, stderr, err := runner.Run(ctx, host, user, port, sb.String())
+	if err != nil {
+		return fmt.Errorf("journal snapshot before %s: %w (%s)", a, err, firstLine(stderr))
+	}
+	for _, line := range nonEmptyLines(stdout) {
+		f := strings.Fields(line)
+		if len(f) < 3 {
+			continue
+		}
+		var idx int
+		if _, err := fmt.Sscanf(f[1], "%d", &idx); err != nil {
+			continue
+		}
+		switch {
+		case f[0] == "file" && idx < len(entry.Files):
+			entry.Files[idx].Existed = f[2] == "1"
+			if !entry.Files[idx].Existed {
+				entry.Files[idx].Backup = ""
+			}
+		case f[0] == "unit" && idx < len(entry.Units):
+			entry.Units[idx].Active = f[2]
+			if len(f) > 3 {
+				entry.Units[idx].Enabled = f[3]
+			}
+		}
+	}
+
+	j.mu.Lock()
+	j.Entries = append(j.Entries, entry)
+	j.mu.Unlock()
+	return nil
+}
+
+// Hosts returns the hosts with journal entries, sorted.
+func (j *Journal) Hosts() []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, e := range j.Entries {
+		if !seen[e.Host] {
+			seen[e.Host] = true
+			hosts = append(hosts, e.Host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// rollbackCmd restores host's journaled files newest first, so each file
+// ends up with its content from before the run, then returns every touched
+// unit to its earliest recorded state.
+func (j *Journal) rollbackCmd(host string) string {
+	var entries []JournalEntry
+	for _, e := range j.Entries {
+		if e.Host == host {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Seq > entries[b].Seq })
+
+	var steps []string
+	units := map[string]JournalUnit{}
+	var unitOrder []string
+	for _, e := range entries {
+		for _, f := range e.Files {
+			if f.Existed {
+				steps = append(steps, fmt.Sprintf("cp -p %s %s", f.Backup, f.Path))
+			} else {
+				steps = append(steps, fmt.Sprintf("rm -f %s", f.Path))
+			}
+		}
+		for _, u := range e.Units {
+			if _, ok := units[u.Name]; !ok {
+				unitOrder = append(unitOrder, u.Name)
+			}
+			// Entries run newest first, so the last write wins with the
+			// state from before the run.
+			units[u.Name] = u
+		}
+	}
+	steps = append(steps, "systemctl daemon-reload")
+
+	// Units last touched by the run go first.
+	for _, name := range unitOrder {
+		u := units[name]
+		switch u.Enabled {
+		case "enabled":
+			steps = append(steps, fmt.Sprintf("systemctl enable %s", u.Name))
+		case "disabled", "":
+			steps = append(steps, fmt.Sprintf("{ systemctl disable %s 2>/dev/null || true; }", u.Name))
+		}
+		if u.Active == "active" {
+			steps = append(steps, fmt.Sprintf("systemctl restart %s", u.Name))
+		} else {
+			steps = append(steps, fmt.Sprintf("{ systemctl stop %s 2>/dev/null || true; }", u.Name))
+		}
+	}
+	return strings.Join(steps, " && ")
+}
+
+// Rollback restores hosts (all journaled hosts when empty) to their state
+// before the run, in parallel. Hosts without journal entries are skipped.
+func (j *Journal) Rollback(
+	ctx context.Context,
+	runner ssh.Runner,
+	user string,
+	port int,
+	hosts []string,
+	concurrency int,
+) ([]ActionResult, error) {
+	if len(hosts) == 0 {
+		hosts = j.Hosts()
+	}
+	var targets []string
+	cmds := map[string]string{}
+	for _, h := range hosts {
+		if cmd := j.rollbackCmd(h); cmd != "" {
+			targets = append(targets, h)
+			cmds[h] = cmd
+		}
+	}
+
+	rs := ssh.ForEachServer(ctx, targets, concurrency,
+		func(ctx context.Context, host string) ([]ActionResult, error) {
+			var out []ActionResult
+			err := runStep(ctx, runner, host, user, port, &out, ActionRollback, "", cmds[host],
+				fmt.Sprintf("roll back %s", host))
+			if err == nil {
+				out[0].Action.Detail = fmt.Sprintf("restored run %s", j.RunID)
+			}
+			return out, err
+		})
+
+	var results []ActionResult
+	var failed []string
+	for _, r := range rs {
+		results = append(results, r.Result...)
+		if r.Err != nil {
+			failed = append(failed, r.Host)
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("rollback failed on %s", strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+// FailedHosts returns the hosts with a failed action in results.
+func FailedHosts(results []ActionResult) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, r := range results {
+		if r.Err != nil && !seen[r.Action.Host] {
+			seen[r.Action.Host] = true
+			hosts = append(hosts, r.Action.Host)
+		}
+	}
+	return hosts
+}
+
+// LoadJournal reads a journal written by Save. A missing file returns nil.
+func LoadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read apply journal: %w", err)
+	}
+	j := &Journal{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("parse apply journal %s: %w", path, err)
+	}
+	return j, nil
+}
+
+// Save writes the journal to path.
+func (j *Journal) Save(path string) error {
+	j.mu.Lock()
+	data, err := json.MarshalIndent(j, "", "  ")
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create journal directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write apply journal: %w", err)
+	}
+	return nil
+}
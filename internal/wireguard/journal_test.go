@@ -0,0 +1,145 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// journalRunner answers snapshot commands with snapshotOut and records
+// everything else.
+type journalRunner struct {
+	mu          sync.Mutex
+	cmds        []string
+	snapshotOut string
+	snapshotErr error
+}
+
+func (r *journalRunner) Run(_ context.Context, _, _ string, _ int, cmd string) (string, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cmds = append(r.cmds, cmd)
+	if strings.Contains(cmd, RemoteJournalDir) {
+		return r.snapshotOut, "", r.snapshotErr
+	}
+	return "", "", nil
+}
+
+func TestJournalSnapshot_RecordsFilesAndUnits(t *testing.T) {
+	j := NewJournal("wg0")
+	runner := &journalRunner{snapshotOut: "file 0 1\nunit 0 active enabled\n"}
+
+	require.NoError(t, j.snapshot(context.Background(), runner, "1.1.1.1", "root", 22, ActionWriteConfig))
+	require.NoError(t, j.snapshot(context.Background(), runner, "1.1.1.1", "root", 22, ActionInstallWG))
+	runner.snapshotOut = "file 0 0\n"
+	require.NoError(t, j.snapshot(context.Background(), runner, "1.1.1.1", "root", 22, ActionWriteHostJWT))
+
+	require.Len(t, runner.cmds, 2, "irreversible actions are not snapshotted")
+	assert.Contains(t, runner.cmds[0], "rm -rf "+RemoteJournalDir, "first snapshot clears older runs")
+	assert.NotContains(t, runner.cmds[1], "rm -rf")
+
+	require.Len(t, j.Entries, 2)
+	assert.Equal(t, []JournalFile{{Path: "/etc/wireguard/wg0.conf", Backup: RemoteJournalDir + "/" + j.RunID + "/1-0", Existed: true}}, j.Entries[0].Files)
+	assert.Equal(t, []JournalUnit{{Name: "wg-quick@wg0", Active: "active", Enabled: "enabled"}}, j.Entries[0].Units)
+	assert.False(t, j.Entries[1].Files[0].Existed)
+	assert.Empty(t, j.Entries[1].Files[0].Backup)
+}
+
+func TestJournalRollbackCmd_RestoresNewestFirstAndEarliestUnitState(t *testing.T) {
+	j := &Journal{RunID: "r", Interface: "wg0", Entries: []JournalEntry{
+		{Seq: 1, Host: "1.1.1.1", Action: ActionInstallCorrosionService,
+			Files: []JournalFile{{Path: "/etc/systemd/system/coold.service", Backup: "/b/1-0", Existed: true}},
+			Units: []JournalUnit{{Name: "coold", Active: "inactive", Enabled: "disabled"}}},
+		{Seq: 2, Host: "2.2.2.2", Action: ActionWriteConfig,
+			Files: []JournalFile{{Path: "/etc/wireguard/wg0.conf", Backup: "/b/2-0", Existed: true}}},
+		{Seq: 3, Host: "1.1.1.1", Action: ActionUpdateCooldSchedulerEnv,
+			Files: []JournalFile{{Path: "/etc/systemd/system/coold.service", Backup: "/b/3-0", Existed: true}},
+			Units: []JournalUnit{{Name: "coold", Active: "active", Enabled: "enabled"}}},
+		{Seq: 4, Host: "1.1.1.1", Action: ActionWriteHostJWT,
+			Files: []JournalFile{{Path: "/etc/coolify/host-jwt"}}},
+	}}
+
+	got := j.rollbackCmd("1.1.1.1")
+	assert.Equal(t, strings.Join([]string{
+		"rm -f /etc/coolify/host-jwt",
+		"cp -p /b/3-0 /etc/systemd/system/coold.service",
+		"cp -p /b/1-0 /etc/systemd/system/coold.service",
+		"systemctl daemon-reload",
+		"{ systemctl disable coold 2>/dev/null || true; }",
+		"{ systemctl stop coold 2>/dev/null || true; }",
+	}, " && "), got)
+	assert.NotContains(t, got, "wg0.conf")
+	assert.Empty(t, j.rollbackCmd("9.9.9.9"))
+	assert.Equal(t, []string{"1.1.1.1", "2.2.2.2"}, j.Hosts())
+}
+
+func TestRunStep_SnapshotsBeforeReversibleActions(t *testing.T) {
+	j := NewJournal("wg0")
+	inner := &journalRunner{snapshotOut: "file 0 1\n"}
+	runner := journaledRunner{Runner: inner, journal: j}
+
+	var out []ActionResult
+	require.NoError(t, runStep(context.Background(), runner, "1.1.1.1", "root", 22, &out,
+		ActionWriteConfig, "", "write wg0", "write config"))
+	require.Len(t, inner.cmds, 2)
+	assert.Contains(t, inner.cmds[0], RemoteJournalDir)
+	assert.Equal(t, "write wg0", inner.cmds[1])
+
+	inner.snapshotErr = errors.New("exit status 1")
+	err := runStep(context.Background(), runner, "1.1.1.1", "root", 22, &out,
+		ActionWriteConfig, "", "write wg0 again", "write config")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "journal snapshot")
+	assert.NotContains(t, inner.cmds, "write wg0 again", "the action does not run without a backup")
+}
+
+func TestJournalRollback_OnlyRequestedHosts(t *testing.T) {
+	j := &Journal{RunID: "r", Entries: []JournalEntry{
+		{Seq: 1, Host: "1.1.1.1", Action: ActionWriteConfig, Files: []JournalFile{{Path: "/a", Backup: "/b/1-0", Existed: true}}},
+		{Seq: 2, Host: "2.2.2.2", Action: ActionWriteConfig, Files: []JournalFile{{Path: "/a", Backup: "/b/2-0", Existed: true}}},
+	}}
+	runner := &recordingRunner{}
+
+	results, err := j.Rollback(context.Background(), runner, "root", 22, []string{"2.2.2.2"}, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, ActionRollback, results[0].Action.Type)
+	assert.Empty(t, runner.calls["1.1.1.1"])
+	require.Len(t, runner.calls["2.2.2.2"], 1)
+	assert.Contains(t, runner.calls["2.2.2.2"][0], "cp -p /b/2-0 /a")
+
+	runner.failOn = "cp -p"
+	_, err = j.Rollback(context.Background(), runner, "root", 22, nil, 2)
+	assert.ErrorContains(t, err, "rollback failed on 1.1.1.1, 2.2.2.2")
+}
+
+func TestJournalSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "mesh-journal.json")
+	missing, err := LoadJournal(path)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	j := &Journal{RunID: "r", Interface: "wg0", Entries: []JournalEntry{
+		{Seq: 1, Host: "1.1.1.1", Action: ActionWriteConfig, Files: []JournalFile{{Path: "/a", Backup: "/b", Existed: true}}},
+	}}
+	require.NoError(t, j.Save(path))
+	got, err := LoadJournal(path)
+	require.NoError(t, err)
+	assert.Equal(t, j.Entries, got.Entries)
+	assert.Equal(t, "wg0", got.Interface)
+}
+
+func TestFailedHosts(t *testing.T) {
+	results := []ActionResult{
+		{Action: PlannedAction{Host: "1.1.1.1"}},
+		{Action: PlannedAction{Host: "2.2.2.2"}, Err: errors.New("x")},
+		{Action: PlannedAction{Host: "2.2.2.2"}, Err: errors.New("y")},
+	}
+	assert.Equal(t, []string{"2.2.2.2"}, FailedHosts(results))
+}
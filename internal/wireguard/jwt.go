@@ -0,0 +1,500 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// DefaultJWTExpiryWarning is how close to expiry a host JWT starts to warn.
+const DefaultJWTExpiryWarning = 30 * 24 * time.Hour
+
+// Host JWT statuses reported by HostJWT.Evaluate.
+const (
+	JWTStatusOK         = "ok"
+	JWTStatusExpiring   = "expiring"
+	JWTStatusExpired    = "expired"
+	JWTStatusRevoked    = "revoked"
+	JWTStatusUntrusted  = "untrusted"
+	JWTStatusMissing    = "missing"
+	JWTStatusUnreadable = "unreadable"
+)
+
+// JWTTrust is what the scheduler on the central host accepts host JWTs by.
+type JWTTrust struct {
+	// Keys are the trusted public keys, current key first.
+	Keys        []services.JWTPublicKey
+	Revocations []services.JWTRevocation
+}
+
+// jwtRevokedMarker separates the key set from the revocations in the
+// output of ReadJWTTrust's command.
+const jwtRevokedMarker = "__COOLIFY_JWT_REVOKED__"
+
+// ReadJWTTrust reads the key set and revocations from the central host.
+// Centrals set up before key sets existed only have the current key.
+func ReadJWTTrust(ctx context.Context, runner ssh.Runner, central, user string, port int) (*JWTTrust, error) {
+	cmd := fmt.Sprintf(`cat %s 2>/dev/null || cat %s; echo %s; cat %s 2>/dev/null || true`,
+		services.SchedulerJWTPubSetPath, services.SchedulerJWTPubPath,
+		jwtRevokedMarker, services.SchedulerJWTRevokedPath)
+	stdout, stderr, err := runner.Run(ctx, central, user, port, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("read JWT keys on %s: %w (stderr: %s)", central, err, strings.TrimSpace(stderr))
+	}
+	keysPart, revokedPart, ok := strings.Cut(stdout, jwtRevokedMarker+"\n")
+	if !ok {
+		return nil, fmt.Errorf("read JWT keys on %s: truncated output", central)
+	}
+	keys, err := services.ParseJWTKeySet([]byte(keysPart))
+	if err != nil {
+		return nil, fmt.Errorf("JWT keys on %s: %w", central, err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no JWT public key on %s; is it the --central host?", central)
+	}
+	revs, err := services.ParseJWTRevocations([]byte(revokedPart))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", central, err)
+	}
+	return &JWTTrust{Keys: keys, Revocations: revs}, nil
+}
+
+// ReadJWTRevocations reads the revocations from the central host. A central
+// without the file has none.
+func ReadJWTRevocations(ctx context.Context, runner ssh.Runner, central, user string, port int) ([]services.JWTRevocation, error) {
+	stdout, stderr, err := runner.Run(ctx, central, user, port,
+		"cat "+services.SchedulerJWTRevokedPath+" 2>/dev/null || true")
+	if err != nil {
+		return nil, fmt.Errorf("read JWT revocations on %s: %w (stderr: %s)", central, err, strings.TrimSpace(stderr))
+	}
+	revs, err := services.ParseJWTRevocations([]byte(stdout))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", central, err)
+	}
+	return revs, nil
+}
+
+// checkSchedulerJWTKeySet fails unless the scheduler on central reads the
+// key set and revocations (see services.MinSchedulerJWTKeySetVersion).
+func checkSchedulerJWTKeySet(ctx context.Context, runner ssh.Runner, central, user string, port int) error {
+	stdout, stderr, err := runner.Run(ctx, central, user, port,
+		"cat "+services.SchedulerVersionPath+" 2>/dev/null || true")
+	if err != nil {
+		return fmt.Errorf("read scheduler version on %s: %w (stderr: %s)", central, err, strings.TrimSpace(stderr))
+	}
+	v := strings.TrimSpace(stdout)
+	if services.SchedulerSupportsJWTKeySet(v) {
+		return nil
+	}
+	if v == "" {
+		v = "of unknown version"
+	}
+	return fmt.Errorf("scheduler %s on %s does not read the JWT key set or revocations (needs %s or later); run `coolify init upgrade --scheduler-version <tag>` first",
+		v, central, services.MinSchedulerJWTKeySetVersion)
+}
+
+// Revoked reports whether a revocation rejects a token with claims c.
+func (t *JWTTrust) Revoked(c *services.HostJWTClaims) bool {
+	for _, r := range t.Revocations {
+		if r.Revokes(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostJWT is the token installed on one host.
+type HostJWT struct {
+	Host  string
+	Token string
+	// Claims is nil when the host has no token.
+	Claims *services.HostJWTClaims
+	// SignedBy is the ID of the trusted key that signed Token; empty when
+	// trust was not checked or no trusted key did.
+	SignedBy string
+	Err      error
+}
+
+// ReadHostJWTs reads and decodes the token on every host. With trust, each
+// token's signature is checked against the trusted keys.
+func ReadHostJWTs(
+	ctx context.Context,
+	runner ssh.Runner,
+	hosts []string,
+	user string,
+	port, concurrency int,
+	trust *JWTTrust,
+) []HostJWT {
+	results := ssh.ForEachServer(ctx, hosts, concurrency,
+		func(ctx context.Context, host string) (HostJWT, error) {
+			h := HostJWT{Host: host}
+			stdout, stderr, err := runner.Run(ctx, host, user, port,
+				fmt.Sprintf(`cat %s 2>/dev/null || true`, services.HostJWTPath))
+			if err != nil {
+				return h, fmt.Errorf("%w (stderr: %s)", err, strings.TrimSpace(stderr))
+			}
+			h.Token = strings.TrimSpace(stdout)
+			if h.Token == "" {
+				return h, nil
+			}
+			if h.Claims, err = services.ParseHostJWT(h.Token); err != nil {
+				return h, err
+			}
+			if trust != nil {
+				h.SignedBy = services.VerifyHostJWT(h.Token, trust.Keys)
+			}
+			return h, nil
+		})
+	out := make([]HostJWT, len(results))
+	for i, r := range results {
+		out[i] = r.Result
+		out[i].Host = r.Host
+		out[i].Err = r.Err
+	}
+	return out
+}
+
+// JWTCheck is the verdict on one host's token.
+type JWTCheck struct {
+	Status   string
+	Severity Severity
+	// Message explains a non-OK status.
+	Message string
+}
+
+// Evaluate grades h at now. A token expiring within warnWithin is a
+// warning. trust may be nil, which skips the signature and revocation
+// checks.
+func (h HostJWT) Evaluate(trust *JWTTrust, now time.Time, warnWithin time.Duration) JWTCheck {
+	switch {
+	case h.Err != nil:
+		return JWTCheck{JWTStatusUnreadable, SeverityUnknown, fmt.Sprintf("read host JWT: %v", h.Err)}
+	case h.Claims == nil:
+		return JWTCheck{JWTStatusMissing, SeverityWarning, "no host JWT; coold cannot reach the scheduler"}
+	case trust != nil && h.SignedBy == "":
+		return JWTCheck{JWTStatusUntrusted, SeverityCritical, "signed by a key the scheduler does not trust"}
+	case trust != nil && trust.Revoked(h.Claims):
+		return JWTCheck{JWTStatusRevoked, SeverityCritical, "revoked"}
+	case !h.Claims.ExpiresAt.After(now):
+		return JWTCheck{JWTStatusExpired, SeverityCritical,
+			fmt.Sprintf("expired %s ago", roundDays(now.Sub(h.Claims.ExpiresAt)))}
+	case h.Claims.ExpiresAt.Sub(now) < warnWithin:
+		return JWTCheck{JWTStatusExpiring, SeverityWarning,
+			fmt.Sprintf("expires in %s", roundDays(h.Claims.ExpiresAt.Sub(now)))}
+	}
+	return JWTCheck{Status: JWTStatusOK, Severity: SeverityOK}
+}
+
+// roundDays renders d in whole days, or hours under two days.
+func roundDays(d time.Duration) string {
+	if d < 48*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// JWTRotateOptions configures RotateHostJWTs.
+type JWTRotateOptions struct {
+	// Hosts get a fresh token. Each must already hold one: its subject and
+	// caps are kept.
+	Hosts []string
+
+	// NewKey replaces the central keypair first. The previous public key
+	// stays trusted until no host's token uses it.
+	NewKey bool
+}
+
+// PlanJWTRotation validates a rotation against the tokens read from the
+// mesh and returns the steps RotateHostJWTs runs.
+func PlanJWTRotation(d *DesiredMesh, trust *JWTTrust, tokens []HostJWT, opts JWTRotateOptions) ([]PlannedAction, error) {
+	if d.CentralHost == "" {
+		return nil, fmt.Errorf("rotating host JWTs needs the --central host")
+	}
+	if len(opts.Hosts) == 0 {
+		return nil, fmt.Errorf("no hosts to re-issue")
+	}
+	byHost := make(map[string]HostJWT, len(tokens))
+	for _, t := range tokens {
+		byHost[t.Host] = t
+	}
+
+	var actions []PlannedAction
+	if opts.NewKey {
+		actions = append(actions, PlannedAction{
+			Host: d.CentralHost, Type: ActionRotateJWTKeypair,
+			Detail: "generate a new ES256 keypair; the current public key stays trusted",
+		})
+	}
+	seen := map[string]bool{}
+	var lifted []string
+	for _, h := range opts.Hosts {
+		t, ok := byHost[h]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("%s is not in the mesh", h)
+		case seen[h]:
+			return nil, fmt.Errorf("%s listed twice", h)
+		case t.Err != nil:
+			return nil, fmt.Errorf("%s: %w", h, t.Err)
+		case t.Claims == nil:
+			return nil, fmt.Errorf("%s has no host JWT to re-issue; run `coolify init bootstrap --central %s`", h, d.CentralHost)
+		}
+		seen[h] = true
+		actions = append(actions, PlannedAction{
+			Host: h, Type: ActionWriteHostJWT,
+			Detail: fmt.Sprintf("re-issue sub=%s caps=%s, restart coold", t.Claims.Subject, strings.Join(t.Claims.Caps, ",")),
+		})
+		for _, r := range trust.Revocations {
+			if r.Subject == t.Claims.Subject {
+				lifted = append(lifted, h)
+				break
+			}
+		}
+	}
+	if len(lifted) > 0 {
+		actions = append(actions, PlannedAction{
+			Host: d.CentralHost, Type: ActionLiftJWTRevocation,
+			Detail: "lift the revocation of " + strings.Join(lifted, ", "),
+		})
+	}
+	if opts.NewKey || len(trust.Keys) > 1 {
+		actions = append(actions, PlannedAction{
+			Host: d.CentralHost, Type: ActionRetireJWTKeys,
+			Detail: "stop trusting keys no host's token uses",
+		})
+	}
+	return actions, nil
+}
+
+// RotateHostJWTs re-issues the tokens of opts.Hosts, keeping their subject
+// and caps, and restarts coold on each. tokens are the ones read from every
+// host in d.Hosts before the rotation. Once every host has been re-issued,
+// their revocations are lifted and the keys no unexpired token on the mesh
+// uses any more are retired. With opts.NewKey the scheduler must read the
+// key set (see services.MinSchedulerJWTKeySetVersion).
+func RotateHostJWTs(
+	ctx context.Context,
+	runner ssh.Runner,
+	user string,
+	port int,
+	d *DesiredMesh,
+	tokens []HostJWT,
+	opts JWTRotateOptions,
+	concurrency int,
+) ([]ActionResult, error) {
+	var out []ActionResult
+	central := d.CentralHost
+
+	if opts.NewKey {
+		if err := checkSchedulerJWTKeySet(ctx, runner, central, user, port); err != nil {
+			return out, err
+		}
+		if err := runStep(ctx, runner, central, user, port, &out,
+			ActionRotateJWTKeypair, "", services.RotateJWTKeypairCommand(),
+			fmt.Sprintf("rotate JWT keypair on %s", central)); err != nil {
+			return out, err
+		}
+	}
+	privKeyPEM, stderr, err := runner.Run(ctx, central, user, port, "cat "+services.SchedulerJWTPrivPath)
+	if err != nil {
+		return out, fmt.Errorf("read jwt.priv from central %s: %w (stderr: %s)", central, err, strings.TrimSpace(stderr))
+	}
+
+	claims := make(map[string]*services.HostJWTClaims, len(tokens))
+	for _, t := range tokens {
+		claims[t.Host] = t.Claims
+	}
+	results := ssh.ForEachServer(ctx, opts.Hosts, concurrency,
+		func(ctx context.Context, host string) ([]ActionResult, error) {
+			var res []ActionResult
+			c := claims[host]
+			if c == nil {
+				return res, fmt.Errorf("%s has no host JWT to re-issue", host)
+			}
+			token, err := services.MintHostJWT([]byte(privKeyPEM), c.Subject, c.Caps)
+			if err != nil {
+				return res, fmt.Errorf("mint JWT for %s: %w", host, err)
+			}
+			err = runStep(ctx, runner, host, user, port, &res,
+				ActionWriteHostJWT, "", writeHostJWTCommand(token)+` && systemctl try-restart coold`,
+				fmt.Sprintf("write host JWT on %s", host))
+			return res, err
+		})
+	var failed bool
+	for _, r := range results {
+		out = append(out, r.Result...)
+		if r.Err != nil {
+			failed = true
+			if len(r.Result) == 0 {
+				out = append(out, ActionResult{
+					Action: PlannedAction{Host: r.Host, Type: ActionWriteHostJWT, Detail: r.Err.Error()},
+					Err:    r.Err,
+				})
+			}
+		}
+	}
+	if failed {
+		return out, fmt.Errorf("re-issue failed on one or more hosts; earlier keys stay trusted")
+	}
+
+	lifted, err := liftJWTRevocations(ctx, runner, user, port, central, claims, opts.Hosts)
+	out = append(out, lifted...)
+	if err != nil {
+		return out, err
+	}
+
+	retired, err := retireJWTKeys(ctx, runner, user, port, d, concurrency)
+	out = append(out, retired...)
+	return out, err
+}
+
+// liftJWTRevocations drops the revocations of the re-issued hosts' subjects
+// so that apply and caps issue them tokens again.
+func liftJWTRevocations(
+	ctx context.Context,
+	runner ssh.Runner,
+	user string,
+	port int,
+	central string,
+	claims map[string]*services.HostJWTClaims,
+	hosts []string,
+) ([]ActionResult, error) {
+	var out []ActionResult
+	revs, err := ReadJWTRevocations(ctx, runner, central, user, port)
+	if err != nil {
+		return out, err
+	}
+	subjects := map[string]string{}
+	for _, h := range hosts {
+		if c := claims[h]; c != nil {
+			subjects[c.Subject] = h
+		}
+	}
+	keep := []services.JWTRevocation{}
+	var lifted []string
+	for _, r := range revs {
+		if h, ok := subjects[r.Subject]; ok {
+			lifted = append(lifted, h)
+			continue
+		}
+		keep = append(keep, r)
+	}
+	if len(lifted) == 0 {
+		return out, nil
+	}
+	data, err := services.EncodeJWTRevocations(keep)
+	if err != nil {
+		return out, err
+	}
+	cmd := heredocWrite(services.SchedulerJWTRevokedPath, string(data), "COOLIFY_JWT_REVOKED_EOF", 0o644) +
+		" && systemctl try-restart scheduler"
+	err = runStep(ctx, runner, central, user, port, &out,
+		ActionLiftJWTRevocation, "", cmd,
+		fmt.Sprintf("lift JWT revocation of %s on %s", strings.Join(lifted, ", "), central))
+	return out, err
+}
+
+// retireJWTKeys drops the trusted keys that no unexpired token on the mesh
+// uses, always keeping the current key. Nothing is retired while a host's
+// token cannot be read.
+func retireJWTKeys(ctx context.Context, runner ssh.Runner, user string, port int, d *DesiredMesh, concurrency int) ([]ActionResult, error) {
+	var out []ActionResult
+	central := d.CentralHost
+	trust, err := ReadJWTTrust(ctx, runner, central, user, port)
+	if err != nil {
+		return out, err
+	}
+	tokens := ReadHostJWTs(ctx, runner, d.Hosts, user, port, concurrency, trust)
+	keep, err := jwtKeysInUse(trust, tokens, time.Now())
+	if err != nil {
+		out = append(out, ActionResult{Action: PlannedAction{
+			Host: central, Type: ActionRetireJWTKeys, Detail: "kept every key: " + err.Error(),
+		}})
+		return out, nil
+	}
+	if len(keep) == len(trust.Keys) {
+		return out, nil
+	}
+	data, err := services.EncodeJWTKeySet(keep)
+	if err != nil {
+		return out, err
+	}
+	cmd := heredocWrite(services.SchedulerJWTPubSetPath, string(data), "COOLIFY_JWT_KEYS_EOF", 0o644) +
+		" && systemctl try-restart scheduler"
+	err = runStep(ctx, runner, central, user, port, &out,
+		ActionRetireJWTKeys, "", cmd,
+		fmt.Sprintf("retire JWT keys on %s", central))
+	return out, err
+}
+
+// jwtKeysInUse returns the current key plus every trusted key an unexpired
+// token in tokens is signed by. It fails when a token could not be read.
+func jwtKeysInUse(trust *JWTTrust, tokens []HostJWT, now time.Time) ([]services.JWTPublicKey, error) {
+	used := map[string]bool{}
+	for _, t := range tokens {
+		switch {
+		case t.Err != nil:
+			return nil, fmt.Errorf("%s's token is unreadable", t.Host)
+		case t.Claims != nil && t.Claims.ExpiresAt.After(now):
+			used[t.SignedBy] = true
+		}
+	}
+	var keep []services.JWTPublicKey
+	for i, k := range trust.Keys {
+		if i == 0 || used[k.ID] {
+			keep = append(keep, k)
+		}
+	}
+	return keep, nil
+}
+
+// RevokeHostJWT makes the scheduler on the central host reject every token
+// issued to subject until now, and restarts it to drop the host's stream.
+// Apply and caps issue no new token to a revoked subject; only RotateHostJWTs
+// lifts the revocation. The scheduler must read the revocations (see
+// services.MinSchedulerJWTKeySetVersion).
+func RevokeHostJWT(
+	ctx context.Context,
+	runner ssh.Runner,
+	central, user string,
+	port int,
+	host, subject string,
+	now time.Time,
+) ([]ActionResult, error) {
+	var out []ActionResult
+	if err := checkSchedulerJWTKeySet(ctx, runner, central, user, port); err != nil {
+		return out, err
+	}
+	current, err := ReadJWTRevocations(ctx, runner, central, user, port)
+	if err != nil {
+		return out, err
+	}
+	revs := []services.JWTRevocation{}
+	for _, r := range current {
+		if r.Subject != subject {
+			revs = append(revs, r)
+		}
+	}
+	revs = append(revs, services.JWTRevocation{Subject: subject, Before: now.Unix(), Host: host})
+	data, err := services.EncodeJWTRevocations(revs)
+	if err != nil {
+		return out, err
+	}
+	cmd := heredocWrite(services.SchedulerJWTRevokedPath, string(data), "COOLIFY_JWT_REVOKED_EOF", 0o644) +
+		" && systemctl try-restart scheduler"
+	err = runStep(ctx, runner, central, user, port, &out,
+		ActionRevokeHostJWT, "", cmd,
+		fmt.Sprintf("revoke host JWT of %s on %s", host, central))
+	return out, err
+}
+
+// writeHostJWTCommand atomically installs token as the host JWT (mode 0600).
+func writeHostJWTCommand(token string) string {
+	return fmt.Sprintf(
+		`mkdir -p /etc/coolify && printf '%%s' '%s' > %[2]s.tmp && chmod 0600 %[2]s.tmp && mv %[2]s.tmp %[2]s`,
+		token, services.HostJWTPath)
+}
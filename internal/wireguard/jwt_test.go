@@ -0,0 +1,254 @@
+package wireguard
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
+)
+
+// jwtRunner fakes the files the JWT commands read and write on each host.
+// Key generation on the central host is done in Go.
+type jwtRunner struct {
+	t     *testing.T
+	mu    sync.Mutex
+	files map[string]map[string]string
+	calls []string
+}
+
+func newJWTKey(t *testing.T) (privPEM, pubPEM string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+}
+
+func (r *jwtRunner) Run(_ context.Context, host, _ string, _ int, cmd string) (string, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, host+": "+cmd)
+	files := r.files[host]
+	switch {
+	case strings.HasPrefix(cmd, "cat "+services.SchedulerJWTPubSetPath):
+		keys := files[services.SchedulerJWTPubSetPath]
+		if keys == "" {
+			keys = files[services.SchedulerJWTPubPath]
+		}
+		return keys + jwtRevokedMarker + "\n" + files[services.SchedulerJWTRevokedPath], "", nil
+	case strings.HasPrefix(cmd, "cat > "):
+		path, rest, _ := strings.Cut(strings.TrimPrefix(cmd, "cat > "), ".tmp <<'")
+		tag, rest, _ := strings.Cut(rest, "'\n")
+		body, _, _ := strings.Cut(rest, tag+"\n")
+		files[path] = body
+	case strings.HasPrefix(cmd, "cat "):
+		return files[strings.Fields(cmd)[1]], "", nil
+	case cmd == services.RotateJWTKeypairCommand():
+		priv, pub := newJWTKey(r.t)
+		set := files[services.SchedulerJWTPubSetPath]
+		if set == "" {
+			set = files[services.SchedulerJWTPubPath]
+		}
+		files[services.SchedulerJWTPubSetPath] = pub + set
+		files[services.SchedulerJWTPubPath] = pub
+		files[services.SchedulerJWTPrivPath] = priv
+	case strings.Contains(cmd, "printf '%s' '"):
+		_, rest, _ := strings.Cut(cmd, "printf '%s' '")
+		token, _, _ := strings.Cut(rest, "'")
+		files[services.HostJWTPath] = token
+	}
+	return "", "", nil
+}
+
+// jwtMesh returns a runner for central c plus hosts h1 and h2, each holding
+// a token signed by the central key.
+func jwtMesh(t *testing.T) (*jwtRunner, *DesiredMesh) {
+	priv, pub := newJWTKey(t)
+	r := &jwtRunner{t: t, files: map[string]map[string]string{
+		"c": {
+			services.SchedulerJWTPrivPath: priv,
+			services.SchedulerJWTPubPath:  pub,
+			services.SchedulerVersionPath: "nightly\n",
+		},
+		"h1": {}, "h2": {},
+	}}
+	for i, h := range []string{"h1", "h2"} {
+		token, err := services.MintHostJWT([]byte(priv), "100.64.0."+string(rune('1'+i)), []string{"coold"})
+		require.NoError(t, err)
+		r.files[h][services.HostJWTPath] = token
+	}
+	return r, &DesiredMesh{Hosts: []string{"h1", "h2"}, CentralHost: "c"}
+}
+
+func TestReadHostJWTs_VerifiesAgainstTrust(t *testing.T) {
+	r, d := jwtMesh(t)
+	r.files["h2"][services.HostJWTPath] = ""
+	trust, err := ReadJWTTrust(context.Background(), r, "c", "root", 22)
+	require.NoError(t, err)
+	require.Len(t, trust.Keys, 1)
+
+	tokens := ReadHostJWTs(context.Background(), r, d.Hosts, "root", 22, 2, trust)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "100.64.0.1", tokens[0].Claims.Subject)
+	assert.Equal(t, trust.Keys[0].ID, tokens[0].SignedBy)
+	assert.Nil(t, tokens[1].Claims)
+}
+
+func TestHostJWTEvaluate(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	claims := func(exp time.Duration) *services.HostJWTClaims {
+		return &services.HostJWTClaims{Subject: "s", IssuedAt: now.Add(-time.Hour), ExpiresAt: now.Add(exp)}
+	}
+	trust := &JWTTrust{Keys: []services.JWTPublicKey{{ID: "k1"}}}
+	for _, tc := range []struct {
+		name string
+		h    HostJWT
+		t    *JWTTrust
+		want string
+		sev  Severity
+	}{
+		{"ok", HostJWT{Claims: claims(90 * 24 * time.Hour), SignedBy: "k1"}, trust, JWTStatusOK, SeverityOK},
+		{"expiring", HostJWT{Claims: claims(10 * 24 * time.Hour), SignedBy: "k1"}, trust, JWTStatusExpiring, SeverityWarning},
+		{"expired", HostJWT{Claims: claims(-time.Hour), SignedBy: "k1"}, trust, JWTStatusExpired, SeverityCritical},
+		{"untrusted", HostJWT{Claims: claims(90 * 24 * time.Hour)}, trust, JWTStatusUntrusted, SeverityCritical},
+		{"unchecked trust", HostJWT{Claims: claims(90 * 24 * time.Hour)}, nil, JWTStatusOK, SeverityOK},
+		{"revoked", HostJWT{Claims: claims(90 * 24 * time.Hour), SignedBy: "k1"},
+			&JWTTrust{Keys: trust.Keys, Revocations: []services.JWTRevocation{{Subject: "s", Before: now.Unix()}}},
+			JWTStatusRevoked, SeverityCritical},
+		{"missing", HostJWT{}, trust, JWTStatusMissing, SeverityWarning},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.h.Evaluate(tc.t, now, DefaultJWTExpiryWarning)
+			assert.Equal(t, tc.want, got.Status)
+			assert.Equal(t, tc.sev, got.Severity)
+		})
+	}
+}
+
+func TestRotateHostJWTs_NewKeyRetiresOldOnceEveryHostMoved(t *testing.T) {
+	r, d := jwtMesh(t)
+	ctx := context.Background()
+	trust, err := ReadJWTTrust(ctx, r, "c", "root", 22)
+	require.NoError(t, err)
+	oldKey := trust.Keys[0].ID
+	tokens := ReadHostJWTs(ctx, r, d.Hosts, "root", 22, 2, trust)
+
+	// Only h1 moves: the old key must stay trusted for h2.
+	opts := JWTRotateOptions{Hosts: []string{"h1"}, NewKey: true}
+	steps, err := PlanJWTRotation(d, trust, tokens, opts)
+	require.NoError(t, err)
+	assert.Equal(t, ActionRotateJWTKeypair, steps[0].Type)
+	assert.Equal(t, ActionRetireJWTKeys, steps[len(steps)-1].Type)
+
+	_, err = RotateHostJWTs(ctx, r, "root", 22, d, tokens, opts, 2)
+	require.NoError(t, err)
+	trust, err = ReadJWTTrust(ctx, r, "c", "root", 22)
+	require.NoError(t, err)
+	require.Len(t, trust.Keys, 2)
+	newKey := trust.Keys[0].ID
+	assert.Equal(t, oldKey, trust.Keys[1].ID)
+
+	after := ReadHostJWTs(ctx, r, d.Hosts, "root", 22, 2, trust)
+	assert.Equal(t, newKey, after[0].SignedBy)
+	assert.Equal(t, "100.64.0.1", after[0].Claims.Subject, "subject is kept")
+	assert.Equal(t, oldKey, after[1].SignedBy)
+
+	// Moving h2 too retires the old key.
+	_, err = RotateHostJWTs(ctx, r, "root", 22, d, after, JWTRotateOptions{Hosts: []string{"h2"}}, 2)
+	require.NoError(t, err)
+	trust, err = ReadJWTTrust(ctx, r, "c", "root", 22)
+	require.NoError(t, err)
+	require.Len(t, trust.Keys, 1)
+	assert.Equal(t, newKey, trust.Keys[0].ID)
+}
+
+func TestPlanJWTRotation_NeedsExistingTokens(t *testing.T) {
+	r, d := jwtMesh(t)
+	r.files["h2"][services.HostJWTPath] = ""
+	trust, err := ReadJWTTrust(context.Background(), r, "c", "root", 22)
+	require.NoError(t, err)
+	tokens := ReadHostJWTs(context.Background(), r, d.Hosts, "root", 22, 2, trust)
+
+	_, err = PlanJWTRotation(d, trust, tokens, JWTRotateOptions{Hosts: d.Hosts})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "h2 has no host JWT")
+
+	_, err = PlanJWTRotation(d, trust, tokens, JWTRotateOptions{Hosts: []string{"h9"}})
+	assert.Error(t, err)
+}
+
+func TestRevokeHostJWT_RejectsCurrentTokenOnly(t *testing.T) {
+	r, d := jwtMesh(t)
+	ctx := context.Background()
+	now := time.Now()
+	r.files["c"][services.SchedulerJWTRevokedPath] =
+		`[{"sub":"100.64.0.9","before":1}]`
+
+	_, err := RevokeHostJWT(ctx, r, "c", "root", 22, "h2", "100.64.0.2", now)
+	require.NoError(t, err)
+	trust, err := ReadJWTTrust(ctx, r, "c", "root", 22)
+	require.NoError(t, err)
+	require.Len(t, trust.Revocations, 2, "revocations stay until a rotate lifts them")
+	assert.Equal(t, services.JWTRevocation{Subject: "100.64.0.2", Before: now.Unix(), Host: "h2"}, trust.Revocations[1])
+
+	assert.Contains(t, r.calls[len(r.calls)-2], "systemctl try-restart scheduler")
+
+	tokens := ReadHostJWTs(ctx, r, d.Hosts, "root", 22, 2, trust)
+	assert.Equal(t, JWTStatusOK, tokens[0].Evaluate(trust, now, 0).Status)
+	assert.Equal(t, JWTStatusRevoked, tokens[1].Evaluate(trust, now, 0).Status)
+}
+
+func TestRevokeHostJWT_NeedsKeySetAwareScheduler(t *testing.T) {
+	r, _ := jwtMesh(t)
+	r.files["c"][services.SchedulerVersionPath] = "v0.2.9\n"
+
+	_, err := RevokeHostJWT(context.Background(), r, "c", "root", 22, "h2", "100.64.0.2", time.Now())
+	require.ErrorContains(t, err, "scheduler v0.2.9 on c does not read the JWT key set")
+	assert.Empty(t, r.files["c"][services.SchedulerJWTRevokedPath])
+
+	delete(r.files["c"], services.SchedulerVersionPath)
+	_, err = RotateHostJWTs(context.Background(), r, "root", 22,
+		&DesiredMesh{Hosts: []string{"h1", "h2"}, CentralHost: "c"}, nil,
+		JWTRotateOptions{Hosts: []string{"h1"}, NewKey: true}, 2)
+	assert.ErrorContains(t, err, "scheduler of unknown version")
+}
+
+func TestRotateHostJWTs_LiftsRevocation(t *testing.T) {
+	r, d := jwtMesh(t)
+	ctx := context.Background()
+	_, err := RevokeHostJWT(ctx, r, "c", "root", 22, "h2", "100.64.0.2", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = RevokeHostJWT(ctx, r, "c", "root", 22, "h9", "100.64.0.9", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	trust, err := ReadJWTTrust(ctx, r, "c", "root", 22)
+	require.NoError(t, err)
+	tokens := ReadHostJWTs(ctx, r, d.Hosts, "root", 22, 2, trust)
+
+	opts := JWTRotateOptions{Hosts: []string{"h2"}}
+	steps, err := PlanJWTRotation(d, trust, tokens, opts)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, PlannedAction{Host: "c", Type: ActionLiftJWTRevocation, Detail: "lift the revocation of h2"}, steps[1])
+
+	_, err = RotateHostJWTs(ctx, r, "root", 22, d, tokens, opts, 2)
+	require.NoError(t, err)
+	revs, err := ReadJWTRevocations(ctx, r, "c", "root", 22)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, "100.64.0.9", revs[0].Subject, "other revocations are kept")
+}
@@ -0,0 +1,525 @@
+package wireguard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// LedgerVersion is the mesh state file format written by this build.
+const LedgerVersion = 1
+
+// RemoteLedgerPath is where --state-remote mirrors the ledger on every host.
+const RemoteLedgerPath = "/etc/coolify/mesh-state.json"
+
+// Ledger is the persisted mesh state: the IPAM allocations plus the identity
+// of every host. Live probes remain the source of truth for what is on a
+// host; the ledger keeps allocations reserved while a host is unreachable
+// and flags hosts whose probed state disagrees with what was recorded.
+type Ledger struct {
+	Version int `json:"version"`
+	// Serial increases on every write so the newest copy wins when local
+	// and remote ledgers disagree.
+	Serial    int64     `json:"serial"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Interface     string `json:"interface,omitempty"`
+	MgmtPool      string `json:"mgmt_pool,omitempty"`
+	ContainerPool string `json:"container_pool,omitempty"`
+	// ContainerPool6 is the IPv6 pool of a dual-stack mesh.
+	ContainerPool6 string `json:"container_pool6,omitempty"`
+
+	// Hosts maps SSH address → recorded host identity.
+	Hosts map[string]*LedgerHost `json:"hosts"`
+	// Subnets maps namespace → host → container subnet (CIDR).
+	Subnets map[string]map[string]string `json:"subnets"`
+	// Subnets6 maps namespace → host → the IPv6 subnet of a dual-stack
+	// bridge.
+	Subnets6 map[string]map[string]string `json:"subnets6,omitempty"`
+	// Operator is the operator peer (`coolify firewall operator join`),
+	// whose mgmt IP no host may be allocated.
+	Operator *LedgerOperator `json:"operator,omitempty"`
+}
+
+// LedgerOperator is the recorded operator peer.
+type LedgerOperator struct {
+	MgmtIP    string `json:"mgmt_ip"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// OperatorLedgerKey is the key the operator's mgmt IP is reserved under in
+// MeshState.AssignedMgmtIPs. It is not a valid SSH address.
+const OperatorLedgerKey = "(operator)"
+
+// LedgerHost is the recorded identity of one host.
+type LedgerHost struct {
+	MgmtIP           string `json:"mgmt_ip,omitempty"`
+	PublicKey        string `json:"public_key,omitempty"`
+	CooldVersion     string `json:"coold_version,omitempty"`
+	CorrosionVersion string `json:"corrosion_version,omitempty"`
+}
+
+// LedgerConflict is a disagreement between the ledger and a live probe.
+type LedgerConflict struct {
+	Host     string
+	Field    string
+	Recorded string
+	Probed   string
+}
+
+func (c LedgerConflict) String() string {
+	return fmt.Sprintf("%s: %s recorded as %s but probed as %s", c.Host, c.Field, c.Recorded, c.Probed)
+}
+
+// NewLedger returns an empty ledger at the current version.
+func NewLedger() *Ledger {
+	return &Ledger{
+		Version: LedgerVersion,
+		Hosts:   map[string]*LedgerHost{},
+		Subnets: map[string]map[string]string{},
+	}
+}
+
+// ParseLedger decodes and validates a mesh state file.
+func ParseLedger(data []byte) (*Ledger, error) {
+	l := &Ledger{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("decode mesh state: %w", err)
+	}
+	switch {
+	case l.Version == 0:
+		return nil, fmt.Errorf("mesh state has no version field")
+	case l.Version > LedgerVersion:
+		return nil, fmt.Errorf("mesh state version %d was written by a newer coolify (this build reads up to %d)", l.Version, LedgerVersion)
+	}
+	if l.Hosts == nil {
+		l.Hosts = map[string]*LedgerHost{}
+	}
+	if l.Subnets == nil {
+		l.Subnets = map[string]map[string]string{}
+	}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LoadLedger reads the ledger at path. A missing file yields (nil, nil).
+func LoadLedger(path string) (*Ledger, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read mesh state: %w", err)
+	}
+	l, err := ParseLedger(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Marshal encodes the ledger as indented JSON.
+func (l *Ledger) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Save atomically writes the ledger to path with mode 0600.
+func (l *Ledger) Save(path string) error {
+	data, err := l.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create mesh state dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write mesh state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write mesh state: %w", err)
+	}
+	return nil
+}
+
+// Validate rejects malformed addresses and allocations claimed twice.
+func (l *Ledger) Validate() error {
+	ipOwner := map[string]string{}
+	for _, host := range sortedKeys(l.Hosts) {
+		h := l.Hosts[host]
+		if h == nil || h.MgmtIP == "" {
+			continue
+		}
+		ip := net.ParseIP(h.MgmtIP)
+		if ip == nil {
+			return fmt.Errorf("host %s: invalid mgmt IP %q", host, h.MgmtIP)
+		}
+		if other, ok := ipOwner[ip.String()]; ok {
+			return fmt.Errorf("mgmt IP %s is recorded for both %s and %s", ip, other, host)
+		}
+		ipOwner[ip.String()] = host
+	}
+	if l.Operator != nil {
+		ip := net.ParseIP(l.Operator.MgmtIP)
+		if ip == nil {
+			return fmt.Errorf("operator peer: invalid mgmt IP %q", l.Operator.MgmtIP)
+		}
+		if other, ok := ipOwner[ip.String()]; ok {
+			return fmt.Errorf("mgmt IP %s is recorded for both %s and the operator peer", ip, other)
+		}
+	}
+
+	type claim struct {
+		owner  string
+		subnet *net.IPNet
+	}
+	var claims []claim
+	for _, subnets := range []map[string]map[string]string{l.Subnets, l.Subnets6} {
+		for _, ns := range sortedKeys(subnets) {
+			for _, host := range sortedKeys(subnets[ns]) {
+				_, sn, err := net.ParseCIDR(subnets[ns][host])
+				if err != nil {
+					return fmt.Errorf("host %s namespace %s: invalid subnet %q", host, ns, subnets[ns][host])
+				}
+				owner := fmt.Sprintf("%s/%s", host, ns)
+				for _, c := range claims {
+					if c.subnet.Contains(sn.IP) || sn.Contains(c.subnet.IP) {
+						return fmt.Errorf("subnet %s (%s) overlaps %s (%s)", sn, owner, c.subnet, c.owner)
+					}
+				}
+				claims = append(claims, claim{owner: owner, subnet: sn})
+			}
+		}
+	}
+	return nil
+}
+
+// Record updates the ledger from a fresh probe of hosts and forgets removed.
+// Hosts that did not answer the probe keep their previous record.
+func (l *Ledger) Record(mesh MeshState, hosts, removed []string) {
+	for _, host := range hosts {
+		s := mesh.Servers[host]
+		if s == nil || (!s.Installed && s.WireGuardMgmtIP == nil) {
+			continue
+		}
+		h := l.Hosts[host]
+		if h == nil {
+			h = &LedgerHost{}
+			l.Hosts[host] = h
+		}
+		if s.WireGuardMgmtIP != nil {
+			h.MgmtIP = s.WireGuardMgmtIP.String()
+		}
+		if s.PublicKey != "" {
+			h.PublicKey = s.PublicKey
+		}
+		if s.CooldVersion != "" {
+			h.CooldVersion = s.CooldVersion
+		}
+		if s.CorrosionVersion != "" {
+			h.CorrosionVersion = s.CorrosionVersion
+		}
+		for ns, nss := range s.Namespaces {
+			if nss == nil {
+				continue
+			}
+			if nss.ContainerSubnet != nil {
+				recordSubnet(&l.Subnets, ns, host, nss.ContainerSubnet)
+			}
+			if nss.ContainerSubnet6 != nil {
+				recordSubnet(&l.Subnets6, ns, host, nss.ContainerSubnet6)
+			}
+		}
+	}
+	for _, host := range removed {
+		delete(l.Hosts, host)
+		for _, subnets := range []map[string]map[string]string{l.Subnets, l.Subnets6} {
+			for ns := range subnets {
+				delete(subnets[ns], host)
+				if len(subnets[ns]) == 0 {
+					delete(subnets, ns)
+				}
+			}
+		}
+	}
+	l.Version = LedgerVersion
+	l.Serial++
+	l.UpdatedAt = time.Now().UTC()
+}
+
+// recordSubnet sets (*subnets)[ns][host], allocating the maps on first use.
+func recordSubnet(subnets *map[string]map[string]string, ns, host string, sn *net.IPNet) {
+	if *subnets == nil {
+		*subnets = map[string]map[string]string{}
+	}
+	if (*subnets)[ns] == nil {
+		(*subnets)[ns] = map[string]string{}
+	}
+	(*subnets)[ns][host] = sn.String()
+}
+
+// RecordOperator records the operator peer, or forgets it when op is nil.
+func (l *Ledger) RecordOperator(op *LedgerOperator) {
+	l.Operator = op
+	l.Version = LedgerVersion
+	l.Serial++
+	l.UpdatedAt = time.Now().UTC()
+}
+
+// ContainerNets returns the recorded container pool and every recorded
+// container subnet, deduplicated: the addresses containers are given.
+func (l *Ledger) ContainerNets() []*net.IPNet {
+	var out []*net.IPNet
+	seen := map[string]bool{}
+	add := func(cidr string) {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil || seen[n.String()] {
+			return
+		}
+		seen[n.String()] = true
+		out = append(out, n)
+	}
+	add(l.ContainerPool)
+	add(l.ContainerPool6)
+	for _, subnets := range []map[string]map[string]string{l.Subnets, l.Subnets6} {
+		for _, ns := range sortedKeys(subnets) {
+			for _, host := range sortedKeys(subnets[ns]) {
+				add(subnets[ns][host])
+			}
+		}
+	}
+	return out
+}
+
+// ReconcileLedger attaches l to mesh so unreachable hosts keep their
+// recorded allocations, and returns every field where a live probe disagrees
+// with the ledger. The probe wins; the ledger is rewritten from a fresh
+// probe after the next apply.
+func ReconcileLedger(mesh *MeshState, l *Ledger) []LedgerConflict {
+	mesh.Ledger = l
+	if l == nil {
+		return nil
+	}
+
+	recordedOwner := map[string]string{}
+	for host, h := range l.Hosts {
+		if h != nil && h.MgmtIP != "" {
+			recordedOwner[h.MgmtIP] = host
+		}
+	}
+
+	if l.Operator != nil {
+		recordedOwner[l.Operator.MgmtIP] = "the operator peer"
+	}
+
+	var conflicts []LedgerConflict
+	for _, host := range sortedKeys(mesh.Servers) {
+		s := mesh.Servers[host]
+		if s == nil {
+			continue
+		}
+		rec := l.Hosts[host]
+		if s.WireGuardMgmtIP != nil {
+			probed := s.WireGuardMgmtIP.String()
+			if rec != nil && rec.MgmtIP != "" && rec.MgmtIP != probed {
+				conflicts = append(conflicts, LedgerConflict{Host: host, Field: "mgmt_ip", Recorded: rec.MgmtIP, Probed: probed})
+			} else if owner, ok := recordedOwner[probed]; ok && owner != host {
+				conflicts = append(conflicts, LedgerConflict{Host: host, Field: "mgmt_ip", Recorded: "owned by " + owner, Probed: probed})
+			}
+		}
+		if rec != nil && rec.PublicKey != "" && s.PublicKey != "" && rec.PublicKey != s.PublicKey {
+			conflicts = append(conflicts, LedgerConflict{
+				Host: host, Field: "public_key",
+				Recorded: truncateKey(rec.PublicKey), Probed: truncateKey(s.PublicKey),
+			})
+		}
+		for _, ns := range sortedKeys(s.Namespaces) {
+			nss := s.Namespaces[ns]
+			if nss == nil {
+				continue
+			}
+			for _, c := range []struct {
+				field    string
+				recorded string
+				probed   *net.IPNet
+			}{
+				{"subnet", l.Subnets[ns][host], nss.ContainerSubnet},
+				{"subnet6", l.Subnets6[ns][host], nss.ContainerSubnet6},
+			} {
+				if c.probed != nil && c.recorded != "" && c.recorded != c.probed.String() {
+					conflicts = append(conflicts, LedgerConflict{
+						Host: host, Field: c.field + "[" + ns + "]",
+						Recorded: c.recorded, Probed: c.probed.String(),
+					})
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+// ledgerMgmtIPs returns the recorded mgmt IPs not already present in probed,
+// skipping any IP a probed host currently holds. The operator peer's IP is
+// returned under OperatorLedgerKey.
+func (l *Ledger) ledgerMgmtIPs(probed map[string]net.IP) map[string]net.IP {
+	held := map[string]bool{}
+	for _, ip := range probed {
+		held[ip.String()] = true
+	}
+	out := map[string]net.IP{}
+	for host, h := range l.Hosts {
+		if h == nil || h.MgmtIP == "" {
+			continue
+		}
+		if _, ok := probed[host]; ok {
+			continue
+		}
+		ip := net.ParseIP(h.MgmtIP)
+		if ip == nil || held[ip.String()] {
+			continue
+		}
+		out[host] = ip
+	}
+	if l.Operator != nil {
+		if ip := net.ParseIP(l.Operator.MgmtIP); ip != nil && !held[ip.String()] {
+			out[OperatorLedgerKey] = ip
+		}
+	}
+	return out
+}
+
+// ledgerSubnets returns the recorded subnets for (namespace, host) pairs
+// the probe did not report, skipping any subnet a probed host holds.
+func ledgerSubnets(recorded map[string]map[string]string, probed map[string]map[string]*net.IPNet) map[string]map[string]*net.IPNet {
+	held := map[string]bool{}
+	for _, byHost := range probed {
+		for _, sn := range byHost {
+			held[sn.String()] = true
+		}
+	}
+	out := map[string]map[string]*net.IPNet{}
+	for ns, byHost := range recorded {
+		for host, cidr := range byHost {
+			if _, ok := probed[ns][host]; ok {
+				continue
+			}
+			_, sn, err := net.ParseCIDR(cidr)
+			if err != nil || held[sn.String()] {
+				continue
+			}
+			if out[ns] == nil {
+				out[ns] = map[string]*net.IPNet{}
+			}
+			out[ns][host] = sn
+		}
+	}
+	return out
+}
+
+// FetchRemoteLedger reads RemoteLedgerPath from every host and returns the
+// copy with the highest serial, or nil when no host has one.
+func FetchRemoteLedger(
+	ctx context.Context,
+	runner ssh.Runner,
+	hosts []string,
+	user string,
+	port int,
+	concurrency int,
+) (*Ledger, error) {
+	results := ssh.ForEachServer(ctx, hosts, concurrency,
+		func(ctx context.Context, host string) (*Ledger, error) {
+			stdout, _, err := runner.Run(ctx, host, user, port,
+				fmt.Sprintf("cat %s 2>/dev/null || true", RemoteLedgerPath))
+			if err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(stdout) == "" {
+				return nil, nil
+			}
+			return ParseLedger([]byte(stdout))
+		})
+
+	var best *Ledger
+	var errs []string
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Host, r.Err))
+			continue
+		}
+		best = NewestLedger(best, r.Result)
+	}
+	if best == nil && len(errs) > 0 {
+		return nil, fmt.Errorf("read remote mesh state:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return best, nil
+}
+
+// PushLedger writes l to RemoteLedgerPath on every host.
+func PushLedger(
+	ctx context.Context,
+	runner ssh.Runner,
+	hosts []string,
+	user string,
+	port int,
+	concurrency int,
+	l *Ledger,
+) error {
+	data, err := l.Marshal()
+	if err != nil {
+		return err
+	}
+	cmd := heredocWrite(RemoteLedgerPath, string(data), "COOLIFY_MESH_STATE_EOF", 0o600)
+	results := ssh.ForEachServer(ctx, hosts, concurrency,
+		func(ctx context.Context, host string) (struct{}, error) {
+			_, stderr, err := runner.Run(ctx, host, user, port, cmd)
+			if err != nil && firstLine(stderr) != "" {
+				err = fmt.Errorf("%w: %s", err, firstLine(stderr))
+			}
+			return struct{}{}, err
+		})
+	var errs []string
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Host, r.Err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("write remote mesh state:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// NewestLedger returns whichever ledger has the higher serial, preferring a
+// on a tie. Either may be nil.
+func NewestLedger(a, b *Ledger) *Ledger {
+	if a == nil {
+		return b
+	}
+	if b != nil && b.Serial > a.Serial {
+		return b
+	}
+	return a
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
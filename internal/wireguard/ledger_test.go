@@ -0,0 +1,285 @@
+package wireguard
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func twoHostLedger() *Ledger {
+	l := NewLedger()
+	l.Serial = 3
+	l.Hosts["1.1.1.1"] = &LedgerHost{MgmtIP: "100.64.0.1", PublicKey: "AAAAAAAA="}
+	l.Hosts["2.2.2.2"] = &LedgerHost{MgmtIP: "100.64.0.2", PublicKey: "BBBBBBBB="}
+	l.Subnets[DefaultNamespace] = map[string]string{
+		"1.1.1.1": "10.210.0.0/24",
+		"2.2.2.2": "10.210.1.0/24",
+	}
+	return l
+}
+
+func TestParseLedger_Versions(t *testing.T) {
+	_, err := ParseLedger([]byte(`{"hosts":{}}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no version")
+
+	_, err = ParseLedger([]byte(`{"version":99}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "newer coolify")
+
+	l, err := ParseLedger([]byte(`{"version":1,"serial":4}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), l.Serial)
+	assert.NotNil(t, l.Hosts)
+	assert.NotNil(t, l.Subnets)
+}
+
+func TestLedgerValidate_RejectsDuplicates(t *testing.T) {
+	l := twoHostLedger()
+	l.Hosts["2.2.2.2"].MgmtIP = "100.64.0.1"
+	err := l.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "100.64.0.1")
+
+	l = twoHostLedger()
+	l.Subnets["alpha"] = map[string]string{"1.1.1.1": "10.210.1.0/25"}
+	err = l.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overlaps")
+
+	require.NoError(t, twoHostLedger().Validate())
+}
+
+func TestLedger_SaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mesh-state.json")
+
+	missing, err := LoadLedger(path)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, twoHostLedger().Save(path))
+	l, err := LoadLedger(path)
+	require.NoError(t, err)
+	assert.Equal(t, twoHostLedger().Hosts, l.Hosts)
+	assert.Equal(t, twoHostLedger().Subnets, l.Subnets)
+}
+
+func TestLedgerRecord(t *testing.T) {
+	l := twoHostLedger()
+	mesh := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": convergedServer("1.1.1.1", "NEWKEY==", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24"),
+		// Unreachable: keeps its previous record.
+		"2.2.2.2": {Host: "2.2.2.2"},
+	}}
+	mesh.Servers["1.1.1.1"].CooldVersion = "v1.2.3"
+
+	l.Record(mesh, []string{"1.1.1.1", "2.2.2.2"}, nil)
+	assert.Equal(t, int64(4), l.Serial)
+	assert.Equal(t, "NEWKEY==", l.Hosts["1.1.1.1"].PublicKey)
+	assert.Equal(t, "v1.2.3", l.Hosts["1.1.1.1"].CooldVersion)
+	assert.Equal(t, "100.64.0.2", l.Hosts["2.2.2.2"].MgmtIP)
+
+	l.Record(mesh, []string{"1.1.1.1"}, []string{"2.2.2.2"})
+	assert.NotContains(t, l.Hosts, "2.2.2.2")
+	assert.NotContains(t, l.Subnets[DefaultNamespace], "2.2.2.2")
+}
+
+func TestReconcileLedger_Conflicts(t *testing.T) {
+	mesh := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": convergedServer("1.1.1.1", "ROTATED=", "BBBBBBBB=", "100.64.0.9", "10.210.5.0/24"),
+		"2.2.2.2": convergedServer("2.2.2.2", "BBBBBBBB=", "AAAAAAAA=", "100.64.0.2", "10.210.1.0/24"),
+	}}
+
+	conflicts := ReconcileLedger(&mesh, twoHostLedger())
+	fields := map[string]bool{}
+	for _, c := range conflicts {
+		assert.Equal(t, "1.1.1.1", c.Host)
+		fields[c.Field] = true
+	}
+	assert.Equal(t, map[string]bool{"mgmt_ip": true, "public_key": true, "subnet[default]": true}, fields)
+	assert.NotNil(t, mesh.Ledger)
+}
+
+func TestReconcileLedger_IPHeldByAnotherHost(t *testing.T) {
+	mesh := MeshState{Servers: map[string]*ServerState{
+		"3.3.3.3": {Host: "3.3.3.3", WireGuardMgmtIP: net.ParseIP("100.64.0.2").To4()},
+	}}
+	conflicts := ReconcileLedger(&mesh, twoHostLedger())
+	require.Len(t, conflicts, 1)
+	assert.Contains(t, conflicts[0].String(), "owned by 2.2.2.2")
+}
+
+// An unreachable host keeps its recorded subnet and mgmt IP instead of
+// having them handed to a new host.
+func TestBuildPlan_LedgerReservesUnreachableHost(t *testing.T) {
+	desired := desiredWithPodman()
+	desired.Hosts = []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}
+	current := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": convergedServer("1.1.1.1", "AAAAAAAA=", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24"),
+		"2.2.2.2": {Host: "2.2.2.2", Namespaces: map[string]*NamespaceServerState{}},
+		"3.3.3.3": {Host: "3.3.3.3", Namespaces: map[string]*NamespaceServerState{}},
+	}}
+	ReconcileLedger(&current, twoHostLedger())
+
+	plan, err := BuildPlan(desired, current)
+	require.NoError(t, err)
+	assert.Equal(t, "100.64.0.2", plan.MgmtAssignments["2.2.2.2"].String())
+	assert.Equal(t, "10.210.1.0/24", plan.SubnetAssignments[DefaultNamespace]["2.2.2.2"].String())
+	assert.Equal(t, "100.64.0.3", plan.MgmtAssignments["3.3.3.3"].String())
+	assert.Equal(t, "10.210.2.0/24", plan.SubnetAssignments[DefaultNamespace]["3.3.3.3"].String())
+}
+
+func TestFetchRemoteLedger_NewestSerialWins(t *testing.T) {
+	older, err := twoHostLedger().Marshal()
+	require.NoError(t, err)
+	newer := twoHostLedger()
+	newer.Serial = 7
+	newerData, err := newer.Marshal()
+	require.NoError(t, err)
+
+	runner := &perHostRunner{out: map[string]string{
+		"1.1.1.1": string(older),
+		"2.2.2.2": string(newerData),
+		"3.3.3.3": "",
+	}}
+	l, err := FetchRemoteLedger(context.Background(), runner, []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}, "root", 22, 2)
+	require.NoError(t, err)
+	require.NotNil(t, l)
+	assert.Equal(t, int64(7), l.Serial)
+
+	assert.Equal(t, newer, NewestLedger(newer, nil))
+	assert.Equal(t, newer, NewestLedger(twoHostLedger(), newer))
+}
+
+// perHostRunner answers every command with a fixed stdout per host.
+type perHostRunner struct {
+	out map[string]string
+}
+
+func (p *perHostRunner) Run(_ context.Context, host, _ string, _ int, _ string) (string, string, error) {
+	return p.out[host], "", nil
+}
+
+// A new host is never given the operator peer's mgmt IP.
+func TestBuildPlan_LedgerReservesOperatorIP(t *testing.T) {
+	desired := desiredTwoHosts()
+	current := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": {Host: "1.1.1.1"},
+		"2.2.2.2": {Host: "2.2.2.2"},
+	}}
+	l := NewLedger()
+	l.RecordOperator(&LedgerOperator{MgmtIP: "100.64.0.1", PublicKey: "T1BFUkFUT1I="})
+	require.NoError(t, l.Validate())
+	ReconcileLedger(&current, l)
+
+	plan, err := BuildPlan(desired, current)
+	require.NoError(t, err)
+	assert.Equal(t, "100.64.0.2", plan.MgmtAssignments["1.1.1.1"].String())
+	assert.Equal(t, "100.64.0.3", plan.MgmtAssignments["2.2.2.2"].String())
+
+	// A host already holding it is reported.
+	current.Servers["1.1.1.1"].WireGuardMgmtIP = net.ParseIP("100.64.0.1").To4()
+	conflicts := ReconcileLedger(&current, l)
+	require.Len(t, conflicts, 1)
+	assert.Contains(t, conflicts[0].String(), "owned by the operator peer")
+
+	l.Hosts["1.1.1.1"] = &LedgerHost{MgmtIP: "100.64.0.1"}
+	assert.ErrorContains(t, l.Validate(), "the operator peer")
+}
+
+func TestBuildPlan_KeepsLedgerOperatorPeer(t *testing.T) {
+	desired := desiredTwoHosts()
+	desired.Hosts = []string{"1.1.1.1"}
+	current := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": {
+			Host:            "1.1.1.1",
+			Installed:       true,
+			KeysExist:       true,
+			PublicKey:       "AAAAAAAA=",
+			WireGuardMgmtIP: net.ParseIP("100.64.0.1").To4(),
+			ListenPort:      51820,
+			Active:          true,
+			Peers:           []Peer{{PublicKey: "T1BFUkFUT1I=", AllowedIPs: []string{"100.64.255.254/32"}}},
+		},
+	}}
+
+	// Without a ledger entry the operator is just a stale peer.
+	plan, err := BuildPlan(desired, current)
+	require.NoError(t, err)
+	var removes []string
+	for _, a := range plan.Actions {
+		if a.Type == ActionRemovePeer {
+			removes = append(removes, a.Detail)
+		}
+	}
+	assert.Len(t, removes, 1)
+
+	l := NewLedger()
+	l.RecordOperator(&LedgerOperator{MgmtIP: "100.64.255.254", PublicKey: "T1BFUkFUT1I="})
+	ReconcileLedger(&current, l)
+	plan, err = BuildPlan(desired, current)
+	require.NoError(t, err)
+	for _, a := range plan.Actions {
+		assert.NotEqual(t, ActionRemovePeer, a.Type, a.Detail)
+	}
+
+	peers := peerConfigs(desired, "1.1.1.1", map[string]string{"1.1.1.1": "AAAAAAAA="},
+		plan.MgmtAssignments, plan.SubnetAssignments, plan.SubnetAssignments6, current.operatorPeer())
+	require.NotEmpty(t, peers)
+	op := peers[len(peers)-1]
+	assert.True(t, op.Operator)
+	assert.Equal(t, "T1BFUkFUT1I=", op.PublicKey)
+	assert.Equal(t, "100.64.255.254", op.MgmtIP.String())
+}
+
+func TestLedgerContainerNets(t *testing.T) {
+	l := NewLedger()
+	assert.Empty(t, l.ContainerNets())
+
+	l.ContainerPool = "10.210.0.0/16"
+	l.Subnets = map[string]map[string]string{
+		"default": {"1.1.1.1": "10.210.0.0/24"},
+		"alpha":   {"1.1.1.1": "10.220.0.0/24", "2.2.2.2": "10.220.0.0/24"},
+	}
+	var got []string
+	for _, n := range l.ContainerNets() {
+		got = append(got, n.String())
+	}
+	assert.Equal(t, []string{"10.210.0.0/16", "10.220.0.0/24", "10.210.0.0/24"}, got)
+}
+
+func TestLedger_DualStackSubnets(t *testing.T) {
+	l := NewLedger()
+	l.ContainerPool, l.ContainerPool6 = "10.210.0.0/16", "fd00:210::/48"
+	s := convergedServer("1.1.1.1", "AAAAAAAA=", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24")
+	s.Namespaces[DefaultNamespace].ContainerSubnet6 = mustParseCIDR("fd00:210::/64")
+	mesh := MeshState{Servers: map[string]*ServerState{"1.1.1.1": s}}
+
+	l.Record(mesh, []string{"1.1.1.1"}, nil)
+	assert.Equal(t, "10.210.0.0/24", l.Subnets[DefaultNamespace]["1.1.1.1"])
+	assert.Equal(t, "fd00:210::/64", l.Subnets6[DefaultNamespace]["1.1.1.1"])
+	require.NoError(t, l.Validate())
+
+	var nets []string
+	for _, n := range l.ContainerNets() {
+		nets = append(nets, n.String())
+	}
+	assert.Equal(t, []string{"10.210.0.0/16", "fd00:210::/48", "10.210.0.0/24", "fd00:210::/64"}, nets)
+
+	// The ledger keeps an unreachable host's IPv6 subnet reserved.
+	reserved := MeshState{Servers: map[string]*ServerState{"1.1.1.1": {Host: "1.1.1.1"}}}
+	ReconcileLedger(&reserved, l)
+	assert.Equal(t, "fd00:210::/64", reserved.AssignedContainerSubnets6()[DefaultNamespace]["1.1.1.1"].String())
+
+	s.Namespaces[DefaultNamespace].ContainerSubnet6 = mustParseCIDR("fd00:210:0:9::/64")
+	conflicts := ReconcileLedger(&mesh, l)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "subnet6[default]", conflicts[0].Field)
+
+	l.Record(mesh, nil, []string{"1.1.1.1"})
+	assert.Empty(t, l.Subnets6)
+}
@@ -0,0 +1,100 @@
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// GenerateKeyPair returns a new base64 WireGuard private/public key pair,
+// the same encoding `wg genkey | wg pubkey` produces. Used for the operator
+// peer, whose key is made on the CLI machine rather than on a host.
+func GenerateKeyPair() (privateKey, publicKey string, err error) {
+	var priv [32]byte
+	if _, err := rand.Read(priv[:]); err != nil {
+		return "", "", fmt.Errorf("generate wireguard key: %w", err)
+	}
+	// Clamp as Curve25519 (and `wg genkey`) expects.
+	priv[0] &= 248
+	priv[31] = (priv[31] & 127) | 64
+	pub, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return "", "", fmt.Errorf("derive wireguard public key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(priv[:]), base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// OperatorIP picks the operator peer's mgmt IP: the highest address of pool
+// that no host holds. Hosts are allocated from the bottom of the pool, so
+// the top is the last place a future host would land.
+func OperatorIP(pool *net.IPNet, used []net.IP) (net.IP, error) {
+	ap, err := newAddrPool(pool, ipBits(normalizeIP(pool.IP)))
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(used))
+	for _, ip := range used {
+		taken[normalizeIP(ip).String()] = true
+	}
+	one := big.NewInt(1)
+	for u := new(big.Int).Set(ap.last); u.Cmp(ap.network) > 0; u.Sub(u, one) {
+		ip := intToIP(u, ap.bits)
+		if !ap.isEdge(ip) && !taken[ip.String()] {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("pool %s has no free address for the operator peer", pool)
+}
+
+// operatorMarker brackets the operator's [Peer] block in a host's config so
+// it can be found and removed again.
+func operatorMarker(edge, publicKey string) string {
+	return "# coolify-operator-" + edge + " " + publicKey
+}
+
+// operatorPeerBlock returns the marked [Peer] block that admits the
+// operator peer on a host, one line per element.
+func operatorPeerBlock(publicKey string, mgmtIP net.IP) []string {
+	return []string{
+		operatorMarker("begin", publicKey),
+		"[Peer]",
+		"PublicKey = " + publicKey,
+		"AllowedIPs = " + HostCIDR(mgmtIP),
+		operatorMarker("end", publicKey),
+	}
+}
+
+// AddOperatorPeerCommand returns the shell command that admits the operator
+// peer on a host: `wg set` for the running interface, plus the marked
+// [Peer] block appended to <iface>.conf unless it is already there. Once
+// the operator is recorded in the ledger every rendered config carries the
+// same block (see MeshState.operatorPeer), so the append only bridges the
+// time until the next apply.
+func AddOperatorPeerCommand(iface, publicKey string, mgmtIP net.IP) string {
+	conf := "/etc/wireguard/" + iface + ".conf"
+	block := "\n" + strings.Join(operatorPeerBlock(publicKey, mgmtIP), "\n")
+	return fmt.Sprintf(`wg set %s peer %s allowed-ips %s && `, iface, publicKey, HostCIDR(mgmtIP)) +
+		fmt.Sprintf(`{ grep -qF '%s' %s || printf '%%s\n' '%s' >> %s; }`,
+			operatorMarker("begin", publicKey), conf, block, conf)
+}
+
+// RemoveOperatorPeerCommand returns the shell command that drops the
+// operator peer from a host, both live and from <iface>.conf.
+func RemoveOperatorPeerCommand(iface, publicKey string) string {
+	conf := "/etc/wireguard/" + iface + ".conf"
+	return fmt.Sprintf(`wg set %s peer %s remove 2>/dev/null; `, iface, publicKey) +
+		fmt.Sprintf(`sed -i '\|^%s$|,\|^%s$|d' %s`,
+			operatorMarker("begin", publicKey), operatorMarker("end", publicKey), conf)
+}
+
+// RenderOperatorConfig returns a complete wg-quick config for the operator
+// peer. Unlike RenderConfig the private key is filled in: the file is
+// written on the operator's own machine.
+func RenderOperatorConfig(privateKey string, mgmtIP net.IP, listenPort int, peers []PeerConfig) string {
+	return strings.Replace(RenderConfig(mgmtIP, listenPort, peers), "__PRIVKEY__", privateKey, 1)
+}
@@ -0,0 +1,81 @@
+package wireguard
+
+import (
+	"encoding/base64"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestGenerateKeyPair_DerivesPublicKey(t *testing.T) {
+	priv, pub, err := GenerateKeyPair()
+	require.NoError(t, err)
+	raw, err := base64.StdEncoding.DecodeString(priv)
+	require.NoError(t, err)
+	require.Len(t, raw, 32)
+	assert.Zero(t, raw[0]&7, "private key must be clamped")
+	assert.Zero(t, raw[31]&128, "private key must be clamped")
+	assert.NotZero(t, raw[31]&64, "private key must be clamped")
+
+	want, err := curve25519.X25519(raw, curve25519.Basepoint)
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(want), pub)
+}
+
+func TestOperatorIP_TopOfPool(t *testing.T) {
+	_, pool, _ := net.ParseCIDR("100.64.0.0/16")
+	ip, err := OperatorIP(pool, []net.IP{net.ParseIP("100.64.0.1")})
+	require.NoError(t, err)
+	assert.Equal(t, "100.64.255.254", ip.String(), "broadcast is skipped")
+
+	ip, err = OperatorIP(pool, []net.IP{net.ParseIP("100.64.255.254")})
+	require.NoError(t, err)
+	assert.Equal(t, "100.64.255.253", ip.String())
+}
+
+func TestOperatorIP_IPv6Pool(t *testing.T) {
+	_, pool, _ := net.ParseCIDR("fd64::/120")
+	ip, err := OperatorIP(pool, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fd64::ff", ip.String())
+}
+
+func TestOperatorIP_FullPool(t *testing.T) {
+	_, pool, _ := net.ParseCIDR("100.64.0.0/30")
+	_, err := OperatorIP(pool, []net.IP{net.ParseIP("100.64.0.1"), net.ParseIP("100.64.0.2")})
+	assert.Error(t, err)
+}
+
+func TestOperatorPeerCommands(t *testing.T) {
+	add := AddOperatorPeerCommand("wg0", "b3Blcm/0b3I+", net.ParseIP("100.64.255.254"))
+	assert.Contains(t, add, "wg set wg0 peer b3Blcm/0b3I+ allowed-ips 100.64.255.254/32")
+	assert.Contains(t, add, "grep -qF '# coolify-operator-begin b3Blcm/0b3I+' /etc/wireguard/wg0.conf")
+	assert.Contains(t, add, "AllowedIPs = 100.64.255.254/32")
+	assert.Contains(t, add, "# coolify-operator-end b3Blcm/0b3I+")
+	assert.Contains(t, add, ">> /etc/wireguard/wg0.conf")
+
+	rm := RemoveOperatorPeerCommand("wg0", "b3Blcm/0b3I+")
+	assert.Contains(t, rm, "wg set wg0 peer b3Blcm/0b3I+ remove")
+	assert.Contains(t, rm,
+		`sed -i '\|^# coolify-operator-begin b3Blcm/0b3I+$|,\|^# coolify-operator-end b3Blcm/0b3I+$|d' /etc/wireguard/wg0.conf`)
+}
+
+func TestRenderConfig_OperatorPeer(t *testing.T) {
+	peers := []PeerConfig{{
+		PublicKey: "b3Blcm/0b3I+",
+		MgmtIP:    net.ParseIP("100.64.255.254"),
+		Operator:  true,
+	}}
+	cfg := RenderConfig(net.ParseIP("100.64.0.1"), 51820, peers)
+	assert.Contains(t, cfg, "# coolify-operator-begin b3Blcm/0b3I+\n[Peer]\nPublicKey = b3Blcm/0b3I+\nAllowedIPs = 100.64.255.254/32\n# coolify-operator-end b3Blcm/0b3I+")
+	assert.NotContains(t, cfg, "Endpoint")
+	assert.NotContains(t, cfg, "PersistentKeepalive")
+
+	cmd := WriteConfigCommand("wg0", net.ParseIP("100.64.0.1"), 51820, peers)
+	assert.Contains(t, cmd, "# coolify-operator-begin b3Blcm/0b3I+")
+	assert.Contains(t, cmd, "AllowedIPs = 100.64.255.254/32")
+	assert.NotContains(t, cmd, "Endpoint")
+}
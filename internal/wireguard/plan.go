@@ -33,6 +33,7 @@ const (
 	ActionInstallCoold            ActionType = "install-coold"
 	ActionWriteCorrosionConfig    ActionType = "write-corrosion-config"
 	ActionWriteCorrosionSchema    ActionType = "write-corrosion-schema"
+	ActionMigrateCorrosionSchema  ActionType = "migrate-corrosion-schema"
 	ActionInstallCorrosionService ActionType = "install-corrosion-service"
 	ActionInstallCooldService     ActionType = "install-coold-service"
 	ActionInstallScheduler        ActionType = "install-scheduler"
@@ -41,6 +42,19 @@ const (
 	ActionWriteHostJWT            ActionType = "write-host-jwt"
 	ActionUpdateCooldSchedulerEnv ActionType = "update-coold-scheduler-env"
 	ActionInstallBuilder          ActionType = "install-builder"
+	ActionDrainHost               ActionType = "drain-host"
+	ActionStopAgents              ActionType = "stop-agents"
+	ActionPurgeCorrosionHost      ActionType = "purge-corrosion-host"
+	ActionRemovePodmanNet         ActionType = "remove-podman-network"
+	ActionTeardownWG              ActionType = "teardown-wg"
+	ActionGenNextKeyPair          ActionType = "gen-next-keypair"
+	ActionUpdatePeerKey           ActionType = "update-peer-key"
+	ActionActivateKeyPair         ActionType = "activate-keypair"
+	ActionVerifyHandshake         ActionType = "verify-handshake"
+	ActionRotateJWTKeypair        ActionType = "rotate-jwt-keypair"
+	ActionRetireJWTKeys           ActionType = "retire-jwt-keys"
+	ActionRevokeHostJWT           ActionType = "revoke-host-jwt"
+	ActionLiftJWTRevocation       ActionType = "lift-jwt-revocation"
 )
 
 // PlannedAction is one step that apply must execute on a host.
@@ -58,6 +72,9 @@ type Plan struct {
 	MgmtAssignments map[string]net.IP
 	// SubnetAssignments maps namespace → host → planned container subnet.
 	SubnetAssignments map[string]map[string]*net.IPNet
+	// SubnetAssignments6 maps namespace → host → planned IPv6 subnet of a
+	// dual-stack bridge; nil without DesiredMesh.ContainerPool6.
+	SubnetAssignments6 map[string]map[string]*net.IPNet
 	// Warnings contains non-fatal conflict messages from the IP allocator.
 	Warnings []Warning
 	// Skipped lists actions that were filtered out by the Intent gate
@@ -104,22 +121,46 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 		}
 	}
 
-	mgmtAssignments, mgmtWarns, err := AllocateMgmtIPs(desired.MgmtPool, current.AssignedMgmtIPs(), desired.Hosts)
+	existingMgmt := current.AssignedMgmtIPs()
+	existingSubnets := current.AssignedContainerSubnets()
+	existingSubnets6 := current.AssignedContainerSubnets6()
+	releaseRemovedHosts(desired, existingMgmt, existingSubnets, existingSubnets6)
+
+	mgmtAssignments, mgmtWarns, err := AllocateMgmtIPs(desired.MgmtPool, existingMgmt, desired.Hosts)
 	if err != nil {
 		return nil, fmt.Errorf("mgmt IP allocation: %w", err)
 	}
 
 	containerAssignments, contWarns, err := AllocateNamespaced(
 		desired.ContainerPool, desired.ContainerPrefix,
-		current.AssignedContainerSubnets(), desired.Namespaces, desired.Hosts)
+		existingSubnets, desired.Namespaces, desired.Hosts)
 	if err != nil {
 		return nil, fmt.Errorf("container subnet allocation: %w", err)
 	}
+	var containerAssignments6 map[string]map[string]*net.IPNet
+	if desired.ContainerPool6 != nil {
+		var cont6Warns []Warning
+		containerAssignments6, cont6Warns, err = AllocateNamespaced(
+			desired.ContainerPool6, desired.ContainerPrefix6,
+			existingSubnets6, desired.Namespaces, desired.Hosts)
+		if err != nil {
+			return nil, fmt.Errorf("IPv6 container subnet allocation: %w", err)
+		}
+		contWarns = append(contWarns, cont6Warns...)
+	}
+
+	drain, teardown, removeWarns := planRemoval(desired, current)
 
 	plan := &Plan{
-		MgmtAssignments:   mgmtAssignments,
-		SubnetAssignments: containerAssignments,
-		Warnings:          append(mgmtWarns, contWarns...),
+		MgmtAssignments:    mgmtAssignments,
+		SubnetAssignments:  containerAssignments,
+		SubnetAssignments6: containerAssignments6,
+		Warnings:           append(append(mgmtWarns, contWarns...), removeWarns...),
+	}
+
+	// --- Remove-host drain (runs before the survivors are touched) ---
+	for _, s := range drain {
+		plan.Actions = append(plan.Actions, s.Action)
 	}
 
 	nsSorted := desired.SortedNamespaces()
@@ -138,7 +179,7 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 			plan.Actions = append(plan.Actions, PlannedAction{
 				Host:   host,
 				Type:   ActionInstallWG,
-				Detail: "wireguard not installed",
+				Detail: "wireguard not installed" + viaPackageManager(state),
 			})
 		}
 
@@ -158,7 +199,7 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 			plan.Actions = append(plan.Actions, PlannedAction{
 				Host:   host,
 				Type:   ActionAllocateMgmtIP,
-				Detail: fmt.Sprintf("%s/32", mgmtIP),
+				Detail: HostCIDR(mgmtIP),
 			})
 		}
 
@@ -176,6 +217,15 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 						Detail:    contSubnet.String(),
 					})
 				}
+				if contSubnet6 := containerAssignments6[ns][host]; contSubnet6 != nil &&
+					(current == nil || !sameSubnet(current.ContainerSubnet6, contSubnet6)) {
+					plan.Actions = append(plan.Actions, PlannedAction{
+						Host:      host,
+						Namespace: ns,
+						Type:      ActionAllocateContainerSubnet,
+						Detail:    contSubnet6.String(),
+					})
+				}
 			}
 		}
 
@@ -189,6 +239,9 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 				desiredPeerKeys[ps.PublicKey] = true
 			}
 		}
+		if op := current.operatorPeer(); op != nil {
+			desiredPeerKeys[op.PublicKey] = true
+		}
 
 		currentPeerKeys := make(map[string]bool)
 		for _, p := range state.Peers {
@@ -216,7 +269,7 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 
 		// --- Config write ---
 		mgmtMismatch := state.WireGuardMgmtIP == nil || !state.WireGuardMgmtIP.Equal(mgmtIP)
-		allowedIPsDrift := allowedIPsNeedsRewrite(host, desired, current, containerAssignments, mgmtAssignments, state)
+		allowedIPsDrift := allowedIPsNeedsRewrite(host, desired, current, containerAssignments, containerAssignments6, mgmtAssignments, state)
 		needsConfig := mgmtMismatch ||
 			allowedIPsDrift ||
 			len(plan.actionsForHost(host, ActionAddPeer)) > 0 ||
@@ -254,7 +307,7 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 				plan.Actions = append(plan.Actions, PlannedAction{
 					Host:   host,
 					Type:   ActionInstallPodman,
-					Detail: "podman not installed",
+					Detail: "podman not installed" + viaPackageManager(state),
 				})
 			}
 			if !state.PodmanSocketActive {
@@ -264,39 +317,51 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 					Detail: "systemctl enable --now podman.socket",
 				})
 			}
-			if !state.IPForwardEnabled {
+			if desired.needsIPForward(state) {
+				detail := "net.ipv4.ip_forward=1"
+				if desired.ContainerIPv6() {
+					detail += ", net.ipv6.conf.all.forwarding=1"
+				}
 				plan.Actions = append(plan.Actions, PlannedAction{
 					Host:   host,
 					Type:   ActionEnableIPForward,
-					Detail: "net.ipv4.ip_forward=1",
+					Detail: detail,
 				})
 			}
 
 			for _, ns := range nsSorted {
 				contSubnet := containerAssignments[ns][host]
+				contSubnet6 := containerAssignments6[ns][host]
 				netName := PodmanNetworkFor(ns)
 				nss := state.Namespaces[ns]
 				gw := MachineIP(contSubnet)
 
 				if nss == nil || !nss.NetworkExists {
+					detail := fmt.Sprintf("%s subnet=%s gateway=%s", netName, contSubnet, gw)
+					if contSubnet6 != nil {
+						detail += fmt.Sprintf(" subnet=%s gateway=%s", contSubnet6, MachineIP(contSubnet6))
+					}
 					plan.Actions = append(plan.Actions, PlannedAction{
 						Host:      host,
 						Namespace: ns,
 						Type:      ActionCreatePodmanNet,
-						Detail:    fmt.Sprintf("%s subnet=%s gateway=%s", netName, contSubnet, gw),
+						Detail:    detail,
 					})
 					continue
 				}
-				if nss.DNSEnabled ||
-					(nss.ContainerSubnet != nil && nss.ContainerSubnet.String() != contSubnet.String()) ||
-					nss.Label != ns {
+				subnetDrift := nss.ContainerSubnet != nil && nss.ContainerSubnet.String() != contSubnet.String()
+				subnet6Drift := !sameSubnet(nss.ContainerSubnet6, contSubnet6)
+				if nss.DNSEnabled || subnetDrift || subnet6Drift || nss.Label != ns {
 					reasons := []string{}
 					if nss.DNSEnabled {
 						reasons = append(reasons, "dns_enabled=true")
 					}
-					if nss.ContainerSubnet != nil && nss.ContainerSubnet.String() != contSubnet.String() {
+					if subnetDrift {
 						reasons = append(reasons, fmt.Sprintf("subnet drift (have %s, want %s)", nss.ContainerSubnet, contSubnet))
 					}
+					if subnet6Drift {
+						reasons = append(reasons, fmt.Sprintf("IPv6 subnet drift (have %s, want %s)", subnetOrNone(nss.ContainerSubnet6), subnetOrNone(contSubnet6)))
+					}
 					if nss.Label != ns {
 						reasons = append(reasons, fmt.Sprintf("label=%q mismatch", nss.Label))
 					}
@@ -311,10 +376,7 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 
 			// Expected firewall unit text — hash it and compare against the
 			// remote unit so adding/removing a namespace reinstalls the unit.
-			var subnets []*net.IPNet
-			for _, ns := range nsSorted {
-				subnets = append(subnets, containerAssignments[ns][host])
-			}
+			subnets := hostContainerSubnets(host, nsSorted, containerAssignments, containerAssignments6)
 			expectedUnit := FirewallServiceUnit(desired.Interface, desired.SortedNamespaces(), subnets, desired.DefaultDenyContainers)
 			expectedUnitHash := sha256Hex([]byte(expectedUnit))
 			unitDrift := state.FirewallUnitSha256 != expectedUnitHash
@@ -323,7 +385,7 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 				state.DefaultDenyActive != desired.DefaultDenyContainers ||
 				unitDrift {
 				detail := fmt.Sprintf("coolify-mesh-fw.service (%s, %d namespace(s), default-deny=%v)",
-					desired.Interface, len(subnets), desired.DefaultDenyContainers)
+					desired.Interface, len(nsSorted), desired.DefaultDenyContainers)
 				if unitDrift && state.FirewallUnitSha256 != "" {
 					detail += " [unit drift]"
 				}
@@ -368,11 +430,14 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 					Detail: fmt.Sprintf("/etc/corrosion/config.toml (peers=%d)", len(peers)),
 				})
 			}
-			expectedSchemaSha := sha256Hex([]byte(services.CoolifySchemaSQL))
-			schemaDrift := state.CorrosionSchemaSha256 != expectedSchemaSha
-			if !state.CorrosionSchemaExists || schemaDrift {
-				detail := "/etc/corrosion/schemas/coolify.sql"
-				if schemaDrift && state.CorrosionSchemaSha256 != "" {
+			// A known older revision is migrated in place and needs no
+			// restart; corrosion only reloads the schema file at start.
+			schema, schemaFrom := classifySchema(state)
+			schemaDrift := schema == schemaFirstWrite || schema == schemaWipe
+			switch schema {
+			case schemaFirstWrite, schemaWipe:
+				detail := services.CorrosionSchemaPath
+				if schema == schemaWipe {
 					detail += " [schema drift — DB will be reset]"
 				}
 				plan.Actions = append(plan.Actions, PlannedAction{
@@ -380,6 +445,18 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 					Type:   ActionWriteCorrosionSchema,
 					Detail: detail,
 				})
+			case schemaMigrate:
+				plan.Actions = append(plan.Actions, PlannedAction{
+					Host:   host,
+					Type:   ActionMigrateCorrosionSchema,
+					Detail: migrationDetail(schemaFrom),
+				})
+			case schemaNewer:
+				plan.Warnings = append(plan.Warnings, Warning{
+					Host: host,
+					Reason: fmt.Sprintf("corrosion schema v%d is newer than this CLI's v%d; leaving it untouched",
+						schemaFrom, services.CorrosionSchemaVersion),
+				})
 			}
 
 			nsConfigs := buildNamespaceConfigs(host, nsSorted, containerAssignments)
@@ -428,18 +505,28 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 			},
 		)
 
-		// Per-host: JWT + coold unit rewrite (inject scheduler env).
+		// Per-host: JWT + coold unit rewrite (inject scheduler env). A
+		// revoked host keeps its rejected token until `init jwt rotate`
+		// lifts the revocation.
 		for _, host := range desired.Hosts {
-			plan.Actions = append(plan.Actions,
-				PlannedAction{
+			if sub := mgmtAssignments[host]; sub != nil && desired.JWTRevoked(sub.String()) {
+				plan.Warnings = append(plan.Warnings, Warning{
+					Host: host,
+					Reason: fmt.Sprintf("host JWT of %s is revoked; not re-issued (lift with `coolify init jwt rotate --hosts %s`)",
+						sub, host),
+				})
+			} else {
+				plan.Actions = append(plan.Actions, PlannedAction{
 					Host:   host,
 					Type:   ActionWriteHostJWT,
-					Detail: services.HostJWTPath,
-				},
+					Detail: fmt.Sprintf("%s (caps=%s)", services.HostJWTPath, strings.Join(desired.HostCaps(host, current.Servers[host]), ",")),
+				})
+			}
+			plan.Actions = append(plan.Actions,
 				PlannedAction{
 					Host:   host,
 					Type:   ActionUpdateCooldSchedulerEnv,
-					Detail: "coold.service += SCHEDULER_URL + HOST_JWT_PATH",
+					Detail: "coold.service += SCHEDULER_URL + HOST_JWT_PATH + CAPS",
 				},
 			)
 		}
@@ -467,6 +554,11 @@ func BuildPlan(desired *DesiredMesh, current MeshState) (*Plan, error) {
 		}
 	}
 
+	// --- Remove-host teardown (runs once the survivors dropped the peer) ---
+	for _, s := range teardown {
+		plan.Actions = append(plan.Actions, s.Action)
+	}
+
 	filterByIntent(plan, desired)
 
 	return plan, nil
@@ -498,6 +590,37 @@ func buildNamespaceConfigs(host string, nsSorted []string, assignments map[strin
 	return out
 }
 
+// hostContainerSubnets returns host's container subnets in namespace order,
+// the IPv6 subnet of a dual-stack bridge right after its namespace's
+// primary one.
+func hostContainerSubnets(host string, nsSorted []string, assignments, assignments6 map[string]map[string]*net.IPNet) []*net.IPNet {
+	var out []*net.IPNet
+	for _, ns := range nsSorted {
+		for _, sn := range []*net.IPNet{assignments[ns][host], assignments6[ns][host]} {
+			if sn != nil {
+				out = append(out, sn)
+			}
+		}
+	}
+	return out
+}
+
+// sameSubnet reports whether a and b are the same subnet, or both nil.
+func sameSubnet(a, b *net.IPNet) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.String() == b.String()
+}
+
+// subnetOrNone renders sn, or "none" for nil.
+func subnetOrNone(sn *net.IPNet) string {
+	if sn == nil {
+		return "none"
+	}
+	return sn.String()
+}
+
 // binaryVersionDrift returns true when a binary needs (re-)installation.
 // Rules:
 //   - not installed → always drift
@@ -515,12 +638,13 @@ func binaryVersionDrift(desiredVersion string, installed bool, haveVersion strin
 }
 
 // allowedIPsNeedsRewrite returns true when any [Peer] block on host does not
-// have the expected AllowedIPs (peer mgmt /32 + every namespace subnet).
+// have the expected AllowedIPs (peer mgmt host prefix + every namespace subnet).
 func allowedIPsNeedsRewrite(
 	host string,
 	desired *DesiredMesh,
 	current MeshState,
 	containerAssignments map[string]map[string]*net.IPNet,
+	containerAssignments6 map[string]map[string]*net.IPNet,
 	mgmtAssignments map[string]net.IP,
 	state *ServerState,
 ) bool {
@@ -543,14 +667,15 @@ func allowedIPsNeedsRewrite(
 		if mgmtIP == nil {
 			continue
 		}
-		entries := map[string]struct{}{fmt.Sprintf("%s/32", mgmtIP): {}}
-		for _, ns := range nsSorted {
-			if sn := containerAssignments[ns][peer]; sn != nil {
-				entries[sn.String()] = struct{}{}
-			}
+		entries := map[string]struct{}{HostCIDR(mgmtIP): {}}
+		for _, sn := range hostContainerSubnets(peer, nsSorted, containerAssignments, containerAssignments6) {
+			entries[sn.String()] = struct{}{}
 		}
 		want[ps.PublicKey] = entries
 	}
+	if op := current.operatorPeer(); op != nil {
+		want[op.PublicKey] = map[string]struct{}{HostCIDR(op.MgmtIP): {}}
+	}
 
 	// Compare against parsed peers in the current config. If any desired peer
 	// has different AllowedIPs (missing or extra), we need to rewrite.
@@ -625,3 +750,11 @@ func truncateKey(key string) string {
 	}
 	return key[:8] + "..."
 }
+
+// viaPackageManager names the manager an install action will use.
+func viaPackageManager(state *ServerState) string {
+	if state.PackageManager == "" {
+		return " (package manager detected on the host)"
+	}
+	return fmt.Sprintf(" (%s via %s)", state.OSID, state.PackageManager)
+}
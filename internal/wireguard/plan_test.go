@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
 )
 
 var (
@@ -338,6 +340,56 @@ func TestBuildPlan_PodmanIdempotent(t *testing.T) {
 	assert.True(t, plan.IsEmpty(), "expected empty plan, got: %+v", plan.Actions)
 }
 
+func TestBuildPlan_DualStackContainerPool(t *testing.T) {
+	desired := desiredWithPodman()
+	desired.ContainerPool6 = mustParseCIDR("fd00:210::/48")
+	desired.ContainerPrefix6 = 64
+	current := MeshState{
+		Servers: map[string]*ServerState{
+			"1.1.1.1": convergedServer("1.1.1.1", "AAAAAAAA=", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24"),
+			"2.2.2.2": convergedServer("2.2.2.2", "BBBBBBBB=", "AAAAAAAA=", "100.64.0.2", "10.210.1.0/24"),
+		},
+	}
+
+	// An IPv4-only mesh gains an IPv6 subnet per host next to its /24.
+	plan, err := BuildPlan(desired, current)
+	require.NoError(t, err)
+	assert.Equal(t, "10.210.0.0/24", plan.SubnetAssignments[DefaultNamespace]["1.1.1.1"].String())
+	assert.Equal(t, "fd00:210::/64", plan.SubnetAssignments6[DefaultNamespace]["1.1.1.1"].String())
+	assert.Equal(t, "fd00:210:0:1::/64", plan.SubnetAssignments6[DefaultNamespace]["2.2.2.2"].String())
+
+	details := map[ActionType]string{}
+	for _, a := range plan.Actions {
+		if a.Host == "1.1.1.1" {
+			details[a.Type] = a.Detail
+		}
+	}
+	assert.Equal(t, "fd00:210::/64", details[ActionAllocateContainerSubnet])
+	assert.Contains(t, details[ActionRecreatePodmanNet], "IPv6 subnet drift (have none, want fd00:210::/64)")
+	assert.Contains(t, details[ActionEnableIPForward], "net.ipv6.conf.all.forwarding=1")
+	assert.Contains(t, details, ActionWriteConfig)
+	assert.Contains(t, details, ActionInstallFirewall)
+
+	peers := peerConfigs(desired, "1.1.1.1", map[string]string{"2.2.2.2": "BBBBBBBB="},
+		plan.MgmtAssignments, plan.SubnetAssignments, plan.SubnetAssignments6, nil)
+	cfg := RenderConfig(plan.MgmtAssignments["1.1.1.1"], 51820, peers)
+	assert.Contains(t, cfg, "AllowedIPs = 100.64.0.2/32, 10.210.1.0/24, fd00:210:0:1::/64")
+
+	// Once both bridges and configs carry both subnets the plan is empty.
+	for host, peer := range map[string]string{"1.1.1.1": "2.2.2.2", "2.2.2.2": "1.1.1.1"} {
+		s := current.Servers[host]
+		nss := s.Namespaces[DefaultNamespace]
+		nss.ContainerSubnet6 = plan.SubnetAssignments6[DefaultNamespace][host]
+		s.IP6ForwardEnabled = true
+		s.FirewallUnitSha256 = sha256Hex([]byte(FirewallServiceUnit("wg0", []string{"default"},
+			[]*net.IPNet{nss.ContainerSubnet, nss.ContainerSubnet6}, false)))
+		s.Peers[0].AllowedIPs = append(s.Peers[0].AllowedIPs, plan.SubnetAssignments6[DefaultNamespace][peer].String())
+	}
+	plan, err = BuildPlan(desired, current)
+	require.NoError(t, err)
+	assert.True(t, plan.IsEmpty(), "expected empty plan, got: %+v", plan.Actions)
+}
+
 func TestBuildPlan_PodmanNotRequested(t *testing.T) {
 	desired := desiredTwoHosts() // InstallPodman == false
 	current := MeshState{
@@ -656,3 +708,92 @@ func TestBuildPlan_CooldNightlyAlwaysDrifts(t *testing.T) {
 	assert.True(t, types[ActionInstallCoold], "nightly tag always triggers install-coold")
 	assert.True(t, types[ActionInstallCorrosion], "nightly tag always triggers install-corrosion")
 }
+
+func desiredRemoveSecondHost() *DesiredMesh {
+	d := desiredWithPodman()
+	d.Hosts = []string{"1.1.1.1"}
+	d.RemoveHosts = []string{"2.2.2.2"}
+	d.Intent = IntentRemoveHost
+	d.InstallCoold = true
+	d.CorrosionAPIPort = 8080
+	return d
+}
+
+func TestBuildPlan_RemoveHost(t *testing.T) {
+	removed := convergedServer("2.2.2.2", "BBBBBBBB=", "AAAAAAAA=", "100.64.0.2", "10.210.1.0/24")
+	removed.CooldInstalled = true
+	removed.CorrosionInstalled = true
+	current := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": convergedServer("1.1.1.1", "AAAAAAAA=", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24"),
+		"2.2.2.2": removed,
+	}}
+
+	plan, err := BuildPlan(desiredRemoveSecondHost(), current)
+	require.NoError(t, err)
+
+	var order []string
+	for _, a := range plan.Actions {
+		order = append(order, a.Host+" "+string(a.Type))
+	}
+	// Drain first, survivor refresh in the middle, teardown last.
+	require.GreaterOrEqual(t, len(order), 6)
+	assert.Equal(t, []string{"2.2.2.2 drain-host", "2.2.2.2 stop-agents"}, order[:2])
+	assert.Equal(t, []string{
+		"1.1.1.1 purge-corrosion-host",
+		"2.2.2.2 remove-podman-network",
+		"2.2.2.2 teardown-wg",
+	}, order[len(order)-3:])
+	assert.Contains(t, order, "1.1.1.1 remove-peer")
+	assert.Contains(t, order, "1.1.1.1 write-config")
+	assert.Contains(t, order, "1.1.1.1 write-corrosion-config")
+
+	// The removed host's mgmt IP and subnet are released, not kept reserved.
+	assert.NotContains(t, plan.MgmtAssignments, "2.2.2.2")
+	assert.NotContains(t, plan.SubnetAssignments[DefaultNamespace], "2.2.2.2")
+	assert.Equal(t, "100.64.0.1", plan.MgmtAssignments["1.1.1.1"].String())
+}
+
+func TestBuildPlan_RemoveUnreachableHostWarns(t *testing.T) {
+	current := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": convergedServer("1.1.1.1", "AAAAAAAA=", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24"),
+		"2.2.2.2": {Host: "2.2.2.2", Namespaces: map[string]*NamespaceServerState{}},
+	}}
+
+	plan, err := BuildPlan(desiredRemoveSecondHost(), current)
+	require.NoError(t, err)
+
+	for _, a := range plan.Actions {
+		assert.NotEqual(t, "2.2.2.2", a.Host, "unreachable host must get no actions")
+	}
+	require.NotEmpty(t, plan.Warnings)
+	assert.Equal(t, "2.2.2.2", plan.Warnings[len(plan.Warnings)-1].Host)
+
+	var types []ActionType
+	for _, a := range plan.Actions {
+		types = append(types, a.Type)
+	}
+	assert.Contains(t, types, ActionRemovePeer)
+}
+
+func TestBuildPlan_SkipsHostJWTOfRevokedHost(t *testing.T) {
+	desired := desiredTwoHosts()
+	desired.CentralHost = "1.1.1.1"
+	desired.JWTRevocations = []services.JWTRevocation{{Subject: "100.64.0.2", Before: 1, Host: "2.2.2.2"}}
+	current := MeshState{Servers: map[string]*ServerState{
+		"1.1.1.1": convergedServer("1.1.1.1", "AAAAAAAA=", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24"),
+		"2.2.2.2": convergedServer("2.2.2.2", "BBBBBBBB=", "AAAAAAAA=", "100.64.0.2", "10.210.1.0/24"),
+	}}
+
+	plan, err := BuildPlan(desired, current)
+	require.NoError(t, err)
+	var jwtHosts []string
+	for _, a := range plan.Actions {
+		if a.Type == ActionWriteHostJWT {
+			jwtHosts = append(jwtHosts, a.Host)
+		}
+	}
+	assert.Equal(t, []string{"1.1.1.1"}, jwtHosts)
+	require.Len(t, plan.Warnings, 1)
+	assert.Equal(t, "2.2.2.2", plan.Warnings[0].Host)
+	assert.Contains(t, plan.Warnings[0].Reason, "jwt rotate --hosts 2.2.2.2")
+}
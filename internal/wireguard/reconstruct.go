@@ -2,212 +2,314 @@ package wireguard
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
 	"strings"
 
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
 	"github.com/coollabsio/coolify-cli/internal/ssh"
 )
 
-// Probe SSHes into host and reads its current WireGuard + Podman state.
-// All commands use `|| true` so a missing package or interface never
-// causes a non-zero exit that would abort the probe.
-func Probe(ctx context.Context, runner ssh.Runner, host, user string, port int, iface string, namespaces []string) (*ServerState, error) {
-	state := &ServerState{
-		Host:       host,
-		Interface:  iface,
-		Namespaces: map[string]*NamespaceServerState{},
-	}
-
-	// 1. Check if WireGuard is installed.
-	stdout, _, _ := runner.Run(ctx, host, user, port,
-		`dpkg-query -W -f='${Status}' wireguard 2>/dev/null | grep -c 'install ok installed' || echo 0`)
-	if strings.TrimSpace(stdout) == "1" {
-		state.Installed = true
-	}
-
-	// 2. Read public key.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`cat /etc/wireguard/publickey 2>/dev/null || true`)
-	if pk := strings.TrimSpace(stdout); pk != "" {
-		state.PublicKey = pk
-		state.KeysExist = true
-	}
+// probeFact is one remote command whose trimmed stdout feeds ServerState.
+// Every command uses `|| true` / `|| echo` so a missing package or
+// interface never causes a non-zero exit that would abort the probe.
+type probeFact struct {
+	key string
+	cmd string
+}
 
-	// 3. Parse the config file for management IP and peer list.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		fmt.Sprintf(`cat /etc/wireguard/%s.conf 2>/dev/null || true`, iface))
-	if strings.TrimSpace(stdout) != "" {
-		parseConfigFile(state, stdout)
+// hostProbeFacts lists the host-level facts in probe order.
+func hostProbeFacts(iface string) []probeFact {
+	return []probeFact{
+		{"os_release", services.OSReleaseCommand},
+		// Every unit the mesh installs is a systemd unit (see checkInitSystem).
+		{"systemd", `test -d /run/systemd/system && echo yes || echo no`},
+		// Installed checks pick the package manager from the host's
+		// os-release like installs do, without waiting for os_release to
+		// be parsed here.
+		{"wg_installed", services.PackageManager("").InstalledCommand(services.PackageWireGuard)},
+		{"wg_pubkey", `cat /etc/wireguard/publickey 2>/dev/null || true`},
+		{"wg_conf", fmt.Sprintf(`cat /etc/wireguard/%s.conf 2>/dev/null || true`, iface)},
+		{"wg_dump", fmt.Sprintf(`wg show %s dump 2>/dev/null || true`, iface)},
+		{"podman_installed", services.PackageManager("").InstalledCommand(services.PackagePodman)},
+		{"podman_socket", `systemctl is-active podman.socket 2>/dev/null || true`},
+		{"ip_forward", `sysctl -n net.ipv4.ip_forward 2>/dev/null || echo 0`},
+		{"ip6_forward", `sysctl -n net.ipv6.conf.all.forwarding 2>/dev/null || echo 0`},
+		// Firewall unit hash detects drift when the desired namespace set
+		// changes (FORWARD jumps gain/lose subnets).
+		{"fw_active", `systemctl is-active coolify-mesh-fw.service 2>/dev/null || true`},
+		{"fw_unit_sha", `sha256sum /etc/systemd/system/coolify-mesh-fw.service 2>/dev/null | awk '{print $1}' || true`},
+		// Default-deny scaffold present (COOLIFY-INTRA chain ends in DROP).
+		{"intra_drop", `iptables -nL COOLIFY-INTRA 2>/dev/null | grep -q DROP && echo yes || echo no`},
+		{"nft", `command -v nft >/dev/null 2>&1 && echo yes || echo no`},
+		{"bridge_table", `nft list table bridge coolify_bridge >/dev/null 2>&1 && echo yes || echo no`},
+		{"corrosion_installed", `test -x /usr/local/bin/corrosion && echo yes || echo no`},
+		{"corrosion_active", `systemctl is-active corrosion 2>/dev/null || true`},
+		{"corrosion_config_sha", `sha256sum /etc/corrosion/config.toml 2>/dev/null | awk '{print $1}' || true`},
+		{"corrosion_schema", `test -f /etc/corrosion/schemas/coolify.sql && echo yes || echo no`},
+		// Schema hash identifies the revision the host is on (see services.CorrosionMigrations).
+		{"corrosion_schema_sha", `sha256sum /etc/corrosion/schemas/coolify.sql 2>/dev/null | awk '{print $1}' || true`},
+		{"corrosion_schema_version", `cat /var/lib/corrosion/coolify-schema.version 2>/dev/null || true`},
+		{"coold_installed", `test -x /usr/local/bin/coold && echo yes || echo no`},
+		// Version markers are empty when absent / pre-migration.
+		{"corrosion_version", `cat /usr/local/bin/corrosion.version 2>/dev/null || true`},
+		{"coold_version", `cat /usr/local/bin/coold.version 2>/dev/null || true`},
+		{"coold_unit_sha", `sha256sum /etc/systemd/system/coold.service 2>/dev/null | awk '{print $1}' || true`},
+		{"coold_active", `systemctl is-active coold 2>/dev/null || true`},
+		// Only the caps claim is kept; the token itself is not stored.
+		{"host_jwt", fmt.Sprintf(`cat %s 2>/dev/null || true`, services.HostJWTPath)},
 	}
+}
 
-	// 4. Check if WG interface is currently up.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		fmt.Sprintf(`wg show %s dump 2>/dev/null || true`, iface))
-	if strings.TrimSpace(stdout) != "" {
-		state.Active = true
+// namespaceProbeFacts lists the per-namespace podman network facts. The
+// first fact (existence) gates the others in the sequential probe.
+func namespaceProbeFacts(ns string) []probeFact {
+	netName := shellQuote(PodmanNetworkFor(ns))
+	prefix := "ns." + ns + "."
+	return []probeFact{
+		{prefix + "exists", fmt.Sprintf(`podman network exists %s 2>/dev/null && echo yes || echo no`, netName)},
+		{prefix + "subnet", fmt.Sprintf(`podman network inspect %s -f '{{range .Subnets}}{{.Subnet}} {{end}}' 2>/dev/null || true`, netName)},
+		{prefix + "dns", fmt.Sprintf(`podman network inspect %s -f '{{.DNSEnabled}}' 2>/dev/null || true`, netName)},
+		{prefix + "label", fmt.Sprintf(`podman network inspect %s -f '{{index .Labels "io.coolify.namespace"}}' 2>/dev/null || true`, netName)},
 	}
+}
 
-	// 5. Podman package installed.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`dpkg-query -W -f='${Status}' podman 2>/dev/null | grep -c 'install ok installed' || echo 0`)
-	if strings.TrimSpace(stdout) == "1" {
-		state.PodmanInstalled = true
+// Probe SSHes into host and reads its current WireGuard + Podman state,
+// one command per fact. ProbeBatch collects the same facts in a single
+// round trip and is preferred by Reconstruct.
+func Probe(ctx context.Context, runner ssh.Runner, host, user string, port int, iface string, namespaces []string) (*ServerState, error) {
+	facts := map[string]string{}
+	run := func(f probeFact) string {
+		stdout, _, _ := runner.Run(ctx, host, user, port, f.cmd)
+		facts[f.key] = stdout
+		return strings.TrimSpace(stdout)
 	}
 
-	// 6. podman.socket active.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`systemctl is-active podman.socket 2>/dev/null || true`)
-	if strings.TrimSpace(stdout) == "active" {
-		state.PodmanSocketActive = true
+	for _, f := range hostProbeFacts(iface) {
+		run(f)
 	}
-
-	// 7. Per-namespace podman network state.
-	if state.PodmanInstalled {
+	if strings.TrimSpace(facts["podman_installed"]) == "1" {
 		for _, ns := range namespaces {
-			nss := &NamespaceServerState{Namespace: ns}
-			netName := PodmanNetworkFor(ns)
-			stdout, _, _ = runner.Run(ctx, host, user, port,
-				fmt.Sprintf(`podman network exists %s 2>/dev/null && echo yes || echo no`, netName))
-			if strings.TrimSpace(stdout) == "yes" {
-				nss.NetworkExists = true
-				stdout, _, _ = runner.Run(ctx, host, user, port,
-					fmt.Sprintf(`podman network inspect %s -f '{{(index .Subnets 0).Subnet}}' 2>/dev/null || true`, netName))
-				if s := strings.TrimSpace(stdout); s != "" {
-					if _, n, err := net.ParseCIDR(s); err == nil {
-						nss.ContainerSubnet = n
-					}
-				}
-				stdout, _, _ = runner.Run(ctx, host, user, port,
-					fmt.Sprintf(`podman network inspect %s -f '{{.DNSEnabled}}' 2>/dev/null || true`, netName))
-				if strings.TrimSpace(stdout) == "true" {
-					nss.DNSEnabled = true
-				}
-				stdout, _, _ = runner.Run(ctx, host, user, port,
-					fmt.Sprintf(`podman network inspect %s -f '{{index .Labels "io.coolify.namespace"}}' 2>/dev/null || true`, netName))
-				nss.Label = strings.TrimSpace(stdout)
+			nsFacts := namespaceProbeFacts(ns)
+			if run(nsFacts[0]) != "yes" {
+				continue
+			}
+			for _, f := range nsFacts[1:] {
+				run(f)
 			}
-			state.Namespaces[ns] = nss
 		}
 	}
 
-	// 8. IP forwarding enabled.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`sysctl -n net.ipv4.ip_forward 2>/dev/null || echo 0`)
-	if strings.TrimSpace(stdout) == "1" {
-		state.IPForwardEnabled = true
+	if err := checkInitSystem(facts); err != nil {
+		return nil, err
 	}
+	return stateFromProbeFacts(host, iface, namespaces, facts), nil
+}
 
-	// 9. coolify-mesh-fw.service active.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`systemctl is-active coolify-mesh-fw.service 2>/dev/null || true`)
-	if strings.TrimSpace(stdout) == "active" {
-		state.FirewallActive = true
+// probeScript renders every fact into one POSIX shell script that prints a
+// single JSON object mapping fact keys to base64-encoded stdout. Base64
+// keeps multi-line output (the WireGuard config) JSON-safe without jq.
+func probeScript(iface string, namespaces []string) string {
+	facts := hostProbeFacts(iface)
+	for _, ns := range namespaces {
+		facts = append(facts, namespaceProbeFacts(ns)...)
 	}
+	return factScript(facts)
+}
 
-	// 9a. Firewall unit hash — detects drift when the desired namespace set
-	// changes (FORWARD jumps gain/lose subnets).
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`sha256sum /etc/systemd/system/coolify-mesh-fw.service 2>/dev/null | awk '{print $1}' || true`)
-	if h := strings.TrimSpace(stdout); h != "" {
-		state.FirewallUnitSha256 = h
+// factScript renders facts into the script format read by parseProbeOutput.
+// Keys carry namespace names, so each is JSON-encoded and shell-quoted.
+func factScript(facts []probeFact) string {
+	var sb strings.Builder
+	sb.WriteString(`_f() { printf '%s:"%s",' "$1" "$(base64 | tr -d '\n')"; }` + "\n")
+	sb.WriteString("printf '{'\n")
+	for _, f := range facts {
+		key, _ := json.Marshal(f.key)
+		fmt.Fprintf(&sb, "{ %s ; } 2>/dev/null | _f %s\n", f.cmd, shellQuote(string(key)))
 	}
+	sb.WriteString(`printf '"_done":""}'` + "\n")
+	return sb.String()
+}
 
-	// 10. Default-deny scaffold present (COOLIFY-INTRA chain ends in DROP).
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`iptables -nL COOLIFY-INTRA 2>/dev/null | grep -q DROP && echo yes || echo no`)
-	if strings.TrimSpace(stdout) == "yes" {
-		state.DefaultDenyActive = true // will be AND-ed with BridgeTableExists below
-	}
+// shellQuote wraps s in POSIX-shell single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// errProbeOutput marks a batched probe whose output could not be read, as
+// opposed to one that could not reach the host.
+var errProbeOutput = errors.New("decode probe output")
 
-	// 10a. nft binary available.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`command -v nft >/dev/null 2>&1 && echo yes || echo no`)
-	if strings.TrimSpace(stdout) == "yes" {
-		state.NftAvailable = true
+// parseProbeOutput decodes the JSON printed by probeScript.
+func parseProbeOutput(stdout string) (map[string]string, error) {
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", errProbeOutput, err)
+	}
+	if _, ok := raw["_done"]; !ok {
+		return nil, fmt.Errorf("%w: incomplete result", errProbeOutput)
+	}
+	facts := make(map[string]string, len(raw))
+	for k, v := range raw {
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: fact %s: %v", errProbeOutput, k, err)
+		}
+		facts[k] = string(decoded)
 	}
+	return facts, nil
+}
 
-	// 10b. nft bridge table for intra-namespace default-deny present.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`nft list table bridge coolify_bridge >/dev/null 2>&1 && echo yes || echo no`)
-	if strings.TrimSpace(stdout) == "yes" {
-		state.BridgeTableExists = true
+// ProbeBatch reads the same state as Probe with a single remote command.
+// A script that ran but exited non-zero is judged by its output; any other
+// error means the host could not be reached.
+func ProbeBatch(ctx context.Context, runner ssh.Runner, host, user string, port int, iface string, namespaces []string) (*ServerState, error) {
+	stdout, stderr, err := runner.Run(ctx, host, user, port, probeScript(iface, namespaces))
+	var exitErr *gossh.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("batched probe: %w (stderr: %s)", err, strings.TrimSpace(stderr))
+	}
+	facts, err := parseProbeOutput(stdout)
+	if err != nil {
+		return nil, err
 	}
+	if err := checkInitSystem(facts); err != nil {
+		return nil, err
+	}
+	return stateFromProbeFacts(host, iface, namespaces, facts), nil
+}
 
-	state.DefaultDenyActive = state.DefaultDenyActive && state.BridgeTableExists
+// errNoSystemd marks a host that was not booted with systemd.
+var errNoSystemd = errors.New("host does not run systemd")
 
-	// 11. Corrosion binary installed.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`test -x /usr/local/bin/corrosion && echo yes || echo no`)
-	if strings.TrimSpace(stdout) == "yes" {
-		state.CorrosionInstalled = true
+// checkInitSystem rejects hosts whose probe reported no systemd. WireGuard,
+// the firewall, Podman's socket, Corrosion and coold are all managed as
+// systemd units, so an OpenRC host (e.g. stock Alpine) cannot join the mesh
+// even though its packages install. A missing fact is not a rejection.
+func checkInitSystem(facts map[string]string) error {
+	if strings.TrimSpace(facts["systemd"]) != "no" {
+		return nil
 	}
-
-	// 12. Corrosion systemd service active.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`systemctl is-active corrosion 2>/dev/null || true`)
-	if strings.TrimSpace(stdout) == "active" {
-		state.CorrosionActive = true
+	id, _ := services.ParseOSRelease(facts["os_release"])
+	if id == "" {
+		id = "unknown distro"
 	}
+	return fmt.Errorf("%w (%s): the mesh manages WireGuard, Podman, Corrosion and coold as systemd units; OpenRC and other init systems are not supported", errNoSystemd, id)
+}
 
-	// 13. Corrosion config hash (empty when missing).
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`sha256sum /etc/corrosion/config.toml 2>/dev/null | awk '{print $1}' || true`)
-	if h := strings.TrimSpace(stdout); h != "" {
-		state.CorrosionConfigHash = h
+// bridgeSubnets splits the space-separated subnets of a podman network into
+// its ContainerSubnet and, on a dual-stack bridge (IPv4 and IPv6), its
+// ContainerSubnet6. A single-family bridge only sets the first.
+func bridgeSubnets(fact string) (primary, v6 *net.IPNet) {
+	var v4s, v6s []*net.IPNet
+	for _, f := range strings.Fields(fact) {
+		_, n, err := net.ParseCIDR(f)
+		if err != nil {
+			continue
+		}
+		if IsIPv6(n.IP) {
+			v6s = append(v6s, n)
+		} else {
+			v4s = append(v4s, n)
+		}
 	}
-
-	// 14. Corrosion schema file present.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`test -f /etc/corrosion/schemas/coolify.sql && echo yes || echo no`)
-	if strings.TrimSpace(stdout) == "yes" {
-		state.CorrosionSchemaExists = true
+	switch {
+	case len(v4s) > 0 && len(v6s) > 0:
+		return v4s[0], v6s[0]
+	case len(v4s) > 0:
+		return v4s[0], nil
+	case len(v6s) > 0:
+		return v6s[0], nil
 	}
+	return nil, nil
+}
+
+// stateFromProbeFacts interprets raw fact output. Missing facts read as
+// empty output, i.e. "not installed / not active".
+func stateFromProbeFacts(host, iface string, namespaces []string, facts map[string]string) *ServerState {
+	fact := func(key string) string { return strings.TrimSpace(facts[key]) }
 
-	// 14a. sha256 of remote schema file (empty when absent). Used to detect
-	// schema revisions so a new schema triggers re-write + DB reset.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`sha256sum /etc/corrosion/schemas/coolify.sql 2>/dev/null | awk '{print $1}' || true`)
-	if h := strings.TrimSpace(stdout); h != "" {
-		state.CorrosionSchemaSha256 = h
+	state := &ServerState{
+		Host:       host,
+		Interface:  iface,
+		Namespaces: map[string]*NamespaceServerState{},
 	}
 
-	// 15. Coold binary installed.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`test -x /usr/local/bin/coold && echo yes || echo no`)
-	if strings.TrimSpace(stdout) == "yes" {
-		state.CooldInstalled = true
+	state.OSID, _ = services.ParseOSRelease(facts["os_release"])
+	state.PackageManager = services.DetectPackageManager(facts["os_release"])
+	state.Installed = fact("wg_installed") == "1"
+	if pk := fact("wg_pubkey"); pk != "" {
+		state.PublicKey = pk
+		state.KeysExist = true
+	}
+	if fact("wg_conf") != "" {
+		parseConfigFile(state, facts["wg_conf"])
+	}
+	state.Active = fact("wg_dump") != ""
+	if state.Active {
+		handshakes := map[string]int64{}
+		for _, p := range ParseWGDumpPeers(facts["wg_dump"]) {
+			handshakes[p.PublicKey] = p.LatestHandshake
+		}
+		for i := range state.Peers {
+			state.Peers[i].LatestHandshake = handshakes[state.Peers[i].PublicKey]
+		}
 	}
 
-	// 15a. version marker for corrosion (empty when absent / pre-migration).
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`cat /usr/local/bin/corrosion.version 2>/dev/null || true`)
-	state.CorrosionVersion = strings.TrimSpace(stdout)
-
-	// 15b. version marker for coold (empty when absent / pre-migration).
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`cat /usr/local/bin/coold.version 2>/dev/null || true`)
-	state.CooldVersion = strings.TrimSpace(stdout)
-
-	// 15c. sha256 of remote coold.service unit (empty when absent).
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`sha256sum /etc/systemd/system/coold.service 2>/dev/null | awk '{print $1}' || true`)
-	if h := strings.TrimSpace(stdout); h != "" {
-		state.CooldUnitSha256 = h
+	state.PodmanInstalled = fact("podman_installed") == "1"
+	state.PodmanSocketActive = fact("podman_socket") == "active"
+
+	if state.PodmanInstalled {
+		for _, ns := range namespaces {
+			prefix := "ns." + ns + "."
+			nss := &NamespaceServerState{Namespace: ns}
+			if fact(prefix+"exists") == "yes" {
+				nss.NetworkExists = true
+				nss.ContainerSubnet, nss.ContainerSubnet6 = bridgeSubnets(fact(prefix + "subnet"))
+				nss.DNSEnabled = fact(prefix+"dns") == "true"
+				nss.Label = fact(prefix + "label")
+			}
+			state.Namespaces[ns] = nss
+		}
 	}
 
-	// 16. Coold systemd service active.
-	stdout, _, _ = runner.Run(ctx, host, user, port,
-		`systemctl is-active coold 2>/dev/null || true`)
-	if strings.TrimSpace(stdout) == "active" {
-		state.CooldActive = true
+	state.IPForwardEnabled = fact("ip_forward") == "1"
+	state.IP6ForwardEnabled = fact("ip6_forward") == "1"
+	state.FirewallActive = fact("fw_active") == "active"
+	state.FirewallUnitSha256 = fact("fw_unit_sha")
+	state.NftAvailable = fact("nft") == "yes"
+	state.BridgeTableExists = fact("bridge_table") == "yes"
+	state.DefaultDenyActive = fact("intra_drop") == "yes" && state.BridgeTableExists
+
+	state.CorrosionInstalled = fact("corrosion_installed") == "yes"
+	state.CorrosionActive = fact("corrosion_active") == "active"
+	state.CorrosionConfigHash = fact("corrosion_config_sha")
+	state.CorrosionSchemaExists = fact("corrosion_schema") == "yes"
+	state.CorrosionSchemaSha256 = fact("corrosion_schema_sha")
+	state.CorrosionSchemaVersion, _ = strconv.Atoi(fact("corrosion_schema_version"))
+	state.CooldInstalled = fact("coold_installed") == "yes"
+	state.CorrosionVersion = fact("corrosion_version")
+	state.CooldVersion = fact("coold_version")
+	state.CooldUnitSha256 = fact("coold_unit_sha")
+	state.CooldActive = fact("coold_active") == "active"
+	if token := fact("host_jwt"); token != "" {
+		if c, err := services.ParseHostJWT(token); err == nil {
+			state.HostCaps = c.Caps
+		}
 	}
 
-	return state, nil
+	return state
 }
 
-// Reconstruct runs Probe on every host in parallel and assembles a MeshState.
+// Reconstruct runs ProbeBatch on every host in parallel and assembles a
+// MeshState. Probe is the fallback for hosts whose batched output cannot be
+// read; unreachable hosts are reported as errors.
 func Reconstruct(
 	ctx context.Context,
 	runner ssh.Runner,
@@ -219,7 +321,13 @@ func Reconstruct(
 	concurrency int,
 ) (MeshState, error) {
 	results := ssh.ForEachServer(ctx, hosts, concurrency, func(ctx context.Context, host string) (*ServerState, error) {
-		return Probe(ctx, runner, host, user, port, iface, namespaces)
+		state, err := ProbeBatch(ctx, runner, host, user, port, iface, namespaces)
+		if errors.Is(err, errProbeOutput) && ctx.Err() == nil {
+			// Hosts without base64 or with an unusual login shell still
+			// answer the per-command probe.
+			return Probe(ctx, runner, host, user, port, iface, namespaces)
+		}
+		return state, err
 	})
 
 	mesh := MeshState{Servers: make(map[string]*ServerState, len(hosts))}
@@ -280,10 +388,12 @@ func parseConfigFile(state *ServerState, content string) {
 			switch strings.ToLower(key) {
 			case "address":
 				// Parse the host portion of "<ip>/<prefix>"; this is the
-				// actual management IP, not the network address.
-				ip, _, err := net.ParseCIDR(value)
+				// actual management IP, not the network address. Only the
+				// first address is the mgmt IP.
+				first, _, _ := strings.Cut(value, ",")
+				ip, _, err := net.ParseCIDR(strings.TrimSpace(first))
 				if err == nil {
-					state.WireGuardMgmtIP = ip.To4()
+					state.WireGuardMgmtIP = normalizeIP(ip)
 				}
 			case "listenport":
 				if p, err := strconv.Atoi(value); err == nil {
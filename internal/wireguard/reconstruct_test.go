@@ -2,13 +2,17 @@ package wireguard
 
 import (
 	"context"
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
 )
 
 // fakeReconRunner is a deterministic ssh.Runner for reconstruct unit tests.
@@ -148,6 +152,21 @@ func TestMeshState_AssignedContainerSubnets(t *testing.T) {
 	assert.Contains(t, subs["alpha"], "a")
 }
 
+func TestBridgeSubnets(t *testing.T) {
+	for _, tt := range []struct {
+		fact, primary, v6 string
+	}{
+		{"10.210.0.0/24 ", "10.210.0.0/24", "<nil>"},
+		{"fd00:210::/64 ", "fd00:210::/64", "<nil>"},
+		{"fd00:210::/64 10.210.0.0/24 ", "10.210.0.0/24", "fd00:210::/64"},
+		{"", "<nil>", "<nil>"},
+	} {
+		primary, v6 := bridgeSubnets(tt.fact)
+		assert.Equal(t, tt.primary, primary.String(), tt.fact)
+		assert.Equal(t, tt.v6, v6.String(), tt.fact)
+	}
+}
+
 func TestTruncateKey(t *testing.T) {
 	tests := []struct {
 		input string
@@ -217,3 +236,142 @@ func TestProbe_NftNotAvailable_BridgeTableAbsent(t *testing.T) {
 	// DefaultDenyActive must be false even though COOLIFY-INTRA has DROP
 	assert.False(t, state.DefaultDenyActive, "DefaultDenyActive should be false when BridgeTableExists is false")
 }
+
+func TestProbe_DetectsPackageManagerFromOSRelease(t *testing.T) {
+	runner := &fakeReconRunner{
+		responses: map[string]string{
+			"os-release": "NAME=\"Rocky Linux\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\n",
+			"rpm -q":     "1\n",
+		},
+	}
+
+	state, err := Probe(context.Background(), runner, "1.1.1.1", "root", 22, "wg0", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "rocky", state.OSID)
+	assert.Equal(t, services.PackageManagerDnf, state.PackageManager)
+	assert.True(t, state.Installed)
+}
+
+func TestProbe_RejectsHostWithoutSystemd(t *testing.T) {
+	runner := &fakeReconRunner{
+		responses: map[string]string{
+			"os-release":          "NAME=\"Alpine Linux\"\nID=alpine\n",
+			"/run/systemd/system": "no\n",
+		},
+	}
+
+	_, err := Probe(context.Background(), runner, "1.1.1.1", "root", 22, "wg0", nil)
+	require.ErrorIs(t, err, errNoSystemd)
+	assert.ErrorContains(t, err, "alpine")
+
+	err = checkInitSystem(map[string]string{"systemd": "no\n"})
+	require.ErrorIs(t, err, errNoSystemd)
+	assert.NoError(t, checkInitSystem(map[string]string{"systemd": "yes\n"}))
+}
+
+// localShellRunner runs commands with the local /bin/sh so the batched probe
+// script is exercised by a real shell.
+type localShellRunner struct{ calls int }
+
+func (l *localShellRunner) Run(ctx context.Context, _, _ string, _ int, cmd string) (string, string, error) {
+	l.calls++
+	var stdout, stderr strings.Builder
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	err := c.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+func TestProbeBatch_MatchesSequentialProbe(t *testing.T) {
+	for _, bin := range []string{"sh", "base64", "tr"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not available", bin)
+		}
+	}
+
+	batchRunner := &localShellRunner{}
+	batch, batchErr := ProbeBatch(context.Background(), batchRunner, "h1", "root", 22, "wg0", []string{"default", "alpha"})
+	assert.Equal(t, 1, batchRunner.calls, "batched probe must use a single round trip")
+
+	seqRunner := &localShellRunner{}
+	seq, seqErr := Probe(context.Background(), seqRunner, "h1", "root", 22, "wg0", []string{"default", "alpha"})
+	assert.Greater(t, seqRunner.calls, 1)
+
+	assert.Equal(t, seqErr, batchErr)
+	if batchErr != nil {
+		require.ErrorIs(t, batchErr, errNoSystemd)
+		t.Skip("local host does not run systemd; only the rejection was compared")
+	}
+	assert.Equal(t, seq, batch)
+}
+
+func TestParseProbeOutput(t *testing.T) {
+	facts, err := parseProbeOutput(`{"wg_conf":"W0ludGVyZmFjZV0KQWRkcmVzcyA9IDEwMC42NC4wLjEvMzIK","nft":"eWVzCg==","_done":""}` + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, "[Interface]\nAddress = 100.64.0.1/32\n", facts["wg_conf"])
+
+	state := stateFromProbeFacts("h1", "wg0", nil, facts)
+	assert.True(t, state.NftAvailable)
+	assert.Equal(t, "100.64.0.1", state.WireGuardMgmtIP.String())
+
+	_, err = parseProbeOutput(`{"nft":"eWVzCg==",`)
+	assert.Error(t, err, "truncated output")
+	_, err = parseProbeOutput(`{"nft":"eWVzCg=="}`)
+	assert.Error(t, err, "missing terminator")
+}
+
+func TestStateFromProbeFacts_HostCaps(t *testing.T) {
+	priv, _ := newJWTKey(t)
+	token, err := services.MintHostJWT([]byte(priv), "100.64.0.1", []string{"coold", "gpu"})
+	require.NoError(t, err)
+
+	state := stateFromProbeFacts("h1", "wg0", nil, map[string]string{"host_jwt": token + "\n"})
+	assert.Equal(t, []string{"coold", "gpu"}, state.HostCaps)
+	assert.Equal(t, []string{"gpu"}, state.Labels())
+
+	state = stateFromProbeFacts("h1", "wg0", nil, map[string]string{"host_jwt": "garbage"})
+	assert.Nil(t, state.HostCaps)
+}
+
+func TestReconstruct_FallsBackToSequentialProbe(t *testing.T) {
+	// fakeReconRunner answers the batched script with a non-JSON response,
+	// so Reconstruct must fall back to per-command probing.
+	runner := &fakeReconRunner{responses: map[string]string{
+		"command -v nft": "yes\n",
+	}}
+	mesh, err := Reconstruct(context.Background(), runner, []string{"h1"}, "root", 22, "wg0", []string{"default"}, 1)
+	require.NoError(t, err)
+	assert.True(t, mesh.Servers["h1"].NftAvailable)
+}
+
+// unreachableRunner fails every command the way a dial or auth error does.
+type unreachableRunner struct{ calls int }
+
+func (u *unreachableRunner) Run(context.Context, string, string, int, string) (string, string, error) {
+	u.calls++
+	return "", "", errors.New("dial tcp 10.0.0.9:22: i/o timeout")
+}
+
+func TestReconstruct_ReportsUnreachableHostWithoutFallback(t *testing.T) {
+	runner := &unreachableRunner{}
+	mesh, err := Reconstruct(context.Background(), runner, []string{"h1"}, "root", 22, "wg0", nil, 1)
+	require.ErrorContains(t, err, "i/o timeout")
+	assert.Equal(t, 1, runner.calls, "a transport error must not fan out into per-fact probes")
+	assert.NotNil(t, mesh.Servers["h1"])
+}
+
+func TestProbeBatch_QuotesNamespaceKeys(t *testing.T) {
+	for _, bin := range []string{"sh", "base64", "tr"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not available", bin)
+		}
+	}
+	stdout, _, err := (&localShellRunner{}).Run(context.Background(), "h1", "root", 22,
+		probeScript("wg0", []string{`a\b`, `x"y`}))
+	require.NoError(t, err)
+	facts, err := parseProbeOutput(stdout)
+	require.NoError(t, err)
+	assert.Contains(t, facts, `ns.a\b.exists`)
+	assert.Contains(t, facts, `ns.x"y.exists`)
+}
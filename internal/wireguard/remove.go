@@ -0,0 +1,212 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// removalStep is a planned remove-host action plus the shell command apply
+// runs for it. BuildPlan only surfaces the action; ApplyMesh runs the command.
+type removalStep struct {
+	Action PlannedAction
+	Cmd    string
+}
+
+// releaseRemovedHosts drops the hosts leaving the mesh from the allocator
+// seed so their mgmt IP and container subnets return to the pools instead of
+// staying reserved.
+func releaseRemovedHosts(d *DesiredMesh, mgmt map[string]net.IP, subnets ...map[string]map[string]*net.IPNet) {
+	if d.Intent != IntentRemoveHost {
+		return
+	}
+	for _, h := range d.RemoveHosts {
+		delete(mgmt, h)
+		for _, assigned := range subnets {
+			for _, byHost := range assigned {
+				delete(byHost, h)
+			}
+		}
+	}
+}
+
+// planRemoval computes the remove-host steps. drain runs before the surviving
+// hosts are reconfigured: it stops the containers on the removed hosts'
+// bridges and stops coold/corrosion so nothing new lands there. teardown runs
+// once the survivors no longer peer with them: it purges the removed hosts'
+// rows from corrosion, deletes their bridges (releasing the container
+// subnets) and takes wg0 down (releasing the mgmt IP).
+//
+// Hosts that answered the probe with no mesh state at all (unreachable or
+// already wiped) get no steps and a warning; the survivors still drop them.
+func planRemoval(d *DesiredMesh, current MeshState) (drain, teardown []removalStep, warns []Warning) {
+	if d.Intent != IntentRemoveHost {
+		return nil, nil, nil
+	}
+	nsSorted := d.SortedNamespaces()
+
+	var purge []removalStep
+	for _, host := range d.RemoveHosts {
+		state := current.Servers[host]
+		if state == nil || (!state.Installed && !state.PodmanInstalled &&
+			!state.CooldInstalled && !state.CorrosionInstalled) {
+			warns = append(warns, Warning{
+				Host:   host,
+				Reason: "no mesh state found (unreachable or already removed); dropping it from every peer without draining",
+			})
+			continue
+		}
+
+		var nets []string
+		for _, ns := range nsSorted {
+			nss := state.Namespaces[ns]
+			if nss == nil || !nss.NetworkExists {
+				continue
+			}
+			netName := PodmanNetworkFor(ns)
+			nets = append(nets, ns)
+			drain = append(drain, removalStep{
+				Action: PlannedAction{
+					Host:      host,
+					Namespace: ns,
+					Type:      ActionDrainHost,
+					Detail:    fmt.Sprintf("stop containers on %s", netName),
+				},
+				Cmd: drainNetworkCmd(netName),
+			})
+		}
+
+		if state.CooldInstalled || state.CooldActive || state.CorrosionInstalled || state.CorrosionActive {
+			drain = append(drain, removalStep{
+				Action: PlannedAction{
+					Host:   host,
+					Type:   ActionStopAgents,
+					Detail: "systemctl disable --now coold corrosion; remove units, host JWT and corrosion DB",
+				},
+				Cmd: stopAgentsCmd,
+			})
+		}
+
+		if d.InstallCoold && state.WireGuardMgmtIP != nil && len(d.Hosts) > 0 {
+			purge = append(purge, removalStep{
+				Action: PlannedAction{
+					Host:   d.Hosts[0],
+					Type:   ActionPurgeCorrosionHost,
+					Detail: fmt.Sprintf("delete service_endpoints of %s (%s)", host, state.WireGuardMgmtIP),
+				},
+				Cmd: services.CorrosionPurgeHostCommand(d.CorrosionAPIPort, state.WireGuardMgmtIP),
+			})
+		}
+
+		for _, ns := range nets {
+			netName := PodmanNetworkFor(ns)
+			detail := netName
+			if nss := state.Namespaces[ns]; nss.ContainerSubnet != nil {
+				released := nss.ContainerSubnet.String()
+				if nss.ContainerSubnet6 != nil {
+					released += ", " + nss.ContainerSubnet6.String()
+				}
+				detail = fmt.Sprintf("%s (releases %s)", netName, released)
+			}
+			teardown = append(teardown, removalStep{
+				Action: PlannedAction{
+					Host:      host,
+					Namespace: ns,
+					Type:      ActionRemovePodmanNet,
+					Detail:    detail,
+				},
+				Cmd: fmt.Sprintf(`podman network rm -f %s 2>&1`, netName),
+			})
+		}
+
+		if state.Installed {
+			detail := fmt.Sprintf("wg-quick@%s down, config removed", d.Interface)
+			if state.WireGuardMgmtIP != nil {
+				detail += fmt.Sprintf(" (releases %s)", HostCIDR(state.WireGuardMgmtIP))
+			}
+			teardown = append(teardown, removalStep{
+				Action: PlannedAction{
+					Host:   host,
+					Type:   ActionTeardownWG,
+					Detail: detail,
+				},
+				Cmd: teardownWGCmd(d.Interface),
+			})
+		}
+	}
+
+	return drain, append(purge, teardown...), warns
+}
+
+// drainNetworkCmd stops every container attached to a namespace bridge.
+func drainNetworkCmd(netName string) string {
+	return fmt.Sprintf(`podman ps -q --filter network=%s | xargs -r podman stop -t 30 2>&1`, netName)
+}
+
+// stopAgentsCmd stops coold/corrosion for good. The corrosion DB is deleted
+// so a host that later rejoins via extend starts from a clean replica, and
+// the host JWT is deleted so it can no longer reach the scheduler.
+const stopAgentsCmd = `systemctl disable --now coold corrosion 2>/dev/null || true; ` +
+	`rm -f /etc/systemd/system/coold.service /etc/systemd/system/corrosion.service ` + services.HostJWTPath + ` && ` +
+	`rm -rf /var/lib/corrosion && ` +
+	`systemctl daemon-reload`
+
+// teardownWGCmd takes wg0 and the mesh firewall down and removes their
+// config. Keys stay in /etc/wireguard so an accidental removal can be undone
+// with extend without re-keying.
+func teardownWGCmd(iface string) string {
+	return fmt.Sprintf(
+		`systemctl disable --now %s wg-quick@%s 2>/dev/null || true; `+
+			`rm -f /etc/wireguard/%s.conf %s && systemctl daemon-reload`,
+		firewallServiceName, iface, iface, firewallUnitPath)
+}
+
+// runRemovalSteps runs steps grouped by host: hosts in parallel, each host's
+// steps in order, stopping at a host's first failure.
+func runRemovalSteps(
+	ctx context.Context,
+	runner ssh.Runner,
+	user string,
+	port int,
+	steps []removalStep,
+	concurrency int,
+) ([]ActionResult, error) {
+	var hosts []string
+	byHost := map[string][]removalStep{}
+	for _, s := range steps {
+		if _, ok := byHost[s.Action.Host]; !ok {
+			hosts = append(hosts, s.Action.Host)
+		}
+		byHost[s.Action.Host] = append(byHost[s.Action.Host], s)
+	}
+
+	rs := ssh.ForEachServer(ctx, hosts, concurrency,
+		func(ctx context.Context, host string) ([]ActionResult, error) {
+			var out []ActionResult
+			for _, s := range byHost[host] {
+				if err := runStep(ctx, runner, host, user, port, &out,
+					s.Action.Type, s.Action.Namespace, s.Cmd,
+					fmt.Sprintf("%s on %s", s.Action.Type, host)); err != nil {
+					return out, err
+				}
+			}
+			return out, nil
+		})
+
+	var results []ActionResult
+	var failed []string
+	for _, r := range rs {
+		results = append(results, r.Result...)
+		if r.Err != nil {
+			failed = append(failed, r.Host)
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("failed on %s", strings.Join(failed, ", "))
+	}
+	return results, nil
+}
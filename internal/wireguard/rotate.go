@@ -0,0 +1,367 @@
+package wireguard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// RotateOptions configures RotateKeys.
+type RotateOptions struct {
+	// Hosts are rotated one at a time, in order. Every host must be in
+	// DesiredMesh.Hosts.
+	Hosts []string
+
+	// PresharedKeys installs a fresh preshared key for every pair that
+	// includes a rotated host. Without it, existing preshared keys are kept.
+	PresharedKeys bool
+
+	// HandshakeTimeout bounds how long a rotated host may take to handshake
+	// with every peer again. PollInterval is the wait between checks.
+	HandshakeTimeout time.Duration
+	PollInterval     time.Duration
+}
+
+const (
+	// DefaultHandshakeTimeout covers one missed keepalive plus a retry.
+	DefaultHandshakeTimeout = 60 * time.Second
+	defaultPollInterval     = 2 * time.Second
+)
+
+// PlanRotation validates a rotation against the probed mesh and returns the
+// steps RotateKeys runs. Every host must be up: a peer that misses the new
+// public key would lose its tunnel to the rotated host.
+func PlanRotation(d *DesiredMesh, current MeshState, opts RotateOptions) ([]PlannedAction, error) {
+	if len(opts.Hosts) == 0 {
+		return nil, fmt.Errorf("no hosts to rotate")
+	}
+	inMesh := make(map[string]bool, len(d.Hosts))
+	for _, h := range d.Hosts {
+		inMesh[h] = true
+		s := current.Servers[h]
+		if s == nil || !s.Active || s.PublicKey == "" || s.WireGuardMgmtIP == nil {
+			return nil, fmt.Errorf("%s has no active %s with a key and mgmt IP; every host must be up to rotate keys", h, d.Interface)
+		}
+	}
+
+	var actions []PlannedAction
+	seen := map[string]bool{}
+	for _, h := range opts.Hosts {
+		if !inMesh[h] {
+			return nil, fmt.Errorf("%s is not in the mesh", h)
+		}
+		if seen[h] {
+			return nil, fmt.Errorf("%s listed twice", h)
+		}
+		seen[h] = true
+
+		actions = append(actions, PlannedAction{
+			Host: h, Type: ActionGenNextKeyPair,
+			Detail: "generate the next keypair next to the current one",
+		})
+		detail := fmt.Sprintf("replace the key of %s", h)
+		if opts.PresharedKeys {
+			detail += ", install a new preshared key"
+		}
+		for _, p := range d.Hosts {
+			if p != h {
+				actions = append(actions, PlannedAction{Host: p, Type: ActionUpdatePeerKey, Detail: detail})
+			}
+		}
+		actions = append(actions,
+			PlannedAction{
+				Host: h, Type: ActionActivateKeyPair,
+				Detail: "switch to the next keypair (previous kept as privatekey.old)",
+			},
+			PlannedAction{
+				Host: h, Type: ActionVerifyHandshake,
+				Detail: fmt.Sprintf("handshake with %d peer(s)", len(d.Hosts)-1),
+			},
+		)
+	}
+	return actions, nil
+}
+
+// RotateKeys replaces the WireGuard keypair of every host in opts.Hosts, one
+// host at a time, so only the links of the host being rotated are down at
+// any moment:
+//
+//  1. The host generates its next keypair without using it.
+//  2. Every peer swaps the host's public key (and preshared key) in wg0.conf
+//     and applies it with `wg syncconf`, which keeps other tunnels up.
+//  3. The host switches to the new keypair the same way.
+//  4. The host must complete a fresh handshake with every peer before the
+//     next host is rotated; otherwise rotation stops.
+//
+// The operator peer recorded in the ledger stays in every rewritten config.
+// Each host that switched keys gets its new public key in
+// current.Servers, so the caller can hand it on to the operator peer.
+func RotateKeys(
+	ctx context.Context,
+	runner ssh.Runner,
+	user string,
+	port int,
+	d *DesiredMesh,
+	current MeshState,
+	opts RotateOptions,
+	concurrency int,
+) ([]ActionResult, error) {
+	if _, err := PlanRotation(d, current, opts); err != nil {
+		return nil, err
+	}
+	if opts.HandshakeTimeout <= 0 {
+		opts.HandshakeTimeout = DefaultHandshakeTimeout
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+
+	mgmt := current.AssignedMgmtIPs()
+	subnets := current.AssignedContainerSubnets()
+	subnets6 := current.AssignedContainerSubnets6()
+	pubkeys := make(map[string]string, len(d.Hosts))
+	for _, h := range d.Hosts {
+		pubkeys[h] = current.Servers[h].PublicKey
+	}
+
+	operator := current.operatorPeer()
+
+	var results []ActionResult
+	for _, host := range opts.Hosts {
+		out, err := rotateHost(ctx, runner, user, port, d, host, pubkeys, mgmt, subnets, subnets6, operator, opts, concurrency)
+		results = append(results, out...)
+		for _, r := range out {
+			if r.Action.Type == ActionActivateKeyPair && r.Err == nil {
+				current.Servers[host].PublicKey = pubkeys[host]
+			}
+		}
+		if err != nil {
+			return results, fmt.Errorf("rotate %s: %w", host, err)
+		}
+	}
+	return results, nil
+}
+
+func rotateHost(
+	ctx context.Context,
+	runner ssh.Runner,
+	user string,
+	port int,
+	d *DesiredMesh,
+	host string,
+	pubkeys map[string]string,
+	mgmt map[string]net.IP,
+	subnets, subnets6 map[string]map[string]*net.IPNet,
+	operator *PeerConfig,
+	opts RotateOptions,
+	concurrency int,
+) ([]ActionResult, error) {
+	var out []ActionResult
+	record := func(t ActionType, h, detail string, err error) {
+		out = append(out, ActionResult{Action: PlannedAction{Host: h, Type: t, Detail: detail}, Err: err})
+	}
+
+	// 1. Next keypair.
+	stdout, stderr, err := runner.Run(ctx, host, user, port, genNextKeyPairCmd)
+	if err != nil {
+		record(ActionGenNextKeyPair, host, firstLine(stderr), err)
+		return out, fmt.Errorf("generate keypair: %w", err)
+	}
+	newKey := strings.TrimSpace(stdout)
+	if len(newKey) != 44 {
+		err = fmt.Errorf("unexpected output %q", newKey)
+		record(ActionGenNextKeyPair, host, err.Error(), err)
+		return out, fmt.Errorf("generate keypair: %w", err)
+	}
+	record(ActionGenNextKeyPair, host, truncateKey(newKey), nil)
+
+	var peers []string
+	psks := map[string]string{}
+	for _, p := range d.Hosts {
+		if p == host {
+			continue
+		}
+		peers = append(peers, p)
+		if opts.PresharedKeys {
+			psk, err := generatePSK()
+			if err != nil {
+				return out, err
+			}
+			psks[p] = psk
+		}
+	}
+
+	// 2. Every peer learns the new key.
+	oldKey := pubkeys[host]
+	pubkeys[host] = newKey
+	rs := ssh.ForEachServer(ctx, peers, concurrency,
+		func(ctx context.Context, peer string) (ActionResult, error) {
+			var cmds []string
+			if psk, ok := psks[peer]; ok {
+				cmds = append(cmds, writePSKCmd(mgmt[host], psk))
+			}
+			cmds = append(cmds, WriteConfigCommand(d.Interface, mgmt[peer], d.ListenPort,
+				peerConfigs(d, peer, pubkeys, mgmt, subnets, subnets6, operator)))
+			_, stderr, err := runner.Run(ctx, peer, user, port, syncConfCmd(d.Interface, cmds))
+			res := ActionResult{Action: PlannedAction{Host: peer, Type: ActionUpdatePeerKey,
+				Detail: fmt.Sprintf("%s → %s", host, truncateKey(newKey))}, Err: err}
+			if err != nil {
+				res.Action.Detail = firstLine(stderr)
+			}
+			return res, err
+		})
+	var failed []string
+	for _, r := range rs {
+		out = append(out, r.Result)
+		if r.Err != nil {
+			failed = append(failed, r.Host)
+		}
+	}
+	if len(failed) > 0 {
+		// The host still runs its old key, so the failed peers keep their
+		// tunnel to it; the others already expect the new key.
+		pubkeys[host] = oldKey
+		return out, fmt.Errorf("update peer key failed on %s; %s still uses its old key and the other peers cannot reach it until rotate-keys is re-run",
+			strings.Join(failed, ", "), host)
+	}
+
+	// 3. The host switches keys.
+	var cmds []string
+	for _, p := range peers {
+		if psk, ok := psks[p]; ok {
+			cmds = append(cmds, writePSKCmd(mgmt[p], psk))
+		}
+	}
+	cmds = append(cmds, activateKeyPairCmd,
+		WriteConfigCommand(d.Interface, mgmt[host], d.ListenPort, peerConfigs(d, host, pubkeys, mgmt, subnets, subnets6, operator)))
+	// The host clock right after the switch: only handshakes from then on
+	// prove the new key works.
+	stdout, stderr, err = runner.Run(ctx, host, user, port, syncConfCmd(d.Interface, cmds)+" && date +%s")
+	var activated int64
+	if err == nil {
+		lines := nonEmptyLines(stdout)
+		if len(lines) > 0 {
+			activated, err = strconv.ParseInt(strings.TrimSpace(lines[len(lines)-1]), 10, 64)
+		}
+	}
+	if err != nil {
+		detail := firstLine(stderr)
+		if detail == "" {
+			detail = err.Error()
+		}
+		record(ActionActivateKeyPair, host, detail, err)
+		return out, fmt.Errorf("activate keypair: %w", err)
+	}
+	record(ActionActivateKeyPair, host, "", nil)
+
+	// 4. Fresh handshakes with every peer.
+	err = waitForHandshakes(ctx, runner, host, user, port, d.Interface, peers, pubkeys, mgmt, activated, opts)
+	detail := fmt.Sprintf("%d peer(s)", len(peers))
+	if err != nil {
+		detail = err.Error()
+	}
+	record(ActionVerifyHandshake, host, detail, err)
+	if err != nil {
+		return out, fmt.Errorf("%w; the previous key is in /etc/wireguard/privatekey.old", err)
+	}
+	return out, nil
+}
+
+// genNextKeyPairCmd writes privatekey.next/publickey.next and prints the new
+// public key.
+const genNextKeyPairCmd = `umask 077 && ` +
+	`wg genkey > /etc/wireguard/privatekey.next && ` +
+	`wg pubkey < /etc/wireguard/privatekey.next > /etc/wireguard/publickey.next && ` +
+	`cat /etc/wireguard/publickey.next`
+
+// activateKeyPairCmd moves the next keypair into place, keeping the previous
+// one as *.old for manual recovery.
+const activateKeyPairCmd = `cp -p /etc/wireguard/privatekey /etc/wireguard/privatekey.old && ` +
+	`cp -p /etc/wireguard/publickey /etc/wireguard/publickey.old && ` +
+	`mv /etc/wireguard/privatekey.next /etc/wireguard/privatekey && ` +
+	`mv /etc/wireguard/publickey.next /etc/wireguard/publickey`
+
+// syncConfCmd runs cmds, then applies wg0.conf to the running interface with
+// `wg syncconf`, which only touches what changed instead of restarting wg0.
+func syncConfCmd(iface string, cmds []string) string {
+	stripped := fmt.Sprintf("/etc/wireguard/.%s.sync", iface)
+	return strings.Join(cmds, " && ") + fmt.Sprintf(
+		` && umask 077 && wg-quick strip %[1]s > %[2]s && wg syncconf %[1]s %[2]s 2>&1; rc=$?; rm -f %[2]s; [ $rc -eq 0 ]`,
+		iface, stripped)
+}
+
+// writePSKCmd stores the preshared key shared with the peer at peerMgmtIP.
+func writePSKCmd(peerMgmtIP net.IP, psk string) string {
+	return fmt.Sprintf("umask 077 && mkdir -p %s && ", PSKDir) +
+		heredocWrite(PSKPath(peerMgmtIP), psk+"\n", "COOLIFY_PSK_EOF", 0o600)
+}
+
+// generatePSK returns a WireGuard preshared key (32 random bytes, base64).
+func generatePSK() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate preshared key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// waitForHandshakes pings every peer from host to trigger a handshake and
+// polls `wg show latest-handshakes` until each peer's is newer than since.
+func waitForHandshakes(
+	ctx context.Context,
+	runner ssh.Runner,
+	host, user string,
+	port int,
+	iface string,
+	peers []string,
+	pubkeys map[string]string,
+	mgmt map[string]net.IP,
+	since int64,
+	opts RotateOptions,
+) error {
+	var sb strings.Builder
+	for _, p := range peers {
+		fmt.Fprintf(&sb, "ping -c 1 -W 1 %s >/dev/null 2>&1 & ", mgmt[p])
+	}
+	fmt.Fprintf(&sb, "wait; wg show %s latest-handshakes", iface)
+	cmd := sb.String()
+
+	deadline := time.Now().Add(opts.HandshakeTimeout)
+	for {
+		stdout, _, err := runner.Run(ctx, host, user, port, cmd)
+		if err != nil {
+			return fmt.Errorf("read handshakes: %w", err)
+		}
+		latest := map[string]int64{}
+		for _, line := range nonEmptyLines(stdout) {
+			f := strings.Fields(line)
+			if len(f) == 2 {
+				latest[f[0]], _ = strconv.ParseInt(f[1], 10, 64)
+			}
+		}
+		var missing []string
+		for _, p := range peers {
+			if latest[pubkeys[p]] < since {
+				missing = append(missing, p)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("no handshake with %s within %s", strings.Join(missing, ", "), opts.HandshakeTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
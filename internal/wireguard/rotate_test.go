@@ -0,0 +1,184 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rotationMesh() (*DesiredMesh, MeshState) {
+	d := desiredWithPodman()
+	d.Hosts = []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}
+	mesh := MeshState{Servers: map[string]*ServerState{}}
+	for i, h := range d.Hosts {
+		mesh.Servers[h] = &ServerState{
+			Host:            h,
+			Active:          true,
+			PublicKey:       fmt.Sprintf("OLD%d", i+1) + strings.Repeat("A", 40),
+			WireGuardMgmtIP: net.ParseIP(fmt.Sprintf("100.64.0.%d", i+1)).To4(),
+			Namespaces:      map[string]*NamespaceServerState{},
+		}
+	}
+	return d, mesh
+}
+
+// rotationRunner fakes the hosts a rotation talks to. New keys are
+// "NEW<host>" padded to 44 characters; handshakes always look fresh unless
+// the host is listed in stale.
+type rotationRunner struct {
+	mu      sync.Mutex
+	cmds    []string // "host: step"
+	psks    map[string]string
+	stale   map[string]bool
+	configs [][2]string // host, last sync command
+}
+
+var pskWriteRe = regexp.MustCompile(`cat > ` + PSKDir + `/(\S+)\.tmp <<'COOLIFY_PSK_EOF'\n(\S+)\n`)
+
+func newKeyFor(host string) string {
+	k := "NEW" + host
+	return k + strings.Repeat("B", 44-len(k))
+}
+
+func (r *rotationRunner) Run(_ context.Context, host, _ string, _ int, cmd string) (string, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case cmd == genNextKeyPairCmd:
+		r.cmds = append(r.cmds, host+": gen")
+		return newKeyFor(host) + "\n", "", nil
+	case strings.Contains(cmd, "latest-handshakes"):
+		r.cmds = append(r.cmds, host+": handshakes")
+		if r.stale[host] {
+			return "", "", nil
+		}
+		var out strings.Builder
+		for _, k := range regexp.MustCompile(`PublicKey = ([^"\s]+)`).FindAllStringSubmatch(r.lastConfig(host), -1) {
+			fmt.Fprintf(&out, "%s\t2000\n", k[1])
+		}
+		return out.String(), "", nil
+	case strings.Contains(cmd, "wg syncconf"):
+		for _, m := range pskWriteRe.FindAllStringSubmatch(cmd, -1) {
+			r.psks[host+"→"+m[1]] = m[2]
+		}
+		if strings.Contains(cmd, activateKeyPairCmd) {
+			r.cmds = append(r.cmds, host+": activate")
+		} else {
+			r.cmds = append(r.cmds, host+": update")
+		}
+		r.configs = append(r.configs, [2]string{host, cmd})
+		return "1000\n", "", nil
+	}
+	return "", "", fmt.Errorf("unexpected command on %s: %s", host, cmd)
+}
+
+func (r *rotationRunner) lastConfig(host string) string {
+	for i := len(r.configs) - 1; i >= 0; i-- {
+		if r.configs[i][0] == host {
+			return r.configs[i][1]
+		}
+	}
+	return ""
+}
+
+func TestPlanRotation(t *testing.T) {
+	d, mesh := rotationMesh()
+
+	steps, err := PlanRotation(d, mesh, RotateOptions{Hosts: []string{"2.2.2.2"}})
+	require.NoError(t, err)
+	var got []string
+	for _, a := range steps {
+		got = append(got, a.Host+" "+string(a.Type))
+	}
+	assert.Equal(t, []string{
+		"2.2.2.2 gen-next-keypair",
+		"1.1.1.1 update-peer-key",
+		"3.3.3.3 update-peer-key",
+		"2.2.2.2 activate-keypair",
+		"2.2.2.2 verify-handshake",
+	}, got)
+
+	_, err = PlanRotation(d, mesh, RotateOptions{Hosts: []string{"9.9.9.9"}})
+	assert.ErrorContains(t, err, "not in the mesh")
+
+	mesh.Servers["3.3.3.3"].Active = false
+	_, err = PlanRotation(d, mesh, RotateOptions{Hosts: []string{"2.2.2.2"}})
+	assert.ErrorContains(t, err, "every host must be up")
+}
+
+func TestRotateKeys_RollingWithPresharedKeys(t *testing.T) {
+	d, mesh := rotationMesh()
+	runner := &rotationRunner{psks: map[string]string{}}
+
+	results, err := RotateKeys(context.Background(), runner, "root", 22, d, mesh,
+		RotateOptions{Hosts: []string{"1.1.1.1", "2.2.2.2"}, PresharedKeys: true, PollInterval: time.Millisecond}, 2)
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.NoError(t, r.Err)
+	}
+
+	// Host 1 is fully verified before host 2 generates its key.
+	idx := func(s string) int {
+		for i, c := range runner.cmds {
+			if c == s {
+				return i
+			}
+		}
+		t.Fatalf("%q not run", s)
+		return -1
+	}
+	assert.Less(t, idx("1.1.1.1: gen"), idx("2.2.2.2: update"))
+	assert.Less(t, idx("2.2.2.2: update"), idx("1.1.1.1: activate"))
+	assert.Less(t, idx("1.1.1.1: handshakes"), idx("2.2.2.2: gen"))
+
+	// Both ends of a pair hold the same preshared key.
+	assert.Equal(t, runner.psks["1.1.1.1→100.64.0.3"], runner.psks["3.3.3.3→100.64.0.1"])
+	assert.NotEmpty(t, runner.psks["1.1.1.1→100.64.0.3"])
+	assert.Equal(t, runner.psks["2.2.2.2→100.64.0.3"], runner.psks["3.3.3.3→100.64.0.2"])
+
+	// Host 3 ends up with both new keys.
+	conf := runner.lastConfig("3.3.3.3")
+	assert.Contains(t, conf, newKeyFor("1.1.1.1"))
+	assert.Contains(t, conf, newKeyFor("2.2.2.2"))
+}
+
+func TestRotateKeys_StopsWhenHandshakesDoNotReturn(t *testing.T) {
+	d, mesh := rotationMesh()
+	runner := &rotationRunner{psks: map[string]string{}, stale: map[string]bool{"1.1.1.1": true}}
+
+	_, err := RotateKeys(context.Background(), runner, "root", 22, d, mesh,
+		RotateOptions{Hosts: []string{"1.1.1.1", "2.2.2.2"}, HandshakeTimeout: 5 * time.Millisecond, PollInterval: time.Millisecond}, 2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no handshake with 2.2.2.2, 3.3.3.3")
+	assert.Contains(t, err.Error(), "privatekey.old")
+	assert.NotContains(t, runner.cmds, "2.2.2.2: gen", "the next host is not touched")
+}
+
+func TestRotateKeys_KeepsOperatorPeer(t *testing.T) {
+	d, mesh := rotationMesh()
+	l := NewLedger()
+	l.RecordOperator(&LedgerOperator{MgmtIP: "100.64.255.254", PublicKey: "T1BFUkFUT1I="})
+	ReconcileLedger(&mesh, l)
+	runner := &rotationRunner{psks: map[string]string{}}
+
+	_, err := RotateKeys(context.Background(), runner, "root", 22, d, mesh,
+		RotateOptions{Hosts: []string{"2.2.2.2"}, PollInterval: time.Millisecond}, 2)
+	require.NoError(t, err)
+
+	for _, h := range d.Hosts {
+		conf := runner.lastConfig(h)
+		assert.Contains(t, conf, "# coolify-operator-begin T1BFUkFUT1I=", h)
+		assert.Contains(t, conf, "AllowedIPs = 100.64.255.254/32", h)
+	}
+	assert.Equal(t, newKeyFor("2.2.2.2"), mesh.Servers["2.2.2.2"].PublicKey, "handed back to the caller")
+	assert.Equal(t, "OLD1"+strings.Repeat("A", 40), mesh.Servers["1.1.1.1"].PublicKey)
+}
@@ -0,0 +1,109 @@
+package wireguard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
+)
+
+// schemaChange is what `coolify init` does to a host's corrosion schema.
+type schemaChange int
+
+const (
+	// schemaCurrent: the host is on services.CorrosionSchemaVersion.
+	schemaCurrent schemaChange = iota
+	// schemaFirstWrite: no schema yet; corrosion creates the DB from it.
+	schemaFirstWrite
+	// schemaMigrate: the host is on an older known revision; the pending
+	// migrations are applied to the live DB.
+	schemaMigrate
+	// schemaNewer: a newer CLI migrated the host. It is left alone.
+	schemaNewer
+	// schemaWipe: the schema is no known revision. It is rewritten and the
+	// DB reset, which only bootstrap and brand-new hosts allow.
+	schemaWipe
+)
+
+// classifySchema compares a host's schema with services.CoolifySchemaSQL.
+// from is the host's version when the change is schemaMigrate.
+func classifySchema(st *ServerState) (change schemaChange, from int) {
+	switch {
+	case st == nil || !st.CorrosionSchemaExists || st.CorrosionSchemaSha256 == "":
+		return schemaFirstWrite, 0
+	case st.CorrosionSchemaVersion > services.CorrosionSchemaVersion:
+		return schemaNewer, st.CorrosionSchemaVersion
+	case st.CorrosionSchemaSha256 == sha256Hex([]byte(services.CoolifySchemaSQL)):
+		return schemaCurrent, services.CorrosionSchemaVersion
+	}
+	if v := services.CorrosionSchemaVersionOf(st.CorrosionSchemaSha256); v > 0 {
+		return schemaMigrate, v
+	}
+	return schemaWipe, 0
+}
+
+// migrationDetail describes the migrations from version from in a plan row.
+func migrationDetail(from int) string {
+	var names []string
+	for _, m := range services.CorrosionMigrationsSince(from) {
+		names = append(names, m.Description)
+	}
+	return fmt.Sprintf("coolify.sql v%d → v%d (%s)", from, services.CorrosionSchemaVersion, strings.Join(names, "; "))
+}
+
+// isNewHost reports whether host joins the mesh on this extend run.
+func isNewHost(d *DesiredMesh, host string) bool {
+	if d.Intent != IntentExtend {
+		return false
+	}
+	for _, h := range d.NewHosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// corrosionSchemaCommand returns the action and shell command that bring
+// host's schema to services.CorrosionSchemaVersion, or an empty command
+// when the schema must be left alone.
+func corrosionSchemaCommand(d *DesiredMesh, host string, st *ServerState) (ActionType, string, error) {
+	write := heredocWrite(services.CorrosionSchemaPath,
+		services.CoolifySchemaSQL, "COOLIFY_SCHEMA_EOF", 0o600) +
+		" && " + services.RecordCorrosionSchemaVersionCommand()
+
+	change, from := classifySchema(st)
+	switch change {
+	case schemaNewer:
+		return ActionWriteCorrosionSchema, "", nil
+	case schemaMigrate:
+		// Like the plan's intent filter, only bootstrap and upgrade
+		// migrate existing hosts.
+		if d.Intent != IntentBootstrap && d.Intent != IntentUpgrade && !isNewHost(d, host) {
+			return ActionMigrateCorrosionSchema, "", nil
+		}
+		// Migrate the running DB first: corrosion validates the statements
+		// and a failure leaves the file (and the next start) on the old
+		// revision.
+		migrate, err := services.CorrosionMigrateCommand(d.CorrosionAPIPort, from)
+		if err != nil {
+			return ActionMigrateCorrosionSchema, "", err
+		}
+		return ActionMigrateCorrosionSchema, migrate + " && " + write, nil
+	case schemaWipe:
+		// Only bootstrap and hosts joining in extend mode hold no
+		// replicated state yet.
+		if d.Intent != IntentBootstrap && !isNewHost(d, host) {
+			return ActionWriteCorrosionSchema, "", fmt.Errorf(
+				"corrosion schema on %s is not a revision this CLI knows; refusing to reset its DB outside bootstrap", host)
+		}
+		// The CR-SQLite DB is incompatible with an unknown schema — stop
+		// corrosion and wipe it so it re-bootstraps. Coold repopulates
+		// within ~2s.
+		return ActionWriteCorrosionSchema, `systemctl stop corrosion 2>/dev/null || true; ` +
+			`rm -f /var/lib/corrosion/corrosion.db ` +
+			`/var/lib/corrosion/corrosion.db-shm ` +
+			`/var/lib/corrosion/corrosion.db-wal && ` + write, nil
+	}
+	return ActionWriteCorrosionSchema, write, nil
+}
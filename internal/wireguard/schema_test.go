@@ -0,0 +1,110 @@
+package wireguard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
+)
+
+func currentSchemaState() *ServerState {
+	return &ServerState{
+		CorrosionSchemaExists: true,
+		CorrosionSchemaSha256: sha256Hex([]byte(services.CoolifySchemaSQL)),
+	}
+}
+
+func TestClassifySchema(t *testing.T) {
+	change, _ := classifySchema(nil)
+	assert.Equal(t, schemaFirstWrite, change)
+	change, _ = classifySchema(&ServerState{})
+	assert.Equal(t, schemaFirstWrite, change)
+
+	change, from := classifySchema(currentSchemaState())
+	assert.Equal(t, schemaCurrent, change)
+	assert.Equal(t, services.CorrosionSchemaVersion, from)
+
+	newer := currentSchemaState()
+	newer.CorrosionSchemaSha256 = "feed"
+	newer.CorrosionSchemaVersion = services.CorrosionSchemaVersion + 1
+	change, from = classifySchema(newer)
+	assert.Equal(t, schemaNewer, change)
+	assert.Equal(t, services.CorrosionSchemaVersion+1, from)
+
+	edited := currentSchemaState()
+	edited.CorrosionSchemaSha256 = "feed"
+	change, _ = classifySchema(edited)
+	assert.Equal(t, schemaWipe, change)
+}
+
+func TestCorrosionSchemaCommand_WriteRecordsVersion(t *testing.T) {
+	action, cmd, err := corrosionSchemaCommand(&DesiredMesh{Intent: IntentUpgrade}, "A", currentSchemaState())
+	require.NoError(t, err)
+	assert.Equal(t, ActionWriteCorrosionSchema, action)
+	assert.Contains(t, cmd, services.CorrosionSchemaPath)
+	assert.Contains(t, cmd, services.CorrosionSchemaVersionPath)
+	assert.NotContains(t, cmd, "corrosion.db")
+}
+
+func TestCorrosionSchemaCommand_NewerSchemaLeftAlone(t *testing.T) {
+	st := currentSchemaState()
+	st.CorrosionSchemaVersion = services.CorrosionSchemaVersion + 1
+	_, cmd, err := corrosionSchemaCommand(&DesiredMesh{Intent: IntentUpgrade}, "A", st)
+	require.NoError(t, err)
+	assert.Empty(t, cmd)
+}
+
+func TestCorrosionSchemaCommand_WipeOnlyOnBootstrapOrNewHost(t *testing.T) {
+	st := currentSchemaState()
+	st.CorrosionSchemaSha256 = "feed"
+
+	_, cmd, err := corrosionSchemaCommand(&DesiredMesh{Intent: IntentBootstrap}, "A", st)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "rm -f /var/lib/corrosion/corrosion.db")
+
+	_, cmd, err = corrosionSchemaCommand(&DesiredMesh{Intent: IntentExtend, NewHosts: []string{"C"}}, "C", st)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "rm -f /var/lib/corrosion/corrosion.db")
+
+	for _, d := range []*DesiredMesh{
+		{Intent: IntentUpgrade},
+		{Intent: IntentExtend, NewHosts: []string{"C"}},
+		{Intent: IntentRemoveHost, RemoveHosts: []string{"C"}},
+	} {
+		_, _, err := corrosionSchemaCommand(d, "A", st)
+		require.Error(t, err, "intent %q", d.Intent)
+		assert.Contains(t, err.Error(), "refusing to reset")
+	}
+}
+
+func TestBuildPlan_SchemaActions(t *testing.T) {
+	desired := desiredWithPodman()
+	desired.InstallCoold = true
+	a := convergedServer("1.1.1.1", "AAAAAAAA=", "BBBBBBBB=", "100.64.0.1", "10.210.0.0/24")
+	b := convergedServer("2.2.2.2", "BBBBBBBB=", "AAAAAAAA=", "100.64.0.2", "10.210.1.0/24")
+	b.CorrosionSchemaExists = true
+	b.CorrosionSchemaSha256 = "feed"
+	b.CorrosionSchemaVersion = services.CorrosionSchemaVersion + 1
+
+	plan, err := BuildPlan(desired, MeshState{Servers: map[string]*ServerState{"1.1.1.1": a, "2.2.2.2": b}})
+	require.NoError(t, err)
+
+	schemaActions := map[string]ActionType{}
+	for _, act := range plan.Actions {
+		if act.Type == ActionWriteCorrosionSchema || act.Type == ActionMigrateCorrosionSchema {
+			schemaActions[act.Host] = act.Type
+		}
+	}
+	assert.Equal(t, map[string]ActionType{"1.1.1.1": ActionWriteCorrosionSchema}, schemaActions,
+		"first write on 1.1.1.1; a newer schema on 2.2.2.2 is left alone")
+	var warned bool
+	for _, w := range plan.Warnings {
+		if w.Host == "2.2.2.2" {
+			warned = true
+			assert.Contains(t, w.Reason, "newer than this CLI")
+		}
+	}
+	assert.True(t, warned)
+}
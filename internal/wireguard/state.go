@@ -5,6 +5,8 @@ package wireguard
 import (
 	"net"
 	"sort"
+
+	"github.com/coollabsio/coolify-cli/internal/services"
 )
 
 // DefaultNamespace is the namespace used when the user does not pass
@@ -44,6 +46,10 @@ type NamespaceServerState struct {
 	// (read from `podman network inspect`). nil when not yet created.
 	ContainerSubnet *net.IPNet
 
+	// ContainerSubnet6 is the IPv6 subnet of a dual-stack bridge, which
+	// carries it next to its IPv4 ContainerSubnet. nil otherwise.
+	ContainerSubnet6 *net.IPNet
+
 	// DNSEnabled is true when the per-namespace network has `dns_enabled=true`
 	// (netavark auto-starts aardvark-dns on the bridge gateway:53). coold owns
 	// that socket, so drift triggers ActionRecreatePodmanNet.
@@ -56,12 +62,21 @@ type NamespaceServerState struct {
 }
 
 // ServerState holds the reconstructed WireGuard + Podman state for one server.
-// It is built from live SSH probes and never cached to disk.
+// It is built from live SSH probes; allocations are persisted separately in
+// the mesh state Ledger.
 type ServerState struct {
 	// Host is the SSH address used to reach this server.
 	// It also serves as the WireGuard Endpoint value for peer configs.
 	Host string
 
+	// OSID is the ID from /etc/os-release (e.g. "debian", "rocky").
+	OSID string
+
+	// PackageManager installs packages on this server; empty when the
+	// distro was not recognised, in which case the install commands detect
+	// the manager on the host.
+	PackageManager services.PackageManager
+
 	// Installed is true when the wireguard package is present.
 	Installed bool
 
@@ -103,6 +118,9 @@ type ServerState struct {
 	// IPForwardEnabled is true when net.ipv4.ip_forward == 1.
 	IPForwardEnabled bool
 
+	// IP6ForwardEnabled is true when net.ipv6.conf.all.forwarding == 1.
+	IP6ForwardEnabled bool
+
 	// FirewallActive is true when coolify-mesh-fw.service is active.
 	FirewallActive bool
 
@@ -136,10 +154,17 @@ type ServerState struct {
 	CorrosionSchemaExists bool
 
 	// CorrosionSchemaSha256 is the sha256 of /etc/corrosion/schemas/coolify.sql
-	// (hex), or empty when absent. Used by BuildPlan to detect schema drift so
-	// a new schema revision triggers re-write + corrosion restart + DB reset.
+	// (hex), or empty when absent. BuildPlan maps it to a revision of
+	// services.CorrosionMigrations to plan a live migration; an unknown
+	// revision triggers re-write + corrosion restart + DB reset.
 	CorrosionSchemaSha256 string
 
+	// CorrosionSchemaVersion is the version recorded in
+	// services.CorrosionSchemaVersionPath, or 0 when absent (hosts installed
+	// before migrations). A version above services.CorrosionSchemaVersion
+	// means a newer CLI migrated the host.
+	CorrosionSchemaVersion int
+
 	// CooldInstalled is true when /usr/local/bin/coold exists and is executable.
 	CooldInstalled bool
 
@@ -159,16 +184,50 @@ type ServerState struct {
 	// or empty when absent. Used by BuildPlan to detect generator changes
 	// (e.g. Requires→Wants) that would otherwise be invisible.
 	CooldUnitSha256 string
+
+	// HostCaps is the caps claim of /etc/coolify/host-jwt, or nil when the
+	// host has no readable token. It carries the capability labels across
+	// runs: a host without an entry in DesiredMesh.HostLabels keeps them.
+	HostCaps []string
 }
 
 // MeshState is the reconstructed state across all servers in the mesh.
 type MeshState struct {
 	// Servers maps host → *ServerState.
 	Servers map[string]*ServerState
+
+	// Ledger is the persisted mesh state attached by ReconcileLedger. When
+	// set, allocations recorded for hosts the probe could not see stay
+	// reserved. nil means probes only.
+	Ledger *Ledger
+}
+
+// packageManager returns the detected package manager of host, or "" to
+// detect it on the host.
+func (m *MeshState) packageManager(host string) services.PackageManager {
+	if s, ok := m.Servers[host]; ok {
+		return s.PackageManager
+	}
+	return ""
+}
+
+// operatorPeer returns the operator peer recorded in the ledger, which
+// every host lists next to its mesh peers, or nil when none has joined.
+func (m *MeshState) operatorPeer() *PeerConfig {
+	if m.Ledger == nil || m.Ledger.Operator == nil || m.Ledger.Operator.PublicKey == "" {
+		return nil
+	}
+	ip := net.ParseIP(m.Ledger.Operator.MgmtIP)
+	if ip == nil {
+		return nil
+	}
+	return &PeerConfig{PublicKey: m.Ledger.Operator.PublicKey, MgmtIP: ip, Operator: true}
 }
 
 // AssignedMgmtIPs returns a map of host → net.IP for all servers that
-// already have a WG management IP assigned.
+// already have a WG management IP assigned, plus the ledger's record for
+// hosts that did not report one and for the operator peer (under
+// OperatorLedgerKey).
 func (m *MeshState) AssignedMgmtIPs() map[string]net.IP {
 	out := make(map[string]net.IP, len(m.Servers))
 	for host, s := range m.Servers {
@@ -176,26 +235,63 @@ func (m *MeshState) AssignedMgmtIPs() map[string]net.IP {
 			out[host] = s.WireGuardMgmtIP
 		}
 	}
+	if m.Ledger != nil {
+		for host, ip := range m.Ledger.ledgerMgmtIPs(out) {
+			out[host] = ip
+		}
+	}
 	return out
 }
 
 // AssignedContainerSubnets returns the per-(namespace, host) subnets that are
-// already assigned on remote podman networks. The result is nested:
+// already assigned on remote podman networks, plus the ledger's record for
+// pairs the probe did not report. The result is nested:
 // `out[namespace][host] = subnet`.
 func (m *MeshState) AssignedContainerSubnets() map[string]map[string]*net.IPNet {
+	var recorded map[string]map[string]string
+	if m.Ledger != nil {
+		recorded = m.Ledger.Subnets
+	}
+	return m.assignedSubnets(func(nss *NamespaceServerState) *net.IPNet { return nss.ContainerSubnet }, recorded)
+}
+
+// AssignedContainerSubnets6 is AssignedContainerSubnets for the IPv6 subnets
+// of dual-stack bridges.
+func (m *MeshState) AssignedContainerSubnets6() map[string]map[string]*net.IPNet {
+	var recorded map[string]map[string]string
+	if m.Ledger != nil {
+		recorded = m.Ledger.Subnets6
+	}
+	return m.assignedSubnets(func(nss *NamespaceServerState) *net.IPNet { return nss.ContainerSubnet6 }, recorded)
+}
+
+func (m *MeshState) assignedSubnets(
+	probed func(*NamespaceServerState) *net.IPNet,
+	recorded map[string]map[string]string,
+) map[string]map[string]*net.IPNet {
 	out := map[string]map[string]*net.IPNet{}
 	for host, s := range m.Servers {
 		if s == nil {
 			continue
 		}
 		for ns, nss := range s.Namespaces {
-			if nss == nil || nss.ContainerSubnet == nil {
+			if nss == nil || probed(nss) == nil {
 				continue
 			}
 			if out[ns] == nil {
 				out[ns] = map[string]*net.IPNet{}
 			}
-			out[ns][host] = nss.ContainerSubnet
+			out[ns][host] = probed(nss)
+		}
+	}
+	if recorded != nil {
+		for ns, byHost := range ledgerSubnets(recorded, out) {
+			if out[ns] == nil {
+				out[ns] = map[string]*net.IPNet{}
+			}
+			for host, sn := range byHost {
+				out[ns][host] = sn
+			}
 		}
 	}
 	return out
@@ -212,8 +308,14 @@ func (s *ServerState) FirewallSubnets() []*net.IPNet {
 	}
 	sort.Strings(names)
 	for _, n := range names {
-		if ns := s.Namespaces[n]; ns != nil && ns.ContainerSubnet != nil {
-			out = append(out, ns.ContainerSubnet)
+		ns := s.Namespaces[n]
+		if ns == nil {
+			continue
+		}
+		for _, sn := range []*net.IPNet{ns.ContainerSubnet, ns.ContainerSubnet6} {
+			if sn != nil {
+				out = append(out, sn)
+			}
 		}
 	}
 	return out
@@ -241,6 +343,12 @@ type DesiredMesh struct {
 	// per namespace).
 	ContainerPrefix int
 
+	// ContainerPool6, when set next to an IPv4 ContainerPool, makes every
+	// bridge dual-stack: each (namespace, host) pair also gets a
+	// /ContainerPrefix6 from it, and peers route both subnets.
+	ContainerPool6   *net.IPNet
+	ContainerPrefix6 int
+
 	// ListenPort is the WireGuard UDP listen port (default 51820).
 	ListenPort int
 
@@ -299,6 +407,17 @@ type DesiredMesh struct {
 	// builder binary is not installed on them.
 	BuilderHosts []string
 
+	// HostLabels maps host → capability labels (gpu, edge, storage, custom)
+	// advertised next to coold and builder in its JWT caps claim and
+	// COOLD_CAPS. A host without an entry keeps the labels of its current
+	// token; an empty entry clears them. See services.ValidateCapLabel.
+	HostLabels map[string][]string
+
+	// JWTRevocations are the host JWT revocations recorded on CentralHost
+	// (see ReadJWTRevocations). Hosts whose subject has one are not issued
+	// a new token by apply or caps; only `init jwt rotate` lifts it.
+	JWTRevocations []services.JWTRevocation
+
 	// BuilderCapacity caps concurrent builds per host. 0 falls back to 2 (the
 	// coold builder adapter's own default).
 	BuilderCapacity int
@@ -331,6 +450,12 @@ type DesiredMesh struct {
 	// host as existing (no-op safe mode).
 	NewHosts []string
 
+	// RemoveHosts lists the hosts leaving the mesh on this run. Only
+	// meaningful when Intent == IntentRemoveHost. They must not appear in
+	// Hosts: Hosts is the surviving mesh, and the removed hosts' mgmt IPs and
+	// container subnets are released back to the pools.
+	RemoveHosts []string
+
 	// AllowReplace unlocks destructive-replace actions on existing hosts in
 	// extend mode (e.g. ActionRecreatePodmanNet). Never unlocks the wipe-DB
 	// branch of ActionWriteCorrosionSchema.
@@ -360,6 +485,11 @@ const (
 	// IntentUpgrade only emits binary-fetch actions + the service-restart
 	// actions that follow them.
 	IntentUpgrade Intent = "upgrade"
+
+	// IntentRemoveHost drains and tears down the hosts in RemoveHosts and
+	// limits the surviving hosts to peer-refresh actions (drop the removed
+	// peer from wg0 and from the corrosion bootstrap list).
+	IntentRemoveHost Intent = "remove-host"
 )
 
 // BuilderHostSet returns the set of hosts that should carry the builder
@@ -392,9 +522,75 @@ func (d *DesiredMesh) HasBuilderCap(host string) bool {
 	return d.BuilderHostSet()[host]
 }
 
+// HostCaps returns the caps claim for host: coold, builder when host is in
+// the builder set, then its labels sorted. s is host's probed state, whose
+// token supplies the labels when HostLabels has no entry for host.
+func (d *DesiredMesh) HostCaps(host string, s *ServerState) []string {
+	caps := []string{services.CapCoold}
+	if d.HasBuilderCap(host) {
+		caps = append(caps, services.CapBuilder)
+	}
+	labels, ok := d.HostLabels[host]
+	if !ok && s != nil {
+		labels = s.Labels()
+	}
+	labels = append([]string(nil), labels...)
+	sort.Strings(labels)
+	for i, l := range labels {
+		if l == services.CapCoold || l == services.CapBuilder || (i > 0 && l == labels[i-1]) {
+			continue
+		}
+		caps = append(caps, l)
+	}
+	return caps
+}
+
+// JWTRevoked reports whether a revocation is recorded for subject.
+func (d *DesiredMesh) JWTRevoked(subject string) bool {
+	for _, r := range d.JWTRevocations {
+		if r.Subject == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// Labels returns the operator labels in the host's current token: its caps
+// minus coold and builder.
+func (s *ServerState) Labels() []string {
+	var labels []string
+	for _, c := range s.HostCaps {
+		if c != services.CapCoold && c != services.CapBuilder {
+			labels = append(labels, c)
+		}
+	}
+	return labels
+}
+
 // SortedNamespaces returns the desired namespaces in deterministic order.
 func (d *DesiredMesh) SortedNamespaces() []string {
 	out := append([]string(nil), d.Namespaces...)
 	sort.Strings(out)
 	return out
 }
+
+// ContainerIPv6 reports whether container subnets come from an IPv6 pool,
+// which needs IPv6 forwarding, ip6tables rules and IPv6 podman networks.
+func (d *DesiredMesh) ContainerIPv6() bool {
+	return d.ContainerPool6 != nil || d.ContainerPool != nil && IsIPv6(d.ContainerPool.IP)
+}
+
+// needsIPForward reports whether state lacks a forwarding sysctl d needs.
+func (d *DesiredMesh) needsIPForward(state *ServerState) bool {
+	return !state.IPForwardEnabled || (d.ContainerIPv6() && !state.IP6ForwardEnabled)
+}
+
+// DefaultContainerPrefix is the per-host container subnet size for pool:
+// /24 for IPv4, /64 for IPv6 (the smallest prefix SLAAC-style tooling
+// expects).
+func DefaultContainerPrefix(pool *net.IPNet) int {
+	if IsIPv6(pool.IP) {
+		return 64
+	}
+	return 24
+}
@@ -0,0 +1,382 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coollabsio/coolify-cli/internal/ssh"
+)
+
+// Severity grades a mesh health finding. The values are the Nagios plugin
+// exit codes so a check can exit with the worst severity directly;
+// SeverityUnknown is left for the check itself failing to run.
+type Severity int
+
+const (
+	SeverityOK       Severity = 0
+	SeverityWarning  Severity = 1
+	SeverityCritical Severity = 2
+	SeverityUnknown  Severity = 3
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityOK:
+		return "OK"
+	case SeverityWarning:
+		return "WARNING"
+	case SeverityCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// DefaultStaleHandshake is how old a peer's latest handshake may get before
+// CheckStatus flags it. With PersistentKeepalive = 25 an idle tunnel still
+// re-handshakes every two minutes, so anything older means the peer is gone.
+const DefaultStaleHandshake = 5 * time.Minute
+
+// UnitNotInstalled is reported for a status unit with no unit file.
+const UnitNotInstalled = "not-installed"
+
+// StatusUnits are the systemd units CheckStatus reports on every host.
+var StatusUnits = []string{"coold", "corrosion", "scheduler"}
+
+// HostStatus is the live health of one host.
+type HostStatus struct {
+	Host string
+
+	// PublicKey and MgmtIP identify the host on wg0. Empty/nil when the
+	// interface is down.
+	PublicKey string
+	MgmtIP    net.IP
+
+	// Active is true when `wg show <iface>` answers.
+	Active bool
+
+	// Peers is parsed from `wg show <iface> dump` and carries the runtime
+	// LatestHandshake rather than the config file view.
+	Peers []Peer
+
+	// Now is the host's clock (Unix seconds) when it was probed; handshake
+	// ages are computed against it so local clock skew does not matter.
+	Now int64
+
+	// Units maps each StatusUnits entry to `systemctl is-active` output, or
+	// UnitNotInstalled.
+	Units map[string]string
+
+	// Reach maps another host to whether its mgmt IP answered a ping from
+	// this host. Hosts that were not pinged are absent.
+	Reach map[string]bool
+
+	// Err is set when the host could not be probed at all; PingErr when
+	// the ping round trip failed.
+	Err     error
+	PingErr error
+}
+
+// MeshStatus is the result of CheckStatus, one entry per host in input order.
+type MeshStatus struct {
+	Hosts []HostStatus
+}
+
+// LinkStatus is one cell of the reachability matrix: From's view of To.
+type LinkStatus struct {
+	From, To string
+
+	// Peered is true when From has To's public key as a WireGuard peer.
+	Peered bool
+
+	// HandshakeAge is the time since From last completed a handshake with
+	// To. Zero when there has never been one.
+	HandshakeAge time.Duration
+
+	// Pinged is true when From pinged To's mgmt IP; Reachable holds the
+	// outcome.
+	Pinged    bool
+	Reachable bool
+}
+
+// StatusProblem is one finding of MeshStatus.Evaluate.
+type StatusProblem struct {
+	Host     string
+	Severity Severity
+	Message  string
+}
+
+// statusFacts lists what CheckStatus reads from each host. The interface
+// line of `wg show dump` carries the private key, so only peer lines are
+// transferred.
+func statusFacts(iface string) []probeFact {
+	facts := []probeFact{
+		{"now", `date +%s`},
+		{"wg_pubkey", fmt.Sprintf(`wg show %s public-key 2>/dev/null || true`, iface)},
+		{"wg_peers", fmt.Sprintf(`wg show %s dump 2>/dev/null | tail -n +2 || true`, iface)},
+		{"wg_addr", fmt.Sprintf(`ip -o addr show dev %s scope global 2>/dev/null | awk '{print $4}' | head -n 1 || true`, iface)},
+	}
+	for _, u := range StatusUnits {
+		facts = append(facts, probeFact{"unit." + u, fmt.Sprintf(
+			`if systemctl cat %[1]s.service >/dev/null 2>&1; then systemctl is-active %[1]s 2>/dev/null || true; else echo %[2]s; fi`,
+			u, UnitNotInstalled)})
+	}
+	return facts
+}
+
+// ParseWGDumpPeers parses the peer lines of `wg show <iface> dump`. The
+// interface line (four fields) and malformed lines are skipped.
+func ParseWGDumpPeers(dump string) []Peer {
+	var peers []Peer
+	for _, line := range nonEmptyLines(dump) {
+		f := strings.Split(strings.TrimSpace(line), "\t")
+		if len(f) < 8 {
+			continue
+		}
+		p := Peer{PublicKey: f[0], PresharedKey: f[1]}
+		if f[2] != "(none)" {
+			p.Endpoint = f[2]
+		}
+		if f[3] != "(none)" {
+			p.AllowedIPs = strings.Split(f[3], ",")
+		}
+		p.LatestHandshake, _ = strconv.ParseInt(f[4], 10, 64)
+		if f[7] != "off" {
+			p.PersistentKeepalive, _ = strconv.Atoi(f[7])
+		}
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// CheckStatus probes every host, then pings every other host's mgmt IP from
+// each host whose interface is up. Two round trips per host.
+func CheckStatus(
+	ctx context.Context,
+	runner ssh.Runner,
+	hosts []string,
+	user string,
+	port int,
+	iface string,
+	concurrency int,
+) MeshStatus {
+	script := factScript(statusFacts(iface))
+	probed := ssh.ForEachServer(ctx, hosts, concurrency,
+		func(ctx context.Context, host string) (HostStatus, error) {
+			stdout, stderr, err := runner.Run(ctx, host, user, port, script)
+			if err != nil {
+				return HostStatus{}, fmt.Errorf("status probe: %w (stderr: %s)", err, strings.TrimSpace(stderr))
+			}
+			facts, err := parseProbeOutput(stdout)
+			if err != nil {
+				return HostStatus{}, err
+			}
+			return hostStatusFromFacts(host, facts), nil
+		})
+
+	status := MeshStatus{Hosts: make([]HostStatus, len(probed))}
+	ipOwner := map[string]string{}
+	for i, r := range probed {
+		if r.Err != nil {
+			status.Hosts[i] = HostStatus{Host: r.Host, Err: r.Err}
+			continue
+		}
+		status.Hosts[i] = r.Result
+		if ip := r.Result.MgmtIP; ip != nil {
+			ipOwner[ip.String()] = r.Host
+		}
+	}
+
+	var pingHosts []string
+	targets := map[string][]string{}
+	for _, h := range status.Hosts {
+		if h.Err != nil || !h.Active {
+			continue
+		}
+		for _, o := range status.Hosts {
+			if o.Host != h.Host && o.MgmtIP != nil {
+				targets[h.Host] = append(targets[h.Host], o.MgmtIP.String())
+			}
+		}
+		if len(targets[h.Host]) > 0 {
+			pingHosts = append(pingHosts, h.Host)
+		}
+	}
+
+	pinged := ssh.ForEachServer(ctx, pingHosts, concurrency,
+		func(ctx context.Context, host string) (map[string]bool, error) {
+			stdout, _, err := runner.Run(ctx, host, user, port, pingScript(targets[host]))
+			if err != nil {
+				return nil, fmt.Errorf("ping: %w", err)
+			}
+			return parsePingOutput(stdout, ipOwner), nil
+		})
+	byHost := map[string]int{}
+	for i, h := range status.Hosts {
+		byHost[h.Host] = i
+	}
+	for _, r := range pinged {
+		if r.Err != nil {
+			status.Hosts[byHost[r.Host]].PingErr = r.Err
+			continue
+		}
+		status.Hosts[byHost[r.Host]].Reach = r.Result
+	}
+	return status
+}
+
+func hostStatusFromFacts(host string, facts map[string]string) HostStatus {
+	fact := func(key string) string { return strings.TrimSpace(facts[key]) }
+
+	h := HostStatus{
+		Host:      host,
+		PublicKey: fact("wg_pubkey"),
+		Peers:     ParseWGDumpPeers(facts["wg_peers"]),
+		Units:     map[string]string{},
+	}
+	h.Active = h.PublicKey != ""
+	h.Now, _ = strconv.ParseInt(fact("now"), 10, 64)
+	if ip, _, err := net.ParseCIDR(fact("wg_addr")); err == nil {
+		h.MgmtIP = normalizeIP(ip)
+	}
+	for _, u := range StatusUnits {
+		h.Units[u] = fact("unit." + u)
+		if h.Units[u] == "" {
+			h.Units[u] = "unknown"
+		}
+	}
+	return h
+}
+
+// pingScript pings every ip in parallel and prints "<ip> ok" or "<ip> fail"
+// per target.
+func pingScript(ips []string) string {
+	var sb strings.Builder
+	for _, ip := range ips {
+		fmt.Fprintf(&sb, "{ ping -c 1 -W 2 %[1]s >/dev/null 2>&1 && echo '%[1]s ok' || echo '%[1]s fail'; } &\n", ip)
+	}
+	sb.WriteString("wait\n")
+	return sb.String()
+}
+
+// parsePingOutput maps pingScript output back to host names via ipOwner.
+func parsePingOutput(stdout string, ipOwner map[string]string) map[string]bool {
+	out := map[string]bool{}
+	for _, line := range nonEmptyLines(stdout) {
+		ip, result, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if host, known := ipOwner[ip]; ok && known {
+			out[host] = result == "ok"
+		}
+	}
+	return out
+}
+
+// Host returns the status of host, or nil when it was not checked.
+func (m *MeshStatus) Host(host string) *HostStatus {
+	for i := range m.Hosts {
+		if m.Hosts[i].Host == host {
+			return &m.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// Link returns from's view of to.
+func (m *MeshStatus) Link(from, to string) LinkStatus {
+	link := LinkStatus{From: from, To: to}
+	f, t := m.Host(from), m.Host(to)
+	if f == nil || t == nil || t.PublicKey == "" {
+		return link
+	}
+	for _, p := range f.Peers {
+		if p.PublicKey != t.PublicKey {
+			continue
+		}
+		link.Peered = true
+		if p.LatestHandshake > 0 {
+			link.HandshakeAge = time.Duration(max(f.Now-p.LatestHandshake, 0)) * time.Second
+			if link.HandshakeAge == 0 {
+				// Handshake in the current second; keep it distinct from
+				// "never".
+				link.HandshakeAge = time.Nanosecond
+			}
+		}
+		break
+	}
+	link.Reachable, link.Pinged = f.Reach[to]
+	return link
+}
+
+// Evaluate grades the mesh. central is the host expected to run the
+// scheduler (empty: none). A handshake older than staleAfter is a warning;
+// a missing peer, a peer that never completed a handshake, a failed ping,
+// a down interface, a failed agent unit or a host that could not be probed
+// is critical.
+func (m *MeshStatus) Evaluate(central string, staleAfter time.Duration) []StatusProblem {
+	var problems []StatusProblem
+	add := func(host string, sev Severity, format string, args ...any) {
+		problems = append(problems, StatusProblem{Host: host, Severity: sev, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, h := range m.Hosts {
+		if h.Err != nil {
+			add(h.Host, SeverityCritical, "probe failed: %v", h.Err)
+			continue
+		}
+		if h.PingErr != nil {
+			add(h.Host, SeverityCritical, "%v", h.PingErr)
+		}
+		if !h.Active {
+			add(h.Host, SeverityCritical, "WireGuard interface is down")
+		}
+
+		for _, u := range StatusUnits {
+			state := h.Units[u]
+			switch {
+			case state == "active":
+			case state == UnitNotInstalled && !(u == "scheduler" && h.Host == central):
+			default:
+				add(h.Host, SeverityCritical, "%s is %s", u, state)
+			}
+		}
+
+		if !h.Active {
+			continue
+		}
+		for _, o := range m.Hosts {
+			if o.Host == h.Host || o.PublicKey == "" {
+				continue
+			}
+			link := m.Link(h.Host, o.Host)
+			switch {
+			case !link.Peered:
+				add(h.Host, SeverityCritical, "%s is not a WireGuard peer", o.Host)
+				continue
+			case link.HandshakeAge == 0:
+				add(h.Host, SeverityCritical, "no handshake with %s yet", o.Host)
+			case link.HandshakeAge > staleAfter:
+				add(h.Host, SeverityWarning, "last handshake with %s was %s ago", o.Host, link.HandshakeAge.Round(time.Second))
+			}
+			if link.Pinged && !link.Reachable {
+				add(h.Host, SeverityCritical, "cannot ping %s (%s)", o.Host, o.MgmtIP)
+			}
+		}
+	}
+	return problems
+}
+
+// WorstSeverity returns the highest severity in problems, SeverityOK when
+// there are none.
+func WorstSeverity(problems []StatusProblem) Severity {
+	worst := SeverityOK
+	for _, p := range problems {
+		if p.Severity > worst {
+			worst = p.Severity
+		}
+	}
+	return worst
+}
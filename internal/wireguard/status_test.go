@@ -0,0 +1,173 @@
+package wireguard
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dumpFixture = "PRIVATE=\tPUB1=\t51820\toff\n" +
+	"PUB2=\t(none)\t2.2.2.2:51820\t100.64.0.2/32,10.210.1.0/24\t1700000000\t100\t200\t25\n" +
+	"PUB3=\t(none)\t(none)\t(none)\t0\t0\t0\toff\n"
+
+func TestParseWGDumpPeers(t *testing.T) {
+	peers := ParseWGDumpPeers(dumpFixture)
+	require.Len(t, peers, 2, "interface line is skipped")
+	assert.Equal(t, Peer{
+		PublicKey:           "PUB2=",
+		PresharedKey:        "(none)",
+		Endpoint:            "2.2.2.2:51820",
+		AllowedIPs:          []string{"100.64.0.2/32", "10.210.1.0/24"},
+		LatestHandshake:     1700000000,
+		PersistentKeepalive: 25,
+	}, peers[0])
+	assert.Empty(t, peers[1].Endpoint)
+	assert.Nil(t, peers[1].AllowedIPs)
+	assert.Zero(t, peers[1].LatestHandshake)
+}
+
+func TestStateFromProbeFacts_MergesHandshakes(t *testing.T) {
+	conf := "[Interface]\nAddress = 100.64.0.1/32\n[Peer]\nPublicKey = PUB2=\n[Peer]\nPublicKey = PUB3=\n"
+	state := stateFromProbeFacts("h1", "wg0", nil, map[string]string{"wg_conf": conf, "wg_dump": dumpFixture})
+	require.Len(t, state.Peers, 2)
+	assert.Equal(t, int64(1700000000), state.Peers[0].LatestHandshake)
+	assert.Zero(t, state.Peers[1].LatestHandshake)
+}
+
+// statusHost is the canned state one host reports to statusRunner.
+type statusHost struct {
+	pub, addr string
+	now       int64
+	peers     map[string]int64 // peer pubkey → latest handshake
+	units     map[string]string
+	down      string // mgmt IP that does not answer pings
+	probeErr  bool
+}
+
+// statusRunner answers the CheckStatus probe and ping scripts.
+type statusRunner struct {
+	hosts map[string]statusHost
+}
+
+func (s *statusRunner) Run(_ context.Context, host, _ string, _ int, cmd string) (string, string, error) {
+	h := s.hosts[host]
+	if strings.Contains(cmd, "ping -c") {
+		var out strings.Builder
+		for _, line := range strings.Split(cmd, "\n") {
+			if fields := strings.Fields(line); len(fields) > 6 && fields[1] == "ping" {
+				ip := fields[6]
+				if ip == h.down {
+					fmt.Fprintf(&out, "%s fail\n", ip)
+				} else {
+					fmt.Fprintf(&out, "%s ok\n", ip)
+				}
+			}
+		}
+		return out.String(), "", nil
+	}
+	if h.probeErr {
+		return "", "connection refused", fmt.Errorf("exit 255")
+	}
+
+	var dump strings.Builder
+	for pub, hs := range h.peers {
+		fmt.Fprintf(&dump, "%s\t(none)\t(none)\t(none)\t%d\t0\t0\t25\n", pub, hs)
+	}
+	facts := map[string]string{
+		"now":       fmt.Sprint(h.now),
+		"wg_pubkey": h.pub,
+		"wg_peers":  dump.String(),
+		"wg_addr":   h.addr,
+	}
+	for _, u := range StatusUnits {
+		facts["unit."+u] = UnitNotInstalled
+		if v, ok := h.units[u]; ok {
+			facts["unit."+u] = v
+		}
+	}
+	var out strings.Builder
+	out.WriteString("{")
+	for k, v := range facts {
+		fmt.Fprintf(&out, "%q:%q,", k, base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+	out.WriteString(`"_done":""}`)
+	return out.String(), "", nil
+}
+
+func healthyStatusHosts() map[string]statusHost {
+	active := map[string]string{"coold": "active", "corrosion": "active"}
+	return map[string]statusHost{
+		"h1": {pub: "PUB1=", addr: "100.64.0.1/32", now: 1000, peers: map[string]int64{"PUB2=": 990, "PUB3=": 950},
+			units: map[string]string{"coold": "active", "corrosion": "active", "scheduler": "active"}},
+		"h2": {pub: "PUB2=", addr: "100.64.0.2/32", now: 1000, peers: map[string]int64{"PUB1=": 990, "PUB3=": 980}, units: active},
+		"h3": {pub: "PUB3=", addr: "100.64.0.3/32", now: 1000, peers: map[string]int64{"PUB1=": 950, "PUB2=": 980}, units: active},
+	}
+}
+
+func TestCheckStatus_Healthy(t *testing.T) {
+	runner := &statusRunner{hosts: healthyStatusHosts()}
+	status := CheckStatus(context.Background(), runner, []string{"h1", "h2", "h3"}, "root", 22, "wg0", 3)
+
+	require.Len(t, status.Hosts, 3)
+	assert.Equal(t, "100.64.0.2", status.Hosts[1].MgmtIP.String())
+	link := status.Link("h1", "h3")
+	assert.True(t, link.Peered)
+	assert.True(t, link.Pinged)
+	assert.True(t, link.Reachable)
+	assert.Equal(t, 50*time.Second, link.HandshakeAge)
+
+	problems := status.Evaluate("h1", DefaultStaleHandshake)
+	assert.Empty(t, problems)
+	assert.Equal(t, SeverityOK, WorstSeverity(problems))
+}
+
+func TestCheckStatus_Problems(t *testing.T) {
+	hosts := healthyStatusHosts()
+	h1 := hosts["h1"]
+	h1.peers = map[string]int64{"PUB2=": 990, "PUB3=": 100} // 15m stale
+	h1.units = map[string]string{"coold": "active", "corrosion": "active"}
+	hosts["h1"] = h1
+	h2 := hosts["h2"]
+	h2.down = "100.64.0.3"
+	h2.units = map[string]string{"coold": "failed", "corrosion": "active"}
+	hosts["h2"] = h2
+	h3 := hosts["h3"]
+	h3.peers = map[string]int64{"PUB1=": 950} // lost h2
+	hosts["h3"] = h3
+
+	status := CheckStatus(context.Background(), &statusRunner{hosts: hosts}, []string{"h1", "h2", "h3"}, "root", 22, "wg0", 3)
+	problems := status.Evaluate("h1", DefaultStaleHandshake)
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%s %s: %s", p.Severity, p.Host, p.Message))
+	}
+	assert.ElementsMatch(t, []string{
+		"CRITICAL h1: scheduler is not-installed",
+		"WARNING h1: last handshake with h3 was 15m0s ago",
+		"CRITICAL h2: coold is failed",
+		"CRITICAL h2: cannot ping h3 (100.64.0.3)",
+		"CRITICAL h3: h2 is not a WireGuard peer",
+	}, got)
+	assert.Equal(t, SeverityCritical, WorstSeverity(problems))
+}
+
+func TestCheckStatus_UnreachableHost(t *testing.T) {
+	hosts := healthyStatusHosts()
+	hosts["h3"] = statusHost{probeErr: true}
+
+	status := CheckStatus(context.Background(), &statusRunner{hosts: hosts}, []string{"h1", "h2", "h3"}, "root", 22, "wg0", 3)
+	require.Error(t, status.Hosts[2].Err)
+	assert.False(t, status.Link("h1", "h3").Pinged, "no mgmt IP known for an unprobed host")
+
+	problems := status.Evaluate("", DefaultStaleHandshake)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "h3", problems[0].Host)
+	assert.Contains(t, problems[0].Message, "probe failed")
+}
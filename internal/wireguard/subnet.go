@@ -1,8 +1,8 @@
 package wireguard
 
 import (
-	"encoding/binary"
 	"fmt"
+	"math/big"
 	"net"
 	"sort"
 )
@@ -14,21 +14,96 @@ type Warning struct {
 }
 
 // MachineIP returns the host address within a per-host subnet — the first
-// usable IP (network address + 1).  For example, 10.210.5.0/24 → 10.210.5.1.
+// usable IP (network address + 1).  For example, 10.210.5.0/24 → 10.210.5.1
+// and fd10:210:5::/64 → fd10:210:5::1.
 //
 // Used for the Podman bridge gateway. WireGuard does NOT use this — wg0
-// gets a separate /32 from the management pool (see AllocateMgmtIPs).
+// gets a separate host address from the management pool (see
+// AllocateMgmtIPs).
 func MachineIP(subnet *net.IPNet) net.IP {
-	return uint32ToIP(ipToUint32(subnet.IP.To4()) + 1)
+	ip := normalizeIP(subnet.IP)
+	return intToIP(new(big.Int).Add(ipToInt(ip), big.NewInt(1)), ipBits(ip))
+}
+
+// HostCIDR renders ip as a single-address prefix: /32 for IPv4, /128 for
+// IPv6.
+func HostCIDR(ip net.IP) string {
+	return fmt.Sprintf("%s/%d", ip, ipBits(ip))
+}
+
+// addrPool iterates the /prefix blocks of an IPv4 or IPv6 pool.
+type addrPool struct {
+	pool    *net.IPNet
+	bits    int
+	prefix  int
+	network *big.Int
+	last    *big.Int
+	step    *big.Int
+}
+
+func newAddrPool(pool *net.IPNet, hostPrefix int) (*addrPool, error) {
+	ip := normalizeIP(pool.IP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid pool %s", pool)
+	}
+	bits := ipBits(ip)
+	ones, maskBits := pool.Mask.Size()
+	if maskBits != bits {
+		return nil, fmt.Errorf("invalid pool %s", pool)
+	}
+	if hostPrefix < ones || hostPrefix > bits {
+		return nil, fmt.Errorf("prefix /%d does not fit inside pool %s", hostPrefix, pool)
+	}
+	network := ipToInt(ip.Mask(pool.Mask))
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	return &addrPool{
+		pool:    pool,
+		bits:    bits,
+		prefix:  hostPrefix,
+		network: network,
+		last:    new(big.Int).Sub(new(big.Int).Add(network, size), big.NewInt(1)),
+		step:    new(big.Int).Lsh(big.NewInt(1), uint(bits-hostPrefix)),
+	}, nil
+}
+
+// fits reports whether subnet is a /prefix of the pool's family inside it.
+func (p *addrPool) fits(subnet *net.IPNet) bool {
+	ip := normalizeIP(subnet.IP)
+	ones, _ := subnet.Mask.Size()
+	return ip != nil && ipBits(ip) == p.bits && p.pool.Contains(ip) && ones == p.prefix
+}
+
+// isEdge reports whether a single-address block is the pool's network
+// address, or its IPv4 broadcast address; many tools refuse both as host IPs.
+func (p *addrPool) isEdge(ip net.IP) bool {
+	n := ipToInt(normalizeIP(ip))
+	return n.Cmp(p.network) == 0 || (p.bits == 32 && n.Cmp(p.last) == 0)
+}
+
+// next returns the lowest block not in used. Single-address blocks skip the
+// pool edges.
+func (p *addrPool) next(used map[string]bool) (*net.IPNet, error) {
+	u := new(big.Int).Set(p.network)
+	if p.prefix == p.bits {
+		u.Add(u, big.NewInt(1))
+	}
+	for ; u.Cmp(p.last) < 0; u.Add(u, p.step) {
+		ip := intToIP(u, p.bits)
+		if !used[ip.String()] {
+			return &net.IPNet{IP: ip, Mask: net.CIDRMask(p.prefix, p.bits)}, nil
+		}
+	}
+	return nil, fmt.Errorf("pool %s is exhausted (no free /%d subnets)", p.pool, p.prefix)
 }
 
 // Allocate assigns a per-host subnet (of size hostPrefix) to every host in
-// hosts, carving them from pool.
+// hosts, carving them from pool. The pool may be IPv4 or IPv6.
 //
 // Rules:
 //   - Duplicate host names in hosts → error (user input bug).
 //   - Existing subnet within pool with correct prefix → kept unchanged (stable).
-//   - Existing subnet outside pool or wrong prefix → warning, reassign.
+//   - Existing subnet outside pool, of the other address family or with a
+//     wrong prefix → warning, reassign.
 //   - Two existing hosts with the same subnet → first (alphabetical) kept,
 //     second gets a warning and is reassigned.
 //   - New hosts receive the lowest free subnet in pool.
@@ -51,16 +126,16 @@ func Allocate(
 		}
 	}
 
-	pool4 := pool.IP.To4()
-	if pool4 == nil {
-		return nil, nil, fmt.Errorf("only IPv4 pools are supported")
+	ap, err := newAddrPool(pool, hostPrefix)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	result := make(map[string]*net.IPNet, len(hosts))
-	usedNetworks := make(map[uint32]bool)
+	usedNetworks := make(map[string]bool)
 	var warnings []Warning
 
-	subnetClaim := make(map[uint32]string)
+	subnetClaim := make(map[string]string)
 
 	// 2. Seed from existing — sorted for deterministic conflict resolution.
 	existingHosts := make([]string, 0, len(existing))
@@ -69,21 +144,13 @@ func Allocate(
 	}
 	sort.Strings(existingHosts)
 
-	// Pool bounds (used for both validation and iteration).
-	pool4Network := ipToUint32(pool4)
-	poolOnes, poolBits := pool.Mask.Size()
-	poolHostBits := poolBits - poolOnes
-	pool4Broadcast := pool4Network | (uint32(1)<<uint(poolHostBits) - 1)
-
 	for _, host := range existingHosts {
 		subnet := existing[host]
 		if subnet == nil {
 			continue
 		}
-		subnet4 := subnet.IP.To4()
-		ones, _ := subnet.Mask.Size()
 
-		if subnet4 == nil || !pool.Contains(subnet4) || ones != hostPrefix {
+		if !ap.fits(subnet) {
 			warnings = append(warnings, Warning{
 				Host:   host,
 				Reason: fmt.Sprintf("existing subnet %s is not a /%d inside pool %s, reassigning", subnet, hostPrefix, pool),
@@ -91,19 +158,20 @@ func Allocate(
 			continue
 		}
 
-		networkU32 := ipToUint32(subnet4)
+		ip := normalizeIP(subnet.IP)
+		key := ip.String()
 
-		// For /32 mgmt IPs, reject pool's network address (.0) and broadcast
-		// (.255.255) — many tools refuse them as host addresses.
-		if hostPrefix == 32 && (networkU32 == pool4Network || networkU32 == pool4Broadcast) {
+		// For single-address mgmt IPs, reject the pool's network address
+		// (and the IPv4 broadcast) — many tools refuse them as host addresses.
+		if hostPrefix == ap.bits && ap.isEdge(ip) {
 			warnings = append(warnings, Warning{
 				Host:   host,
-				Reason: fmt.Sprintf("existing mgmt IP %s is the pool network or broadcast address, reassigning", subnet4),
+				Reason: fmt.Sprintf("existing mgmt IP %s is the pool network or broadcast address, reassigning", ip),
 			})
 			continue
 		}
 
-		if claimant, exists := subnetClaim[networkU32]; exists {
+		if claimant, exists := subnetClaim[key]; exists {
 			warnings = append(warnings, Warning{
 				Host:   host,
 				Reason: fmt.Sprintf("duplicate subnet %s (already claimed by %s), reassigning", subnet, claimant),
@@ -111,45 +179,21 @@ func Allocate(
 			continue
 		}
 
-		subnetClaim[networkU32] = host
-		usedNetworks[networkU32] = true
-		result[host] = cloneIPNet(subnet)
+		subnetClaim[key] = host
+		usedNetworks[key] = true
+		result[host] = &net.IPNet{IP: cloneIP(ip), Mask: net.CIDRMask(hostPrefix, ap.bits)}
 	}
 
 	// 3. Iterate the pool to assign new hosts.
-	hostSubnetSize := 32 - hostPrefix
-	step := uint32(1) << uint(hostSubnetSize)
-
-	nextFreeSubnet := func() (*net.IPNet, error) {
-		// For /32 allocations (mgmt IPs), skip both the pool network address
-		// (.0) and the pool broadcast address (.255.255) since many tools
-		// refuse them as host IPs. For larger subnets (e.g. /24), the bridge
-		// inside the subnet handles its own .0/.broadcast — we only need to
-		// not start the iterator at the broadcast itself.
-		start := pool4Network
-		end := pool4Broadcast
-		if hostPrefix == 32 {
-			start = pool4Network + 1
-			// end stays at broadcast; loop is u < end so broadcast is excluded.
-		}
-		for u := start; u < end; u += step {
-			if !usedNetworks[u] {
-				mask := net.CIDRMask(hostPrefix, 32)
-				return &net.IPNet{IP: uint32ToIP(u), Mask: mask}, nil
-			}
-		}
-		return nil, fmt.Errorf("pool %s is exhausted (no free /%d subnets)", pool, hostPrefix)
-	}
-
 	for _, host := range hosts {
 		if _, already := result[host]; already {
 			continue
 		}
-		subnet, err := nextFreeSubnet()
+		subnet, err := ap.next(usedNetworks)
 		if err != nil {
 			return nil, warnings, fmt.Errorf("allocating subnet for %s: %w", host, err)
 		}
-		usedNetworks[ipToUint32(subnet.IP.To4())] = true
+		usedNetworks[subnet.IP.String()] = true
 		result[host] = subnet
 	}
 
@@ -158,9 +202,9 @@ func Allocate(
 
 // AllocateNamespaced assigns a per-host /<hostPrefix> subnet for every
 // (namespace, host) pair in `namespaces × hosts`, carving them from a single
-// shared pool. Stable: existing valid assignments are preserved so re-runs
-// reproduce the same subnets. Invalid or duplicate existing assignments
-// produce a warning and get reassigned to the next free block.
+// shared IPv4 or IPv6 pool. Stable: existing valid assignments are preserved
+// so re-runs reproduce the same subnets. Invalid or duplicate existing
+// assignments produce a warning and get reassigned to the next free block.
 //
 // Iteration order is deterministic (namespaces then hosts as passed in),
 // which keeps warnings and subnet layout reproducible for tests.
@@ -173,9 +217,9 @@ func AllocateNamespaced(
 	namespaces []string,
 	hosts []string,
 ) (map[string]map[string]*net.IPNet, []Warning, error) {
-	pool4 := pool.IP.To4()
-	if pool4 == nil {
-		return nil, nil, fmt.Errorf("only IPv4 pools are supported")
+	ap, err := newAddrPool(pool, hostPrefix)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Dedup hosts (user input bug).
@@ -200,17 +244,12 @@ func AllocateNamespaced(
 		}
 	}
 
-	pool4Network := ipToUint32(pool4)
-	poolOnes, poolBits := pool.Mask.Size()
-	poolHostBits := poolBits - poolOnes
-	pool4Broadcast := pool4Network | (uint32(1)<<uint(poolHostBits) - 1)
-
 	result := make(map[string]map[string]*net.IPNet, len(namespaces))
 	for _, ns := range namespaces {
 		result[ns] = make(map[string]*net.IPNet, len(hosts))
 	}
-	usedNetworks := make(map[uint32]bool)
-	subnetClaim := make(map[uint32]string) // "ns/host" for conflict messages
+	usedNetworks := make(map[string]bool)
+	subnetClaim := make(map[string]string) // "ns/host" for conflict messages
 	var warnings []Warning
 
 	// 1. Seed from existing assignments in deterministic order.
@@ -231,53 +270,40 @@ func AllocateNamespaced(
 			if subnet == nil {
 				continue
 			}
-			subnet4 := subnet.IP.To4()
-			ones, _ := subnet.Mask.Size()
-			if subnet4 == nil || !pool.Contains(subnet4) || ones != hostPrefix {
+			if !ap.fits(subnet) {
 				warnings = append(warnings, Warning{
 					Host:   host,
 					Reason: fmt.Sprintf("existing subnet %s in namespace %q is not a /%d inside pool %s, reassigning", subnet, ns, hostPrefix, pool),
 				})
 				continue
 			}
-			networkU32 := ipToUint32(subnet4)
-			if claimant, dup := subnetClaim[networkU32]; dup {
+			key := normalizeIP(subnet.IP).String()
+			if claimant, dup := subnetClaim[key]; dup {
 				warnings = append(warnings, Warning{
 					Host:   host,
 					Reason: fmt.Sprintf("duplicate subnet %s in namespace %q (already claimed by %s), reassigning", subnet, ns, claimant),
 				})
 				continue
 			}
-			subnetClaim[networkU32] = ns + "/" + host
-			usedNetworks[networkU32] = true
+			subnetClaim[key] = ns + "/" + host
+			usedNetworks[key] = true
 			result[ns][host] = cloneIPNet(subnet)
 		}
 	}
 
 	// 2. Assign remaining (ns, host) pairs in input order.
-	hostSubnetSize := 32 - hostPrefix
-	step := uint32(1) << uint(hostSubnetSize)
-	nextFree := func() (*net.IPNet, error) {
-		for u := pool4Network; u < pool4Broadcast; u += step {
-			if !usedNetworks[u] {
-				return &net.IPNet{IP: uint32ToIP(u), Mask: net.CIDRMask(hostPrefix, 32)}, nil
-			}
-		}
-		return nil, fmt.Errorf("pool %s is exhausted (no free /%d subnets)", pool, hostPrefix)
-	}
-
 	for _, ns := range namespaces {
 		for _, host := range hosts {
 			if _, ok := result[ns][host]; ok {
 				continue
 			}
-			subnet, err := nextFree()
+			subnet, err := ap.next(usedNetworks)
 			if err != nil {
 				return nil, warnings, fmt.Errorf("allocating subnet for %s/%s: %w", ns, host, err)
 			}
-			u := ipToUint32(subnet.IP.To4())
-			usedNetworks[u] = true
-			subnetClaim[u] = ns + "/" + host
+			key := subnet.IP.String()
+			usedNetworks[key] = true
+			subnetClaim[key] = ns + "/" + host
 			result[ns][host] = subnet
 		}
 	}
@@ -285,43 +311,67 @@ func AllocateNamespaced(
 	return result, warnings, nil
 }
 
-// AllocateMgmtIPs assigns a /32 management IP to every host in hosts from pool.
+// AllocateMgmtIPs assigns a single-address management IP (/32 or /128) to
+// every host in hosts from pool.
 // Wraps Allocate by promoting/demoting between net.IP and *net.IPNet.
 func AllocateMgmtIPs(
 	pool *net.IPNet,
 	existing map[string]net.IP,
 	hosts []string,
 ) (map[string]net.IP, []Warning, error) {
+	bits := ipBits(normalizeIP(pool.IP))
 	wrapped := make(map[string]*net.IPNet, len(existing))
 	for h, ip := range existing {
-		ip4 := ip.To4()
-		if ip4 == nil {
+		ip = normalizeIP(ip)
+		if ip == nil {
 			continue
 		}
-		wrapped[h] = &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+		wrapped[h] = &net.IPNet{IP: ip, Mask: net.CIDRMask(ipBits(ip), ipBits(ip))}
 	}
 
-	subnets, warns, err := Allocate(pool, 32, wrapped, hosts)
+	subnets, warns, err := Allocate(pool, bits, wrapped, hosts)
 	if err != nil {
 		return nil, warns, err
 	}
 
 	out := make(map[string]net.IP, len(subnets))
 	for h, n := range subnets {
-		out[h] = cloneIP(n.IP.To4())
+		out[h] = cloneIP(n.IP)
 	}
 	return out, warns, nil
 }
 
-// ipToUint32 converts a 4-byte IP to a uint32 for arithmetic.
-func ipToUint32(ip net.IP) uint32 {
-	return binary.BigEndian.Uint32(ip.To4())
+// normalizeIP returns the 4-byte form of an IPv4 address and the 16-byte
+// form of an IPv6 one, or nil for an invalid IP.
+func normalizeIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip.To16()
+}
+
+// ipBits returns the address length of ip's family: 32 or 128.
+func ipBits(ip net.IP) int {
+	if ip.To4() != nil {
+		return 32
+	}
+	return 128
+}
+
+// IsIPv6 reports whether ip is an IPv6 (not IPv4-mapped) address.
+func IsIPv6(ip net.IP) bool {
+	return ip != nil && ip.To4() == nil
+}
+
+// ipToInt converts an IP to an integer for arithmetic.
+func ipToInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(normalizeIP(ip))
 }
 
-// uint32ToIP converts a uint32 back to a net.IP.
-func uint32ToIP(u uint32) net.IP {
-	ip := make(net.IP, 4)
-	binary.BigEndian.PutUint32(ip, u)
+// intToIP converts an integer back to a bits-long net.IP.
+func intToIP(i *big.Int, bits int) net.IP {
+	ip := make(net.IP, bits/8)
+	i.FillBytes(ip)
 	return ip
 }
 
@@ -25,6 +25,7 @@ func TestMachineIP(t *testing.T) {
 		{"10.210.5.0/24", "10.210.5.1"},
 		{"10.210.255.0/24", "10.210.255.1"},
 		{"192.168.0.0/24", "192.168.0.1"},
+		{"fd00:210:0:1::/64", "fd00:210:0:1::1"},
 	}
 	for _, tt := range tests {
 		n := mustParseCIDR(tt.subnet)
@@ -221,3 +222,29 @@ func TestAllocate_EmptyHosts(t *testing.T) {
 	assert.Empty(t, warns)
 	assert.Empty(t, got)
 }
+
+func TestAllocate_IPv6Pool(t *testing.T) {
+	pool := mustParseCIDR("fd00:210::/48")
+	got, warns, err := Allocate(pool, 64, nil, []string{"h1", "h2"})
+	require.NoError(t, err)
+	assert.Empty(t, warns)
+	assert.Equal(t, "fd00:210::/64", got["h1"].String())
+	assert.Equal(t, "fd00:210:0:1::/64", got["h2"].String())
+}
+
+func TestAllocateMgmtIPs_IPv6Pool(t *testing.T) {
+	pool := mustParseCIDR("fd00:64::/64")
+	existing := map[string]net.IP{"h1": net.ParseIP("fd00:64::1")}
+	got, warns, err := AllocateMgmtIPs(pool, existing, []string{"h1", "h2"})
+	require.NoError(t, err)
+	assert.Empty(t, warns)
+	assert.Equal(t, "fd00:64::1", got["h1"].String())
+	assert.Equal(t, "fd00:64::2", got["h2"].String())
+	assert.Equal(t, "fd00:64::2/128", HostCIDR(got["h2"]))
+}
+
+func TestAllocate_PrefixOutsidePool_Errors(t *testing.T) {
+	_, _, err := Allocate(mustParseCIDR("10.210.0.0/16"), 8, nil, []string{"h1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not fit inside pool")
+}